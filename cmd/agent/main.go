@@ -2,6 +2,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -11,7 +13,10 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/KodaTao/AgentChassis/pkg/chassis"
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/mcp"
 	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/scheduler"
 	"github.com/KodaTao/AgentChassis/pkg/server"
 )
 
@@ -33,6 +38,7 @@ It focuses on efficient Function calling with minimal token usage.`,
 
 	// 添加子命令
 	rootCmd.AddCommand(serveCmd())
+	rootCmd.AddCommand(mcpCmd())
 	rootCmd.AddCommand(versionCmd())
 
 	if err := rootCmd.Execute(); err != nil {
@@ -66,14 +72,7 @@ func serveCmd() *cobra.Command {
 			}
 
 			// 创建应用
-			app = chassis.New(
-				chassis.WithServerPort(config.Server.Port),
-				chassis.WithServerMode(config.Server.Mode),
-				chassis.WithLLMConfig(config.LLM),
-				chassis.WithLogLevel(config.Log.Level),
-				chassis.WithDatabasePath(config.Database.Path),
-				chassis.WithTelegram(config.Telegram),
-			)
+			app = newApp(config)
 
 			// 初始化
 			if err := app.Initialize(); err != nil {
@@ -85,6 +84,9 @@ func serveCmd() *cobra.Command {
 				Host: config.Server.Host,
 				Port: config.Server.Port,
 				Mode: config.Server.Mode,
+
+				MaxBodyBytes:    config.Server.MaxBodyBytes,
+				MaxMessageChars: config.Server.MaxMessageChars,
 			})
 
 			// 优雅关闭
@@ -109,13 +111,91 @@ func serveCmd() *cobra.Command {
 	return cmd
 }
 
+// mcpCmd 以 MCP server 的形式通过 stdio 暴露函数
+func mcpCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve functions to MCP clients over stdio",
+		Long: `Expose registered functions as MCP (Model Context Protocol) tools over stdio,
+so MCP clients such as Claude Desktop can list and call them directly.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// 标准输出专用于 JSON-RPC 消息，其他输出改到 stderr
+			if config.Log.Output != "file" {
+				config.Log.Output = "stderr"
+			}
+			if config.Console.Output == chassis.ConsoleOutputStdout {
+				config.Console.Output = chassis.ConsoleOutputStderr
+			}
+			if config.Observability.Events.Output == "stdout" {
+				fmt.Fprintln(os.Stderr, "observability.events.output=stdout is not supported in mcp mode, events disabled")
+				config.Observability.Events.Output = ""
+			}
+
+			// 只提供函数，调度器和 Telegram Bot 由 serve 进程负责
+			app = newApp(config, chassis.WithMCPMode())
+			if err := app.Initialize(); err != nil {
+				return fmt.Errorf("failed to initialize: %w", err)
+			}
+			defer app.Shutdown()
+
+			ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			agent := app.GetAgent()
+			srv := mcp.NewServer(agent.GetRegistry(), agent.GetExecutor(), mcp.Config{
+				Version: chassis.Version,
+				Scopes:  config.MCP.Scopes,
+				Logger:  app.GetLogger(),
+			})
+			app.GetLogger().Info("MCP server started", "transport", "stdio")
+			if err := srv.ServeStdio(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// newApp 按配置创建应用，extra 追加在配置生成的选项之后
+func newApp(config *chassis.Config, extra ...chassis.Option) *chassis.App {
+	opts := []chassis.Option{
+		chassis.WithServerPort(config.Server.Port),
+		chassis.WithServerMode(config.Server.Mode),
+		chassis.WithShutdownTimeout(config.Server.ShutdownTimeout),
+		chassis.WithLLMConfig(config.LLM),
+		chassis.WithLogConfig(config.Log),
+		chassis.WithObservability(config.Observability),
+		chassis.WithDatabaseConfig(config.Database),
+		chassis.WithTelegram(config.Telegram),
+		chassis.WithAgentConfig(config.Agent),
+		chassis.WithBuiltins(config.Builtins),
+		chassis.WithWebhook(config.Webhook),
+		chassis.WithAuth(config.Auth),
+		chassis.WithAudit(config.Audit),
+		chassis.WithConsole(config.Console),
+		chassis.WithScheduler(config.Scheduler),
+		chassis.WithSessionStorage(config.Session),
+		chassis.WithResources(config.Resources),
+		chassis.WithPromptVars(config.PromptVars),
+		chassis.WithDedupCalls(config.DedupCalls),
+		chassis.WithMaxHistoryTokens(config.MaxHistoryTokens),
+		chassis.WithRateLimits(config.RateLimits),
+	}
+	return chassis.New(append(opts, extra...)...)
+}
+
 // versionCmd 显示版本信息
 func versionCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
 		Short: "Print version information",
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("AgentChassis v0.1.0")
+			fmt.Println("AgentChassis v" + chassis.Version)
 			fmt.Println("The Lightweight, Pluggable Agent Framework for Go")
 		},
 	}
@@ -129,6 +209,9 @@ func loadConfig() (*chassis.Config, error) {
 	v.SetDefault("server.host", "0.0.0.0")
 	v.SetDefault("server.port", 8080)
 	v.SetDefault("server.mode", "debug")
+	v.SetDefault("server.shutdown_timeout", "30s")
+	v.SetDefault("server.max_body_bytes", 1<<20)
+	v.SetDefault("server.max_message_chars", 16000)
 
 	v.SetDefault("llm.provider", "openai")
 	v.SetDefault("llm.base_url", "https://api.openai.com/v1")
@@ -136,12 +219,66 @@ func loadConfig() (*chassis.Config, error) {
 	v.SetDefault("llm.timeout", 60)
 	v.SetDefault("llm.max_tokens", 4096)
 	v.SetDefault("llm.temperature", 0.7)
+	v.SetDefault("llm.call_mode", "xml")
+	v.SetDefault("llm.response_format", "")
+	v.SetDefault("llm.circuit_breaker.failure_threshold", 5)
+	v.SetDefault("llm.circuit_breaker.open_timeout", "30s")
+	v.SetDefault("llm.cache.mode", "auto")
+	v.SetDefault("llm.cache.ttl", "10m")
+	v.SetDefault("llm.cache.max_entries", 1000)
 
 	v.SetDefault("database.path", "~/.agentchassis/data.db")
+	v.SetDefault("database.scheduler_path", "")
+	v.SetDefault("database.session_path", "")
+	v.SetDefault("database.journal_mode", "WAL")
+	v.SetDefault("database.busy_timeout", "5s")
+	v.SetDefault("database.synchronous", "NORMAL")
+	v.SetDefault("database.max_open_conns", 1)
+
+	v.SetDefault("session.persist", false)
+	v.SetDefault("session.compress", false)
 
 	v.SetDefault("log.level", "info")
 	v.SetDefault("log.format", "text")
 	v.SetDefault("log.output", "stdout")
+	v.SetDefault("log.max_size_mb", 100)
+	v.SetDefault("log.max_backups", 7)
+	v.SetDefault("log.max_age_days", 30)
+	v.SetDefault("log.llm_verbose", false)
+	v.SetDefault("log.llm_verbose_max_chars", 2000)
+
+	v.SetDefault("telegram.max_queued_messages", 10)
+
+	v.SetDefault("agent.max_iterations", 10)
+	v.SetDefault("agent.timeout", "5m")
+	v.SetDefault("agent.max_unknown_calls", 3)
+	v.SetDefault("agent.parallel_calls", false)
+	v.SetDefault("agent.persona", "")
+	v.SetDefault("agent.language", "")
+	v.SetDefault("agent.empty_reply_retries", 1)
+	v.SetDefault("agent.max_function_calls", 0)
+	v.SetDefault("agent.show_thoughts", false)
+	v.SetDefault("agent.max_result_chars", chassis.DefaultMaxResultChars)
+	v.SetDefault("agent.summarize_results", false)
+	v.SetDefault("agent.slim_results", false)
+	v.SetDefault("agent.transient_retries", 1)
+
+	v.SetDefault("console.output", chassis.ConsoleOutputLog)
+	v.SetDefault("console.pretty", false)
+
+	v.SetDefault("audit.enabled", false)
+	v.SetDefault("audit.mask_pii", false)
+	v.SetDefault("audit.retention_days", 0)
+
+	v.SetDefault("scheduler.delay_max_concurrent", scheduler.DefaultDelayMaxConcurrent)
+
+	v.SetDefault("resources.enabled", false)
+	v.SetDefault("resources.goroutine_growth", function.DefaultGoroutineGrowth)
+	v.SetDefault("resources.heap_alloc_mb", function.DefaultHeapAllocBytes>>20)
+	v.SetDefault("resources.unhealthy_after", function.DefaultUnhealthyAfter)
+
+	v.SetDefault("dedup_calls", true)
+	v.SetDefault("max_history_tokens", 0)
 
 	// 配置文件
 	if cfgFile != "" {
@@ -172,5 +309,10 @@ func loadConfig() (*chassis.Config, error) {
 		return nil, err
 	}
 
+	// 展开配置中的 ${VAR} 环境变量引用
+	if err := chassis.ExpandEnv(config); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
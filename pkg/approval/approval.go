@@ -0,0 +1,188 @@
+// Package approval 提供函数调用的人工审批记录
+// 标记为需审批的高危函数被 AI 调用时不会立即执行，而是持久化一条待审批记录，
+// 管理员批准后才执行，拒绝或过期则不执行
+package approval
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/KodaTao/AgentChassis/pkg/storage"
+)
+
+// 审批状态
+const (
+	StatusPending  = "pending"  // 等待审批
+	StatusApproved = "approved" // 已批准并执行
+	StatusRejected = "rejected" // 已拒绝，未执行
+)
+
+// 错误定义
+var (
+	ErrNotFound        = errors.New("approval request not found")
+	ErrAlreadyDecided  = errors.New("approval request has already been decided")
+	ErrInvalidDecision = errors.New("decision must be approved or rejected")
+)
+
+// Request 一次函数调用的审批请求
+type Request struct {
+	ID           string     `gorm:"primarykey;size:32" json:"id"`
+	SessionID    string     `gorm:"index" json:"session_id"`             // 发起调用的会话，审批期间该会话暂停
+	RequestID    string     `gorm:"index" json:"request_id"`             // 发起调用的 Chat 请求 ID
+	CallID       string     `json:"call_id"`                             // 调用 ID，格式为 <request_id>.<iteration>.<index>
+	Iteration    int        `json:"iteration"`                           // 第几轮 LLM 调用
+	CallIndex    int        `json:"call_index"`                          // 本轮中的第几个调用
+	UserID       string     `gorm:"index" json:"user_id,omitempty"`      // 发起对话的用户
+	Channel      string     `gorm:"type:text" json:"channel,omitempty"`  // 渠道上下文（JSON），结果据此通知用户
+	FunctionName string     `gorm:"not null;index" json:"function_name"` // 函数名
+	Params       string     `gorm:"type:text" json:"params"`             // 调用参数（JSON 对象）
+	Data         string     `gorm:"type:text" json:"data,omitempty"`     // 调用附带的数据块
+	CallerScopes string     `gorm:"type:text" json:"-"`                  // 发起调用者的作用域（JSON 数组），批准后按此执行；为空表示内部调用，不受限
+	Status       string     `gorm:"not null;index" json:"status"`        // pending, approved, rejected
+	DecidedBy    string     `json:"decided_by,omitempty"`                // 审批人
+	Reason       string     `gorm:"type:text" json:"reason,omitempty"`   // 审批意见
+	Result       string     `gorm:"type:text" json:"result,omitempty"`   // 批准后的执行结果摘要
+	CreatedAt    time.Time  `gorm:"not null;index" json:"created_at"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty"`
+}
+
+// TableName 指定表名
+func (Request) TableName() string {
+	return "approval_requests"
+}
+
+// Filter 审批请求查询条件，零值字段不参与过滤
+type Filter struct {
+	Status       string
+	SessionID    string
+	FunctionName string
+}
+
+// Repository 审批请求数据访问层
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建 Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// migrations 审批请求表的迁移，schema 变化时在末尾追加新版本
+var migrations = []storage.Migration{
+	{
+		Version: 1,
+		Name:    "create approval_requests",
+		Up:      storage.CreateTables(&Request{}),
+		Down:    storage.DropTables(&Request{}),
+	},
+	{
+		Version: 2,
+		Name:    "add caller_scopes to approval_requests",
+		Up:      storage.AddColumns(&Request{}, "CallerScopes"),
+		Down:    storage.DropColumns(&Request{}, "CallerScopes"),
+	},
+}
+
+// Migrate 按版本执行未应用的迁移
+func (r *Repository) Migrate() error {
+	return storage.NewMigrator(r.db, "approval", migrations...).Migrate()
+}
+
+// Create 创建审批请求
+func (r *Repository) Create(req *Request) error {
+	if req.Status == "" {
+		req.Status = StatusPending
+	}
+	return r.db.Create(req).Error
+}
+
+// Get 获取审批请求
+func (r *Repository) Get(id string) (*Request, error) {
+	var req Request
+	err := r.db.Where("id = ?", id).First(&req).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// Decide 把待审批的请求标记为批准或拒绝，返回更新后的记录
+// 以 status = pending 为条件更新，同一请求被并发审批时只有一方成功，另一方返回 ErrAlreadyDecided
+func (r *Repository) Decide(id, status, decidedBy, reason string) (*Request, error) {
+	if status != StatusApproved && status != StatusRejected {
+		return nil, ErrInvalidDecision
+	}
+
+	now := time.Now()
+	result := r.db.Model(&Request{}).
+		Where("id = ? AND status = ?", id, StatusPending).
+		Updates(map[string]any{
+			"status":     status,
+			"decided_by": decidedBy,
+			"reason":     reason,
+			"decided_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		req, err := r.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		return req, ErrAlreadyDecided
+	}
+	return r.Get(id)
+}
+
+// SetResult 记录批准后的执行结果
+func (r *Repository) SetResult(id, result string) error {
+	return r.db.Model(&Request{}).Where("id = ?", id).Update("result", result).Error
+}
+
+// List 按条件列出审批请求，按创建时间倒序
+func (r *Repository) List(filter Filter, limit, offset int) ([]Request, error) {
+	var requests []Request
+	query := r.applyFilter(filter)
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	err := query.Order("created_at DESC").Find(&requests).Error
+	return requests, err
+}
+
+// Count 按条件统计审批请求数量
+func (r *Repository) Count(filter Filter) (int64, error) {
+	var count int64
+	err := r.applyFilter(filter).Count(&count).Error
+	return count, err
+}
+
+// applyFilter 构建带过滤条件的查询
+func (r *Repository) applyFilter(filter Filter) *gorm.DB {
+	query := r.db.Model(&Request{})
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.SessionID != "" {
+		query = query.Where("session_id = ?", filter.SessionID)
+	}
+	if filter.FunctionName != "" {
+		query = query.Where("function_name = ?", filter.FunctionName)
+	}
+
+	return query
+}
@@ -0,0 +1,91 @@
+package approval
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/storage/storagetest"
+)
+
+func TestRepository_Decide(t *testing.T) {
+	repo := storagetest.NewRepository(t, NewRepository)
+	if err := repo.Create(&Request{ID: "a1", SessionID: "s1", FunctionName: "transfer", Params: "{}"}); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := repo.Decide("a1", StatusApproved, "admin", "ok")
+	if err != nil {
+		t.Fatalf("Decide: %v", err)
+	}
+	if req.Status != StatusApproved || req.DecidedBy != "admin" || req.Reason != "ok" || req.DecidedAt == nil {
+		t.Errorf("decided request = %+v", req)
+	}
+
+	// 已决定的请求不能再次审批，也不会被覆盖
+	req, err = repo.Decide("a1", StatusRejected, "other", "no")
+	if !errors.Is(err, ErrAlreadyDecided) {
+		t.Fatalf("second Decide error = %v, want ErrAlreadyDecided", err)
+	}
+	if req.Status != StatusApproved || req.DecidedBy != "admin" {
+		t.Errorf("request after second Decide = %+v", req)
+	}
+
+	if _, err := repo.Decide("missing", StatusApproved, "admin", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Decide missing error = %v, want ErrNotFound", err)
+	}
+	if _, err := repo.Decide("a1", StatusPending, "admin", ""); !errors.Is(err, ErrInvalidDecision) {
+		t.Errorf("Decide pending error = %v, want ErrInvalidDecision", err)
+	}
+}
+
+func TestRepository_ListAndCount(t *testing.T) {
+	repo := storagetest.NewRepository(t, NewRepository)
+	for _, req := range []*Request{
+		{ID: "a1", SessionID: "s1", FunctionName: "transfer"},
+		{ID: "a2", SessionID: "s1", FunctionName: "delete_user"},
+		{ID: "a3", SessionID: "s2", FunctionName: "transfer"},
+	} {
+		if err := repo.Create(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.Decide("a3", StatusRejected, "admin", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetResult("a3", "skipped"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int64
+	}{
+		{"all", Filter{}, 3},
+		{"pending", Filter{Status: StatusPending}, 2},
+		{"session", Filter{SessionID: "s1"}, 2},
+		{"function", Filter{FunctionName: "transfer"}, 2},
+		{"combined", Filter{Status: StatusRejected, FunctionName: "transfer"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := repo.Count(tt.filter)
+			if err != nil || count != tt.want {
+				t.Errorf("Count = %d, %v, want %d", count, err, tt.want)
+			}
+			requests, err := repo.List(tt.filter, 0, 0)
+			if err != nil || int64(len(requests)) != tt.want {
+				t.Errorf("List returned %d, %v, want %d", len(requests), err, tt.want)
+			}
+		})
+	}
+
+	page, err := repo.List(Filter{}, 2, 2)
+	if err != nil || len(page) != 1 {
+		t.Errorf("List page = %d, %v, want 1", len(page), err)
+	}
+	req, err := repo.Get("a3")
+	if err != nil || req.Result != "skipped" {
+		t.Errorf("Get = %+v, %v", req, err)
+	}
+}
@@ -0,0 +1,195 @@
+// Package audit 提供对话的持久化审计日志
+// 每次 Chat 的输入、输出、函数调用、token 用量等以结构化记录落库，独立于普通日志，便于合规查询和导出
+package audit
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/KodaTao/AgentChassis/pkg/storage"
+)
+
+// 记录状态
+const (
+	StatusSuccess   = "success"
+	StatusError     = "error"
+	StatusCancelled = "cancelled"
+	StatusPending   = "pending" // 有函数调用等待用户确认
+)
+
+// 记录的操作类型
+const (
+	ActionChat     = "chat"     // 一次对话
+	ActionConfirm  = "confirm"  // 用户确认或拒绝挂起的函数调用
+	ActionApproval = "approval" // 管理员批准或拒绝审批请求
+)
+
+// Record 一次对话（或对挂起调用的确认、审批）的审计记录
+type Record struct {
+	ID               uint      `gorm:"primarykey" json:"id"`
+	Action           string    `gorm:"index" json:"action"`                       // 操作类型：chat, confirm, approval
+	RequestID        string    `gorm:"index" json:"request_id"`                   // Chat 请求 ID，确认和审批记录使用触发它的请求 ID
+	SessionID        string    `gorm:"index" json:"session_id"`                   // 所属会话
+	Caller           string    `gorm:"index" json:"caller,omitempty"`             // 经过鉴权的调用方身份，如 api_key:admin、telegram:123456
+	UserID           string    `gorm:"index" json:"user_id,omitempty"`            // 长期记忆归属的用户，内部调用时取自渠道，追溯调用方以 Caller 为准
+	Channel          string    `json:"channel,omitempty"`                         // 渠道类型
+	Input            string    `gorm:"type:text" json:"input"`                    // 用户输入
+	Output           string    `gorm:"type:text" json:"output"`                   // 最终回复
+	FunctionCalls    string    `gorm:"type:text" json:"function_calls,omitempty"` // 函数调用（JSON 数组）
+	PromptTokens     int       `json:"prompt_tokens"`                             // 输入 token 数（各轮累计）
+	CompletionTokens int       `json:"completion_tokens"`                         // 输出 token 数（各轮累计）
+	TotalTokens      int       `json:"total_tokens"`                              // 总 token 数
+	Status           string    `gorm:"not null;index" json:"status"`              // success, error, cancelled, pending
+	Error            string    `gorm:"type:text" json:"error,omitempty"`          // 错误信息
+	DurationMs       int64     `json:"duration_ms"`                               // 总耗时（毫秒）
+	Masked           bool      `json:"masked,omitempty"`                          // 文本字段是否已做 PII 脱敏
+	CreatedAt        time.Time `gorm:"not null;index" json:"created_at"`          // 请求时间
+}
+
+// TableName 指定表名
+func (Record) TableName() string {
+	return "audit_records"
+}
+
+// Mask 对记录中的文本字段做 PII 脱敏
+// 调用方和用户标识保持原样，否则无法按用户查询
+func (r *Record) Mask() {
+	r.Input = MaskPII(r.Input)
+	r.Output = MaskPII(r.Output)
+	r.FunctionCalls = MaskPII(r.FunctionCalls)
+	r.Error = MaskPII(r.Error)
+	r.Masked = true
+}
+
+// Filter 审计记录查询条件，零值字段不参与过滤
+type Filter struct {
+	Action    string
+	Caller    string
+	UserID    string
+	SessionID string
+	RequestID string
+	Status    string
+	Since     time.Time // 起始时间（含）
+	Until     time.Time // 结束时间（不含）
+}
+
+// Repository 审计记录数据访问层
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建 Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// migrations 审计记录表的迁移，schema 变化时在末尾追加新版本
+var migrations = []storage.Migration{
+	{
+		Version: 1,
+		Name:    "create audit_records",
+		Up:      storage.CreateTables(&Record{}),
+		Down:    storage.DropTables(&Record{}),
+	},
+	{
+		Version: 2,
+		Name:    "add action and caller columns to audit_records",
+		Up:      storage.AddColumns(&Record{}, "Action", "Caller"),
+		Down:    storage.DropColumns(&Record{}, "Action", "Caller"),
+	},
+}
+
+// Migrate 按版本执行未应用的迁移
+func (r *Repository) Migrate() error {
+	return storage.NewMigrator(r.db, "audit", migrations...).Migrate()
+}
+
+// Create 创建审计记录
+func (r *Repository) Create(record *Record) error {
+	return r.db.Create(record).Error
+}
+
+// List 按条件列出审计记录，按时间倒序
+func (r *Repository) List(filter Filter, limit, offset int) ([]Record, error) {
+	var records []Record
+	query := r.applyFilter(filter)
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	err := query.Order("id DESC").Find(&records).Error
+	return records, err
+}
+
+// Count 按条件统计审计记录数量
+func (r *Repository) Count(filter Filter) (int64, error) {
+	var count int64
+	err := r.applyFilter(filter).Count(&count).Error
+	return count, err
+}
+
+// Each 按时间正序分批遍历符合条件的记录，用于导出大量记录而不一次性载入内存
+// fn 返回错误时停止遍历并返回该错误
+func (r *Repository) Each(filter Filter, batchSize int, fn func(Record) error) error {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	var batch []Record
+	var fnErr error
+	result := r.applyFilter(filter).Order("id ASC").FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		for _, record := range batch {
+			if fnErr = fn(record); fnErr != nil {
+				return fnErr
+			}
+		}
+		return nil
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	return result.Error
+}
+
+// DeleteBefore 删除指定时间之前的记录，返回删除数量，用于执行保留期
+func (r *Repository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", before).Delete(&Record{})
+	return result.RowsAffected, result.Error
+}
+
+// applyFilter 构建带过滤条件的查询
+func (r *Repository) applyFilter(filter Filter) *gorm.DB {
+	query := r.db.Model(&Record{})
+
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Caller != "" {
+		query = query.Where("caller = ?", filter.Caller)
+	}
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.SessionID != "" {
+		query = query.Where("session_id = ?", filter.SessionID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	return query
+}
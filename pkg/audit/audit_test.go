@@ -0,0 +1,182 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/storage/storagetest"
+)
+
+func TestMaskPII(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"no pii", "hello world", "hello world"},
+		{"email", "mail alice@example.com now", "mail a****@example.com now"},
+		{"phone", "call 13812345678", "call 138****5678"},
+		{"phone with country code", "call +86 13812345678", "call +86 138****5678"},
+		{"id card", "id 11010519491231002X", "id **************002X"},
+		{"valid card", "card 4111111111111111", "card ************1111"},
+		{"invalid card checksum", "order 4111111111111112", "order 4111111111111112"},
+		{"timestamp", "at 1700000000000", "at 1700000000000"},
+		{"several", "a@b.co and 13912345678", "a****@b.co and 139****5678"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskPII(tt.in); got != tt.want {
+				t.Errorf("MaskPII(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   bool
+	}{
+		{"4111111111111111", true},
+		{"5500005555555559", true},
+		{"6222021234567890125", false},
+		{"79927398713", true},
+		{"79927398710", false},
+	}
+	for _, tt := range tests {
+		if got := luhnValid(tt.digits); got != tt.want {
+			t.Errorf("luhnValid(%q) = %v, want %v", tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestRecord_Mask(t *testing.T) {
+	r := &Record{
+		Caller: "api_key:admin",
+		UserID: "alice@example.com",
+		Input:  "my email is alice@example.com",
+		Output: "ok",
+		Error:  "failed for 13812345678",
+	}
+	r.Mask()
+	if !r.Masked || r.Input != "my email is a****@example.com" || r.Error != "failed for 138****5678" {
+		t.Errorf("Mask() = %+v", r)
+	}
+	if r.UserID != "alice@example.com" || r.Caller != "api_key:admin" {
+		t.Errorf("identities should stay queryable: %+v", r)
+	}
+}
+
+func TestRepository(t *testing.T) {
+	repo := storagetest.NewRepository(t, NewRepository)
+
+	now := time.Now()
+	records := []*Record{
+		{Action: ActionChat, RequestID: "r1", SessionID: "s1", Caller: "api_key:web", UserID: "u1", Status: StatusSuccess, CreatedAt: now.Add(-3 * time.Hour)},
+		{Action: ActionConfirm, RequestID: "r1", SessionID: "s1", Caller: "telegram:1", UserID: "u1", Status: StatusSuccess, CreatedAt: now.Add(-2 * time.Hour)},
+		{Action: ActionChat, RequestID: "r2", SessionID: "s2", Caller: "api_key:web", UserID: "u2", Status: StatusError, CreatedAt: now.Add(-time.Hour)},
+		{Action: ActionApproval, RequestID: "r2", SessionID: "s2", Caller: "api_key:admin", UserID: "u2", Status: StatusCancelled, CreatedAt: now},
+	}
+	for _, r := range records {
+		if err := repo.Create(r); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int64
+	}{
+		{"no filter", Filter{}, 4},
+		{"by action", Filter{Action: ActionChat}, 2},
+		{"by caller", Filter{Caller: "api_key:web"}, 2},
+		{"by user", Filter{UserID: "u2"}, 2},
+		{"by session", Filter{SessionID: "s1"}, 2},
+		{"by request", Filter{RequestID: "r2"}, 2},
+		{"by status", Filter{Status: StatusError}, 1},
+		{"by time range", Filter{Since: now.Add(-150 * time.Minute), Until: now.Add(-time.Minute)}, 2},
+		{"combined", Filter{Action: ActionChat, Caller: "api_key:web", UserID: "u1"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := repo.Count(tt.filter)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if count != tt.want {
+				t.Errorf("Count() = %d, want %d", count, tt.want)
+			}
+			list, err := repo.List(tt.filter, 0, 0)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if int64(len(list)) != tt.want {
+				t.Errorf("List() returned %d records, want %d", len(list), tt.want)
+			}
+		})
+	}
+
+	// List 按时间倒序并分页
+	page, err := repo.List(Filter{}, 2, 1)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page) != 2 || page[0].Action != ActionChat || page[0].RequestID != "r2" || page[1].Action != ActionConfirm {
+		t.Errorf("List(limit 2, offset 1) = %+v", page)
+	}
+}
+
+func TestRepository_Each(t *testing.T) {
+	repo := storagetest.NewRepository(t, NewRepository)
+	for i := 0; i < 5; i++ {
+		if err := repo.Create(&Record{Action: ActionChat, Status: StatusSuccess, CreatedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []uint
+	if err := repo.Each(Filter{}, 2, func(r Record) error {
+		ids = append(ids, r.ID)
+		return nil
+	}); err != nil {
+		t.Fatalf("Each() error = %v", err)
+	}
+	if len(ids) != 5 || ids[0] > ids[4] {
+		t.Errorf("Each() visited %v, want 5 records in ascending order", ids)
+	}
+
+	stop := errors.New("stop")
+	visited := 0
+	err := repo.Each(Filter{}, 2, func(r Record) error {
+		visited++
+		if visited == 3 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || visited != 3 {
+		t.Errorf("Each() = %v after %d records, want stop after 3", err, visited)
+	}
+}
+
+func TestRepository_DeleteBefore(t *testing.T) {
+	repo := storagetest.NewRepository(t, NewRepository)
+	now := time.Now()
+	for _, at := range []time.Time{now.Add(-48 * time.Hour), now.Add(-25 * time.Hour), now} {
+		if err := repo.Create(&Record{Action: ActionChat, Status: StatusSuccess, CreatedAt: at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := repo.DeleteBefore(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteBefore() error = %v", err)
+	}
+	if count, _ := repo.Count(Filter{}); deleted != 2 || count != 1 {
+		t.Errorf("DeleteBefore() deleted %d, %d left; want 2 deleted, 1 left", deleted, count)
+	}
+}
@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"regexp"
+	"strings"
+)
+
+// PII 匹配规则，按顺序应用：身份证号要先于银行卡号匹配，否则会被当作卡号
+var (
+	emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	idCardPattern = regexp.MustCompile(`\b\d{17}[\dXx]\b`)
+	cardPattern   = regexp.MustCompile(`\b\d{13,19}\b`)
+	phonePattern  = regexp.MustCompile(`(?:\+?86[\- ]?)?\b1[3-9]\d{9}\b`)
+)
+
+// MaskPII 对文本中的常见个人敏感信息做部分遮盖
+// 邮箱保留首字符和域名，手机号保留前 3 位和后 4 位，身份证号和通过 Luhn 校验的银行卡号只保留后 4 位；
+// 基于正则匹配，只能覆盖格式明确的信息，不保证识别所有 PII
+func MaskPII(text string) string {
+	if text == "" {
+		return text
+	}
+	text = emailPattern.ReplaceAllStringFunc(text, maskEmail)
+	text = idCardPattern.ReplaceAllStringFunc(text, func(s string) string { return keepSuffix(s, 4) })
+	text = cardPattern.ReplaceAllStringFunc(text, maskCard)
+	text = phonePattern.ReplaceAllStringFunc(text, maskPhone)
+	return text
+}
+
+// maskEmail alice@example.com -> a****@example.com
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	return email[:1] + "****" + email[at:]
+}
+
+// maskPhone 13812345678 -> 138****5678，带国家码的保留国家码
+func maskPhone(phone string) string {
+	number := phone[len(phone)-11:]
+	return phone[:len(phone)-11] + number[:3] + "****" + number[7:]
+}
+
+// maskCard 通过 Luhn 校验的银行卡号只保留后 4 位，订单号、时间戳等其他长数字保持原样
+func maskCard(s string) string {
+	if !luhnValid(s) {
+		return s
+	}
+	return keepSuffix(s, 4)
+}
+
+// luhnValid 数字串是否通过 Luhn 校验（银行卡号的校验位算法）
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// keepSuffix 只保留末尾 n 位，其余替换为 *
+func keepSuffix(s string, n int) string {
+	return strings.Repeat("*", len(s)-n) + s[len(s)-n:]
+}
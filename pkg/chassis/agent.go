@@ -3,11 +3,19 @@ package chassis
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/KodaTao/AgentChassis/pkg/approval"
+	"github.com/KodaTao/AgentChassis/pkg/audit"
 	"github.com/KodaTao/AgentChassis/pkg/function"
 	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/memory"
 	"github.com/KodaTao/AgentChassis/pkg/observability"
 	"github.com/KodaTao/AgentChassis/pkg/prompt"
 	"github.com/KodaTao/AgentChassis/pkg/protocol"
@@ -24,13 +32,109 @@ type Agent struct {
 	parser          *protocol.Parser
 	encoder         *protocol.Encoder
 	promptGenerator *prompt.Generator
+	callLogRepo     *function.CallLogRepository // 可选，设置后异步记录函数调用
+	memoryRepo      *memory.Repository          // 可选，设置后把用户记忆提供给 AI
+	auditRepo       *audit.Repository           // 可选，设置后异步写入每次对话的审计记录
+	approvalRepo    *approval.Repository        // 可选，设置后需审批的函数调用会持久化并等待审批
+	llmPool         *llm.Pool                   // 可选，设置后函数内部和后台任务（如会话摘要）按路由选择 Provider
+	logger          *slog.Logger                // 可选，为 nil 时使用全局 logger
+	llmVerbose      *observability.LLMVerboseConfig
+	events          *observability.Emitter // 可选，为 nil 时结构化事件写入包级的 Sink
 	config          *AgentConfig
+
+	writes sync.WaitGroup // 进行中的异步记录写入，关闭数据库前需等待
+
+	activeMu sync.Mutex
+	active   map[string]*activeChat // 会话 ID -> 正在进行的对话，用于中途取消
+
+	heldMu sync.Mutex
+	held   map[string]*heldCall // 待确认调用 ID -> 挂起的调用
+
+	summaryMu      sync.Mutex
+	summaryTimers  map[string]*time.Timer // 会话 ID -> 等待执行的自动摘要
+	summaryStopped bool                   // stopSummaries 之后不再开始新的摘要
+	summaryStop    context.Context        // 关闭时取消，中止进行中的摘要
+	summaryCancel  context.CancelFunc
+	summaries      sync.WaitGroup // 进行中的摘要，关闭数据库前需等待
 }
 
 // AgentConfig Agent 配置
 type AgentConfig struct {
 	MaxIterations int           // 最大迭代次数（防止无限循环）
-	Timeout       time.Duration // 单次执行超时
+	Timeout       time.Duration // 单次对话（含所有轮次的 LLM 调用和函数执行）的超时，0 表示不限制
+	OnEvent       EventHandler  // 可选，执行步骤事件回调
+
+	// ParallelCalls 同一轮中的多个函数调用并行执行（结果仍按调用顺序反馈给 AI）
+	ParallelCalls bool
+
+	// Persona 助手的人设描述，会加入系统提示词
+	Persona string
+
+	// ChannelPrompts 按渠道类型（ChannelContext.Type）附加到系统提示词的指令，
+	// 未指定渠道的请求使用 APIChannelType 对应的指令
+	ChannelPrompts map[string]string
+
+	// MaxUnknownCalls 连续调用同一个不存在函数的次数上限，达到后提前结束对话循环
+	MaxUnknownCalls int
+
+	// CallMode 函数调用方式：CallModeXML（默认）或 CallModeTools
+	CallMode string
+
+	// PromptVars 注入到系统提示词的自定义变量（如用户名、地点）
+	PromptVars map[string]any
+
+	// DedupCalls 同一轮内完全相同（name+params+data）的调用只执行一次，结果复用给重复项
+	DedupCalls bool
+
+	// MaxHistoryTokens 会话历史的估算 token 上限，在按条数截断之外再按 token 截断，0 表示不限制
+	MaxHistoryTokens int
+
+	// ConfirmFunctions 需要用户确认后才执行的函数，仅对声明了 ConfirmCalls 的请求生效
+	ConfirmFunctions []string
+
+	// ApprovalFunctions 需要管理员审批后才执行的函数，对所有请求生效
+	// 函数也可以通过 function.ApprovalFunction 声明自己需要审批
+	ApprovalFunctions []string
+
+	// RateLimits 按函数名限制每分钟最多调用次数，覆盖函数通过 RateLimitedFunction 声明的限制
+	RateLimits map[string]int
+
+	// Examples few-shot 示例，每次请求时插入在系统提示之后（不写入会话历史）
+	Examples []Example
+
+	// EmptyReplyRetries AI 返回空回复（或只有空白字符）时的重试次数，仍为空时回复 EmptyReplyMessage，0 或负数表示不重试
+	EmptyReplyRetries int
+
+	// MaxFunctionCalls 单次请求中最多实际执行的函数调用数，独立于 MaxIterations 的安全闸，0 表示不限制
+	// 达到上限后不再执行新的调用，直接结束对话并在回复中说明
+	MaxFunctionCalls int
+
+	// ShowThoughts 收集每轮 AI 在函数调用前的说明文字，放入 ChatResponse.Thoughts 并触发 EventThought 事件
+	// 默认关闭，保持响应简洁
+	ShowThoughts bool
+
+	// MaxResultChars 单个函数结果反馈给 AI 时的字符数上限，超出时保留头尾、截断中间，0 或负数表示不限制
+	MaxResultChars int
+
+	// SummarizeResults 超长结果先调用 LLM 摘要，摘要失败时再截断
+	SummarizeResults bool
+
+	// SlimResults 新一轮对话开始时，把会话中之前轮次的函数结果精简为只保留 message/error，去掉 data 和 markdown 原文
+	SlimResults bool
+
+	// AuditMaskPII 写入审计记录前对输入、输出和函数调用中的邮箱、手机号、证件号等做部分遮盖
+	AuditMaskPII bool
+
+	// TransientRetries 函数临时失败（超时、网络错误、上游 5xx）时自动重试的次数，0 表示不重试
+	// 只有声明了幂等（function.IdempotentFunction）的函数会在这些情况下重试，其余函数只在连接被拒绝时重试
+	// 重试后仍失败才把错误反馈给 AI
+	TransientRetries int
+
+	// Language 请求未指定语言时系统提示词使用的语言（如 en、zh、ja），为空或没有对应模板时使用英文
+	Language string
+
+	// Summary 会话自动摘要，消息超过阈值时在后台把最旧的一批压缩为一条摘要
+	Summary SummaryConfig
 }
 
 // DefaultAgentConfig 返回默认 Agent 配置
@@ -38,6 +142,15 @@ func DefaultAgentConfig() *AgentConfig {
 	return &AgentConfig{
 		MaxIterations: 10,
 		Timeout:       5 * time.Minute,
+
+		MaxUnknownCalls:   3,
+		CallMode:          CallModeXML,
+		DedupCalls:        true,
+		EmptyReplyRetries: 1,
+		MaxResultChars:    DefaultMaxResultChars,
+		TransientRetries:  1,
+
+		ConfirmFunctions: append([]string(nil), DefaultConfirmFunctions...),
 	}
 }
 
@@ -46,24 +159,41 @@ func NewAgent(provider llm.Provider, registry *function.Registry, config *AgentC
 	if config == nil {
 		config = DefaultAgentConfig()
 	}
+	promptGenerator := prompt.NewGenerator()
+	promptGenerator.SetExtra(config.PromptVars)
+	promptGenerator.SetPersona(config.Persona)
+	promptGenerator.SetChannelPrompts(config.ChannelPrompts)
+	executor := function.NewExecutor(registry, 30*time.Second)
+	for name, perMinute := range config.RateLimits {
+		executor.SetRateLimit(name, perMinute)
+	}
+	executor.SetApprovalFunctions(config.ApprovalFunctions)
 	return &Agent{
 		provider:        provider,
 		registry:        registry,
-		executor:        function.NewExecutor(registry, 30*time.Second),
+		executor:        executor,
 		sessionManager:  NewSessionManager(nil),
 		parser:          protocol.NewParser(),
 		encoder:         protocol.NewEncoder(),
-		promptGenerator: prompt.NewGenerator(),
+		promptGenerator: promptGenerator,
 		config:          config,
+		active:          make(map[string]*activeChat),
+		held:            make(map[string]*heldCall),
 	}
 }
 
+// SetPromptVars 设置注入到系统提示词的自定义变量，对之后新建的会话生效
+func (a *Agent) SetPromptVars(vars map[string]any) {
+	a.promptGenerator.SetExtra(vars)
+}
+
 // 类型别名，保持向后兼容
 type (
 	ChannelContext = types.ChannelContext
 	ChatRequest    = types.ChatRequest
 	ChatResponse   = types.ChatResponse
 	FunctionCall   = types.FunctionCall
+	HistoryMessage = types.HistoryMessage
 )
 
 // 确保 Agent 实现了 types.Agent 接口
@@ -77,6 +207,44 @@ var _ types.Agent = (*Agent)(nil)
 // 4. 解析并执行 Function 调用
 // 5. 循环直到 LLM 给出最终回复
 func (a *Agent) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	start := time.Now()
+
+	// 每次请求一个 request_id，本次触发的所有函数调用、日志和调用记录都关联到它
+	// 调用方指定的 ID 不合法（过长或含特殊字符）时重新生成
+	requestID := GetRequestID(ctx)
+	if !ValidRequestID(requestID) {
+		requestID = newRequestID()
+		ctx = WithRequestID(ctx, requestID)
+	}
+	ctx = observability.WithLogFields(a.logContext(ctx), "request_id", requestID)
+
+	// 开启审计时累计本次请求各轮 LLM 调用的 token 用量
+	var usage *llm.UsageRecorder
+	if a.auditRepo != nil {
+		usage = &llm.UsageRecorder{}
+		ctx = llm.WithUsageRecorder(ctx, usage)
+	}
+
+	resp, err := a.chat(ctx, req)
+	if resp != nil {
+		resp.RequestID = requestID
+		a.scheduleSummary(resp.SessionID)
+	}
+	a.emitChatEvent(requestID, req, resp, err, time.Since(start))
+	if usage != nil {
+		a.recordAudit(ctx, requestID, req, resp, err, usage.Usage(), start)
+	}
+	return resp, err
+}
+
+// chat Chat 的实现
+func (a *Agent) chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	// 本次请求的模型参数覆盖
+	opts := chatOptions(req)
+	if err := opts.ValidateFor(a.config.CallMode); err != nil {
+		return nil, fmt.Errorf("invalid chat options: %w", err)
+	}
+
 	// 生成或使用提供的 session ID
 	sessionID := req.SessionID
 	if sessionID == "" {
@@ -86,125 +254,638 @@ func (a *Agent) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error
 	// 添加 session ID 到 context
 	ctx = WithSessionID(ctx, sessionID)
 
-	// 获取或创建会话
-	session := a.sessionManager.GetOrCreate(sessionID)
+	// 添加渠道上下文到 context，函数据此获取默认通知渠道
+	if req.Channel != nil {
+		ctx = types.WithChannel(ctx, req.Channel)
+	}
+
+	// 添加用户标识到 context，记忆函数据此读写当前用户的记忆
+	userID := requestUserID(req)
+	if userID != "" {
+		ctx = memory.WithUserID(ctx, userID)
+	}
+
+	if a.config.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, a.config.Timeout)
+		defer cancelTimeout()
+	}
 
-	// 如果是新会话，添加系统提示
-	if len(session.Messages) == 0 {
-		systemPrompt, err := a.promptGenerator.GenerateSystemPrompt(a.registry.ListInfo())
+	// 登记进行中的对话，CancelSession 通过取消 ctx 终止 LLM 请求和函数执行
+	ctx, done := a.beginChat(ctx, sessionID)
+	defer done()
+
+	// 获取或创建会话，对话结束时（注销进行中的对话之前）写入持久化存储
+	session := a.sessionManager.GetOrCreate(sessionID, types.CallerFromContext(ctx))
+	defer a.sessionManager.Save(session)
+	newSession := len(session.Messages) == 0
+
+	// 有函数调用等待审批时会话暂停，避免 AI 在审批结果出来之前继续操作
+	if pending := a.pendingApproval(session); pending != "" {
+		return nil, fmt.Errorf("%w: approval request %s", ErrSessionAwaitingApproval, pending)
+	}
+
+	// 新会话或函数注册表有变化时，（重新）生成系统提示（只包含调用者有权调用的函数）
+	// 会话换了渠道继续时也要重新生成，使用新渠道的指令
+	version := a.registry.Version()
+	channel := promptChannel(req.Channel)
+	language := a.promptLanguage(req)
+	if len(session.Messages) == 0 || session.PromptVersion != version || session.PromptChannel != channel || session.PromptLanguage != language {
+		systemPrompt, err := a.generateSystemPrompt(ctx, channel, language)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate system prompt: %w", err)
 		}
-		session.AddMessage(llm.RoleSystem, systemPrompt)
+		if len(session.Messages) > 0 {
+			observability.InfoContext(ctx, "Refreshing system prompt",
+				"from_version", session.PromptVersion,
+				"to_version", version,
+			)
+		}
+		session.SetSystemPrompt(systemPrompt)
+		session.PromptVersion = version
+		session.PromptChannel = channel
+		session.PromptLanguage = language
 	}
 
-	// 添加用户消息
-	session.AddMessage(llm.RoleUser, req.Message)
+	// 新会话先写入调用方自带的历史消息
+	if newSession {
+		for _, msg := range req.History {
+			session.AddMessage(llm.Role(msg.Role), msg.Content)
+		}
+	}
+
+	// 之前轮次的图片已被模型看过，替换为文字标记后再添加本次的用户消息，附带的图片作为多模态输入
+	session.dropImages()
+	if a.config.SlimResults {
+		session.slimResults(a.parser, a.encoder)
+	}
+	userMessage := llm.Message{Role: llm.RoleUser, Content: req.Message}
+	for _, img := range req.Images {
+		userMessage.Images = append(userMessage.Images, llm.Image{MimeType: img.MimeType, Data: img.Content})
+	}
+	session.AppendMessage(userMessage)
 
 	// 执行对话循环
 	var functionCalls []FunctionCall
 	var finalReply string
+	var unknown unknownCallTracker
+	var cancelled bool
+	var pending *PendingCall
+	var pendingReply string
+	var awaiting *PendingCall // 等待管理员审批的调用
+	var callCount int         // 本次请求已实际执行的函数调用数
+	var thoughts []string
+	tools := a.toolDefinitions(ctx)
+	promptSection := joinPromptSections(a.memoryPrompt(ctx, userID), a.confirmPrompt(req))
+	examples := a.exampleMessages(ctx)
 
 	for i := 0; i < a.config.MaxIterations; i++ {
 		// 调用 LLM
 		observability.InfoContext(ctx, "Calling LLM", "iteration", i+1)
 
-		reply, err := a.provider.Chat(ctx, session.GetMessages())
+		a.emit(ctx, Event{Type: EventLLMStart, Iteration: i + 1})
+		llmStart := time.Now()
+
+		// 记忆、确认说明和 few-shot 示例只拼进本次请求，不写入会话历史
+		messages := withExamples(withMemoryPrompt(session.GetMessages(), promptSection), examples)
+		reply, err := a.callLLMRetryEmpty(ctx, messages, tools, opts)
+
+		llmEnd := Event{
+			Type:       EventLLMEnd,
+			Iteration:  i + 1,
+			Status:     "success",
+			DurationMs: time.Since(llmStart).Milliseconds(),
+		}
 		if err != nil {
-			return nil, fmt.Errorf("LLM call failed: %w", err)
+			llmEnd.Status = "error"
+			llmEnd.Error = err.Error()
 		}
+		a.emit(ctx, llmEnd)
 
-		// 添加 AI 回复到会话
-		session.AddMessage(llm.RoleAssistant, reply)
+		if err != nil {
+			if chatCancelled(ctx) {
+				cancelled = true
+				break
+			}
+			return nil, fmt.Errorf("LLM call failed: %w", err)
+		}
 
-		// 检查是否包含函数调用
-		if !a.parser.HasCall(reply) {
-			// 没有函数调用，这是最终回复
-			finalReply = reply
+		// 重试后仍是空回复：不把空消息写入会话，改为回复友好提示
+		if isEmptyReply(reply) {
+			observability.WarnContext(ctx, "LLM returned an empty reply after retries", "iteration", i+1)
+			session.AddMessage(llm.RoleAssistant, EmptyReplyMessage)
+			finalReply = EmptyReplyMessage
 			break
 		}
 
-		// 解析函数调用
-		calls, err := a.parser.ParseCalls(reply)
+		// 添加 AI 回复到会话
+		session.AppendMessage(reply)
+
+		// 解析函数调用，没有函数调用时这是最终回复
+		calls, err := a.extractCalls(reply)
 		if err != nil {
 			observability.WarnContext(ctx, "Failed to parse function calls", "error", err)
-			finalReply = reply
+			finalReply = reply.Content
+			break
+		}
+		if len(calls) == 0 {
+			finalReply = reply.Content
 			break
 		}
+		a.correctCallNames(ctx, calls)
+
+		// 调用前的说明文字是 AI 本轮的"思考过程"
+		if a.config.ShowThoughts {
+			if thought := strings.TrimSpace(a.parser.ExtractTextBeforeCall(reply.Content)); thought != "" {
+				thoughts = append(thoughts, thought)
+				a.emit(ctx, Event{Type: EventThought, Iteration: i + 1, Content: thought})
+			}
+		}
 
 		// 执行每个函数调用
-		var results []string
-		for _, call := range calls {
-			observability.InfoContext(ctx, "Executing function", "name", call.Name)
+		results := make([]string, 0, len(calls))
+		executed := make(map[string]dedupedCall) // 本轮已执行的调用，用于去重
+		var prefetched map[int]function.ExecuteResponse
+		if a.config.ParallelCalls {
+			prefetched = a.prefetchCalls(ctx, req, calls, i+1, a.remainingCalls(callCount))
+		}
+		for idx, call := range calls {
+			// 已取消时不再执行剩余的函数（tools 模式下由 appendResults 补上 skipped 结果）
+			if chatCancelled(ctx) {
+				cancelled = true
+				break
+			}
 
-			// 执行函数
-			execResp := a.executor.Execute(ctx, function.ExecuteRequest{
-				FunctionName: call.Name,
-				Params:       call.Params,
-				Data:         call.Data,
-			})
+			trace := newCallTrace(ctx, i+1, idx+1)
+			observability.InfoContext(ctx, "Executing function", "name", call.Name, "call_id", trace.CallID)
 
 			// 记录调用结果
 			fc := FunctionCall{
 				Name:   call.Name,
+				CallID: trace.CallID,
 				Status: "success",
 			}
 
 			var resultStr string
+			if call.parseErr != nil {
+				// 原生 tools 模式下参数不是合法 JSON，不执行，直接反馈给 AI
+				fc.Status = "error"
+				fc.Result = call.parseErr.Error()
+				resultStr = a.encoder.EncodeError(call.Name, call.parseErr.Error())
+				functionCalls = append(functionCalls, fc)
+				results = append(results, resultStr)
+				continue
+			}
+
+			// 需要管理员审批的调用持久化为审批请求并暂停会话，本轮剩余的调用也不再执行
+			if a.needsApproval(call.Name) {
+				var err error
+				awaiting, err = a.requestApproval(ctx, session, userID, req.Channel, trace, call.CallRequest)
+				if err != nil {
+					observability.ErrorContext(ctx, "Failed to create approval request", "name", call.Name, "error", err)
+					fc.Status = "error"
+					fc.Result = "failed to request approval: " + err.Error()
+					functionCalls = append(functionCalls, fc)
+					results = append(results, a.encoder.EncodeError(call.Name, fc.Result))
+					continue
+				}
+				pendingReply = reply.Content
+				functionCalls = append(functionCalls, FunctionCall{Name: call.Name, CallID: trace.CallID, Status: string(protocol.StatusPending)})
+				resultStr, _ = a.encoder.EncodeResult(&protocol.CallResult{
+					Name:    call.Name,
+					Status:  protocol.StatusPending,
+					Message: approvalPendingMessage,
+				})
+				results = append(results, resultStr)
+				break
+			}
+
+			// 需要用户确认的调用先挂起，本轮剩余的调用也不再执行
+			if a.needsConfirmation(req, call.Name) {
+				pending = a.holdCall(sessionID, userID, req.Channel, trace, call.CallRequest)
+				pendingReply = reply.Content
+				functionCalls = append(functionCalls, FunctionCall{Name: call.Name, CallID: trace.CallID, Status: string(protocol.StatusPending)})
+				resultStr, _ = a.encoder.EncodeResult(&protocol.CallResult{
+					Name:    call.Name,
+					Status:  protocol.StatusPending,
+					Message: pendingCallMessage,
+				})
+				results = append(results, resultStr)
+				break
+			}
+
+			// AI 重复输出了完全相同的调用，复用第一次的结果，避免重复的副作用
+			key := callKey(call.CallRequest)
+			if prev, ok := executed[key]; ok && a.config.DedupCalls {
+				observability.WarnContext(ctx, "Skipping duplicate function call", "name", call.Name)
+				functionCalls = append(functionCalls, prev.fc)
+				results = append(results, prev.result)
+				continue
+			}
+
+			// 达到本次请求的函数调用上限，不再执行新的调用
+			if a.remainingCalls(callCount) == 0 {
+				observability.WarnContext(ctx, "Aborting chat loop: function call limit reached",
+					"name", call.Name,
+					"limit", a.config.MaxFunctionCalls,
+				)
+				functionCalls = append(functionCalls, FunctionCall{Name: call.Name, CallID: trace.CallID, Status: "error", Result: callLimitMessage})
+				results = append(results, a.encoder.EncodeError(call.Name, callLimitMessage))
+				finalReply = fmt.Sprintf("已停止：本次请求的函数调用次数已达上限（%d 次）。", a.config.MaxFunctionCalls)
+				break
+			}
+			callCount++
+
+			// 执行函数
+			execReq := function.ExecuteRequest{
+				FunctionName: call.Name,
+				Params:       call.Params,
+				Data:         call.Data,
+			}
+			execResp, ok := prefetched[idx]
+			if !ok {
+				a.emit(ctx, Event{Type: EventFunctionStart, Iteration: i + 1, FunctionName: call.Name, CallID: trace.CallID})
+				execResp = a.executor.Execute(trace.context(ctx), execReq)
+			}
+			execResp, retried := a.retryTransient(trace.context(ctx), execReq, execResp)
+			a.recordCall(sessionID, trace, execReq, execResp)
+
+			fnEnd := Event{
+				Type:         EventFunctionEnd,
+				Iteration:    i + 1,
+				FunctionName: call.Name,
+				CallID:       trace.CallID,
+				Status:       "success",
+				DurationMs:   execResp.Duration.Milliseconds(),
+			}
 			if execResp.Error != nil {
+				fnEnd.Status = "error"
+				fnEnd.Error = execResp.Error.Error()
+			}
+			a.emit(ctx, fnEnd)
+
+			if errors.Is(execResp.Error, function.ErrFunctionNotFound) {
+				// 附上可用函数列表，引导 AI 改用正确的函数名
+				unknown.record(call.Name)
+				fc.Status = "error"
+				fc.Result = execResp.Error.Error()
+				resultStr = a.encoder.EncodeError(call.Name, a.unknownFunctionHint(ctx, call.Name, execResp.Error))
+			} else if execResp.Error != nil {
+				unknown.reset()
 				fc.Status = "error"
 				fc.Result = execResp.Error.Error()
-				resultStr = a.encoder.EncodeError(call.Name, execResp.Error.Error())
+				resultStr = a.encodeFailure(call.Name, execResp.Error, retried)
 			} else {
+				unknown.reset()
 				fc.Result = execResp.Result.Message
+				fc.Markdown = a.displayMarkdown(execResp.Result)
+				var attachmentInfos []protocol.AttachmentInfo
+				fc.Attachments, attachmentInfos = resultAttachments(execResp.Result)
 				result := &protocol.CallResult{
-					Name:     call.Name,
-					Status:   protocol.StatusSuccess,
-					Message:  execResp.Result.Message,
-					Data:     execResp.Result.Data,
-					Markdown: execResp.Result.Markdown,
+					Name:        call.Name,
+					Status:      protocol.StatusSuccess,
+					Message:     execResp.Result.Message,
+					Data:        execResp.Result.Data,
+					Markdown:    execResp.Result.Markdown,
+					Attachments: attachmentInfos,
 				}
 				resultStr, _ = a.encoder.EncodeResult(result)
+				resultStr = a.limitResult(ctx, call.Name, resultStr)
 			}
 
 			functionCalls = append(functionCalls, fc)
 			results = append(results, resultStr)
+			executed[key] = dedupedCall{fc: fc, result: resultStr}
+
+			// 连续调用同一个不存在的函数，说明 AI 陷入循环，提前结束
+			if a.config.MaxUnknownCalls > 0 && unknown.count >= a.config.MaxUnknownCalls {
+				observability.WarnContext(ctx, "Aborting chat loop: repeated calls to unknown function",
+					"name", call.Name,
+					"attempts", unknown.count,
+				)
+				finalReply = fmt.Sprintf("已停止：AI 多次尝试调用不存在的函数 %q。", call.Name)
+				break
+			}
 		}
 
-		// 将函数结果添加到会话（作为用户消息，因为这是给 AI 看的）
-		combinedResults := ""
-		for _, r := range results {
-			combinedResults += r + "\n"
+		// 将函数结果添加到会话
+		a.appendResults(session, calls, results)
+
+		if finalReply != "" || pending != nil || awaiting != nil || cancelled || chatCancelled(ctx) {
+			cancelled = cancelled || chatCancelled(ctx)
+			break
 		}
-		session.AddMessage(llm.RoleUser, combinedResults)
+	}
+
+	if cancelled {
+		a.truncateHistory(session)
+		return &ChatResponse{
+			SessionID:     sessionID,
+			Reply:         cancelledReply,
+			FunctionCalls: functionCalls,
+			Thoughts:      thoughts,
+			Cancelled:     true,
+		}, nil
+	}
+
+	if awaiting != nil {
+		a.truncateHistory(session)
+		reply := a.parser.StripCalls(pendingReply)
+		if reply == "" {
+			reply = fmt.Sprintf("调用 %s 需要管理员审批，审批通过后会自动执行。", awaiting.Name)
+		}
+		return &ChatResponse{
+			SessionID:     sessionID,
+			Reply:         reply,
+			FunctionCalls: functionCalls,
+			Thoughts:      thoughts,
+			Approval:      awaiting,
+		}, nil
+	}
+
+	if pending != nil {
+		a.truncateHistory(session)
+		reply := a.parser.StripCalls(pendingReply)
+		if reply == "" {
+			reply = fmt.Sprintf("请确认是否执行 %s。", pending.Name)
+		}
+		return &ChatResponse{
+			SessionID:     sessionID,
+			Reply:         reply,
+			FunctionCalls: functionCalls,
+			Thoughts:      thoughts,
+			Pending:       pending,
+		}, nil
 	}
 
 	// 提取 AI 回复中的纯文本部分（去掉函数调用）
 	if finalReply == "" {
-		finalReply = "I've completed the requested operations."
-	} else {
-		// 如果回复中包含函数调用，提取调用前后的文本
-		textBefore := a.parser.ExtractTextBeforeCall(finalReply)
-		textAfter := a.parser.ExtractTextAfterCall(finalReply)
-		if textBefore != "" || textAfter != "" {
-			finalReply = textBefore + " " + textAfter
-		}
+		finalReply = "已完成请求的操作。"
+	} else if text := a.parser.StripCalls(finalReply); text != "" {
+		// 去掉回复中所有函数调用块，保留调用之间的说明文字
+		finalReply = text
 	}
 
 	// 截断会话历史（防止 token 超限）
-	session.Truncate(a.sessionManager.config.MaxHistory)
+	a.truncateHistory(session)
 
 	return &ChatResponse{
 		SessionID:     sessionID,
 		Reply:         finalReply,
 		FunctionCalls: functionCalls,
+		Thoughts:      thoughts,
 	}, nil
 }
 
+// callLimitMessage 达到函数调用上限时反馈给 AI 的说明
+const callLimitMessage = "not executed: the function call limit for this request has been reached"
+
+// remainingCalls 本次请求还能执行的函数调用数，-1 表示不限制
+func (a *Agent) remainingCalls(count int) int {
+	if a.config.MaxFunctionCalls <= 0 {
+		return -1
+	}
+	if count >= a.config.MaxFunctionCalls {
+		return 0
+	}
+	return a.config.MaxFunctionCalls - count
+}
+
+// displayMarkdown 返回给终端用户展示的 Markdown
+// 函数自带 Markdown 时直接使用，否则在开启 AutoMarkdown 时根据 Data 生成表格
+func (a *Agent) displayMarkdown(result function.Result) string {
+	if result.Markdown != "" || !result.AutoMarkdown {
+		return result.Markdown
+	}
+	return a.encoder.EncodeMarkdownTable(result.Data)
+}
+
+// resultAttachments 拆分函数返回的附件：完整文件交给渠道发给用户，元信息反馈给 AI
+func resultAttachments(result function.Result) ([]types.Attachment, []protocol.AttachmentInfo) {
+	if len(result.Attachments) == 0 {
+		return nil, nil
+	}
+
+	files := make([]types.Attachment, 0, len(result.Attachments))
+	infos := make([]protocol.AttachmentInfo, 0, len(result.Attachments))
+	for _, att := range result.Attachments {
+		files = append(files, types.Attachment{
+			Filename: att.Filename,
+			MimeType: att.MimeType,
+			Content:  att.Content,
+		})
+		infos = append(infos, protocol.AttachmentInfo{
+			Filename: att.Filename,
+			MimeType: att.MimeType,
+			Size:     len(att.Content),
+		})
+	}
+	return files, infos
+}
+
+// unknownCallTracker 记录连续调用同一个不存在函数的次数
+type unknownCallTracker struct {
+	name  string
+	count int
+}
+
+// record 记录一次对不存在函数的调用
+func (t *unknownCallTracker) record(name string) {
+	if t.name != name {
+		t.name = name
+		t.count = 0
+	}
+	t.count++
+}
+
+// reset 调用了已存在的函数，清空计数
+func (t *unknownCallTracker) reset() {
+	t.name = ""
+	t.count = 0
+}
+
+// truncateHistory 截断会话历史：先按条数，再按估算的 token 预算
+func (a *Agent) truncateHistory(session *Session) {
+	session.Truncate(a.sessionManager.config.MaxHistory)
+	session.TruncateByTokens(a.config.MaxHistoryTokens)
+}
+
+// dedupedCall 本轮已执行调用的结果，供完全相同的重复调用复用
+type dedupedCall struct {
+	fc     FunctionCall
+	result string
+}
+
+// callKey 生成调用的去重键，由函数名、参数（按 key 排序）和 data 组成
+func callKey(call *protocol.CallRequest) string {
+	keys := make([]string, 0, len(call.Params))
+	for k := range call.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(call.Name)
+	for _, k := range keys {
+		// 使用 %q 转义，避免不同参数拼接后产生相同的键
+		fmt.Fprintf(&b, "\x00%q=%q", k, call.Params[k])
+	}
+	fmt.Fprintf(&b, "\x00%q", call.Data)
+	return b.String()
+}
+
+// unknownFunctionHint 生成函数不存在时反馈给 AI 的错误信息，包含最接近的函数名建议和可用函数列表
+func (a *Agent) unknownFunctionHint(ctx context.Context, name string, err error) string {
+	infos := a.registry.ListInfoForContext(ctx)
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return err.Error() + ". No functions are available; answer the user directly."
+	}
+	if suggestions := function.SuggestNames(name, names); len(suggestions) > 0 {
+		return fmt.Sprintf("%s. Did you mean %s? Available functions: %s. Use one of these exact names or answer directly.",
+			err.Error(), strings.Join(suggestions, " or "), strings.Join(names, ", "))
+	}
+	return fmt.Sprintf("%s. Available functions: %s. Use one of these exact names or answer directly.",
+		err.Error(), strings.Join(names, ", "))
+}
+
+// correctCallNames 纠正写法有偏差的函数名（如 sendMessage -> send_message）
+// 在确认、去重和执行之前纠正，保证后续流程看到的都是真实函数名
+func (a *Agent) correctCallNames(ctx context.Context, calls []pendingCall) {
+	for _, call := range calls {
+		if corrected, ok := a.registry.Correct(call.Name); ok {
+			observability.WarnContext(ctx, "Function name corrected", "requested", call.Name, "corrected", corrected)
+			call.Name = corrected
+		}
+	}
+}
+
+// chatOptions 从对话请求中提取模型参数覆盖
+func chatOptions(req ChatRequest) llm.ChatOptions {
+	return llm.ChatOptions{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+
+		ResponseFormat: req.ResponseFormat,
+	}
+}
+
+// SetTaskManager 设置异步函数的任务管理器，与内置的 check_task_status 函数共享同一个实例
+func (a *Agent) SetTaskManager(tasks *function.TaskManager) {
+	a.executor.SetTaskManager(tasks)
+}
+
+// SetLogger 设置 Agent 的 logger，对话、函数调用和 LLM 请求的日志都写入该 logger
+// verbose 为 LLM 完整内容日志配置，为 nil 时使用全局配置
+func (a *Agent) SetLogger(logger *slog.Logger, verbose *observability.LLMVerboseConfig) {
+	a.logger = logger
+	a.llmVerbose = verbose
+	a.executor.SetLogger(logger)
+	a.sessionManager.SetLogger(logger)
+}
+
+// SetEmitter 设置结构化事件的输出，对话、函数调用、审批以及 LLM 断路器和缓存的事件都写入该 Emitter
+func (a *Agent) SetEmitter(events *observability.Emitter) {
+	a.events = events
+}
+
+// log 返回 Agent 的 logger
+func (a *Agent) log() *slog.Logger {
+	if a.logger != nil {
+		return a.logger
+	}
+	return observability.DefaultLogger()
+}
+
+// logContext 在 ctx 中附加 Agent 的 logger、事件输出和 LLM 完整内容日志配置
+func (a *Agent) logContext(ctx context.Context) context.Context {
+	ctx = observability.WithLogger(ctx, a.logger)
+	ctx = observability.WithEmitter(ctx, a.events)
+	if a.llmVerbose != nil {
+		ctx = observability.WithLLMVerbose(ctx, *a.llmVerbose)
+	}
+	return ctx
+}
+
+// SetProviderPool 设置 LLM Provider 池，函数内部通过 LLMFromContext 获取，会话摘要使用 session_summary 路由
+func (a *Agent) SetProviderPool(pool *llm.Pool) {
+	a.llmPool = pool
+	a.executor.SetProviderPool(pool)
+}
+
+// SetCallLogRepository 设置函数调用记录仓库
+func (a *Agent) SetCallLogRepository(repo *function.CallLogRepository) {
+	a.callLogRepo = repo
+}
+
+// recordCall 异步记录函数调用，不阻塞对话
+func (a *Agent) recordCall(sessionID string, trace callTrace, req function.ExecuteRequest, resp function.ExecuteResponse) {
+	a.emitFunctionCallEvent(sessionID, trace, req, resp)
+
+	if a.callLogRepo == nil {
+		return
+	}
+
+	log := function.NewCallLog(sessionID, req, resp)
+	trace.apply(log)
+	a.writes.Add(1)
+	go func() {
+		defer a.writes.Done()
+		if err := a.callLogRepo.Create(log); err != nil {
+			a.log().Warn("Failed to record function call", "name", log.FunctionName, "error", err)
+		}
+	}()
+}
+
+// WaitWrites 等待所有异步写入的记录落库，关闭数据库前调用
+func (a *Agent) WaitWrites() {
+	a.writes.Wait()
+}
+
 // ChatStream 流式对话（返回 channel）
+// 思考过程和函数调用的开始/结束在执行过程中实时发送，夹在回复内容之前，前端可据此展示"正在调用 X..."
+// ctx 取消后不再发送，消费方不必读完 channel
 func (a *Agent) ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamResponse, error) {
 	ch := make(chan StreamResponse, 100)
 
+	// send 在消费方停止读取（ctx 取消）时放弃发送，返回 false，避免 goroutine 永久阻塞
+	send := func(r StreamResponse) bool {
+		select {
+		case ch <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// 保留调用方设置的请求级事件回调
+	prev, _ := ctx.Value(eventHandlerKey).(EventHandler)
+	ctx = WithEventHandler(ctx, func(event Event) {
+		if prev != nil {
+			prev(event)
+		}
+		switch event.Type {
+		case EventThought:
+			send(StreamResponse{Type: StreamThought, Thought: event.Content})
+		case EventFunctionStart:
+			send(StreamResponse{Type: StreamFunctionCallStart, Name: event.FunctionName, CallID: event.CallID})
+		case EventFunctionEnd:
+			send(StreamResponse{
+				Type:       StreamFunctionCallEnd,
+				Name:       event.FunctionName,
+				CallID:     event.CallID,
+				Status:     event.Status,
+				DurationMs: event.DurationMs,
+			})
+		}
+	})
+
 	go func() {
 		defer close(ch)
 
@@ -212,32 +893,55 @@ func (a *Agent) ChatStream(ctx context.Context, req ChatRequest) (<-chan StreamR
 		// 后续可以改为真正的流式实现
 		resp, err := a.Chat(ctx, req)
 		if err != nil {
-			ch <- StreamResponse{Error: err, Done: true}
+			send(StreamResponse{Type: StreamError, Error: err, Done: true})
 			return
 		}
 
 		// 逐字符发送回复（模拟流式）
 		for _, char := range resp.Reply {
-			ch <- StreamResponse{Content: string(char)}
+			if !send(StreamResponse{Type: StreamContent, Content: string(char)}) {
+				return
+			}
 		}
 
-		ch <- StreamResponse{
+		send(StreamResponse{
+			Type:          StreamDone,
 			SessionID:     resp.SessionID,
 			FunctionCalls: resp.FunctionCalls,
 			Done:          true,
-		}
+		})
 	}()
 
 	return ch, nil
 }
 
+// StreamType 流式响应片段的类型
+type StreamType string
+
+const (
+	StreamContent           StreamType = "content"             // 回复内容片段
+	StreamThought           StreamType = "thought"             // 某一轮的思考过程（需开启 ShowThoughts）
+	StreamFunctionCallStart StreamType = "function_call_start" // 开始执行函数
+	StreamFunctionCallEnd   StreamType = "function_call_end"   // 函数执行结束
+	StreamDone              StreamType = "done"                // 对话完成，附带会话 ID 和函数调用记录
+	StreamError             StreamType = "error"               // 对话失败
+)
+
 // StreamResponse 流式响应
 type StreamResponse struct {
+	Type          StreamType     `json:"type"`
 	SessionID     string         `json:"session_id,omitempty"`
 	Content       string         `json:"content,omitempty"`
+	Thought       string         `json:"thought,omitempty"` // 某一轮的思考过程，先于回复内容发送
 	FunctionCalls []FunctionCall `json:"function_calls,omitempty"`
 	Error         error          `json:"error,omitempty"`
 	Done          bool           `json:"done"`
+
+	// 函数调用事件的字段
+	Name       string `json:"name,omitempty"`        // 函数名
+	CallID     string `json:"call_id,omitempty"`     // 调用 ID
+	Status     string `json:"status,omitempty"`      // 结束事件的状态：success, error
+	DurationMs int64  `json:"duration_ms,omitempty"` // 结束事件的耗时（毫秒）
 }
 
 // GetSession 获取会话
@@ -255,11 +959,26 @@ func (a *Agent) ListSessions() []string {
 	return a.sessionManager.List()
 }
 
+// ListSessionsOwnedBy 列出属于 owner（types.CallerFromContext 得到的调用方）的会话 ID
+func (a *Agent) ListSessionsOwnedBy(owner string) []string {
+	return a.sessionManager.ListOwnedBy(owner)
+}
+
+// SetSessionStore 设置会话持久化存储，内存中没有的会话从存储中读回
+func (a *Agent) SetSessionStore(store *SessionStore) {
+	a.sessionManager.SetStore(store)
+}
+
 // GetRegistry 获取函数注册表
 func (a *Agent) GetRegistry() *function.Registry {
 	return a.registry
 }
 
+// GetExecutor 获取函数执行器，供 MCP 等外部适配层直接调用函数
+func (a *Agent) GetExecutor() *function.Executor {
+	return a.executor
+}
+
 // generateSessionID 生成会话 ID
 func generateSessionID() string {
 	return fmt.Sprintf("session_%d", time.Now().UnixNano())
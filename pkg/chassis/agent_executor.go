@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/KodaTao/AgentChassis/pkg/function"
 	"github.com/KodaTao/AgentChassis/pkg/scheduler"
 )
 
@@ -12,7 +13,7 @@ import (
 // 明确告诉 AI 这是一个定时任务被触发了，需要立即执行，而不是创建新任务
 const taskExecutionPromptPrefix = `【系统提示：这是一个定时任务被触发了，请立即执行以下任务内容。
 重要：不要创建新的定时任务或延时任务，而是直接执行任务。
-如果任务需要通知用户，请使用 send_message 函数发送消息。】
+如果任务需要通知用户，请使用 send_message 函数发送消息（通知创建任务的用户时无需填写 to 和 channel）。】
 
 任务内容：`
 
@@ -28,8 +29,14 @@ func NewAgentExecutorAdapter(agent *Agent) scheduler.AgentExecutor {
 }
 
 // Execute 执行一次独立的对话，返回 LLM 的最终回复
-// 每次执行使用独立的 session，不保留历史上下文
-func (a *agentExecutorAdapter) Execute(ctx context.Context, prompt string) (string, error) {
+// 每次执行使用独立的 session，不保留历史上下文；channel 作为对话的渠道上下文，
+// send_message 未指定渠道时默认发往该渠道；scopes 非 nil 时以创建者的作用域执行，
+// 防止受限的 key 借定时任务调用超出其作用域的函数
+func (a *agentExecutorAdapter) Execute(ctx context.Context, prompt string, channel *ChannelContext, scopes []string) (string, error) {
+	if scopes != nil {
+		ctx = function.WithCallerScopes(ctx, scopes)
+	}
+
 	// 添加任务执行前缀，明确告诉 AI 这是任务触发时刻
 	// 防止 AI 误解并递归创建新任务
 	fullPrompt := fmt.Sprintf("%s%s", taskExecutionPromptPrefix, prompt)
@@ -39,6 +46,7 @@ func (a *agentExecutorAdapter) Execute(ctx context.Context, prompt string) (stri
 	req := ChatRequest{
 		SessionID: "", // 空 session ID 会触发创建新会话
 		Message:   fullPrompt,
+		Channel:   channel,
 	}
 
 	resp, err := a.agent.Chat(ctx, req)
@@ -0,0 +1,65 @@
+package chassis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+// newScopedExecutor 创建带一个 admin 作用域函数的 Agent 及其任务执行适配器
+func newScopedExecutor(t *testing.T, provider *fakeProvider) (*countingFunction, func(scopes []string) (string, error)) {
+	t.Helper()
+	fn := &countingFunction{name: "reset_all"}
+	registry := function.NewRegistry()
+	if err := registry.Register(fn); err != nil {
+		t.Fatal(err)
+	}
+	registry.SetScopes("reset_all", "admin")
+
+	executor := NewAgentExecutorAdapter(NewAgent(provider, registry, DefaultAgentConfig()))
+	return fn, func(scopes []string) (string, error) {
+		return executor.Execute(context.Background(), "reset everything", nil, scopes)
+	}
+}
+
+func TestAgentExecutor_CallerScopes(t *testing.T) {
+	t.Run("limited scopes cannot call admin function", func(t *testing.T) {
+		provider := &fakeProvider{name: "main", reply: "done", replies: []string{`<call name="reset_all"></call>`}}
+		fn, execute := newScopedExecutor(t, provider)
+
+		if _, err := execute([]string{"tasks"}); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		if n := fn.calls.Load(); n != 0 {
+			t.Fatalf("admin function ran %d times for a limited-scope task", n)
+		}
+		if len(provider.requests) < 2 || !hasPermissionDenied(provider.requests[1]) {
+			t.Error("expected the function result to report permission denied")
+		}
+	})
+
+	t.Run("internal task is unrestricted", func(t *testing.T) {
+		provider := &fakeProvider{name: "main", reply: "done", replies: []string{`<call name="reset_all"></call>`}}
+		fn, execute := newScopedExecutor(t, provider)
+
+		if _, err := execute(nil); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		if n := fn.calls.Load(); n != 1 {
+			t.Fatalf("admin function ran %d times, want 1", n)
+		}
+	})
+}
+
+// hasPermissionDenied 请求中是否有报告权限不足的函数结果
+func hasPermissionDenied(messages []llm.Message) bool {
+	for _, m := range messages {
+		if m.Role == llm.RoleTool && strings.Contains(m.Content, function.ErrPermissionDenied.Error()) {
+			return true
+		}
+	}
+	return false
+}
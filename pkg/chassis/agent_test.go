@@ -0,0 +1,303 @@
+package chassis
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/protocol"
+)
+
+// newTestAgent 创建注册了给定函数的 Agent，configure 可调整默认配置
+func newTestAgent(t *testing.T, provider llm.Provider, configure func(*AgentConfig), fns ...function.Function) *Agent {
+	t.Helper()
+	registry := function.NewRegistry()
+	for _, fn := range fns {
+		if err := registry.Register(fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := DefaultAgentConfig()
+	if configure != nil {
+		configure(config)
+	}
+	return NewAgent(provider, registry, config)
+}
+
+// toolResults 返回请求中所有函数结果消息的内容
+func toolResults(messages []llm.Message) []string {
+	var results []string
+	for _, m := range messages {
+		if m.Role == llm.RoleTool {
+			results = append(results, m.Content)
+		}
+	}
+	return results
+}
+
+func TestAgent_UnknownFunction(t *testing.T) {
+	// AI 一直调用不存在的 list_order
+	provider := &fakeProvider{name: "main", reply: `<call name="list_order"></call>`}
+	agent := newTestAgent(t, provider, nil, &staticFunction{name: "list_orders", result: "no orders"})
+
+	resp, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "show my orders"})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	// 反馈给 AI 的错误带有最接近的函数名建议和可用函数列表
+	results := toolResults(provider.requests[1])
+	if len(results) != 1 {
+		t.Fatalf("got %d function results in the second request, want 1", len(results))
+	}
+	for _, want := range []string{"Did you mean list_orders?", "Available functions: list_orders."} {
+		if !strings.Contains(results[0], want) {
+			t.Errorf("hint %q does not contain %q", results[0], want)
+		}
+	}
+
+	// 连续 MaxUnknownCalls 次后提前结束，不再请求 LLM
+	max := DefaultAgentConfig().MaxUnknownCalls
+	if len(provider.requests) != max {
+		t.Errorf("LLM called %d times, want %d", len(provider.requests), max)
+	}
+	if len(resp.FunctionCalls) != max {
+		t.Errorf("got %d function calls, want %d", len(resp.FunctionCalls), max)
+	}
+	if !strings.Contains(resp.Reply, `"list_order"`) {
+		t.Errorf("reply = %q, want the abort message naming list_order", resp.Reply)
+	}
+}
+
+func TestCallKey(t *testing.T) {
+	call := func(name string, params map[string]string, data string) *protocol.CallRequest {
+		return &protocol.CallRequest{Name: name, Params: params, Data: data}
+	}
+	tests := []struct {
+		name string
+		a, b *protocol.CallRequest
+		same bool
+	}{
+		{
+			name: "identical",
+			a:    call("notify", map[string]string{"to": "bob"}, ""),
+			b:    call("notify", map[string]string{"to": "bob"}, ""),
+			same: true,
+		},
+		{
+			name: "param order does not matter",
+			a:    call("notify", map[string]string{"to": "bob", "text": "hi"}, ""),
+			b:    call("notify", map[string]string{"text": "hi", "to": "bob"}, ""),
+			same: true,
+		},
+		{
+			name: "different function",
+			a:    call("notify", map[string]string{"to": "bob"}, ""),
+			b:    call("notify_all", map[string]string{"to": "bob"}, ""),
+		},
+		{
+			name: "different param value",
+			a:    call("notify", map[string]string{"to": "bob"}, ""),
+			b:    call("notify", map[string]string{"to": "amy"}, ""),
+		},
+		{
+			name: "different data",
+			a:    call("notify", nil, "users[1]{id}:\n  1"),
+			b:    call("notify", nil, "users[1]{id}:\n  2"),
+		},
+		{
+			name: "separator inside a value",
+			a:    call("notify", map[string]string{"a": "1\x00\"b\"=\"2\""}, ""),
+			b:    call("notify", map[string]string{"a": "1", "b": "2"}, ""),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := callKey(tt.a) == callKey(tt.b); got != tt.same {
+				t.Errorf("callKey equal = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestAgent_DedupCalls(t *testing.T) {
+	// 同一轮中两次完全相同的调用和一次参数不同的调用
+	provider := &fakeProvider{name: "main", reply: "done", replies: []string{
+		`<call name="notify"><p>to: bob</p></call>
+<call name="notify"><p>to: bob</p></call>
+<call name="notify"><p>to: amy</p></call>`,
+	}}
+	fn := &countingFunction{name: "notify"}
+	agent := newTestAgent(t, provider, nil, fn)
+
+	resp, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "notify bob and amy"})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if n := fn.calls.Load(); n != 2 {
+		t.Errorf("function ran %d times, want 2 (duplicate skipped, different params run)", n)
+	}
+	if len(resp.FunctionCalls) != 3 {
+		t.Fatalf("got %d function calls, want 3", len(resp.FunctionCalls))
+	}
+	// 重复项复用第一次的记录
+	if resp.FunctionCalls[1].CallID != resp.FunctionCalls[0].CallID || resp.FunctionCalls[2].CallID == resp.FunctionCalls[0].CallID {
+		t.Errorf("call IDs = %s, %s, %s", resp.FunctionCalls[0].CallID, resp.FunctionCalls[1].CallID, resp.FunctionCalls[2].CallID)
+	}
+
+	// 关闭去重后每次调用都会执行
+	provider = &fakeProvider{name: "main", reply: "done", replies: []string{
+		`<call name="notify"><p>to: bob</p></call>
+<call name="notify"><p>to: bob</p></call>`,
+	}}
+	fn = &countingFunction{name: "notify"}
+	agent = newTestAgent(t, provider, func(c *AgentConfig) { c.DedupCalls = false }, fn)
+	if _, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "notify bob"}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if n := fn.calls.Load(); n != 2 {
+		t.Errorf("function ran %d times with dedup disabled, want 2", n)
+	}
+}
+
+func TestAgent_MaxFunctionCalls(t *testing.T) {
+	threeCalls := `<call name="notify"><p>to: a</p></call>
+<call name="notify"><p>to: b</p></call>
+<call name="notify"><p>to: c</p></call>`
+
+	tests := []struct {
+		name     string
+		replies  []string
+		parallel bool
+	}{
+		{name: "one round", replies: []string{threeCalls}},
+		{name: "one round parallel", replies: []string{threeCalls}, parallel: true},
+		{name: "across rounds", replies: []string{`<call name="notify"><p>to: a</p></call>`, `<call name="notify"><p>to: b</p></call>
+<call name="notify"><p>to: c</p></call>`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{name: "main", reply: "done", replies: tt.replies}
+			fn := &countingFunction{name: "notify"}
+			agent := newTestAgent(t, provider, func(c *AgentConfig) {
+				c.MaxFunctionCalls = 2
+				c.ParallelCalls = tt.parallel
+			}, fn)
+
+			resp, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "notify everyone"})
+			if err != nil {
+				t.Fatalf("Chat: %v", err)
+			}
+			if n := fn.calls.Load(); n != 2 {
+				t.Errorf("function ran %d times, want 2", n)
+			}
+			if len(resp.FunctionCalls) != 3 {
+				t.Fatalf("got %d function calls, want 3", len(resp.FunctionCalls))
+			}
+			// 超出上限的调用被拒绝，并以固定的说明反馈给 AI
+			refused := resp.FunctionCalls[2]
+			if refused.Status != "error" || refused.Result != callLimitMessage {
+				t.Errorf("third call = %+v, want refused with %q", refused, callLimitMessage)
+			}
+			if resp.Reply != "已停止：本次请求的函数调用次数已达上限（2 次）。" {
+				t.Errorf("reply = %q", resp.Reply)
+			}
+			// 达到上限后不再请求 LLM
+			if len(provider.requests) != len(tt.replies) {
+				t.Errorf("LLM called %d times, want %d", len(provider.requests), len(tt.replies))
+			}
+		})
+	}
+}
+
+func TestAgent_ShowThoughts(t *testing.T) {
+	replies := func() []string {
+		return []string{
+			"Let me notify bob first.\n<call name=\"notify\"><p>to: bob</p></call>",
+			"<call name=\"notify\"><p>to: amy</p></call>",
+			"Now amy.\n<call name=\"notify\"><p>to: amy2</p></call>",
+		}
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		provider := &fakeProvider{name: "main", reply: "all done", replies: replies()}
+		var events []Event
+		agent := newTestAgent(t, provider, func(c *AgentConfig) {
+			c.ShowThoughts = true
+			c.OnEvent = func(e Event) {
+				if e.Type == EventThought {
+					events = append(events, e)
+				}
+			}
+		}, &countingFunction{name: "notify"})
+
+		resp, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "notify everyone"})
+		if err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+		// 没有说明文字的轮次不产生思考
+		want := []string{"Let me notify bob first.", "Now amy."}
+		if !reflect.DeepEqual(resp.Thoughts, want) {
+			t.Errorf("thoughts = %q, want %q", resp.Thoughts, want)
+		}
+		if len(events) != 2 || events[0].Iteration != 1 || events[1].Iteration != 3 || events[1].Content != "Now amy." {
+			t.Errorf("thought events = %+v", events)
+		}
+		if resp.Reply != "all done" {
+			t.Errorf("reply = %q", resp.Reply)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		provider := &fakeProvider{name: "main", reply: "all done", replies: replies()}
+		agent := newTestAgent(t, provider, nil, &countingFunction{name: "notify"})
+
+		resp, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "notify everyone"})
+		if err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+		if resp.Thoughts != nil {
+			t.Errorf("thoughts = %q, want none", resp.Thoughts)
+		}
+	})
+}
+
+func TestAgent_ChannelPrompts(t *testing.T) {
+	provider := &fakeProvider{name: "main", reply: "ok"}
+	agent := newTestAgent(t, provider, func(c *AgentConfig) {
+		c.ChannelPrompts = map[string]string{
+			"telegram":     "Reply briefly with emoji.",
+			APIChannelType: "Reply with structured text.",
+		}
+	})
+
+	systemPrompt := func(i int) string {
+		return provider.requests[i][0].Content
+	}
+	chat := func(channel *ChannelContext) {
+		t.Helper()
+		if _, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "hi", Channel: channel}); err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+	}
+
+	// 未指定渠道的请求使用 api 的指令
+	chat(nil)
+	if p := systemPrompt(0); !strings.Contains(p, "Reply with structured text.") || strings.Contains(p, "emoji") {
+		t.Errorf("api system prompt:\n%s", p)
+	}
+
+	// 同一会话换到 Telegram 继续时重新生成系统提示
+	chat(&ChannelContext{Type: "telegram", ChatID: "1"})
+	if p := systemPrompt(1); !strings.Contains(p, "Reply briefly with emoji.") || strings.Contains(p, "structured text") {
+		t.Errorf("telegram system prompt:\n%s", p)
+	}
+	if n := len(agent.GetSession("s1").GetMessages()); n != 5 {
+		t.Errorf("session has %d messages, want 5 (one system prompt plus two exchanges)", n)
+	}
+}
@@ -2,14 +2,27 @@
 package chassis
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
-
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/approval"
+	"github.com/KodaTao/AgentChassis/pkg/audit"
 	"github.com/KodaTao/AgentChassis/pkg/function"
 	"github.com/KodaTao/AgentChassis/pkg/function/builtin"
+	"github.com/KodaTao/AgentChassis/pkg/function/webhook"
 	"github.com/KodaTao/AgentChassis/pkg/llm"
-	"github.com/KodaTao/AgentChassis/pkg/llm/openai"
+	"github.com/KodaTao/AgentChassis/pkg/memory"
 	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/prompt"
 	"github.com/KodaTao/AgentChassis/pkg/scheduler"
 	"github.com/KodaTao/AgentChassis/pkg/storage"
 	"github.com/KodaTao/AgentChassis/pkg/telegram"
@@ -19,13 +32,29 @@ import (
 // 这是整个框架的入口点
 type App struct {
 	config              *Config
+	logger              *slog.Logger // App 自己的 logger，由 WithLogger 指定或按 Log 配置创建
 	registry            *function.Registry
+	deps                *function.Dependencies // 函数共享的依赖，执行时注入 context
+	baseline            function.Snapshot      // 初始化完成时的能力快照，用于对比运行期的动态变更
 	agent               *Agent
 	provider            llm.Provider
+	llmPool             *llm.Pool // 函数内部调用 LLM 时可选的 Provider
 	delayScheduler      *scheduler.DelayScheduler
 	cronScheduler       *scheduler.CronScheduler
+	callLogRepo         *function.CallLogRepository
+	auditRepo           *audit.Repository // 未开启审计时为 nil
+	auditStop           chan struct{}     // 关闭时停止审计记录的定期清理
+	promptStop          chan struct{}     // 关闭时停止提示词模板文件的热重载
+	approvalRepo        *approval.Repository
+	memoryRepo          *memory.Repository
+	taskManager         *function.TaskManager // 异步函数的后台任务，与 check_task_status 共享
+	dbs                 *storage.Databases
+	webhookManager      *webhook.Manager
 	telegramBot         *telegram.Bot
 	sendMessageFunction *builtin.SendMessageFunction // 保存引用以便后续注入 Telegram 发送器
+	llmProbe            llmProbe                     // 缓存 LLM 健康探测结果
+	events              *observability.Emitter       // App 自己的事件输出目标，与其他 App 互不影响
+	eventSink           *observability.JSONSink      // 内置的事件输出，关闭时需要释放文件
 }
 
 // New 创建新的 App 实例
@@ -38,9 +67,20 @@ func New(opts ...Option) *App {
 		opt(config)
 	}
 
+	logger := config.logger
+	if logger == nil {
+		logger = observability.DefaultLogger()
+	}
+	registry := function.NewRegistry()
+	registry.SetLogger(config.logger)
+
 	return &App{
 		config:   config,
-		registry: function.NewRegistry(),
+		logger:   logger,
+		registry: registry,
+		deps:     function.NewDependencies(),
+		events:   observability.NewEmitter(config.logger),
+		dbs:      storage.NewDatabases(),
 	}
 }
 
@@ -54,29 +94,83 @@ func (a *App) RegisterAll(fns ...function.Function) error {
 	return a.registry.RegisterAll(fns...)
 }
 
+// RegisterAlias 为已注册的 Function 注册别名
+func (a *App) RegisterAlias(alias, target string) error {
+	return a.registry.RegisterAlias(alias, target)
+}
+
+// Dependencies 返回函数共享的依赖容器，通过 function.Provide(app.Dependencies(), db) 注册，
+// 函数执行时用 function.Dependency[*sql.DB](ctx) 获取
+func (a *App) Dependencies() *function.Dependencies {
+	return a.deps
+}
+
+// RegisterSink 注册结构化事件的输出目标（如写入 ClickHouse、Kafka 的自定义实现）
+// 只接收本 App 的事件，同一进程中的其他 App 不会写入；Shutdown 后不再写入
+func (a *App) RegisterSink(sink observability.Sink) {
+	a.events.Register(sink)
+}
+
+// initEventSink 按配置启用内置的事件输出
+func (a *App) initEventSink() error {
+	cfg := a.config.Observability.Events
+	switch strings.ToLower(cfg.Output) {
+	case "":
+		return nil
+	case "stdout":
+		a.eventSink = observability.NewStdoutSink()
+	case "file":
+		path := cfg.FilePath
+		if path == "" {
+			path = "agentchassis-events.jsonl"
+		}
+		sink, err := observability.NewFileSink(path)
+		if err != nil {
+			return err
+		}
+		a.eventSink = sink
+	default:
+		return fmt.Errorf("unsupported event output: %s (want stdout or file)", cfg.Output)
+	}
+	a.events.Register(a.eventSink)
+	return nil
+}
+
 // Initialize 初始化应用
 // 包括：日志、数据库、LLM Provider、Agent
 func (a *App) Initialize() error {
-	// 1. 初始化日志
-	if err := observability.InitLogger(observability.LogConfig{
-		Level:    a.config.Log.Level,
-		Format:   a.config.Log.Format,
-		Output:   a.config.Log.Output,
-		FilePath: a.config.Log.FilePath,
-	}); err != nil {
-		return fmt.Errorf("failed to initialize logger: %w", err)
+	// 1. 初始化日志（通过 WithLogger 指定了 logger 时直接使用，不修改全局实例）
+	if a.config.logger == nil {
+		if err := observability.InitLogger(observability.LogConfig{
+			Level:      a.config.Log.Level,
+			Format:     a.config.Log.Format,
+			Output:     a.config.Log.Output,
+			FilePath:   a.config.Log.FilePath,
+			MaxSizeMB:  a.config.Log.MaxSizeMB,
+			MaxBackups: a.config.Log.MaxBackups,
+			MaxAgeDays: a.config.Log.MaxAgeDays,
+			Compress:   a.config.Log.Compress,
+
+			LLMVerbose:         a.config.Log.LLMVerbose,
+			LLMVerboseMaxChars: a.config.Log.LLMVerboseMaxChars,
+		}); err != nil {
+			return fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		a.logger = observability.DefaultLogger()
+	}
+	a.registry.SetLogger(a.logger)
+	if err := a.initEventSink(); err != nil {
+		return fmt.Errorf("failed to initialize event sink: %w", err)
 	}
 
-	observability.Info("Initializing AgentChassis",
+	a.logger.Info("Initializing AgentChassis",
 		"server_port", a.config.Server.Port,
 		"llm_provider", a.config.LLM.Provider,
 		"llm_model", a.config.LLM.Model,
 	)
 
-	// 2. 初始化数据库
-	if err := storage.InitDB(storage.Config{
-		Path: a.config.Database.Path,
-	}); err != nil {
+	// 2. 初始化数据库（调度、会话数据可配置独立的库，避免争抢写锁）
+	if err := a.openDatabases(); err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 
@@ -87,66 +181,156 @@ func (a *App) Initialize() error {
 		return fmt.Errorf("LLM API key is required")
 	}
 
-	// 根据 provider 类型创建实例
-	switch a.config.LLM.Provider {
-	case "openai", "azure", "custom":
-		a.provider = openai.NewProviderFromLLMConfig(llm.Config{
-			Provider:    a.config.LLM.Provider,
-			APIKey:      apiKey,
-			BaseURL:     a.config.LLM.BaseURL,
-			Model:       a.config.LLM.Model,
-			Timeout:     a.config.LLM.Timeout,
-			MaxTokens:   a.config.LLM.MaxTokens,
-			Temperature: a.config.LLM.Temperature,
+	provider, err := a.newLLMProvider(a.config.LLM)
+	if err != nil {
+		return err
+	}
+	a.provider = provider
+	// 响应缓存放在断路器外层，断路器打开时缓存仍可命中
+	if err := llm.ValidateCacheMode(a.config.LLM.Cache.Mode); err != nil {
+		return err
+	}
+	if cache := a.config.LLM.Cache; cache.Mode != llm.CacheModeOff {
+		a.provider = llm.NewCachingProvider(a.provider, cache, llm.ChatOptions{
+			Model:          a.config.LLM.Model,
+			Temperature:    a.config.LLM.Temperature,
+			MaxTokens:      a.config.LLM.MaxTokens,
+			ResponseFormat: a.config.LLM.ResponseFormat,
 		})
-	default:
-		return fmt.Errorf("unsupported LLM provider: %s", a.config.LLM.Provider)
+		a.logger.Info("LLM response cache enabled",
+			"mode", cache.Mode,
+			"ttl", cache.TTL,
+			"max_entries", cache.MaxEntries,
+		)
+	}
+	if err := ValidateCallMode(a.config.LLM.CallMode); err != nil {
+		return err
+	}
+	if err := llm.ValidateResponseFormatFor(a.config.LLM.ResponseFormat, a.config.LLM.CallMode); err != nil {
+		return err
+	}
+	if err := a.config.Builtins.Validate(); err != nil {
+		return err
+	}
+	if err := a.config.Console.Validate(); err != nil {
+		return err
 	}
 
-	observability.Info("LLM Provider initialized",
+	a.logger.Info("LLM Provider initialized",
 		"provider", a.provider.Name(),
 		"model", a.config.LLM.Model,
 		"api_key", llm.MaskAPIKey(apiKey),
 	)
-
-	// 4. 初始化 DelayScheduler（此时还没有 AgentExecutor，后续设置）
-	db := storage.GetDB()
-	logger := slog.Default()
-	a.delayScheduler = scheduler.NewDelayScheduler(db, logger)
-	if err := a.delayScheduler.Start(); err != nil {
-		return fmt.Errorf("failed to start delay scheduler: %w", err)
+	if err := a.initProviderPool(); err != nil {
+		return err
 	}
 
-	observability.Info("DelayScheduler started")
+	// 启动自检：异步探测 LLM 是否可用，失败只记录警告，不阻塞启动
+	go func() {
+		if err := a.pingLLM(context.Background()); err != nil {
+			a.logger.Warn("LLM provider ping failed", "provider", a.provider.Name(), "error", err)
+		}
+	}()
 
-	// 5. 初始化 CronScheduler（此时还没有 AgentExecutor，后续设置）
-	a.cronScheduler = scheduler.NewCronScheduler(db, logger)
-	if err := a.cronScheduler.Start(); err != nil {
-		return fmt.Errorf("failed to start cron scheduler: %w", err)
+	// 4-5. 创建 DelayScheduler 和 CronScheduler（此时还没有 AgentExecutor，注入后再启动）
+	// MCP 模式下不创建，避免与共享同一数据库的 serve 进程重复执行任务
+	db := a.dbs.Get(storage.DefaultDBName)
+	if !a.config.mcpMode {
+		a.initSchedulers()
 	}
 
-	observability.Info("CronScheduler started")
-
-	// 6. 注册内置调度函数
+	// 6. 注册内置调度函数、记忆函数和异步任务查询函数
 	a.registerBuiltinSchedulerFunctions()
+	a.memoryRepo = memory.NewRepository(db)
+	if err := a.memoryRepo.Migrate(); err != nil {
+		return fmt.Errorf("failed to migrate user_memories table: %w", err)
+	}
+	a.registerBuiltin(builtin.NewRememberFunction(a.memoryRepo))
+	a.registerBuiltin(builtin.NewRecallFunction(a.memoryRepo))
+	a.taskManager = function.NewTaskManager()
+	a.registerBuiltin(builtin.NewCheckTaskStatusFunction(a.taskManager))
+
+	// 恢复通过 HTTP 注册的 webhook 函数（在内置函数之后，避免覆盖同名内置函数）
+	a.webhookManager = webhook.NewManager(db, a.registry, webhook.Guard{
+		AllowPrivateNetworks: a.config.Webhook.AllowPrivateNetworks,
+	})
+	a.webhookManager.SetLogger(a.logger)
+	if err := a.webhookManager.Start(); err != nil {
+		return fmt.Errorf("failed to start webhook manager: %w", err)
+	}
 
-	// 7. 创建 Agent
-	a.agent = NewAgent(a.provider, a.registry, nil)
+	// 应用配置中的函数作用域
+	for name, scopes := range a.config.Auth.FunctionScopes {
+		a.registry.SetScopes(name, scopes...)
+	}
 
-	// 8. 设置 AgentExecutor 到调度器（解决循环依赖）
-	// Agent 创建完成后，将其适配为 AgentExecutor 并注入到调度器
-	executor := NewAgentExecutorAdapter(a.agent)
-	a.delayScheduler.SetAgentExecutor(executor)
-	a.cronScheduler.SetAgentExecutor(executor)
+	// 7. 创建 Agent，并启用函数调用记录
+	agentConfig := a.agentConfig()
+	if a.config.LLM.CallMode != "" {
+		agentConfig.CallMode = a.config.LLM.CallMode
+	}
+	agentConfig.PromptVars = a.config.PromptVars
+	agentConfig.DedupCalls = a.config.DedupCalls
+	agentConfig.MaxHistoryTokens = a.config.MaxHistoryTokens
+	agentConfig.RateLimits = a.config.RateLimits
+	agentConfig.AuditMaskPII = a.config.Audit.MaskPII
+	a.agent = NewAgent(a.provider, a.registry, agentConfig)
+	a.agent.SetProviderPool(a.llmPool)
+	a.agent.SetEmitter(a.events)
+	a.agent.SetLogger(a.logger, &observability.LLMVerboseConfig{
+		Enabled:  a.config.Log.LLMVerbose,
+		MaxChars: a.config.Log.LLMVerboseMaxChars,
+	})
+	a.agent.executor.SetDependencies(a.deps)
+	if err := a.validateLanguage(); err != nil {
+		return err
+	}
+	if err := a.initPromptTemplates(); err != nil {
+		return err
+	}
+	if a.config.Resources.Enabled {
+		a.agent.executor.SetResourceMonitor(function.NewResourceMonitor(a.config.Resources.Thresholds()))
+	}
 
-	observability.Info("AgentExecutor injected to schedulers")
+	a.callLogRepo = function.NewCallLogRepository(a.dbs.Get(storage.SessionDBName))
+	if err := a.callLogRepo.Migrate(); err != nil {
+		return fmt.Errorf("failed to migrate function_call_logs table: %w", err)
+	}
+	a.agent.SetCallLogRepository(a.callLogRepo)
+	if err := a.initSessionStore(); err != nil {
+		return err
+	}
+	a.agent.SetMemoryRepository(a.memoryRepo)
+	a.agent.SetTaskManager(a.taskManager)
+	a.approvalRepo = approval.NewRepository(a.dbs.Get(storage.SessionDBName))
+	if err := a.approvalRepo.Migrate(); err != nil {
+		return fmt.Errorf("failed to migrate approval_requests table: %w", err)
+	}
+	a.agent.SetApprovalRepository(a.approvalRepo)
+	if err := a.initAudit(); err != nil {
+		return err
+	}
+
+	// 8. 设置 AgentExecutor 到调度器（解决循环依赖）并启动调度器
+	// 必须先注入再启动：启动时恢复的到期任务和错过的 cron 触发会立即执行
+	if a.delayScheduler != nil {
+		executor := NewAgentExecutorAdapter(a.agent)
+		a.delayScheduler.SetAgentExecutor(executor)
+		a.cronScheduler.SetAgentExecutor(executor)
+
+		a.logger.Info("AgentExecutor injected to schedulers")
+		if err := a.startSchedulers(); err != nil {
+			return err
+		}
+	}
 
-	observability.Info("AgentChassis initialized",
+	a.baseline = a.registry.Snapshot()
+	a.logger.Info("AgentChassis initialized",
 		"registered_functions", a.registry.Count(),
 	)
 
-	// 9. 初始化 Telegram Bot（可选）
-	if a.config.Telegram.Enabled {
+	// 9. 初始化 Telegram Bot（可选，MCP 模式下不启动，避免与 serve 进程争抢同一个 Bot 的消息）
+	if a.config.Telegram.Enabled && !a.config.mcpMode {
 		if err := a.initTelegramBot(); err != nil {
 			return fmt.Errorf("failed to initialize telegram bot: %w", err)
 		}
@@ -155,17 +339,198 @@ func (a *App) Initialize() error {
 	return nil
 }
 
+// initSessionStore 开启会话持久化时创建会话存储
+func (a *App) initSessionStore() error {
+	if !a.config.Session.Persist {
+		return nil
+	}
+	store := NewSessionStore(a.dbs.Get(storage.SessionDBName), a.config.Session.Compress)
+	if err := store.Migrate(); err != nil {
+		return fmt.Errorf("failed to migrate chat_sessions table: %w", err)
+	}
+	a.agent.SetSessionStore(store)
+	return nil
+}
+
+// initSchedulers 创建延时任务和定时任务调度器，注入 AgentExecutor 后由 startSchedulers 启动
+func (a *App) initSchedulers() {
+	schedulerDB := a.dbs.Get(storage.SchedulerDBName)
+	a.delayScheduler = scheduler.NewDelayScheduler(schedulerDB, a.logger)
+	a.delayScheduler.SetMaxConcurrent(a.config.Scheduler.DelayMaxConcurrent)
+	a.delayScheduler.SetPastTolerance(a.config.Scheduler.DelayPastTolerance)
+	a.delayScheduler.SetEmitter(a.events)
+
+	a.cronScheduler = scheduler.NewCronScheduler(schedulerDB, a.logger)
+	a.cronScheduler.SetEmitter(a.events)
+
+	// 任务结果推送与 webhook 函数共用内网访问限制
+	notifier := scheduler.NewWebhookNotifier(webhook.Guard{
+		AllowPrivateNetworks: a.config.Webhook.AllowPrivateNetworks,
+	}.HTTPClient())
+	a.delayScheduler.SetResultNotifier(notifier)
+	a.cronScheduler.SetResultNotifier(notifier)
+}
+
+// startSchedulers 启动调度器，恢复持久化的任务并补偿执行错过的触发
+func (a *App) startSchedulers() error {
+	if err := a.delayScheduler.Start(); err != nil {
+		return fmt.Errorf("failed to start delay scheduler: %w", err)
+	}
+	a.logger.Info("DelayScheduler started")
+
+	if err := a.cronScheduler.Start(); err != nil {
+		return fmt.Errorf("failed to start cron scheduler: %w", err)
+	}
+	a.logger.Info("CronScheduler started")
+	return nil
+}
+
+// initPromptTemplates 加载外置的系统提示词模板，开启热重载时监视文件变化
+func (a *App) initPromptTemplates() error {
+	cfg := a.config.Prompt
+	if cfg.SystemTemplatePath == "" && cfg.ToolsTemplatePath == "" {
+		return nil
+	}
+	for _, path := range []string{cfg.SystemTemplatePath, cfg.ToolsTemplatePath} {
+		if _, err := os.Stat(path); path != "" && errors.Is(err, fs.ErrNotExist) {
+			a.logger.Warn("Prompt template file not found, using the built-in template", "path", path)
+		}
+	}
+	generator := a.agent.promptGenerator
+	if err := generator.LoadTemplateFiles(cfg.files()); err != nil {
+		return fmt.Errorf("failed to load prompt templates: %w", err)
+	}
+
+	if cfg.HotReload {
+		a.promptStop = make(chan struct{})
+		go generator.WatchTemplateFiles(cfg.files(), cfg.ReloadInterval, a.logger, a.promptStop)
+	}
+	a.logger.Info("Prompt templates loaded",
+		"system", cfg.SystemTemplatePath,
+		"tools", cfg.ToolsTemplatePath,
+		"hot_reload", cfg.HotReload,
+	)
+	return nil
+}
+
+// validateLanguage 校验配置的默认语言有对应的系统提示模板，避免拼写错误时悄悄回退到英文
+func (a *App) validateLanguage() error {
+	lang := a.config.Agent.Language
+	if lang == "" || a.agent.promptGenerator.HasLanguage(lang) {
+		return nil
+	}
+	return fmt.Errorf("invalid agent.language %q: %w (available: %s)",
+		lang, prompt.ErrUnknownLanguage, strings.Join(a.agent.promptGenerator.Languages(), ", "))
+}
+
+// initAudit 开启审计日志时创建审计记录仓库，并按保留期定期清理
+func (a *App) initAudit() error {
+	if !a.config.Audit.Enabled {
+		return nil
+	}
+	a.auditRepo = audit.NewRepository(a.dbs.Get(storage.SessionDBName))
+	if err := a.auditRepo.Migrate(); err != nil {
+		return fmt.Errorf("failed to migrate audit_records table: %w", err)
+	}
+	a.agent.SetAuditRepository(a.auditRepo)
+
+	if days := a.config.Audit.RetentionDays; days > 0 {
+		a.auditStop = make(chan struct{})
+		go runAuditRetention(a.auditRepo, a.logger, time.Duration(days)*24*time.Hour, a.auditStop)
+	}
+
+	a.logger.Info("Audit log enabled",
+		"mask_pii", a.config.Audit.MaskPII,
+		"retention_days", a.config.Audit.RetentionDays,
+	)
+	return nil
+}
+
+// agentConfig 根据配置文件的 agent 段构造 AgentConfig，未设置的字段使用默认值
+func (a *App) agentConfig() *AgentConfig {
+	cfg := DefaultAgentConfig()
+	settings := a.config.Agent
+	if settings.MaxIterations > 0 {
+		cfg.MaxIterations = settings.MaxIterations
+	}
+	if settings.Timeout > 0 {
+		cfg.Timeout = settings.Timeout
+	}
+	if settings.MaxUnknownCalls > 0 {
+		cfg.MaxUnknownCalls = settings.MaxUnknownCalls
+	}
+	if settings.EmptyReplyRetries != 0 {
+		cfg.EmptyReplyRetries = settings.EmptyReplyRetries
+	}
+	cfg.MaxFunctionCalls = settings.MaxFunctionCalls
+	cfg.ParallelCalls = settings.ParallelCalls
+	cfg.ShowThoughts = settings.ShowThoughts
+	if settings.MaxResultChars != 0 {
+		cfg.MaxResultChars = settings.MaxResultChars
+	}
+	cfg.SummarizeResults = settings.SummarizeResults
+	cfg.SlimResults = settings.SlimResults
+	if settings.TransientRetries != 0 {
+		cfg.TransientRetries = settings.TransientRetries
+	}
+	cfg.Persona = settings.Persona
+	cfg.Language = settings.Language
+	cfg.ChannelPrompts = settings.ChannelPrompts
+	cfg.Examples = settings.Examples
+	cfg.ApprovalFunctions = settings.ApprovalFunctions
+	cfg.Summary = settings.Summary
+	return cfg
+}
+
+// openDatabases 打开默认库、调度库和会话库
+// 调度库、会话库未单独配置路径时与默认库共用同一连接
+func (a *App) openDatabases() error {
+	cfg := a.config.Database
+	paths := []struct {
+		name string
+		path string
+	}{
+		{storage.DefaultDBName, cfg.Path},
+		{storage.SchedulerDBName, cfg.SchedulerPath},
+		{storage.SessionDBName, cfg.SessionPath},
+	}
+
+	for _, p := range paths {
+		path := p.path
+		if path == "" {
+			path = cfg.Path
+		}
+		if _, err := a.dbs.Open(p.name, storage.Config{
+			Path:         path,
+			JournalMode:  cfg.JournalMode,
+			BusyTimeout:  cfg.BusyTimeout,
+			Synchronous:  cfg.Synchronous,
+			MaxOpenConns: cfg.MaxOpenConns,
+			Logger:       a.logger,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // initTelegramBot 初始化 Telegram Bot
 func (a *App) initTelegramBot() error {
-	logger := slog.Default()
-
 	botConfig := telegram.Config{
 		Enabled:    a.config.Telegram.Enabled,
 		Token:      a.config.Telegram.Token,
 		SessionTTL: a.config.Telegram.SessionTTL,
+
+		AllowedChatIDs:   a.config.Telegram.AllowedChatIDs,
+		AllowedUsernames: a.config.Telegram.AllowedUsernames,
+
+		MaxQueuedMessages: a.config.Telegram.MaxQueuedMessages,
+
+		AcceptImages:  a.config.Telegram.AcceptImages,
+		MaxImageBytes: a.config.Telegram.MaxImageBytes,
 	}
 
-	bot, err := telegram.NewBot(botConfig, a.agent, logger)
+	bot, err := telegram.NewBot(botConfig, a.agent, a.logger)
 	if err != nil {
 		return err
 	}
@@ -175,13 +540,13 @@ func (a *App) initTelegramBot() error {
 	// 将 Telegram Bot 注入到 SendMessageFunction
 	if a.sendMessageFunction != nil {
 		a.sendMessageFunction.SetTelegramSender(bot)
-		observability.Info("Telegram sender injected to SendMessageFunction")
+		a.logger.Info("Telegram sender injected to SendMessageFunction")
 	}
 
 	// 启动 Bot（异步接收消息）
 	bot.Start()
 
-	observability.Info("Telegram Bot started")
+	a.logger.Info("Telegram Bot started")
 	return nil
 }
 
@@ -189,26 +554,41 @@ func (a *App) initTelegramBot() error {
 func (a *App) registerBuiltinSchedulerFunctions() {
 	// 注册消息发送函数（通用的外部通知函数，可直接调用或被延时任务调用）
 	// 保存引用以便后续注入 Telegram 发送器
-	a.sendMessageFunction = builtin.NewSendMessageFunction()
-	_ = a.registry.Register(a.sendMessageFunction)
-
-	// 注册延时任务管理函数
-	_ = a.registry.Register(builtin.NewDelayCreateFunction(a.delayScheduler))
-	_ = a.registry.Register(builtin.NewDelayListFunction(a.delayScheduler))
-	_ = a.registry.Register(builtin.NewDelayCancelFunction(a.delayScheduler))
-	_ = a.registry.Register(builtin.NewDelayGetFunction(a.delayScheduler))
-
-	// 注册定时任务管理函数
-	_ = a.registry.Register(builtin.NewCronCreateFunction(a.cronScheduler))
-	_ = a.registry.Register(builtin.NewCronListFunction(a.cronScheduler))
-	_ = a.registry.Register(builtin.NewCronDeleteFunction(a.cronScheduler))
-	_ = a.registry.Register(builtin.NewCronGetFunction(a.cronScheduler))
-	_ = a.registry.Register(builtin.NewCronHistoryFunction(a.cronScheduler))
-
-	observability.Info("Registered builtin functions",
-		"delay_functions", []string{"send_message", "delay_create", "delay_list", "delay_cancel", "delay_get"},
-		"cron_functions", []string{"cron_create", "cron_list", "cron_delete", "cron_get", "cron_history"},
-	)
+	sendMessage := builtin.NewSendMessageFunction()
+	sendMessage.SetConsoleOutput(builtin.ConsoleOutput{
+		Writer: a.config.Console.writer(),
+		Pretty: a.config.Console.Pretty,
+	})
+	if a.registerBuiltin(sendMessage) {
+		a.sendMessageFunction = sendMessage
+	}
+
+	// 注册延时任务和定时任务管理函数（可通过 builtins 配置裁剪），没有启动调度器时（MCP 模式）不注册
+	var fns []function.Function
+	if a.delayScheduler != nil {
+		fns = []function.Function{
+			builtin.NewDelayCreateFunction(a.delayScheduler),
+			builtin.NewDelayListFunction(a.delayScheduler),
+			builtin.NewDelayCancelFunction(a.delayScheduler),
+			builtin.NewDelayGetFunction(a.delayScheduler),
+			builtin.NewCronCreateFunction(a.cronScheduler),
+			builtin.NewCronListFunction(a.cronScheduler),
+			builtin.NewCronDeleteFunction(a.cronScheduler),
+			builtin.NewCronGetFunction(a.cronScheduler),
+			builtin.NewCronHistoryFunction(a.cronScheduler),
+		}
+	}
+	registered := make([]string, 0, len(fns)+1)
+	if a.sendMessageFunction != nil {
+		registered = append(registered, a.sendMessageFunction.Name())
+	}
+	for _, fn := range fns {
+		if a.registerBuiltin(fn) {
+			registered = append(registered, fn.Name())
+		}
+	}
+
+	a.logger.Info("Registered builtin functions", "functions", registered)
 }
 
 // GetAgent 获取 Agent 实例
@@ -216,11 +596,88 @@ func (a *App) GetAgent() *Agent {
 	return a.agent
 }
 
+// GetLogger 获取 App 的 logger
+func (a *App) GetLogger() *slog.Logger {
+	return a.logger
+}
+
 // GetRegistry 获取函数注册表
 func (a *App) GetRegistry() *function.Registry {
 	return a.registry
 }
 
+// GetBaselineSnapshot 获取初始化完成时的函数快照
+func (a *App) GetBaselineSnapshot() function.Snapshot {
+	return a.baseline
+}
+
+// GetResourceMonitor 获取函数资源监控器，未开启资源监控时返回 nil
+func (a *App) GetResourceMonitor() *function.ResourceMonitor {
+	return a.agent.executor.GetResourceMonitor()
+}
+
+// GetCallLogRepository 获取函数调用记录仓库
+func (a *App) GetCallLogRepository() *function.CallLogRepository {
+	return a.callLogRepo
+}
+
+// GetAuditRepository 获取审计记录仓库，未开启审计时返回 nil
+func (a *App) GetAuditRepository() *audit.Repository {
+	return a.auditRepo
+}
+
+// GetApprovalRepository 获取函数调用审批记录仓库
+func (a *App) GetApprovalRepository() *approval.Repository {
+	return a.approvalRepo
+}
+
+// DecideApproval 批准或拒绝函数调用审批请求，并把结果通知发起调用的渠道（目前支持 Telegram）
+func (a *App) DecideApproval(ctx context.Context, id string, approved bool, decidedBy, reason string) (*ChatResponse, error) {
+	resp, err := a.agent.DecideApproval(ctx, id, approved, decidedBy, reason)
+	if err != nil {
+		return nil, err
+	}
+	a.notifyApprovalResult(id, resp)
+	return resp, nil
+}
+
+// notifyApprovalResult 把审批后的回复发送到发起调用的渠道，失败只记录日志
+func (a *App) notifyApprovalResult(id string, resp *ChatResponse) {
+	if a.telegramBot == nil {
+		return
+	}
+	req, err := a.approvalRepo.Get(id)
+	if err != nil || req.Channel == "" {
+		return
+	}
+	var channel ChannelContext
+	if err := json.Unmarshal([]byte(req.Channel), &channel); err != nil || channel.Type != "telegram" {
+		return
+	}
+	chatID, err := strconv.ParseInt(channel.ChatID, 10, 64)
+	if err != nil {
+		return
+	}
+	if err := a.telegramBot.SendSessionMessage(chatID, resp.SessionID, resp.Reply); err != nil {
+		a.logger.Warn("Failed to notify approval result", "approval_id", id, "chat_id", chatID, "error", err)
+	}
+}
+
+// GetMemoryRepository 获取用户记忆仓库
+func (a *App) GetMemoryRepository() *memory.Repository {
+	return a.memoryRepo
+}
+
+// GetWebhookManager 获取 webhook 函数管理器
+func (a *App) GetWebhookManager() *webhook.Manager {
+	return a.webhookManager
+}
+
+// GetDatabases 获取数据库连接管理器
+func (a *App) GetDatabases() *storage.Databases {
+	return a.dbs
+}
+
 // GetConfig 获取配置
 func (a *App) GetConfig() *Config {
 	return a.config
@@ -231,31 +688,67 @@ func (a *App) GetProvider() llm.Provider {
 	return a.provider
 }
 
+// GetProviderPool 获取函数内部可用的 LLM Provider 池
+func (a *App) GetProviderPool() *llm.Pool {
+	return a.llmPool
+}
+
 // Shutdown 关闭应用
 func (a *App) Shutdown() error {
-	observability.Info("Shutting down AgentChassis")
+	a.logger.Info("Shutting down AgentChassis")
 
 	// 停止 Telegram Bot
 	if a.telegramBot != nil {
 		a.telegramBot.Stop()
-		observability.Info("Telegram Bot stopped")
+		a.logger.Info("Telegram Bot stopped")
 	}
 
-	// 停止调度器
+	// 并发停止调度器和异步任务，等待在途任务完成，总耗时不超过一个 ShutdownTimeout
+	timeout := a.config.Server.ShutdownTimeout
+	var wg sync.WaitGroup
+	stop := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
 	if a.delayScheduler != nil {
-		a.delayScheduler.Stop()
+		stop(func() { a.delayScheduler.Stop(timeout) })
 	}
 	if a.cronScheduler != nil {
-		a.cronScheduler.Stop()
+		stop(func() { a.cronScheduler.Stop(timeout) })
+	}
+	if a.taskManager != nil {
+		stop(func() { a.taskManager.Shutdown(timeout) })
+	}
+	if a.agent != nil {
+		stop(a.agent.stopSummaries)
+	}
+	wg.Wait()
+	if a.agent != nil {
+		a.agent.WaitWrites()
+	}
+	if a.auditStop != nil {
+		close(a.auditStop)
+	}
+	if a.promptStop != nil {
+		close(a.promptStop)
+	}
+
+	// 先移除事件输出目标再关闭文件，之后的事件不会写入已关闭的文件
+	a.events.Reset()
+	if a.eventSink != nil {
+		_ = a.eventSink.Close()
 	}
 
 	// 关闭数据库
-	if err := storage.Close(); err != nil {
-		observability.Error("Failed to close database", "error", err)
+	if err := a.dbs.Close(); err != nil {
+		a.logger.Error("Failed to close database", "error", err)
 		return err
 	}
 
-	observability.Info("AgentChassis shutdown complete")
+	a.logger.Info("AgentChassis shutdown complete")
 	return nil
 }
 
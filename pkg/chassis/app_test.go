@@ -0,0 +1,101 @@
+package chassis
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/scheduler"
+)
+
+func TestApp_MCPMode(t *testing.T) {
+	app := New(
+		WithMCPMode(),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		WithDatabasePath(filepath.Join(t.TempDir(), "data.db")),
+		WithLLMConfig(llm.Config{Provider: "openai", APIKey: "test", BaseURL: "http://127.0.0.1:1", Model: "test-model"}),
+		// 不是 MCP 模式时创建 Bot 需要访问 Telegram 服务，初始化会失败
+		WithTelegram(TelegramConfig{Enabled: true, Token: "123:invalid"}),
+	)
+	if err := app.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	defer app.Shutdown()
+
+	if app.GetDelayScheduler() != nil || app.GetCronScheduler() != nil {
+		t.Error("schedulers started in MCP mode")
+	}
+	if app.GetTelegramBot() != nil {
+		t.Error("telegram bot started in MCP mode")
+	}
+	for _, name := range []string{"delay_create", "cron_create"} {
+		if _, ok := app.GetRegistry().Get(name); ok {
+			t.Errorf("%s registered without a scheduler", name)
+		}
+	}
+	for _, name := range []string{"send_message", "remember"} {
+		if _, ok := app.GetRegistry().Get(name); !ok {
+			t.Errorf("%s not registered in MCP mode", name)
+		}
+	}
+}
+
+func TestApp_CronMisfireOnStartup(t *testing.T) {
+	llmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"done"}}]}`))
+	}))
+	defer llmServer.Close()
+	dbPath := filepath.Join(t.TempDir(), "data.db")
+	newApp := func() *App {
+		app := New(
+			WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			WithDatabasePath(dbPath),
+			WithLLMConfig(llm.Config{Provider: "openai", APIKey: "test", BaseURL: llmServer.URL, Model: "test-model", Timeout: 5}),
+		)
+		if err := app.Initialize(); err != nil {
+			t.Fatalf("Initialize: %v", err)
+		}
+		return app
+	}
+
+	// 第一次运行创建任务，并模拟停机期间错过了一次触发
+	app := newApp()
+	task, err := app.GetCronScheduler().CreateTaskWithOptions("report", "0 0 * * * *", "汇总日报", "", scheduler.CronTaskOptions{
+		MisfirePolicy: scheduler.MisfireRunOnce,
+	})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	if err := app.GetCronScheduler().GetTaskRepository().UpdateNextRunAt(task.ID, time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	app.Shutdown()
+
+	// 按 App 的启动顺序重启：补偿执行时 AgentExecutor 已经注入
+	app = newApp()
+	defer app.Shutdown()
+	repo := app.GetCronScheduler().GetExecutionRepository()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		execs, err := repo.ListByTaskID(task.ID, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(execs) > 0 && execs[0].Status != scheduler.CronStatusRunning {
+			if execs[0].Status != scheduler.CronStatusCompleted || !execs[0].Misfire {
+				t.Errorf("misfire execution = %+v, want a completed misfire run", execs[0])
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("missed run was not executed after restart")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,271 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/approval"
+	"github.com/KodaTao/AgentChassis/pkg/audit"
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/memory"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/protocol"
+	"github.com/KodaTao/AgentChassis/pkg/scheduler"
+	"github.com/KodaTao/AgentChassis/pkg/types"
+)
+
+// ErrSessionAwaitingApproval 会话中有函数调用等待管理员审批
+var ErrSessionAwaitingApproval = types.ErrSessionAwaitingApproval
+
+// ErrApprovalNotConfigured 函数需要审批但 Agent 没有设置审批记录仓库，调用不会执行
+var ErrApprovalNotConfigured = errors.New("approval workflow is not configured")
+
+// approvalPendingMessage 调用等待审批时反馈给 AI 的说明
+const approvalPendingMessage = "This call requires administrator approval and has NOT been executed yet. Do not call it again or try to work around it; tell the user it is awaiting approval."
+
+// approvalResumePrompt 批准并执行后恢复对话时发给 AI 的提示
+const approvalResumePrompt = "[System notice] The administrator approved the pending %s call and it has been executed; the result is above. Continue with the user's request and report the outcome."
+
+// SetApprovalRepository 设置审批记录仓库，未设置时需要审批的函数调用会直接失败
+func (a *Agent) SetApprovalRepository(repo *approval.Repository) {
+	a.approvalRepo = repo
+}
+
+// needsApproval 该函数是否需要管理员审批：配置在 ApprovalFunctions 中，或函数声明了 RequiresApproval
+// 与 Executor 的判断一致，Executor 会拒绝执行未经批准的这类调用
+func (a *Agent) needsApproval(name string) bool {
+	return a.executor.RequiresApproval(name)
+}
+
+// requestApproval 持久化审批请求并暂停会话，返回交给调用方展示的审批信息
+func (a *Agent) requestApproval(ctx context.Context, session *Session, userID string, channel *ChannelContext, trace callTrace, call *protocol.CallRequest) (*PendingCall, error) {
+	if a.approvalRepo == nil {
+		return nil, ErrApprovalNotConfigured
+	}
+
+	params, err := json.Marshal(call.Params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode params: %w", err)
+	}
+	var channelJSON []byte
+	if channel != nil {
+		if channelJSON, err = json.Marshal(channel); err != nil {
+			return nil, fmt.Errorf("failed to encode channel: %w", err)
+		}
+	}
+
+	req := &approval.Request{
+		ID:           newPendingCallID(),
+		SessionID:    session.ID,
+		RequestID:    trace.RequestID,
+		CallID:       trace.CallID,
+		Iteration:    trace.Iteration,
+		CallIndex:    trace.Index,
+		UserID:       userID,
+		Channel:      string(channelJSON),
+		FunctionName: call.Name,
+		Params:       string(params),
+		Data:         call.Data,
+		CallerScopes: scheduler.EncodeCallerScopes(function.CallerScopesFromContext(ctx)),
+	}
+	if err := a.approvalRepo.Create(req); err != nil {
+		return nil, err
+	}
+	a.activeMu.Lock()
+	session.PendingApproval = req.ID
+	a.activeMu.Unlock()
+
+	observability.InfoContext(ctx, "Function call awaiting approval",
+		"approval_id", req.ID,
+		"name", call.Name,
+		"call_id", trace.CallID,
+	)
+	a.emitApprovalEvent(req)
+
+	return &PendingCall{
+		ID:     req.ID,
+		Name:   call.Name,
+		Params: call.Params,
+	}, nil
+}
+
+// DecideApproval 批准或拒绝审批请求
+// 批准时执行该调用，把结果写入会话并恢复对话，返回 AI 继续处理后的回复；
+// 拒绝时不执行，把拒绝原因写入会话并恢复会话。会话已不存在（如服务重启）时批准仍会执行函数。
+// 执行与恢复的对话都以发起调用者的作用域进行，审批人只提供 decidedBy，其作用域不会带入会话
+func (a *Agent) DecideApproval(ctx context.Context, id string, approved bool, decidedBy, reason string) (*ChatResponse, error) {
+	ctx = a.logContext(ctx)
+	start := time.Now()
+	if a.approvalRepo == nil {
+		return nil, ErrApprovalNotConfigured
+	}
+
+	status := approval.StatusRejected
+	if approved {
+		status = approval.StatusApproved
+	}
+	req, err := a.approvalRepo.Decide(id, status, decidedBy, reason)
+	if err != nil {
+		return nil, err
+	}
+	a.emitApprovalEvent(req)
+
+	var params map[string]string
+	if err := json.Unmarshal([]byte(req.Params), &params); err != nil {
+		return nil, fmt.Errorf("failed to decode params of approval request %s: %w", id, err)
+	}
+	var channel *ChannelContext
+	if req.Channel != "" {
+		channel = &ChannelContext{}
+		if err := json.Unmarshal([]byte(req.Channel), channel); err != nil {
+			return nil, fmt.Errorf("failed to decode channel of approval request %s: %w", id, err)
+		}
+	}
+
+	// 审批后的执行仍归属于发起它的那次请求，恢复的对话则是一次新请求
+	// 两者都不随审批请求取消，并换回发起者的作用域
+	ctx = requesterContext(ctx, req.CallerScopes)
+	resumeCtx := ctx
+	trace := callTrace{RequestID: req.RequestID, CallID: req.CallID, Iteration: req.Iteration, Index: req.CallIndex}
+	ctx = WithSessionID(ctx, req.SessionID)
+	ctx = WithRequestID(ctx, req.RequestID)
+	ctx = observability.WithLogFields(ctx, "request_id", req.RequestID, "approval_id", id)
+	if channel != nil {
+		ctx = types.WithChannel(ctx, channel)
+	}
+	if req.UserID != "" {
+		ctx = memory.WithUserID(ctx, req.UserID)
+	}
+
+	// 审批结果写入会话之后才恢复会话，期间到达的新消息仍返回 ErrSessionAwaitingApproval
+	session := a.sessionManager.Get(req.SessionID)
+
+	verb := "reject"
+	if approved {
+		verb = "approve"
+	}
+	record := &audit.Record{
+		Action:    audit.ActionApproval,
+		RequestID: req.RequestID,
+		SessionID: req.SessionID,
+		UserID:    req.UserID,
+		Input:     fmt.Sprintf("%s %s (approval %s, call %s, decided by %s)", verb, req.FunctionName, id, req.CallID, decidedBy),
+	}
+	if reason != "" {
+		record.Input += ": " + reason
+	}
+	if channel != nil {
+		record.Channel = channel.Type
+	}
+
+	if !approved {
+		observability.InfoContext(ctx, "Function call rejected", "name", req.FunctionName, "decided_by", decidedBy)
+		message := "the administrator rejected this call; it was not executed"
+		if reason != "" {
+			message += ": " + reason
+		}
+		if session != nil {
+			session.AddMessage(llm.RoleTool, a.encoder.EncodeError(req.FunctionName, message))
+			a.sessionManager.Save(session)
+			a.clearPendingApproval(session, id)
+		}
+		reply := fmt.Sprintf("已拒绝：%s 未执行。", req.FunctionName)
+		if reason != "" {
+			reply = fmt.Sprintf("%s原因：%s", reply, reason)
+		}
+		resp := &ChatResponse{
+			SessionID: req.SessionID,
+			RequestID: req.RequestID,
+			Reply:     reply,
+			Cancelled: true,
+		}
+		a.auditDecision(ctx, record, resp, nil, start)
+		return resp, nil
+	}
+
+	observability.InfoContext(ctx, "Function call approved", "name", req.FunctionName, "decided_by", decidedBy)
+	call := &protocol.CallRequest{Name: req.FunctionName, Params: params, Data: req.Data}
+	fc, resultStr := a.runDeferredCall(function.WithApproved(ctx), req.SessionID, trace, call)
+	if err := a.approvalRepo.SetResult(id, fc.Status+": "+fc.Result); err != nil {
+		observability.WarnContext(ctx, "Failed to record approval result", "error", err)
+	}
+
+	reply := fc.Result
+	if fc.Status == "error" {
+		reply = fmt.Sprintf("执行 %s 失败：%s", req.FunctionName, fc.Result)
+	}
+	resp := &ChatResponse{
+		SessionID:     req.SessionID,
+		RequestID:     req.RequestID,
+		Reply:         reply,
+		FunctionCalls: []FunctionCall{fc},
+	}
+	a.auditDecision(ctx, record, resp, nil, start)
+	if session == nil {
+		return resp, nil
+	}
+
+	// 写入执行结果后恢复对话，由 AI 继续完成用户的请求并说明结果
+	session.AddMessage(llm.RoleTool, resultStr)
+	a.clearPendingApproval(session, id)
+	resumed, err := a.Chat(resumeCtx, ChatRequest{
+		SessionID: req.SessionID,
+		Message:   fmt.Sprintf(approvalResumePrompt, req.FunctionName),
+		Channel:   channel,
+		UserID:    req.UserID,
+	})
+	if err != nil {
+		observability.WarnContext(ctx, "Failed to resume chat after approval", "error", err)
+		return resp, nil
+	}
+	resumed.FunctionCalls = append([]FunctionCall{fc}, resumed.FunctionCalls...)
+	return resumed, nil
+}
+
+// requesterContext 返回以发起调用者作用域执行的 context，丢弃审批人的作用域
+// scopes 为空表示调用由未鉴权的内部请求发起，按内部调用处理
+func requesterContext(ctx context.Context, scopes string) context.Context {
+	ctx = context.WithoutCancel(ctx)
+	if requesterScopes := scheduler.ParseCallerScopes(scopes); requesterScopes != nil {
+		return function.WithCallerScopes(ctx, requesterScopes)
+	}
+	return function.WithoutCallerScopes(ctx)
+}
+
+// pendingApproval 返回会话等待中的审批请求 ID，与暂停、恢复会话一样持有 activeMu
+func (a *Agent) pendingApproval(session *Session) string {
+	a.activeMu.Lock()
+	defer a.activeMu.Unlock()
+	return session.PendingApproval
+}
+
+// clearPendingApproval 审批请求 id 已处理，恢复暂停的会话
+func (a *Agent) clearPendingApproval(session *Session, id string) {
+	a.activeMu.Lock()
+	defer a.activeMu.Unlock()
+	if session.PendingApproval == id {
+		session.PendingApproval = ""
+	}
+}
+
+// emitApprovalEvent 输出审批请求状态变化的结构化事件，可据此通知管理员
+func (a *Agent) emitApprovalEvent(req *approval.Request) {
+	a.events.Emit(observability.Event{
+		Kind:      observability.EventApproval,
+		SessionID: req.SessionID,
+		Name:      req.FunctionName,
+		Status:    req.Status,
+		Attributes: map[string]any{
+			"approval_id": req.ID,
+			"request_id":  req.RequestID,
+			"call_id":     req.CallID,
+			"user_id":     req.UserID,
+			"decided_by":  req.DecidedBy,
+		},
+	})
+}
@@ -0,0 +1,198 @@
+package chassis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/KodaTao/AgentChassis/pkg/approval"
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+// countingFunction 记录执行次数的测试函数
+type countingFunction struct {
+	name  string
+	calls atomic.Int32
+}
+
+func (f *countingFunction) Name() string             { return f.name }
+func (f *countingFunction) Description() string      { return "test function " + f.name }
+func (f *countingFunction) ParamsType() reflect.Type { return nil }
+func (f *countingFunction) Execute(ctx context.Context, params any) (function.Result, error) {
+	f.calls.Add(1)
+	return function.Result{Message: "sent"}, nil
+}
+
+// newApprovalAgent 创建 transfer 需要审批、审批记录写入内存数据库的 Agent
+func newApprovalAgent(t *testing.T, provider *fakeProvider) (*Agent, *approval.Repository, *countingFunction) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := approval.NewRepository(db)
+	if err := repo.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	fn := &countingFunction{name: "transfer"}
+	registry := function.NewRegistry()
+	if err := registry.Register(fn); err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultAgentConfig()
+	config.ApprovalFunctions = []string{"transfer"}
+	agent := NewAgent(provider, registry, config)
+	agent.SetApprovalRepository(repo)
+	return agent, repo, fn
+}
+
+// requestApprovalInSession 让 AI 在会话 s1 中调用 transfer，返回审批请求 ID
+func requestApprovalInSession(t *testing.T, agent *Agent, fn *countingFunction) string {
+	t.Helper()
+	resp, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "send 10 to bob"})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Approval == nil {
+		t.Fatalf("expected an approval request, got %+v", resp)
+	}
+	if n := fn.calls.Load(); n != 0 {
+		t.Fatalf("transfer executed %d times before approval", n)
+	}
+
+	// 审批完成前会话暂停
+	if _, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "hurry up"}); !errors.Is(err, ErrSessionAwaitingApproval) {
+		t.Fatalf("Chat on paused session error = %v, want ErrSessionAwaitingApproval", err)
+	}
+	return resp.Approval.ID
+}
+
+func TestAgent_DecideApproval_Approve(t *testing.T) {
+	provider := &fakeProvider{name: "main", reply: "ok", replies: []string{`<call name="transfer"></call>`, "done: sent 10 to bob"}}
+	agent, repo, fn := newApprovalAgent(t, provider)
+	id := requestApprovalInSession(t, agent, fn)
+
+	resp, err := agent.DecideApproval(context.Background(), id, true, "admin", "")
+	if err != nil {
+		t.Fatalf("DecideApproval: %v", err)
+	}
+	if n := fn.calls.Load(); n != 1 {
+		t.Errorf("transfer executed %d times, want 1", n)
+	}
+	// 批准后恢复对话，返回 AI 继续处理后的回复
+	if resp.Reply != "done: sent 10 to bob" || len(resp.FunctionCalls) != 1 || resp.FunctionCalls[0].Status != "success" {
+		t.Errorf("resumed response = %+v", resp)
+	}
+
+	req, err := repo.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Status != approval.StatusApproved || req.DecidedBy != "admin" || req.Result != "success: sent" {
+		t.Errorf("approval request = %+v", req)
+	}
+
+	if _, err := agent.DecideApproval(context.Background(), id, false, "admin", ""); !errors.Is(err, approval.ErrAlreadyDecided) {
+		t.Errorf("second DecideApproval error = %v, want ErrAlreadyDecided", err)
+	}
+	if n := fn.calls.Load(); n != 1 {
+		t.Errorf("transfer executed %d times after second decision, want 1", n)
+	}
+
+	// 会话已恢复
+	if _, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "thanks"}); err != nil {
+		t.Errorf("Chat after approval: %v", err)
+	}
+}
+
+func TestAgent_DecideApproval_Reject(t *testing.T) {
+	provider := &fakeProvider{name: "main", reply: "ok", replies: []string{`<call name="transfer"></call>`}}
+	agent, repo, fn := newApprovalAgent(t, provider)
+	id := requestApprovalInSession(t, agent, fn)
+
+	resp, err := agent.DecideApproval(context.Background(), id, false, "admin", "over the limit")
+	if err != nil {
+		t.Fatalf("DecideApproval: %v", err)
+	}
+	if fn.calls.Load() != 0 {
+		t.Error("rejected call was executed")
+	}
+	if !resp.Cancelled || !strings.Contains(resp.Reply, "over the limit") {
+		t.Errorf("response = %+v", resp)
+	}
+
+	// 拒绝原因作为函数结果写入会话，AI 在后续对话中可以看到
+	messages := agent.sessionManager.Get("s1").GetMessages()
+	last := messages[len(messages)-1]
+	if last.Role != llm.RoleTool || !strings.Contains(last.Content, "over the limit") {
+		t.Errorf("last session message = %+v", last)
+	}
+
+	if req, err := repo.Get(id); err != nil || req.Status != approval.StatusRejected || req.Reason != "over the limit" {
+		t.Errorf("approval request = %+v, %v", req, err)
+	}
+	if _, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "ok then"}); err != nil {
+		t.Errorf("Chat after rejection: %v", err)
+	}
+}
+
+// adminFunction 需要 admin 作用域的测试函数
+type adminFunction struct {
+	countingFunction
+}
+
+func (f *adminFunction) Scopes() []string { return []string{"admin"} }
+
+func TestAgent_DecideApproval_KeepsRequesterScopes(t *testing.T) {
+	provider := &fakeProvider{name: "main", reply: "ok", replies: []string{`<call name="transfer"></call>`, `<call name="wipe"></call>`, "done"}}
+	agent, repo, fn := newApprovalAgent(t, provider)
+	wipe := &adminFunction{countingFunction{name: "wipe"}}
+	if err := agent.registry.Register(wipe); err != nil {
+		t.Fatal(err)
+	}
+
+	userCtx := function.WithCallerScopes(context.Background(), []string{"user"})
+	resp, err := agent.Chat(userCtx, ChatRequest{SessionID: "s1", Message: "send 10 to bob"})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Approval == nil {
+		t.Fatalf("expected an approval request, got %+v", resp)
+	}
+	if req, err := repo.Get(resp.Approval.ID); err != nil || req.CallerScopes != `["user"]` {
+		t.Fatalf("approval request = %+v, %v", req, err)
+	}
+
+	// 审批人拥有 admin 作用域，恢复的对话仍只能使用发起者的作用域
+	adminCtx := function.WithCallerScopes(context.Background(), []string{"admin"})
+	resumed, err := agent.DecideApproval(adminCtx, resp.Approval.ID, true, "admin", "")
+	if err != nil {
+		t.Fatalf("DecideApproval: %v", err)
+	}
+	if n := fn.calls.Load(); n != 1 {
+		t.Errorf("transfer executed %d times, want 1", n)
+	}
+	if n := wipe.calls.Load(); n != 0 {
+		t.Errorf("admin-scoped wipe executed %d times in the resumed session", n)
+	}
+	if len(resumed.FunctionCalls) != 2 || resumed.FunctionCalls[1].Name != "wipe" || resumed.FunctionCalls[1].Status != "error" {
+		t.Errorf("resumed function calls = %+v", resumed.FunctionCalls)
+	}
+
+	// 恢复对话的系统提示不包含发起者无权调用的函数
+	provider.mu.Lock()
+	system := provider.requests[len(provider.requests)-1][0]
+	provider.mu.Unlock()
+	if system.Role != llm.RoleSystem || strings.Contains(system.Content, "wipe") {
+		t.Errorf("resumed system prompt lists admin-scoped wipe")
+	}
+}
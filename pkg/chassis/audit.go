@@ -0,0 +1,126 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/audit"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/types"
+)
+
+// auditCleanupInterval 按保留期清理审计记录的间隔
+const auditCleanupInterval = time.Hour
+
+// auditedCall 审计记录中的函数调用，不含附件内容等大字段
+type auditedCall struct {
+	Name   string `json:"name"`
+	CallID string `json:"call_id,omitempty"`
+	Status string `json:"status"`
+	Result string `json:"result,omitempty"`
+}
+
+// SetAuditRepository 设置审计记录仓库，设置后每次 Chat 都会落一条审计记录
+func (a *Agent) SetAuditRepository(repo *audit.Repository) {
+	a.auditRepo = repo
+}
+
+// recordAudit 异步写入一次对话的审计记录，不阻塞对话
+// 调用方身份取自 ctx 中经过鉴权的身份（types.WithCaller），请求中的 user_id 只作为用户标识记录
+func (a *Agent) recordAudit(ctx context.Context, requestID string, req ChatRequest, resp *ChatResponse, err error, usage llm.Usage, start time.Time) {
+	record := &audit.Record{
+		Action:           audit.ActionChat,
+		RequestID:        requestID,
+		SessionID:        req.SessionID,
+		UserID:           requestUserID(req),
+		Input:            req.Message,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+	if req.Channel != nil {
+		record.Channel = req.Channel.Type
+	}
+	a.writeAudit(ctx, record, resp, err, start)
+}
+
+// auditDecision 异步写入确认或审批挂起调用的审计记录，record 中已填好操作类型、请求和会话等字段
+// 只记录被确认或审批的这次调用及其结果，审批后恢复的对话另有一条 chat 记录
+func (a *Agent) auditDecision(ctx context.Context, record *audit.Record, resp *ChatResponse, err error, start time.Time) {
+	if a.auditRepo == nil {
+		return
+	}
+	a.writeAudit(ctx, record, resp, err, start)
+}
+
+// writeAudit 补全调用方、结果、状态和耗时后异步写入审计记录，关闭数据库前通过 WaitWrites 等待写入完成
+func (a *Agent) writeAudit(ctx context.Context, record *audit.Record, resp *ChatResponse, err error, start time.Time) {
+	record.Caller = types.CallerFromContext(ctx)
+	record.Status = audit.StatusSuccess
+	record.DurationMs = time.Since(start).Milliseconds()
+	record.CreatedAt = start
+
+	switch {
+	case err != nil:
+		record.Status = audit.StatusError
+		record.Error = err.Error()
+	case resp.Cancelled:
+		record.Status = audit.StatusCancelled
+	case resp.Pending != nil || resp.Approval != nil:
+		record.Status = audit.StatusPending
+	}
+	if resp != nil {
+		record.SessionID = resp.SessionID
+		record.Output = resp.Reply
+		if len(resp.FunctionCalls) > 0 {
+			calls := make([]auditedCall, 0, len(resp.FunctionCalls))
+			for _, fc := range resp.FunctionCalls {
+				calls = append(calls, auditedCall{Name: fc.Name, CallID: fc.CallID, Status: fc.Status, Result: fc.Result})
+			}
+			if b, err := json.Marshal(calls); err == nil {
+				record.FunctionCalls = string(b)
+			}
+		}
+	}
+
+	if a.config.AuditMaskPII {
+		record.Mask()
+	}
+
+	a.writes.Add(1)
+	go func() {
+		defer a.writes.Done()
+		if err := a.auditRepo.Create(record); err != nil {
+			a.log().Warn("Failed to record audit entry", "request_id", record.RequestID, "error", err)
+		}
+	}()
+}
+
+// runAuditRetention 定期删除超过保留期的审计记录，直到 stop 关闭
+func runAuditRetention(repo *audit.Repository, logger *slog.Logger, retention time.Duration, stop <-chan struct{}) {
+	cleanup := func() {
+		deleted, err := repo.DeleteBefore(time.Now().Add(-retention))
+		if err != nil {
+			logger.Warn("Failed to clean up audit records", "error", err)
+			return
+		}
+		if deleted > 0 {
+			logger.Info("Expired audit records deleted", "count", deleted, "retention", retention)
+		}
+	}
+
+	cleanup()
+	ticker := time.NewTicker(auditCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			cleanup()
+		case <-stop:
+			return
+		}
+	}
+}
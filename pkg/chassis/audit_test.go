@@ -0,0 +1,116 @@
+package chassis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/KodaTao/AgentChassis/pkg/audit"
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/types"
+)
+
+// staticFunction 返回固定结果的测试函数
+type staticFunction struct {
+	name   string
+	result string
+}
+
+func (f *staticFunction) Name() string             { return f.name }
+func (f *staticFunction) Description() string      { return "test function " + f.name }
+func (f *staticFunction) ParamsType() reflect.Type { return nil }
+func (f *staticFunction) Execute(ctx context.Context, params any) (function.Result, error) {
+	return function.Result{Message: f.result}, nil
+}
+
+// newAuditAgent 创建写入内存数据库审计记录的 Agent
+func newAuditAgent(t *testing.T, provider *fakeProvider, fns ...function.Function) (*Agent, *audit.Repository) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := audit.NewRepository(db)
+	if err := repo.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := function.NewRegistry()
+	for _, fn := range fns {
+		if err := registry.Register(fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := DefaultAgentConfig()
+	config.ConfirmFunctions = []string{"transfer"}
+	config.AuditMaskPII = true
+	agent := NewAgent(provider, registry, config)
+	agent.SetAuditRepository(repo)
+	return agent, repo
+}
+
+func TestAgent_AuditChat(t *testing.T) {
+	agent, repo := newAuditAgent(t, &fakeProvider{name: "main", reply: "noted"})
+
+	// 请求体中的 user_id 可以随意填写，审计的调用方身份只取自 context
+	ctx := types.WithCaller(context.Background(), "api_key:web")
+	_, err := agent.Chat(ctx, ChatRequest{SessionID: "s1", UserID: "admin", Message: "my email is alice@example.com"})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	agent.WaitWrites()
+
+	records, err := repo.List(audit.Filter{}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d audit records, want 1 (WaitWrites should drain pending writes)", len(records))
+	}
+	r := records[0]
+	if r.Action != audit.ActionChat || r.Caller != "api_key:web" || r.UserID != "admin" || r.Status != audit.StatusSuccess {
+		t.Errorf("record = %+v", r)
+	}
+	if !r.Masked || r.Input != "my email is a****@example.com" || r.Output != "noted" {
+		t.Errorf("input = %q, output = %q, masked = %v", r.Input, r.Output, r.Masked)
+	}
+}
+
+func TestAgent_AuditConfirmCall(t *testing.T) {
+	provider := &fakeProvider{name: "main", reply: `<call name="transfer"></call>`}
+	agent, repo := newAuditAgent(t, provider, &staticFunction{name: "transfer", result: "sent"})
+
+	resp, err := agent.Chat(types.WithCaller(context.Background(), "telegram:1"), ChatRequest{
+		SessionID: "s1", Message: "send it", ConfirmCalls: true,
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Pending == nil {
+		t.Fatalf("expected a pending call, got %+v", resp)
+	}
+
+	if _, err := agent.ConfirmCall(types.WithCaller(context.Background(), "telegram:2"), "s1", resp.Pending.ID, true); err != nil {
+		t.Fatalf("ConfirmCall: %v", err)
+	}
+	agent.WaitWrites()
+
+	records, err := repo.List(audit.Filter{Action: audit.ActionConfirm}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d confirm records, want 1", len(records))
+	}
+	r := records[0]
+	if r.Caller != "telegram:2" || r.RequestID != resp.RequestID || r.SessionID != "s1" || r.Status != audit.StatusSuccess {
+		t.Errorf("record = %+v", r)
+	}
+	if r.Input != "confirm transfer (call "+resp.RequestID+".1.1)" || r.Output != "sent" || r.FunctionCalls == "" {
+		t.Errorf("input = %q, output = %q, calls = %q", r.Input, r.Output, r.FunctionCalls)
+	}
+}
@@ -0,0 +1,108 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"fmt"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+)
+
+// 内置函数分组
+const (
+	BuiltinGroupMessaging = "messaging" // send_message
+	BuiltinGroupDelay     = "delay"     // delay_*
+	BuiltinGroupCron      = "cron"      // cron_*
+	BuiltinGroupScheduler = "scheduler" // delay + cron
+	BuiltinGroupMemory    = "memory"    // remember、recall
+	BuiltinGroupTasks     = "tasks"     // check_task_status
+)
+
+// builtinGroups 分组 -> 包含的内置函数
+var builtinGroups = map[string][]string{
+	BuiltinGroupMessaging: {"send_message"},
+	BuiltinGroupDelay:     {"delay_create", "delay_list", "delay_cancel", "delay_get"},
+	BuiltinGroupCron:      {"cron_create", "cron_list", "cron_delete", "cron_get", "cron_history"},
+	BuiltinGroupMemory:    {"remember", "recall"},
+	BuiltinGroupTasks:     {"check_task_status"},
+}
+
+func init() {
+	builtinGroups[BuiltinGroupScheduler] = append(
+		append([]string(nil), builtinGroups[BuiltinGroupDelay]...),
+		builtinGroups[BuiltinGroupCron]...,
+	)
+}
+
+// BuiltinsConfig 内置函数配置，用于裁剪暴露给 AI 的能力
+// 列表项可以是分组名（messaging、delay、cron、scheduler、memory、tasks）或单个函数名
+type BuiltinsConfig struct {
+	// Enabled 启用的内置函数，为空时全部启用
+	Enabled []string `mapstructure:"enabled"`
+
+	// Disabled 禁用的内置函数，在 Enabled 之后生效
+	Disabled []string `mapstructure:"disabled"`
+}
+
+// expand 将分组名和函数名展开为函数名集合，遇到未知名称时返回错误
+func (c BuiltinsConfig) expand(names []string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	for _, name := range names {
+		if fns, ok := builtinGroups[name]; ok {
+			for _, fn := range fns {
+				set[fn] = true
+			}
+			continue
+		}
+		if !isBuiltinFunction(name) {
+			return nil, fmt.Errorf("unknown builtin function or group: %s", name)
+		}
+		set[name] = true
+	}
+	return set, nil
+}
+
+// Validate 检查配置中的分组名和函数名
+func (c BuiltinsConfig) Validate() error {
+	if _, err := c.expand(c.Enabled); err != nil {
+		return err
+	}
+	_, err := c.expand(c.Disabled)
+	return err
+}
+
+// IsEnabled 判断内置函数是否启用，配置无效时按全部启用处理（Initialize 会先校验）
+func (c BuiltinsConfig) IsEnabled(name string) bool {
+	if len(c.Enabled) > 0 {
+		enabled, err := c.expand(c.Enabled)
+		if err == nil && !enabled[name] {
+			return false
+		}
+	}
+	disabled, err := c.expand(c.Disabled)
+	return err != nil || !disabled[name]
+}
+
+// isBuiltinFunction 是否为内置函数名
+func isBuiltinFunction(name string) bool {
+	for group, fns := range builtinGroups {
+		if group == BuiltinGroupScheduler {
+			continue
+		}
+		for _, fn := range fns {
+			if fn == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// registerBuiltin 按配置注册内置函数，返回是否已注册
+func (a *App) registerBuiltin(fn function.Function) bool {
+	if !a.config.Builtins.IsEnabled(fn.Name()) {
+		a.logger.Info("Builtin function disabled by config", "name", fn.Name())
+		return false
+	}
+	_ = a.registry.Register(fn)
+	return true
+}
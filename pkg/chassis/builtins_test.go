@@ -0,0 +1,72 @@
+package chassis
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+func TestBuiltinsConfig_IsEnabled(t *testing.T) {
+	tests := []struct {
+		name   string
+		config BuiltinsConfig
+		fn     string
+		want   bool
+	}{
+		{name: "empty enables all", config: BuiltinsConfig{}, fn: "cron_create", want: true},
+		{name: "group enabled", config: BuiltinsConfig{Enabled: []string{"delay"}}, fn: "delay_get", want: true},
+		{name: "other group not enabled", config: BuiltinsConfig{Enabled: []string{"delay"}}, fn: "cron_get", want: false},
+		{name: "scheduler covers cron", config: BuiltinsConfig{Enabled: []string{"scheduler"}}, fn: "cron_history", want: true},
+		{name: "single function enabled", config: BuiltinsConfig{Enabled: []string{"send_message"}}, fn: "send_message", want: true},
+		{name: "disabled after enabled", config: BuiltinsConfig{Enabled: []string{"scheduler"}, Disabled: []string{"delay_cancel"}}, fn: "delay_cancel", want: false},
+		{name: "disabled group", config: BuiltinsConfig{Disabled: []string{"memory"}}, fn: "recall", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.IsEnabled(tt.fn); got != tt.want {
+				t.Errorf("IsEnabled(%q) = %v, want %v", tt.fn, got, tt.want)
+			}
+		})
+	}
+
+	if err := (BuiltinsConfig{Enabled: []string{"delay", "bogus"}}).Validate(); err == nil {
+		t.Error("Validate accepted an unknown name")
+	}
+}
+
+func TestApp_Builtins(t *testing.T) {
+	newApp := func(cfg BuiltinsConfig) *App {
+		return New(
+			WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			WithDatabasePath(filepath.Join(t.TempDir(), "data.db")),
+			WithLLMConfig(llm.Config{Provider: "openai", APIKey: "test", BaseURL: "http://127.0.0.1:1", Model: "test-model"}),
+			WithBuiltins(cfg),
+		)
+	}
+
+	app := newApp(BuiltinsConfig{Enabled: []string{"messaging", "delay"}, Disabled: []string{"delay_cancel"}})
+	if err := app.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	defer app.Shutdown()
+
+	for name, want := range map[string]bool{
+		"send_message": true,
+		"delay_create": true,
+		"delay_cancel": false,
+		"cron_create":  false,
+		"remember":     false,
+	} {
+		if _, ok := app.GetRegistry().Get(name); ok != want {
+			t.Errorf("%s registered = %v, want %v", name, ok, want)
+		}
+	}
+
+	if err := newApp(BuiltinsConfig{Disabled: []string{"bogus"}}).Initialize(); err == nil {
+		t.Error("Initialize accepted an unknown builtin name")
+	}
+}
@@ -0,0 +1,60 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrChatCancelled 对话被 CancelSession 主动取消
+var ErrChatCancelled = errors.New("chat cancelled")
+
+// cancelledReply 对话被取消时返回的回复
+const cancelledReply = "已取消：请求在完成前被停止。"
+
+// activeChat 正在进行中的对话
+type activeChat struct {
+	cancel context.CancelCauseFunc
+}
+
+// beginChat 登记会话上正在进行的对话，返回可被 CancelSession 取消的 context
+// 对话结束后必须调用 done 注销
+func (a *Agent) beginChat(ctx context.Context, sessionID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	chat := &activeChat{cancel: cancel}
+
+	a.activeMu.Lock()
+	a.active[sessionID] = chat
+	a.activeMu.Unlock()
+
+	return ctx, func() {
+		a.activeMu.Lock()
+		// 同一会话可能已有更新的对话登记，只注销自己
+		if a.active[sessionID] == chat {
+			delete(a.active, sessionID)
+		}
+		a.activeMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// CancelSession 取消会话上正在进行的对话
+// 当前的 LLM 请求和函数执行通过 context 终止，Chat 返回 Cancelled 为 true 的响应
+// 会话没有正在进行的对话时返回 false
+func (a *Agent) CancelSession(sessionID string) bool {
+	a.activeMu.Lock()
+	chat, ok := a.active[sessionID]
+	a.activeMu.Unlock()
+
+	if !ok {
+		return false
+	}
+	chat.cancel(ErrChatCancelled)
+	a.log().Info("Chat cancelled", "session_id", sessionID)
+	return true
+}
+
+// chatCancelled 对话是否已被 CancelSession 取消
+func chatCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrChatCancelled)
+}
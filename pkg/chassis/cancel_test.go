@@ -0,0 +1,95 @@
+package chassis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+// waitFunction 阻塞到 context 结束，记录看到的错误
+type waitFunction struct {
+	started chan struct{}
+	err     chan error
+}
+
+func (f *waitFunction) Name() string             { return "wait" }
+func (f *waitFunction) Description() string      { return "blocks until cancelled" }
+func (f *waitFunction) ParamsType() reflect.Type { return nil }
+func (f *waitFunction) Execute(ctx context.Context, params any) (function.Result, error) {
+	close(f.started)
+	<-ctx.Done()
+	f.err <- ctx.Err()
+	return function.Result{}, ctx.Err()
+}
+
+func TestAgent_CancelSession(t *testing.T) {
+	provider := &fakeProvider{name: "main", reply: "after", replies: []string{`<call name="wait"></call>`}}
+	fn := &waitFunction{started: make(chan struct{}), err: make(chan error, 1)}
+	agent := newTestAgent(t, provider, nil, fn)
+
+	if agent.CancelSession("s1") {
+		t.Fatal("CancelSession returned true without an active chat")
+	}
+
+	type result struct {
+		resp *ChatResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "wait for it"})
+		done <- result{resp, err}
+	}()
+
+	select {
+	case <-fn.started:
+	case <-time.After(time.Second):
+		t.Fatal("function did not start")
+	}
+	if !agent.CancelSession("s1") {
+		t.Fatal("CancelSession returned false for an in-flight chat")
+	}
+
+	// 正在执行的函数通过 context 终止
+	if err := <-fn.err; !errors.Is(err, context.Canceled) {
+		t.Errorf("function context error = %v, want context.Canceled", err)
+	}
+	r := <-done
+	if r.err != nil {
+		t.Fatalf("Chat: %v", r.err)
+	}
+	if !r.resp.Cancelled || r.resp.Reply != cancelledReply {
+		t.Errorf("response = %+v, want a cancelled response", r.resp)
+	}
+	if agent.CancelSession("s1") {
+		t.Error("chat still registered as active after it returned")
+	}
+
+	// 会话保持一致：调用和它的结果都在历史中，之后可以继续对话
+	roles := func(messages []llm.Message) []llm.Role {
+		var out []llm.Role
+		for _, m := range messages {
+			if m.Role != llm.RoleSystem {
+				out = append(out, m.Role)
+			}
+		}
+		return out
+	}
+	want := []llm.Role{llm.RoleUser, llm.RoleAssistant, llm.RoleTool}
+	if got := roles(agent.GetSession("s1").GetMessages()); !reflect.DeepEqual(got, want) {
+		t.Errorf("session roles = %v, want %v", got, want)
+	}
+
+	resp, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "again"})
+	if err != nil {
+		t.Fatalf("Chat after cancel: %v", err)
+	}
+	if resp.Cancelled || resp.Reply != "after" {
+		t.Errorf("response after cancel = %+v", resp)
+	}
+}
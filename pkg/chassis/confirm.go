@@ -0,0 +1,231 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/audit"
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/memory"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/protocol"
+	"github.com/KodaTao/AgentChassis/pkg/scheduler"
+	"github.com/KodaTao/AgentChassis/pkg/types"
+)
+
+// DefaultConfirmFunctions 默认需要用户确认后才执行的函数
+var DefaultConfirmFunctions = []string{"delay_create", "cron_create"}
+
+// PendingCallTTL 待确认调用的有效期，超时后无法再确认
+const PendingCallTTL = 30 * time.Minute
+
+// ErrPendingCallNotFound 待确认的调用不存在、已处理或已过期
+var ErrPendingCallNotFound = types.ErrPendingCallNotFound
+
+// pendingCallMessage 调用挂起时反馈给 AI 的说明
+const pendingCallMessage = "This call is awaiting the user's confirmation and has NOT been executed yet. Do not call it again; the outcome will be reported separately."
+
+// 类型别名，保持向后兼容
+type PendingCall = types.PendingCall
+
+// 确保 Agent 实现了 types.CallConfirmer 接口
+var _ types.CallConfirmer = (*Agent)(nil)
+
+// heldCall 挂起等待确认的函数调用
+type heldCall struct {
+	sessionID string
+	userID    string
+	channel   *ChannelContext
+	trace     callTrace
+	call      *protocol.CallRequest
+	createdAt time.Time
+}
+
+// needsConfirmation 本次请求中该函数是否需要先挂起等待用户确认
+// 只有渠道声明支持交互式确认（ChatRequest.ConfirmCalls）时才生效
+func (a *Agent) needsConfirmation(req ChatRequest, name string) bool {
+	if !req.ConfirmCalls {
+		return false
+	}
+	for _, n := range a.config.ConfirmFunctions {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmPrompt 渠道支持交互式确认时附加到系统提示的说明，没有需要确认的函数时返回空字符串
+// 告诉 AI 这些函数由渠道的按钮确认，信息完整时直接调用，不必先用文字询问
+func (a *Agent) confirmPrompt(req ChatRequest) string {
+	if !req.ConfirmCalls || len(a.config.ConfirmFunctions) == 0 {
+		return ""
+	}
+	return "## Confirmation\n\nThis channel confirms actions with buttons. Calls to " +
+		strings.Join(a.config.ConfirmFunctions, ", ") +
+		" are held until the user presses Confirm or Cancel. When the information is complete, call the function directly and summarize what it will do in your reply; do not ask the user to confirm in text first.\n"
+}
+
+// holdCall 挂起调用，返回交给渠道展示的待确认信息
+func (a *Agent) holdCall(sessionID, userID string, channel *ChannelContext, trace callTrace, call *protocol.CallRequest) *PendingCall {
+	id := newPendingCallID()
+
+	a.heldMu.Lock()
+	// 顺便清理过期的挂起调用，避免用户一直不点按钮导致堆积
+	for key, held := range a.held {
+		if time.Since(held.createdAt) > PendingCallTTL {
+			delete(a.held, key)
+		}
+	}
+	a.held[id] = &heldCall{
+		sessionID: sessionID,
+		userID:    userID,
+		channel:   channel,
+		trace:     trace,
+		call:      call,
+		createdAt: time.Now(),
+	}
+	a.heldMu.Unlock()
+
+	return &PendingCall{
+		ID:            id,
+		Name:          call.Name,
+		Params:        call.Params,
+		HumanReadable: scheduler.DescribeCron(call.Params["cron_expr"]),
+	}
+}
+
+// ConfirmCall 确认或拒绝挂起的函数调用
+// approved 为 true 时执行该调用并返回执行结果，否则不执行并返回 Cancelled 为 true 的响应；
+// 处理结果会作为函数结果追加到会话，AI 在后续对话中可以看到
+func (a *Agent) ConfirmCall(ctx context.Context, sessionID, callID string, approved bool) (*ChatResponse, error) {
+	ctx = a.logContext(ctx)
+	start := time.Now()
+	a.heldMu.Lock()
+	held, ok := a.held[callID]
+	if ok && held.sessionID == sessionID {
+		delete(a.held, callID)
+	}
+	a.heldMu.Unlock()
+
+	if !ok || held.sessionID != sessionID || time.Since(held.createdAt) > PendingCallTTL {
+		return nil, ErrPendingCallNotFound
+	}
+
+	// 确认后的执行仍归属于挂起它的那次请求
+	ctx = WithSessionID(ctx, sessionID)
+	ctx = WithRequestID(ctx, held.trace.RequestID)
+	ctx = observability.WithLogFields(ctx, "request_id", held.trace.RequestID)
+	if held.channel != nil {
+		ctx = types.WithChannel(ctx, held.channel)
+	}
+	if held.userID != "" {
+		ctx = memory.WithUserID(ctx, held.userID)
+	}
+	session := a.sessionManager.Get(sessionID)
+	name := held.call.Name
+
+	verb := "confirm"
+	if !approved {
+		verb = "decline"
+	}
+	record := &audit.Record{
+		Action:    audit.ActionConfirm,
+		RequestID: held.trace.RequestID,
+		SessionID: sessionID,
+		UserID:    held.userID,
+		Input:     fmt.Sprintf("%s %s (call %s)", verb, name, held.trace.CallID),
+	}
+	if held.channel != nil {
+		record.Channel = held.channel.Type
+	}
+
+	if !approved {
+		observability.InfoContext(ctx, "Pending call declined", "name", name)
+		if session != nil {
+			session.AddMessage(llm.RoleTool, a.encoder.EncodeError(name, "the user declined this call; it was not executed"))
+			a.sessionManager.Save(session)
+		}
+		resp := &ChatResponse{
+			SessionID: sessionID,
+			Reply:     fmt.Sprintf("已取消：%s 未执行。", name),
+			Cancelled: true,
+		}
+		a.auditDecision(ctx, record, resp, nil, start)
+		return resp, nil
+	}
+
+	observability.InfoContext(ctx, "Pending call confirmed", "name", name)
+	fc, resultStr := a.runDeferredCall(ctx, sessionID, held.trace, held.call)
+
+	if session != nil {
+		session.AddMessage(llm.RoleTool, resultStr)
+		a.truncateHistory(session)
+		a.sessionManager.Save(session)
+	}
+
+	reply := fc.Result
+	if fc.Status == "error" {
+		reply = fmt.Sprintf("执行 %s 失败：%s", name, fc.Result)
+	}
+	resp := &ChatResponse{
+		SessionID:     sessionID,
+		RequestID:     held.trace.RequestID,
+		Reply:         reply,
+		FunctionCalls: []FunctionCall{fc},
+	}
+	a.auditDecision(ctx, record, resp, nil, start)
+	return resp, nil
+}
+
+// runDeferredCall 执行一个先前被挂起的调用（用户确认或管理员审批后），返回调用记录和反馈给 AI 的结果
+func (a *Agent) runDeferredCall(ctx context.Context, sessionID string, trace callTrace, call *protocol.CallRequest) (FunctionCall, string) {
+	name := call.Name
+	execReq := function.ExecuteRequest{
+		FunctionName: name,
+		Params:       call.Params,
+		Data:         call.Data,
+	}
+	execResp := a.executor.Execute(trace.context(ctx), execReq)
+	execResp, retried := a.retryTransient(trace.context(ctx), execReq, execResp)
+	a.recordCall(sessionID, trace, execReq, execResp)
+
+	fc := FunctionCall{Name: name, CallID: trace.CallID, Status: "success"}
+	var resultStr string
+	if execResp.Error != nil {
+		fc.Status = "error"
+		fc.Result = execResp.Error.Error()
+		resultStr = a.encodeFailure(name, execResp.Error, retried)
+	} else {
+		fc.Result = execResp.Result.Message
+		fc.Markdown = a.displayMarkdown(execResp.Result)
+		var attachmentInfos []protocol.AttachmentInfo
+		fc.Attachments, attachmentInfos = resultAttachments(execResp.Result)
+		resultStr, _ = a.encoder.EncodeResult(&protocol.CallResult{
+			Name:        name,
+			Status:      protocol.StatusSuccess,
+			Message:     execResp.Result.Message,
+			Data:        execResp.Result.Data,
+			Markdown:    execResp.Result.Markdown,
+			Attachments: attachmentInfos,
+		})
+		resultStr = a.limitResult(ctx, name, resultStr)
+	}
+	return fc, resultStr
+}
+
+// newPendingCallID 生成待确认调用的 ID
+// 保持简短：Telegram 的 callback data 最长 64 字节
+func newPendingCallID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
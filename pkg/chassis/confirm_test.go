@@ -0,0 +1,65 @@
+package chassis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+)
+
+func TestAgent_ConfirmPrompt(t *testing.T) {
+	config := DefaultAgentConfig()
+	config.ConfirmFunctions = []string{"transfer"}
+
+	tests := []struct {
+		name         string
+		confirmCalls bool
+		want         bool
+	}{
+		{"confirming channel", true, true},
+		{"plain request", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{name: "main", reply: "ok"}
+			agent := NewAgent(provider, function.NewRegistry(), config)
+			_, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "send 10 to bob", ConfirmCalls: tt.confirmCalls})
+			if err != nil {
+				t.Fatalf("Chat: %v", err)
+			}
+
+			system := provider.requests[0][0].Content
+			if got := strings.Contains(system, "## Confirmation") && strings.Contains(system, "transfer"); got != tt.want {
+				t.Errorf("confirmation section in system prompt = %v, want %v", got, tt.want)
+			}
+
+			// 说明只拼进本次请求，会话中的系统提示和用户消息保持原样
+			for _, msg := range agent.sessionManager.Get("s1").GetMessages() {
+				if strings.Contains(msg.Content, "## Confirmation") {
+					t.Errorf("confirmation section stored in session: %q", msg.Content)
+				}
+			}
+			if user := provider.requests[0][len(provider.requests[0])-1]; user.Content != "send 10 to bob" {
+				t.Errorf("user message = %q", user.Content)
+			}
+		})
+	}
+}
+
+func TestJoinPromptSections(t *testing.T) {
+	tests := []struct {
+		sections []string
+		want     string
+	}{
+		{nil, ""},
+		{[]string{"", " \n"}, ""},
+		{[]string{"## A\n", ""}, "## A"},
+		{[]string{"## A\n", "## B\n"}, "## A\n\n## B"},
+	}
+	for _, tt := range tests {
+		if got := joinPromptSections(tt.sections...); got != tt.want {
+			t.Errorf("joinPromptSections(%q) = %q, want %q", tt.sections, got, tt.want)
+		}
+	}
+}
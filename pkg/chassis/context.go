@@ -3,10 +3,14 @@ package chassis
 
 import (
 	"context"
+	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/protocol"
 )
 
 // Session 对话会话
@@ -15,6 +19,21 @@ type Session struct {
 	Messages  []llm.Message `json:"messages"`
 	CreatedAt time.Time     `json:"created_at"`
 	UpdatedAt time.Time     `json:"updated_at"`
+
+	// PromptVersion 生成系统提示时的函数注册表版本
+	PromptVersion uint64 `json:"-"`
+
+	// PromptChannel 生成系统提示时的渠道类型
+	PromptChannel string `json:"-"`
+
+	// PromptLanguage 生成系统提示时的语言
+	PromptLanguage string `json:"-"`
+
+	// PendingApproval 等待管理员审批的请求 ID，不为空时会话暂停，审批完成后恢复
+	PendingApproval string `json:"pending_approval,omitempty"`
+
+	// Owner 创建会话的调用方（types.CallerFromContext），未鉴权时为空；创建后不再修改
+	Owner string `json:"owner,omitempty"`
 }
 
 // AddMessage 添加消息到会话
@@ -26,6 +45,66 @@ func (s *Session) AddMessage(role llm.Role, content string) {
 	s.UpdatedAt = time.Now()
 }
 
+// AppendMessage 追加完整消息（包含工具调用等附加字段）
+func (s *Session) AppendMessage(msg llm.Message) {
+	s.Messages = append(s.Messages, msg)
+	s.UpdatedAt = time.Now()
+}
+
+// imagePlaceholder 图片数据从会话中移除后留在消息中的标记
+const imagePlaceholder = "[image]"
+
+// dropImages 把会话中的图片替换为文字标记
+// 图片只在发送它的那一轮对话中传给模型，模型的回复已包含对图片的理解；
+// 之后每次请求都重发图片会浪费大量 token，也会让会话长期占用图片大小的内存
+func (s *Session) dropImages() {
+	for i := range s.Messages {
+		msg := &s.Messages[i]
+		if len(msg.Images) == 0 {
+			continue
+		}
+		markers := strings.TrimSuffix(strings.Repeat(imagePlaceholder+" ", len(msg.Images)), " ")
+		if msg.Content == "" {
+			msg.Content = markers
+		} else {
+			msg.Content += "\n" + markers
+		}
+		msg.Images = nil
+	}
+}
+
+// slimResults 把会话中 tool 消息里的函数结果精简为只保留函数名、状态和 message/error，去掉 data 和 markdown 原文
+// 之前轮次的结果已被模型用过，精简后会话占用的内存、落库大小和之后每次请求的 token 都大幅减少；
+// 只改写结果块本身，块之外的文本原样保留，用户消息不受影响
+func (s *Session) slimResults(parser *protocol.Parser, encoder *protocol.Encoder) {
+	for i := range s.Messages {
+		msg := &s.Messages[i]
+		if msg.Role != llm.RoleTool || !parser.HasResult(msg.Content) {
+			continue
+		}
+		msg.Content = parser.ReplaceResults(msg.Content, func(summary protocol.ResultSummary) string {
+			encoded, _ := encoder.EncodeResult(&protocol.CallResult{
+				Name:    summary.Name,
+				Status:  summary.Status,
+				Message: summary.Message,
+				Error:   summary.Error,
+			})
+			return encoded
+		})
+	}
+}
+
+// SetSystemPrompt 设置系统提示
+// 已有系统消息时替换第一条，否则插入到最前面
+func (s *Session) SetSystemPrompt(content string) {
+	if len(s.Messages) > 0 && s.Messages[0].Role == llm.RoleSystem {
+		s.Messages[0].Content = content
+	} else {
+		s.Messages = append([]llm.Message{{Role: llm.RoleSystem, Content: content}}, s.Messages...)
+	}
+	s.UpdatedAt = time.Now()
+}
+
 // GetMessages 获取所有消息
 func (s *Session) GetMessages() []llm.Message {
 	return s.Messages
@@ -42,12 +121,46 @@ func (s *Session) Truncate(maxMessages int) {
 	if len(s.Messages) > 0 && s.Messages[0].Role == llm.RoleSystem {
 		// 保留系统消息 + 最近的消息
 		systemMsg := s.Messages[0]
-		recentMessages := s.Messages[len(s.Messages)-(maxMessages-1):]
+		recentMessages := dropOrphanToolResults(s.Messages[len(s.Messages)-(maxMessages-1):])
 		s.Messages = append([]llm.Message{systemMsg}, recentMessages...)
 	} else {
 		// 只保留最近的消息
-		s.Messages = s.Messages[len(s.Messages)-maxMessages:]
+		s.Messages = dropOrphanToolResults(s.Messages[len(s.Messages)-maxMessages:])
+	}
+}
+
+// TruncateByTokens 按估算的 token 数截断消息历史
+// 始终保留第一条系统消息（如果有）和最新的一条消息，从旧到新丢弃直到总量不超过 maxTokens
+// maxTokens <= 0 时不截断
+func (s *Session) TruncateByTokens(maxTokens int) {
+	if maxTokens <= 0 || llm.EstimateMessagesTokens(s.Messages) <= maxTokens {
+		return
+	}
+
+	var systemMsg []llm.Message
+	history := s.Messages
+	if len(history) > 0 && history[0].Role == llm.RoleSystem {
+		systemMsg = history[:1]
+		history = history[1:]
+	}
+
+	total := llm.EstimateMessagesTokens(s.Messages)
+	for len(history) > 1 && total > maxTokens {
+		total -= llm.EstimateMessageTokens(history[0])
+		history = history[1:]
+	}
+	history = dropOrphanToolResults(history)
+
+	s.Messages = append(append([]llm.Message{}, systemMsg...), history...)
+}
+
+// dropOrphanToolResults 去掉开头失去对应调用的 tool 消息
+// 截断可能切在 tool_calls 与其结果之间，孤立的 tool 消息会被 API 拒绝
+func dropOrphanToolResults(messages []llm.Message) []llm.Message {
+	for len(messages) > 0 && messages[0].Role == llm.RoleTool {
+		messages = messages[1:]
 	}
+	return messages
 }
 
 // Clear 清空消息历史（保留系统消息）
@@ -61,11 +174,13 @@ func (s *Session) Clear() {
 }
 
 // SessionManager 会话管理器
-// 管理多个会话，支持并发访问
+// 管理多个会话，支持并发访问；设置了 SessionStore 时内存中没有的会话从存储中读回
 type SessionManager struct {
 	mu       sync.RWMutex
 	sessions map[string]*Session
 	config   *SessionConfig
+	store    *SessionStore
+	logger   *slog.Logger // 为 nil 时使用全局 logger
 }
 
 // NewSessionManager 创建会话管理器
@@ -79,52 +194,150 @@ func NewSessionManager(config *SessionConfig) *SessionManager {
 	}
 }
 
+// SetLogger 设置读写持久化存储失败时使用的 logger
+func (m *SessionManager) SetLogger(logger *slog.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logger = logger
+}
+
+// log 返回会话管理器的 logger，调用方需持有锁
+func (m *SessionManager) log() *slog.Logger {
+	if m.logger != nil {
+		return m.logger
+	}
+	return observability.DefaultLogger()
+}
+
+// SetStore 设置会话持久化存储
+func (m *SessionManager) SetStore(store *SessionStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store = store
+}
+
 // Get 获取会话，如果不存在则返回 nil
 func (m *SessionManager) Get(id string) *Session {
 	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.sessions[id]
-}
+	session, store := m.sessions[id], m.store
+	m.mu.RUnlock()
+	if session != nil || store == nil {
+		return session
+	}
 
-// GetOrCreate 获取或创建会话
-func (m *SessionManager) GetOrCreate(id string) *Session {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	return m.load(id)
+}
 
+// load 从存储中读回内存中没有的会话，调用方需持有写锁
+func (m *SessionManager) load(id string) *Session {
 	if session, ok := m.sessions[id]; ok {
 		return session
 	}
+	if m.store == nil {
+		return nil
+	}
+	session, err := m.store.Load(id)
+	if err != nil {
+		m.log().Warn("Failed to load session", "session_id", id, "error", err)
+		return nil
+	}
+	if session != nil {
+		m.sessions[id] = session
+	}
+	return session
+}
+
+// GetOrCreate 获取或创建会话，新建的会话归属于 owner
+func (m *SessionManager) GetOrCreate(id, owner string) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session := m.load(id); session != nil {
+		return session
+	}
 
 	session := &Session{
 		ID:        id,
 		Messages:  make([]llm.Message, 0),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
+		Owner:     owner,
 	}
 	m.sessions[id] = session
 	return session
 }
 
+// Save 把会话写入持久化存储，未设置存储时什么也不做
+// 需在会话上没有其他写入时调用（如对话结束前）
+func (m *SessionManager) Save(session *Session) {
+	m.mu.RLock()
+	store, logger := m.store, m.log()
+	m.mu.RUnlock()
+	if store == nil || session == nil {
+		return
+	}
+	if err := store.Save(session); err != nil {
+		logger.Warn("Failed to save session", "session_id", session.ID, "error", err)
+	}
+}
+
 // Delete 删除会话
 func (m *SessionManager) Delete(id string) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, ok := m.sessions[id]; ok {
-		delete(m.sessions, id)
-		return true
+	session := m.load(id)
+	if session == nil {
+		return false
+	}
+	delete(m.sessions, id)
+	if m.store != nil {
+		if err := m.store.Delete(id); err != nil {
+			m.log().Warn("Failed to delete stored session", "session_id", id, "error", err)
+		}
 	}
-	return false
+	return true
 }
 
-// List 列出所有会话 ID
+// List 列出所有会话 ID，包括只在存储中的会话
 func (m *SessionManager) List() []string {
+	return m.list(nil)
+}
+
+// ListOwnedBy 列出属于 owner 的会话 ID
+func (m *SessionManager) ListOwnedBy(owner string) []string {
+	return m.list(&owner)
+}
+
+// list 列出内存和存储中的会话 ID，owner 不为 nil 时只列出属于它的会话
+func (m *SessionManager) list(owner *string) []string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	ids := make([]string, 0, len(m.sessions))
-	for id := range m.sessions {
-		ids = append(ids, id)
+	for id, session := range m.sessions {
+		if owner == nil || session.Owner == *owner {
+			ids = append(ids, id)
+		}
+	}
+	if m.store != nil {
+		var stored []string
+		var err error
+		if owner == nil {
+			stored, err = m.store.IDs()
+		} else {
+			stored, err = m.store.OwnedIDs(*owner)
+		}
+		if err != nil {
+			m.log().Warn("Failed to list stored sessions", "error", err)
+		}
+		for _, id := range stored {
+			if _, ok := m.sessions[id]; !ok {
+				ids = append(ids, id)
+			}
+		}
 	}
 	return ids
 }
@@ -143,6 +356,11 @@ func (m *SessionManager) CleanExpired() int {
 			count++
 		}
 	}
+	if m.store != nil {
+		if _, err := m.store.DeleteBefore(expireTime); err != nil {
+			m.log().Warn("Failed to delete expired sessions", "error", err)
+		}
+	}
 	return count
 }
 
@@ -154,10 +372,13 @@ const (
 	SessionIDKey ContextKey = "session_id"
 	// TraceIDKey 追踪 ID 上下文键
 	TraceIDKey ContextKey = "trace_id"
+	// RequestIDKey 请求 ID 上下文键
+	RequestIDKey ContextKey = "request_id"
 )
 
-// WithSessionID 将会话 ID 添加到 context
+// WithSessionID 将会话 ID 添加到 context，之后的上下文日志都会带上 session_id
 func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	ctx = observability.WithLogFields(ctx, "session_id", sessionID)
 	return context.WithValue(ctx, SessionIDKey, sessionID)
 }
 
@@ -169,8 +390,9 @@ func GetSessionID(ctx context.Context) string {
 	return ""
 }
 
-// WithTraceID 将追踪 ID 添加到 context
+// WithTraceID 将追踪 ID 添加到 context，之后的上下文日志都会带上 trace_id
 func WithTraceID(ctx context.Context, traceID string) context.Context {
+	ctx = observability.WithLogFields(ctx, "trace_id", traceID)
 	return context.WithValue(ctx, TraceIDKey, traceID)
 }
 
@@ -181,3 +403,16 @@ func GetTraceID(ctx context.Context) string {
 	}
 	return ""
 }
+
+// WithRequestID 指定本次 Chat 的请求 ID（如 HTTP 请求头 X-Request-ID），未指定或不满足 ValidRequestID 时 Chat 会自动生成
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, RequestIDKey, requestID)
+}
+
+// GetRequestID 从 context 获取请求 ID
+func GetRequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(RequestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
@@ -0,0 +1,262 @@
+package chassis
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/protocol"
+	"github.com/KodaTao/AgentChassis/pkg/types"
+)
+
+func TestSession_TruncateByTokens(t *testing.T) {
+	// 每条消息 4（开销）+ 25（100 个 ASCII 字符）= 29 token
+	text := strings.Repeat("a", 100)
+	msg := func(role llm.Role) llm.Message { return llm.Message{Role: role, Content: text} }
+	system := llm.Message{Role: llm.RoleSystem, Content: "sys"} // 4 + 1 = 5 token
+
+	tests := []struct {
+		name      string
+		messages  []llm.Message
+		maxTokens int
+		wantRoles []llm.Role
+	}{
+		{
+			name:      "disabled",
+			messages:  []llm.Message{system, msg(llm.RoleUser), msg(llm.RoleAssistant)},
+			maxTokens: 0,
+			wantRoles: []llm.Role{llm.RoleSystem, llm.RoleUser, llm.RoleAssistant},
+		},
+		{
+			name:      "within budget",
+			messages:  []llm.Message{system, msg(llm.RoleUser), msg(llm.RoleAssistant)},
+			maxTokens: 63,
+			wantRoles: []llm.Role{llm.RoleSystem, llm.RoleUser, llm.RoleAssistant},
+		},
+		{
+			name:      "drops oldest first",
+			messages:  []llm.Message{system, msg(llm.RoleUser), msg(llm.RoleAssistant), msg(llm.RoleUser)},
+			maxTokens: 63,
+			wantRoles: []llm.Role{llm.RoleSystem, llm.RoleAssistant, llm.RoleUser},
+		},
+		{
+			name:      "keeps system and latest message over budget",
+			messages:  []llm.Message{system, msg(llm.RoleUser), msg(llm.RoleAssistant), msg(llm.RoleUser)},
+			maxTokens: 10,
+			wantRoles: []llm.Role{llm.RoleSystem, llm.RoleUser},
+		},
+		{
+			name:      "without system prompt",
+			messages:  []llm.Message{msg(llm.RoleUser), msg(llm.RoleAssistant), msg(llm.RoleUser)},
+			maxTokens: 58,
+			wantRoles: []llm.Role{llm.RoleAssistant, llm.RoleUser},
+		},
+		{
+			name: "drops orphan tool results",
+			messages: []llm.Message{
+				system,
+				{Role: llm.RoleAssistant, Content: text, ToolCalls: []llm.ToolCall{{ID: "1", Name: "f"}}},
+				{Role: llm.RoleTool, ToolCallID: "1", Content: "ok"},
+				msg(llm.RoleAssistant),
+				msg(llm.RoleUser),
+			},
+			maxTokens: 70,
+			wantRoles: []llm.Role{llm.RoleSystem, llm.RoleAssistant, llm.RoleUser},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &Session{Messages: tt.messages}
+			session.TruncateByTokens(tt.maxTokens)
+
+			var roles []llm.Role
+			for _, m := range session.Messages {
+				roles = append(roles, m.Role)
+			}
+			if len(roles) != len(tt.wantRoles) {
+				t.Fatalf("roles = %v, want %v", roles, tt.wantRoles)
+			}
+			for i := range roles {
+				if roles[i] != tt.wantRoles[i] {
+					t.Fatalf("roles = %v, want %v", roles, tt.wantRoles)
+				}
+			}
+		})
+	}
+}
+
+func TestSession_DropImages(t *testing.T) {
+	img := llm.Image{MimeType: "image/png", Data: []byte("png")}
+
+	tests := []struct {
+		name string
+		msg  llm.Message
+		want string
+	}{
+		{"no images", llm.Message{Role: llm.RoleUser, Content: "hi"}, "hi"},
+		{"image with text", llm.Message{Role: llm.RoleUser, Content: "what is this?", Images: []llm.Image{img}}, "what is this?\n[image]"},
+		{"image only", llm.Message{Role: llm.RoleUser, Images: []llm.Image{img}}, "[image]"},
+		{"several images", llm.Message{Role: llm.RoleUser, Content: "compare", Images: []llm.Image{img, img}}, "compare\n[image] [image]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &Session{Messages: []llm.Message{tt.msg}}
+			session.dropImages()
+			if got := session.Messages[0]; got.Content != tt.want || got.Images != nil {
+				t.Errorf("message = %q with %d images, want %q without images", got.Content, len(got.Images), tt.want)
+			}
+		})
+	}
+}
+
+func TestAgent_ImagesSentOnlyOnce(t *testing.T) {
+	provider := &fakeProvider{name: "main", reply: "a cat"}
+	agent := NewAgent(provider, function.NewRegistry(), DefaultAgentConfig())
+	countImages := func(messages []llm.Message) int {
+		n := 0
+		for _, msg := range messages {
+			n += len(msg.Images)
+		}
+		return n
+	}
+
+	_, err := agent.Chat(context.Background(), ChatRequest{
+		SessionID: "s1",
+		Message:   "what is this?",
+		Images:    []types.Attachment{{MimeType: "image/jpeg", Content: []byte("jpeg")}},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got := countImages(provider.requests[0]); got != 1 {
+		t.Fatalf("first request carried %d images, want 1", got)
+	}
+
+	if _, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "what color is it?"}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	last := provider.requests[len(provider.requests)-1]
+	if got := countImages(last); got != 0 {
+		t.Errorf("follow-up request carried %d images, want 0", got)
+	}
+	var found bool
+	for _, msg := range last {
+		found = found || msg.Content == "what is this?\n"+imagePlaceholder
+	}
+	if !found {
+		t.Error("follow-up request lost the image placeholder")
+	}
+}
+
+func TestSession_SlimResults(t *testing.T) {
+	encoder := protocol.NewEncoder()
+	full, _ := encoder.EncodeResult(&protocol.CallResult{
+		Name:     "list_orders",
+		Status:   protocol.StatusSuccess,
+		Message:  "found <2> orders",
+		Data:     []map[string]any{{"id": 1}, {"id": 2}},
+		Markdown: "| id |\n|----|\n| 1 |\n| 2 |",
+	})
+	failed := encoder.EncodeClassifiedError("refund", "timeout", "upstream timed out", "retry later")
+
+	session := &Session{Messages: []llm.Message{
+		{Role: llm.RoleSystem, Content: "sys"},
+		{Role: llm.RoleUser, Content: "list my orders"},
+		{Role: llm.RoleAssistant, Content: `<call name="list_orders"></call>`},
+		{Role: llm.RoleTool, Content: "before\n" + full + "\nbetween\n" + failed + "\nafter", ToolCallID: "call_1"},
+		{Role: llm.RoleAssistant, Content: "You have 2 orders."},
+		{Role: llm.RoleUser, Content: "I pasted this: " + full},
+	}}
+	before := append([]llm.Message(nil), session.Messages...)
+
+	session.slimResults(protocol.NewParser(), encoder)
+
+	slim := session.Messages[3]
+	if slim.ToolCallID != "call_1" {
+		t.Errorf("ToolCallID = %q, want call_1", slim.ToolCallID)
+	}
+	if strings.Contains(slim.Content, "<data") || strings.Contains(slim.Content, "<output") || strings.Contains(slim.Content, "<hint>") {
+		t.Errorf("slim result still has data, markdown or hint:\n%s", slim.Content)
+	}
+	// 结果块之外的文本原样保留
+	if !strings.HasPrefix(slim.Content, "before\n<result") || !strings.Contains(slim.Content, "</result>\nbetween\n<result") || !strings.HasSuffix(slim.Content, "</result>\nafter") {
+		t.Errorf("text around the result blocks changed:\n%s", slim.Content)
+	}
+	got := protocol.NewParser().ExtractResultSummaries(slim.Content)
+	want := protocol.NewParser().ExtractResultSummaries(before[3].Content)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summaries = %+v, want %+v", got, want)
+	}
+	// 只精简 tool 消息，用户消息中的结果块不受影响
+	for _, i := range []int{0, 1, 2, 4, 5} {
+		if !reflect.DeepEqual(session.Messages[i], before[i]) {
+			t.Errorf("message %d changed: %+v", i, session.Messages[i])
+		}
+	}
+
+	// 再次精简不改变内容
+	content := slim.Content
+	session.slimResults(protocol.NewParser(), encoder)
+	if session.Messages[3].Content != content {
+		t.Errorf("slimResults is not idempotent:\n%s\nvs\n%s", session.Messages[3].Content, content)
+	}
+}
+
+// ordersFunction 返回结构化数据的测试函数
+type ordersFunction struct{}
+
+func (f *ordersFunction) Name() string             { return "list_orders" }
+func (f *ordersFunction) Description() string      { return "list orders" }
+func (f *ordersFunction) ParamsType() reflect.Type { return nil }
+func (f *ordersFunction) Execute(ctx context.Context, params any) (function.Result, error) {
+	return function.Result{
+		Message: "found 1 order",
+		Data:    []map[string]any{{"id": "ORDER-42"}},
+	}, nil
+}
+
+func TestAgent_SlimResults(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		provider := &fakeProvider{name: "main", reply: "done", replies: []string{`<call name="list_orders"></call>`}}
+		registry := function.NewRegistry()
+		if err := registry.Register(&ordersFunction{}); err != nil {
+			t.Fatal(err)
+		}
+		config := DefaultAgentConfig()
+		config.SlimResults = enabled
+		agent := NewAgent(provider, registry, config)
+
+		for _, message := range []string{"list my orders", "thanks"} {
+			if _, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: message}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// 第一轮中 AI 拿到完整结果，之后的轮次只在开启精简时去掉 data
+		hasData := func(messages []llm.Message) bool {
+			for _, msg := range messages {
+				if msg.Role != llm.RoleAssistant && strings.Contains(msg.Content, "ORDER-42") {
+					return true
+				}
+			}
+			return false
+		}
+		if len(provider.requests) != 3 {
+			t.Fatalf("LLM requests = %d, want 3", len(provider.requests))
+		}
+		if !hasData(provider.requests[1]) {
+			t.Errorf("enabled=%v: result data missing in the turn that called the function", enabled)
+		}
+		if got := hasData(provider.requests[2]); got == enabled {
+			t.Errorf("enabled=%v: result data in the next turn = %v", enabled, got)
+		}
+		if !strings.Contains(agent.sessionManager.Get("s1").Messages[3].Content, "found 1 order") {
+			t.Errorf("enabled=%v: result message lost: %+v", enabled, agent.sessionManager.Get("s1").Messages)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+// 消息编辑相关错误
+var (
+	ErrMessageNotEditable = errors.New("only user messages can be edited or deleted")
+	ErrSessionBusy        = errors.New("a chat is in progress on this session")
+)
+
+// EditMessage 替换会话中某条用户消息的内容，并删除其后的所有消息
+// 之后的 AI 回复和函数结果都基于原来的内容，保留它们会让上下文前后矛盾；
+// 以用户消息为界截断也保证了 tool_calls 与结果的配对不会被破坏
+func (m *SessionManager) EditMessage(sessionID string, index int, content string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, err := m.userMessageAt(sessionID, index)
+	if err != nil {
+		return err
+	}
+	session.Messages[index].Content = content
+	session.Messages = session.Messages[:index+1]
+	session.UpdatedAt = time.Now()
+	return nil
+}
+
+// DeleteMessage 删除会话中某条用户消息及其后的所有消息，会话回到这条消息之前的状态
+func (m *SessionManager) DeleteMessage(sessionID string, index int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, err := m.userMessageAt(sessionID, index)
+	if err != nil {
+		return err
+	}
+	session.Messages = session.Messages[:index]
+	session.UpdatedAt = time.Now()
+	return nil
+}
+
+// userMessageAt 返回会话，并校验 index 指向一条用户消息，调用方需持有锁
+func (m *SessionManager) userMessageAt(sessionID string, index int) (*Session, error) {
+	session := m.load(sessionID)
+	if session == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	if index < 0 || index >= len(session.Messages) {
+		return nil, fmt.Errorf("%w: %d (session has %d messages)", ErrMessageIndexInvalid, index, len(session.Messages))
+	}
+	if role := session.Messages[index].Role; role != llm.RoleUser {
+		return nil, fmt.Errorf("%w: message %d is a %s message", ErrMessageNotEditable, index, role)
+	}
+	return session, nil
+}
+
+// EditMessage 修正会话中某条用户消息，删除其后的 AI 回复和函数结果
+// 下标与会话导出（include_system=true）中的消息顺序一致；会话上有进行中的对话时返回 ErrSessionBusy
+// 检查和修改期间持有 activeMu，新对话无法在两者之间开始
+func (a *Agent) EditMessage(sessionID string, index int, content string) error {
+	a.activeMu.Lock()
+	defer a.activeMu.Unlock()
+	if _, busy := a.active[sessionID]; busy {
+		return ErrSessionBusy
+	}
+	if err := a.sessionManager.EditMessage(sessionID, index, content); err != nil {
+		return err
+	}
+	a.sessionManager.Save(a.sessionManager.Get(sessionID))
+	return nil
+}
+
+// DeleteMessage 删除会话中某条用户消息及其后的所有消息
+func (a *Agent) DeleteMessage(sessionID string, index int) error {
+	a.activeMu.Lock()
+	defer a.activeMu.Unlock()
+	if _, busy := a.active[sessionID]; busy {
+		return ErrSessionBusy
+	}
+	if err := a.sessionManager.DeleteMessage(sessionID, index); err != nil {
+		return err
+	}
+	a.sessionManager.Save(a.sessionManager.Get(sessionID))
+	return nil
+}
@@ -0,0 +1,84 @@
+package chassis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+func TestSessionManager_EditMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		index   int
+		wantErr error
+		wantLen int
+	}{
+		// system + a(u) b(a) c(u) d(a)
+		{"truncates after edited message", 1, nil, 2},
+		{"last user message", 3, nil, 4},
+		{"system prompt", 0, ErrMessageNotEditable, 5},
+		{"assistant message", 2, ErrMessageNotEditable, 5},
+		{"negative index", -1, ErrMessageIndexInvalid, 5},
+		{"index out of range", 5, ErrMessageIndexInvalid, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewSessionManager(nil)
+			session := m.GetOrCreate("s1", "")
+			session.Messages = conversation(4)
+
+			err := m.EditMessage("s1", tt.index, "edited")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EditMessage() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := len(session.Messages); got != tt.wantLen {
+				t.Errorf("len(Messages) = %d, want %d", got, tt.wantLen)
+			}
+			if err == nil && session.Messages[tt.index].Content != "edited" {
+				t.Errorf("content = %q, want edited", session.Messages[tt.index].Content)
+			}
+		})
+	}
+}
+
+func TestSessionManager_DeleteMessage(t *testing.T) {
+	m := NewSessionManager(nil)
+	session := m.GetOrCreate("s1", "")
+	session.Messages = conversation(4)
+
+	if err := m.DeleteMessage("s1", 2); !errors.Is(err, ErrMessageNotEditable) {
+		t.Fatalf("deleting an assistant message: error = %v", err)
+	}
+	if err := m.DeleteMessage("s1", 3); err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+	if got := len(session.Messages); got != 3 {
+		t.Errorf("len(Messages) = %d, want 3", got)
+	}
+	if last := session.Messages[len(session.Messages)-1]; last.Role != llm.RoleAssistant {
+		t.Errorf("last message role = %s, want assistant", last.Role)
+	}
+	if err := m.DeleteMessage("missing", 1); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("missing session: error = %v", err)
+	}
+}
+
+func TestAgent_EditMessageBusy(t *testing.T) {
+	agent := NewAgent(&fakeProvider{name: "fake"}, function.NewRegistry(), nil)
+	session := agent.sessionManager.GetOrCreate("s1", "")
+	session.Messages = conversation(4)
+	agent.active["s1"] = &activeChat{}
+
+	if err := agent.EditMessage("s1", 1, "edited"); !errors.Is(err, ErrSessionBusy) {
+		t.Errorf("EditMessage() error = %v, want ErrSessionBusy", err)
+	}
+	if err := agent.DeleteMessage("s1", 1); !errors.Is(err, ErrSessionBusy) {
+		t.Errorf("DeleteMessage() error = %v, want ErrSessionBusy", err)
+	}
+	if got := len(session.Messages); got != 5 {
+		t.Errorf("busy session changed: %d messages", got)
+	}
+}
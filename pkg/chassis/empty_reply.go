@@ -0,0 +1,29 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"strings"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// EmptyReplyMessage 重试后 AI 仍返回空回复时给用户的提示
+const EmptyReplyMessage = "抱歉，AI 未能生成有效回复，请换个说法重试。"
+
+// isEmptyReply 判断 AI 回复是否为空：既没有文本内容（或只有空白字符），也没有原生工具调用
+func isEmptyReply(reply llm.Message) bool {
+	return strings.TrimSpace(reply.Content) == "" && len(reply.ToolCalls) == 0
+}
+
+// callLLMRetryEmpty 请求 LLM，回复为空时最多重试 EmptyReplyRetries 次
+// 部分模型偶发返回空字符串，重试通常就能拿到正常回复；重试后仍为空时原样返回空回复，由调用方处理
+func (a *Agent) callLLMRetryEmpty(ctx context.Context, messages []llm.Message, tools []llm.ToolDefinition, opts llm.ChatOptions) (llm.Message, error) {
+	reply, err := a.callLLM(ctx, messages, tools, opts)
+	for attempt := 1; err == nil && isEmptyReply(reply) && attempt <= a.config.EmptyReplyRetries; attempt++ {
+		observability.WarnContext(ctx, "LLM returned an empty reply, retrying", "attempt", attempt)
+		reply, err = a.callLLM(ctx, messages, tools, opts)
+	}
+	return reply, err
+}
@@ -0,0 +1,53 @@
+package chassis
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAgent_EmptyReplyRetry(t *testing.T) {
+	t.Run("retry once then reply", func(t *testing.T) {
+		provider := &fakeProvider{name: "main", reply: "unexpected", replies: []string{" \n ", "hello"}}
+		agent := newTestAgent(t, provider, nil)
+
+		resp, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "hi"})
+		if err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+		if resp.Reply != "hello" {
+			t.Errorf("reply = %q, want hello", resp.Reply)
+		}
+		if len(provider.requests) != 2 {
+			t.Errorf("LLM called %d times, want 2 (exactly one retry)", len(provider.requests))
+		}
+	})
+
+	t.Run("still empty after retries", func(t *testing.T) {
+		provider := &fakeProvider{name: "main", reply: ""}
+		agent := newTestAgent(t, provider, nil)
+
+		resp, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "hi"})
+		if err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+		if resp.Reply != EmptyReplyMessage {
+			t.Errorf("reply = %q, want EmptyReplyMessage", resp.Reply)
+		}
+		if len(provider.requests) != 2 {
+			t.Errorf("LLM called %d times, want 2", len(provider.requests))
+		}
+	})
+
+	t.Run("retries disabled", func(t *testing.T) {
+		provider := &fakeProvider{name: "main", reply: "hello", replies: []string{""}}
+		agent := newTestAgent(t, provider, func(c *AgentConfig) { c.EmptyReplyRetries = 0 })
+
+		resp, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "hi"})
+		if err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+		if resp.Reply != EmptyReplyMessage || len(provider.requests) != 1 {
+			t.Errorf("reply = %q after %d calls, want EmptyReplyMessage after 1", resp.Reply, len(provider.requests))
+		}
+	})
+}
@@ -0,0 +1,92 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ErrEnvNotSet 配置引用的环境变量未设置且没有默认值
+var ErrEnvNotSet = errors.New("environment variable not set")
+
+// envPlaceholder 匹配 ${VAR} 和 ${VAR:-default} 形式的环境变量引用
+// 只识别带花括号的写法，避免误替换密码等值中出现的普通 $ 字符
+var envPlaceholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandEnv 将配置中所有字符串字段里的 ${VAR} 替换为环境变量的值
+// 会递归处理嵌套结构体、slice 和 map（如 prompt_vars）
+// 这样 telegram token、数据库路径等敏感配置都可以通过环境变量注入，不必明文写在配置文件里
+// 引用的变量未设置时返回 ErrEnvNotSet，列出所有缺少的变量；可选的配置用 ${VAR:-default} 指定默认值（可以为空）
+func ExpandEnv(cfg *Config) error {
+	if cfg == nil {
+		return nil
+	}
+	var missing []string
+	expandEnvValue(reflect.ValueOf(cfg).Elem(), &missing)
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf("%w: %s", ErrEnvNotSet, strings.Join(slices.Compact(missing), ", "))
+	}
+	return nil
+}
+
+// expandEnvString 替换单个字符串中的环境变量引用，未设置且没有默认值的变量记入 missing
+func expandEnvString(s string, missing *[]string) string {
+	return envPlaceholder.ReplaceAllStringFunc(s, func(match string) string {
+		m := envPlaceholder.FindStringSubmatch(match)
+		if value, ok := os.LookupEnv(m[1]); ok {
+			return value
+		}
+		if m[2] != "" {
+			return m[3]
+		}
+		*missing = append(*missing, m[1])
+		return ""
+	})
+}
+
+// expandEnvValue 递归替换 v 中的字符串，v 必须可设置
+func expandEnvValue(v reflect.Value, missing *[]string) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(expandEnvString(v.String(), missing))
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			expandEnvValue(v.Elem(), missing)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Field(i); field.CanSet() {
+				expandEnvValue(field, missing)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			expandEnvValue(v.Index(i), missing)
+		}
+	case reflect.Map:
+		// map 的值不可寻址，复制一份处理后写回
+		iter := v.MapRange()
+		for iter.Next() {
+			val := reflect.New(iter.Value().Type()).Elem()
+			val.Set(iter.Value())
+			expandEnvValue(val, missing)
+			v.SetMapIndex(iter.Key(), val)
+		}
+	case reflect.Interface:
+		if v.IsNil() || !v.CanSet() {
+			return
+		}
+		val := reflect.New(v.Elem().Type()).Elem()
+		val.Set(v.Elem())
+		expandEnvValue(val, missing)
+		v.Set(val)
+	}
+}
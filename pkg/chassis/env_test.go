@@ -0,0 +1,77 @@
+package chassis
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExpandEnvString(t *testing.T) {
+	t.Setenv("AC_TEST_TOKEN", "secret")
+	t.Setenv("AC_TEST_EMPTY", "")
+
+	tests := []struct {
+		name        string
+		in          string
+		want        string
+		wantMissing []string
+	}{
+		{"no reference", "plain value", "plain value", nil},
+		{"whole value", "${AC_TEST_TOKEN}", "secret", nil},
+		{"embedded", "Bearer ${AC_TEST_TOKEN}!", "Bearer secret!", nil},
+		{"repeated", "${AC_TEST_TOKEN}:${AC_TEST_TOKEN}", "secret:secret", nil},
+		{"set but empty", "[${AC_TEST_EMPTY}]", "[]", nil},
+		{"set ignores default", "${AC_TEST_TOKEN:-fallback}", "secret", nil},
+		{"empty ignores default", "${AC_TEST_EMPTY:-fallback}", "", nil},
+		{"unset uses default", "${AC_TEST_UNSET:-fallback}", "fallback", nil},
+		{"unset with empty default", "${AC_TEST_UNSET:-}", "", nil},
+		{"unset", "${AC_TEST_UNSET}", "", []string{"AC_TEST_UNSET"}},
+		{"bare dollar is kept", "pa$$word $HOME", "pa$$word $HOME", nil},
+		{"invalid name is kept", "${1ABC}", "${1ABC}", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var missing []string
+			if got := expandEnvString(tt.in, &missing); got != tt.want {
+				t.Errorf("expandEnvString(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if len(missing) != len(tt.wantMissing) || (len(missing) > 0 && missing[0] != tt.wantMissing[0]) {
+				t.Errorf("missing = %v, want %v", missing, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("AC_TEST_TOKEN", "secret")
+
+	cfg := DefaultConfig()
+	cfg.Telegram.Token = "${AC_TEST_TOKEN}"
+	cfg.Auth.APIKeys = []APIKeyConfig{{Name: "admin", Key: "${AC_TEST_TOKEN}"}}
+	cfg.PromptVars = map[string]any{"company": "${AC_TEST_TOKEN:-acme}", "count": 3}
+	if err := ExpandEnv(cfg); err != nil {
+		t.Fatalf("ExpandEnv: %v", err)
+	}
+	if cfg.Telegram.Token != "secret" || cfg.Auth.APIKeys[0].Key != "secret" {
+		t.Errorf("struct and slice fields not expanded: %q, %q", cfg.Telegram.Token, cfg.Auth.APIKeys[0].Key)
+	}
+	if cfg.PromptVars["company"] != "secret" || cfg.PromptVars["count"] != 3 {
+		t.Errorf("map values = %v", cfg.PromptVars)
+	}
+}
+
+func TestExpandEnv_Unset(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Telegram.Token = "${AC_TEST_UNSET_B}"
+	cfg.LLM.APIKey = "${AC_TEST_UNSET_A}"
+	cfg.Database.Path = "${AC_TEST_UNSET_A}/data.db"
+
+	err := ExpandEnv(cfg)
+	if !errors.Is(err, ErrEnvNotSet) {
+		t.Fatalf("ExpandEnv() error = %v, want ErrEnvNotSet", err)
+	}
+	// 缺少的变量去重并排序后一次列出
+	if want := ErrEnvNotSet.Error() + ": AC_TEST_UNSET_A, AC_TEST_UNSET_B"; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
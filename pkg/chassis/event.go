@@ -0,0 +1,121 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// EventType Agent 执行事件类型
+type EventType string
+
+const (
+	EventLLMStart      EventType = "llm_start"      // 开始调用 LLM
+	EventLLMEnd        EventType = "llm_end"        // LLM 返回
+	EventFunctionStart EventType = "function_start" // 开始执行函数
+	EventFunctionEnd   EventType = "function_end"   // 函数执行结束
+	EventThought       EventType = "thought"        // AI 在函数调用前的说明文字（需开启 ShowThoughts）
+)
+
+// Event Agent 执行过程中的步骤事件
+type Event struct {
+	Type         EventType `json:"type"`
+	SessionID    string    `json:"session_id"`
+	Iteration    int       `json:"iteration"`               // 第几轮 LLM 调用（从 1 开始）
+	FunctionName string    `json:"function_name,omitempty"` // 函数事件时有值
+	CallID       string    `json:"call_id,omitempty"`       // 函数事件的调用 ID
+	RequestID    string    `json:"request_id,omitempty"`    // 所属 Chat 请求
+	Status       string    `json:"status,omitempty"`        // 结束事件的状态：success, error
+	Error        string    `json:"error,omitempty"`         // 失败原因
+	DurationMs   int64     `json:"duration_ms,omitempty"`   // 结束事件的耗时（毫秒）
+	Content      string    `json:"content,omitempty"`       // 思考事件的文本
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// EventHandler 事件回调
+// 在 Chat 所在的 goroutine 中同步调用，耗时操作请自行异步处理
+type EventHandler func(Event)
+
+// eventHandlerKey 请求级事件回调的上下文键
+const eventHandlerKey ContextKey = "event_handler"
+
+// WithEventHandler 为单次请求设置事件回调，与 AgentConfig.OnEvent 同时生效
+func WithEventHandler(ctx context.Context, handler EventHandler) context.Context {
+	return context.WithValue(ctx, eventHandlerKey, handler)
+}
+
+// emit 触发事件，依次通知全局回调和请求级回调
+func (a *Agent) emit(ctx context.Context, event Event) {
+	event.SessionID = GetSessionID(ctx)
+	event.RequestID = GetRequestID(ctx)
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	if a.config.OnEvent != nil {
+		a.config.OnEvent(event)
+	}
+	if handler, ok := ctx.Value(eventHandlerKey).(EventHandler); ok && handler != nil {
+		handler(event)
+	}
+}
+
+// emitChatEvent 输出一次对话请求的结构化事件
+func (a *Agent) emitChatEvent(requestID string, req ChatRequest, resp *ChatResponse, err error, duration time.Duration) {
+	event := observability.Event{
+		Kind:       observability.EventChat,
+		SessionID:  req.SessionID,
+		Status:     "success",
+		DurationMs: duration.Milliseconds(),
+		Attributes: map[string]any{"request_id": requestID},
+	}
+	if resp != nil {
+		event.SessionID = resp.SessionID
+	}
+	if req.Channel != nil {
+		event.Attributes["channel"] = req.Channel.Type
+	}
+
+	switch {
+	case err != nil:
+		event.Status = "error"
+		event.Error = err.Error()
+	case resp.Cancelled:
+		event.Status = "cancelled"
+	case resp.Pending != nil:
+		event.Status = "pending"
+	case resp.Approval != nil:
+		event.Status = "awaiting_approval"
+	}
+	if resp != nil {
+		event.SessionID = resp.SessionID
+		event.Attributes["function_calls"] = len(resp.FunctionCalls)
+	}
+
+	a.events.Emit(event)
+}
+
+// emitFunctionCallEvent 输出一次函数调用的结构化事件
+func (a *Agent) emitFunctionCallEvent(sessionID string, trace callTrace, req function.ExecuteRequest, resp function.ExecuteResponse) {
+	event := observability.Event{
+		Kind:       observability.EventFunctionCall,
+		SessionID:  sessionID,
+		Name:       req.FunctionName,
+		Status:     function.CallStatusSuccess,
+		DurationMs: resp.Duration.Milliseconds(),
+		Attributes: map[string]any{
+			"request_id": trace.RequestID,
+			"call_id":    trace.CallID,
+			"iteration":  trace.Iteration,
+			"call_index": trace.Index,
+		},
+	}
+	if resp.Error != nil {
+		event.Status = function.CallStatusError
+		event.Error = resp.Error.Error()
+	}
+	a.events.Emit(event)
+}
@@ -0,0 +1,85 @@
+package chassis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+func TestAgent_Events(t *testing.T) {
+	provider := &fakeProvider{name: "main", reply: "done", replies: []string{`<call name="notify"></call>`}}
+	var global []Event
+	agent := newTestAgent(t, provider, func(c *AgentConfig) {
+		c.OnEvent = func(e Event) { global = append(global, e) }
+	}, &countingFunction{name: "notify"})
+
+	var request []Event
+	ctx := WithEventHandler(context.Background(), func(e Event) { request = append(request, e) })
+	if _, err := agent.Chat(ctx, ChatRequest{SessionID: "s1", Message: "notify"}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	// 一轮函数调用：LLM -> 函数 -> LLM
+	type step struct {
+		Type      EventType
+		Iteration int
+		Function  string
+		Status    string
+	}
+	want := []step{
+		{EventLLMStart, 1, "", ""},
+		{EventLLMEnd, 1, "", "success"},
+		{EventFunctionStart, 1, "notify", ""},
+		{EventFunctionEnd, 1, "notify", "success"},
+		{EventLLMStart, 2, "", ""},
+		{EventLLMEnd, 2, "", "success"},
+	}
+	var got []step
+	for _, e := range global {
+		got = append(got, step{e.Type, e.Iteration, e.FunctionName, e.Status})
+		if e.SessionID != "s1" || e.RequestID == "" || e.Timestamp.IsZero() {
+			t.Errorf("event %s missing context: %+v", e.Type, e)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+
+	// 函数开始和结束事件的调用 ID 一致
+	if global[2].CallID == "" || global[2].CallID != global[3].CallID {
+		t.Errorf("function call IDs = %q, %q", global[2].CallID, global[3].CallID)
+	}
+	// 请求级回调收到同样的事件
+	if !reflect.DeepEqual(request, global) {
+		t.Errorf("request handler got %d events, global handler %d", len(request), len(global))
+	}
+}
+
+func TestAgent_EmitsSinkEvents(t *testing.T) {
+	var events []observability.Event
+	observability.RegisterSink(observability.SinkFunc(func(e observability.Event) error {
+		events = append(events, e)
+		return nil
+	}))
+	t.Cleanup(observability.ResetSinks)
+
+	provider := &fakeProvider{name: "main", reply: "done", replies: []string{`<call name="notify"></call>`}}
+	agent := newTestAgent(t, provider, nil, &countingFunction{name: "notify"})
+	if _, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "notify"}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	// 先输出函数调用事件，对话结束时输出对话事件
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	call, chat := events[0], events[1]
+	if call.Kind != observability.EventFunctionCall || call.Name != "notify" || call.Status != "success" || call.SessionID != "s1" {
+		t.Errorf("function call event = %+v", call)
+	}
+	if chat.Kind != observability.EventChat || chat.Status != "success" || chat.SessionID != "s1" || chat.Attributes["function_calls"] != 1 {
+		t.Errorf("chat event = %+v", chat)
+	}
+}
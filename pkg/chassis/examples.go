@@ -0,0 +1,62 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+// Example few-shot 示例：一组 user/assistant 消息对，插入在系统提示之后、真实对话之前
+// Assistant 应按当前调用方式书写（xml 模式下包含 <call> 标签），示范正确的函数调用格式
+type Example struct {
+	// Function 示例所属的函数，为空表示始终注入；非空时只在该函数对调用者可用时注入
+	Function string `mapstructure:"function"`
+
+	// User 用户消息
+	User string `mapstructure:"user"`
+
+	// Assistant AI 的正确回复
+	Assistant string `mapstructure:"assistant"`
+}
+
+// exampleMessages 返回本次请求需要注入的示例消息，只包含调用者可用函数的示例
+func (a *Agent) exampleMessages(ctx context.Context) []llm.Message {
+	if len(a.config.Examples) == 0 {
+		return nil
+	}
+
+	available := make(map[string]bool)
+	for _, info := range a.registry.ListInfoForContext(ctx) {
+		available[info.Name] = true
+	}
+
+	var messages []llm.Message
+	for _, ex := range a.config.Examples {
+		if ex.Function != "" && !available[ex.Function] {
+			continue
+		}
+		messages = append(messages,
+			llm.Message{Role: llm.RoleUser, Content: ex.User},
+			llm.Message{Role: llm.RoleAssistant, Content: ex.Assistant},
+		)
+	}
+	return messages
+}
+
+// withExamples 返回在系统消息之后插入示例的消息副本，不修改会话本身
+// 示例不写入会话历史，避免被截断或在函数变化后过时
+func withExamples(messages, examples []llm.Message) []llm.Message {
+	if len(examples) == 0 {
+		return messages
+	}
+
+	out := make([]llm.Message, 0, len(messages)+len(examples))
+	rest := messages
+	if len(messages) > 0 && messages[0].Role == llm.RoleSystem {
+		out = append(out, messages[0])
+		rest = messages[1:]
+	}
+	out = append(out, examples...)
+	return append(out, rest...)
+}
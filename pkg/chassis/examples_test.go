@@ -0,0 +1,51 @@
+package chassis
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+func TestAgent_Examples(t *testing.T) {
+	provider := &fakeProvider{name: "main", reply: "ok"}
+	agent := newTestAgent(t, provider, func(c *AgentConfig) {
+		c.Examples = []Example{
+			{User: "hi", Assistant: "hello"},
+			{Function: "notify", User: "tell bob", Assistant: `<call name="notify"></call>`},
+			{Function: "transfer", User: "pay bob", Assistant: `<call name="transfer"></call>`},
+			{Function: "reset_all", User: "reset", Assistant: `<call name="reset_all"></call>`},
+		}
+	}, &countingFunction{name: "notify"}, &countingFunction{name: "reset_all"})
+	agent.registry.SetScopes("reset_all", "admin")
+
+	// 调用者没有 admin 作用域，reset_all 不可用；transfer 未注册
+	ctx := function.WithCallerScopes(context.Background(), []string{"tasks"})
+	if _, err := agent.Chat(ctx, ChatRequest{SessionID: "s1", Message: "notify bob"}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	request := provider.requests[0]
+	if request[0].Role != llm.RoleSystem {
+		t.Fatalf("first message role = %s, want system", request[0].Role)
+	}
+	want := []llm.Message{
+		{Role: llm.RoleUser, Content: "hi"},
+		{Role: llm.RoleAssistant, Content: "hello"},
+		{Role: llm.RoleUser, Content: "tell bob"},
+		{Role: llm.RoleAssistant, Content: `<call name="notify"></call>`},
+		{Role: llm.RoleUser, Content: "notify bob"},
+	}
+	if got := request[1:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("messages after the system prompt = %+v, want %+v", got, want)
+	}
+
+	// 示例不写入会话历史
+	for _, m := range agent.GetSession("s1").GetMessages() {
+		if m.Content == "hi" || m.Content == "tell bob" {
+			t.Errorf("example %q was stored in the session", m.Content)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/protocol"
+)
+
+// 导出格式
+const (
+	ExportFormatMarkdown = "markdown"
+	ExportFormatJSON     = "json"
+)
+
+// callBlockRe 匹配 AI 回复中的函数调用块
+var callBlockRe = regexp.MustCompile(`(?s)<call[^>]*>.*?</call>`)
+
+// SessionExport 会话的结构化导出
+type SessionExport struct {
+	SessionID string            `json:"session_id"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Messages  []ExportedMessage `json:"messages"`
+}
+
+// ExportedMessage 导出的单条消息
+// 函数结果消息只保留每个结果的摘要，去掉 XML 噪音
+type ExportedMessage struct {
+	Role            llm.Role                 `json:"role"`
+	Content         string                   `json:"content,omitempty"`
+	FunctionResults []protocol.ResultSummary `json:"function_results,omitempty"`
+	ToolCalls       []llm.ToolCall           `json:"tool_calls,omitempty"` // 原生 tools 模式下的函数调用
+}
+
+// Export 将会话导出为结构化数据
+// includeSystem 控制是否包含系统提示
+func (s *Session) Export(includeSystem bool) *SessionExport {
+	parser := protocol.NewParser()
+	export := &SessionExport{
+		SessionID: s.ID,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+		Messages:  make([]ExportedMessage, 0, len(s.Messages)),
+	}
+
+	for _, msg := range s.Messages {
+		if msg.Role == llm.RoleSystem && !includeSystem {
+			continue
+		}
+
+		exported := ExportedMessage{Role: msg.Role, ToolCalls: msg.ToolCalls}
+		if (msg.Role == llm.RoleUser || msg.Role == llm.RoleTool) && parser.HasResult(msg.Content) {
+			exported.FunctionResults = parser.ExtractResultSummaries(msg.Content)
+		} else {
+			exported.Content = msg.Content
+		}
+		export.Messages = append(export.Messages, exported)
+	}
+
+	return export
+}
+
+// ExportMarkdown 将会话渲染为可读的 Markdown 对话
+// 函数调用以代码块展示，函数结果只保留摘要
+func (s *Session) ExportMarkdown(includeSystem bool) string {
+	export := s.Export(includeSystem)
+
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("# 会话 %s\n\n", export.SessionID))
+	buf.WriteString(fmt.Sprintf("- 创建时间：%s\n", export.CreatedAt.Format(time.RFC3339)))
+	buf.WriteString(fmt.Sprintf("- 更新时间：%s\n", export.UpdatedAt.Format(time.RFC3339)))
+
+	for _, msg := range export.Messages {
+		buf.WriteString("\n---\n\n")
+
+		switch {
+		case len(msg.FunctionResults) > 0:
+			buf.WriteString("### ⚙️ Function Results\n\n")
+			for _, r := range msg.FunctionResults {
+				text := r.Message
+				if r.Status == protocol.StatusError {
+					text = r.Error
+				}
+				buf.WriteString(fmt.Sprintf("- `%s` (%s): %s\n", r.Name, r.Status, text))
+			}
+		case msg.Role == llm.RoleSystem:
+			buf.WriteString("### 🛠 System\n\n")
+			buf.WriteString(fenced("", msg.Content))
+		case msg.Role == llm.RoleAssistant:
+			buf.WriteString("### 🤖 Assistant\n\n")
+			buf.WriteString(renderAssistantMarkdown(msg.Content))
+			for _, tc := range msg.ToolCalls {
+				buf.WriteString(fmt.Sprintf("`%s`\n\n", tc.Name))
+				buf.WriteString(fenced("json", tc.Arguments))
+			}
+		default:
+			buf.WriteString("### 👤 User\n\n")
+			buf.WriteString(strings.TrimSpace(msg.Content) + "\n")
+		}
+	}
+
+	return buf.String()
+}
+
+// renderAssistantMarkdown 渲染 AI 回复，把函数调用块替换为代码块
+func renderAssistantMarkdown(content string) string {
+	var buf strings.Builder
+	last := 0
+	for _, loc := range callBlockRe.FindAllStringIndex(content, -1) {
+		if text := strings.TrimSpace(content[last:loc[0]]); text != "" {
+			buf.WriteString(text + "\n\n")
+		}
+		buf.WriteString(fenced("xml", content[loc[0]:loc[1]]))
+		buf.WriteString("\n")
+		last = loc[1]
+	}
+	if text := strings.TrimSpace(content[last:]); text != "" {
+		buf.WriteString(text + "\n")
+	}
+	return buf.String()
+}
+
+// fenced 用 Markdown 代码块包裹内容
+func fenced(lang, content string) string {
+	return "```" + lang + "\n" + strings.TrimSpace(content) + "\n```\n"
+}
+
+// ExportSession 复制一份会话用于导出
+// 复制期间持有 activeMu，会话上有进行中的对话时返回 ErrSessionBusy，避免读到写了一半的消息
+func (a *Agent) ExportSession(id string) (*Session, error) {
+	a.activeMu.Lock()
+	defer a.activeMu.Unlock()
+	if _, busy := a.active[id]; busy {
+		return nil, ErrSessionBusy
+	}
+
+	session := a.sessionManager.Get(id)
+	if session == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	snapshot := *session
+	snapshot.Messages = append([]llm.Message(nil), session.Messages...)
+	return &snapshot, nil
+}
+
+// SessionOwner 返回会话的创建者，会话不存在时返回 ErrSessionNotFound
+func (a *Agent) SessionOwner(id string) (string, error) {
+	session := a.sessionManager.Get(id)
+	if session == nil {
+		return "", fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return session.Owner, nil
+}
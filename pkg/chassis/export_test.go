@@ -0,0 +1,112 @@
+package chassis
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/protocol"
+)
+
+// exportSession 含系统提示、函数调用和函数结果的会话
+func exportSession(t *testing.T) *Session {
+	t.Helper()
+	result, err := protocol.NewEncoder().EncodeResult(&protocol.CallResult{
+		Name:    "weather",
+		Status:  protocol.StatusSuccess,
+		Message: "sunny",
+		Data:    map[string]any{"temp": 25},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Session{
+		ID: "s1",
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "secret system prompt"},
+			{Role: llm.RoleUser, Content: "weather?"},
+			{Role: llm.RoleAssistant, Content: `Let me check. <call name="weather"><city>Paris</city></call>`},
+			{Role: llm.RoleTool, Content: result},
+			{Role: llm.RoleAssistant, Content: "It is sunny."},
+		},
+	}
+}
+
+func TestSession_Export(t *testing.T) {
+	session := exportSession(t)
+
+	tests := []struct {
+		name          string
+		includeSystem bool
+		wantLen       int
+	}{
+		{"without system prompt", false, 4},
+		{"with system prompt", true, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := session.Export(tt.includeSystem)
+			if len(export.Messages) != tt.wantLen {
+				t.Fatalf("exported %d messages, want %d", len(export.Messages), tt.wantLen)
+			}
+			if hasSystem := export.Messages[0].Role == llm.RoleSystem; hasSystem != tt.includeSystem {
+				t.Errorf("first message role = %s, includeSystem = %v", export.Messages[0].Role, tt.includeSystem)
+			}
+
+			// 函数结果只保留摘要，不带 XML 原文和 data
+			results := export.Messages[len(export.Messages)-2]
+			if results.Content != "" {
+				t.Errorf("function result content = %q, want empty", results.Content)
+			}
+			want := protocol.ResultSummary{Name: "weather", Status: protocol.StatusSuccess, Message: "sunny"}
+			if len(results.FunctionResults) != 1 || results.FunctionResults[0] != want {
+				t.Errorf("function results = %+v, want [%+v]", results.FunctionResults, want)
+			}
+		})
+	}
+}
+
+func TestSession_ExportMarkdown(t *testing.T) {
+	session := exportSession(t)
+
+	md := session.ExportMarkdown(false)
+	if strings.Contains(md, "secret system prompt") {
+		t.Errorf("markdown includes the system prompt:\n%s", md)
+	}
+	if !strings.Contains(md, "Let me check.\n\n```xml\n<call name=\"weather\"><city>Paris</city></call>\n```") {
+		t.Errorf("call block is not rendered as a code block:\n%s", md)
+	}
+	if !strings.Contains(md, "- `weather` (success): sunny") || strings.Contains(md, "<result") || strings.Contains(md, "temp") {
+		t.Errorf("function result is not reduced to its message:\n%s", md)
+	}
+
+	if md := session.ExportMarkdown(true); !strings.Contains(md, "### 🛠 System\n\n```\nsecret system prompt\n```") {
+		t.Errorf("markdown misses the system prompt:\n%s", md)
+	}
+}
+
+func TestAgent_ExportSession(t *testing.T) {
+	agent := NewAgent(&fakeProvider{name: "fake"}, function.NewRegistry(), nil)
+	if _, err := agent.ExportSession("missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("ExportSession(missing) error = %v, want ErrSessionNotFound", err)
+	}
+
+	session := agent.sessionManager.GetOrCreate("s1", "api_key:web")
+	session.Messages = conversation(2)
+	snapshot, err := agent.ExportSession("s1")
+	if err != nil {
+		t.Fatalf("ExportSession: %v", err)
+	}
+	// 导出的是副本，之后的对话不影响已导出的内容
+	session.AddMessage(llm.RoleUser, "later")
+	if len(snapshot.Messages) != 3 || snapshot.Owner != "api_key:web" {
+		t.Errorf("snapshot = %+v", snapshot)
+	}
+
+	agent.active["s1"] = &activeChat{}
+	if _, err := agent.ExportSession("s1"); !errors.Is(err, ErrSessionBusy) {
+		t.Errorf("ExportSession during chat error = %v, want ErrSessionBusy", err)
+	}
+}
@@ -0,0 +1,90 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+// 会话分支相关错误
+var (
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrMessageIndexInvalid = errors.New("message index out of range")
+)
+
+// Fork 从源会话分叉出新会话，复制 Messages[0..upToMessageIndex]（含）的消息，源会话不受影响
+// upToMessageIndex 为 Session.Messages 中的下标（有系统提示时下标 0 是系统提示），小于 0 时复制全部消息；
+// 截止位置落在函数调用与其结果之间时，会丢弃末尾不完整的调用，保证新会话可以继续对话；
+// 新会话与源会话归属于同一调用方
+func (m *SessionManager) Fork(sourceID string, upToMessageIndex int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	source := m.load(sourceID)
+	if source == nil {
+		return "", fmt.Errorf("%w: %s", ErrSessionNotFound, sourceID)
+	}
+
+	end := len(source.Messages)
+	if upToMessageIndex >= 0 {
+		if upToMessageIndex >= len(source.Messages) {
+			return "", fmt.Errorf("%w: %d (session has %d messages)", ErrMessageIndexInvalid, upToMessageIndex, len(source.Messages))
+		}
+		end = upToMessageIndex + 1
+	}
+
+	messages := make([]llm.Message, 0, end)
+	for _, msg := range trimIncompleteToolCalls(source.Messages[:end]) {
+		msg.ToolCalls = append([]llm.ToolCall(nil), msg.ToolCalls...)
+		messages = append(messages, msg)
+	}
+
+	now := time.Now()
+	forked := &Session{
+		ID:             generateSessionID(),
+		Messages:       messages,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		PromptVersion:  source.PromptVersion,
+		PromptChannel:  source.PromptChannel,
+		PromptLanguage: source.PromptLanguage,
+		Owner:          source.Owner,
+	}
+	m.sessions[forked.ID] = forked
+	if m.store != nil {
+		if err := m.store.Save(forked); err != nil {
+			m.log().Warn("Failed to save session", "session_id", forked.ID, "error", err)
+		}
+	}
+	return forked.ID, nil
+}
+
+// trimIncompleteToolCalls 去掉末尾缺少结果的工具调用
+// 原生 tools 模式下 assistant 的 tool_calls 必须紧跟每个调用的 tool 结果，否则 API 会拒绝请求
+func trimIncompleteToolCalls(messages []llm.Message) []llm.Message {
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role == llm.RoleTool {
+			continue
+		}
+		if msg.Role == llm.RoleAssistant && len(msg.ToolCalls) > len(messages)-1-i {
+			return messages[:i]
+		}
+		break
+	}
+	return messages
+}
+
+// ForkSession 从指定会话的某条消息处分叉出新会话，返回新会话 ID
+// 复制期间持有 activeMu，源会话上有进行中的对话时返回 ErrSessionBusy，避免复制到写了一半的消息
+func (a *Agent) ForkSession(sourceID string, upToMessageIndex int) (string, error) {
+	a.activeMu.Lock()
+	defer a.activeMu.Unlock()
+	if _, busy := a.active[sourceID]; busy {
+		return "", ErrSessionBusy
+	}
+	return a.sessionManager.Fork(sourceID, upToMessageIndex)
+}
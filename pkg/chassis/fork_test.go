@@ -0,0 +1,109 @@
+package chassis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+func TestSessionManager_Fork(t *testing.T) {
+	toolSession := []llm.Message{
+		{Role: llm.RoleSystem, Content: "sys"},
+		{Role: llm.RoleUser, Content: "q"},
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "1", Name: "f"}, {ID: "2", Name: "g"}}},
+		{Role: llm.RoleTool, ToolCallID: "1", Content: "r1"},
+		{Role: llm.RoleTool, ToolCallID: "2", Content: "r2"},
+		{Role: llm.RoleAssistant, Content: "answer"},
+	}
+
+	tests := []struct {
+		name     string
+		messages []llm.Message
+		upTo     int
+		wantLen  int
+		wantErr  error
+	}{
+		{"copies everything", conversation(4), -1, 5, nil},
+		{"copies up to index", conversation(4), 2, 3, nil},
+		{"system prompt only", conversation(4), 0, 1, nil},
+		{"index out of range", conversation(4), 5, 0, ErrMessageIndexInvalid},
+		{"drops call without results", toolSession, 2, 2, nil},
+		{"drops call with partial results", toolSession, 3, 2, nil},
+		{"keeps call with all results", toolSession, 4, 5, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewSessionManager(nil)
+			source := m.GetOrCreate("src", "")
+			source.Messages = tt.messages
+
+			id, err := m.Fork("src", tt.upTo)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Fork() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			forked := m.Get(id)
+			if forked == nil || id == "src" {
+				t.Fatalf("forked session %q not created", id)
+			}
+			if got := len(forked.Messages); got != tt.wantLen {
+				t.Errorf("len(Messages) = %d, want %d", got, tt.wantLen)
+			}
+			if got := len(source.Messages); got != len(tt.messages) {
+				t.Errorf("source changed: %d messages", got)
+			}
+		})
+	}
+}
+
+func TestSessionManager_ForkIsIndependent(t *testing.T) {
+	m := NewSessionManager(nil)
+	source := m.GetOrCreate("src", "")
+	source.Messages = []llm.Message{
+		{Role: llm.RoleUser, Content: "q"},
+		{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "1", Name: "f"}}},
+		{Role: llm.RoleTool, ToolCallID: "1", Content: "r"},
+	}
+
+	id, err := m.Fork("src", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forked := m.Get(id)
+	forked.Messages[0].Content = "changed"
+	forked.Messages[1].ToolCalls[0].Name = "changed"
+	forked.Messages = append(forked.Messages, llm.Message{Role: llm.RoleUser, Content: "more"})
+
+	if source.Messages[0].Content != "q" || source.Messages[1].ToolCalls[0].Name != "f" || len(source.Messages) != 3 {
+		t.Errorf("editing the fork changed the source: %+v", source.Messages)
+	}
+}
+
+func TestSessionManager_ForkNotFound(t *testing.T) {
+	if _, err := NewSessionManager(nil).Fork("missing", -1); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Fork() error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestAgent_ForkSessionBusy(t *testing.T) {
+	agent := NewAgent(&fakeProvider{name: "fake"}, function.NewRegistry(), nil)
+	agent.sessionManager.GetOrCreate("src", "").Messages = conversation(2)
+	agent.active["src"] = &activeChat{}
+
+	if _, err := agent.ForkSession("src", -1); !errors.Is(err, ErrSessionBusy) {
+		t.Fatalf("ForkSession() error = %v, want ErrSessionBusy", err)
+	}
+	if got := len(agent.sessionManager.List()); got != 1 {
+		t.Errorf("%d sessions, want only the source", got)
+	}
+
+	delete(agent.active, "src")
+	if _, err := agent.ForkSession("src", -1); err != nil {
+		t.Errorf("ForkSession() after chat ended: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+// Version 框架版本号
+const Version = "0.1.0"
+
+// 健康状态
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusUnhealthy = "unhealthy"
+)
+
+// HealthCheck 单项依赖检查结果
+type HealthCheck struct {
+	Status string `json:"status"`          // healthy, unhealthy
+	Error  string `json:"error,omitempty"` // 不健康时的原因
+}
+
+// HealthReport 整体健康报告
+type HealthReport struct {
+	Status    string                 `json:"status"`
+	Checks    map[string]HealthCheck `json:"checks"`
+	LLMCache  *llm.CacheStats        `json:"llm_cache,omitempty"` // 开启响应缓存时的命中统计
+	Version   string                 `json:"version"`
+	Timestamp int64                  `json:"timestamp"`
+}
+
+// IsHealthy 是否所有依赖都就绪
+func (r *HealthReport) IsHealthy() bool {
+	return r.Status == HealthStatusHealthy
+}
+
+// llmProbeTTL LLM 探测结果的缓存时间，避免每次健康检查都请求上游 API
+const llmProbeTTL = 30 * time.Second
+
+// llmProbe 缓存最近一次 LLM 探测结果
+type llmProbe struct {
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// pingLLM 探测 LLM Provider 是否可用，结果缓存 llmProbeTTL
+// 探测使用独立的短超时（llm.DefaultPingTimeout），不影响对话请求
+func (a *App) pingLLM(ctx context.Context) error {
+	a.llmProbe.mu.Lock()
+	defer a.llmProbe.mu.Unlock()
+
+	if !a.llmProbe.checked.IsZero() && time.Since(a.llmProbe.checked) < llmProbeTTL {
+		return a.llmProbe.err
+	}
+
+	err := llm.Ping(ctx, a.provider, llm.DefaultPingTimeout)
+	// 调用方取消导致的失败不代表 Provider 不可用，不缓存
+	if ctx.Err() == nil {
+		a.llmProbe.checked = time.Now()
+		a.llmProbe.err = err
+	}
+	return err
+}
+
+// CheckHealth 检查关键依赖的就绪状态
+// 包括：数据库连接、LLM Provider（实现 llm.Pinger 时实际探测）、调度器
+func (a *App) CheckHealth(ctx context.Context) *HealthReport {
+	report := &HealthReport{
+		Status:    HealthStatusHealthy,
+		Checks:    make(map[string]HealthCheck),
+		Version:   Version,
+		Timestamp: time.Now().Unix(),
+	}
+
+	// 数据库
+	if err := a.dbs.Ping(); err != nil {
+		report.Checks["database"] = unhealthy(err.Error())
+	} else {
+		report.Checks["database"] = healthy()
+	}
+
+	// LLM Provider
+	if a.provider == nil {
+		report.Checks["llm"] = unhealthy("provider not initialized")
+	} else if breaker, ok := llm.As[*llm.CircuitBreaker](a.provider); ok && breaker.State() == llm.BreakerOpen {
+		report.Checks["llm"] = unhealthy("circuit breaker is open")
+	} else if err := a.pingLLM(ctx); err != nil {
+		report.Checks["llm"] = unhealthy(err.Error())
+	} else {
+		report.Checks["llm"] = healthy()
+	}
+	if cache, ok := llm.As[*llm.CachingProvider](a.provider); ok {
+		stats := cache.Stats()
+		report.LLMCache = &stats
+	}
+
+	// 调度器
+	switch {
+	case a.delayScheduler == nil || !a.delayScheduler.IsRunning():
+		report.Checks["scheduler"] = unhealthy("delay scheduler not running")
+	case a.cronScheduler == nil || !a.cronScheduler.IsRunning():
+		report.Checks["scheduler"] = unhealthy("cron scheduler not running")
+	default:
+		report.Checks["scheduler"] = healthy()
+	}
+
+	for _, check := range report.Checks {
+		if check.Status != HealthStatusHealthy {
+			report.Status = HealthStatusUnhealthy
+			break
+		}
+	}
+
+	return report
+}
+
+func healthy() HealthCheck {
+	return HealthCheck{Status: HealthStatusHealthy}
+}
+
+func unhealthy(reason string) HealthCheck {
+	return HealthCheck{Status: HealthStatusUnhealthy, Error: reason}
+}
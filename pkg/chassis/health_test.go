@@ -0,0 +1,66 @@
+package chassis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// pingingProvider 记录 Ping 次数的测试 Provider
+type pingingProvider struct {
+	fakeProvider
+	err   error
+	pings int
+}
+
+func (p *pingingProvider) Ping(ctx context.Context) error {
+	p.pings++
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.err
+}
+
+func TestApp_PingLLMCached(t *testing.T) {
+	provider := &pingingProvider{fakeProvider: fakeProvider{name: "fake"}, err: errors.New("unauthorized")}
+	app := &App{provider: provider}
+
+	for i := 0; i < 3; i++ {
+		if err := app.pingLLM(context.Background()); err == nil || err.Error() != "unauthorized" {
+			t.Fatalf("pingLLM error = %v, want unauthorized", err)
+		}
+	}
+	if provider.pings != 1 {
+		t.Errorf("provider pinged %d times within llmProbeTTL, want 1", provider.pings)
+	}
+
+	// 缓存过期后重新探测
+	provider.err = nil
+	app.llmProbe.checked = time.Now().Add(-llmProbeTTL)
+	if err := app.pingLLM(context.Background()); err != nil {
+		t.Fatalf("pingLLM after TTL error = %v", err)
+	}
+	if provider.pings != 2 {
+		t.Errorf("provider pinged %d times after TTL, want 2", provider.pings)
+	}
+}
+
+func TestApp_PingLLMCancelledNotCached(t *testing.T) {
+	provider := &pingingProvider{fakeProvider: fakeProvider{name: "fake"}}
+	app := &App{provider: provider}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := app.pingLLM(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("pingLLM with cancelled ctx error = %v, want context.Canceled", err)
+	}
+
+	// 调用方取消导致的失败不缓存，下一次健康检查重新探测
+	if err := app.pingLLM(context.Background()); err != nil {
+		t.Fatalf("pingLLM error = %v, want nil", err)
+	}
+	if provider.pings != 2 {
+		t.Errorf("provider pinged %d times, want 2", provider.pings)
+	}
+}
@@ -0,0 +1,79 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"strings"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/memory"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// SetMemoryRepository 设置用户记忆仓库，设置后对话时会把用户的记忆提供给 AI
+func (a *Agent) SetMemoryRepository(repo *memory.Repository) {
+	a.memoryRepo = repo
+}
+
+// requestUserID 返回请求的用户标识：优先使用 UserID，否则由渠道类型和聊天 ID 组成（如 telegram:123456）
+func requestUserID(req ChatRequest) string {
+	if req.UserID != "" {
+		return req.UserID
+	}
+	if req.Channel != nil && req.Channel.ChatID != "" {
+		return req.Channel.Type + ":" + req.Channel.ChatID
+	}
+	return ""
+}
+
+// memoryPrompt 生成用户记忆的系统提示片段，没有记忆时返回空字符串
+func (a *Agent) memoryPrompt(ctx context.Context, userID string) string {
+	if a.memoryRepo == nil || userID == "" {
+		return ""
+	}
+
+	memories, err := a.memoryRepo.List(userID)
+	if err != nil {
+		observability.WarnContext(ctx, "Failed to load user memories", "user_id", userID, "error", err)
+		return ""
+	}
+	if len(memories) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("## User Memory\n\nThings you remember about this user (use remember to update them):\n")
+	for _, m := range memories {
+		b.WriteString("- " + m.Key + ": " + m.Value + "\n")
+	}
+	return b.String()
+}
+
+// joinPromptSections 合并只拼进本次请求的系统提示片段，忽略空片段
+func joinPromptSections(sections ...string) string {
+	var parts []string
+	for _, s := range sections {
+		if s = strings.TrimSpace(s); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// withMemoryPrompt 返回把记忆追加到系统消息后的消息副本，不修改会话本身
+// 记忆可能随时变化，每次请求时拼接而不是写入会话历史
+func withMemoryPrompt(messages []llm.Message, section string) []llm.Message {
+	if section == "" {
+		return messages
+	}
+
+	out := make([]llm.Message, 0, len(messages)+1)
+	if len(messages) > 0 && messages[0].Role == llm.RoleSystem {
+		system := messages[0]
+		system.Content = strings.TrimRight(system.Content, "\n") + "\n\n" + section
+		out = append(out, system)
+		return append(out, messages[1:]...)
+	}
+	out = append(out, llm.Message{Role: llm.RoleSystem, Content: section})
+	return append(out, messages...)
+}
@@ -2,19 +2,275 @@
 package chassis
 
 import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
 	"time"
 
+	"github.com/KodaTao/AgentChassis/pkg/function"
 	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/prompt"
 )
 
 // Config 应用配置
 type Config struct {
-	Server        ServerConfig        `mapstructure:"server"`
-	LLM           llm.Config          `mapstructure:"llm"`
-	Database      DatabaseConfig      `mapstructure:"database"`
-	Log           LogConfig           `mapstructure:"log"`
-	Observability ObservabilityConfig `mapstructure:"observability"`
-	Telegram      TelegramConfig      `mapstructure:"telegram"`
+	Server        ServerConfig         `mapstructure:"server"`
+	LLM           llm.Config           `mapstructure:"llm"`
+	Database      DatabaseConfig       `mapstructure:"database"`
+	Log           LogConfig            `mapstructure:"log"`
+	Observability ObservabilityConfig  `mapstructure:"observability"`
+	Telegram      TelegramConfig       `mapstructure:"telegram"`
+	Webhook       WebhookConfig        `mapstructure:"webhook"`
+	Auth          AuthConfig           `mapstructure:"auth"`
+	Agent         AgentSettings        `mapstructure:"agent"`
+	Builtins      BuiltinsConfig       `mapstructure:"builtins"`
+	Audit         AuditConfig          `mapstructure:"audit"`
+	Console       ConsoleConfig        `mapstructure:"console"`
+	Scheduler     SchedulerConfig      `mapstructure:"scheduler"`
+	Session       SessionStorageConfig `mapstructure:"session"`
+	Resources     ResourceConfig       `mapstructure:"resources"`
+	MCP           MCPConfig            `mapstructure:"mcp"`
+
+	// PromptVars 注入到系统提示词的自定义变量（如用户名、地点）
+	PromptVars map[string]any `mapstructure:"prompt_vars"`
+
+	// Prompt 外置的系统提示词模板
+	Prompt PromptConfig `mapstructure:"prompt"`
+
+	// DedupCalls 同一轮内完全相同的函数调用只执行一次，默认开启
+	DedupCalls bool `mapstructure:"dedup_calls"`
+
+	// MaxHistoryTokens 会话历史的估算 token 上限，0 表示只按条数截断
+	MaxHistoryTokens int `mapstructure:"max_history_tokens"`
+
+	// RateLimits 按函数名限制每分钟最多调用次数（所有会话共享），覆盖函数自己声明的限制
+	RateLimits map[string]int `mapstructure:"function_rate_limits"`
+
+	// logger 由 WithLogger 指定的 logger，为空时按 Log 配置创建并设置为全局默认实例
+	logger *slog.Logger
+
+	// mcpMode 由 WithMCPMode 设置，只初始化函数及其依赖，不启动调度器和 Telegram Bot
+	mcpMode bool
+}
+
+// PromptConfig 外置系统提示词模板配置
+// 模板文件不存在时使用内置模板，修改提示词无需重新编译
+type PromptConfig struct {
+	// SystemTemplatePath XML 调用模式的系统提示词模板文件，语法与 templates.SystemPrompt 相同
+	SystemTemplatePath string `mapstructure:"system_template_path"`
+
+	// ToolsTemplatePath 原生 function calling 模式（call_mode: tools）的系统提示词模板文件
+	ToolsTemplatePath string `mapstructure:"tools_template_path"`
+
+	// HotReload 模板文件修改后自动重新加载，对之后新建的会话生效
+	HotReload bool `mapstructure:"hot_reload"`
+
+	// ReloadInterval 检查模板文件变化的间隔，默认 2s
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+}
+
+// files 返回配置的模板文件
+func (c PromptConfig) files() prompt.TemplateFiles {
+	return prompt.TemplateFiles{System: c.SystemTemplatePath, Tools: c.ToolsTemplatePath}
+}
+
+// AuthConfig HTTP API 鉴权配置
+type AuthConfig struct {
+	// APIKeys 允许访问的 API Key 及其作用域，为空时不开启鉴权
+	APIKeys []APIKeyConfig `mapstructure:"api_keys"`
+
+	// FunctionScopes 函数所需的作用域（函数名 -> 作用域列表），覆盖函数自身的声明
+	FunctionScopes map[string][]string `mapstructure:"function_scopes"`
+}
+
+// MCPConfig agent mcp 命令的配置
+type MCPConfig struct {
+	// Scopes MCP 客户端拥有的作用域，为空时只能调用不需要作用域的函数；
+	// 需要作用域的函数（如管理类函数）必须显式配置对应作用域或 "*"
+	Scopes []string `mapstructure:"scopes"`
+}
+
+// APIKeyConfig 单个 API Key 配置
+type APIKeyConfig struct {
+	// Name Key 的名称，仅用于日志
+	Name string `mapstructure:"name"`
+
+	// Key API Key，支持 ${ENV} 引用环境变量
+	Key string `mapstructure:"key"`
+
+	// Scopes 该 Key 拥有的作用域，"*" 表示全部
+	Scopes []string `mapstructure:"scopes"`
+}
+
+// Enabled 是否开启鉴权
+func (c AuthConfig) Enabled() bool {
+	return len(c.APIKeys) > 0
+}
+
+// WebhookConfig webhook 函数配置
+type WebhookConfig struct {
+	// AllowPrivateNetworks 是否允许 webhook 目标为内网/回环地址（仅用于本地开发）
+	AllowPrivateNetworks bool `mapstructure:"allow_private_networks"`
+}
+
+// AgentSettings Agent 行为配置（配置文件中的 agent 段）
+// 数值字段为零值时使用 DefaultAgentConfig 的默认值
+type AgentSettings struct {
+	// MaxIterations 单次对话中 LLM 调用的最大轮数，默认 10
+	MaxIterations int `mapstructure:"max_iterations"`
+
+	// Timeout 单次对话的总超时，默认 5m
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// MaxUnknownCalls 连续调用不存在函数的次数上限，默认 3
+	MaxUnknownCalls int `mapstructure:"max_unknown_calls"`
+
+	// ParallelCalls 同一轮中的多个函数调用是否并行执行
+	ParallelCalls bool `mapstructure:"parallel_calls"`
+
+	// Persona 助手的人设描述，会加入系统提示词
+	Persona string `mapstructure:"persona"`
+
+	// ChannelPrompts 按渠道类型附加的系统提示指令，如 telegram、api（未指定渠道的 HTTP 请求）
+	ChannelPrompts map[string]string `mapstructure:"channel_prompts"`
+
+	// EmptyReplyRetries AI 返回空回复时的重试次数，默认 1，负数表示不重试
+	EmptyReplyRetries int `mapstructure:"empty_reply_retries"`
+
+	// Examples few-shot 示例，可按函数分组，只在相关函数可用时注入
+	Examples []Example `mapstructure:"examples"`
+
+	// MaxFunctionCalls 单次请求中最多执行的函数调用数，0 表示不限制
+	MaxFunctionCalls int `mapstructure:"max_function_calls"`
+
+	// ShowThoughts 在响应中返回每轮函数调用前的说明文字（thoughts 字段）
+	ShowThoughts bool `mapstructure:"show_thoughts"`
+
+	// MaxResultChars 单个函数结果反馈给 AI 时的字符数上限，默认 16000，负数表示不限制
+	MaxResultChars int `mapstructure:"max_result_chars"`
+
+	// SummarizeResults 超长结果先调用 LLM 摘要，失败时再截断
+	SummarizeResults bool `mapstructure:"summarize_results"`
+
+	// SlimResults 新一轮对话开始时把之前轮次的函数结果精简为只保留 message/error，减少会话占用和 token
+	SlimResults bool `mapstructure:"slim_results"`
+
+	// TransientRetries 函数临时失败时自动重试的次数，默认 1，负数表示不重试
+	TransientRetries int `mapstructure:"transient_retries"`
+
+	// Language 系统提示词的默认语言：en（默认）、zh、ja，请求可通过 language 字段覆盖
+	Language string `mapstructure:"language"`
+
+	// ApprovalFunctions 需要管理员审批后才执行的函数，通过 /api/v1/approvals 审批
+	ApprovalFunctions []string `mapstructure:"approval_functions"`
+
+	// Summary 会话自动摘要：消息超过阈值时后台调用 LLM 压缩最旧的消息，比硬截断保留更多上下文
+	Summary SummaryConfig `mapstructure:"summary"`
+}
+
+// AuditConfig 对话审计日志配置
+// 开启后每次 Chat 的输入、输出、函数调用和 token 用量都会结构化落库，独立于普通日志
+type AuditConfig struct {
+	// Enabled 是否开启审计日志
+	Enabled bool `mapstructure:"enabled"`
+
+	// MaskPII 写入前对邮箱、手机号、身份证号、银行卡号做部分遮盖
+	MaskPII bool `mapstructure:"mask_pii"`
+
+	// RetentionDays 审计记录保留天数，超期记录每小时清理一次，0 表示永久保留
+	RetentionDays int `mapstructure:"retention_days"`
+}
+
+// SchedulerConfig 任务调度配置
+type SchedulerConfig struct {
+	// DelayMaxConcurrent 同时执行的延时任务数上限，超出时按优先级排队，默认 4
+	DelayMaxConcurrent int `mapstructure:"delay_max_concurrent"`
+
+	// DelayPastTolerance 延时任务的 run_at 早于当前时间多久以内视为立即执行（吸收时钟漂移），默认 10s，负数表示不容忍
+	DelayPastTolerance time.Duration `mapstructure:"delay_past_tolerance"`
+}
+
+// SessionStorageConfig 会话持久化配置
+// 开启后会话在每次对话结束时写入会话库，重启后可以继续；内存中没有的会话在访问时从库中读回
+type SessionStorageConfig struct {
+	// Persist 是否把会话写入会话库
+	Persist bool `mapstructure:"persist"`
+
+	// Compress 写入前用 gzip 压缩消息，读回时自动解压；切换开关不影响已写入会话的读取
+	Compress bool `mapstructure:"compress"`
+}
+
+// ResourceConfig 函数资源监控配置
+// 数值字段为零值时使用默认值，负数表示不检查该项
+type ResourceConfig struct {
+	// Enabled 开启函数执行前后的资源采样，默认关闭
+	// 采样的是整个进程的 goroutine 数和堆分配量，不是单个函数的用量，函数并发执行时会互相干扰
+	Enabled bool `mapstructure:"enabled"`
+
+	// GoroutineGrowth 单次调用结束后残留的 goroutine 数上限，默认 10
+	GoroutineGrowth int `mapstructure:"goroutine_growth"`
+
+	// HeapAllocMB 单次调用期间的堆分配量上限（MB），默认 64
+	HeapAllocMB int `mapstructure:"heap_alloc_mb"`
+
+	// UnhealthyAfter 连续异常多少次后把函数标记为不健康，默认 3
+	UnhealthyAfter int `mapstructure:"unhealthy_after"`
+}
+
+// Thresholds 转换为资源监控的判定阈值
+func (c ResourceConfig) Thresholds() function.ResourceThresholds {
+	thresholds := function.DefaultResourceThresholds()
+	if c.GoroutineGrowth != 0 {
+		thresholds.GoroutineGrowth = c.GoroutineGrowth
+	}
+	if c.HeapAllocMB > 0 {
+		thresholds.HeapAllocBytes = uint64(c.HeapAllocMB) << 20
+	} else if c.HeapAllocMB < 0 {
+		thresholds.HeapAllocBytes = 0
+	}
+	if c.UnhealthyAfter != 0 {
+		thresholds.UnhealthyAfter = c.UnhealthyAfter
+	}
+	return thresholds
+}
+
+// console 渠道的输出目标
+const (
+	ConsoleOutputLog    = "log"    // 只写结构化日志（默认）
+	ConsoleOutputStdout = "stdout" // 同时写到标准输出
+	ConsoleOutputStderr = "stderr" // 同时写到标准错误
+)
+
+// ConsoleConfig send_message 的 console 渠道配置
+type ConsoleConfig struct {
+	// Output 输出目标：log（默认，只写日志）、stdout、stderr
+	Output string `mapstructure:"output"`
+
+	// Pretty 写到终端时使用框线美化，适合本地调试
+	Pretty bool `mapstructure:"pretty"`
+}
+
+// Validate 校验输出目标
+func (c ConsoleConfig) Validate() error {
+	switch c.Output {
+	case "", ConsoleOutputLog, ConsoleOutputStdout, ConsoleOutputStderr:
+		return nil
+	default:
+		return fmt.Errorf("unsupported console output: %s (want %s, %s or %s)", c.Output, ConsoleOutputLog, ConsoleOutputStdout, ConsoleOutputStderr)
+	}
+}
+
+// writer 返回输出目标对应的 Writer，只写日志时返回 nil
+func (c ConsoleConfig) writer() io.Writer {
+	switch c.Output {
+	case ConsoleOutputStdout:
+		return os.Stdout
+	case ConsoleOutputStderr:
+		return os.Stderr
+	default:
+		return nil
+	}
 }
 
 // TelegramConfig Telegram Bot 配置
@@ -27,6 +283,23 @@ type TelegramConfig struct {
 
 	// SessionTTL Session 映射保留时间
 	SessionTTL time.Duration `mapstructure:"session_ttl"`
+
+	// AllowedChatIDs 允许的会话 ID 白名单（私聊为用户 ID，群聊为群 ID）
+	AllowedChatIDs []int64 `mapstructure:"allowed_chat_ids"`
+
+	// AllowedUsernames 允许的用户名白名单
+	// 与 AllowedChatIDs 均为空时不限制
+	AllowedUsernames []string `mapstructure:"allowed_usernames"`
+
+	// MaxQueuedMessages 每个 chat 最多排队的消息数
+	// 同一 chat 的消息串行处理，超出上限的消息会被丢弃并提示用户
+	MaxQueuedMessages int `mapstructure:"max_queued_messages"`
+
+	// AcceptImages 是否接收用户发送的图片，作为多模态输入传给模型（需要模型支持，如 gpt-4o）
+	AcceptImages bool `mapstructure:"accept_images"`
+
+	// MaxImageBytes 单张图片最大字节数，0 使用默认值（10MB）
+	MaxImageBytes int `mapstructure:"max_image_bytes"`
 }
 
 // ServerConfig 服务器配置
@@ -39,12 +312,39 @@ type ServerConfig struct {
 
 	// Mode 运行模式：debug, release, test
 	Mode string `mapstructure:"mode"`
+
+	// ShutdownTimeout 优雅关闭时等待在途任务完成的最长时间
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+
+	// MaxBodyBytes 请求体大小上限（字节），超限返回 413，负数不限制
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes"`
+
+	// MaxMessageChars 对话消息的字符数上限，负数不限制
+	MaxMessageChars int `mapstructure:"max_message_chars"`
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
 	// Path 数据库文件路径
 	Path string `mapstructure:"path"`
+
+	// SchedulerPath 延时任务、定时任务及执行历史的数据库路径，为空时与 Path 共用
+	SchedulerPath string `mapstructure:"scheduler_path"`
+
+	// SessionPath 会话相关数据（函数调用记录）的数据库路径，为空时与 Path 共用
+	SessionPath string `mapstructure:"session_path"`
+
+	// JournalMode SQLite 日志模式，默认 WAL
+	JournalMode string `mapstructure:"journal_mode"`
+
+	// BusyTimeout 遇到锁时的等待时间，默认 5s
+	BusyTimeout time.Duration `mapstructure:"busy_timeout"`
+
+	// Synchronous 同步级别，默认 NORMAL
+	Synchronous string `mapstructure:"synchronous"`
+
+	// MaxOpenConns 最大连接数，默认 1
+	MaxOpenConns int `mapstructure:"max_open_conns"`
 }
 
 // LogConfig 日志配置
@@ -55,17 +355,46 @@ type LogConfig struct {
 	// Format 日志格式：text, json
 	Format string `mapstructure:"format"`
 
-	// Output 输出目标：stdout, file
+	// Output 输出目标：stdout, stderr, file
 	Output string `mapstructure:"output"`
 
 	// FilePath 日志文件路径（当 Output 为 file 时生效）
 	FilePath string `mapstructure:"file_path"`
+
+	// MaxSizeMB 单个日志文件最大大小（MB），超过后轮转
+	MaxSizeMB int `mapstructure:"max_size_mb"`
+
+	// MaxBackups 保留的旧日志文件数量
+	MaxBackups int `mapstructure:"max_backups"`
+
+	// MaxAgeDays 旧日志文件保留天数
+	MaxAgeDays int `mapstructure:"max_age_days"`
+
+	// Compress 是否压缩旧日志文件
+	Compress bool `mapstructure:"compress"`
+
+	// LLMVerbose 是否记录发给 LLM 的完整 messages 和原始响应（含对话内容，生产环境建议关闭）
+	LLMVerbose bool `mapstructure:"llm_verbose"`
+
+	// LLMVerboseMaxChars 完整内容日志中单条内容的最大字符数
+	LLMVerboseMaxChars int `mapstructure:"llm_verbose_max_chars"`
 }
 
 // ObservabilityConfig 可观测性配置
 type ObservabilityConfig struct {
 	Metrics MetricsConfig `mapstructure:"metrics"`
 	Tracing TracingConfig `mapstructure:"tracing"`
+	Events  EventsConfig  `mapstructure:"events"`
+}
+
+// EventsConfig 结构化事件流配置
+// 对话、函数调用、任务执行等关键事件以 JSON Lines 格式输出，便于导入外部分析系统
+type EventsConfig struct {
+	// Output 内置输出目标：stdout、file，为空时不启用内置输出（仍可通过 App.RegisterSink 注册自定义 Sink）
+	Output string `mapstructure:"output"`
+
+	// FilePath Output 为 file 时的文件路径
+	FilePath string `mapstructure:"file_path"`
 }
 
 // MetricsConfig 指标配置
@@ -90,25 +419,37 @@ type TracingConfig struct {
 func DefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Host: "0.0.0.0",
-			Port: 8080,
-			Mode: "debug",
+			Host:            "0.0.0.0",
+			Port:            8080,
+			Mode:            "debug",
+			ShutdownTimeout: 30 * time.Second,
+			MaxBodyBytes:    1 << 20,
+			MaxMessageChars: 16000,
 		},
 		LLM: llm.Config{
 			Provider:    "openai",
 			BaseURL:     "https://api.openai.com/v1",
 			Model:       "gpt-4",
 			Timeout:     60,
-			MaxTokens:   4096,
-			Temperature: 0.7,
+			MaxTokens:   llm.Int(4096),
+			Temperature: llm.Float64(0.7),
 		},
 		Database: DatabaseConfig{
-			Path: "~/.agentchassis/data.db",
+			Path:         "~/.agentchassis/data.db",
+			JournalMode:  "WAL",
+			BusyTimeout:  5 * time.Second,
+			Synchronous:  "NORMAL",
+			MaxOpenConns: 1,
 		},
 		Log: LogConfig{
-			Level:  "info",
-			Format: "text",
-			Output: "stdout",
+			Level:      "info",
+			Format:     "text",
+			Output:     "stdout",
+			MaxSizeMB:  100,
+			MaxBackups: 7,
+			MaxAgeDays: 30,
+
+			LLMVerboseMaxChars: 2000,
 		},
 		Observability: ObservabilityConfig{
 			Metrics: MetricsConfig{
@@ -123,7 +464,15 @@ func DefaultConfig() *Config {
 			Enabled:    false,
 			Token:      "",
 			SessionTTL: 24 * time.Hour,
+
+			MaxQueuedMessages: 10,
 		},
+		Agent: AgentSettings{
+			MaxIterations:   10,
+			Timeout:         5 * time.Minute,
+			MaxUnknownCalls: 3,
+		},
+		DedupCalls: true,
 	}
 }
 
@@ -144,6 +493,13 @@ func WithServerMode(mode string) Option {
 	}
 }
 
+// WithShutdownTimeout 设置优雅关闭的等待时间
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.Server.ShutdownTimeout = timeout
+	}
+}
+
 // WithLLMConfig 设置 LLM 配置
 func WithLLMConfig(cfg llm.Config) Option {
 	return func(c *Config) {
@@ -158,6 +514,30 @@ func WithLogLevel(level string) Option {
 	}
 }
 
+// WithLogConfig 设置完整的日志配置
+func WithLogConfig(cfg LogConfig) Option {
+	return func(c *Config) {
+		c.Log = cfg
+	}
+}
+
+// WithLogger 指定 App 使用的 logger，此时 Log 配置不再生效，也不会修改全局 logger
+// 同一进程中运行多个 App 实例时，应为每个实例指定各自的 logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Config) {
+		c.logger = logger
+	}
+}
+
+// WithMCPMode 以 MCP server 模式初始化（agent mcp）：只提供函数注册表和执行器，
+// 不启动延时/定时调度器和 Telegram Bot，也不注册依赖调度器的 delay_* / cron_* 函数。
+// MCP 客户端通常与 serve 进程共用配置和数据库，两边都启动调度器会重复执行任务，Bot 也会争抢消息
+func WithMCPMode() Option {
+	return func(c *Config) {
+		c.mcpMode = true
+	}
+}
+
 // WithDatabasePath 设置数据库路径
 func WithDatabasePath(path string) Option {
 	return func(c *Config) {
@@ -165,6 +545,13 @@ func WithDatabasePath(path string) Option {
 	}
 }
 
+// WithDatabaseConfig 设置完整的数据库配置
+func WithDatabaseConfig(cfg DatabaseConfig) Option {
+	return func(c *Config) {
+		c.Database = cfg
+	}
+}
+
 // WithTelegram 设置telegram设置
 func WithTelegram(t TelegramConfig) Option {
 	return func(c *Config) {
@@ -172,6 +559,113 @@ func WithTelegram(t TelegramConfig) Option {
 	}
 }
 
+// WithAgentConfig 设置 Agent 行为配置
+func WithAgentConfig(cfg AgentSettings) Option {
+	return func(c *Config) {
+		c.Agent = cfg
+	}
+}
+
+// WithWebhook 设置 webhook 函数配置
+func WithWebhook(cfg WebhookConfig) Option {
+	return func(c *Config) {
+		c.Webhook = cfg
+	}
+}
+
+// WithAuth 设置 HTTP API 鉴权配置
+func WithAuth(cfg AuthConfig) Option {
+	return func(c *Config) {
+		c.Auth = cfg
+	}
+}
+
+// WithBuiltins 设置内置函数的启用范围
+func WithBuiltins(cfg BuiltinsConfig) Option {
+	return func(c *Config) {
+		c.Builtins = cfg
+	}
+}
+
+// WithAudit 设置对话审计日志配置
+func WithAudit(cfg AuditConfig) Option {
+	return func(c *Config) {
+		c.Audit = cfg
+	}
+}
+
+// WithConsole 设置 console 通知渠道的输出方式
+func WithConsole(cfg ConsoleConfig) Option {
+	return func(c *Config) {
+		c.Console = cfg
+	}
+}
+
+// WithScheduler 设置任务调度配置
+func WithScheduler(cfg SchedulerConfig) Option {
+	return func(c *Config) {
+		c.Scheduler = cfg
+	}
+}
+
+// WithSessionStorage 设置会话持久化
+func WithSessionStorage(cfg SessionStorageConfig) Option {
+	return func(c *Config) {
+		c.Session = cfg
+	}
+}
+
+// WithResources 设置函数资源监控配置
+func WithResources(cfg ResourceConfig) Option {
+	return func(c *Config) {
+		c.Resources = cfg
+	}
+}
+
+// WithObservability 设置可观测性配置
+func WithObservability(cfg ObservabilityConfig) Option {
+	return func(c *Config) {
+		c.Observability = cfg
+	}
+}
+
+// WithDedupCalls 设置是否对同一轮内完全相同的函数调用去重
+func WithDedupCalls(enabled bool) Option {
+	return func(c *Config) {
+		c.DedupCalls = enabled
+	}
+}
+
+// WithMaxHistoryTokens 设置会话历史的估算 token 上限
+func WithMaxHistoryTokens(maxTokens int) Option {
+	return func(c *Config) {
+		c.MaxHistoryTokens = maxTokens
+	}
+}
+
+// WithRateLimits 设置按函数名的每分钟调用次数上限
+func WithRateLimits(limits map[string]int) Option {
+	return func(c *Config) {
+		c.RateLimits = limits
+	}
+}
+
+// WithPromptTemplates 从文件加载系统提示词模板，hotReload 为 true 时文件修改后自动重新加载
+func WithPromptTemplates(systemPath, toolsPath string, hotReload bool) Option {
+	return func(c *Config) {
+		c.Prompt.SystemTemplatePath = systemPath
+		c.Prompt.ToolsTemplatePath = toolsPath
+		c.Prompt.HotReload = hotReload
+	}
+}
+
+// WithPromptVars 设置注入到系统提示词的自定义变量
+func WithPromptVars(vars map[string]any) Option {
+	return func(c *Config) {
+		c.PromptVars = vars
+	}
+}
+
 // SessionConfig 会话配置
 type SessionConfig struct {
 	// MaxHistory 最大历史消息数
@@ -0,0 +1,66 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"sync"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+)
+
+// prefetchCalls 并行执行本轮中可以直接执行的调用，返回按调用下标索引的执行结果
+// 预先执行的调用在开始前发出 EventFunctionStart，串行流程不再重复发送；对话已取消时不预先执行
+// 参数解析失败的调用不执行；遇到需要审批或用户确认的调用时，它及之后的调用都不预先执行，
+// 与串行执行时"挂起后跳过剩余调用"的行为保持一致；开启去重时相同调用只执行一次
+// budget 为本次请求剩余的调用额度（-1 表示不限制），超出额度的调用不预先执行；iteration 为当前轮次，用于调用追踪
+func (a *Agent) prefetchCalls(ctx context.Context, req ChatRequest, calls []pendingCall, iteration, budget int) map[int]function.ExecuteResponse {
+	indexes := make([]int, 0, len(calls))
+	seen := make(map[string]bool)
+	for i, call := range calls {
+		if a.needsApproval(call.Name) || a.needsConfirmation(req, call.Name) {
+			break
+		}
+		if call.parseErr != nil {
+			continue
+		}
+		if a.config.DedupCalls {
+			key := callKey(call.CallRequest)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		indexes = append(indexes, i)
+	}
+	if budget >= 0 && len(indexes) > budget {
+		indexes = indexes[:budget]
+	}
+
+	// 只有一个可执行的调用时没必要并行，交给串行流程
+	if len(indexes) < 2 || ctx.Err() != nil {
+		return nil
+	}
+
+	responses := make([]function.ExecuteResponse, len(indexes))
+	var wg sync.WaitGroup
+	for j, i := range indexes {
+		trace := newCallTrace(ctx, iteration, i+1)
+		a.emit(ctx, Event{Type: EventFunctionStart, Iteration: iteration, FunctionName: calls[i].Name, CallID: trace.CallID})
+		wg.Add(1)
+		go func(j int, call pendingCall, trace callTrace) {
+			defer wg.Done()
+			responses[j] = a.executor.Execute(trace.context(ctx), function.ExecuteRequest{
+				FunctionName: call.Name,
+				Params:       call.Params,
+				Data:         call.Data,
+			})
+		}(j, calls[i], trace)
+	}
+	wg.Wait()
+
+	prefetched := make(map[int]function.ExecuteResponse, len(indexes))
+	for j, i := range indexes {
+		prefetched[i] = responses[j]
+	}
+	return prefetched
+}
@@ -0,0 +1,54 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"fmt"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/llm/openai"
+)
+
+// newLLMProvider 按配置创建 Provider，配置了断路器时包装断路器
+func (a *App) newLLMProvider(cfg llm.Config) (llm.Provider, error) {
+	cfg.APIKey = llm.ResolveAPIKey(cfg.APIKey)
+
+	var provider llm.Provider
+	// 根据 provider 类型创建实例
+	switch cfg.Provider {
+	case "openai", "azure", "custom":
+		provider = openai.NewProviderFromLLMConfig(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider: %s", cfg.Provider)
+	}
+	// 连续失败后快速失败，避免请求堆积在不可用的上游
+	if breaker := cfg.CircuitBreaker; breaker.FailureThreshold > 0 {
+		provider = llm.NewCircuitBreaker(provider, breaker)
+		a.logger.Info("LLM circuit breaker enabled",
+			"model", cfg.Model,
+			"failure_threshold", breaker.FailureThreshold,
+			"open_timeout", breaker.OpenTimeout,
+		)
+	}
+	return provider, nil
+}
+
+// initProviderPool 创建 llm.providers 中的命名 Provider 并按 llm.routes 配置函数路由
+// 默认 Provider 为主对话使用的 Provider
+func (a *App) initProviderPool() error {
+	a.llmPool = llm.NewPool(a.provider)
+	for name, cfg := range a.config.LLM.Providers {
+		cfg = cfg.Inherit(a.config.LLM)
+		provider, err := a.newLLMProvider(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to create LLM provider %s: %w", name, err)
+		}
+		a.llmPool.Register(name, provider)
+		a.logger.Info("LLM pool provider initialized", "name", name, "model", cfg.Model)
+	}
+	for fn, name := range a.config.LLM.Routes {
+		if err := a.llmPool.Route(fn, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
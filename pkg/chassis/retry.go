@@ -0,0 +1,46 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// transientRetryDelay 临时失败后自动重试前的等待时间
+const transientRetryDelay = 500 * time.Millisecond
+
+// retryTransient 函数临时失败时按 TransientRetries 自动重试，返回最后一次的执行结果和重试次数
+// 只重试声明了幂等的函数，其余函数只在连接被拒绝时重试，见 function.ShouldRetry
+func (a *Agent) retryTransient(ctx context.Context, req function.ExecuteRequest, resp function.ExecuteResponse) (function.ExecuteResponse, int) {
+	fn, _ := a.registry.Get(req.FunctionName)
+	retried := 0
+	for retried < a.config.TransientRetries && function.ShouldRetry(fn, resp.Error) {
+		observability.WarnContext(ctx, "Retrying function after transient failure",
+			"name", req.FunctionName,
+			"attempt", retried+1,
+			"error", resp.Error,
+		)
+		select {
+		case <-ctx.Done():
+			return resp, retried
+		case <-time.After(transientRetryDelay):
+		}
+		resp = a.executor.Execute(ctx, req)
+		retried++
+	}
+	return resp, retried
+}
+
+// encodeFailure 编码函数失败结果，附带错误类别和处理建议，引导 AI 修正参数、重试或放弃
+func (a *Agent) encodeFailure(name string, err error, retried int) string {
+	class := function.ClassifyError(err)
+	hint := class.Hint()
+	if retried > 0 {
+		hint = fmt.Sprintf("The call was already retried automatically %d time(s) and still failed. %s", retried, hint)
+	}
+	return a.encoder.EncodeClassifiedError(name, string(class), err.Error(), hint)
+}
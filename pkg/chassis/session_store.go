@@ -0,0 +1,171 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/storage"
+)
+
+// SessionRecord 落库的会话，消息序列化为 JSON，开启压缩时再经过 gzip
+type SessionRecord struct {
+	ID         string    `gorm:"primarykey"`
+	Messages   []byte    `gorm:"not null"`
+	Compressed bool      `gorm:"not null"` // 按写入时的设置记录，切换开关后旧记录仍能读回
+	Owner      string    // 创建会话的调用方
+	CreatedAt  time.Time `gorm:"not null"`
+	UpdatedAt  time.Time `gorm:"not null;index"`
+}
+
+// TableName 指定表名
+func (SessionRecord) TableName() string {
+	return "chat_sessions"
+}
+
+// SessionStore 会话持久化存储，写入前可 gzip 压缩消息，读回时自动解压
+type SessionStore struct {
+	db       *gorm.DB
+	compress bool
+}
+
+// NewSessionStore 创建 SessionStore
+func NewSessionStore(db *gorm.DB, compress bool) *SessionStore {
+	return &SessionStore{db: db, compress: compress}
+}
+
+// sessionMigrations 会话表的迁移，schema 变化时在末尾追加新版本
+var sessionMigrations = []storage.Migration{
+	{
+		Version: 1,
+		Name:    "create chat_sessions",
+		Up:      storage.CreateTables(&SessionRecord{}),
+		Down:    storage.DropTables(&SessionRecord{}),
+	},
+	{
+		Version: 2,
+		Name:    "add owner to chat_sessions",
+		Up:      storage.AddColumns(&SessionRecord{}, "Owner"),
+		Down:    storage.DropColumns(&SessionRecord{}, "Owner"),
+	},
+}
+
+// Migrate 按版本执行未应用的迁移
+func (s *SessionStore) Migrate() error {
+	return storage.NewMigrator(s.db, "session", sessionMigrations...).Migrate()
+}
+
+// Save 写入会话，已存在时覆盖
+func (s *SessionStore) Save(session *Session) error {
+	data, err := encodeMessages(session.Messages, s.compress)
+	if err != nil {
+		return err
+	}
+	return s.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&SessionRecord{
+		ID:         session.ID,
+		Messages:   data,
+		Compressed: s.compress,
+		Owner:      session.Owner,
+		CreatedAt:  session.CreatedAt,
+		UpdatedAt:  session.UpdatedAt,
+	}).Error
+}
+
+// Load 读取会话，不存在时返回 nil
+func (s *SessionStore) Load(id string) (*Session, error) {
+	var record SessionRecord
+	err := s.db.Where("id = ?", id).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := decodeMessages(record.Messages, record.Compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode session %s: %w", id, err)
+	}
+	return &Session{
+		ID:        record.ID,
+		Messages:  messages,
+		CreatedAt: record.CreatedAt,
+		UpdatedAt: record.UpdatedAt,
+		Owner:     record.Owner,
+	}, nil
+}
+
+// IDs 列出所有已存储的会话 ID
+func (s *SessionStore) IDs() ([]string, error) {
+	var ids []string
+	err := s.db.Model(&SessionRecord{}).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// OwnedIDs 列出属于 owner 的已存储会话 ID
+func (s *SessionStore) OwnedIDs(owner string) ([]string, error) {
+	var ids []string
+	err := s.db.Model(&SessionRecord{}).Where("owner = ?", owner).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Delete 删除会话
+func (s *SessionStore) Delete(id string) error {
+	return s.db.Where("id = ?", id).Delete(&SessionRecord{}).Error
+}
+
+// DeleteBefore 删除最后更新时间早于 t 的会话，返回删除条数
+func (s *SessionStore) DeleteBefore(t time.Time) (int64, error) {
+	result := s.db.Where("updated_at < ?", t).Delete(&SessionRecord{})
+	return result.RowsAffected, result.Error
+}
+
+// encodeMessages 把消息序列化为 JSON，compress 为 true 时再 gzip 压缩
+func encodeMessages(messages []llm.Message, compress bool) ([]byte, error) {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return nil, err
+	}
+	if !compress {
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeMessages encodeMessages 的逆过程
+func decodeMessages(data []byte, compressed bool) ([]llm.Message, error) {
+	if compressed {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+
+	var messages []llm.Message
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
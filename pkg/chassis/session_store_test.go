@@ -0,0 +1,213 @@
+package chassis
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+// historyProvider 总是回复 reply 的 Provider，记录每次请求的消息；未实现的方法不应被调用
+type historyProvider struct {
+	llm.Provider
+	reply    string
+	requests [][]llm.Message
+}
+
+func (p *historyProvider) Name() string { return "history" }
+
+func (p *historyProvider) Chat(ctx context.Context, messages []llm.Message) (string, error) {
+	return p.ChatWithOptions(ctx, messages, llm.ChatOptions{})
+}
+
+func (p *historyProvider) ChatWithOptions(ctx context.Context, messages []llm.Message, opts llm.ChatOptions) (string, error) {
+	p.requests = append(p.requests, append([]llm.Message(nil), messages...))
+	return p.reply, nil
+}
+
+// openSessionDB 创建内存数据库并执行会话表迁移
+func openSessionDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	if err := NewSessionStore(db, false).Migrate(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return db
+}
+
+func TestSessionStore_RoundTrip(t *testing.T) {
+	result := `<result name="list_orders" status="success">` + strings.Repeat("\n  <data>order</data>", 200) + "\n</result>"
+	session := &Session{
+		ID: "s1",
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: "sys"},
+			{Role: llm.RoleUser, Content: "list my orders"},
+			{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "call_1", Name: "list_orders", Arguments: "{}"}}},
+			{Role: llm.RoleTool, Content: result, ToolCallID: "call_1"},
+		},
+		CreatedAt: time.Now().Add(-time.Hour).Truncate(time.Second),
+		UpdatedAt: time.Now().Truncate(time.Second),
+	}
+
+	sizes := make(map[bool]int)
+	for _, compress := range []bool{false, true} {
+		db := openSessionDB(t)
+		store := NewSessionStore(db, compress)
+		if err := store.Save(session); err != nil {
+			t.Fatal(err)
+		}
+
+		var record SessionRecord
+		if err := db.First(&record, "id = ?", "s1").Error; err != nil {
+			t.Fatal(err)
+		}
+		if record.Compressed != compress {
+			t.Errorf("compress=%v: record.Compressed = %v", compress, record.Compressed)
+		}
+		sizes[compress] = len(record.Messages)
+
+		loaded, err := store.Load("s1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(loaded.Messages, session.Messages) {
+			t.Errorf("compress=%v: messages = %+v, want %+v", compress, loaded.Messages, session.Messages)
+		}
+		if !loaded.CreatedAt.Equal(session.CreatedAt) || !loaded.UpdatedAt.Equal(session.UpdatedAt) {
+			t.Errorf("compress=%v: times = %v/%v", compress, loaded.CreatedAt, loaded.UpdatedAt)
+		}
+	}
+	if sizes[true] >= sizes[false]/4 {
+		t.Errorf("compressed size = %d, uncompressed = %d, want much smaller", sizes[true], sizes[false])
+	}
+}
+
+func TestSessionStore_ToggleCompress(t *testing.T) {
+	db := openSessionDB(t)
+	session := &Session{ID: "s1", Messages: []llm.Message{{Role: llm.RoleUser, Content: "hi"}}}
+	if err := NewSessionStore(db, true).Save(session); err != nil {
+		t.Fatal(err)
+	}
+
+	// 关闭压缩后仍能读回之前压缩写入的会话
+	loaded, err := NewSessionStore(db, false).Load("s1")
+	if err != nil || loaded == nil || loaded.Messages[0].Content != "hi" {
+		t.Fatalf("Load = %+v, %v", loaded, err)
+	}
+
+	if missing, err := NewSessionStore(db, false).Load("missing"); missing != nil || err != nil {
+		t.Errorf("Load(missing) = %+v, %v, want nil, nil", missing, err)
+	}
+}
+
+func TestSessionManager_Store(t *testing.T) {
+	db := openSessionDB(t)
+	store := NewSessionStore(db, true)
+
+	m := NewSessionManager(nil)
+	m.SetStore(store)
+	session := m.GetOrCreate("s1", "api_key:web")
+	session.AddMessage(llm.RoleUser, "hi")
+	m.Save(session)
+
+	// 新的管理器（模拟重启）按需从存储读回
+	restarted := NewSessionManager(nil)
+	restarted.SetStore(store)
+	if ids := restarted.List(); !reflect.DeepEqual(ids, []string{"s1"}) {
+		t.Errorf("List = %v, want [s1]", ids)
+	}
+	if ids := restarted.ListOwnedBy("api_key:web"); !reflect.DeepEqual(ids, []string{"s1"}) {
+		t.Errorf("ListOwnedBy(web) = %v, want [s1]", ids)
+	}
+	if ids := restarted.ListOwnedBy("api_key:other"); len(ids) != 0 {
+		t.Errorf("ListOwnedBy(other) = %v, want none", ids)
+	}
+	loaded := restarted.Get("s1")
+	if loaded == nil || len(loaded.Messages) != 1 || loaded.Messages[0].Content != "hi" || loaded.Owner != "api_key:web" {
+		t.Fatalf("Get = %+v", loaded)
+	}
+	if restarted.GetOrCreate("s1", "api_key:other") != loaded {
+		t.Error("GetOrCreate did not reuse the loaded session")
+	}
+
+	if !restarted.Delete("s1") {
+		t.Fatal("Delete = false")
+	}
+	if got, _ := store.Load("s1"); got != nil {
+		t.Errorf("session still stored after Delete: %+v", got)
+	}
+	if NewSessionManager(nil).Delete("s1") {
+		t.Error("Delete of an unknown session = true")
+	}
+}
+
+func TestAgent_SessionStore(t *testing.T) {
+	store := NewSessionStore(openSessionDB(t), true)
+	newAgent := func(reply string) (*Agent, *historyProvider) {
+		provider := &historyProvider{reply: reply}
+		agent := NewAgent(provider, function.NewRegistry(), DefaultAgentConfig())
+		agent.SetSessionStore(store)
+		return agent, provider
+	}
+
+	first, _ := newAgent("Nice to meet you, Alice.")
+	if _, err := first.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "I am Alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重启后的 Agent 带着之前的历史继续对话
+	second, provider := newAgent("You are Alice.")
+	if _, err := second.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "who am I?"}); err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	for _, msg := range provider.requests[0] {
+		if msg.Role != llm.RoleSystem {
+			contents = append(contents, msg.Content)
+		}
+	}
+	want := []string{"I am Alice", "Nice to meet you, Alice.", "who am I?"}
+	if !reflect.DeepEqual(contents, want) {
+		t.Errorf("history sent to the LLM = %q, want %q", contents, want)
+	}
+
+	stored, err := store.Load("s1")
+	if err != nil || len(stored.Messages) != 5 {
+		t.Fatalf("stored session = %+v, %v", stored, err)
+	}
+}
+
+func TestAgent_SessionStoreEdit(t *testing.T) {
+	store := NewSessionStore(openSessionDB(t), false)
+	agent := NewAgent(&historyProvider{reply: "hello"}, function.NewRegistry(), DefaultAgentConfig())
+	agent.SetSessionStore(store)
+	if _, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := agent.EditMessage("s1", 1, "hi there"); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := store.Load("s1")
+	if err != nil || len(stored.Messages) != 2 || stored.Messages[1].Content != "hi there" {
+		t.Fatalf("stored session after edit = %+v, %v", stored, err)
+	}
+
+	if err := agent.DeleteMessage("s1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := store.Load("s1"); len(stored.Messages) != 1 {
+		t.Errorf("stored messages after delete = %+v", stored.Messages)
+	}
+}
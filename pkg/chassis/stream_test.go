@@ -0,0 +1,70 @@
+package chassis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+)
+
+func TestAgent_ChatStreamConsumerCancel(t *testing.T) {
+	reply := strings.Repeat("字", 1000)
+	agent := NewAgent(&fakeProvider{name: "main", reply: reply}, function.NewRegistry(), DefaultAgentConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := agent.ChatStream(ctx, ChatRequest{SessionID: "s1", Message: "hi"})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+
+	// 读到第一个片段后取消并停止读取，缓冲区写满后发送方应随 ctx 退出
+	if r := <-ch; r.Type != StreamContent {
+		t.Fatalf("first chunk type = %s, want content", r.Type)
+	}
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+
+	received := 1
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for r := range ch {
+			received++
+			if r.Type == StreamDone {
+				t.Error("got done chunk after the consumer cancelled")
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stream channel was not closed after cancel")
+	}
+	if received >= len([]rune(reply)) {
+		t.Errorf("received %d chunks, want the stream to stop early after cancel", received)
+	}
+}
+
+func TestAgent_ChatStream(t *testing.T) {
+	agent := NewAgent(&fakeProvider{name: "main", reply: "hello"}, function.NewRegistry(), DefaultAgentConfig())
+
+	ch, err := agent.ChatStream(context.Background(), ChatRequest{SessionID: "s1", Message: "hi"})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+
+	var content strings.Builder
+	var last StreamResponse
+	for r := range ch {
+		content.WriteString(r.Content)
+		last = r
+	}
+	if content.String() != "hello" {
+		t.Errorf("content = %q, want hello", content.String())
+	}
+	if last.Type != StreamDone || !last.Done || last.SessionID != "s1" {
+		t.Errorf("last chunk = %+v, want done for session s1", last)
+	}
+}
@@ -0,0 +1,265 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// 会话自动摘要的默认值
+const (
+	DefaultSummaryTriggerMessages = 16
+	DefaultSummaryKeepRecent      = 6
+)
+
+// summaryTimeout 单次会话摘要（一次 LLM 调用）的超时
+const summaryTimeout = 2 * time.Minute
+
+// SummaryRoute 会话摘要在 llm.routes 中使用的路由名，可以把摘要交给便宜的模型
+const SummaryRoute = "session_summary"
+
+// summaryMaxChars 摘要消息的字符数上限
+const summaryMaxChars = 4000
+
+// summaryPrefix 摘要消息的开头，告诉 AI 这条消息是之前对话的摘要而不是它的原话
+const summaryPrefix = "[Summary of the earlier conversation]\n"
+
+// sessionSummaryPrompt 让 LLM 压缩早期对话的系统提示
+const sessionSummaryPrompt = "You are compressing the beginning of a conversation between a user and an assistant that can call functions, so the assistant can continue it with less context. Write a concise summary that keeps the user's goals and preferences, decisions made, facts, numbers and identifiers learned, functions called and their outcomes, and anything still unresolved. If the conversation starts with an earlier summary, merge it in. Reply with the summary only."
+
+// SummaryConfig 会话自动摘要配置
+// 会话消息超过阈值时，后台调用 LLM 把最旧的一批消息压缩为一条摘要消息，保留系统提示和近期消息
+// 摘要在对话结束后进行，按条数和 token 的硬截断仍然生效，触发阈值需低于截断上限才有机会摘要
+type SummaryConfig struct {
+	// Enabled 是否开启自动摘要
+	Enabled bool `mapstructure:"enabled"`
+
+	// TriggerMessages 会话中（不含系统提示）的消息数超过该值时摘要，默认 16，负数表示不按条数触发
+	// 会话最多保留 20 条消息，超过 19 时消息会先被截断
+	TriggerMessages int `mapstructure:"trigger_messages"`
+
+	// TriggerTokens 会话的估算 token 数超过该值时摘要，0 表示不按 token 触发
+	TriggerTokens int `mapstructure:"trigger_tokens"`
+
+	// KeepRecent 摘要时至少原样保留的最近消息数，默认 6；保留部分总是从一条用户消息开始
+	KeepRecent int `mapstructure:"keep_recent"`
+
+	// IdleAfter 会话空闲多久后摘要，期间有新对话则重新计时；0 表示每次对话结束后立即在后台摘要
+	IdleAfter time.Duration `mapstructure:"idle_after"`
+}
+
+// withDefaults 填充未设置的字段
+func (c SummaryConfig) withDefaults() SummaryConfig {
+	if c.TriggerMessages == 0 {
+		c.TriggerMessages = DefaultSummaryTriggerMessages
+	}
+	if c.KeepRecent <= 0 {
+		c.KeepRecent = DefaultSummaryKeepRecent
+	}
+	return c
+}
+
+// triggered 会话历史（不含系统提示）是否达到摘要阈值
+func (c SummaryConfig) triggered(history []llm.Message) bool {
+	if c.TriggerMessages > 0 && len(history) > c.TriggerMessages {
+		return true
+	}
+	return c.TriggerTokens > 0 && llm.EstimateMessagesTokens(history) > c.TriggerTokens
+}
+
+// summaryBatch 返回需要摘要的最旧一批消息在 Messages 中的起始下标和内容（副本）
+// 至少保留最近 keepRecent 条，且保留部分从一条用户消息开始，不会拆开函数调用与其结果
+func (s *Session) summaryBatch(keepRecent int) (int, []llm.Message) {
+	start := 0
+	if len(s.Messages) > 0 && s.Messages[0].Role == llm.RoleSystem {
+		start = 1
+	}
+	cut := -1
+	for i := len(s.Messages) - keepRecent; i > start; i-- {
+		if s.Messages[i].Role == llm.RoleUser {
+			cut = i
+			break
+		}
+	}
+	if cut < 0 {
+		return start, nil
+	}
+	return start, append([]llm.Message(nil), s.Messages[start:cut]...)
+}
+
+// replaceWithSummary 把从 start 开始的 batch 替换为一条摘要消息
+// 这段消息在摘要期间被修改过（如被截断或编辑）时不替换并返回 false
+func (s *Session) replaceWithSummary(start int, batch []llm.Message, summary string) bool {
+	end := start + len(batch)
+	if end > len(s.Messages) || !reflect.DeepEqual(s.Messages[start:end], batch) {
+		return false
+	}
+	messages := make([]llm.Message, 0, len(s.Messages)-len(batch)+1)
+	messages = append(messages, s.Messages[:start]...)
+	messages = append(messages, llm.Message{Role: llm.RoleAssistant, Content: summaryPrefix + summary})
+	messages = append(messages, s.Messages[end:]...)
+	s.Messages = messages
+	s.UpdatedAt = time.Now()
+	return true
+}
+
+// scheduleSummary 对话结束后安排会话的自动摘要
+// 设置了 IdleAfter 时等会话空闲后再摘要，同一会话上的新对话会重新计时
+func (a *Agent) scheduleSummary(sessionID string) {
+	if !a.config.Summary.Enabled || sessionID == "" {
+		return
+	}
+
+	a.summaryMu.Lock()
+	defer a.summaryMu.Unlock()
+	if a.summaryStopped {
+		return
+	}
+	if a.summaryTimers == nil {
+		a.summaryTimers = make(map[string]*time.Timer)
+		a.summaryStop, a.summaryCancel = context.WithCancel(context.Background())
+	}
+	if timer, ok := a.summaryTimers[sessionID]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(a.config.Summary.IdleAfter, func() {
+		a.summaryMu.Lock()
+		if a.summaryTimers[sessionID] == timer {
+			delete(a.summaryTimers, sessionID)
+		}
+		if a.summaryStopped {
+			a.summaryMu.Unlock()
+			return
+		}
+		a.summaries.Add(1)
+		stop := a.summaryStop
+		a.summaryMu.Unlock()
+		defer a.summaries.Done()
+
+		ctx, cancel := context.WithTimeout(WithSessionID(a.logContext(context.Background()), sessionID), summaryTimeout)
+		defer cancel()
+		defer context.AfterFunc(stop, cancel)()
+		if err := a.summarizeSession(ctx, sessionID); err != nil {
+			observability.WarnContext(ctx, "Failed to summarize session history", "error", err)
+		}
+	})
+	a.summaryTimers[sessionID] = timer
+}
+
+// stopSummaries 取消尚未开始的会话摘要，中止进行中的摘要并等待其结束，
+// 之后不会再写入会话存储，关闭数据库前调用
+func (a *Agent) stopSummaries() {
+	a.summaryMu.Lock()
+	a.summaryStopped = true
+	for id, timer := range a.summaryTimers {
+		timer.Stop()
+		delete(a.summaryTimers, id)
+	}
+	if a.summaryCancel != nil {
+		a.summaryCancel()
+	}
+	a.summaryMu.Unlock()
+	a.summaries.Wait()
+}
+
+// summarizeSession 会话达到阈值时把最旧的一批消息压缩为摘要
+// 读取和替换消息时持有 activeMu 并确认会话上没有进行中的对话；调用 LLM 期间不持锁，
+// 期间会话开始了新对话或消息被修改时放弃本次摘要，等下次对话结束后再触发
+func (a *Agent) summarizeSession(ctx context.Context, sessionID string) error {
+	cfg := a.config.Summary.withDefaults()
+
+	a.activeMu.Lock()
+	session := a.sessionManager.Get(sessionID)
+	_, busy := a.active[sessionID]
+	var start int
+	var batch []llm.Message
+	if session != nil && !busy && session.PendingApproval == "" {
+		history := session.Messages
+		if len(history) > 0 && history[0].Role == llm.RoleSystem {
+			history = history[1:]
+		}
+		if cfg.triggered(history) {
+			start, batch = session.summaryBatch(cfg.KeepRecent)
+		}
+	}
+	a.activeMu.Unlock()
+
+	// 只有一条消息（通常是上一次的摘要）时没有可压缩的内容
+	if len(batch) < 2 {
+		return nil
+	}
+
+	summary, err := a.summarizeMessages(ctx, batch)
+	if err != nil {
+		return err
+	}
+
+	a.activeMu.Lock()
+	defer a.activeMu.Unlock()
+	if _, busy := a.active[sessionID]; busy || a.sessionManager.Get(sessionID) != session {
+		observability.InfoContext(ctx, "Session changed while summarizing, skipping")
+		return nil
+	}
+	if !session.replaceWithSummary(start, batch, summary) {
+		observability.InfoContext(ctx, "Session changed while summarizing, skipping")
+		return nil
+	}
+	a.sessionManager.Save(session)
+	observability.InfoContext(ctx, "Session history summarized",
+		"summarized_messages", len(batch),
+		"remaining_messages", len(session.Messages),
+	)
+	return nil
+}
+
+// summaryProvider 返回会话摘要使用的 Provider：优先使用 SummaryRoute 路由的 Provider，
+// 并绕过断路器，后台摘要的失败不应让用户对话快速失败
+func (a *Agent) summaryProvider() llm.Provider {
+	provider := a.provider
+	if a.llmPool != nil {
+		provider = a.llmPool.For(SummaryRoute)
+	}
+	if breaker, ok := llm.As[*llm.CircuitBreaker](provider); ok {
+		return breaker.Unwrap()
+	}
+	return provider
+}
+
+// summarizeMessages 调用 LLM 把一批消息压缩为摘要
+func (a *Agent) summarizeMessages(ctx context.Context, messages []llm.Message) (string, error) {
+	summary, err := a.summaryProvider().ChatWithOptions(ctx, []llm.Message{
+		{Role: llm.RoleSystem, Content: sessionSummaryPrompt},
+		{Role: llm.RoleUser, Content: summaryTranscript(messages)},
+	}, llm.ChatOptions{})
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return truncateMiddle(summary, summaryMaxChars), nil
+}
+
+// summaryTranscript 把消息整理为纯文本对话记录，原生工具调用写成 name(arguments)，图片只标注数量
+func summaryTranscript(messages []llm.Message) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		fmt.Fprintf(&b, "%s: %s", msg.Role, strings.TrimSpace(msg.Content))
+		for _, call := range msg.ToolCalls {
+			fmt.Fprintf(&b, "\n[called %s(%s)]", call.Name, call.Arguments)
+		}
+		if len(msg.Images) > 0 {
+			fmt.Fprintf(&b, "\n[%d image(s) attached]", len(msg.Images))
+		}
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,316 @@
+package chassis
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+// fakeProvider 返回固定回复的测试 Provider，记录收到的请求
+type fakeProvider struct {
+	name    string
+	reply   string
+	replies []string // 依次返回的回复，用完后返回 reply
+	err     error
+
+	mu       sync.Mutex
+	requests [][]llm.Message
+}
+
+func (p *fakeProvider) Chat(ctx context.Context, messages []llm.Message) (string, error) {
+	return p.ChatWithOptions(ctx, messages, llm.ChatOptions{})
+}
+
+func (p *fakeProvider) ChatWithOptions(ctx context.Context, messages []llm.Message, opts llm.ChatOptions) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, messages)
+	if len(p.replies) > 0 {
+		reply := p.replies[0]
+		p.replies = p.replies[1:]
+		return reply, p.err
+	}
+	return p.reply, p.err
+}
+
+func (p *fakeProvider) ChatWithTools(ctx context.Context, messages []llm.Message, tools []llm.ToolDefinition, opts llm.ChatOptions) (llm.Message, error) {
+	content, err := p.ChatWithOptions(ctx, messages, opts)
+	return llm.Message{Role: llm.RoleAssistant, Content: content}, err
+}
+
+func (p *fakeProvider) ChatStream(ctx context.Context, messages []llm.Message) (<-chan llm.StreamChunk, error) {
+	content, err := p.ChatWithOptions(ctx, messages, llm.ChatOptions{})
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan llm.StreamChunk, 2)
+	ch <- llm.StreamChunk{Content: content}
+	ch <- llm.StreamChunk{Done: true}
+	close(ch)
+	return ch, nil
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) requestCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.requests)
+}
+
+// conversation 生成以系统提示开头、user/assistant 交替的 n 条消息
+func conversation(n int) []llm.Message {
+	messages := []llm.Message{{Role: llm.RoleSystem, Content: "system"}}
+	for i := 0; i < n; i++ {
+		role := llm.RoleUser
+		if i%2 == 1 {
+			role = llm.RoleAssistant
+		}
+		messages = append(messages, llm.Message{Role: role, Content: string(rune('a' + i))})
+	}
+	return messages
+}
+
+func TestSummaryConfig_Triggered(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SummaryConfig
+		history []llm.Message
+		want    bool
+	}{
+		{"below message threshold", SummaryConfig{TriggerMessages: 4}, conversation(4)[1:], false},
+		{"above message threshold", SummaryConfig{TriggerMessages: 4}, conversation(5)[1:], true},
+		{"message trigger disabled", SummaryConfig{TriggerMessages: -1}, conversation(50)[1:], false},
+		{"above token threshold", SummaryConfig{TriggerMessages: -1, TriggerTokens: 10},
+			[]llm.Message{{Role: llm.RoleUser, Content: strings.Repeat("word ", 100)}}, true},
+		{"below token threshold", SummaryConfig{TriggerMessages: -1, TriggerTokens: 1000},
+			[]llm.Message{{Role: llm.RoleUser, Content: "hi"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.triggered(tt.history); got != tt.want {
+				t.Errorf("triggered() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryConfig_Defaults(t *testing.T) {
+	got := SummaryConfig{}.withDefaults()
+	if got.TriggerMessages != DefaultSummaryTriggerMessages || got.KeepRecent != DefaultSummaryKeepRecent {
+		t.Errorf("withDefaults() = %+v", got)
+	}
+	if got := (SummaryConfig{TriggerMessages: -1}).withDefaults(); got.TriggerMessages != -1 {
+		t.Errorf("negative TriggerMessages overwritten: %d", got.TriggerMessages)
+	}
+}
+
+func TestSession_SummaryBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		messages   []llm.Message
+		keepRecent int
+		wantStart  int
+		wantLen    int
+	}{
+		// system + a(u) b(a) c(u) d(a) e(u) f(a)：保留最近 2 条，从 e 开始
+		{"cuts at user message", conversation(6), 2, 1, 4},
+		// 保留最近 3 条时 d 是 assistant，向前找到 c
+		{"keeps the user message before the tail", conversation(6), 3, 1, 2},
+		{"nothing to summarize", conversation(2), 2, 1, 0},
+		{"without system prompt", conversation(6)[1:], 2, 0, 4},
+		{
+			"does not split tool results",
+			[]llm.Message{
+				{Role: llm.RoleSystem, Content: "system"},
+				{Role: llm.RoleUser, Content: "q1"},
+				{Role: llm.RoleAssistant, Content: "call"},
+				{Role: llm.RoleUser, Content: "q2"},
+				{Role: llm.RoleAssistant, ToolCalls: []llm.ToolCall{{ID: "1", Name: "f"}}},
+				{Role: llm.RoleTool, ToolCallID: "1", Content: "result"},
+				{Role: llm.RoleAssistant, Content: "answer"},
+			},
+			2, 1, 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &Session{Messages: tt.messages}
+			start, batch := session.summaryBatch(tt.keepRecent)
+			if start != tt.wantStart || len(batch) != tt.wantLen {
+				t.Fatalf("summaryBatch() = %d, %d messages, want %d, %d", start, len(batch), tt.wantStart, tt.wantLen)
+			}
+			if len(batch) > 0 {
+				next := tt.messages[start+len(batch)]
+				if next.Role != llm.RoleUser {
+					t.Errorf("kept messages start with %s, want user", next.Role)
+				}
+			}
+		})
+	}
+}
+
+func TestSession_ReplaceWithSummary(t *testing.T) {
+	session := &Session{Messages: conversation(6)}
+	start, batch := session.summaryBatch(2)
+
+	if !session.replaceWithSummary(start, batch, "short") {
+		t.Fatal("replaceWithSummary() = false, want true")
+	}
+	if got := len(session.Messages); got != 4 {
+		t.Fatalf("len(Messages) = %d, want 4", got)
+	}
+	summary := session.Messages[1]
+	if summary.Role != llm.RoleAssistant || summary.Content != summaryPrefix+"short" {
+		t.Errorf("summary message = %+v", summary)
+	}
+	if session.Messages[2].Content != "e" {
+		t.Errorf("first kept message = %q, want e", session.Messages[2].Content)
+	}
+}
+
+func TestSession_ReplaceWithSummaryConflict(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *Session)
+	}{
+		{"message edited", func(s *Session) { s.Messages[2].Content = "edited" }},
+		{"history truncated", func(s *Session) { s.Messages = append(s.Messages[:1], s.Messages[3:]...) }},
+		{"history shortened", func(s *Session) { s.Messages = s.Messages[:2] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &Session{Messages: conversation(6)}
+			start, batch := session.summaryBatch(2)
+			tt.modify(session)
+			before := append([]llm.Message(nil), session.Messages...)
+
+			if session.replaceWithSummary(start, batch, "short") {
+				t.Fatal("replaceWithSummary() = true, want false")
+			}
+			if len(session.Messages) != len(before) {
+				t.Errorf("messages changed after a rejected replace")
+			}
+		})
+	}
+}
+
+func newSummaryAgent(provider llm.Provider) *Agent {
+	config := DefaultAgentConfig()
+	config.Summary = SummaryConfig{Enabled: true, TriggerMessages: 4, KeepRecent: 2}
+	return NewAgent(provider, function.NewRegistry(), config)
+}
+
+func TestAgent_SummarizeSession(t *testing.T) {
+	provider := &fakeProvider{name: "main", reply: "  they asked about a and c  "}
+	agent := newSummaryAgent(provider)
+	session := agent.sessionManager.GetOrCreate("s1", "")
+	session.Messages = conversation(6)
+
+	if err := agent.summarizeSession(context.Background(), "s1"); err != nil {
+		t.Fatalf("summarizeSession: %v", err)
+	}
+	if got := len(session.Messages); got != 4 {
+		t.Fatalf("len(Messages) = %d, want 4", got)
+	}
+	if got := session.Messages[1].Content; got != summaryPrefix+"they asked about a and c" {
+		t.Errorf("summary = %q", got)
+	}
+	if got := provider.requestCount(); got != 1 {
+		t.Errorf("provider called %d times, want 1", got)
+	}
+}
+
+func TestAgent_SummarizeSessionSkipsBusy(t *testing.T) {
+	provider := &fakeProvider{name: "main", reply: "summary"}
+	agent := newSummaryAgent(provider)
+	session := agent.sessionManager.GetOrCreate("s1", "")
+	session.Messages = conversation(6)
+	agent.active["s1"] = &activeChat{}
+
+	if err := agent.summarizeSession(context.Background(), "s1"); err != nil {
+		t.Fatalf("summarizeSession: %v", err)
+	}
+	if got := len(session.Messages); got != 7 {
+		t.Errorf("busy session was summarized: %d messages", got)
+	}
+	if got := provider.requestCount(); got != 0 {
+		t.Errorf("provider called %d times, want 0", got)
+	}
+}
+
+func TestAgent_SummaryProvider(t *testing.T) {
+	main := &fakeProvider{name: "main", reply: "main"}
+	cheap := &fakeProvider{name: "cheap", reply: "cheap"}
+	breaker := llm.NewCircuitBreaker(cheap, llm.BreakerConfig{FailureThreshold: 1})
+
+	agent := newSummaryAgent(llm.NewCircuitBreaker(main, llm.BreakerConfig{FailureThreshold: 1}))
+	if got := agent.summaryProvider(); got != llm.Provider(main) {
+		t.Errorf("without pool: got %s, want the unwrapped main provider", got.Name())
+	}
+
+	pool := llm.NewPool(agent.provider)
+	pool.Register("cheap", breaker)
+	if err := pool.Route(SummaryRoute, "cheap"); err != nil {
+		t.Fatal(err)
+	}
+	agent.SetProviderPool(pool)
+	if got := agent.summaryProvider(); got != llm.Provider(cheap) {
+		t.Errorf("with route: got %s, want the unwrapped cheap provider", got.Name())
+	}
+}
+
+// blockingSummaryProvider 收到请求后一直阻塞到 ctx 取消
+type blockingSummaryProvider struct {
+	fakeProvider
+	started chan struct{}
+}
+
+func (p *blockingSummaryProvider) ChatWithOptions(ctx context.Context, messages []llm.Message, opts llm.ChatOptions) (string, error) {
+	close(p.started)
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestAgent_StopSummaries(t *testing.T) {
+	provider := &blockingSummaryProvider{fakeProvider: fakeProvider{name: "main"}, started: make(chan struct{})}
+	agent := newSummaryAgent(provider)
+	session := agent.sessionManager.GetOrCreate("s1", "")
+	session.Messages = conversation(6)
+
+	agent.scheduleSummary("s1")
+	select {
+	case <-provider.started:
+	case <-time.After(time.Second):
+		t.Fatal("summary did not start")
+	}
+
+	// 进行中的摘要被中止，stopSummaries 等它结束后才返回
+	done := make(chan struct{})
+	go func() {
+		agent.stopSummaries()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("stopSummaries did not cancel the in-flight summary")
+	}
+	if got := len(session.Messages); got != 7 {
+		t.Errorf("cancelled summary changed the session: %d messages", got)
+	}
+
+	// 停止后不再安排新的摘要
+	agent.scheduleSummary("s1")
+	if len(agent.summaryTimers) != 0 {
+		t.Errorf("summary scheduled after stop: %v", agent.summaryTimers)
+	}
+}
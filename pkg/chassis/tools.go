@@ -0,0 +1,168 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/prompt/templates"
+	"github.com/KodaTao/AgentChassis/pkg/protocol"
+)
+
+// 函数调用方式
+const (
+	CallModeXML   = "xml"   // AI 在回复文本中输出 <call> XML，由 Parser 解析
+	CallModeTools = "tools" // 使用模型原生 function calling（tools / tool_calls）
+)
+
+// ValidateCallMode 校验函数调用方式，空字符串视为默认的 xml
+func ValidateCallMode(mode string) error {
+	switch mode {
+	case "", CallModeXML, CallModeTools:
+		return nil
+	default:
+		return fmt.Errorf("unsupported call mode: %s (want %s or %s)", mode, CallModeXML, CallModeTools)
+	}
+}
+
+// pendingCall 待执行的函数调用
+type pendingCall struct {
+	*protocol.CallRequest
+	toolCallID string // 原生 tools 模式下的调用 ID，结果需按 ID 回传
+	parseErr   error  // 参数解析失败时不执行，直接把错误反馈给 AI
+}
+
+// useTools 是否使用原生 function calling
+func (a *Agent) useTools() bool {
+	return a.config.CallMode == CallModeTools
+}
+
+// generateSystemPrompt 按调用方式和语言生成系统提示（只包含调用者有权调用的函数），并附加渠道指令
+func (a *Agent) generateSystemPrompt(ctx context.Context, channel, language string) (string, error) {
+	functions := a.registry.ListInfoForContext(ctx)
+	if a.useTools() {
+		return a.promptGenerator.GenerateToolsPromptForLanguage(functions, channel, language)
+	}
+	return a.promptGenerator.GenerateSystemPromptForLanguage(functions, channel, language)
+}
+
+// promptLanguage 返回本次请求的系统提示语言：优先使用请求指定的语言，否则使用配置的默认语言
+// 返回的是实际使用的模板语言，没有对应模板的语言会回退
+func (a *Agent) promptLanguage(req ChatRequest) string {
+	language := req.Language
+	if language == "" {
+		language = a.config.Language
+	}
+	return a.promptGenerator.ResolveLanguage(language)
+}
+
+// RegisterPromptLanguage 注册自定义语言的系统提示词模板（或覆盖内置的 en、zh、ja 模板）
+// 模板结构应与内置模板一致，可参考 templates.SystemPrompt；之后新建或切换到该语言的会话生效
+func (a *Agent) RegisterPromptLanguage(language string, set templates.Set) error {
+	return a.promptGenerator.RegisterLanguage(language, set)
+}
+
+// APIChannelType 未指定渠道的请求（如直接调用 HTTP API）在 ChannelPrompts 中对应的渠道类型
+const APIChannelType = "api"
+
+// promptChannel 返回选择系统提示变体时使用的渠道类型
+func promptChannel(channel *ChannelContext) string {
+	if channel == nil || channel.Type == "" {
+		return APIChannelType
+	}
+	return channel.Type
+}
+
+// toolDefinitions 将调用者可用的函数转换为原生工具定义，xml 模式下返回 nil
+func (a *Agent) toolDefinitions(ctx context.Context) []llm.ToolDefinition {
+	if !a.useTools() {
+		return nil
+	}
+
+	infos := a.registry.ListInfoForContext(ctx)
+	tools := make([]llm.ToolDefinition, 0, len(infos))
+	for _, info := range infos {
+		tools = append(tools, llm.ToolDefinition{
+			Name:        info.Name,
+			Description: info.Description,
+			Parameters:  function.ParamsJSONSchema(info.Parameters),
+		})
+	}
+	return tools
+}
+
+// callLLM 按调用方式请求 LLM，返回 AI 回复消息
+func (a *Agent) callLLM(ctx context.Context, messages []llm.Message, tools []llm.ToolDefinition, opts llm.ChatOptions) (llm.Message, error) {
+	// 按 provider 声明的角色约束规整消息，如函数结果与用户消息连续出现时合并
+	messages = llm.NormalizeRoles(messages, llm.ConstraintsOf(a.provider))
+	if a.useTools() {
+		return a.provider.ChatWithTools(ctx, messages, tools, opts)
+	}
+
+	content, err := a.provider.ChatWithOptions(ctx, messages, opts)
+	if err != nil {
+		return llm.Message{}, err
+	}
+	return llm.Message{Role: llm.RoleAssistant, Content: content}, nil
+}
+
+// extractCalls 从 AI 回复中提取函数调用
+// tools 模式读取结构化的 ToolCalls，xml 模式解析回复文本中的 <call>
+func (a *Agent) extractCalls(reply llm.Message) ([]pendingCall, error) {
+	if a.useTools() {
+		calls := make([]pendingCall, 0, len(reply.ToolCalls))
+		for _, tc := range reply.ToolCalls {
+			params, err := function.ParamsFromJSON(tc.Arguments)
+			calls = append(calls, pendingCall{
+				CallRequest: &protocol.CallRequest{Name: tc.Name, Params: params},
+				toolCallID:  tc.ID,
+				parseErr:    err,
+			})
+		}
+		return calls, nil
+	}
+
+	if !a.parser.HasCall(reply.Content) {
+		return nil, nil
+	}
+	parsed, err := a.parser.ParseCalls(reply.Content)
+	if err != nil {
+		return nil, err
+	}
+	calls := make([]pendingCall, len(parsed))
+	for i, call := range parsed {
+		calls[i] = pendingCall{CallRequest: call}
+	}
+	return calls, nil
+}
+
+// appendResults 将函数结果添加到会话
+// tools 模式每个调用对应一条 tool 消息，未执行的调用（提前结束时）也要回复，否则下次请求会被 API 拒绝；
+// xml 模式合并为一条用户消息
+func (a *Agent) appendResults(session *Session, calls []pendingCall, results []string) {
+	if a.useTools() {
+		for i, call := range calls {
+			content := a.encoder.EncodeError(call.Name, "skipped: the conversation was stopped before this call ran")
+			if i < len(results) {
+				content = results[i]
+			}
+			session.AppendMessage(llm.Message{
+				Role:       llm.RoleTool,
+				Content:    content,
+				ToolCallID: call.toolCallID,
+			})
+		}
+		return
+	}
+
+	// XML 协议下用 tool 角色标注函数结果，与真实用户输入区分
+	// Provider 对不支持的 API 会降级为带标记的 user 消息
+	var combined strings.Builder
+	for _, r := range results {
+		combined.WriteString(r + "\n")
+	}
+	session.AddMessage(llm.RoleTool, combined.String())
+}
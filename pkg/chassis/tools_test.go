@@ -0,0 +1,92 @@
+package chassis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/protocol"
+)
+
+// toolsProvider 依次返回给定的原生工具调用，用完后返回 fakeProvider 的文本回复
+type toolsProvider struct {
+	*fakeProvider
+	toolCalls [][]llm.ToolCall
+}
+
+func (p *toolsProvider) ChatWithTools(ctx context.Context, messages []llm.Message, tools []llm.ToolDefinition, opts llm.ChatOptions) (llm.Message, error) {
+	reply, err := p.fakeProvider.ChatWithTools(ctx, messages, tools, opts)
+	if len(p.toolCalls) > 0 {
+		reply.ToolCalls = p.toolCalls[0]
+		p.toolCalls = p.toolCalls[1:]
+	}
+	return reply, err
+}
+
+func TestAgent_AppendResultsSkipped(t *testing.T) {
+	agent := newTestAgent(t, &fakeProvider{name: "main"}, func(c *AgentConfig) { c.CallMode = CallModeTools })
+	session := &Session{ID: "s1"}
+
+	calls := []pendingCall{
+		{CallRequest: &protocol.CallRequest{Name: "a"}, toolCallID: "call_1"},
+		{CallRequest: &protocol.CallRequest{Name: "b"}, toolCallID: "call_2"},
+		{CallRequest: &protocol.CallRequest{Name: "c"}, toolCallID: "call_3"},
+	}
+	agent.appendResults(session, calls, []string{"result a"})
+
+	// 每个调用都有一条按 ID 对应的 tool 消息，未执行的调用标记为 skipped
+	if len(session.Messages) != 3 {
+		t.Fatalf("got %d messages, want 3", len(session.Messages))
+	}
+	for i, m := range session.Messages {
+		if m.Role != llm.RoleTool || m.ToolCallID != calls[i].toolCallID {
+			t.Errorf("message %d = %s/%s, want tool/%s", i, m.Role, m.ToolCallID, calls[i].toolCallID)
+		}
+	}
+	if session.Messages[0].Content != "result a" {
+		t.Errorf("first result = %q", session.Messages[0].Content)
+	}
+	for _, m := range session.Messages[1:] {
+		if !strings.Contains(m.Content, "skipped") {
+			t.Errorf("unexecuted call result = %q, want skipped", m.Content)
+		}
+	}
+}
+
+func TestAgent_ToolsParseError(t *testing.T) {
+	provider := &toolsProvider{
+		fakeProvider: &fakeProvider{name: "main", reply: "fixed"},
+		toolCalls: [][]llm.ToolCall{{
+			{ID: "call_1", Name: "notify", Arguments: `{"to": "bob"`},
+		}},
+	}
+	fn := &countingFunction{name: "notify"}
+	agent := newTestAgent(t, provider, func(c *AgentConfig) { c.CallMode = CallModeTools }, fn)
+
+	resp, err := agent.Chat(context.Background(), ChatRequest{SessionID: "s1", Message: "notify bob"})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	// 参数不是合法 JSON 时不执行，错误反馈给 AI 后由 AI 继续处理
+	if n := fn.calls.Load(); n != 0 {
+		t.Errorf("function ran %d times with invalid arguments", n)
+	}
+	if len(resp.FunctionCalls) != 1 || resp.FunctionCalls[0].Status != "error" {
+		t.Fatalf("function calls = %+v, want one error", resp.FunctionCalls)
+	}
+	if resp.Reply != "fixed" {
+		t.Errorf("reply = %q, want fixed", resp.Reply)
+	}
+
+	var result *llm.Message
+	for i, m := range provider.requests[1] {
+		if m.Role == llm.RoleTool {
+			result = &provider.requests[1][i]
+		}
+	}
+	if result == nil || result.ToolCallID != "call_1" || !strings.Contains(result.Content, resp.FunctionCalls[0].Result) {
+		t.Errorf("tool result = %+v, want the parse error for call_1", result)
+	}
+}
@@ -0,0 +1,81 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/memory"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/types"
+)
+
+// callTrace 单个函数调用在请求中的位置，用于把一次请求触发的所有调用串成调用树
+type callTrace struct {
+	RequestID string // 所属 Chat 请求
+	CallID    string // 调用 ID，格式为 <request_id>.<iteration>.<index>
+	Iteration int    // 第几轮 LLM 调用（从 1 开始）
+	Index     int    // 本轮中的第几个调用（从 1 开始）
+}
+
+// newCallTrace 生成第 iteration 轮第 index 个调用的追踪信息
+func newCallTrace(ctx context.Context, iteration, index int) callTrace {
+	requestID := GetRequestID(ctx)
+	return callTrace{
+		RequestID: requestID,
+		CallID:    fmt.Sprintf("%s.%d.%d", requestID, iteration, index),
+		Iteration: iteration,
+		Index:     index,
+	}
+}
+
+// context 返回带调用追踪字段的 context，函数执行期间的日志都会带上这些字段，
+// 函数可以通过 function.MetadataFromContext 获取会话、用户、渠道和追踪信息
+func (t callTrace) context(ctx context.Context) context.Context {
+	ctx = function.WithMetadata(ctx, function.Metadata{
+		UserID:    memory.UserIDFromContext(ctx),
+		SessionID: GetSessionID(ctx),
+		Channel:   types.ChannelFromContext(ctx),
+		TraceID:   GetTraceID(ctx),
+		RequestID: t.RequestID,
+		CallID:    t.CallID,
+		Iteration: t.Iteration,
+		Index:     t.Index,
+	})
+	return observability.WithLogFields(ctx,
+		"call_id", t.CallID,
+		"iteration", t.Iteration,
+		"call_index", t.Index,
+	)
+}
+
+// apply 将追踪信息写入持久化的调用记录
+func (t callTrace) apply(log *function.CallLog) {
+	log.RequestID = t.RequestID
+	log.CallID = t.CallID
+	log.Iteration = t.Iteration
+	log.CallIndex = t.Index
+}
+
+// requestIDPattern 允许沿用的外部请求 ID：字母、数字和 . _ -，最长 64 个字符
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ValidRequestID 外部传入的请求 ID（如 X-Request-ID）是否可以直接沿用
+// 请求 ID 会写入日志、调用记录和调用 ID，不合法的值应替换为新生成的 ID
+func ValidRequestID(id string) bool {
+	return requestIDPattern.MatchString(id)
+}
+
+// newRequestID 生成请求 ID
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,64 @@
+package chassis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"abc123", true},
+		{"req-1.2_3", true},
+		{strings.Repeat("a", 64), true},
+		{"", false},
+		{strings.Repeat("a", 65), false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{"quote\"", false},
+		{"请求", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidRequestID(tt.id); got != tt.want {
+			t.Errorf("ValidRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestAgent_ChatReplacesInvalidRequestID(t *testing.T) {
+	agent := NewAgent(&fakeProvider{name: "fake", reply: "hi"}, function.NewRegistry(), nil)
+
+	tests := []struct {
+		name      string
+		requestID string
+		keep      bool
+	}{
+		{"valid id is kept", "upstream-42", true},
+		{"invalid id is replaced", "bad id\n", false},
+		{"missing id is generated", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.requestID != "" {
+				ctx = WithRequestID(ctx, tt.requestID)
+			}
+			resp, err := agent.Chat(ctx, ChatRequest{Message: "hello"})
+			if err != nil {
+				t.Fatalf("Chat: %v", err)
+			}
+			if got := resp.RequestID == tt.requestID; got != tt.keep {
+				t.Errorf("RequestID = %q, kept = %v, want %v", resp.RequestID, got, tt.keep)
+			}
+			if !ValidRequestID(resp.RequestID) {
+				t.Errorf("RequestID %q is not valid", resp.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Package chassis 提供 AgentChassis 核心框架
+package chassis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/protocol"
+)
+
+// DefaultMaxResultChars 函数结果反馈给 AI 前的默认字符数上限
+const DefaultMaxResultChars = 16000
+
+// truncatedMarker 截断处插入的说明，告诉 AI 中间的内容已被省略
+const truncatedMarker = "\n\n... [result too long: %d of %d characters omitted] ...\n\n"
+
+// summarizePrompt 让 LLM 摘要超长函数结果的系统提示
+const summarizePrompt = "You are summarizing the output of a function call for another assistant that will use it to answer the user. Keep every fact, number, identifier, error and conclusion that could matter; drop repetition and boilerplate. Reply with the summary only."
+
+// limitResult 对超过 MaxResultChars 的函数结果做截断或摘要，避免单个结果撑爆上下文窗口
+// 开启 SummarizeResults 时先尝试让 LLM 摘要，失败时退回截断
+func (a *Agent) limitResult(ctx context.Context, name, result string) string {
+	limit := a.config.MaxResultChars
+	if limit <= 0 {
+		return result
+	}
+	length := len([]rune(result))
+	if length <= limit {
+		return result
+	}
+
+	if a.config.SummarizeResults {
+		summary, err := a.summarizeResult(ctx, result, limit)
+		if err == nil {
+			observability.InfoContext(ctx, "Function result summarized", "name", name, "chars", length)
+			encoded, _ := a.encoder.EncodeResult(&protocol.CallResult{
+				Name:    name,
+				Status:  protocol.StatusSuccess,
+				Message: fmt.Sprintf("The result was too long (%d characters) and has been summarized; details may be missing:\n%s", length, summary),
+			})
+			return encoded
+		}
+		observability.WarnContext(ctx, "Failed to summarize function result, truncating instead", "name", name, "error", err)
+	}
+
+	observability.InfoContext(ctx, "Function result truncated", "name", name, "chars", length, "limit", limit)
+	return truncateMiddle(result, limit)
+}
+
+// summarizeResult 调用 LLM 摘要函数结果，输入本身也按 4 倍上限截断，摘要长度不超过 limit
+func (a *Agent) summarizeResult(ctx context.Context, result string, limit int) (string, error) {
+	summary, err := a.provider.ChatWithOptions(ctx, []llm.Message{
+		{Role: llm.RoleSystem, Content: summarizePrompt},
+		{Role: llm.RoleUser, Content: truncateMiddle(result, limit*4)},
+	}, llm.ChatOptions{})
+	if err != nil {
+		return "", err
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return truncateMiddle(summary, limit), nil
+}
+
+// truncateMiddle 保留开头和结尾、省略中间部分，使结果不超过 limit 个字符（不含省略说明）
+// 开头保留得多一些：结果的结构和主要信息通常在前面，结尾保留收尾标签和最后的输出
+func truncateMiddle(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	head := limit * 2 / 3
+	tail := limit - head
+	return string(runes[:head]) +
+		fmt.Sprintf(truncatedMarker, len(runes)-limit, len(runes)) +
+		string(runes[len(runes)-tail:])
+}
@@ -0,0 +1,35 @@
+package chassis
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTruncateMiddle(t *testing.T) {
+	marker := func(omitted, total int) string { return fmt.Sprintf(truncatedMarker, omitted, total) }
+
+	tests := []struct {
+		name  string
+		in    string
+		limit int
+		want  string
+	}{
+		{"empty", "", 5, ""},
+		{"shorter than limit", "abc", 5, "abc"},
+		{"exactly limit", "abcde", 5, "abcde"},
+		{"keeps more head than tail", "abcdefghij", 6, "abcd" + marker(4, 10) + "ij"},
+		{"one over limit", "abcdefg", 6, "abcd" + marker(1, 7) + "fg"},
+		{"limit one keeps only tail", "abcdef", 1, marker(5, 6) + "f"},
+		{"zero limit", "abc", 0, marker(3, 3)},
+		{"counts runes not bytes", "你好世界再见朋友", 3, "你好" + marker(5, 8) + "友"},
+		{"multibyte within limit", "你好世界", 4, "你好世界"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateMiddle(tt.in, tt.limit); got != tt.want {
+				t.Errorf("truncateMiddle(%q, %d) = %q, want %q", tt.in, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// ErrApprovalRequired 函数需要管理员审批，只能经由 Agent 的审批流程执行
+var ErrApprovalRequired = errors.New("function requires administrator approval")
+
+// approvedKey context 中审批通过标记的 key
+type approvedKey struct{}
+
+// WithApproved 标记 context 中的调用已经管理员批准，Executor 据此放行需要审批的函数
+// 只应由审批流程在批准后设置
+func WithApproved(ctx context.Context) context.Context {
+	return context.WithValue(ctx, approvedKey{}, true)
+}
+
+// isApproved context 中的调用是否已经管理员批准
+func isApproved(ctx context.Context) bool {
+	approved, _ := ctx.Value(approvedKey{}).(bool)
+	return approved
+}
+
+// SetApprovalFunctions 设置需要管理员审批的函数名，与函数通过 ApprovalFunction 的声明合并生效
+func (e *Executor) SetApprovalFunctions(names []string) {
+	e.approvalFunctions = slices.Clone(names)
+}
+
+// RequiresApproval 函数是否需要管理员审批：配置在 SetApprovalFunctions 中，或函数声明了 RequiresApproval
+// 通过别名调用时，配置了别名或目标函数名都需要审批
+func (e *Executor) RequiresApproval(name string) bool {
+	if slices.Contains(e.approvalFunctions, name) {
+		return true
+	}
+	fn, ok := e.registry.Get(name)
+	if !ok {
+		return false
+	}
+	if slices.Contains(e.approvalFunctions, fn.Name()) {
+		return true
+	}
+	if af, ok := fn.(ApprovalFunction); ok {
+		return af.RequiresApproval()
+	}
+	return false
+}
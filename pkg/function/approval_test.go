@@ -0,0 +1,67 @@
+package function
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// approvalMockFunction 声明了需要审批的 Mock 函数
+type approvalMockFunction struct {
+	MockFunction
+	required bool
+}
+
+func (m *approvalMockFunction) RequiresApproval() bool { return m.required }
+
+func TestExecutor_RequiresApproval(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(&MockFunction{name: "read"})
+	registry.Register(&MockFunction{name: "transfer"})
+	registry.Register(&approvalMockFunction{MockFunction: MockFunction{name: "drop_table"}, required: true})
+	registry.Register(&approvalMockFunction{MockFunction: MockFunction{name: "optional"}, required: false})
+	if err := registry.RegisterAlias("pay", "transfer"); err != nil {
+		t.Fatal(err)
+	}
+	executor := NewExecutor(registry, 0)
+	executor.SetApprovalFunctions([]string{"transfer"})
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"read", false},
+		{"transfer", true},
+		{"pay", true},
+		{"drop_table", true},
+		{"optional", false},
+		{"missing", false},
+	}
+	for _, tt := range tests {
+		if got := executor.RequiresApproval(tt.name); got != tt.want {
+			t.Errorf("RequiresApproval(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExecutor_ApprovalGate(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(&MockFunction{name: "read"})
+	registry.Register(&approvalMockFunction{MockFunction: MockFunction{name: "drop_table"}, required: true})
+	executor := NewExecutor(registry, 0)
+
+	// 直接调用 Executor（如 MCP）不能绕过审批
+	resp := executor.Execute(context.Background(), ExecuteRequest{FunctionName: "drop_table"})
+	if !errors.Is(resp.Error, ErrApprovalRequired) {
+		t.Errorf("unapproved call error = %v, want ErrApprovalRequired", resp.Error)
+	}
+
+	resp = executor.Execute(WithApproved(context.Background()), ExecuteRequest{FunctionName: "drop_table"})
+	if resp.Error != nil || resp.Result.Message != "executed" {
+		t.Errorf("approved call = %+v", resp)
+	}
+
+	if resp := executor.Execute(context.Background(), ExecuteRequest{FunctionName: "read"}); resp.Error != nil {
+		t.Errorf("call without approval requirement error = %v", resp.Error)
+	}
+}
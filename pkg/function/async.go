@@ -0,0 +1,220 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultAsyncTimeout 异步函数在后台执行的默认超时，函数可通过 TimeoutFunction 覆盖
+const DefaultAsyncTimeout = 30 * time.Minute
+
+// TaskRetention 已结束的异步任务保留多久，过期后无法再查询
+const TaskRetention = time.Hour
+
+// ErrTaskNotFound 异步任务不存在或已过期
+var ErrTaskNotFound = errors.New("async task not found or expired")
+
+// AsyncFunction 可选接口：声明函数为异步长任务
+// Executor 校验参数后立即返回任务句柄（task_id），函数在后台执行，
+// AI 通过内置的 check_task_status 函数查询进度和结果；适用于耗时数分钟的数据处理等操作
+type AsyncFunction interface {
+	// Async 返回是否异步执行
+	Async() bool
+}
+
+// TaskStatus 异步任务状态
+type TaskStatus string
+
+const (
+	TaskRunning   TaskStatus = "running"
+	TaskSucceeded TaskStatus = "succeeded"
+	TaskFailed    TaskStatus = "failed"
+	TaskCancelled TaskStatus = "cancelled"
+)
+
+// TaskInfo 异步任务的状态快照
+type TaskInfo struct {
+	ID              string     `json:"task_id"`
+	FunctionName    string     `json:"function_name"`
+	Status          TaskStatus `json:"status"`
+	Progress        int        `json:"progress"`                   // 进度百分比（0-100），由函数通过 ReportProgress 上报
+	ProgressMessage string     `json:"progress_message,omitempty"` // 进度说明
+	Result          *Result    `json:"result,omitempty"`           // 成功时的执行结果
+	Error           string     `json:"error,omitempty"`            // 失败原因
+	StartedAt       time.Time  `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+}
+
+// Done 任务是否已结束
+func (t TaskInfo) Done() bool {
+	return t.Status != TaskRunning
+}
+
+// asyncTask 后台执行中的任务
+type asyncTask struct {
+	mu     sync.Mutex
+	info   TaskInfo
+	cancel context.CancelFunc
+}
+
+// snapshot 返回任务状态的副本
+func (t *asyncTask) snapshot() TaskInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.info
+}
+
+// TaskManager 异步任务管理器（内存）
+// 进程重启后任务丢失；已结束的任务保留 TaskRetention 后清理
+type TaskManager struct {
+	mu    sync.Mutex
+	tasks map[string]*asyncTask
+	wg    sync.WaitGroup
+}
+
+// NewTaskManager 创建异步任务管理器
+func NewTaskManager() *TaskManager {
+	return &TaskManager{tasks: make(map[string]*asyncTask)}
+}
+
+// taskKey context 中当前异步任务的 key
+type taskKey struct{}
+
+// Start 在后台启动任务，立即返回任务快照
+// 任务 context 脱离调用方的取消（对话结束后继续执行），但保留其中的值（会话、渠道、调用元数据等）
+func (m *TaskManager) Start(ctx context.Context, name string, timeout time.Duration, run func(context.Context) (Result, error)) TaskInfo {
+	if timeout <= 0 {
+		timeout = DefaultAsyncTimeout
+	}
+	taskCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	task := &asyncTask{
+		info: TaskInfo{
+			ID:           newTaskID(),
+			FunctionName: name,
+			Status:       TaskRunning,
+			StartedAt:    time.Now(),
+		},
+		cancel: cancel,
+	}
+	taskCtx = context.WithValue(taskCtx, taskKey{}, task)
+
+	m.mu.Lock()
+	m.prune()
+	m.tasks[task.info.ID] = task
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer cancel()
+		result, err := run(taskCtx)
+
+		task.mu.Lock()
+		defer task.mu.Unlock()
+		now := time.Now()
+		task.info.FinishedAt = &now
+		switch {
+		case errors.Is(taskCtx.Err(), context.Canceled):
+			task.info.Status = TaskCancelled
+			task.info.Error = "task was cancelled"
+		case err != nil:
+			task.info.Status = TaskFailed
+			task.info.Error = err.Error()
+		default:
+			task.info.Status = TaskSucceeded
+			task.info.Progress = 100
+			task.info.Result = &result
+		}
+	}()
+
+	return task.snapshot()
+}
+
+// Get 返回任务的状态快照
+func (m *TaskManager) Get(id string) (TaskInfo, error) {
+	m.mu.Lock()
+	task, ok := m.tasks[id]
+	m.mu.Unlock()
+	if !ok {
+		return TaskInfo{}, fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+	}
+	return task.snapshot(), nil
+}
+
+// Cancel 取消运行中的任务，任务结束后状态为 cancelled
+func (m *TaskManager) Cancel(id string) error {
+	m.mu.Lock()
+	task, ok := m.tasks[id]
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+	}
+	task.cancel()
+	return nil
+}
+
+// Shutdown 取消所有运行中的任务，并最多等待 timeout 让它们退出
+func (m *TaskManager) Shutdown(timeout time.Duration) {
+	m.mu.Lock()
+	for _, task := range m.tasks {
+		task.cancel()
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// prune 清理过期的已结束任务，调用方需持有锁
+func (m *TaskManager) prune() {
+	for id, task := range m.tasks {
+		info := task.snapshot()
+		if info.FinishedAt != nil && time.Since(*info.FinishedAt) > TaskRetention {
+			delete(m.tasks, id)
+		}
+	}
+}
+
+// ReportProgress 异步函数上报执行进度，percent 取值 0-100
+// 在非异步执行的 context 中调用时忽略
+func ReportProgress(ctx context.Context, percent int, message string) {
+	task, ok := ctx.Value(taskKey{}).(*asyncTask)
+	if !ok {
+		return
+	}
+	percent = max(0, min(percent, 100))
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	if task.info.Status == TaskRunning {
+		task.info.Progress = percent
+		task.info.ProgressMessage = message
+	}
+}
+
+// isAsync 判断函数是否声明为异步执行
+func isAsync(fn Function) bool {
+	a, ok := fn.(AsyncFunction)
+	return ok && a.Async()
+}
+
+// newTaskID 生成异步任务 ID
+func newTaskID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("task_%x", time.Now().UnixNano())
+	}
+	return "task_" + hex.EncodeToString(b)
+}
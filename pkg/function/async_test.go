@@ -0,0 +1,135 @@
+package function
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// AsyncMockFunction 声明为异步执行的测试函数
+type AsyncMockFunction struct {
+	MockFunction
+}
+
+func (m *AsyncMockFunction) Async() bool { return true }
+
+// waitTask 等待任务结束
+func waitTask(t *testing.T, tasks *TaskManager, id string) TaskInfo {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		info, err := tasks.Get(id)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", id, err)
+		}
+		if info.Done() {
+			return info
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("task %s did not finish in time", id)
+	return TaskInfo{}
+}
+
+func TestExecutor_Async(t *testing.T) {
+	registry := NewRegistry()
+
+	release := make(chan struct{})
+	registry.Register(&AsyncMockFunction{MockFunction{
+		name:       "async_test",
+		paramsType: reflect.TypeOf(TestParams{}),
+		executeFunc: func(ctx context.Context, params any) (Result, error) {
+			ReportProgress(ctx, 40, "processing")
+			<-release
+			return Result{Message: "done " + params.(TestParams).Name}, nil
+		},
+	}})
+
+	executor := NewExecutor(registry, 5*time.Second)
+
+	// 调用方的 context 结束后任务仍继续执行
+	ctx, cancel := context.WithCancel(context.Background())
+	resp := executor.Execute(ctx, ExecuteRequest{
+		FunctionName: "async_test",
+		Params:       map[string]string{"name": "report"},
+	})
+	cancel()
+	if resp.Error != nil {
+		t.Fatalf("Execute() error = %v", resp.Error)
+	}
+
+	data, ok := resp.Result.Data.(map[string]any)
+	if !ok {
+		t.Fatalf("Result.Data = %T, want map with task_id", resp.Result.Data)
+	}
+	id, _ := data["task_id"].(string)
+	tasks := executor.GetTaskManager()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		info, err := tasks.Get(id)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", id, err)
+		}
+		if info.Progress == 40 {
+			if info.Status != TaskRunning || info.ProgressMessage != "processing" {
+				t.Errorf("running task = %+v, want running with progress message", info)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("progress was not reported, task = %+v", info)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	close(release)
+	info := waitTask(t, tasks, id)
+	if info.Status != TaskSucceeded || info.Progress != 100 || info.Result == nil || info.Result.Message != "done report" {
+		t.Errorf("finished task = %+v, want succeeded with result", info)
+	}
+}
+
+func TestExecutor_AsyncInvalidParams(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(&AsyncMockFunction{MockFunction{
+		name:       "async_invalid",
+		paramsType: reflect.TypeOf(TestParams{}),
+	}})
+
+	executor := NewExecutor(registry, 5*time.Second)
+
+	// 参数错误同步返回，不启动任务
+	resp := executor.Execute(context.Background(), ExecuteRequest{FunctionName: "async_invalid"})
+	if resp.Error == nil {
+		t.Error("Execute() with missing required param should fail synchronously")
+	}
+}
+
+func TestTaskManager_FailAndCancel(t *testing.T) {
+	tasks := NewTaskManager()
+
+	failed := tasks.Start(context.Background(), "fail", time.Second, func(ctx context.Context) (Result, error) {
+		return Result{}, errors.New("boom")
+	})
+	if info := waitTask(t, tasks, failed.ID); info.Status != TaskFailed || info.Error != "boom" {
+		t.Errorf("failed task = %+v, want failed with error", info)
+	}
+
+	cancelled := tasks.Start(context.Background(), "slow", time.Minute, func(ctx context.Context) (Result, error) {
+		<-ctx.Done()
+		return Result{}, ctx.Err()
+	})
+	if err := tasks.Cancel(cancelled.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if info := waitTask(t, tasks, cancelled.ID); info.Status != TaskCancelled {
+		t.Errorf("cancelled task status = %s, want %s", info.Status, TaskCancelled)
+	}
+
+	if _, err := tasks.Get("task_missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Get() missing task error = %v, want ErrTaskNotFound", err)
+	}
+}
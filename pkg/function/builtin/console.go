@@ -0,0 +1,102 @@
+// Package builtin 提供内置的 Function 实现
+package builtin
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// consoleBoxWidth 美化模式下消息框的内容宽度（按终端显示列数计）
+const consoleBoxWidth = 60
+
+// ConsoleOutput console 渠道的输出方式
+// 默认只写结构化日志；写到终端时 Pretty 控制是否使用框线美化
+type ConsoleOutput struct {
+	// Writer 额外写入的目标（如 os.Stdout），为 nil 时只记录日志
+	Writer io.Writer
+
+	// Pretty 以框线样式输出，适合本地交互调试；否则每条消息输出一行纯文本
+	Pretty bool
+}
+
+// write 将消息写入 Writer，未配置时什么也不做
+func (o ConsoleOutput) write(to, message string, at time.Time) {
+	if o.Writer == nil {
+		return
+	}
+	timestamp := at.Format("2006-01-02 15:04:05")
+	if !o.Pretty {
+		fmt.Fprintf(o.Writer, "[%s] message to %s: %s\n", timestamp, to, strings.ReplaceAll(message, "\n", " "))
+		return
+	}
+
+	var buf strings.Builder
+	border := strings.Repeat("═", consoleBoxWidth+2)
+	buf.WriteString("\n╔" + border + "╗\n")
+	buf.WriteString(boxLine("📬 新消息通知"))
+	buf.WriteString("╠" + border + "╣\n")
+	buf.WriteString(boxLine("收件人: " + to))
+	buf.WriteString(boxLine("时间:   " + timestamp))
+	buf.WriteString("╠" + border + "╣\n")
+	for _, line := range wrapDisplay(message, consoleBoxWidth) {
+		buf.WriteString(boxLine(line))
+	}
+	buf.WriteString("╚" + border + "╝\n\n")
+	_, _ = io.WriteString(o.Writer, buf.String())
+}
+
+// boxLine 输出框内的一行，按显示宽度补齐右侧空格，中文等宽字符也能对齐
+func boxLine(text string) string {
+	padding := max(consoleBoxWidth-displayWidth(text), 0)
+	return "║ " + text + strings.Repeat(" ", padding) + " ║\n"
+}
+
+// wrapDisplay 按显示宽度折行，保留原有的换行
+func wrapDisplay(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line strings.Builder
+		lineWidth := 0
+		for _, r := range paragraph {
+			w := runeWidth(r)
+			if lineWidth+w > width {
+				lines = append(lines, line.String())
+				line.Reset()
+				lineWidth = 0
+			}
+			line.WriteRune(r)
+			lineWidth += w
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// displayWidth 字符串在终端中占用的列数
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth 字符的显示宽度：中日韩文字、全角符号和 emoji 占两列，组合字符不占列
+func runeWidth(r rune) int {
+	switch {
+	case unicode.Is(unicode.Mn, r), r == '\u200d', r == '\ufe0f':
+		return 0
+	case unicode.Is(unicode.Han, r), unicode.Is(unicode.Hangul, r),
+		unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r),
+		r >= 0x3000 && r <= 0x303f, // 中文标点
+		r >= 0xff00 && r <= 0xff60, // 全角字符
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1faff: // emoji
+		return 2
+	default:
+		return 1
+	}
+}
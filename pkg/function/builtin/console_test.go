@@ -0,0 +1,64 @@
+package builtin
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRuneWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		r    rune
+		want int
+	}{
+		{"ascii letter", 'a', 1},
+		{"ascii space", ' ', 1},
+		{"latin accented", 'é', 1},
+		{"han", '中', 2},
+		{"hangul", '한', 2},
+		{"hiragana", 'あ', 2},
+		{"katakana", 'カ', 2},
+		{"chinese punctuation", '。', 2},
+		{"fullwidth letter", 'Ａ', 2},
+		{"fullwidth yen", '￥', 2},
+		{"emoji", '😀', 2},
+		{"combining accent", '\u0301', 0},
+		{"zero width joiner", '\u200d', 0},
+		{"variation selector", '\ufe0f', 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runeWidth(tt.r); got != tt.want {
+				t.Errorf("runeWidth(%q) = %d, want %d", tt.r, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapDisplay(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  []string
+	}{
+		{"empty", "", 10, []string{""}},
+		{"fits", "hello", 10, []string{"hello"}},
+		{"exactly width", "hello", 5, []string{"hello"}},
+		{"wraps ascii", "abcdefg", 3, []string{"abc", "def", "g"}},
+		{"keeps newlines", "ab\ncd", 10, []string{"ab", "cd"}},
+		{"keeps empty lines", "ab\n\ncd", 10, []string{"ab", "", "cd"}},
+		{"wide runes", "你好世界", 4, []string{"你好", "世界"}},
+		{"wide rune does not split a column", "a你好", 4, []string{"a你", "好"}},
+		{"zero width stays on line", "ab\u0301c", 3, []string{"ab\u0301c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrapDisplay(tt.text, tt.width); !slices.Equal(got, tt.want) {
+				t.Errorf("wrapDisplay(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+		})
+	}
+}
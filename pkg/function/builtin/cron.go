@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/KodaTao/AgentChassis/pkg/function"
@@ -18,6 +19,12 @@ type CronCreateParams struct {
 	Prompt      string `json:"prompt" desc:"任务触发时发送给AI的提示词，AI会根据提示词决定执行什么操作" required:"true"`
 	Description string `json:"description" desc:"任务描述"`
 	Channel     string `json:"channel" desc:"渠道上下文JSON，如 {\"type\":\"console\"} 或 {\"type\":\"telegram\",\"chat_id\":\"123\"}"`
+
+	ConcurrencyPolicy string `json:"concurrency_policy" desc:"上次执行未完成时的处理策略：allow（并发执行）、skip（跳过本次）、queue（排队等待），默认 allow" default:"allow"`
+	MisfirePolicy     string `json:"misfire_policy" desc:"服务停机期间错过执行时的补偿策略：ignore（忽略）、run_once（恢复后补偿执行一次）、run_all（补偿每一次错过的执行），默认 ignore；每日报表等不能漏的任务建议 run_once" default:"ignore"`
+
+	Tags  string `json:"tags" desc:"标签（可选），逗号分隔，如 report,project-x，用于分类查看和批量管理"`
+	Group string `json:"group" desc:"分组（可选），如项目名"`
 }
 
 // CronCreateFunction 创建定时任务的函数
@@ -51,8 +58,20 @@ func (f *CronCreateFunction) Execute(ctx context.Context, params any) (function.
 		fullPrompt = fmt.Sprintf("【渠道信息：%s】\n%s", p.Channel, p.Prompt)
 	}
 
-	// 创建任务（传递渠道信息）
-	task, err := f.scheduler.CreateTask(p.Name, p.CronExpr, fullPrompt, p.Description, p.Channel)
+	tags, err := scheduler.ParseTags(p.Tags)
+	if err != nil {
+		return function.Result{}, err
+	}
+
+	// 创建任务（传递渠道信息和调用者作用域）
+	task, err := f.scheduler.CreateTaskWithOptions(p.Name, p.CronExpr, fullPrompt, p.Description, scheduler.CronTaskOptions{
+		ConcurrencyPolicy: scheduler.ConcurrencyPolicy(p.ConcurrencyPolicy),
+		MisfirePolicy:     scheduler.MisfirePolicy(p.MisfirePolicy),
+		Channel:           p.Channel,
+		Tags:              tags,
+		Group:             p.Group,
+		CallerScopes:      scheduler.EncodeCallerScopes(function.CallerScopesFromContext(ctx)),
+	})
 	if err != nil {
 		return function.Result{}, err
 	}
@@ -69,21 +88,41 @@ func (f *CronCreateFunction) Execute(ctx context.Context, params any) (function.
 		"prompt":      task.Prompt,
 		"description": task.Description,
 		"next_run_at": nextRunStr,
+
+		"concurrency_policy": task.ConcurrencyPolicy,
+		"misfire_policy":     task.MisfirePolicy,
 	}
 	if task.Channel != "" {
 		data["channel"] = task.Channel
 	}
+	addTaskLabels(data, task.Tags, task.Group)
+	humanReadable := scheduler.DescribeCron(task.CronExpr)
+	if humanReadable != "" {
+		data["human_readable"] = humanReadable
+	}
+
+	// 附上接下来几次触发时间，便于向用户确认表达式是否符合预期
+	upcoming := []string{}
+	if runs, err := f.scheduler.PreviewNextRuns(task.CronExpr, 3); err == nil {
+		for _, run := range runs {
+			upcoming = append(upcoming, run.Format("2006-01-02 15:04:05"))
+		}
+		data["upcoming_runs"] = upcoming
+	}
 
 	return function.Result{
-		Message: fmt.Sprintf("定时任务创建成功（ID: %d），下次触发时间: %s", task.ID, nextRunStr),
-		Data:    data,
+		Message: fmt.Sprintf("定时任务创建成功（ID: %d，%s），接下来将在以下时间触发: %s",
+			task.ID, cronSchedule(task.CronExpr, humanReadable), strings.Join(upcoming, "、")),
+		Data: data,
 	}, nil
 }
 
 // CronListParams 列出定时任务的参数
 type CronListParams struct {
-	Limit  int `json:"limit" desc:"返回数量限制，默认20" default:"20"`
-	Offset int `json:"offset" desc:"偏移量，用于分页" default:"0"`
+	Tag    string `json:"tag" desc:"按标签筛选（可选）"`
+	Group  string `json:"group" desc:"按分组筛选（可选）"`
+	Limit  int    `json:"limit" desc:"返回数量限制，默认20" default:"20"`
+	Offset int    `json:"offset" desc:"偏移量，用于分页" default:"0"`
 }
 
 // CronListFunction 列出定时任务的函数
@@ -101,7 +140,7 @@ func (f *CronListFunction) Name() string {
 }
 
 func (f *CronListFunction) Description() string {
-	return "列出所有定时任务"
+	return "列出定时任务，可按标签、分组筛选"
 }
 
 func (f *CronListFunction) ParamsType() reflect.Type {
@@ -116,12 +155,13 @@ func (f *CronListFunction) Execute(ctx context.Context, params any) (function.Re
 		limit = 20
 	}
 
-	tasks, err := f.scheduler.ListTasks(limit, p.Offset)
+	filter := scheduler.CronTaskFilter{Tag: p.Tag, Group: p.Group}
+	tasks, err := f.scheduler.ListTasksFiltered(filter, limit, p.Offset)
 	if err != nil {
 		return function.Result{}, err
 	}
 
-	total, err := f.scheduler.CountTasks()
+	total, err := f.scheduler.CountTasksFiltered(filter)
 	if err != nil {
 		return function.Result{}, err
 	}
@@ -142,6 +182,10 @@ func (f *CronListFunction) Execute(ctx context.Context, params any) (function.Re
 			"next_run_at": nextRunStr,
 			"created_at":  task.CreatedAt.Format(time.RFC3339),
 		}
+		if humanReadable := scheduler.DescribeCron(task.CronExpr); humanReadable != "" {
+			taskList[i]["human_readable"] = humanReadable
+		}
+		addTaskLabels(taskList[i], task.Tags, task.Group)
 	}
 
 	return function.Result{
@@ -248,13 +292,26 @@ func (f *CronGetFunction) Execute(ctx context.Context, params any) (function.Res
 		"created_at":  task.CreatedAt.Format(time.RFC3339),
 		"updated_at":  task.UpdatedAt.Format(time.RFC3339),
 	}
+	addTaskLabels(data, task.Tags, task.Group)
+	humanReadable := scheduler.DescribeCron(task.CronExpr)
+	if humanReadable != "" {
+		data["human_readable"] = humanReadable
+	}
 
 	return function.Result{
-		Message: fmt.Sprintf("定时任务（ID: %d）: %s，下次执行时间: %s", task.ID, task.Name, nextRunStr),
+		Message: fmt.Sprintf("定时任务（ID: %d）: %s，%s，下次执行时间: %s", task.ID, task.Name, cronSchedule(task.CronExpr, humanReadable), nextRunStr),
 		Data:    data,
 	}, nil
 }
 
+// cronSchedule 返回执行周期的展示文本，有中文描述时优先使用
+func cronSchedule(expr, humanReadable string) string {
+	if humanReadable != "" {
+		return humanReadable + "执行"
+	}
+	return "Cron 表达式 " + expr
+}
+
 // CronHistoryParams 获取执行历史的参数
 type CronHistoryParams struct {
 	ID     uint `json:"id" desc:"任务ID" required:"true"`
@@ -3,8 +3,10 @@ package builtin
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/KodaTao/AgentChassis/pkg/function"
@@ -17,6 +19,11 @@ type DelayCreateParams struct {
 	RunAt   string `json:"run_at" desc:"执行时间，ISO8601格式，如 2024-01-15T10:30:00+08:00" required:"true"`
 	Prompt  string `json:"prompt" desc:"任务触发时发送给AI的提示词，AI会根据提示词决定执行什么操作" required:"true"`
 	Channel string `json:"channel" desc:"渠道上下文JSON，如 {\"type\":\"console\"} 或 {\"type\":\"telegram\",\"chat_id\":\"123\"}"`
+
+	IdempotencyKey string `json:"idempotency_key" desc:"幂等键（可选），相同键且任务仍待执行时返回已有任务而不重复创建，如 water-reminder-20240115-1030"`
+	Priority       int    `json:"priority" desc:"优先级（可选），同时到期的任务中数值越大越先执行，默认 0"`
+	Tags           string `json:"tags" desc:"标签（可选），逗号分隔，如 reminder,health，用于分类查看和批量管理"`
+	Group          string `json:"group" desc:"分组（可选），如项目名"`
 }
 
 // DelayCreateFunction 创建延时任务的函数
@@ -56,8 +63,24 @@ func (f *DelayCreateFunction) Execute(ctx context.Context, params any) (function
 		fullPrompt = fmt.Sprintf("【渠道信息：%s】\n%s", p.Channel, p.Prompt)
 	}
 
-	// 创建任务（传递渠道信息）
-	task, err := f.scheduler.CreateTask(p.Name, runAt, fullPrompt, p.Channel)
+	tags, err := scheduler.ParseTags(p.Tags)
+	if err != nil {
+		return function.Result{}, err
+	}
+
+	// 创建任务（传递渠道信息和调用者作用域）
+	task, existed, err := f.scheduler.CreateTaskWithOptions(p.Name, runAt, fullPrompt, scheduler.DelayTaskOptions{
+		IdempotencyKey: p.IdempotencyKey,
+		Channel:        p.Channel,
+		Priority:       p.Priority,
+		Tags:           tags,
+		Group:          p.Group,
+		CallerScopes:   scheduler.EncodeCallerScopes(function.CallerScopesFromContext(ctx)),
+	})
+	if errors.Is(err, scheduler.ErrRunAtInPast) {
+		// 时间算错属于参数问题，错误中带有服务器当前时间，AI 可据此重新计算
+		return function.Result{}, function.WithErrorClass(err, function.ErrorClassInvalidParams)
+	}
 	if err != nil {
 		return function.Result{}, err
 	}
@@ -72,6 +95,22 @@ func (f *DelayCreateFunction) Execute(ctx context.Context, params any) (function
 	if task.Channel != "" {
 		data["channel"] = task.Channel
 	}
+	if task.Priority != 0 {
+		data["priority"] = task.Priority
+	}
+	addTaskLabels(data, task.Tags, task.Group)
+	if task.IdempotencyKey != nil {
+		data["idempotency_key"] = *task.IdempotencyKey
+	}
+
+	if existed {
+		data["existed"] = true
+		return function.Result{
+			Message: fmt.Sprintf("相同幂等键的延时任务已存在（ID: %d），将在 %s 触发，未重复创建",
+				task.ID, task.RunAt.Format("2006-01-02 15:04:05")),
+			Data: data,
+		}, nil
+	}
 
 	return function.Result{
 		Message: fmt.Sprintf("延时任务创建成功（ID: %d），将在 %s 触发AI执行",
@@ -83,6 +122,8 @@ func (f *DelayCreateFunction) Execute(ctx context.Context, params any) (function
 // DelayListParams 列出延时任务的参数
 type DelayListParams struct {
 	Status string `json:"status" desc:"按状态筛选：pending/running/completed/failed/cancelled/missed，不填则返回所有"`
+	Tag    string `json:"tag" desc:"按标签筛选（可选）"`
+	Group  string `json:"group" desc:"按分组筛选（可选）"`
 	Limit  int    `json:"limit" desc:"返回数量限制，默认20" default:"20"`
 	Offset int    `json:"offset" desc:"偏移量，用于分页" default:"0"`
 }
@@ -102,7 +143,7 @@ func (f *DelayListFunction) Name() string {
 }
 
 func (f *DelayListFunction) Description() string {
-	return "列出延时任务，可按状态筛选。状态包括：pending（待执行）、running（执行中）、completed（已完成）、failed（失败）、cancelled（已取消）、missed（错过执行）"
+	return "列出延时任务，可按状态、标签、分组筛选。状态包括：pending（待执行）、running（执行中）、completed（已完成）、failed（失败）、cancelled（已取消）、missed（错过执行）"
 }
 
 func (f *DelayListFunction) ParamsType() reflect.Type {
@@ -117,6 +158,7 @@ func (f *DelayListFunction) Execute(ctx context.Context, params any) (function.R
 		limit = 20
 	}
 
+	filter := scheduler.DelayTaskFilter{Tag: p.Tag, Group: p.Group}
 	var status *scheduler.TaskStatus
 	if p.Status != "" {
 		s := scheduler.TaskStatus(p.Status)
@@ -130,12 +172,14 @@ func (f *DelayListFunction) Execute(ctx context.Context, params any) (function.R
 		}
 	}
 
-	tasks, err := f.scheduler.ListTasks(status, limit, p.Offset)
+	filter.Status = status
+
+	tasks, err := f.scheduler.ListTasksFiltered(filter, limit, p.Offset)
 	if err != nil {
 		return function.Result{}, err
 	}
 
-	total, err := f.scheduler.CountTasks(status)
+	total, err := f.scheduler.CountTasksFiltered(filter)
 	if err != nil {
 		return function.Result{}, err
 	}
@@ -157,6 +201,7 @@ func (f *DelayListFunction) Execute(ctx context.Context, params any) (function.R
 		if task.Error != "" {
 			taskList[i]["error"] = task.Error
 		}
+		addTaskLabels(taskList[i], task.Tags, task.Group)
 	}
 
 	message := fmt.Sprintf("找到 %d 个延时任务（共 %d 个）", len(tasks), total)
@@ -272,6 +317,7 @@ func (f *DelayGetFunction) Execute(ctx context.Context, params any) (function.Re
 	if task.Error != "" {
 		data["error"] = task.Error
 	}
+	addTaskLabels(data, task.Tags, task.Group)
 
 	statusDesc := ""
 	switch task.Status {
@@ -294,3 +340,13 @@ func (f *DelayGetFunction) Execute(ctx context.Context, params any) (function.Re
 		Data:    data,
 	}, nil
 }
+
+// addTaskLabels 在任务输出中附上标签（逗号分隔）和分组，未设置时不输出
+func addTaskLabels(data map[string]any, tags []string, group string) {
+	if len(tags) > 0 {
+		data["tags"] = strings.Join(tags, ",")
+	}
+	if group != "" {
+		data["group"] = group
+	}
+}
@@ -0,0 +1,132 @@
+// Package builtin 提供内置的 Function 实现
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/memory"
+)
+
+// errNoUser 当前对话没有用户标识，无法读写记忆
+var errNoUser = errors.New("no user identity in this conversation, memory is unavailable")
+
+// RememberParams 记住用户信息的参数
+type RememberParams struct {
+	Key   string `json:"key" desc:"记忆键，简短的英文或拼音标识，如 nickname、timezone、home_city" required:"true"`
+	Value string `json:"value" desc:"记忆内容，如 小明、Asia/Shanghai、杭州" required:"true"`
+}
+
+// RememberFunction 记住当前用户的偏好或信息
+type RememberFunction struct {
+	repo *memory.Repository
+}
+
+// NewRememberFunction 创建 RememberFunction
+func NewRememberFunction(repo *memory.Repository) *RememberFunction {
+	return &RememberFunction{repo: repo}
+}
+
+func (f *RememberFunction) Name() string {
+	return "remember"
+}
+
+func (f *RememberFunction) Description() string {
+	return "长期记住当前用户的偏好或信息（如称呼、时区、常用地点），之后的对话中会自动提供给AI。相同key会覆盖旧值。只在用户明确告知或要求记住时调用。"
+}
+
+func (f *RememberFunction) ParamsType() reflect.Type {
+	return reflect.TypeOf(RememberParams{})
+}
+
+func (f *RememberFunction) Execute(ctx context.Context, params any) (function.Result, error) {
+	p := params.(RememberParams)
+
+	userID := memory.UserIDFromContext(ctx)
+	if userID == "" {
+		return function.Result{}, errNoUser
+	}
+
+	if err := f.repo.Set(userID, p.Key, p.Value); err != nil {
+		return function.Result{}, err
+	}
+
+	return function.Result{
+		Message: fmt.Sprintf("已记住 %s：%s", p.Key, p.Value),
+		Data: map[string]any{
+			"key":   p.Key,
+			"value": p.Value,
+		},
+	}, nil
+}
+
+// RecallParams 读取用户记忆的参数
+type RecallParams struct {
+	Key string `json:"key" desc:"要读取的记忆键，不填则返回该用户的全部记忆"`
+}
+
+// RecallFunction 读取当前用户的记忆
+type RecallFunction struct {
+	repo *memory.Repository
+}
+
+// NewRecallFunction 创建 RecallFunction
+func NewRecallFunction(repo *memory.Repository) *RecallFunction {
+	return &RecallFunction{repo: repo}
+}
+
+func (f *RecallFunction) Name() string {
+	return "recall"
+}
+
+func (f *RecallFunction) Description() string {
+	return "读取当前用户的长期记忆。指定key时返回该条记忆，不指定时返回全部记忆。"
+}
+
+func (f *RecallFunction) ParamsType() reflect.Type {
+	return reflect.TypeOf(RecallParams{})
+}
+
+func (f *RecallFunction) Execute(ctx context.Context, params any) (function.Result, error) {
+	p := params.(RecallParams)
+
+	userID := memory.UserIDFromContext(ctx)
+	if userID == "" {
+		return function.Result{}, errNoUser
+	}
+
+	if p.Key != "" {
+		m, err := f.repo.Get(userID, p.Key)
+		if err != nil {
+			if errors.Is(err, memory.ErrMemoryNotFound) {
+				return function.Result{Message: fmt.Sprintf("没有关于 %s 的记忆", p.Key)}, nil
+			}
+			return function.Result{}, err
+		}
+		return function.Result{
+			Message: fmt.Sprintf("%s：%s", m.Key, m.Value),
+			Data: map[string]any{
+				"key":   m.Key,
+				"value": m.Value,
+			},
+		}, nil
+	}
+
+	memories, err := f.repo.List(userID)
+	if err != nil {
+		return function.Result{}, err
+	}
+
+	items := make(map[string]any, len(memories))
+	for _, m := range memories {
+		items[m.Key] = m.Value
+	}
+
+	return function.Result{
+		Message: fmt.Sprintf("找到 %d 条记忆", len(memories)),
+		Data:    items,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/memory"
+	"github.com/KodaTao/AgentChassis/pkg/storage/storagetest"
+)
+
+func TestMemoryFunctions_UserIsolation(t *testing.T) {
+	repo := storagetest.NewRepository(t, memory.NewRepository)
+	remember := NewRememberFunction(repo)
+	recall := NewRecallFunction(repo)
+
+	alice := memory.WithUserID(context.Background(), "telegram:1")
+	bob := memory.WithUserID(context.Background(), "api_key:web")
+
+	if _, err := remember.Execute(alice, RememberParams{Key: "city", Value: "Paris"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := recall.Execute(alice, RecallParams{Key: "city"})
+	if err != nil || result.Message != "city：Paris" {
+		t.Errorf("alice recall = %+v, %v", result, err)
+	}
+
+	result, err = recall.Execute(bob, RecallParams{Key: "city"})
+	if err != nil || result.Message != "没有关于 city 的记忆" {
+		t.Errorf("bob recall of alice's key = %+v, %v", result, err)
+	}
+	result, err = recall.Execute(bob, RecallParams{})
+	if err != nil || len(result.Data.(map[string]any)) != 0 {
+		t.Errorf("bob recall all = %+v, %v", result, err)
+	}
+}
+
+func TestMemoryFunctions_NoUser(t *testing.T) {
+	repo := storagetest.NewRepository(t, memory.NewRepository)
+	ctx := context.Background()
+
+	if _, err := NewRememberFunction(repo).Execute(ctx, RememberParams{Key: "k", Value: "v"}); !errors.Is(err, errNoUser) {
+		t.Errorf("remember without user error = %v, want errNoUser", err)
+	}
+	if _, err := NewRecallFunction(repo).Execute(ctx, RecallParams{}); !errors.Is(err, errNoUser) {
+		t.Errorf("recall without user error = %v, want errNoUser", err)
+	}
+}
@@ -10,13 +10,14 @@ import (
 
 	"github.com/KodaTao/AgentChassis/pkg/function"
 	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/types"
 )
 
 // NotificationChannel 通知渠道类型
 type NotificationChannel string
 
 const (
-	ChannelConsole  NotificationChannel = "console"  // 控制台输出（默认）
+	ChannelConsole  NotificationChannel = "console"  // 控制台（默认写日志，可配置输出到终端）
 	ChannelTelegram NotificationChannel = "telegram" // Telegram 消息
 	ChannelEmail    NotificationChannel = "email"    // 邮件（待实现）
 	ChannelSMS      NotificationChannel = "sms"      // 短信（待实现）
@@ -31,9 +32,9 @@ type TelegramSender interface {
 
 // SendMessageParams 发送消息的参数
 type SendMessageParams struct {
-	To      string `json:"to" desc:"接收者（人名、邮箱、telegram chat_id 等，根据渠道而定）" required:"true"`
+	To      string `json:"to" desc:"接收者（人名、邮箱、telegram chat_id 等，根据渠道而定），不填则发给当前对话的用户"`
 	Message string `json:"message" desc:"消息内容" required:"true"`
-	Channel string `json:"channel" desc:"通知渠道：console（控制台，默认）、telegram、email、sms、wechat" default:"console"`
+	Channel string `json:"channel" desc:"通知渠道：console、telegram、email、sms、wechat，不填则使用当前对话的渠道（没有时为 console）"`
 }
 
 // SendMessageFunction 发送消息的函数
@@ -41,6 +42,7 @@ type SendMessageParams struct {
 // 支持控制台输出、Telegram，未来可扩展为邮件、短信、微信等渠道
 type SendMessageFunction struct {
 	telegramSender TelegramSender
+	console        ConsoleOutput
 }
 
 func (f *SendMessageFunction) Name() string {
@@ -48,7 +50,7 @@ func (f *SendMessageFunction) Name() string {
 }
 
 func (f *SendMessageFunction) Description() string {
-	return "向指定的人发送消息通知。可以直接调用，也可以配合延时任务在指定时间发送。支持控制台输出和 Telegram，未来可扩展邮件、短信、微信等渠道。对于 Telegram 渠道，to 参数需要是 chat_id。"
+	return "向指定的人发送消息通知。可以直接调用，也可以配合延时任务在指定时间发送。支持控制台输出和 Telegram，未来可扩展邮件、短信、微信等渠道。对于 Telegram 渠道，to 参数需要是 chat_id；发给当前对话的用户时 to 和 channel 都可以不填。"
 }
 
 func (f *SendMessageFunction) ParamsType() reflect.Type {
@@ -58,6 +60,19 @@ func (f *SendMessageFunction) ParamsType() reflect.Type {
 func (f *SendMessageFunction) Execute(ctx context.Context, params any) (function.Result, error) {
 	p := params.(SendMessageParams)
 
+	// 未指定渠道或接收者时，默认发往当前对话（或触发任务时记录）的渠道
+	if current := types.ChannelFromContext(ctx); current != nil {
+		if p.Channel == "" {
+			p.Channel = current.Type
+		}
+		if p.To == "" && p.Channel == current.Type {
+			p.To = current.ChatID
+		}
+	}
+	if p.To == "" {
+		return function.Result{}, fmt.Errorf("to is required when the conversation has no channel")
+	}
+
 	// 确定通知渠道
 	channel := NotificationChannel(p.Channel)
 	if channel == "" {
@@ -73,33 +88,11 @@ func (f *SendMessageFunction) Execute(ctx context.Context, params any) (function
 
 	switch channel {
 	case ChannelConsole:
-		// 控制台输出
-		fmt.Printf("\n")
-		fmt.Printf("╔══════════════════════════════════════════════════════════════╗\n")
-		fmt.Printf("║  📬 新消息通知                                                ║\n")
-		fmt.Printf("╠══════════════════════════════════════════════════════════════╣\n")
-		fmt.Printf("║  收件人: %-52s ║\n", p.To)
-		fmt.Printf("║  时间:   %-52s ║\n", timestamp)
-		fmt.Printf("╠══════════════════════════════════════════════════════════════╣\n")
-		fmt.Printf("║  内容: %-54s ║\n", truncateString(p.Message, 54))
-		if len(p.Message) > 54 {
-			// 长消息换行显示
-			remaining := p.Message[54:]
-			for len(remaining) > 0 {
-				lineLen := 54
-				if len(remaining) < lineLen {
-					lineLen = len(remaining)
-				}
-				fmt.Printf("║         %-54s ║\n", remaining[:lineLen])
-				remaining = remaining[lineLen:]
-			}
-		}
-		fmt.Printf("╚══════════════════════════════════════════════════════════════╝\n")
-		fmt.Printf("\n")
+		// 默认只记录日志，不污染标准输出；配置了输出目标时同时写入
+		f.console.write(p.To, p.Message, now)
 
 		deliveryStatus = "delivered"
 
-		// 同时记录到日志
 		observability.Info("Message sent",
 			"channel", "console",
 			"to", p.To,
@@ -201,6 +194,11 @@ func NewSendMessageFunctionWithTelegram(telegramSender TelegramSender) *SendMess
 	}
 }
 
+// SetConsoleOutput 设置 console 渠道的输出方式
+func (f *SendMessageFunction) SetConsoleOutput(output ConsoleOutput) {
+	f.console = output
+}
+
 // SetTelegramSender 设置 Telegram 发送器（用于延迟注入）
 func (f *SendMessageFunction) SetTelegramSender(sender TelegramSender) {
 	f.telegramSender = sender
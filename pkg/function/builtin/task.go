@@ -0,0 +1,79 @@
+// Package builtin 提供内置的 Function 实现
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+)
+
+// CheckTaskStatusParams 查询异步任务的参数
+type CheckTaskStatusParams struct {
+	TaskID string `json:"task_id" desc:"异步函数返回的任务ID，形如 task_xxxx" required:"true"`
+}
+
+// CheckTaskStatusFunction 查询异步长任务的进度和结果
+type CheckTaskStatusFunction struct {
+	tasks *function.TaskManager
+}
+
+// NewCheckTaskStatusFunction 创建 CheckTaskStatusFunction
+func NewCheckTaskStatusFunction(tasks *function.TaskManager) *CheckTaskStatusFunction {
+	return &CheckTaskStatusFunction{tasks: tasks}
+}
+
+func (f *CheckTaskStatusFunction) Name() string {
+	return "check_task_status"
+}
+
+func (f *CheckTaskStatusFunction) Description() string {
+	return "查询异步函数在后台执行的任务进度和结果（不适用于延时任务和定时任务）。状态包括：running（执行中）、succeeded（已完成，返回函数结果）、failed（失败）、cancelled（已取消）。任务仍在执行时可告知用户进度，稍后再查询。"
+}
+
+func (f *CheckTaskStatusFunction) ParamsType() reflect.Type {
+	return reflect.TypeOf(CheckTaskStatusParams{})
+}
+
+func (f *CheckTaskStatusFunction) Execute(ctx context.Context, params any) (function.Result, error) {
+	p := params.(CheckTaskStatusParams)
+
+	task, err := f.tasks.Get(p.TaskID)
+	if err != nil {
+		return function.Result{}, err
+	}
+
+	switch task.Status {
+	case function.TaskSucceeded:
+		// 直接返回函数自己的结果，AI 看到的与同步执行时一致
+		result := *task.Result
+		result.Message = fmt.Sprintf("任务 %s（%s）已完成：%s", task.ID, task.FunctionName, result.Message)
+		return result, nil
+	case function.TaskRunning:
+		message := fmt.Sprintf("任务 %s（%s）执行中，进度 %d%%", task.ID, task.FunctionName, task.Progress)
+		if task.ProgressMessage != "" {
+			message += "：" + task.ProgressMessage
+		}
+		return function.Result{
+			Message: message,
+			Data: map[string]any{
+				"task_id":          task.ID,
+				"status":           task.Status,
+				"progress":         task.Progress,
+				"progress_message": task.ProgressMessage,
+				"started_at":       task.StartedAt.Format(time.RFC3339),
+			},
+		}, nil
+	default:
+		return function.Result{
+			Message: fmt.Sprintf("任务 %s（%s）%s：%s", task.ID, task.FunctionName, task.Status, task.Error),
+			Data: map[string]any{
+				"task_id": task.ID,
+				"status":  task.Status,
+				"error":   task.Error,
+			},
+		}, nil
+	}
+}
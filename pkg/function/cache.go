@@ -0,0 +1,129 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize 默认缓存条目上限
+const DefaultCacheSize = 256
+
+// ResultCache 函数结果缓存
+// 基于 LRU 淘汰，每个条目带独立 TTL，线程安全
+type ResultCache struct {
+	mu      sync.Mutex
+	maxSize int
+	ll      *list.List
+	items   map[string]*list.Element
+}
+
+// cacheEntry 缓存条目
+type cacheEntry struct {
+	key       string
+	result    Result
+	expiresAt time.Time
+}
+
+// NewResultCache 创建结果缓存
+// maxSize <= 0 时使用默认上限
+func NewResultCache(maxSize int) *ResultCache {
+	if maxSize <= 0 {
+		maxSize = DefaultCacheSize
+	}
+	return &ResultCache{
+		maxSize: maxSize,
+		ll:      list.New(),
+		items:   make(map[string]*list.Element),
+	}
+}
+
+// Get 获取缓存结果，过期条目会被移除
+func (c *ResultCache) Get(key string) (Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return Result{}, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(elem)
+		return Result{}, false
+	}
+
+	c.ll.MoveToFront(elem)
+	return entry.result, true
+}
+
+// Set 写入缓存，超出上限时淘汰最久未使用的条目
+func (c *ResultCache) Set(key string, result Result, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.result = result
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	elem := c.ll.PushFront(&cacheEntry{key: key, result: result, expiresAt: expiresAt})
+	c.items[key] = elem
+
+	for c.ll.Len() > c.maxSize {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Len 返回当前缓存条目数
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Clear 清空缓存
+func (c *ResultCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// removeElement 移除条目（调用方需持有锁）
+func (c *ResultCache) removeElement(elem *list.Element) {
+	c.ll.Remove(elem)
+	delete(c.items, elem.Value.(*cacheEntry).key)
+}
+
+// cacheKey 根据函数名、参数和数据生成缓存键
+// 参数按 key 排序以保证相同参数得到相同的键
+func cacheKey(name string, params map[string]string, data string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	h.Write([]byte(name))
+	for _, k := range keys {
+		h.Write([]byte{0})
+		h.Write([]byte(k))
+		h.Write([]byte{'='})
+		h.Write([]byte(params[k]))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(data))
+
+	return name + ":" + hex.EncodeToString(h.Sum(nil))
+}
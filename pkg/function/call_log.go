@@ -0,0 +1,154 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/KodaTao/AgentChassis/pkg/storage"
+)
+
+// 调用状态
+const (
+	CallStatusSuccess = "success"
+	CallStatusError   = "error"
+)
+
+// CallLog 函数调用记录，用于事后审计
+type CallLog struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	SessionID    string    `gorm:"index" json:"session_id"`             // 所属会话
+	RequestID    string    `gorm:"index" json:"request_id,omitempty"`   // 所属 Chat 请求
+	CallID       string    `json:"call_id,omitempty"`                   // 调用 ID
+	Iteration    int       `json:"iteration,omitempty"`                 // 第几轮 LLM 调用
+	CallIndex    int       `json:"call_index,omitempty"`                // 本轮中的第几个调用
+	FunctionName string    `gorm:"not null;index" json:"function_name"` // 函数名
+	Params       string    `gorm:"type:text" json:"params,omitempty"`   // 调用参数（JSON）
+	Status       string    `gorm:"not null;index" json:"status"`        // success, error
+	DurationMs   int64     `json:"duration_ms"`                         // 执行耗时（毫秒）
+	Error        string    `gorm:"type:text" json:"error,omitempty"`    // 错误信息
+	CreatedAt    time.Time `gorm:"not null;index" json:"created_at"`    // 调用时间
+}
+
+// TableName 指定表名
+func (CallLog) TableName() string {
+	return "function_call_logs"
+}
+
+// NewCallLog 根据执行请求和响应构建调用记录
+func NewCallLog(sessionID string, req ExecuteRequest, resp ExecuteResponse) *CallLog {
+	log := &CallLog{
+		SessionID:    sessionID,
+		FunctionName: req.FunctionName,
+		Status:       CallStatusSuccess,
+		DurationMs:   resp.Duration.Milliseconds(),
+		CreatedAt:    time.Now(),
+	}
+
+	if len(req.Params) > 0 {
+		if b, err := json.Marshal(req.Params); err == nil {
+			log.Params = string(b)
+		}
+	}
+
+	if resp.Error != nil {
+		log.Status = CallStatusError
+		log.Error = resp.Error.Error()
+	}
+
+	return log
+}
+
+// CallLogFilter 调用记录查询条件，零值字段不参与过滤
+type CallLogFilter struct {
+	RequestID    string
+	FunctionName string
+	Status       string
+	Since        time.Time // 起始时间（含）
+	Until        time.Time // 结束时间（不含）
+}
+
+// CallLogRepository 函数调用记录数据访问层
+type CallLogRepository struct {
+	db *gorm.DB
+}
+
+// NewCallLogRepository 创建 CallLogRepository
+func NewCallLogRepository(db *gorm.DB) *CallLogRepository {
+	return &CallLogRepository{db: db}
+}
+
+// callLogMigrations 调用记录表的迁移，schema 变化时在末尾追加新版本
+var callLogMigrations = []storage.Migration{
+	{
+		Version: 1,
+		Name:    "create function_call_logs",
+		Up:      storage.CreateTables(&CallLog{}),
+		Down:    storage.DropTables(&CallLog{}),
+	},
+	{
+		Version: 2,
+		Name:    "add request tracing columns to function_call_logs",
+		Up:      storage.AddColumns(&CallLog{}, "RequestID", "CallID", "Iteration", "CallIndex"),
+		Down:    storage.DropColumns(&CallLog{}, "RequestID", "CallID", "Iteration", "CallIndex"),
+	},
+}
+
+// Migrate 按版本执行未应用的迁移
+func (r *CallLogRepository) Migrate() error {
+	return storage.NewMigrator(r.db, "call_log", callLogMigrations...).Migrate()
+}
+
+// Create 创建调用记录
+func (r *CallLogRepository) Create(log *CallLog) error {
+	return r.db.Create(log).Error
+}
+
+// List 按条件列出调用记录，按时间倒序
+func (r *CallLogRepository) List(filter CallLogFilter, limit, offset int) ([]CallLog, error) {
+	var logs []CallLog
+	query := r.applyFilter(filter)
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	err := query.Order("id DESC").Find(&logs).Error
+	return logs, err
+}
+
+// Count 按条件统计调用记录数量
+func (r *CallLogRepository) Count(filter CallLogFilter) (int64, error) {
+	var count int64
+	err := r.applyFilter(filter).Count(&count).Error
+	return count, err
+}
+
+// applyFilter 构建带过滤条件的查询
+func (r *CallLogRepository) applyFilter(filter CallLogFilter) *gorm.DB {
+	query := r.db.Model(&CallLog{})
+
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.FunctionName != "" {
+		query = query.Where("function_name = ?", filter.FunctionName)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	return query
+}
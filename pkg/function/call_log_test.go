@@ -0,0 +1,86 @@
+package function
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/storage/storagetest"
+)
+
+func TestNewCallLog(t *testing.T) {
+	req := ExecuteRequest{
+		FunctionName: "greet",
+		Params:       map[string]string{"name": "alice"},
+	}
+
+	log := NewCallLog("session_1", req, ExecuteResponse{Duration: 15 * time.Millisecond})
+	if log.Status != CallStatusSuccess || log.DurationMs != 15 {
+		t.Errorf("NewCallLog() = %+v, want success with 15ms", log)
+	}
+	if log.Params != `{"name":"alice"}` {
+		t.Errorf("log.Params = %s, want JSON params", log.Params)
+	}
+
+	log = NewCallLog("session_1", req, ExecuteResponse{Error: errors.New("boom")})
+	if log.Status != CallStatusError || log.Error != "boom" {
+		t.Errorf("NewCallLog() = %+v, want error status", log)
+	}
+}
+
+func TestCallLogRepository_ListWithFilter(t *testing.T) {
+	repo := storagetest.NewRepository(t, NewCallLogRepository)
+
+	now := time.Now()
+	logs := []*CallLog{
+		{SessionID: "s1", RequestID: "r1", FunctionName: "greet", Status: CallStatusSuccess, CreatedAt: now.Add(-2 * time.Hour)},
+		{SessionID: "s1", RequestID: "r1", FunctionName: "greet", Status: CallStatusError, Error: "boom", CreatedAt: now.Add(-time.Hour)},
+		{SessionID: "s2", FunctionName: "send_message", Status: CallStatusSuccess, CreatedAt: now},
+	}
+	for _, log := range logs {
+		if err := repo.Create(log); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter CallLogFilter
+		want   int64
+	}{
+		{name: "no filter", filter: CallLogFilter{}, want: 3},
+		{name: "by request id", filter: CallLogFilter{RequestID: "r1"}, want: 2},
+		{name: "by function name", filter: CallLogFilter{FunctionName: "greet"}, want: 2},
+		{name: "by status", filter: CallLogFilter{Status: CallStatusError}, want: 1},
+		{name: "by time range", filter: CallLogFilter{Since: now.Add(-90 * time.Minute), Until: now.Add(-time.Minute)}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := repo.Count(tt.filter)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if count != tt.want {
+				t.Errorf("Count() = %d, want %d", count, tt.want)
+			}
+
+			list, err := repo.List(tt.filter, 10, 0)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if int64(len(list)) != tt.want {
+				t.Errorf("List() returned %d logs, want %d", len(list), tt.want)
+			}
+		})
+	}
+
+	// 分页，按时间倒序
+	page, err := repo.List(CallLogFilter{}, 1, 1)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page) != 1 || page[0].Status != CallStatusError {
+		t.Errorf("List(limit=1, offset=1) = %+v, want the second newest log", page)
+	}
+}
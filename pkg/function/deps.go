@@ -0,0 +1,84 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrDependencyNotFound context 中没有请求的依赖
+var ErrDependencyNotFound = errors.New("dependency not found")
+
+// Dependencies 函数共享依赖（数据库连接、HTTP client、配置等）的容器
+// 按类型存取，同一类型需要多个实例时附加名称区分；执行器执行函数时把容器写入 context
+type Dependencies struct {
+	mu     sync.RWMutex
+	values map[dependencyKey]any
+}
+
+// dependencyKey 依赖的类型和名称
+type dependencyKey struct {
+	typ  reflect.Type
+	name string
+}
+
+// NewDependencies 创建依赖容器
+func NewDependencies() *Dependencies {
+	return &Dependencies{values: make(map[dependencyKey]any)}
+}
+
+// Provide 以类型 T 注册依赖，同类型重复注册时覆盖
+// T 可以是接口类型，如 Provide[Store](deps, &sqlStore{})，函数按接口获取，测试时可替换为 mock
+func Provide[T any](d *Dependencies, value T) {
+	ProvideNamed(d, "", value)
+}
+
+// ProvideNamed 以类型 T 和名称注册依赖，用于同一类型有多个实例的情况（如主库和只读库）
+func ProvideNamed[T any](d *Dependencies, name string, value T) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.values[dependencyKey{typ: reflect.TypeFor[T](), name: name}] = value
+}
+
+// lookup 按类型和名称查找依赖
+func (d *Dependencies) lookup(typ reflect.Type, name string) (any, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	value, ok := d.values[dependencyKey{typ: typ, name: name}]
+	return value, ok
+}
+
+// dependenciesKey context 中依赖容器的 key
+type dependenciesKey struct{}
+
+// WithDependencies 将依赖容器写入 context
+// 框架执行函数时自动写入；单元测试中可直接用它构造 context 调用函数的 Execute
+func WithDependencies(ctx context.Context, d *Dependencies) context.Context {
+	return context.WithValue(ctx, dependenciesKey{}, d)
+}
+
+// Dependency 从 context 获取类型为 T 的依赖，未注册时返回 ErrDependencyNotFound
+func Dependency[T any](ctx context.Context) (T, error) {
+	return NamedDependency[T](ctx, "")
+}
+
+// NamedDependency 从 context 获取类型为 T、名称为 name 的依赖
+func NamedDependency[T any](ctx context.Context, name string) (T, error) {
+	var zero T
+	typ := reflect.TypeFor[T]()
+	d, _ := ctx.Value(dependenciesKey{}).(*Dependencies)
+	if d == nil {
+		return zero, fmt.Errorf("%w: %s (no dependencies in context)", ErrDependencyNotFound, typ)
+	}
+	value, ok := d.lookup(typ, name)
+	if !ok {
+		if name != "" {
+			return zero, fmt.Errorf("%w: %s named %q", ErrDependencyNotFound, typ, name)
+		}
+		return zero, fmt.Errorf("%w: %s", ErrDependencyNotFound, typ)
+	}
+	return value.(T), nil
+}
@@ -0,0 +1,91 @@
+package function
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// greeter 测试用的接口依赖
+type greeter interface {
+	Greet(name string) string
+}
+
+type prefixGreeter struct{ prefix string }
+
+func (g prefixGreeter) Greet(name string) string { return g.prefix + name }
+
+func TestDependency_InjectedByExecutor(t *testing.T) {
+	registry := NewRegistry()
+
+	var got string
+	var gotErr error
+	registry.Register(&MockFunction{
+		name:       "greet",
+		paramsType: reflect.TypeOf(TestParams{}),
+		executeFunc: func(ctx context.Context, params any) (Result, error) {
+			g, err := Dependency[greeter](ctx)
+			if err != nil {
+				gotErr = err
+				return Result{}, err
+			}
+			got = g.Greet(params.(TestParams).Name)
+			return Result{Message: got}, nil
+		},
+	})
+
+	executor := NewExecutor(registry, 5*time.Second)
+	req := ExecuteRequest{FunctionName: "greet", Params: map[string]string{"name": "bob"}}
+
+	// 未设置依赖容器时返回 ErrDependencyNotFound
+	executor.Execute(context.Background(), req)
+	if !errors.Is(gotErr, ErrDependencyNotFound) {
+		t.Errorf("Dependency() without container error = %v, want ErrDependencyNotFound", gotErr)
+	}
+
+	deps := NewDependencies()
+	Provide[greeter](deps, prefixGreeter{prefix: "hello "})
+	executor.SetDependencies(deps)
+
+	resp := executor.Execute(context.Background(), req)
+	if resp.Error != nil {
+		t.Fatalf("Execute() error = %v", resp.Error)
+	}
+	if got != "hello bob" {
+		t.Errorf("Greet() = %q, want %q", got, "hello bob")
+	}
+}
+
+func TestDependency_TypesAndNames(t *testing.T) {
+	deps := NewDependencies()
+	Provide(deps, 42)
+	ProvideNamed(deps, "replica", "db-replica")
+	Provide(deps, "db-primary")
+	ctx := WithDependencies(context.Background(), deps)
+
+	if n, err := Dependency[int](ctx); err != nil || n != 42 {
+		t.Errorf("Dependency[int]() = %v, %v, want 42", n, err)
+	}
+	if s, err := Dependency[string](ctx); err != nil || s != "db-primary" {
+		t.Errorf("Dependency[string]() = %q, %v, want db-primary", s, err)
+	}
+	if s, err := NamedDependency[string](ctx, "replica"); err != nil || s != "db-replica" {
+		t.Errorf("NamedDependency[string](replica) = %q, %v, want db-replica", s, err)
+	}
+	if _, err := NamedDependency[string](ctx, "missing"); !errors.Is(err, ErrDependencyNotFound) {
+		t.Errorf("NamedDependency[string](missing) error = %v, want ErrDependencyNotFound", err)
+	}
+	// 按具体类型注册的值不能按接口获取，反之亦然
+	Provide(deps, prefixGreeter{})
+	if _, err := Dependency[greeter](ctx); !errors.Is(err, ErrDependencyNotFound) {
+		t.Errorf("Dependency[greeter]() error = %v, want ErrDependencyNotFound", err)
+	}
+
+	// 覆盖已注册的依赖
+	Provide(deps, 7)
+	if n, _ := Dependency[int](ctx); n != 7 {
+		t.Errorf("Dependency[int]() after override = %d, want 7", n)
+	}
+}
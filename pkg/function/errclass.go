@@ -0,0 +1,110 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// ErrorClass 函数执行失败的类别，告诉 AI 该如何处理这次失败
+type ErrorClass string
+
+const (
+	ErrorClassInvalidParams ErrorClass = "invalid_params" // 参数错误，修正参数后重新调用
+	ErrorClassTransient     ErrorClass = "transient"      // 临时失败（超时、网络抖动、限流），稍后重试可能成功
+	ErrorClassPermanent     ErrorClass = "permanent"      // 不可恢复，用相同参数重试没有意义
+)
+
+// Hint 返回附带给 AI 的处理建议
+func (c ErrorClass) Hint() string {
+	switch c {
+	case ErrorClassInvalidParams:
+		return "The parameters are invalid. Fix them according to the function's parameter definition and call it again."
+	case ErrorClassTransient:
+		return "This is a temporary failure such as a timeout or network error. You may retry the call once more; if it keeps failing, tell the user the service is temporarily unavailable."
+	default:
+		return "This failure cannot be fixed by retrying. Do not call the function again with the same parameters; explain the problem to the user or try a different approach."
+	}
+}
+
+// ClassifiedError 自带类别的错误
+// 函数实现可以返回该接口的错误（或用 WithErrorClass 包装），明确告知 Agent 失败是否值得重试
+type ClassifiedError interface {
+	error
+	ErrorClass() ErrorClass
+}
+
+// classifiedError WithErrorClass 包装后的错误，错误信息与原错误相同
+type classifiedError struct {
+	err   error
+	class ErrorClass
+}
+
+func (e *classifiedError) Error() string          { return e.err.Error() }
+func (e *classifiedError) Unwrap() error          { return e.err }
+func (e *classifiedError) ErrorClass() ErrorClass { return e.class }
+
+// WithErrorClass 为错误标注类别，err 为 nil 时返回 nil
+func WithErrorClass(err error, class ErrorClass) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{err: err, class: class}
+}
+
+// ClassifyError 判断函数执行失败的类别
+// 优先使用错误自带的类别；超时、连接被拒绝/重置等网络错误视为临时失败；
+// 函数不存在视为参数错误；其余错误（包括权限不足、调用方取消）视为不可恢复
+func ClassifyError(err error) ErrorClass {
+	var classified ClassifiedError
+	if errors.As(err, &classified) {
+		return classified.ErrorClass()
+	}
+
+	switch {
+	case errors.Is(err, ErrFunctionNotFound):
+		return ErrorClassInvalidParams
+	case errors.Is(err, ErrRateLimited),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET):
+		return ErrorClassTransient
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorClassTransient
+	}
+	return ErrorClassPermanent
+}
+
+// IsRetryable 失败后是否值得立即自动重试一次
+// 被限流的调用在同一分钟内重试仍会被拒绝，不自动重试
+func IsRetryable(err error) bool {
+	return err != nil && ClassifyError(err) == ErrorClassTransient && !errors.Is(err, ErrRateLimited)
+}
+
+// IsIdempotent 函数是否通过 IdempotentFunction 声明了幂等
+func IsIdempotent(fn Function) bool {
+	if f, ok := fn.(IdempotentFunction); ok {
+		return f.Idempotent()
+	}
+	return false
+}
+
+// ShouldRetry 函数失败后是否可以自动重试
+// 幂等函数的临时失败都可以重试；非幂等函数在超时或连接中断时可能已经产生了副作用，
+// 只在连接被拒绝（请求确定没有发出）时重试
+func ShouldRetry(fn Function, err error) bool {
+	if !IsRetryable(err) {
+		return false
+	}
+	if fn != nil && IsIdempotent(fn) {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
@@ -0,0 +1,98 @@
+package function
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"explicit class", WithErrorClass(errors.New("bad input"), ErrorClassInvalidParams), ErrorClassInvalidParams},
+		{"wrapped explicit class", fmt.Errorf("outer: %w", WithErrorClass(errors.New("flaky"), ErrorClassTransient)), ErrorClassTransient},
+		{"not found", fmt.Errorf("%w: foo", ErrFunctionNotFound), ErrorClassInvalidParams},
+		{"timeout", fmt.Errorf("function execution timeout: %w", context.DeadlineExceeded), ErrorClassTransient},
+		{"connection refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), ErrorClassTransient},
+		{"rate limited", fmt.Errorf("%w: 5 calls per minute", ErrRateLimited), ErrorClassTransient},
+		{"cancelled", context.Canceled, ErrorClassPermanent},
+		{"permission", ErrPermissionDenied, ErrorClassPermanent},
+		{"plain", errors.New("record not found"), ErrorClassPermanent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if IsRetryable(fmt.Errorf("%w: 5 calls per minute", ErrRateLimited)) {
+		t.Error("rate limited calls should not be retried immediately")
+	}
+	if !IsRetryable(context.DeadlineExceeded) {
+		t.Error("timeouts should be retryable")
+	}
+}
+
+func TestExecutor_InvalidParamsClass(t *testing.T) {
+	registry := NewRegistry()
+	_ = registry.Register(&MockFunction{name: "test", description: "测试", paramsType: reflect.TypeOf(TestParams{})})
+	executor := NewExecutor(registry, 0)
+
+	resp := executor.Execute(context.Background(), ExecuteRequest{
+		FunctionName: "test",
+		Params:       map[string]string{"count": "10"},
+	})
+	if resp.Error == nil {
+		t.Fatal("expected error for missing required param")
+	}
+	if got := ClassifyError(resp.Error); got != ErrorClassInvalidParams {
+		t.Errorf("ClassifyError() = %s, want %s (error: %v)", got, ErrorClassInvalidParams, resp.Error)
+	}
+}
+
+// idempotentFunction 声明了幂等的测试函数
+type idempotentFunction struct {
+	MockFunction
+}
+
+func (f *idempotentFunction) Idempotent() bool { return true }
+
+func TestShouldRetry(t *testing.T) {
+	plain := &MockFunction{name: "send"}
+	idempotent := &idempotentFunction{MockFunction{name: "lookup"}}
+	reset := fmt.Errorf("read: %w", syscall.ECONNRESET)
+	refused := fmt.Errorf("dial: %w", syscall.ECONNREFUSED)
+
+	tests := []struct {
+		name string
+		fn   Function
+		err  error
+		want bool
+	}{
+		{"idempotent timeout", idempotent, context.DeadlineExceeded, true},
+		{"idempotent reset", idempotent, reset, true},
+		{"idempotent permanent", idempotent, errors.New("bad"), false},
+		{"idempotent rate limited", idempotent, ErrRateLimited, false},
+		{"plain timeout", plain, context.DeadlineExceeded, false},
+		{"plain reset", plain, reset, false},
+		{"plain explicit transient", plain, WithErrorClass(errors.New("502 bad gateway"), ErrorClassTransient), false},
+		{"plain refused", plain, refused, true},
+		{"unknown function refused", nil, refused, true},
+		{"no error", idempotent, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldRetry(tt.fn, tt.err); got != tt.want {
+				t.Errorf("ShouldRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,9 +4,15 @@ package function
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
 	"time"
 
+	"github.com/KodaTao/AgentChassis/pkg/llm"
 	"github.com/KodaTao/AgentChassis/pkg/observability"
 )
 
@@ -15,6 +21,15 @@ import (
 type Executor struct {
 	registry *Registry
 	timeout  time.Duration
+	cache    *ResultCache
+	limiter  *RateLimiter
+	tasks    *TaskManager
+	monitor  *ResourceMonitor
+	llmPool  *llm.Pool
+	deps     *Dependencies
+	logger   *slog.Logger // 为 nil 时使用 context 中的 logger 或全局实例
+
+	approvalFunctions []string // 需要管理员审批的函数名，见 SetApprovalFunctions
 }
 
 // NewExecutor 创建函数执行器
@@ -25,6 +40,9 @@ func NewExecutor(registry *Registry, timeout time.Duration) *Executor {
 	return &Executor{
 		registry: registry,
 		timeout:  timeout,
+		cache:    NewResultCache(DefaultCacheSize),
+		limiter:  NewRateLimiter(),
+		tasks:    NewTaskManager(),
 	}
 }
 
@@ -46,9 +64,25 @@ type ExecuteResponse struct {
 // 自动处理参数解析、超时控制和错误捕获
 func (e *Executor) Execute(ctx context.Context, req ExecuteRequest) ExecuteResponse {
 	start := time.Now()
+	if e.llmPool != nil {
+		ctx = llm.WithPool(ctx, e.llmPool)
+	}
+	if e.deps != nil {
+		ctx = WithDependencies(ctx, e.deps)
+	}
+	if e.logger != nil {
+		ctx = observability.WithLogger(ctx, e.logger)
+	}
 
-	// 获取函数
+	// 获取函数，名称写法有偏差（大小写、分隔符）且能唯一匹配时自动纠正
 	fn, ok := e.registry.Get(req.FunctionName)
+	if !ok {
+		if corrected, found := e.registry.Correct(req.FunctionName); found {
+			observability.WarnContext(ctx, "Function name corrected", "requested", req.FunctionName, "corrected", corrected)
+			req.FunctionName = corrected
+			fn, ok = e.registry.Get(corrected)
+		}
+	}
 	if !ok {
 		return ExecuteResponse{
 			Error:    fmt.Errorf("%w: %s", ErrFunctionNotFound, req.FunctionName),
@@ -56,21 +90,75 @@ func (e *Executor) Execute(ctx context.Context, req ExecuteRequest) ExecuteRespo
 		}
 	}
 
-	// 创建带超时的 context
-	execCtx, cancel := context.WithTimeout(ctx, e.timeout)
-	defer cancel()
+	// 校验调用者作用域
+	if err := e.registry.Authorize(ctx, req.FunctionName); err != nil {
+		return ExecuteResponse{
+			Error:    err,
+			Duration: time.Since(start),
+		}
+	}
+
+	// 需要审批的函数只能在批准后执行，MCP 等直接调用 Executor 的入口不能绕过审批
+	if e.RequiresApproval(req.FunctionName) && !isApproved(ctx) {
+		observability.FunctionCallLog(ctx, req.FunctionName, "approval_required", time.Since(start).Milliseconds())
+		return ExecuteResponse{
+			Error:    fmt.Errorf("%w: %s", ErrApprovalRequired, req.FunctionName),
+			Duration: time.Since(start),
+		}
+	}
+
+	// 检查结果缓存（仅对声明了可缓存的函数生效）
+	cacheable, ttl := isCacheable(fn)
+	var key string
+	if cacheable {
+		key = cacheKey(fn.Name(), req.Params, req.Data)
+		if result, ok := e.cache.Get(key); ok {
+			duration := time.Since(start)
+			observability.FunctionCallLog(ctx, req.FunctionName, "cached", duration.Milliseconds())
+			return ExecuteResponse{
+				Result:   result,
+				Duration: duration,
+			}
+		}
+	}
+
+	// 检查调用频率（缓存命中不占用额度）
+	if err := e.limiter.Allow(fn); err != nil {
+		observability.FunctionCallLog(ctx, req.FunctionName, "rate_limited", time.Since(start).Milliseconds())
+		return ExecuteResponse{
+			Error:    err,
+			Duration: time.Since(start),
+		}
+	}
 
 	// 解析参数
 	params, err := e.parseParams(fn, req.Params)
 	if err != nil {
 		return ExecuteResponse{
-			Error:    fmt.Errorf("failed to parse params: %w", err),
+			Error:    WithErrorClass(fmt.Errorf("failed to parse params: %w", err), ErrorClassInvalidParams),
 			Duration: time.Since(start),
 		}
 	}
 
+	// 校验参数
+	if err := ValidateProvidedParams(params, req.Params); err != nil {
+		return ExecuteResponse{
+			Error:    WithErrorClass(fmt.Errorf("invalid params: %w", err), ErrorClassInvalidParams),
+			Duration: time.Since(start),
+		}
+	}
+
+	// 异步函数在后台执行，立即返回任务句柄
+	if isAsync(fn) {
+		return e.startAsync(ctx, fn, params, start)
+	}
+
+	// 创建带超时的 context（优先使用函数自己声明的超时），并写入实际执行的函数名
+	execCtx, cancel := context.WithTimeout(withFunctionName(ctx, fn.Name()), e.timeoutFor(fn))
+	defer cancel()
+
 	// 执行函数（带 panic 恢复）
-	result, execErr := e.executeWithRecover(execCtx, fn, params)
+	result, execErr := e.executeMonitored(execCtx, fn, params)
 	duration := time.Since(start)
 
 	// 记录日志
@@ -80,6 +168,11 @@ func (e *Executor) Execute(ctx context.Context, req ExecuteRequest) ExecuteRespo
 	}
 	observability.FunctionCallLog(ctx, req.FunctionName, status, duration.Milliseconds())
 
+	// 只缓存成功的结果
+	if cacheable && execErr == nil {
+		e.cache.Set(key, result, ttl)
+	}
+
 	return ExecuteResponse{
 		Result:   result,
 		Duration: duration,
@@ -87,6 +180,62 @@ func (e *Executor) Execute(ctx context.Context, req ExecuteRequest) ExecuteRespo
 	}
 }
 
+// startAsync 在后台启动异步函数，返回给 AI 的结果中包含用于查询进度的 task_id
+func (e *Executor) startAsync(ctx context.Context, fn Function, params any, start time.Time) ExecuteResponse {
+	timeout := DefaultAsyncTimeout
+	if t, ok := fn.(TimeoutFunction); ok && t.Timeout() > 0 {
+		timeout = t.Timeout()
+	}
+
+	name := fn.Name()
+	task := e.tasks.Start(withFunctionName(ctx, name), name, timeout, func(taskCtx context.Context) (Result, error) {
+		taskStart := time.Now()
+		result, err := e.executeMonitored(taskCtx, fn, params)
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		observability.FunctionCallLog(taskCtx, name, "async_"+status, time.Since(taskStart).Milliseconds())
+		return result, err
+	})
+
+	duration := time.Since(start)
+	observability.FunctionCallLog(ctx, name, "async_started", duration.Milliseconds())
+	return ExecuteResponse{
+		Result: Result{
+			Message: fmt.Sprintf("Task %s started in the background. Call check_task_status with this task_id to get its progress and result.", task.ID),
+			Data: map[string]any{
+				"task_id": task.ID,
+				"status":  task.Status,
+			},
+		},
+		Duration: duration,
+	}
+}
+
+// timeoutFor 返回函数生效的执行超时
+func (e *Executor) timeoutFor(fn Function) time.Duration {
+	if t, ok := fn.(TimeoutFunction); ok {
+		if timeout := t.Timeout(); timeout > 0 {
+			return timeout
+		}
+	}
+	return e.timeout
+}
+
+// isCacheable 判断函数是否声明了可缓存
+func isCacheable(fn Function) (bool, time.Duration) {
+	c, ok := fn.(CacheableFunction)
+	if !ok {
+		return false, 0
+	}
+	cacheable, ttl := c.Cacheable()
+	if !cacheable || ttl <= 0 {
+		return false, 0
+	}
+	return true, ttl
+}
+
 // parseParams 解析参数
 func (e *Executor) parseParams(fn Function, rawParams map[string]string) (any, error) {
 	paramType := fn.ParamsType()
@@ -114,18 +263,21 @@ func (e *Executor) parseParams(fn Function, rawParams map[string]string) (any, e
 	return paramValue.Interface(), nil
 }
 
-// executeWithRecover 执行函数并恢复 panic
-func (e *Executor) executeWithRecover(ctx context.Context, fn Function, params any) (result Result, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("function panicked: %v", r)
-			observability.Error("Function panicked",
-				"function", fn.Name(),
-				"panic", r,
-			)
-		}
-	}()
+// executeMonitored 执行函数，并在前后采样资源用量，记录到资源画像
+func (e *Executor) executeMonitored(ctx context.Context, fn Function, params any) (Result, error) {
+	monitor := e.monitor
+	if monitor == nil {
+		return e.executeWithRecover(ctx, fn, params)
+	}
+	before := sampleResources()
+	result, err := e.executeWithRecover(ctx, fn, params)
+	monitor.record(ctx, fn.Name(), before, sampleResources())
+	return result, err
+}
 
+// executeWithRecover 执行函数并恢复 panic
+// 函数在独立 goroutine 中执行，recover 必须在该 goroutine 内，否则 panic 会导致整个进程退出
+func (e *Executor) executeWithRecover(ctx context.Context, fn Function, params any) (Result, error) {
 	// 使用 channel 实现超时控制
 	done := make(chan struct{})
 	var execResult Result
@@ -133,6 +285,18 @@ func (e *Executor) executeWithRecover(ctx context.Context, fn Function, params a
 
 	go func() {
 		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				location := panicLocation()
+				execErr = fmt.Errorf("function panicked at %s: %v", location, r)
+				observability.ErrorContext(ctx, "Function panicked",
+					"function", fn.Name(),
+					"panic", r,
+					"location", location,
+					"stack", string(debug.Stack()),
+				)
+			}
+		}()
 		execResult, execErr = fn.Execute(ctx, params)
 	}()
 
@@ -144,6 +308,24 @@ func (e *Executor) executeWithRecover(ctx context.Context, fn Function, params a
 	}
 }
 
+// panicLocation 返回触发 panic 的代码位置（跳过 runtime 内部帧），如 pkg.(*T).Method (file.go:42)
+// 只能在 recover 所在的 defer 函数中直接调用
+func panicLocation() string {
+	pcs := make([]uintptr, 32)
+	// 跳过 runtime.Callers、panicLocation 和 defer 函数本身
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			return fmt.Sprintf("%s (%s:%d)", frame.Function, filepath.Base(frame.File), frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
 // ExecuteAsync 异步执行函数
 // 返回一个 channel，完成后会收到结果
 func (e *Executor) ExecuteAsync(ctx context.Context, req ExecuteRequest) <-chan ExecuteResponse {
@@ -158,7 +340,7 @@ func (e *Executor) ExecuteAsync(ctx context.Context, req ExecuteRequest) <-chan
 	return ch
 }
 
-// SetTimeout 设置超时时间
+// SetTimeout 设置默认超时时间（未实现 TimeoutFunction 的函数使用）
 func (e *Executor) SetTimeout(timeout time.Duration) {
 	e.timeout = timeout
 }
@@ -167,3 +349,53 @@ func (e *Executor) SetTimeout(timeout time.Duration) {
 func (e *Executor) GetTimeout() time.Duration {
 	return e.timeout
 }
+
+// SetCache 替换结果缓存（可用于调整缓存大小）
+func (e *Executor) SetCache(cache *ResultCache) {
+	e.cache = cache
+}
+
+// SetRateLimit 设置函数每分钟最多调用次数，覆盖函数自己声明的限制；perMinute <= 0 时取消配置
+func (e *Executor) SetRateLimit(name string, perMinute int) {
+	e.limiter.SetLimit(name, perMinute)
+}
+
+// SetTaskManager 替换异步任务管理器（可用于与内置的 check_task_status 函数共享）
+func (e *Executor) SetTaskManager(tasks *TaskManager) {
+	e.tasks = tasks
+}
+
+// SetProviderPool 设置函数内部可用的 LLM Provider 池，函数通过 LLMFromContext 获取
+func (e *Executor) SetProviderPool(pool *llm.Pool) {
+	e.llmPool = pool
+}
+
+// SetDependencies 设置函数共享的依赖容器，函数通过 Dependency[T](ctx) 获取
+func (e *Executor) SetDependencies(deps *Dependencies) {
+	e.deps = deps
+}
+
+// SetLogger 设置函数调用日志使用的 logger
+func (e *Executor) SetLogger(logger *slog.Logger) {
+	e.logger = logger
+}
+
+// GetTaskManager 获取异步任务管理器
+func (e *Executor) GetTaskManager() *TaskManager {
+	return e.tasks
+}
+
+// SetResourceMonitor 设置资源监控器，默认不开启，传 nil 关闭资源采样
+func (e *Executor) SetResourceMonitor(monitor *ResourceMonitor) {
+	e.monitor = monitor
+}
+
+// GetResourceMonitor 获取资源监控器，未开启采样时返回 nil
+func (e *Executor) GetResourceMonitor() *ResourceMonitor {
+	return e.monitor
+}
+
+// GetCache 获取结果缓存
+func (e *Executor) GetCache() *ResultCache {
+	return e.cache
+}
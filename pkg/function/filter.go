@@ -0,0 +1,87 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CategorizedFunction 可选接口：声明函数所属分类，如 "scheduler"、"memory"
+type CategorizedFunction interface {
+	// Category 返回分类名称，为空表示未分类
+	Category() string
+}
+
+// TaggedFunction 可选接口：声明函数的标签，用于在函数较多时检索
+type TaggedFunction interface {
+	// Tags 返回标签列表
+	Tags() []string
+}
+
+// categoryOf 返回函数声明的分类
+func categoryOf(fn Function) string {
+	if c, ok := fn.(CategorizedFunction); ok {
+		return c.Category()
+	}
+	return ""
+}
+
+// tagsOf 返回函数声明的标签
+func tagsOf(fn Function) []string {
+	if t, ok := fn.(TaggedFunction); ok {
+		return t.Tags()
+	}
+	return nil
+}
+
+// FunctionFilter 函数列表的过滤条件，零值表示不过滤
+type FunctionFilter struct {
+	// Query 关键词，不区分大小写地匹配名称、别名或描述
+	Query string
+
+	// Category 分类，精确匹配（不区分大小写）
+	Category string
+
+	// Tag 标签，函数的标签中包含该值即匹配（不区分大小写）
+	Tag string
+}
+
+// Match 判断函数信息是否满足过滤条件
+func (f FunctionFilter) Match(info FunctionInfo) bool {
+	if f.Category != "" && !strings.EqualFold(info.Category, f.Category) {
+		return false
+	}
+	if f.Tag != "" && !slices.ContainsFunc(info.Tags, func(tag string) bool {
+		return strings.EqualFold(tag, f.Tag)
+	}) {
+		return false
+	}
+	if q := strings.ToLower(strings.TrimSpace(f.Query)); q != "" {
+		matched := strings.Contains(strings.ToLower(info.Name), q) ||
+			strings.Contains(strings.ToLower(info.Description), q) ||
+			slices.ContainsFunc(info.Aliases, func(alias string) bool {
+				return strings.Contains(strings.ToLower(alias), q)
+			})
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// ListInfoFiltered 列出调用者有权调用且满足过滤条件的 Function 信息，按名称排序以便分页
+func (r *Registry) ListInfoFiltered(ctx context.Context, filter FunctionFilter) []FunctionInfo {
+	all := r.ListInfoForContext(ctx)
+	infos := make([]FunctionInfo, 0, len(all))
+	for _, info := range all {
+		if filter.Match(info) {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
@@ -0,0 +1,83 @@
+package function
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// taggedMockFunction 声明了分类和标签的 Mock 函数
+type taggedMockFunction struct {
+	MockFunction
+	category string
+	tags     []string
+}
+
+func (m *taggedMockFunction) Category() string { return m.category }
+func (m *taggedMockFunction) Tags() []string   { return m.tags }
+
+func TestRegistry_ListInfoFiltered(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(&taggedMockFunction{
+		MockFunction: MockFunction{name: "delay_create", description: "创建延时任务"},
+		category:     "scheduler",
+		tags:         []string{"task", "write"},
+	})
+	registry.Register(&taggedMockFunction{
+		MockFunction: MockFunction{name: "cron_list", description: "列出定时任务"},
+		category:     "scheduler",
+		tags:         []string{"task", "read"},
+	})
+	registry.Register(&taggedMockFunction{
+		MockFunction: MockFunction{name: "recall", description: "Recall user memories"},
+		category:     "memory",
+		tags:         []string{"read"},
+	})
+	registry.Register(&MockFunction{name: "greet", description: "打招呼"})
+	registry.RegisterWithScopes(&MockFunction{name: "admin_tool", description: "admin only"}, "admin")
+	if err := registry.RegisterAlias("remind_later", "delay_create"); err != nil {
+		t.Fatalf("RegisterAlias() error = %v", err)
+	}
+
+	names := func(infos []FunctionInfo) []string {
+		out := make([]string, 0, len(infos))
+		for _, info := range infos {
+			out = append(out, info.Name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		filter FunctionFilter
+		want   []string
+	}{
+		{name: "no filter sorted by name", ctx: context.Background(), want: []string{"admin_tool", "cron_list", "delay_create", "greet", "recall"}},
+		{name: "query matches name", ctx: context.Background(), filter: FunctionFilter{Query: "CRON"}, want: []string{"cron_list"}},
+		{name: "query matches description", ctx: context.Background(), filter: FunctionFilter{Query: "任务"}, want: []string{"cron_list", "delay_create"}},
+		{name: "query matches alias", ctx: context.Background(), filter: FunctionFilter{Query: "remind"}, want: []string{"delay_create"}},
+		{name: "category", ctx: context.Background(), filter: FunctionFilter{Category: "Scheduler"}, want: []string{"cron_list", "delay_create"}},
+		{name: "tag", ctx: context.Background(), filter: FunctionFilter{Tag: "read"}, want: []string{"cron_list", "recall"}},
+		{name: "category and tag", ctx: context.Background(), filter: FunctionFilter{Category: "scheduler", Tag: "write"}, want: []string{"delay_create"}},
+		{name: "no match", ctx: context.Background(), filter: FunctionFilter{Query: "nothing"}, want: []string{}},
+		{name: "respects scopes", ctx: WithCallerScopes(context.Background(), nil), filter: FunctionFilter{Query: "admin"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(registry.ListInfoFiltered(tt.ctx, tt.filter))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListInfoFiltered() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	infos := registry.ListInfoFiltered(context.Background(), FunctionFilter{Category: "memory"})
+	if len(infos) != 1 {
+		t.Fatalf("ListInfoFiltered(memory) = %v", names(infos))
+	}
+	if info := infos[0]; info.Category != "memory" || !reflect.DeepEqual(info.Tags, []string{"read"}) {
+		t.Errorf("GetInfo() category = %q, tags = %v", info.Category, info.Tags)
+	}
+}
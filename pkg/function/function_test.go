@@ -2,7 +2,9 @@ package function
 
 import (
 	"context"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -90,6 +92,53 @@ func TestRegistry_Get(t *testing.T) {
 	}
 }
 
+func TestRegistry_RegisterAlias(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(&MockFunction{name: "send_message"})
+	registry.Register(&MockFunction{name: "greet"})
+
+	if err := registry.RegisterAlias("notify", "send_message"); err != nil {
+		t.Fatalf("RegisterAlias() error = %v", err)
+	}
+	// 别名可以指向另一个别名
+	if err := registry.RegisterAlias("alert", "notify"); err != nil {
+		t.Fatalf("RegisterAlias() chained error = %v", err)
+	}
+
+	got, ok := registry.Get("alert")
+	if !ok || got.Name() != "send_message" {
+		t.Errorf("Get(alert) should resolve to send_message, got %v, %v", got, ok)
+	}
+	if !registry.Has("notify") {
+		t.Error("Has(notify) should be true")
+	}
+
+	// 别名不能覆盖真实函数
+	if err := registry.RegisterAlias("greet", "send_message"); !errors.Is(err, ErrAliasConflict) {
+		t.Errorf("alias shadowing a function should fail with ErrAliasConflict, got %v", err)
+	}
+	// 别名不能成环
+	if err := registry.RegisterAlias("notify", "alert"); !errors.Is(err, ErrAliasCycle) {
+		t.Errorf("alias cycle should fail with ErrAliasCycle, got %v", err)
+	}
+	// 目标必须存在
+	if err := registry.RegisterAlias("ping", "not_exist"); !errors.Is(err, ErrFunctionNotFound) {
+		t.Errorf("alias to missing function should fail with ErrFunctionNotFound, got %v", err)
+	}
+
+	for _, info := range registry.ListInfo() {
+		if info.Name == "send_message" && !reflect.DeepEqual(info.Aliases, []string{"alert", "notify"}) {
+			t.Errorf("send_message aliases = %v, want [alert notify]", info.Aliases)
+		}
+	}
+
+	// 作用域按目标函数校验
+	registry.SetScopes("send_message", "notify:send")
+	if err := registry.Authorize(WithCallerScopes(context.Background(), nil), "notify"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Authorize(alias) should check the target's scopes, got %v", err)
+	}
+}
+
 func TestRegistry_List(t *testing.T) {
 	registry := NewRegistry()
 
@@ -152,6 +201,34 @@ func TestRegistry_Unregister(t *testing.T) {
 	}
 }
 
+func TestRegistry_Version(t *testing.T) {
+	registry := NewRegistry()
+	v0 := registry.Version()
+
+	registry.Register(&MockFunction{name: "versioned"})
+	v1 := registry.Version()
+	if v1 <= v0 {
+		t.Errorf("Version() after Register = %d, want > %d", v1, v0)
+	}
+
+	// 读取操作不改变版本
+	registry.ListInfo()
+	if registry.Version() != v1 {
+		t.Error("Version() should not change on read")
+	}
+
+	// 注销不存在的函数不改变版本
+	registry.Unregister("not_exist")
+	if registry.Version() != v1 {
+		t.Error("Version() should not change when nothing is removed")
+	}
+
+	registry.Unregister("versioned")
+	if registry.Version() <= v1 {
+		t.Error("Version() should increase after Unregister")
+	}
+}
+
 func TestExtractParamInfo(t *testing.T) {
 	fn := &MockFunction{
 		name:       "param_test",
@@ -242,6 +319,94 @@ func TestParseParams_Default(t *testing.T) {
 	}
 }
 
+// NestedParams 带嵌套对象的测试参数
+type NestedParams struct {
+	Name    string     `json:"name" desc:"用户名" required:"true"`
+	Address Address    `json:"address" desc:"地址"`
+	Contact *Contact   `json:"contact"`
+	Tags    []TreeNode `json:"tags"`
+	Created time.Time  `json:"created"`
+}
+
+type Address struct {
+	City   string `json:"city" desc:"城市" required:"true"`
+	Street string `json:"street"`
+}
+
+type Contact struct {
+	Phone string `json:"phone"`
+}
+
+// TreeNode 自引用结构体，用于验证不会无限展开
+type TreeNode struct {
+	Label    string     `json:"label"`
+	Children []TreeNode `json:"children"`
+}
+
+func TestExtractParamInfo_Nested(t *testing.T) {
+	fn := &MockFunction{
+		name:       "nested_test",
+		paramsType: reflect.TypeOf(NestedParams{}),
+	}
+
+	params := ExtractParamInfo(fn)
+
+	address := findParam(params, "address")
+	if address == nil {
+		t.Fatal("Should have 'address' parameter")
+	}
+	if address.Type != "object" {
+		t.Errorf("address.Type = %s, want object", address.Type)
+	}
+	if len(address.Fields) != 2 {
+		t.Fatalf("address.Fields has %d fields, want 2", len(address.Fields))
+	}
+	city := findParam(address.Fields, "city")
+	if city == nil || !city.Required || city.Description != "城市" {
+		t.Errorf("address.city = %+v, want required with description", city)
+	}
+
+	contact := findParam(params, "contact")
+	if contact == nil || len(contact.Fields) != 1 {
+		t.Errorf("contact should expand pointer struct fields, got %+v", contact)
+	}
+
+	// 自引用结构体只展开一层
+	tags := findParam(params, "tags")
+	if tags == nil || len(tags.Fields) != 2 {
+		t.Fatalf("tags should expand slice element fields, got %+v", tags)
+	}
+	if children := findParam(tags.Fields, "children"); children == nil || len(children.Fields) != 0 {
+		t.Errorf("tags.children should not recurse, got %+v", children)
+	}
+
+	// time.Time 不展开
+	if created := findParam(params, "created"); created == nil || len(created.Fields) != 0 {
+		t.Errorf("created should not expand time.Time fields, got %+v", created)
+	}
+}
+
+func TestParseParams_Nested(t *testing.T) {
+	rawParams := map[string]string{
+		"name":           "alice",
+		"address.city":   "Beijing",
+		"address.street": "Chang'an Ave",
+		"contact.phone":  "123456",
+	}
+
+	var params NestedParams
+	if err := ParseParams(rawParams, &params); err != nil {
+		t.Fatalf("ParseParams() error = %v", err)
+	}
+
+	if params.Address.City != "Beijing" || params.Address.Street != "Chang'an Ave" {
+		t.Errorf("params.Address = %+v", params.Address)
+	}
+	if params.Contact == nil || params.Contact.Phone != "123456" {
+		t.Errorf("params.Contact = %+v, want phone 123456", params.Contact)
+	}
+}
+
 func TestExecutor_Execute(t *testing.T) {
 	registry := NewRegistry()
 
@@ -305,6 +470,86 @@ func TestExecutor_Timeout(t *testing.T) {
 	}
 }
 
+// TimeoutMockFunction 声明了自己超时的 Mock 函数
+type TimeoutMockFunction struct {
+	MockFunction
+	timeout time.Duration
+}
+
+func (m *TimeoutMockFunction) Timeout() time.Duration { return m.timeout }
+
+func TestExecutor_FunctionTimeout(t *testing.T) {
+	registry := NewRegistry()
+
+	sleep := func(d time.Duration) func(ctx context.Context, params any) (Result, error) {
+		return func(ctx context.Context, params any) (Result, error) {
+			select {
+			case <-time.After(d):
+				return Result{Message: "done"}, nil
+			case <-ctx.Done():
+				return Result{}, ctx.Err()
+			}
+		}
+	}
+
+	// 快函数声明更短的超时，应在默认超时之前失败
+	registry.Register(&TimeoutMockFunction{
+		MockFunction: MockFunction{name: "fast_func", executeFunc: sleep(time.Second)},
+		timeout:      50 * time.Millisecond,
+	})
+	// 慢函数声明更长的超时，不应被默认超时误杀
+	registry.Register(&TimeoutMockFunction{
+		MockFunction: MockFunction{name: "slow_func", executeFunc: sleep(200 * time.Millisecond)},
+		timeout:      time.Second,
+	})
+	// 返回 0 时回退到默认超时
+	registry.Register(&TimeoutMockFunction{
+		MockFunction: MockFunction{name: "default_func", executeFunc: sleep(200 * time.Millisecond)},
+	})
+
+	executor := NewExecutor(registry, 100*time.Millisecond)
+
+	start := time.Now()
+	resp := executor.Execute(context.Background(), ExecuteRequest{FunctionName: "fast_func"})
+	if resp.Error == nil {
+		t.Error("fast_func should time out")
+	}
+	if elapsed := time.Since(start); elapsed > 90*time.Millisecond {
+		t.Errorf("fast_func should use its own timeout, took %v", elapsed)
+	}
+
+	resp = executor.Execute(context.Background(), ExecuteRequest{FunctionName: "slow_func"})
+	if resp.Error != nil {
+		t.Errorf("slow_func should not time out, got %v", resp.Error)
+	}
+
+	resp = executor.Execute(context.Background(), ExecuteRequest{FunctionName: "default_func"})
+	if resp.Error == nil {
+		t.Error("default_func should use the executor default timeout")
+	}
+}
+
+func TestExecutor_Panic(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(&MockFunction{
+		name: "panic_func",
+		executeFunc: func(ctx context.Context, params any) (Result, error) {
+			var m map[string]int
+			m["boom"] = 1 // nil map 写入触发 panic
+			return Result{}, nil
+		},
+	})
+
+	executor := NewExecutor(registry, time.Second)
+	resp := executor.Execute(context.Background(), ExecuteRequest{FunctionName: "panic_func"})
+	if resp.Error == nil {
+		t.Fatal("Execute() should return an error when the function panics")
+	}
+	if !strings.Contains(resp.Error.Error(), "function_test.go:") {
+		t.Errorf("panic error should include the location, got %q", resp.Error)
+	}
+}
+
 func TestExecutor_NotFound(t *testing.T) {
 	registry := NewRegistry()
 	executor := NewExecutor(registry, 5*time.Second)
@@ -337,3 +582,128 @@ func TestToSnakeCase(t *testing.T) {
 		}
 	}
 }
+
+// CacheableMockFunction 可缓存的 Mock 函数
+type CacheableMockFunction struct {
+	MockFunction
+	ttl time.Duration
+}
+
+func (m *CacheableMockFunction) Cacheable() (bool, time.Duration) { return true, m.ttl }
+
+func TestExecutor_Cache(t *testing.T) {
+	registry := NewRegistry()
+
+	calls := 0
+	fn := &CacheableMockFunction{
+		MockFunction: MockFunction{
+			name:       "cached_func",
+			paramsType: reflect.TypeOf(TestParams{}),
+			executeFunc: func(ctx context.Context, params any) (Result, error) {
+				calls++
+				return Result{Message: "Hello " + params.(TestParams).Name}, nil
+			},
+		},
+		ttl: time.Minute,
+	}
+	registry.Register(fn)
+
+	executor := NewExecutor(registry, 5*time.Second)
+
+	req := ExecuteRequest{
+		FunctionName: "cached_func",
+		Params:       map[string]string{"name": "World"},
+	}
+	executor.Execute(context.Background(), req)
+	resp := executor.Execute(context.Background(), req)
+
+	if resp.Error != nil {
+		t.Fatalf("Execute() error = %v", resp.Error)
+	}
+	if resp.Result.Message != "Hello World" {
+		t.Errorf("Result.Message = %s, want 'Hello World'", resp.Result.Message)
+	}
+	if calls != 1 {
+		t.Errorf("Execute called %d times, want 1 (second call should hit cache)", calls)
+	}
+
+	// 不同参数不应命中缓存
+	executor.Execute(context.Background(), ExecuteRequest{
+		FunctionName: "cached_func",
+		Params:       map[string]string{"name": "Go"},
+	})
+	if calls != 2 {
+		t.Errorf("Execute called %d times, want 2", calls)
+	}
+}
+
+func TestResultCache_LRU(t *testing.T) {
+	cache := NewResultCache(2)
+
+	cache.Set("a", Result{Message: "a"}, time.Minute)
+	cache.Set("b", Result{Message: "b"}, time.Minute)
+	cache.Get("a") // a 变为最近使用
+	cache.Set("c", Result{Message: "c"}, time.Minute)
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("b should be evicted")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Error("a should still be cached")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", cache.Len())
+	}
+
+	// 过期条目
+	cache.Set("d", Result{Message: "d"}, -time.Second)
+	if _, ok := cache.Get("d"); ok {
+		t.Error("expired entry should not be returned")
+	}
+}
+
+func TestParseParams_FieldErrors(t *testing.T) {
+	type params struct {
+		Count   int     `json:"count"`
+		Small   int8    `json:"small"`
+		Size    uint    `json:"size"`
+		Ratio   float64 `json:"ratio"`
+		Enabled bool    `json:"enabled"`
+		Address struct {
+			Zip int `json:"zip"`
+		} `json:"address"`
+	}
+
+	tests := []struct {
+		name    string
+		raw     map[string]string
+		wantErr string
+	}{
+		{name: "integer", raw: map[string]string{"count": "abc"}, wantErr: "field 'count' expects integer, got 'abc'"},
+		{name: "integer out of range", raw: map[string]string{"small": "300"}, wantErr: "field 'small' expects integer between -128 and 127, got '300'"},
+		{name: "negative unsigned", raw: map[string]string{"size": "-1"}, wantErr: "field 'size' expects non-negative integer, got '-1'"},
+		{name: "number", raw: map[string]string{"ratio": "half"}, wantErr: "field 'ratio' expects number, got 'half'"},
+		{name: "boolean", raw: map[string]string{"enabled": "maybe"}, wantErr: "field 'enabled' expects boolean (true or false), got 'maybe'"},
+		{name: "nested", raw: map[string]string{"address.zip": "x1"}, wantErr: "field 'address.zip' expects integer, got 'x1'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p params
+			err := ParseParams(tt.raw, &p)
+			var paramErr *ParamError
+			if !errors.As(err, &paramErr) || err.Error() != tt.wantErr {
+				t.Errorf("ParseParams() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 合法的写法
+	var p params
+	err := ParseParams(map[string]string{"count": " 3.0 ", "size": "7", "ratio": "-0.5", "enabled": "No", "small": ""}, &p)
+	if err != nil {
+		t.Fatalf("ParseParams() error = %v", err)
+	}
+	if p.Count != 3 || p.Size != 7 || p.Ratio != -0.5 || p.Enabled || p.Small != 0 {
+		t.Errorf("ParseParams() = %+v", p)
+	}
+}
@@ -0,0 +1,132 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MaxSuggestions 函数名不存在时最多给出的建议数
+const MaxSuggestions = 3
+
+// Correct 尝试纠正写法有偏差的函数名，如 sendMessage、send-message、Send_Message -> send_message
+// 忽略大小写和分隔符后与恰好一个函数（或别名）匹配时返回其真实函数名；
+// name 本身存在、无匹配或匹配不唯一时返回 false
+func (r *Registry) Correct(name string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.functions[r.resolve(name)]; ok {
+		return "", false
+	}
+
+	key := nameKey(name)
+	if key == "" {
+		return "", false
+	}
+	matched := ""
+	for _, candidate := range r.namesLocked() {
+		if nameKey(candidate) != key {
+			continue
+		}
+		target := r.resolve(candidate)
+		if matched != "" && matched != target {
+			return "", false
+		}
+		matched = target
+	}
+	if _, ok := r.functions[matched]; !ok {
+		return "", false
+	}
+	return matched, true
+}
+
+// namesLocked 返回所有函数名和别名，调用方需持有读锁
+func (r *Registry) namesLocked() []string {
+	names := make([]string, 0, len(r.functions)+len(r.aliases))
+	for name := range r.functions {
+		names = append(names, name)
+	}
+	for alias := range r.aliases {
+		names = append(names, alias)
+	}
+	return names
+}
+
+// SuggestNames 从 candidates 中找出与 name 最接近的名称，按相似度排序，最多 MaxSuggestions 个
+// 忽略大小写和分隔符后按编辑距离比较，距离过大的候选不会给出
+func SuggestNames(name string, candidates []string) []string {
+	key := nameKey(name)
+	if key == "" {
+		return nil
+	}
+
+	type scored struct {
+		name     string
+		distance int
+	}
+	// 允许的编辑距离随名称长度增加，短名称只容忍 1 个字符的偏差
+	limit := len(key)/4 + 1
+	if limit > 3 {
+		limit = 3
+	}
+
+	var matches []scored
+	for _, candidate := range candidates {
+		candidateKey := nameKey(candidate)
+		d := editDistance(key, candidateKey)
+		// 编辑距离足够小，或者是某个函数名的一部分（如 weather -> get_weather）
+		if d <= limit || (len(key) >= 4 && strings.Contains(candidateKey, key)) {
+			matches = append(matches, scored{candidate, d})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+	if len(matches) > MaxSuggestions {
+		matches = matches[:MaxSuggestions]
+	}
+
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = m.name
+	}
+	return names
+}
+
+// nameKey 名称的比较键：去掉分隔符并转为小写，只保留字母和数字
+func nameKey(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+// editDistance 计算两个字符串的 Levenshtein 编辑距离
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,73 @@
+package function
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRegistry_Correct(t *testing.T) {
+	registry := NewRegistry()
+	_ = registry.Register(&MockFunction{name: "send_message", paramsType: reflect.TypeOf(TestParams{})})
+	_ = registry.Register(&MockFunction{name: "get_weather", paramsType: reflect.TypeOf(TestParams{})})
+	_ = registry.RegisterAlias("weather_now", "get_weather")
+
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"sendMessage", "send_message", true},
+		{"send-message", "send_message", true},
+		{"SEND_MESSAGE", "send_message", true},
+		{"WeatherNow", "get_weather", true},
+		{"send_message", "", false}, // 本身存在，无需纠正
+		{"send_msg", "", false},     // 只有编辑距离接近，不自动纠正
+	}
+	for _, tt := range tests {
+		got, ok := registry.Correct(tt.name)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Correct(%q) = %q, %v; want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// 规范化后匹配多个函数时不纠正
+	_ = registry.Register(&MockFunction{name: "sendmessage", paramsType: reflect.TypeOf(TestParams{})})
+	if got, ok := registry.Correct("Send-Message"); ok {
+		t.Errorf("Correct() with ambiguous matches = %q, want no correction", got)
+	}
+}
+
+func TestSuggestNames(t *testing.T) {
+	candidates := []string{"send_message", "delay_create", "delay_cancel", "get_weather"}
+
+	if got := SuggestNames("send_mesage", candidates); !reflect.DeepEqual(got, []string{"send_message"}) {
+		t.Errorf("SuggestNames(send_mesage) = %v", got)
+	}
+	if got := SuggestNames("delay_creat", candidates); len(got) == 0 || got[0] != "delay_create" {
+		t.Errorf("SuggestNames(delay_creat) = %v, want delay_create first", got)
+	}
+	if got := SuggestNames("weather", candidates); !reflect.DeepEqual(got, []string{"get_weather"}) {
+		t.Errorf("SuggestNames(weather) = %v", got)
+	}
+	if got := SuggestNames("calculate", candidates); len(got) != 0 {
+		t.Errorf("SuggestNames(calculate) = %v, want none", got)
+	}
+}
+
+func TestExecutor_CorrectsFunctionName(t *testing.T) {
+	registry := NewRegistry()
+	_ = registry.Register(&MockFunction{name: "test_func", paramsType: reflect.TypeOf(TestParams{})})
+	executor := NewExecutor(registry, 0)
+
+	resp := executor.Execute(context.Background(), ExecuteRequest{
+		FunctionName: "testFunc",
+		Params:       map[string]string{"name": "x"},
+	})
+	if resp.Error != nil {
+		t.Fatalf("Execute() error = %v", resp.Error)
+	}
+	if resp.Result.Message != "executed" {
+		t.Errorf("Result.Message = %q", resp.Result.Message)
+	}
+}
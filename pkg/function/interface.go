@@ -4,6 +4,7 @@ package function
 import (
 	"context"
 	"reflect"
+	"time"
 )
 
 // Function 是所有可调用函数的基础接口
@@ -28,6 +29,43 @@ type Function interface {
 	ParamsType() reflect.Type
 }
 
+// CacheableFunction 可选接口：声明函数结果可缓存
+// 适用于幂等的只读函数（如查汇率、查时区），Executor 会按函数名+参数缓存结果
+type CacheableFunction interface {
+	// Cacheable 返回是否可缓存以及缓存有效期
+	Cacheable() (bool, time.Duration)
+}
+
+// IdempotentFunction 可选接口：声明函数是幂等的，重复执行不会产生额外的副作用
+// 只有声明了幂等的函数在超时、连接重置、上游 5xx 等临时失败后才会被自动重试；
+// 其余函数（如发送消息、创建任务、POST webhook）只在连接被拒绝（请求未发出）时重试
+type IdempotentFunction interface {
+	// Idempotent 返回是否幂等
+	Idempotent() bool
+}
+
+// TimeoutFunction 可选接口：声明函数自己的执行超时
+// Executor 优先使用该超时，返回值 <= 0 时回退到 Executor 的默认超时
+// 适用于明显快于或慢于默认值的函数（如 5 秒的 HTTP 查询、10 分钟的大文件处理）
+type TimeoutFunction interface {
+	// Timeout 返回函数的执行超时
+	Timeout() time.Duration
+}
+
+// ApprovalFunction 可选接口：声明函数属于高危操作，AI 调用后需管理员审批才执行
+// 适用于删除数据、转账、生产变更等不能只靠 AI 或终端用户确认的操作
+type ApprovalFunction interface {
+	// RequiresApproval 返回是否需要审批
+	RequiresApproval() bool
+}
+
+// OutputFunction 可选接口：声明函数结果中 Data 的类型
+// 用于导出输出的 JSON Schema，方便外部系统（如 MCP 客户端）了解返回结构
+type OutputFunction interface {
+	// OutputType 返回 Result.Data 的反射类型，返回 nil 表示不声明
+	OutputType() reflect.Type
+}
+
 // Result 函数执行结果
 type Result struct {
 	// Data 结构化数据，将被编码为 TOON 格式
@@ -41,6 +79,21 @@ type Result struct {
 	// Message 简短的文本消息
 	// 用于向 AI 简要说明执行结果
 	Message string `json:"message,omitempty"`
+
+	// AutoMarkdown 为 true 且 Markdown 为空时，由框架根据 Data 自动生成 Markdown 表格
+	// 生成的表格只用于给终端用户展示，发给 AI 的仍是 TOON
+	AutoMarkdown bool `json:"-"`
+
+	// Attachments 函数生成的文件（如图表、PDF 报表），直接交给终端用户
+	// 发给 AI 的结果中只包含文件名、类型和大小，不包含文件内容
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment 函数返回的文件
+type Attachment struct {
+	Filename string `json:"filename"`
+	MimeType string `json:"mime_type"`
+	Content  []byte `json:"content"`
 }
 
 // FunctionInfo 函数元信息，用于 API 返回和 Prompt 生成
@@ -48,6 +101,10 @@ type FunctionInfo struct {
 	Name        string       `json:"name"`
 	Description string       `json:"description"`
 	Parameters  []ParamInfo  `json:"parameters,omitempty"`
+	Scopes      []string     `json:"scopes,omitempty"` // 调用所需的作用域
+	Aliases     []string     `json:"aliases,omitempty"` // 指向该函数的别名
+	Category    string       `json:"category,omitempty"` // 所属分类
+	Tags        []string     `json:"tags,omitempty"`     // 标签
 }
 
 // ParamInfo 参数元信息
@@ -57,4 +114,13 @@ type ParamInfo struct {
 	Description string `json:"description,omitempty"`
 	Required    bool   `json:"required"`
 	Default     string `json:"default,omitempty"`
+
+	// Validation 校验规则摘要，如 "min=1, max=65535"
+	Validation string `json:"validation,omitempty"`
+
+	// Enum 允许的取值，来自 enum tag，如 enum:"daily,weekly,monthly"
+	Enum []string `json:"enum,omitempty"`
+
+	// Fields 嵌套对象的子字段，仅当 Type 为 object 或 array[object] 时有值
+	Fields []ParamInfo `json:"fields,omitempty"`
 }
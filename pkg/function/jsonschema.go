@@ -0,0 +1,209 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FunctionSchema 函数的 JSON Schema 描述
+// Parameters 可直接用作 OpenAI function calling 的 parameters 或 MCP tool 的 inputSchema
+type FunctionSchema struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+	Output      map[string]any `json:"output,omitempty"` // 仅实现了 OutputFunction 的函数才有
+}
+
+// ExportJSONSchema 导出所有 Function 的 JSON Schema，按函数名排序
+func (r *Registry) ExportJSONSchema() []FunctionSchema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schemas := make([]FunctionSchema, 0, len(r.functions))
+	for _, fn := range r.functions {
+		schemas = append(schemas, FunctionJSONSchema(fn))
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Name < schemas[j].Name
+	})
+	return schemas
+}
+
+// ExportJSONSchemaForContext 导出 context 中的调用者有权调用的 Function 的 JSON Schema
+func (r *Registry) ExportJSONSchemaForContext(ctx context.Context) []FunctionSchema {
+	all := r.ExportJSONSchema()
+	schemas := make([]FunctionSchema, 0, len(all))
+	for _, schema := range all {
+		if r.Authorize(ctx, schema.Name) == nil {
+			schemas = append(schemas, schema)
+		}
+	}
+	return schemas
+}
+
+// FunctionJSONSchema 生成单个 Function 的 JSON Schema
+func FunctionJSONSchema(fn Function) FunctionSchema {
+	schema := FunctionSchema{
+		Name:        fn.Name(),
+		Description: fn.Description(),
+		Parameters:  ParamsJSONSchema(ExtractParamInfo(fn)),
+	}
+	if out, ok := fn.(OutputFunction); ok {
+		schema.Output = TypeJSONSchema(out.OutputType())
+	}
+	return schema
+}
+
+// TypeJSONSchema 将任意 Go 类型转换为 JSON Schema，字段规则与参数结构体一致
+// 返回 nil 表示 t 为 nil
+func TypeJSONSchema(t reflect.Type) map[string]any {
+	if t == nil {
+		return nil
+	}
+	var fields []ParamInfo
+	if nested := nestedStructType(t); nested != nil {
+		fields = extractStructParams(nested, map[reflect.Type]bool{})
+	}
+	return typeJSONSchema(getTypeName(t), fields)
+}
+
+// ParamsJSONSchema 将参数信息转换为 JSON Schema（object 类型）
+// 用于向支持原生 function calling 的模型描述函数参数
+func ParamsJSONSchema(params []ParamInfo) map[string]any {
+	properties := make(map[string]any, len(params))
+	required := []string{}
+
+	for _, p := range params {
+		properties[p.Name] = paramJSONSchema(p)
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// paramJSONSchema 单个参数的 JSON Schema
+func paramJSONSchema(p ParamInfo) map[string]any {
+	schema := typeJSONSchema(p.Type, p.Fields)
+	if len(p.Enum) > 0 {
+		// 数组的枚举约束作用于元素
+		target := schema
+		if items, ok := schema["items"].(map[string]any); ok {
+			target = items
+		}
+		target["enum"] = enumJSONValues(target["type"], p.Enum)
+	}
+
+	// 默认值和校验规则以文字形式附在描述中，避免类型不匹配
+	desc := p.Description
+	if p.Default != "" {
+		desc = strings.TrimSpace(desc + " (default: " + p.Default + ")")
+	}
+	if p.Validation != "" {
+		desc = strings.TrimSpace(desc + " [" + p.Validation + "]")
+	}
+	if desc != "" {
+		schema["description"] = desc
+	}
+	return schema
+}
+
+// enumJSONValues 按 JSON Schema 类型转换枚举值，数值类型无法解析时保留原字符串
+func enumJSONValues(typ any, enum []string) []any {
+	values := make([]any, len(enum))
+	for i, value := range enum {
+		values[i] = value
+		switch typ {
+		case "integer":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				values[i] = n
+			}
+		case "number":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				values[i] = f
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(value); err == nil {
+				values[i] = b
+			}
+		}
+	}
+	return values
+}
+
+// typeJSONSchema 将参数类型名转换为 JSON Schema 类型
+func typeJSONSchema(typeName string, fields []ParamInfo) map[string]any {
+	switch {
+	case typeName == "string", typeName == "integer", typeName == "number", typeName == "boolean":
+		return map[string]any{"type": typeName}
+	case typeName == "object":
+		return ParamsJSONSchema(fields)
+	case strings.HasPrefix(typeName, "array[") && strings.HasSuffix(typeName, "]"):
+		elem := typeName[len("array[") : len(typeName)-1]
+		return map[string]any{
+			"type":  "array",
+			"items": typeJSONSchema(elem, fields),
+		}
+	case strings.HasPrefix(typeName, "map["):
+		return map[string]any{"type": "object"}
+	default:
+		// time.Time 等其他类型按字符串传递
+		return map[string]any{"type": "string"}
+	}
+}
+
+// ParamsFromJSON 将原生 function calling 的 JSON 参数转换为 Executor 使用的字符串参数
+// 嵌套对象展开为 "parent.child" 形式的参数名，数组保留为 JSON 字符串
+func ParamsFromJSON(arguments string) (map[string]string, error) {
+	params := make(map[string]string)
+	if strings.TrimSpace(arguments) == "" {
+		return params, nil
+	}
+
+	var args map[string]any
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments JSON: %w", err)
+	}
+	flattenArguments("", args, params)
+	return params, nil
+}
+
+// flattenArguments 递归展开参数
+func flattenArguments(prefix string, args map[string]any, params map[string]string) {
+	for key, value := range args {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+
+		switch v := value.(type) {
+		case nil:
+			continue
+		case string:
+			params[name] = v
+		case bool:
+			params[name] = strconv.FormatBool(v)
+		case float64:
+			params[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case map[string]any:
+			flattenArguments(name, v, params)
+		default:
+			encoded, _ := json.Marshal(v)
+			params[name] = string(encoded)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package function
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParamsJSONSchema(t *testing.T) {
+	fn := &MockFunction{name: "nested", paramsType: reflect.TypeOf(NestedParams{})}
+	schema := ParamsJSONSchema(ExtractParamInfo(fn))
+
+	if schema["type"] != "object" {
+		t.Fatalf("schema type = %v, want object", schema["type"])
+	}
+	if required, _ := schema["required"].([]string); len(required) != 1 || required[0] != "name" {
+		t.Errorf("required = %v, want [name]", schema["required"])
+	}
+
+	props := schema["properties"].(map[string]any)
+	name := props["name"].(map[string]any)
+	if name["type"] != "string" || name["description"] != "用户名" {
+		t.Errorf("name schema = %v", name)
+	}
+
+	// 嵌套对象展开为子 schema
+	address := props["address"].(map[string]any)
+	if address["type"] != "object" {
+		t.Errorf("address type = %v, want object", address["type"])
+	}
+	city := address["properties"].(map[string]any)["city"].(map[string]any)
+	if city["type"] != "string" {
+		t.Errorf("address.city schema = %v", city)
+	}
+
+	// 结构体数组
+	tags := props["tags"].(map[string]any)
+	if tags["type"] != "array" || tags["items"].(map[string]any)["type"] != "object" {
+		t.Errorf("tags schema = %v", tags)
+	}
+
+	// time.Time 按字符串处理
+	if props["created"].(map[string]any)["type"] != "string" {
+		t.Errorf("created schema = %v", props["created"])
+	}
+}
+
+func TestParamsFromJSON(t *testing.T) {
+	params, err := ParamsFromJSON(`{"name":"Tom","count":3,"ratio":0.5,"enabled":true,"address":{"city":"Beijing"},"ids":[1,2],"empty":null}`)
+	if err != nil {
+		t.Fatalf("ParamsFromJSON() error = %v", err)
+	}
+
+	want := map[string]string{
+		"name":         "Tom",
+		"count":        "3",
+		"ratio":        "0.5",
+		"enabled":      "true",
+		"address.city": "Beijing",
+		"ids":          "[1,2]",
+	}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("ParamsFromJSON() = %v, want %v", params, want)
+	}
+
+	// 展开后的参数可以直接用于 ParseParams
+	var target NestedParams
+	if err := ParseParams(params, &target); err != nil {
+		t.Fatalf("ParseParams() error = %v", err)
+	}
+	if target.Name != "Tom" || target.Address.City != "Beijing" {
+		t.Errorf("ParseParams() = %+v", target)
+	}
+
+	if params, err := ParamsFromJSON(""); err != nil || len(params) != 0 {
+		t.Errorf("ParamsFromJSON(\"\") = %v, %v, want empty", params, err)
+	}
+	if _, err := ParamsFromJSON("{invalid"); err == nil {
+		t.Error("ParamsFromJSON() should fail on invalid JSON")
+	}
+}
+
+// reportOutput 测试用的函数输出
+type reportOutput struct {
+	Total int      `json:"total" desc:"总数"`
+	Items []string `json:"items"`
+}
+
+// outputFunction 声明了输出类型的测试函数
+type outputFunction struct {
+	MockFunction
+}
+
+func (f *outputFunction) OutputType() reflect.Type { return reflect.TypeOf(reportOutput{}) }
+
+func TestParamsJSONSchema_Enum(t *testing.T) {
+	props := ParamsJSONSchema(ExtractParamInfo(&MockFunction{
+		name:       "validated",
+		paramsType: reflect.TypeOf(ValidatedParams{}),
+	}))["properties"].(map[string]any)
+
+	mode := props["mode"].(map[string]any)
+	if !reflect.DeepEqual(mode["enum"], []any{"daily", "weekly"}) {
+		t.Errorf("mode enum = %v, want [daily weekly]", mode["enum"])
+	}
+
+	// 数组的枚举作用于元素，数值枚举转换为数字
+	items := props["days"].(map[string]any)["items"].(map[string]any)
+	if !reflect.DeepEqual(items["enum"], []any{int64(1), int64(7)}) {
+		t.Errorf("days items enum = %v, want [1 7]", items["enum"])
+	}
+}
+
+func TestRegistry_ExportJSONSchema(t *testing.T) {
+	registry := NewRegistry()
+	_ = registry.Register(&MockFunction{name: "b_func", description: "B", paramsType: reflect.TypeOf(TestParams{})})
+	_ = registry.Register(&outputFunction{MockFunction{name: "a_func", description: "A"}})
+	_ = registry.RegisterWithScopes(&MockFunction{name: "c_admin", description: "C"}, "admin")
+
+	schemas := registry.ExportJSONSchema()
+	if len(schemas) != 3 || schemas[0].Name != "a_func" || schemas[1].Name != "b_func" {
+		t.Fatalf("ExportJSONSchema() = %+v, want sorted by name", schemas)
+	}
+
+	if schemas[1].Parameters["type"] != "object" || schemas[1].Output != nil {
+		t.Errorf("b_func schema = %+v", schemas[1])
+	}
+
+	output := schemas[0].Output
+	if output == nil || output["type"] != "object" {
+		t.Fatalf("a_func output = %v, want object schema", output)
+	}
+	total := output["properties"].(map[string]any)["total"].(map[string]any)
+	if total["type"] != "integer" || total["description"] != "总数" {
+		t.Errorf("output.total = %v", total)
+	}
+
+	// 无权调用的函数不导出
+	if got := registry.ExportJSONSchemaForContext(WithCallerScopes(context.Background(), nil)); len(got) != 2 {
+		t.Errorf("ExportJSONSchemaForContext() returned %d schemas, want 2", len(got))
+	}
+}
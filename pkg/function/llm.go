@@ -0,0 +1,20 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"context"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+// LLMFromContext 返回当前函数内部调用 LLM 应使用的 Provider
+// 按 Provider 池中为该函数配置的路由选择，没有路由时为默认 Provider；
+// 执行器未设置 Provider 池时返回 nil。需要按名称选择时可用 llm.PoolFromContext(ctx).Get(name)
+func LLMFromContext(ctx context.Context) llm.Provider {
+	pool := llm.PoolFromContext(ctx)
+	if pool == nil {
+		return nil
+	}
+	md, _ := MetadataFromContext(ctx)
+	return pool.For(md.FunctionName)
+}
@@ -0,0 +1,65 @@
+package function
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+// namedProvider 只用于区分的 Provider，不会真正发送请求
+type namedProvider struct {
+	llm.Provider
+	name string
+}
+
+func (p *namedProvider) Name() string { return p.name }
+
+func TestLLMFromContext_Routing(t *testing.T) {
+	registry := NewRegistry()
+
+	got := map[string]llm.Provider{}
+	for _, name := range []string{"summarize", "plain"} {
+		registry.Register(&MockFunction{
+			name:       name,
+			paramsType: reflect.TypeOf(TestParams{}),
+			executeFunc: func(ctx context.Context, params any) (Result, error) {
+				got[name] = LLMFromContext(ctx)
+				return Result{Message: "ok"}, nil
+			},
+		})
+	}
+
+	executor := NewExecutor(registry, 5*time.Second)
+	params := map[string]string{"name": "a"}
+
+	// 未设置 Provider 池时函数拿不到 Provider
+	executor.Execute(context.Background(), ExecuteRequest{FunctionName: "plain", Params: params})
+	if got["plain"] != nil {
+		t.Errorf("LLMFromContext() without pool = %v, want nil", got["plain"])
+	}
+
+	strong := &namedProvider{name: "strong"}
+	cheap := &namedProvider{name: "cheap"}
+	pool := llm.NewPool(strong)
+	pool.Register("cheap", cheap)
+	if err := pool.Route("summarize", "cheap"); err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	if err := pool.Route("plain", "missing"); !errors.Is(err, llm.ErrProviderNotFound) {
+		t.Errorf("Route() to unknown provider error = %v, want ErrProviderNotFound", err)
+	}
+	executor.SetProviderPool(pool)
+
+	executor.Execute(context.Background(), ExecuteRequest{FunctionName: "summarize", Params: params})
+	executor.Execute(context.Background(), ExecuteRequest{FunctionName: "plain", Params: params})
+	if got["summarize"] != cheap {
+		t.Errorf("routed function got %v, want cheap provider", got["summarize"])
+	}
+	if got["plain"] != strong {
+		t.Errorf("unrouted function got %v, want default provider", got["plain"])
+	}
+}
@@ -0,0 +1,52 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"context"
+
+	"github.com/KodaTao/AgentChassis/pkg/types"
+)
+
+// Metadata 函数执行时的调用上下文
+// 由框架在执行前写入 context，函数通过 MetadataFromContext 获取，无需各自约定 context key
+type Metadata struct {
+	// FunctionName 实际执行的函数名（别名已解析为目标函数）
+	FunctionName string `json:"function_name"`
+
+	// UserID 调用者的用户标识，内部调用（如 HTTP 直接调用函数）时为空
+	UserID string `json:"user_id,omitempty"`
+
+	// SessionID 触发调用的会话
+	SessionID string `json:"session_id,omitempty"`
+
+	// Channel 消息来源渠道，未指定渠道时为 nil
+	Channel *types.ChannelContext `json:"channel,omitempty"`
+
+	// 调用追踪信息
+	TraceID   string `json:"trace_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"` // 所属 Chat 请求
+	CallID    string `json:"call_id,omitempty"`    // 格式为 <request_id>.<iteration>.<index>
+	Iteration int    `json:"iteration,omitempty"`  // 第几轮 LLM 调用（从 1 开始）
+	Index     int    `json:"index,omitempty"`      // 本轮中的第几个调用（从 1 开始）
+}
+
+// metadataKey context 中函数执行元数据的 key
+type metadataKey struct{}
+
+// WithMetadata 将函数执行元数据写入 context
+func WithMetadata(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// MetadataFromContext 获取函数执行元数据，ok 为 false 表示 context 不是由框架发起的函数调用
+func MetadataFromContext(ctx context.Context) (md Metadata, ok bool) {
+	md, ok = ctx.Value(metadataKey{}).(Metadata)
+	return md, ok
+}
+
+// withFunctionName 补充实际执行的函数名，context 中尚无元数据时新建一份
+func withFunctionName(ctx context.Context, name string) context.Context {
+	md, _ := MetadataFromContext(ctx)
+	md.FunctionName = name
+	return WithMetadata(ctx, md)
+}
@@ -0,0 +1,66 @@
+package function
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/types"
+)
+
+func TestExecutor_Metadata(t *testing.T) {
+	registry := NewRegistry()
+
+	var got Metadata
+	var ok bool
+	registry.Register(&MockFunction{
+		name:       "meta_test",
+		paramsType: reflect.TypeOf(TestParams{}),
+		executeFunc: func(ctx context.Context, params any) (Result, error) {
+			got, ok = MetadataFromContext(ctx)
+			return Result{Message: "ok"}, nil
+		},
+	})
+	if err := registry.RegisterAlias("meta_alias", "meta_test"); err != nil {
+		t.Fatalf("RegisterAlias() error = %v", err)
+	}
+
+	executor := NewExecutor(registry, 5*time.Second)
+
+	// 内部调用：没有调用上下文，仍能拿到实际执行的函数名
+	executor.Execute(context.Background(), ExecuteRequest{FunctionName: "meta_alias", Params: map[string]string{"name": "a"}})
+	if !ok || got.FunctionName != "meta_test" {
+		t.Errorf("metadata = %+v, ok = %v, want FunctionName meta_test", got, ok)
+	}
+
+	// 框架写入的调用上下文原样传给函数
+	channel := &types.ChannelContext{Type: "telegram", ChatID: "42"}
+	ctx := WithMetadata(context.Background(), Metadata{
+		UserID:    "u1",
+		SessionID: "s1",
+		Channel:   channel,
+		RequestID: "r1",
+		CallID:    "r1.1.2",
+		Iteration: 1,
+		Index:     2,
+	})
+	executor.Execute(ctx, ExecuteRequest{FunctionName: "meta_test", Params: map[string]string{"name": "a"}})
+	want := Metadata{
+		FunctionName: "meta_test",
+		UserID:       "u1",
+		SessionID:    "s1",
+		Channel:      channel,
+		RequestID:    "r1",
+		CallID:       "r1.1.2",
+		Iteration:    1,
+		Index:        2,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("metadata = %+v, want %+v", got, want)
+	}
+
+	if _, ok := MetadataFromContext(context.Background()); ok {
+		t.Error("MetadataFromContext() ok = true for empty context")
+	}
+}
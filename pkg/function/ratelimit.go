@@ -0,0 +1,115 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimitedFunction 可选接口：声明函数每分钟最多调用次数
+// 适用于调用外部付费或有配额限制 API 的函数，限制对所有会话共同生效
+type RateLimitedFunction interface {
+	// RateLimit 返回每分钟最多调用次数，<= 0 表示不限制
+	RateLimit() int
+}
+
+// ErrRateLimited 函数调用过于频繁
+var ErrRateLimited = fmt.Errorf("rate limit exceeded")
+
+// tokenBucket 令牌桶：容量为每分钟上限，按 上限/分钟 的速率匀速补充
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	rate     float64 // 每秒补充的令牌数
+	last     time.Time
+}
+
+// newTokenBucket 创建装满令牌的令牌桶
+func newTokenBucket(perMinute int) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		last:     time.Now(),
+	}
+}
+
+// take 尝试取一个令牌，成功返回 0，否则返回距离下一个令牌可用的等待时间
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// RateLimiter 按函数名限制调用频率，每个函数一个令牌桶，线程安全
+// 显式配置的限制优先于函数通过 RateLimitedFunction 声明的限制
+type RateLimiter struct {
+	mu      sync.Mutex
+	limits  map[string]int
+	buckets map[string]*tokenBucket
+}
+
+// NewRateLimiter 创建 RateLimiter
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		limits:  make(map[string]int),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// SetLimit 设置函数每分钟最多调用次数，perMinute <= 0 时移除配置的限制
+func (l *RateLimiter) SetLimit(name string, perMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if perMinute <= 0 {
+		delete(l.limits, name)
+	} else {
+		l.limits[name] = perMinute
+	}
+	// 限制变化后重新计数
+	delete(l.buckets, name)
+}
+
+// limitFor 返回函数生效的每分钟调用上限，0 表示不限制；调用方需持有锁
+func (l *RateLimiter) limitFor(fn Function) int {
+	if limit, ok := l.limits[fn.Name()]; ok {
+		return limit
+	}
+	if r, ok := fn.(RateLimitedFunction); ok && r.RateLimit() > 0 {
+		return r.RateLimit()
+	}
+	return 0
+}
+
+// Allow 消耗函数的一次调用额度，超限时返回 ErrRateLimited
+func (l *RateLimiter) Allow(fn Function) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.limitFor(fn)
+	if limit <= 0 {
+		return nil
+	}
+
+	bucket, ok := l.buckets[fn.Name()]
+	if !ok || bucket.capacity != float64(limit) {
+		bucket = newTokenBucket(limit)
+		l.buckets[fn.Name()] = bucket
+	}
+
+	if wait := bucket.take(time.Now()); wait > 0 {
+		return fmt.Errorf("%w: %s can be called at most %d times per minute, retry in %s or try another approach",
+			ErrRateLimited, fn.Name(), limit, wait.Round(time.Second))
+	}
+	return nil
+}
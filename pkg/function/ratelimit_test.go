@@ -0,0 +1,70 @@
+package function
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// rateLimitedMockFunction 声明了调用频率限制的 Mock 函数
+type rateLimitedMockFunction struct {
+	MockFunction
+	limit int
+}
+
+func (m *rateLimitedMockFunction) RateLimit() int { return m.limit }
+
+func TestExecutor_RateLimit(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(&rateLimitedMockFunction{MockFunction: MockFunction{name: "paid_api"}, limit: 2})
+	registry.Register(&MockFunction{name: "configured"})
+	registry.Register(&MockFunction{name: "unlimited"})
+
+	executor := NewExecutor(registry, time.Second)
+	executor.SetRateLimit("configured", 1)
+
+	tests := []struct {
+		name    string
+		allowed int
+	}{
+		{name: "paid_api", allowed: 2},
+		{name: "configured", allowed: 1},
+		{name: "unlimited", allowed: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < tt.allowed; i++ {
+				resp := executor.Execute(context.Background(), ExecuteRequest{FunctionName: tt.name})
+				if resp.Error != nil {
+					t.Fatalf("call %d: unexpected error = %v", i+1, resp.Error)
+				}
+			}
+			if tt.name == "unlimited" {
+				return
+			}
+			resp := executor.Execute(context.Background(), ExecuteRequest{FunctionName: tt.name})
+			if !errors.Is(resp.Error, ErrRateLimited) {
+				t.Errorf("call %d: error = %v, want ErrRateLimited", tt.allowed+1, resp.Error)
+			}
+		})
+	}
+}
+
+func TestTokenBucket_Refill(t *testing.T) {
+	bucket := newTokenBucket(60) // 每秒补充 1 个
+	now := bucket.last
+
+	for i := 0; i < 60; i++ {
+		if wait := bucket.take(now); wait != 0 {
+			t.Fatalf("take %d: wait = %v, want 0", i+1, wait)
+		}
+	}
+	if wait := bucket.take(now); wait <= 0 || wait > time.Second {
+		t.Errorf("empty bucket: wait = %v, want (0, 1s]", wait)
+	}
+	if wait := bucket.take(now.Add(time.Second)); wait != 0 {
+		t.Errorf("after refill: wait = %v, want 0", wait)
+	}
+}
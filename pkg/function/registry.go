@@ -4,6 +4,8 @@ package function
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"sort"
 	"sync"
 	"time"
 
@@ -15,17 +17,38 @@ import (
 type Registry struct {
 	mu        sync.RWMutex
 	functions map[string]Function
+	scopes    map[string][]string // 注册时指定的作用域，优先于 ScopedFunction 声明
+	aliases   map[string]string   // 别名 -> 目标名称，目标可以是函数名或另一个别名
+	version   uint64              // 每次函数集合或作用域变化时递增
+	logger    *slog.Logger        // 为 nil 时使用全局 logger
 }
 
 // NewRegistry 创建新的注册表
 func NewRegistry() *Registry {
 	return &Registry{
 		functions: make(map[string]Function),
+		scopes:    make(map[string][]string),
+		aliases:   make(map[string]string),
 	}
 }
 
+// SetLogger 设置注册、注销函数时使用的 logger
+func (r *Registry) SetLogger(logger *slog.Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logger = logger
+}
+
+// log 返回注册表的 logger，调用方需持有锁
+func (r *Registry) log() *slog.Logger {
+	if r.logger != nil {
+		return r.logger
+	}
+	return observability.DefaultLogger()
+}
+
 // Register 注册一个 Function
-// 如果同名 Function 已存在，会被覆盖
+// 如果同名 Function 已存在，会被覆盖；与已有别名同名时别名失效，真实函数优先
 func (r *Registry) Register(fn Function) error {
 	if fn == nil {
 		return ErrNilFunction
@@ -39,7 +62,145 @@ func (r *Registry) Register(fn Function) error {
 	defer r.mu.Unlock()
 
 	r.functions[name] = fn
-	observability.Info("Function registered", "name", name)
+	delete(r.aliases, name)
+	r.version++
+	r.log().Info("Function registered", "name", name)
+	return nil
+}
+
+// RegisterWithScopes 注册 Function 并指定调用所需的作用域
+func (r *Registry) RegisterWithScopes(fn Function, scopes ...string) error {
+	if err := r.Register(fn); err != nil {
+		return err
+	}
+	r.SetScopes(fn.Name(), scopes...)
+	return nil
+}
+
+// SetScopes 设置已注册 Function 所需的作用域，覆盖函数自身的声明
+// 不传 scopes 时恢复使用函数自身的声明
+func (r *Registry) SetScopes(name string, scopes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.version++
+	if len(scopes) == 0 {
+		delete(r.scopes, name)
+		return
+	}
+	r.scopes[name] = scopes
+}
+
+// RegisterAlias 为 Function 注册别名，AI 用别名调用时会透明地解析到目标函数
+// 常用于让同一个函数接受多个名字，或重命名函数后保持旧名兼容
+// 别名不能与已注册的函数同名，也不能形成环；目标可以是另一个别名，但最终必须指向已注册的函数
+func (r *Registry) RegisterAlias(alias, target string) error {
+	if alias == "" || target == "" {
+		return ErrEmptyFunctionName
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.functions[alias]; ok {
+		return fmt.Errorf("%w: %s", ErrAliasConflict, alias)
+	}
+	// 沿着别名链查找最终目标，途中回到 alias 说明会形成环
+	name := target
+	for i := 0; ; i++ {
+		if name == alias || i > len(r.aliases) {
+			return fmt.Errorf("%w: %s -> %s", ErrAliasCycle, alias, target)
+		}
+		next, ok := r.aliases[name]
+		if !ok {
+			break
+		}
+		name = next
+	}
+	if _, ok := r.functions[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrFunctionNotFound, target)
+	}
+
+	r.aliases[alias] = target
+	r.version++
+	r.log().Info("Function alias registered", "alias", alias, "target", name)
+	return nil
+}
+
+// UnregisterAlias 注销别名
+func (r *Registry) UnregisterAlias(alias string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.aliases[alias]; !ok {
+		return false
+	}
+	delete(r.aliases, alias)
+	r.version++
+	return true
+}
+
+// Resolve 将名称解析为真实的函数名，name 不是别名时原样返回
+func (r *Registry) Resolve(name string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.resolve(name)
+}
+
+// resolve 调用方需持有读锁
+// RegisterAlias 保证了别名链无环，这里仍限制步数作为兜底
+func (r *Registry) resolve(name string) string {
+	if _, ok := r.functions[name]; ok {
+		return name
+	}
+	for i := 0; i <= len(r.aliases); i++ {
+		target, ok := r.aliases[name]
+		if !ok {
+			break
+		}
+		name = target
+	}
+	return name
+}
+
+// aliasesOf 返回最终指向 name 的所有别名，调用方需持有读锁
+func (r *Registry) aliasesOf(name string) []string {
+	var aliases []string
+	for alias := range r.aliases {
+		if alias != name && r.resolve(alias) == name {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// RequiredScopes 返回调用 Function 所需的作用域
+func (r *Registry) RequiredScopes(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.requiredScopes(name)
+}
+
+// requiredScopes 调用方需持有读锁
+func (r *Registry) requiredScopes(name string) []string {
+	name = r.resolve(name)
+	if scopes, ok := r.scopes[name]; ok {
+		return scopes
+	}
+	if s, ok := r.functions[name].(ScopedFunction); ok {
+		return s.Scopes()
+	}
+	return nil
+}
+
+// Authorize 校验 context 中的调用者是否可调用指定 Function
+func (r *Registry) Authorize(ctx context.Context, name string) error {
+	if scope := missingScope(ctx, r.RequiredScopes(name)); scope != "" {
+		return fmt.Errorf("%w: function %s requires scope %q", ErrPermissionDenied, name, scope)
+	}
 	return nil
 }
 
@@ -53,21 +214,21 @@ func (r *Registry) RegisterAll(fns ...Function) error {
 	return nil
 }
 
-// Get 获取指定名称的 Function
+// Get 获取指定名称的 Function，name 为别名时返回其指向的函数
 func (r *Registry) Get(name string) (Function, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	fn, ok := r.functions[name]
+	fn, ok := r.functions[r.resolve(name)]
 	return fn, ok
 }
 
-// Has 检查是否存在指定名称的 Function
+// Has 检查是否存在指定名称的 Function（包括别名）
 func (r *Registry) Has(name string) bool {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	_, ok := r.functions[name]
+	_, ok := r.functions[r.resolve(name)]
 	return ok
 }
 
@@ -94,12 +255,29 @@ func (r *Registry) ListInfo() []FunctionInfo {
 			Name:        fn.Name(),
 			Description: fn.Description(),
 			Parameters:  ExtractParamInfo(fn),
+			Scopes:      r.requiredScopes(fn.Name()),
+			Aliases:     r.aliasesOf(fn.Name()),
+			Category:    categoryOf(fn),
+			Tags:        tagsOf(fn),
 		}
 		infos = append(infos, info)
 	}
 	return infos
 }
 
+// ListInfoForContext 列出 context 中的调用者有权调用的 Function 信息
+// 用于生成系统提示，避免 AI 尝试调用无权限的函数
+func (r *Registry) ListInfoForContext(ctx context.Context) []FunctionInfo {
+	all := r.ListInfo()
+	infos := make([]FunctionInfo, 0, len(all))
+	for _, info := range all {
+		if missingScope(ctx, info.Scopes) == "" {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
 // Unregister 注销一个 Function
 func (r *Registry) Unregister(name string) bool {
 	r.mu.Lock()
@@ -107,12 +285,22 @@ func (r *Registry) Unregister(name string) bool {
 
 	if _, ok := r.functions[name]; ok {
 		delete(r.functions, name)
-		observability.Info("Function unregistered", "name", name)
+		delete(r.scopes, name)
+		r.version++
+		r.log().Info("Function unregistered", "name", name)
 		return true
 	}
 	return false
 }
 
+// Version 返回注册表的版本号
+// 注册、注销函数或修改作用域都会使版本号递增，可用于判断系统提示是否需要刷新
+func (r *Registry) Version() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version
+}
+
 // Count 返回已注册的 Function 数量
 func (r *Registry) Count() int {
 	r.mu.RLock()
@@ -128,6 +316,9 @@ func (r *Registry) Execute(ctx context.Context, name string, params any) (Result
 	if !ok {
 		return Result{}, fmt.Errorf("%w: %s", ErrFunctionNotFound, name)
 	}
+	if err := r.Authorize(ctx, name); err != nil {
+		return Result{}, err
+	}
 
 	start := time.Now()
 	result, err := fn.Execute(ctx, params)
@@ -148,9 +339,12 @@ var (
 	ErrNilFunction       = fmt.Errorf("function cannot be nil")
 	ErrEmptyFunctionName = fmt.Errorf("function name cannot be empty")
 	ErrFunctionNotFound  = fmt.Errorf("function not found")
+	ErrAliasConflict     = fmt.Errorf("alias conflicts with a registered function")
+	ErrAliasCycle        = fmt.Errorf("alias would create a cycle")
 )
 
-// DefaultRegistry 默认的全局注册表
+// DefaultRegistry 默认的全局注册表，仅作为简单程序的便捷入口
+// App 使用各自独立的注册表，不读取 DefaultRegistry；需要时可通过 App.RegisterAll 导入其中的函数
 var DefaultRegistry = NewRegistry()
 
 // Register 向默认注册表注册 Function
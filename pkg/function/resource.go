@@ -0,0 +1,209 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"context"
+	"runtime"
+	"runtime/metrics"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// 资源监控的默认阈值
+const (
+	DefaultGoroutineGrowth = 10       // 单次调用结束后残留的 goroutine 数
+	DefaultHeapAllocBytes  = 64 << 20 // 单次调用期间的堆分配量（64MB）
+	DefaultUnhealthyAfter  = 3        // 连续异常多少次后标记为不健康
+)
+
+// heapAllocsMetric 进程累计堆分配字节数，读取时不会 stop-the-world（不同于 runtime.ReadMemStats）
+const heapAllocsMetric = "/gc/heap/allocs:bytes"
+
+// ResourceThresholds 资源异常的判定阈值
+type ResourceThresholds struct {
+	// GoroutineGrowth 调用结束后 goroutine 数比调用前多出的数量上限，<= 0 表示不检查
+	GoroutineGrowth int
+
+	// HeapAllocBytes 单次调用期间的堆分配量上限，0 表示不检查
+	HeapAllocBytes uint64
+
+	// UnhealthyAfter 连续多少次调用异常后把函数标记为不健康，<= 0 表示只告警不标记
+	UnhealthyAfter int
+}
+
+// DefaultResourceThresholds 返回默认阈值
+func DefaultResourceThresholds() ResourceThresholds {
+	return ResourceThresholds{
+		GoroutineGrowth: DefaultGoroutineGrowth,
+		HeapAllocBytes:  DefaultHeapAllocBytes,
+		UnhealthyAfter:  DefaultUnhealthyAfter,
+	}
+}
+
+// ResourceProfile 单个函数的资源画像
+type ResourceProfile struct {
+	Name                 string     `json:"name"`
+	Calls                int64      `json:"calls"`
+	MaxGoroutineGrowth   int        `json:"max_goroutine_growth"`
+	TotalGoroutineGrowth int64      `json:"total_goroutine_growth"` // 累计残留，持续增长通常意味着 goroutine 泄漏
+	TotalAllocBytes      uint64     `json:"total_alloc_bytes"`
+	MaxAllocBytes        uint64     `json:"max_alloc_bytes"`
+	Anomalies            int64      `json:"anomalies"`
+	ConsecutiveAnomalies int        `json:"consecutive_anomalies"`
+	Unhealthy            bool       `json:"unhealthy"`
+	LastAnomaly          string     `json:"last_anomaly,omitempty"`
+	LastAnomalyAt        *time.Time `json:"last_anomaly_at,omitempty"`
+}
+
+// resourceSample 某一时刻的进程资源采样
+type resourceSample struct {
+	goroutines int
+	allocBytes uint64
+}
+
+// ResourceMonitor 在函数执行前后采样 goroutine 数和堆分配量，发现异常增长时告警并标记不健康，线程安全
+// 采样的是整个进程的数据，其他函数并发执行时会互相干扰，因此只有连续多次异常才标记为不健康
+type ResourceMonitor struct {
+	mu         sync.RWMutex
+	thresholds ResourceThresholds
+	profiles   map[string]*ResourceProfile
+}
+
+// NewResourceMonitor 创建资源监控器
+func NewResourceMonitor(thresholds ResourceThresholds) *ResourceMonitor {
+	return &ResourceMonitor{
+		thresholds: thresholds,
+		profiles:   make(map[string]*ResourceProfile),
+	}
+}
+
+// sampleResources 采样当前 goroutine 数和累计堆分配量
+func sampleResources() resourceSample {
+	samples := []metrics.Sample{{Name: heapAllocsMetric}}
+	metrics.Read(samples)
+	var alloc uint64
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		alloc = samples[0].Value.Uint64()
+	}
+	return resourceSample{goroutines: runtime.NumGoroutine(), allocBytes: alloc}
+}
+
+// record 记录一次调用前后的采样结果，返回本次是否异常
+func (m *ResourceMonitor) record(ctx context.Context, name string, before, after resourceSample) bool {
+	growth := after.goroutines - before.goroutines
+	var alloc uint64
+	if after.allocBytes > before.allocBytes {
+		alloc = after.allocBytes - before.allocBytes
+	}
+
+	m.mu.Lock()
+	profile, ok := m.profiles[name]
+	if !ok {
+		profile = &ResourceProfile{Name: name}
+		m.profiles[name] = profile
+	}
+	profile.Calls++
+	profile.TotalGoroutineGrowth += int64(growth)
+	profile.MaxGoroutineGrowth = max(profile.MaxGoroutineGrowth, growth)
+	profile.TotalAllocBytes += alloc
+	profile.MaxAllocBytes = max(profile.MaxAllocBytes, alloc)
+
+	var reason string
+	switch {
+	case m.thresholds.GoroutineGrowth > 0 && growth > m.thresholds.GoroutineGrowth:
+		reason = "goroutine_growth"
+	case m.thresholds.HeapAllocBytes > 0 && alloc > m.thresholds.HeapAllocBytes:
+		reason = "heap_alloc"
+	}
+	if reason == "" {
+		profile.ConsecutiveAnomalies = 0
+		m.mu.Unlock()
+		return false
+	}
+
+	profile.Anomalies++
+	profile.ConsecutiveAnomalies++
+	profile.LastAnomaly = reason
+	now := time.Now()
+	profile.LastAnomalyAt = &now
+	markUnhealthy := !profile.Unhealthy && m.thresholds.UnhealthyAfter > 0 &&
+		profile.ConsecutiveAnomalies >= m.thresholds.UnhealthyAfter
+	if markUnhealthy {
+		profile.Unhealthy = true
+	}
+	consecutive := profile.ConsecutiveAnomalies
+	m.mu.Unlock()
+
+	observability.WarnContext(ctx, "Function resource usage anomaly",
+		"function", name,
+		"reason", reason,
+		"goroutine_growth", growth,
+		"alloc_bytes", alloc,
+		"consecutive", consecutive,
+	)
+	if markUnhealthy {
+		observability.WarnContext(ctx, "Function marked unhealthy", "function", name, "consecutive_anomalies", consecutive)
+	}
+	return true
+}
+
+// Profile 获取单个函数的资源画像
+func (m *ResourceMonitor) Profile(name string) (ResourceProfile, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	profile, ok := m.profiles[name]
+	if !ok {
+		return ResourceProfile{}, false
+	}
+	return *profile, true
+}
+
+// Profiles 返回所有执行过的函数的资源画像，按函数名排序
+func (m *ResourceMonitor) Profiles() []ResourceProfile {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	profiles := make([]ResourceProfile, 0, len(m.profiles))
+	for _, profile := range m.profiles {
+		profiles = append(profiles, *profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles
+}
+
+// IsHealthy 函数是否健康（没有执行记录的函数视为健康）
+func (m *ResourceMonitor) IsHealthy(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	profile, ok := m.profiles[name]
+	return !ok || !profile.Unhealthy
+}
+
+// Reset 清除函数的不健康标记和连续异常计数，返回函数是否有执行记录
+// 适用于修复或替换函数实现之后
+func (m *ResourceMonitor) Reset(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	profile, ok := m.profiles[name]
+	if !ok {
+		return false
+	}
+	profile.Unhealthy = false
+	profile.ConsecutiveAnomalies = 0
+	return true
+}
+
+// SetThresholds 修改判定阈值，对之后的调用生效
+func (m *ResourceMonitor) SetThresholds(thresholds ResourceThresholds) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.thresholds = thresholds
+}
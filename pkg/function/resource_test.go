@@ -0,0 +1,80 @@
+package function
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestResourceMonitor_DetectsGoroutineLeak(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	registry := NewRegistry()
+	_ = registry.Register(&MockFunction{
+		name:       "leaky",
+		paramsType: reflect.TypeOf(TestParams{}),
+		executeFunc: func(ctx context.Context, params any) (Result, error) {
+			for i := 0; i < 20; i++ {
+				go func() { <-release }()
+			}
+			return Result{Message: "ok"}, nil
+		},
+	})
+	_ = registry.Register(&MockFunction{name: "clean", paramsType: reflect.TypeOf(TestParams{})})
+
+	executor := NewExecutor(registry, 0)
+	monitor := NewResourceMonitor(ResourceThresholds{GoroutineGrowth: 10, UnhealthyAfter: 2})
+	executor.SetResourceMonitor(monitor)
+
+	req := ExecuteRequest{FunctionName: "leaky", Params: map[string]string{"name": "x"}}
+	executor.Execute(context.Background(), req)
+	if !monitor.IsHealthy("leaky") {
+		t.Fatal("function marked unhealthy after a single anomaly")
+	}
+	executor.Execute(context.Background(), req)
+	if monitor.IsHealthy("leaky") {
+		t.Fatal("function should be unhealthy after 2 consecutive anomalies")
+	}
+
+	profile, ok := monitor.Profile("leaky")
+	if !ok {
+		t.Fatal("missing profile for leaky")
+	}
+	if profile.Calls != 2 || profile.Anomalies != 2 || profile.LastAnomaly != "goroutine_growth" {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+	if profile.MaxGoroutineGrowth < 20 {
+		t.Errorf("MaxGoroutineGrowth = %d, want >= 20", profile.MaxGoroutineGrowth)
+	}
+
+	executor.Execute(context.Background(), ExecuteRequest{FunctionName: "clean", Params: map[string]string{"name": "x"}})
+	if !monitor.IsHealthy("clean") {
+		t.Error("clean function should be healthy")
+	}
+	if got := len(monitor.Profiles()); got != 2 {
+		t.Errorf("Profiles() returned %d profiles, want 2", got)
+	}
+
+	if !monitor.Reset("leaky") || !monitor.IsHealthy("leaky") {
+		t.Error("Reset() should clear the unhealthy flag")
+	}
+	if monitor.Reset("unknown") {
+		t.Error("Reset() of a function without profile should return false")
+	}
+}
+
+func TestResourceMonitor_HeapAlloc(t *testing.T) {
+	monitor := NewResourceMonitor(ResourceThresholds{HeapAllocBytes: 1 << 20})
+
+	if monitor.record(context.Background(), "small", resourceSample{allocBytes: 100}, resourceSample{allocBytes: 200}) {
+		t.Error("small allocation reported as anomaly")
+	}
+	if !monitor.record(context.Background(), "big", resourceSample{allocBytes: 0}, resourceSample{allocBytes: 2 << 20}) {
+		t.Error("large allocation not reported as anomaly")
+	}
+	// 未设置 UnhealthyAfter 时只告警不标记
+	if !monitor.IsHealthy("big") {
+		t.Error("function marked unhealthy although UnhealthyAfter is 0")
+	}
+}
@@ -2,10 +2,17 @@
 package function
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// timeType time.Time 按标量处理，不展开子字段
+var timeType = reflect.TypeOf(time.Time{})
+
 // ExtractParamInfo 从 Function 中提取参数信息
 // 使用反射读取参数结构体的字段和 tag
 func ExtractParamInfo(fn Function) []ParamInfo {
@@ -24,13 +31,17 @@ func ExtractParamInfo(fn Function) []ParamInfo {
 		return nil
 	}
 
-	return extractStructParams(paramType)
+	return extractStructParams(paramType, map[reflect.Type]bool{})
 }
 
 // extractStructParams 从结构体类型提取参数信息
-func extractStructParams(t reflect.Type) []ParamInfo {
+// visiting 记录当前递归路径上的类型，避免自引用结构体无限展开
+func extractStructParams(t reflect.Type, visiting map[reflect.Type]bool) []ParamInfo {
 	var params []ParamInfo
 
+	visiting[t] = true
+	defer delete(visiting, t)
+
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 
@@ -43,7 +54,7 @@ func extractStructParams(t reflect.Type) []ParamInfo {
 		if field.Anonymous {
 			// 递归处理嵌入的结构体
 			if field.Type.Kind() == reflect.Struct {
-				embedded := extractStructParams(field.Type)
+				embedded := extractStructParams(field.Type, visiting)
 				params = append(params, embedded...)
 			}
 			continue
@@ -55,6 +66,13 @@ func extractStructParams(t reflect.Type) []ParamInfo {
 			Description: field.Tag.Get("desc"),
 			Required:    isRequired(field),
 			Default:     field.Tag.Get("default"),
+			Validation:  describeValidation(field),
+			Enum:        parseEnum(field.Tag.Get("enum")),
+		}
+
+		// 嵌套结构体：递归提取子字段
+		if nested := nestedStructType(field.Type); nested != nil && !visiting[nested] {
+			param.Fields = extractStructParams(nested, visiting)
 		}
 
 		params = append(params, param)
@@ -63,6 +81,22 @@ func extractStructParams(t reflect.Type) []ParamInfo {
 	return params
 }
 
+// nestedStructType 返回需要展开子字段的结构体类型
+// 支持 struct、*struct 以及元素为 struct 的 slice/array，time.Time 除外
+func nestedStructType(t reflect.Type) reflect.Type {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		t = t.Elem()
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil
+	}
+	return t
+}
+
 // getFieldName 获取字段名称
 // 优先使用 json tag，否则使用字段名（转小写）
 func getFieldName(field reflect.StructField) string {
@@ -111,12 +145,27 @@ func getTypeName(t reflect.Type) string {
 	case reflect.Ptr:
 		return getTypeName(t.Elem())
 	case reflect.Struct:
+		// time.Time 以 RFC3339 字符串传递
+		if t == timeType {
+			return "string"
+		}
 		return "object"
 	default:
 		return t.String()
 	}
 }
 
+// parseEnum 解析逗号分隔的 enum tag
+func parseEnum(tag string) []string {
+	var values []string
+	for _, value := range strings.Split(tag, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // isRequired 判断字段是否必填
 func isRequired(field reflect.StructField) bool {
 	// 检查 required tag
@@ -157,8 +206,13 @@ func toSnakeCase(s string) string {
 }
 
 // ParseParams 将 map[string]string 解析为目标结构体
-// 使用反射填充结构体字段
+// 使用反射填充结构体字段；取值无法转换为字段类型时返回 *ParamError，指出具体参数和期望类型
 func ParseParams(params map[string]string, target any) error {
+	return parseParams(params, target, "")
+}
+
+// parseParams 填充结构体字段，prefix 为嵌套字段的参数名前缀，用于错误中的完整参数名
+func parseParams(params map[string]string, target any, prefix string) error {
 	v := reflect.ValueOf(target)
 	if v.Kind() != reflect.Ptr || v.IsNil() {
 		return ErrInvalidTarget
@@ -180,6 +234,15 @@ func ParseParams(params map[string]string, target any) error {
 
 		// 获取参数名
 		paramName := getFieldName(field)
+
+		// 嵌套结构体：使用 "parent.child" 形式的参数名填充子字段
+		if nested := nestedObjectValue(fieldValue, params, paramName); nested.IsValid() {
+			if err := parseParams(subParams(params, paramName), nested.Addr().Interface(), prefix+paramName+"."); err != nil {
+				return err
+			}
+			continue
+		}
+
 		paramValue, ok := params[paramName]
 		if !ok {
 			// 检查是否有默认值
@@ -192,98 +255,125 @@ func ParseParams(params map[string]string, target any) error {
 		}
 
 		// 设置字段值
-		if err := setFieldValue(fieldValue, paramValue); err != nil {
-			return err
+		if expected := setFieldValue(fieldValue, paramValue); expected != "" {
+			return &ParamError{Field: prefix + paramName, Expected: expected, Value: paramValue}
 		}
 	}
 
 	return nil
 }
 
-// setFieldValue 设置字段值
-func setFieldValue(field reflect.Value, value string) error {
+// nestedObjectValue 如果字段是嵌套结构体且存在以 prefix. 开头的参数，返回可填充的结构体值
+// *struct 字段会按需分配
+func nestedObjectValue(field reflect.Value, params map[string]string, prefix string) reflect.Value {
+	t := field.Type()
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return reflect.Value{}
+	}
+	if len(subParams(params, prefix)) == 0 {
+		return reflect.Value{}
+	}
+
+	if isPtr {
+		if field.IsNil() {
+			field.Set(reflect.New(t))
+		}
+		return field.Elem()
+	}
+	return field
+}
+
+// subParams 提取以 prefix. 开头的参数，并去掉前缀
+func subParams(params map[string]string, prefix string) map[string]string {
+	sub := make(map[string]string)
+	for key, value := range params {
+		if rest, ok := strings.CutPrefix(key, prefix+"."); ok {
+			sub[rest] = value
+		}
+	}
+	return sub
+}
+
+// setFieldValue 设置字段值，成功返回空字符串，取值无法转换时返回期望的类型描述
+// 数值和布尔值的空字符串视为未提供，保留零值
+func setFieldValue(field reflect.Value, value string) string {
+	if field.Kind() != reflect.String {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return ""
+		}
+	}
+
 	switch field.Kind() {
 	case reflect.String:
 		field.SetString(value)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		var intVal int64
-		_, err := parseNumber(value, &intVal)
+		n, err := parseInt(value, field.Type().Bits())
+		if errors.Is(err, strconv.ErrRange) {
+			maxVal := int64(^uint64(0) >> (65 - field.Type().Bits()))
+			return fmt.Sprintf("integer between %d and %d", -maxVal-1, maxVal)
+		}
 		if err != nil {
-			return err
+			return "integer"
 		}
-		field.SetInt(intVal)
+		field.SetInt(n)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		var intVal int64
-		_, err := parseNumber(value, &intVal)
+		n, err := strconv.ParseUint(trimIntegralFloat(value), 10, field.Type().Bits())
+		if errors.Is(err, strconv.ErrRange) {
+			return fmt.Sprintf("non-negative integer at most %d", ^uint64(0)>>(64-field.Type().Bits()))
+		}
 		if err != nil {
-			return err
+			return "non-negative integer"
 		}
-		field.SetUint(uint64(intVal))
+		field.SetUint(n)
 	case reflect.Float32, reflect.Float64:
-		var floatVal float64
-		_, err := parseNumber(value, &floatVal)
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
 		if err != nil {
-			return err
+			return "number"
 		}
-		field.SetFloat(floatVal)
+		field.SetFloat(f)
 	case reflect.Bool:
-		boolVal := value == "true" || value == "1" || value == "yes"
-		field.SetBool(boolVal)
-	default:
-		// 对于复杂类型，暂不处理
-		return nil
+		switch strings.ToLower(value) {
+		case "true", "1", "yes":
+			field.SetBool(true)
+		case "false", "0", "no":
+			field.SetBool(false)
+		default:
+			return "boolean (true or false)"
+		}
 	}
-	return nil
+	// 对于复杂类型，暂不处理
+	return ""
 }
 
-// parseNumber 解析数字字符串
-func parseNumber(s string, target any) (bool, error) {
-	switch v := target.(type) {
-	case *int64:
-		var n int64
-		for _, c := range s {
-			if c >= '0' && c <= '9' {
-				n = n*10 + int64(c-'0')
-			} else if c == '-' && n == 0 {
-				continue
-			} else {
-				break
-			}
-		}
-		if len(s) > 0 && s[0] == '-' {
-			n = -n
-		}
-		*v = n
-		return true, nil
-	case *float64:
-		var n float64
-		var decimal float64 = 0
-		var decimalPlace float64 = 1
-		inDecimal := false
-		negative := false
-
-		for i, c := range s {
-			if c == '-' && i == 0 {
-				negative = true
-			} else if c == '.' {
-				inDecimal = true
-			} else if c >= '0' && c <= '9' {
-				if inDecimal {
-					decimalPlace *= 10
-					decimal += float64(c-'0') / decimalPlace
-				} else {
-					n = n*10 + float64(c-'0')
-				}
-			}
-		}
-		n += decimal
-		if negative {
-			n = -n
-		}
-		*v = n
-		return true, nil
+// parseInt 解析整数，允许 "3.0" 这类小数部分为 0 的写法（JSON 数字常被序列化成浮点数）
+func parseInt(value string, bits int) (int64, error) {
+	return strconv.ParseInt(trimIntegralFloat(value), 10, bits)
+}
+
+// trimIntegralFloat 去掉 "3.0"、"3.00" 中全为 0 的小数部分
+func trimIntegralFloat(value string) string {
+	integer, frac, ok := strings.Cut(value, ".")
+	if ok && integer != "" && strings.Trim(frac, "0") == "" {
+		return integer
 	}
-	return false, nil
+	return value
+}
+
+// ParamError 参数取值无法转换为字段类型
+// Message 面向 AI，指出哪个参数、期望什么类型、实际收到了什么，便于精确修正
+type ParamError struct {
+	Field    string // 参数名，嵌套字段为 "parent.child"
+	Expected string // 期望的类型，如 integer
+	Value    string // 实际收到的取值
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("field '%s' expects %s, got '%s'", e.Field, e.Expected, e.Value)
 }
 
 // 错误定义
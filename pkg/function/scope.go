@@ -0,0 +1,74 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"context"
+	"fmt"
+)
+
+// ScopeAll 通配作用域，拥有该作用域的调用者可调用所有函数
+const ScopeAll = "*"
+
+// ScopedFunction 可选接口：声明调用函数所需的作用域
+// 调用者必须拥有全部所需作用域才能看到并调用该函数
+type ScopedFunction interface {
+	// Scopes 返回所需的作用域列表，为空表示不限制
+	Scopes() []string
+}
+
+// ErrPermissionDenied 调用者缺少函数所需的作用域
+var ErrPermissionDenied = fmt.Errorf("permission denied")
+
+// scopesKey context 中调用者作用域的 key
+type scopesKey struct{}
+
+// WithCallerScopes 将调用者拥有的作用域写入 context
+// 未写入作用域的 context（如内部调度任务）不受限制
+func WithCallerScopes(ctx context.Context, scopes []string) context.Context {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return context.WithValue(ctx, scopesKey{}, scopes)
+}
+
+// WithoutCallerScopes 清除 context 中的调用者作用域，之后按未鉴权的内部调用处理
+// 用于以他人身份继续执行时丢弃当前调用者（如审批人）的作用域
+func WithoutCallerScopes(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopesKey{}, nil)
+}
+
+// CallerScopesFromContext 获取调用者作用域，ok 为 false 表示未鉴权的内部调用
+func CallerScopesFromContext(ctx context.Context) (scopes []string, ok bool) {
+	scopes, ok = ctx.Value(scopesKey{}).([]string)
+	return scopes, ok
+}
+
+// HasScope 调用者是否拥有该作用域（或 "*"），未鉴权的内部调用视为拥有
+func HasScope(ctx context.Context, scope string) bool {
+	return missingScope(ctx, []string{scope}) == ""
+}
+
+// missingScope 返回调用者缺少的第一个作用域，满足全部作用域时返回空字符串
+func missingScope(ctx context.Context, required []string) string {
+	if len(required) == 0 {
+		return ""
+	}
+	granted, ok := CallerScopesFromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	set := make(map[string]bool, len(granted))
+	for _, s := range granted {
+		if s == ScopeAll {
+			return ""
+		}
+		set[s] = true
+	}
+	for _, s := range required {
+		if !set[s] {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,112 @@
+package function
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// scopedMockFunction 声明了作用域的 Mock 函数
+type scopedMockFunction struct {
+	MockFunction
+	scopes []string
+}
+
+func (m *scopedMockFunction) Scopes() []string { return m.scopes }
+
+func TestRegistry_Scopes(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(&MockFunction{name: "public_func"})
+	registry.Register(&scopedMockFunction{MockFunction: MockFunction{name: "declared_func"}, scopes: []string{"tasks"}})
+	registry.RegisterWithScopes(&MockFunction{name: "admin_func"}, "admin")
+
+	names := func(infos []FunctionInfo) map[string]bool {
+		m := make(map[string]bool)
+		for _, info := range infos {
+			m[info.Name] = true
+		}
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		visible []string
+		hidden  []string
+	}{
+		{name: "internal caller", ctx: context.Background(), visible: []string{"public_func", "declared_func", "admin_func"}},
+		{name: "no scopes", ctx: WithCallerScopes(context.Background(), nil), visible: []string{"public_func"}, hidden: []string{"declared_func", "admin_func"}},
+		{name: "tasks scope", ctx: WithCallerScopes(context.Background(), []string{"tasks"}), visible: []string{"public_func", "declared_func"}, hidden: []string{"admin_func"}},
+		{name: "wildcard", ctx: WithCallerScopes(context.Background(), []string{ScopeAll}), visible: []string{"public_func", "declared_func", "admin_func"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(registry.ListInfoForContext(tt.ctx))
+			for _, name := range tt.visible {
+				if !got[name] {
+					t.Errorf("%s should be visible", name)
+				}
+				if err := registry.Authorize(tt.ctx, name); err != nil {
+					t.Errorf("Authorize(%s) error = %v", name, err)
+				}
+			}
+			for _, name := range tt.hidden {
+				if got[name] {
+					t.Errorf("%s should be hidden", name)
+				}
+				if err := registry.Authorize(tt.ctx, name); !errors.Is(err, ErrPermissionDenied) {
+					t.Errorf("Authorize(%s) error = %v, want ErrPermissionDenied", name, err)
+				}
+			}
+		})
+	}
+
+	// 注册时指定的作用域覆盖函数声明
+	registry.SetScopes("declared_func", "admin")
+	ctx := WithCallerScopes(context.Background(), []string{"tasks"})
+	if err := registry.Authorize(ctx, "declared_func"); err == nil {
+		t.Error("SetScopes should override declared scopes")
+	}
+}
+
+func TestExecutor_PermissionDenied(t *testing.T) {
+	registry := NewRegistry()
+	executed := false
+	registry.RegisterWithScopes(&MockFunction{
+		name: "admin_func",
+		executeFunc: func(ctx context.Context, params any) (Result, error) {
+			executed = true
+			return Result{}, nil
+		},
+	}, "admin")
+
+	executor := NewExecutor(registry, 0)
+	ctx := WithCallerScopes(context.Background(), []string{"user"})
+	resp := executor.Execute(ctx, ExecuteRequest{FunctionName: "admin_func"})
+	if !errors.Is(resp.Error, ErrPermissionDenied) {
+		t.Errorf("Execute() error = %v, want ErrPermissionDenied", resp.Error)
+	}
+	if executed {
+		t.Error("Function should not be executed without required scope")
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want bool
+	}{
+		{"internal caller", context.Background(), true},
+		{"no scopes", WithCallerScopes(context.Background(), nil), false},
+		{"other scope", WithCallerScopes(context.Background(), []string{"tasks"}), false},
+		{"admin", WithCallerScopes(context.Background(), []string{"tasks", "admin"}), true},
+		{"wildcard", WithCallerScopes(context.Background(), []string{ScopeAll}), true},
+	}
+	for _, tt := range tests {
+		if got := HasScope(tt.ctx, "admin"); got != tt.want {
+			t.Errorf("%s: HasScope(admin) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// Snapshot 注册表在某一时刻的能力快照，可序列化保存，之后作为基线与当前注册表比较
+type Snapshot struct {
+	Version   uint64         `json:"version"`   // 快照时注册表的版本号
+	TakenAt   time.Time      `json:"taken_at"`  // 快照时间
+	Functions []FunctionInfo `json:"functions"` // 按名称排序的函数信息
+}
+
+// FunctionChange 两个快照中同名但定义不同的函数
+type FunctionChange struct {
+	Name   string       `json:"name"`
+	Fields []string     `json:"fields"` // 发生变化的部分：description、parameters、scopes、aliases
+	Before FunctionInfo `json:"before"`
+	After  FunctionInfo `json:"after"`
+}
+
+// SnapshotDiff 两个快照之间的差异，各列表均按函数名排序
+type SnapshotDiff struct {
+	Added   []FunctionInfo   `json:"added"`
+	Removed []FunctionInfo   `json:"removed"`
+	Changed []FunctionChange `json:"changed"`
+}
+
+// Empty 两个快照是否完全一致
+func (d SnapshotDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Snapshot 返回当前所有函数信息的快照
+func (r *Registry) Snapshot() Snapshot {
+	infos := r.ListInfo()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return Snapshot{
+		Version:   r.Version(),
+		TakenAt:   time.Now(),
+		Functions: infos,
+	}
+}
+
+// Diff 返回当前注册表相对基线快照新增、删除和变更的函数
+func (r *Registry) Diff(base Snapshot) SnapshotDiff {
+	return DiffSnapshots(base, r.Snapshot())
+}
+
+// DiffSnapshots 比较两个快照，返回 to 相对 from 新增、删除和变更的函数
+func DiffSnapshots(from, to Snapshot) SnapshotDiff {
+	before := make(map[string]FunctionInfo, len(from.Functions))
+	for _, info := range from.Functions {
+		before[info.Name] = info
+	}
+	after := make(map[string]FunctionInfo, len(to.Functions))
+	for _, info := range to.Functions {
+		after[info.Name] = info
+	}
+
+	diff := SnapshotDiff{
+		Added:   []FunctionInfo{},
+		Removed: []FunctionInfo{},
+		Changed: []FunctionChange{},
+	}
+	for name, info := range after {
+		old, ok := before[name]
+		if !ok {
+			diff.Added = append(diff.Added, info)
+			continue
+		}
+		if fields := changedFields(old, info); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, FunctionChange{Name: name, Fields: fields, Before: old, After: info})
+		}
+	}
+	for name, info := range before {
+		if _, ok := after[name]; !ok {
+			diff.Removed = append(diff.Removed, info)
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Name < diff.Added[j].Name })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Name < diff.Removed[j].Name })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Name < diff.Changed[j].Name })
+	return diff
+}
+
+// changedFields 返回同名函数发生变化的部分
+// 按 JSON 形式比较，基线快照经过序列化后 nil 与空切片不会被误判为变更
+func changedFields(a, b FunctionInfo) []string {
+	var fields []string
+	if a.Description != b.Description {
+		fields = append(fields, "description")
+	}
+	if !sameJSON(a.Parameters, b.Parameters) {
+		fields = append(fields, "parameters")
+	}
+	if !sameJSON(a.Scopes, b.Scopes) {
+		fields = append(fields, "scopes")
+	}
+	if !sameJSON(a.Aliases, b.Aliases) {
+		fields = append(fields, "aliases")
+	}
+	if a.Category != b.Category {
+		fields = append(fields, "category")
+	}
+	if !sameJSON(a.Tags, b.Tags) {
+		fields = append(fields, "tags")
+	}
+	return fields
+}
+
+// sameJSON 比较两个值序列化后是否一致，空切片与 nil 视为相同
+func sameJSON[T any](a, b []T) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
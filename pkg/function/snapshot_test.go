@@ -0,0 +1,54 @@
+package function
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRegistry_SnapshotDiff(t *testing.T) {
+	registry := NewRegistry()
+	_ = registry.Register(&MockFunction{name: "keep", description: "不变", paramsType: reflect.TypeOf(TestParams{})})
+	_ = registry.Register(&MockFunction{name: "edit", description: "旧描述", paramsType: reflect.TypeOf(TestParams{})})
+	_ = registry.Register(&MockFunction{name: "drop", description: "将被注销", paramsType: reflect.TypeOf(TestParams{})})
+
+	base := registry.Snapshot()
+	if len(base.Functions) != 3 || base.Functions[0].Name != "drop" || base.Functions[2].Name != "keep" {
+		t.Fatalf("snapshot functions not sorted by name: %+v", base.Functions)
+	}
+
+	// 基线经过序列化后再比较，模拟保存到文件的场景
+	raw, err := json.Marshal(base)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var saved Snapshot
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if diff := registry.Diff(saved); !diff.Empty() {
+		t.Fatalf("expected no diff against an identical snapshot, got %+v", diff)
+	}
+
+	registry.Unregister("drop")
+	_ = registry.Register(&MockFunction{name: "edit", description: "新描述", paramsType: reflect.TypeOf(TestParams{})})
+	_ = registry.Register(&MockFunction{name: "new", description: "新增", paramsType: reflect.TypeOf(TestParams{})})
+	registry.SetScopes("keep", "admin")
+
+	diff := registry.Diff(saved)
+	if len(diff.Added) != 1 || diff.Added[0].Name != "new" {
+		t.Errorf("Added = %+v, want [new]", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name != "drop" {
+		t.Errorf("Removed = %+v, want [drop]", diff.Removed)
+	}
+	if len(diff.Changed) != 2 {
+		t.Fatalf("Changed = %+v, want edit and keep", diff.Changed)
+	}
+	if c := diff.Changed[0]; c.Name != "edit" || !reflect.DeepEqual(c.Fields, []string{"description"}) {
+		t.Errorf("Changed[0] = %+v, want edit/description", c)
+	}
+	if c := diff.Changed[1]; c.Name != "keep" || !reflect.DeepEqual(c.Fields, []string{"scopes"}) {
+		t.Errorf("Changed[1] = %+v, want keep/scopes", c)
+	}
+}
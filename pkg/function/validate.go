@@ -0,0 +1,286 @@
+// Package function 提供 Function 接口定义和相关类型
+package function
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidateParams 按 tag 校验已解析的参数结构体
+// 支持的 tag：
+//   - required:"true"                 必填
+//   - validate:"email" / validate:"url" 格式校验（可与 required 组合，逗号分隔）
+//   - min:"1" max:"65535"             数值范围
+//   - minlen:"1" maxlen:"100"         字符串长度（按字符计）或数组长度
+//   - pattern:"^[a-z_]+$"             正则匹配
+//   - enum:"daily,weekly"             取值必须在列表中（数组逐个元素校验）
+//
+// 非必填字段为零值时视为未提供，跳过其余校验
+func ValidateParams(params any) error {
+	return ValidateProvidedParams(params, nil)
+}
+
+// ValidateProvidedParams 与 ValidateParams 相同，但 raw 中提供了非空取值的参数即使解析为零值（如 port=0）
+// 也要通过 min/max、pattern 等校验；只有未提供的参数在零值时跳过校验
+func ValidateProvidedParams(params any, raw map[string]string) error {
+	if params == nil {
+		return nil
+	}
+
+	v := reflect.ValueOf(params)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return validateStruct(v, "", raw)
+}
+
+// validateStruct 递归校验结构体字段，prefix 为嵌套字段的路径前缀
+func validateStruct(v reflect.Value, prefix string, raw map[string]string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+
+		// 嵌入字段按同一层级处理
+		if field.Anonymous && fieldValue.Kind() == reflect.Struct {
+			if err := validateStruct(fieldValue, prefix, raw); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := prefix + getFieldName(field)
+		if err := validateField(field, fieldValue, name, isProvided(field, name, raw)); err != nil {
+			return err
+		}
+
+		// 嵌套结构体
+		nested := fieldValue
+		if nested.Kind() == reflect.Ptr && !nested.IsNil() {
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct && nested.Type() != timeType {
+			if err := validateStruct(nested, name+".", raw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isProvided 参数是否提供了非空取值，或由 default tag 填充
+func isProvided(field reflect.StructField, name string, raw map[string]string) bool {
+	if field.Tag.Get("default") != "" {
+		return true
+	}
+	value, ok := raw[name]
+	return ok && strings.TrimSpace(value) != ""
+}
+
+// validateField 校验单个字段，provided 为调用方是否提供了该参数
+// 零值只在必填时直接报错；未提供的零值跳过其余校验，提供了的零值（如 port=0）照常校验
+func validateField(field reflect.StructField, v reflect.Value, name string, provided bool) error {
+	if v.IsZero() {
+		if isRequired(field) {
+			return newValidationError(name, "%s is required", name)
+		}
+		if !provided || v.Kind() == reflect.Ptr {
+			return nil
+		}
+	}
+
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	// 格式校验
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		switch strings.TrimSpace(rule) {
+		case "email":
+			addr, err := mail.ParseAddress(v.String())
+			if err != nil || addr.Address != v.String() {
+				return newValidationError(name, "%s must be a valid email address", name)
+			}
+		case "url":
+			u, err := url.Parse(v.String())
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return newValidationError(name, "%s must be a valid URL", name)
+			}
+		}
+	}
+
+	// 数值范围
+	if minTag, maxTag := field.Tag.Get("min"), field.Tag.Get("max"); minTag != "" || maxTag != "" {
+		num, ok := numericValue(v)
+		if ok {
+			minVal, hasMin := parseBound(minTag)
+			maxVal, hasMax := parseBound(maxTag)
+			switch {
+			case hasMin && hasMax && (num < minVal || num > maxVal):
+				return newValidationError(name, "%s must be between %s and %s", name, minTag, maxTag)
+			case hasMin && num < minVal:
+				return newValidationError(name, "%s must be at least %s", name, minTag)
+			case hasMax && num > maxVal:
+				return newValidationError(name, "%s must be at most %s", name, maxTag)
+			}
+		}
+	}
+
+	// 长度
+	if minTag, maxTag := field.Tag.Get("minlen"), field.Tag.Get("maxlen"); minTag != "" || maxTag != "" {
+		length, ok := lengthOf(v)
+		if ok {
+			unit := "characters long"
+			if v.Kind() != reflect.String {
+				unit = "items"
+			}
+			if minLen, err := strconv.Atoi(minTag); err == nil && length < minLen {
+				return newValidationError(name, "%s must be at least %d %s", name, minLen, unit)
+			}
+			if maxLen, err := strconv.Atoi(maxTag); err == nil && length > maxLen {
+				return newValidationError(name, "%s must be at most %d %s", name, maxLen, unit)
+			}
+		}
+	}
+
+	// 正则
+	if pattern := field.Tag.Get("pattern"); pattern != "" && v.Kind() == reflect.String {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return newValidationError(name, "%s has an invalid pattern: %v", name, err)
+		}
+		if !re.MatchString(v.String()) {
+			return newValidationError(name, "%s must match pattern %s", name, pattern)
+		}
+	}
+
+	// 枚举
+	if enum := parseEnum(field.Tag.Get("enum")); len(enum) > 0 {
+		if err := validateEnum(v, name, enum); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateEnum 校验取值是否在枚举列表中，数组逐个元素校验
+func validateEnum(v reflect.Value, name string, enum []string) error {
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			if err := validateEnum(v.Index(i), name, enum); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	value := fmt.Sprint(v.Interface())
+	for _, allowed := range enum {
+		if value == allowed {
+			return nil
+		}
+	}
+	return newValidationError(name, "%s must be one of: %s", name, strings.Join(enum, ", "))
+}
+
+// numericValue 将数值字段转换为 float64
+func numericValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// parseBound 解析 min/max tag
+func parseBound(tag string) (float64, bool) {
+	if tag == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(tag, 64)
+	return f, err == nil
+}
+
+// lengthOf 返回字符串（按字符）或数组的长度
+func lengthOf(v reflect.Value) (int, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(v.String()), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len(), true
+	}
+	return 0, false
+}
+
+// patternCache 缓存已编译的正则，避免每次调用重复编译
+var patternCache sync.Map
+
+// compilePattern 编译并缓存正则
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+// describeValidation 汇总字段的校验规则，用于 Schema 展示
+func describeValidation(field reflect.StructField) string {
+	var rules []string
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule = strings.TrimSpace(rule); rule == "email" || rule == "url" {
+			rules = append(rules, rule)
+		}
+	}
+	for _, key := range []string{"min", "max", "minlen", "maxlen", "pattern"} {
+		if val := field.Tag.Get(key); val != "" {
+			rules = append(rules, key+"="+val)
+		}
+	}
+	if enum := parseEnum(field.Tag.Get("enum")); len(enum) > 0 {
+		rules = append(rules, "enum="+strings.Join(enum, "|"))
+	}
+	return strings.Join(rules, ", ")
+}
+
+// ValidationError 参数校验错误
+// Message 面向 AI，说明哪个参数不符合什么要求，便于自我纠正
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// newValidationError 创建参数校验错误
+func newValidationError(field, format string, args ...any) error {
+	return &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
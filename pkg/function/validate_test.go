@@ -0,0 +1,152 @@
+package function
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ValidatedParams 带校验规则的测试参数
+type ValidatedParams struct {
+	Email string   `json:"email" validate:"required,email"`
+	Site  string   `json:"site" validate:"url"`
+	Port  int      `json:"port" min:"1" max:"65535"`
+	Title string   `json:"title" minlen:"2" maxlen:"5"`
+	Code  string   `json:"code" pattern:"^[a-z_]+$"`
+	Tags  []string `json:"tags" maxlen:"2"`
+	Mode  string   `json:"mode" enum:"daily,weekly"`
+	Days  []int    `json:"days" enum:"1,7"`
+}
+
+func TestValidateParams(t *testing.T) {
+	valid := ValidatedParams{Email: "a@example.com"}
+
+	tests := []struct {
+		name    string
+		modify  func(p *ValidatedParams)
+		wantErr string
+	}{
+		{name: "valid", modify: func(p *ValidatedParams) {}},
+		{name: "all fields valid", modify: func(p *ValidatedParams) {
+			p.Site, p.Port, p.Title, p.Code = "https://example.com", 8080, "标题", "clean_logs"
+			p.Mode, p.Days = "weekly", []int{1, 7}
+		}},
+		{name: "missing required", modify: func(p *ValidatedParams) { p.Email = "" }, wantErr: "email is required"},
+		{name: "invalid email", modify: func(p *ValidatedParams) { p.Email = "not-an-email" }, wantErr: "email must be a valid email address"},
+		{name: "email with display name", modify: func(p *ValidatedParams) { p.Email = "Bob <b@example.com>" }, wantErr: "email must be a valid email address"},
+		{name: "invalid url", modify: func(p *ValidatedParams) { p.Site = "example.com" }, wantErr: "site must be a valid URL"},
+		{name: "port out of range", modify: func(p *ValidatedParams) { p.Port = 70000 }, wantErr: "port must be between 1 and 65535"},
+		{name: "title too short", modify: func(p *ValidatedParams) { p.Title = "a" }, wantErr: "title must be at least 2 characters long"},
+		{name: "title too long", modify: func(p *ValidatedParams) { p.Title = "abcdef" }, wantErr: "title must be at most 5 characters long"},
+		{name: "pattern mismatch", modify: func(p *ValidatedParams) { p.Code = "Clean-Logs" }, wantErr: "code must match pattern ^[a-z_]+$"},
+		{name: "too many tags", modify: func(p *ValidatedParams) { p.Tags = []string{"a", "b", "c"} }, wantErr: "tags must be at most 2 items"},
+		{name: "mode not in enum", modify: func(p *ValidatedParams) { p.Mode = "hourly" }, wantErr: "mode must be one of: daily, weekly"},
+		{name: "array element not in enum", modify: func(p *ValidatedParams) { p.Days = []int{1, 3} }, wantErr: "days must be one of: 1, 7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := valid
+			tt.modify(&params)
+
+			err := ValidateParams(params)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateParams() error = %v, want nil", err)
+				}
+				return
+			}
+
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ValidateParams() error = %v, want %q", err, tt.wantErr)
+			}
+			var vErr *ValidationError
+			if !errors.As(err, &vErr) {
+				t.Errorf("ValidateParams() error should be *ValidationError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestExecutor_ValidateParams(t *testing.T) {
+	registry := NewRegistry()
+	executed := false
+	_ = registry.Register(&MockFunction{
+		name:       "set_port",
+		paramsType: reflect.TypeOf(ValidatedParams{}),
+		executeFunc: func(ctx context.Context, params any) (Result, error) {
+			executed = true
+			return Result{Message: "ok"}, nil
+		},
+	})
+
+	executor := NewExecutor(registry, 0)
+	resp := executor.Execute(context.Background(), ExecuteRequest{
+		FunctionName: "set_port",
+		Params:       map[string]string{"email": "a@example.com", "port": "8080", "title": "x"},
+	})
+
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "title must be at least 2 characters long") {
+		t.Errorf("Execute() error = %v, want validation error", resp.Error)
+	}
+	if executed {
+		t.Error("Function should not be executed when validation fails")
+	}
+}
+
+func TestValidateProvidedParams_ZeroValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     map[string]string
+		params  ValidatedParams
+		wantErr string
+	}{
+		{name: "port not provided", raw: map[string]string{"email": "a@example.com"}, params: ValidatedParams{Email: "a@example.com"}},
+		{name: "port empty", raw: map[string]string{"email": "a@example.com", "port": " "}, params: ValidatedParams{Email: "a@example.com"}},
+		{name: "port zero below min", raw: map[string]string{"email": "a@example.com", "port": "0"}, params: ValidatedParams{Email: "a@example.com"}, wantErr: "port must be between 1 and 65535"},
+		{name: "days zero not in enum", raw: map[string]string{"email": "a@example.com", "days": "[]"}, params: ValidatedParams{Email: "a@example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProvidedParams(tt.params, tt.raw)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateProvidedParams() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ValidateProvidedParams() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecutor_ValidateParams_ZeroPort(t *testing.T) {
+	registry := NewRegistry()
+	executed := false
+	_ = registry.Register(&MockFunction{
+		name:       "set_port",
+		paramsType: reflect.TypeOf(ValidatedParams{}),
+		executeFunc: func(ctx context.Context, params any) (Result, error) {
+			executed = true
+			return Result{Message: "ok"}, nil
+		},
+	})
+
+	executor := NewExecutor(registry, 0)
+	resp := executor.Execute(context.Background(), ExecuteRequest{
+		FunctionName: "set_port",
+		Params:       map[string]string{"email": "a@example.com", "port": "0"},
+	})
+
+	if resp.Error == nil || !strings.Contains(resp.Error.Error(), "port must be between 1 and 65535") {
+		t.Errorf("Execute() error = %v, want port range error", resp.Error)
+	}
+	if executed {
+		t.Error("Function should not be executed when validation fails")
+	}
+}
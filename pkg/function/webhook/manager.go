@@ -0,0 +1,159 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/storage"
+)
+
+// 错误定义
+var (
+	ErrNotFound     = errors.New("webhook function not found")
+	ErrNameConflict = errors.New("function name already registered")
+	ErrNotWebhook   = errors.New("function is not a webhook function")
+)
+
+// Manager 管理 webhook 函数的注册、注销和重启恢复
+type Manager struct {
+	db       *gorm.DB
+	registry *function.Registry
+	guard    Guard
+	client   *http.Client
+	logger   *slog.Logger // 为 nil 时使用全局 logger
+
+	mu    sync.Mutex
+	names map[string]bool // 已注册的 webhook 函数名
+}
+
+// NewManager 创建 webhook 函数管理器
+func NewManager(db *gorm.DB, registry *function.Registry, guard Guard) *Manager {
+	return &Manager{
+		db:       db,
+		registry: registry,
+		guard:    guard,
+		client:   guard.HTTPClient(),
+		names:    make(map[string]bool),
+	}
+}
+
+// SetLogger 设置恢复 webhook 函数时使用的 logger，需在 Start 之前调用
+func (m *Manager) SetLogger(logger *slog.Logger) {
+	m.logger = logger
+}
+
+// log 返回 Manager 的 logger
+func (m *Manager) log() *slog.Logger {
+	if m.logger != nil {
+		return m.logger
+	}
+	return observability.DefaultLogger()
+}
+
+// Start 迁移表结构并恢复已持久化的 webhook 函数
+func (m *Manager) Start() error {
+	if err := storage.NewMigrator(m.db, "webhook", migrations...).Migrate(); err != nil {
+		return fmt.Errorf("failed to migrate webhook_functions table: %w", err)
+	}
+
+	var defs []Definition
+	if err := m.db.Order("id ASC").Find(&defs).Error; err != nil {
+		return fmt.Errorf("failed to load webhook functions: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, def := range defs {
+		if m.registry.Has(def.Name) {
+			m.log().Warn("Skip webhook function, name already registered", "name", def.Name)
+			continue
+		}
+		if err := m.registry.Register(newFunction(def, m.client)); err != nil {
+			m.log().Warn("Failed to restore webhook function", "name", def.Name, "error", err)
+			continue
+		}
+		m.names[def.Name] = true
+	}
+
+	m.log().Info("Webhook functions restored", "count", len(m.names))
+	return nil
+}
+
+// Register 校验、持久化并注册 webhook 函数
+// 同名的 webhook 函数会被更新；与内置或代码注册的函数重名时返回 ErrNameConflict
+func (m *Manager) Register(ctx context.Context, def Definition) (*Definition, error) {
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	if err := m.guard.CheckURL(ctx, def.URL); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.registry.Has(def.Name) && !m.names[def.Name] {
+		return nil, fmt.Errorf("%w: %s", ErrNameConflict, def.Name)
+	}
+
+	// 持久化（同名则更新）
+	var existing Definition
+	err := m.db.Where("name = ?", def.Name).First(&existing).Error
+	switch {
+	case err == nil:
+		def.ID = existing.ID
+		def.CreatedAt = existing.CreatedAt
+		if err := m.db.Save(&def).Error; err != nil {
+			return nil, err
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if err := m.db.Create(&def).Error; err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := m.registry.Register(newFunction(def, m.client)); err != nil {
+		return nil, err
+	}
+	m.names[def.Name] = true
+
+	return &def, nil
+}
+
+// Unregister 注销并删除 webhook 函数
+func (m *Manager) Unregister(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.names[name] {
+		if m.registry.Has(name) {
+			return fmt.Errorf("%w: %s", ErrNotWebhook, name)
+		}
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	if err := m.db.Where("name = ?", name).Delete(&Definition{}).Error; err != nil {
+		return err
+	}
+	m.registry.Unregister(name)
+	delete(m.names, name)
+	return nil
+}
+
+// List 列出所有 webhook 函数定义
+func (m *Manager) List() ([]Definition, error) {
+	var defs []Definition
+	err := m.db.Order("id ASC").Find(&defs).Error
+	return defs, err
+}
@@ -0,0 +1,271 @@
+// Package webhook 提供通过 HTTP 动态注册的外部函数（webhook 函数）
+// 调用时框架把参数以 JSON POST 到目标 URL，把返回的 JSON 包装为 function.Result
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/storage"
+)
+
+// 超时限制
+const (
+	DefaultTimeout = 10 * time.Second
+	MaxTimeout     = 60 * time.Second
+)
+
+// maxResponseSize 响应体最大读取字节数
+const maxResponseSize = 1 << 20
+
+// namePattern 函数名和参数名的命名规范
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ParamSpec 参数定义
+type ParamSpec struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"` // string, integer, number, boolean
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Default     string `json:"default,omitempty"`
+}
+
+// Definition webhook 函数定义（持久化）
+type Definition struct {
+	ID          uint        `gorm:"primarykey" json:"id"`
+	Name        string      `gorm:"not null;uniqueIndex" json:"name"`
+	Description string      `gorm:"type:text" json:"description"`
+	URL         string      `gorm:"not null" json:"url"`
+	Params      []ParamSpec `gorm:"serializer:json;type:text" json:"params,omitempty"`
+	TimeoutSec  int         `json:"timeout_sec,omitempty"` // 调用超时（秒），0 表示默认 10 秒
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Definition) TableName() string {
+	return "webhook_functions"
+}
+
+// migrations webhook 函数定义表的迁移，schema 变化时在末尾追加新版本
+var migrations = []storage.Migration{
+	{
+		Version: 1,
+		Name:    "create webhook_functions",
+		Up:      storage.CreateTables(&Definition{}),
+		Down:    storage.DropTables(&Definition{}),
+	},
+}
+
+// Timeout 返回生效的调用超时
+func (d *Definition) Timeout() time.Duration {
+	if d.TimeoutSec <= 0 {
+		return DefaultTimeout
+	}
+	timeout := time.Duration(d.TimeoutSec) * time.Second
+	if timeout > MaxTimeout {
+		return MaxTimeout
+	}
+	return timeout
+}
+
+// Validate 校验定义的名称、参数和超时
+// URL 的安全校验由 Guard 负责
+func (d *Definition) Validate() error {
+	if !namePattern.MatchString(d.Name) {
+		return fmt.Errorf("invalid function name %q: must match %s", d.Name, namePattern)
+	}
+	if strings.TrimSpace(d.Description) == "" {
+		return fmt.Errorf("description is required")
+	}
+	if d.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if d.TimeoutSec < 0 || time.Duration(d.TimeoutSec)*time.Second > MaxTimeout {
+		return fmt.Errorf("timeout_sec must be between 0 and %d", int(MaxTimeout/time.Second))
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range d.Params {
+		if !namePattern.MatchString(p.Name) {
+			return fmt.Errorf("invalid param name %q: must match %s", p.Name, namePattern)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("duplicate param name %q", p.Name)
+		}
+		seen[p.Name] = true
+		if _, ok := paramKinds[p.Type]; !ok {
+			return fmt.Errorf("unsupported type %q for param %q", p.Type, p.Name)
+		}
+	}
+	return nil
+}
+
+// paramKinds 支持的参数类型
+var paramKinds = map[string]reflect.Type{
+	"string":  reflect.TypeOf(""),
+	"integer": reflect.TypeOf(int64(0)),
+	"number":  reflect.TypeOf(float64(0)),
+	"boolean": reflect.TypeOf(false),
+}
+
+// Function 把 Definition 适配为 function.Function
+type Function struct {
+	def        Definition
+	paramsType reflect.Type
+	client     *http.Client
+}
+
+// newFunction 根据定义创建 webhook 函数
+func newFunction(def Definition, client *http.Client) *Function {
+	return &Function{
+		def:        def,
+		paramsType: buildParamsType(def.Params),
+		client:     client,
+	}
+}
+
+// buildParamsType 根据参数定义动态构建结构体类型
+// 这样 Schema 提取、参数解析和校验都能复用已有的反射逻辑
+func buildParamsType(params []ParamSpec) reflect.Type {
+	if len(params) == 0 {
+		return nil
+	}
+
+	fields := make([]reflect.StructField, 0, len(params))
+	for i, p := range params {
+		tag := fmt.Sprintf(`json:"%s"`, p.Name)
+		if p.Description != "" {
+			tag += fmt.Sprintf(` desc:%q`, p.Description)
+		}
+		if p.Required {
+			tag += ` required:"true"`
+		}
+		if p.Default != "" {
+			tag += fmt.Sprintf(` default:%q`, p.Default)
+		}
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("P%d", i),
+			Type: paramKinds[p.Type],
+			Tag:  reflect.StructTag(tag),
+		})
+	}
+	return reflect.StructOf(fields)
+}
+
+// Name 返回函数名称
+func (f *Function) Name() string {
+	return f.def.Name
+}
+
+// Description 返回函数描述
+func (f *Function) Description() string {
+	return f.def.Description
+}
+
+// ParamsType 返回动态构建的参数类型
+func (f *Function) ParamsType() reflect.Type {
+	return f.paramsType
+}
+
+// Timeout 实现 function.TimeoutFunction，Executor 使用 webhook 自己的超时
+func (f *Function) Timeout() time.Duration {
+	return f.def.Timeout()
+}
+
+// Definition 返回函数定义
+func (f *Function) Definition() Definition {
+	return f.def
+}
+
+// Execute 把参数 POST 到目标 URL，并把 JSON 响应包装为 Result
+func (f *Function) Execute(ctx context.Context, params any) (function.Result, error) {
+	body := []byte("{}")
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return function.Result{}, fmt.Errorf("failed to marshal params: %w", err)
+		}
+		body = b
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.def.Timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.def.URL, bytes.NewReader(body))
+	if err != nil {
+		return function.Result{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-AgentChassis-Function", f.def.Name)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return function.Result{}, fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return function.Result{}, fmt.Errorf("failed to read webhook response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, truncate(string(respBody), 200))
+		// 5xx 和 429 通常是上游的临时故障，其余 4xx 重试无意义
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return function.Result{}, function.WithErrorClass(err, function.ErrorClassTransient)
+		}
+		return function.Result{}, function.WithErrorClass(err, function.ErrorClassPermanent)
+	}
+
+	return decodeResult(respBody), nil
+}
+
+// decodeResult 解析 webhook 响应
+// JSON 对象中的 message/markdown 字段会映射到 Result 对应字段，其余作为 Data
+// 非 JSON 响应按纯文本作为 Message 返回
+func decodeResult(body []byte) function.Result {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return function.Result{Message: "ok"}
+	}
+
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return function.Result{Message: truncate(string(body), 2000)}
+	}
+
+	result := function.Result{Data: data}
+	if obj, ok := data.(map[string]any); ok {
+		if msg, ok := obj["message"].(string); ok {
+			result.Message = msg
+			delete(obj, "message")
+		}
+		if md, ok := obj["markdown"].(string); ok {
+			result.Markdown = md
+			delete(obj, "markdown")
+		}
+		if len(obj) == 0 {
+			result.Data = nil
+		}
+	}
+	return result
+}
+
+// truncate 截断过长的文本
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
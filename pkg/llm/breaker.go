@@ -0,0 +1,252 @@
+// Package llm 提供 LLM 适配层接口和实现
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// ErrCircuitOpen 断路器打开，请求未发送直接失败
+var ErrCircuitOpen = errors.New("llm circuit breaker is open")
+
+// DefaultBreakerOpenTimeout 断路器打开后进入半开状态前的默认冷却时间
+const DefaultBreakerOpenTimeout = 30 * time.Second
+
+// BreakerState 断路器状态
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // 正常放行请求
+	BreakerOpen     BreakerState = "open"      // 连续失败后快速失败，不发请求
+	BreakerHalfOpen BreakerState = "half_open" // 冷却结束，放行一个试探请求
+)
+
+// BreakerConfig 断路器配置
+type BreakerConfig struct {
+	// FailureThreshold 连续失败多少次后打开断路器，0 表示不启用断路器
+	FailureThreshold int `mapstructure:"failure_threshold"`
+
+	// OpenTimeout 断路器打开后多久进入半开状态试探恢复，默认 30s
+	OpenTimeout time.Duration `mapstructure:"open_timeout"`
+}
+
+// CircuitBreaker 包装 Provider 的断路器
+// 连续失败 FailureThreshold 次后打开，OpenTimeout 内的请求直接返回 ErrCircuitOpen；
+// 之后进入半开状态，只放行一个试探请求，成功则关闭，失败则重新打开
+type CircuitBreaker struct {
+	provider Provider
+	config   BreakerConfig
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int       // 连续失败次数
+	openedAt time.Time // 最近一次打开的时间
+	probing  bool      // 半开状态下是否已有试探请求在途
+}
+
+// 确保 CircuitBreaker 实现了 Provider 和 Pinger 接口
+var (
+	_ Provider = (*CircuitBreaker)(nil)
+	_ Pinger   = (*CircuitBreaker)(nil)
+)
+
+// NewCircuitBreaker 创建断路器
+func NewCircuitBreaker(provider Provider, config BreakerConfig) *CircuitBreaker {
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = DefaultBreakerOpenTimeout
+	}
+	return &CircuitBreaker{
+		provider: provider,
+		config:   config,
+		state:    BreakerClosed,
+	}
+}
+
+// State 返回断路器当前状态
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.config.OpenTimeout {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Unwrap 返回被包装的 Provider
+func (b *CircuitBreaker) Unwrap() Provider {
+	return b.provider
+}
+
+// Name 返回提供商名称
+func (b *CircuitBreaker) Name() string {
+	return b.provider.Name()
+}
+
+// Chat 发送对话请求
+func (b *CircuitBreaker) Chat(ctx context.Context, messages []Message) (string, error) {
+	if err := b.allow(ctx); err != nil {
+		return "", err
+	}
+	content, err := b.provider.Chat(ctx, messages)
+	b.record(ctx, err)
+	return content, err
+}
+
+// ChatWithOptions 发送对话请求，并按 opts 覆盖本次请求的模型参数
+func (b *CircuitBreaker) ChatWithOptions(ctx context.Context, messages []Message, opts ChatOptions) (string, error) {
+	if err := b.allow(ctx); err != nil {
+		return "", err
+	}
+	content, err := b.provider.ChatWithOptions(ctx, messages, opts)
+	b.record(ctx, err)
+	return content, err
+}
+
+// ChatWithTools 发送带原生工具定义的对话请求
+func (b *CircuitBreaker) ChatWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, opts ChatOptions) (Message, error) {
+	if err := b.allow(ctx); err != nil {
+		return Message{}, err
+	}
+	msg, err := b.provider.ChatWithTools(ctx, messages, tools, opts)
+	b.record(ctx, err)
+	return msg, err
+}
+
+// ChatStream 发送流式对话请求，流中途出错同样计为一次失败
+func (b *CircuitBreaker) ChatStream(ctx context.Context, messages []Message) (<-chan StreamChunk, error) {
+	if err := b.allow(ctx); err != nil {
+		return nil, err
+	}
+	chunks, err := b.provider.ChatStream(ctx, messages)
+	if err != nil {
+		b.record(ctx, err)
+		return nil, err
+	}
+
+	out := make(chan StreamChunk)
+	go func() {
+		defer close(out)
+		var streamErr error
+		for chunk := range chunks {
+			if chunk.Error != nil {
+				streamErr = chunk.Error
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				// 调用方已不再读取，继续排空上游避免其阻塞
+			}
+		}
+		b.record(ctx, streamErr)
+	}()
+	return out, nil
+}
+
+// Ping 透传健康探测，不经过断路器，探测结果也不计入失败次数
+func (b *CircuitBreaker) Ping(ctx context.Context) error {
+	return Ping(ctx, b.provider, 0)
+}
+
+// allow 判断是否放行请求
+func (b *CircuitBreaker) allow(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		remaining := b.config.OpenTimeout - time.Since(b.openedAt)
+		if remaining > 0 {
+			return fmt.Errorf("%w: retry in %s", ErrCircuitOpen, remaining.Round(time.Second))
+		}
+		b.transition(ctx, BreakerHalfOpen)
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return fmt.Errorf("%w: waiting for the recovery probe", ErrCircuitOpen)
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record 记录请求结果并更新状态
+// 调用方取消和请求参数错误不代表服务不可用，不计入失败
+func (b *CircuitBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil && !countsAsFailure(ctx, err) {
+		if b.state == BreakerHalfOpen {
+			// 试探请求没有得出结论，允许下一个请求继续试探
+			b.probing = false
+		}
+		return
+	}
+
+	if err == nil {
+		b.failures = 0
+		b.probing = false
+		if b.state != BreakerClosed {
+			b.transition(ctx, BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	switch {
+	case b.state == BreakerHalfOpen:
+		b.probing = false
+		b.openedAt = time.Now()
+		b.transition(ctx, BreakerOpen)
+	case b.state == BreakerClosed && b.failures >= b.config.FailureThreshold:
+		b.openedAt = time.Now()
+		b.transition(ctx, BreakerOpen)
+	}
+}
+
+// transition 切换状态并输出日志和结构化事件（写入 ctx 中的 logger 和 Emitter），调用方需持有锁
+func (b *CircuitBreaker) transition(ctx context.Context, to BreakerState) {
+	from := b.state
+	b.state = to
+
+	attrs := []any{
+		"provider", b.provider.Name(),
+		"from", from,
+		"to", to,
+		"consecutive_failures", b.failures,
+	}
+	if to == BreakerOpen {
+		observability.WarnContext(ctx, "LLM circuit breaker opened", append(attrs, "open_timeout", b.config.OpenTimeout)...)
+	} else {
+		observability.InfoContext(ctx, "LLM circuit breaker state changed", attrs...)
+	}
+	observability.EmitEventContext(ctx, observability.Event{
+		Kind:   observability.EventCircuitBreaker,
+		Name:   b.provider.Name(),
+		Status: string(to),
+		Attributes: map[string]any{
+			"from":                 string(from),
+			"consecutive_failures": b.failures,
+		},
+	})
+}
+
+// countsAsFailure 错误是否说明 Provider 不可用
+func countsAsFailure(ctx context.Context, err error) bool {
+	if ctx.Err() == context.Canceled || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.IsInvalidRequest() {
+		return false
+	}
+	return true
+}
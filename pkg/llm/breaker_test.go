@@ -0,0 +1,198 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubProvider 返回预设回复或错误的测试 Provider，并记录实际调用次数
+type stubProvider struct {
+	mu    sync.Mutex
+	err   error
+	reply string
+	calls int
+}
+
+func (p *stubProvider) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *stubProvider) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func (p *stubProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	return p.ChatWithOptions(ctx, messages, ChatOptions{})
+}
+
+func (p *stubProvider) ChatWithOptions(ctx context.Context, messages []Message, opts ChatOptions) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.err != nil {
+		return "", p.err
+	}
+	return p.reply, nil
+}
+
+func (p *stubProvider) ChatWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, opts ChatOptions) (Message, error) {
+	content, err := p.ChatWithOptions(ctx, messages, opts)
+	return Message{Role: RoleAssistant, Content: content}, err
+}
+
+func (p *stubProvider) ChatStream(ctx context.Context, messages []Message) (<-chan StreamChunk, error) {
+	content, err := p.ChatWithOptions(ctx, messages, ChatOptions{})
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan StreamChunk, 2)
+	ch <- StreamChunk{Content: content}
+	ch <- StreamChunk{Done: true}
+	close(ch)
+	return ch, nil
+}
+
+func (p *stubProvider) Name() string { return "stub" }
+
+var errUnavailable = &APIError{StatusCode: http.StatusServiceUnavailable, Message: "unavailable"}
+
+func TestCircuitBreaker_StateMachine(t *testing.T) {
+	stub := &stubProvider{err: errUnavailable}
+	b := NewCircuitBreaker(stub, BreakerConfig{FailureThreshold: 2, OpenTimeout: 50 * time.Millisecond})
+	ctx := context.Background()
+
+	// 未达阈值前保持关闭
+	if _, err := b.Chat(ctx, nil); !errors.Is(err, errUnavailable) {
+		t.Fatalf("first call: got %v", err)
+	}
+	if got := b.State(); got != BreakerClosed {
+		t.Fatalf("after 1 failure: state = %s, want closed", got)
+	}
+
+	// 达到阈值后打开，之后的请求不再发送
+	b.Chat(ctx, nil)
+	if got := b.State(); got != BreakerOpen {
+		t.Fatalf("after 2 failures: state = %s, want open", got)
+	}
+	if _, err := b.Chat(ctx, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open breaker: got %v, want ErrCircuitOpen", err)
+	}
+	if got := stub.callCount(); got != 2 {
+		t.Fatalf("provider called %d times, want 2", got)
+	}
+
+	// 冷却后半开，试探失败重新打开
+	time.Sleep(60 * time.Millisecond)
+	if got := b.State(); got != BreakerHalfOpen {
+		t.Fatalf("after timeout: state = %s, want half_open", got)
+	}
+	if _, err := b.Chat(ctx, nil); !errors.Is(err, errUnavailable) {
+		t.Fatalf("failed probe: got %v", err)
+	}
+	if got := b.State(); got != BreakerOpen {
+		t.Fatalf("after failed probe: state = %s, want open", got)
+	}
+
+	// 再次冷却后试探成功，断路器关闭
+	time.Sleep(60 * time.Millisecond)
+	stub.setErr(nil)
+	if _, err := b.Chat(ctx, nil); err != nil {
+		t.Fatalf("successful probe: %v", err)
+	}
+	if got := b.State(); got != BreakerClosed {
+		t.Fatalf("after successful probe: state = %s, want closed", got)
+	}
+}
+
+func TestCircuitBreaker_SingleProbe(t *testing.T) {
+	stub := &stubProvider{err: errUnavailable}
+	b := NewCircuitBreaker(stub, BreakerConfig{FailureThreshold: 1, OpenTimeout: 10 * time.Millisecond})
+	b.Chat(context.Background(), nil)
+	time.Sleep(20 * time.Millisecond)
+
+	// 第一个请求成为试探请求，试探在途时其余请求直接失败
+	if err := b.allow(context.Background()); err != nil {
+		t.Fatalf("probe not allowed: %v", err)
+	}
+	if err := b.allow(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second request during probe: got %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestCircuitBreaker_IgnoredErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  func() context.Context
+		err  error
+	}{
+		{
+			name: "cancelled by caller",
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			err: context.Canceled,
+		},
+		{
+			name: "wrapped cancel",
+			ctx:  context.Background,
+			err:  errors.Join(errors.New("request failed"), context.Canceled),
+		},
+		{
+			name: "bad request",
+			ctx:  context.Background,
+			err:  &APIError{StatusCode: http.StatusBadRequest, Message: "context too long"},
+		},
+		{
+			name: "model not found",
+			ctx:  context.Background,
+			err:  &APIError{StatusCode: http.StatusNotFound, Message: "no such model"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubProvider{err: tt.err}
+			b := NewCircuitBreaker(stub, BreakerConfig{FailureThreshold: 1})
+			for i := 0; i < 3; i++ {
+				b.Chat(tt.ctx(), nil)
+			}
+			if got := b.State(); got != BreakerClosed {
+				t.Errorf("state = %s, want closed", got)
+			}
+			if got := stub.callCount(); got != 3 {
+				t.Errorf("provider called %d times, want 3", got)
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker_IgnoredErrorReleasesProbe(t *testing.T) {
+	stub := &stubProvider{err: errUnavailable}
+	b := NewCircuitBreaker(stub, BreakerConfig{FailureThreshold: 1, OpenTimeout: 10 * time.Millisecond})
+	b.Chat(context.Background(), nil)
+	time.Sleep(20 * time.Millisecond)
+
+	// 试探请求因参数错误失败时不下结论，下一个请求可以继续试探
+	stub.setErr(&APIError{StatusCode: http.StatusBadRequest})
+	b.Chat(context.Background(), nil)
+	if got := b.State(); got != BreakerHalfOpen {
+		t.Fatalf("state = %s, want half_open", got)
+	}
+	stub.setErr(nil)
+	if _, err := b.Chat(context.Background(), nil); err != nil {
+		t.Fatalf("next probe: %v", err)
+	}
+	if got := b.State(); got != BreakerClosed {
+		t.Fatalf("state = %s, want closed", got)
+	}
+}
@@ -0,0 +1,278 @@
+// Package llm 提供 LLM 适配层接口和实现
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// 响应缓存模式
+const (
+	CacheModeOff    = "off"    // 不缓存
+	CacheModeAuto   = "auto"   // 只缓存 temperature=0 的确定性请求（默认）
+	CacheModeAlways = "always" // 缓存所有请求，适合开发调试时重放相同对话
+)
+
+// 响应缓存默认值
+const (
+	DefaultCacheTTL        = 10 * time.Minute
+	DefaultCacheMaxEntries = 1000
+)
+
+// CacheConfig 响应缓存配置
+type CacheConfig struct {
+	// Mode 缓存模式：off、auto（默认，仅 temperature=0 时缓存）、always
+	Mode string `mapstructure:"mode"`
+
+	// TTL 缓存有效期，默认 10m
+	TTL time.Duration `mapstructure:"ttl"`
+
+	// MaxEntries 最多缓存的响应数，超出时淘汰最久未使用的，默认 1000
+	MaxEntries int `mapstructure:"max_entries"`
+}
+
+// ValidateCacheMode 校验缓存模式，空字符串视为 auto
+func ValidateCacheMode(mode string) error {
+	switch mode {
+	case "", CacheModeOff, CacheModeAuto, CacheModeAlways:
+		return nil
+	default:
+		return fmt.Errorf("unsupported llm cache mode: %s (want %s, %s or %s)", mode, CacheModeOff, CacheModeAuto, CacheModeAlways)
+	}
+}
+
+// CacheStats 缓存命中统计
+type CacheStats struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"` // 命中次数占可缓存请求的比例，尚无请求时为 0
+	Entries int     `json:"entries"`
+}
+
+// CachingProvider 包装 Provider 的响应缓存
+// 按 messages、tools 以及生效的 model、temperature、max_tokens、response_format 计算哈希，
+// 相同请求在 TTL 内直接返回缓存的回复，不再请求上游；流式请求和失败的请求不缓存
+type CachingProvider struct {
+	provider Provider
+	config   CacheConfig
+	defaults ChatOptions // Provider 的默认模型参数，用于计算生效值
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// cachedResponse 缓存条目
+type cachedResponse struct {
+	key       string
+	message   Message
+	expiresAt time.Time
+}
+
+// 确保 CachingProvider 实现了 Provider 和 Pinger 接口
+var (
+	_ Provider = (*CachingProvider)(nil)
+	_ Pinger   = (*CachingProvider)(nil)
+)
+
+// NewCachingProvider 创建响应缓存，defaults 为被包装 Provider 的默认模型参数
+func NewCachingProvider(provider Provider, config CacheConfig, defaults ChatOptions) *CachingProvider {
+	if config.Mode == "" {
+		config.Mode = CacheModeAuto
+	}
+	if config.TTL <= 0 {
+		config.TTL = DefaultCacheTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultCacheMaxEntries
+	}
+	return &CachingProvider{
+		provider: provider,
+		config:   config,
+		defaults: defaults,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Unwrap 返回被包装的 Provider
+func (c *CachingProvider) Unwrap() Provider {
+	return c.provider
+}
+
+// Name 返回提供商名称
+func (c *CachingProvider) Name() string {
+	return c.provider.Name()
+}
+
+// Stats 返回缓存命中统计
+func (c *CachingProvider) Stats() CacheStats {
+	c.mu.Lock()
+	entries := c.ll.Len()
+	c.mu.Unlock()
+	stats := CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Entries: entries}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// Chat 发送对话请求
+func (c *CachingProvider) Chat(ctx context.Context, messages []Message) (string, error) {
+	return c.ChatWithOptions(ctx, messages, ChatOptions{})
+}
+
+// ChatWithOptions 发送对话请求，并按 opts 覆盖本次请求的模型参数
+func (c *CachingProvider) ChatWithOptions(ctx context.Context, messages []Message, opts ChatOptions) (string, error) {
+	msg, err := c.cached(ctx, messages, nil, opts, func() (Message, error) {
+		content, err := c.provider.ChatWithOptions(ctx, messages, opts)
+		return Message{Role: RoleAssistant, Content: content}, err
+	})
+	return msg.Content, err
+}
+
+// ChatWithTools 发送带原生工具定义的对话请求
+func (c *CachingProvider) ChatWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, opts ChatOptions) (Message, error) {
+	return c.cached(ctx, messages, tools, opts, func() (Message, error) {
+		return c.provider.ChatWithTools(ctx, messages, tools, opts)
+	})
+}
+
+// ChatStream 流式请求不缓存，直接透传
+func (c *CachingProvider) ChatStream(ctx context.Context, messages []Message) (<-chan StreamChunk, error) {
+	return c.provider.ChatStream(ctx, messages)
+}
+
+// Ping 透传健康探测
+func (c *CachingProvider) Ping(ctx context.Context) error {
+	return Ping(ctx, c.provider, 0)
+}
+
+// cached 命中时返回缓存的回复，否则调用 fetch 并缓存成功的结果
+func (c *CachingProvider) cached(ctx context.Context, messages []Message, tools []ToolDefinition, opts ChatOptions, fetch func() (Message, error)) (Message, error) {
+	effective := c.effective(opts)
+	if !c.cacheable(effective) {
+		return fetch()
+	}
+
+	key, err := cacheKey(messages, tools, effective)
+	if err != nil {
+		return fetch()
+	}
+
+	if msg, ok := c.get(key); ok {
+		c.hits.Add(1)
+		observability.InfoContext(ctx, "LLM cache hit", "provider", c.provider.Name(), "model", effective.Model, "key", key[:12])
+		observability.EmitEventContext(ctx, observability.Event{
+			Kind:       observability.EventLLMCache,
+			Name:       c.provider.Name(),
+			Status:     "hit",
+			Attributes: map[string]any{"model": effective.Model},
+		})
+		return msg, nil
+	}
+
+	c.misses.Add(1)
+	msg, err := fetch()
+	if err != nil {
+		return msg, err
+	}
+	c.set(key, msg)
+	return msg, nil
+}
+
+// effective 合并本次覆盖参数和 Provider 默认参数
+func (c *CachingProvider) effective(opts ChatOptions) ChatOptions {
+	if opts.Model == "" {
+		opts.Model = c.defaults.Model
+	}
+	if opts.Temperature == nil {
+		opts.Temperature = c.defaults.Temperature
+	}
+	if opts.MaxTokens == nil {
+		opts.MaxTokens = c.defaults.MaxTokens
+	}
+	if opts.ResponseFormat == "" {
+		opts.ResponseFormat = c.defaults.ResponseFormat
+	}
+	return opts
+}
+
+// cacheable 按缓存模式判断请求是否可缓存
+func (c *CachingProvider) cacheable(opts ChatOptions) bool {
+	switch c.config.Mode {
+	case CacheModeAlways:
+		return true
+	case CacheModeAuto:
+		return opts.Temperature != nil && *opts.Temperature == 0
+	default:
+		return false
+	}
+}
+
+// get 读取未过期的缓存条目
+func (c *CachingProvider) get(key string) (Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return Message{}, false
+	}
+	entry := elem.Value.(*cachedResponse)
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return Message{}, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.message, true
+}
+
+// set 写入缓存，超出上限时淘汰最久未使用的条目
+func (c *CachingProvider) set(key string, msg Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.config.TTL)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*cachedResponse)
+		entry.message = msg
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&cachedResponse{key: key, message: msg, expiresAt: expiresAt})
+	for c.ll.Len() > c.config.MaxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// cacheKey 计算请求的缓存键
+func cacheKey(messages []Message, tools []ToolDefinition, opts ChatOptions) (string, error) {
+	b, err := json.Marshal(struct {
+		Messages []Message        `json:"messages"`
+		Tools    []ToolDefinition `json:"tools,omitempty"`
+		Options  ChatOptions      `json:"options"`
+	}{messages, tools, opts})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
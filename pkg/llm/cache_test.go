@@ -0,0 +1,187 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func userMessages(content string) []Message {
+	return []Message{{Role: RoleUser, Content: content}}
+}
+
+func TestCachingProvider_Modes(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		temperature *float64
+		wantCalls   int
+	}{
+		{"auto caches deterministic requests", CacheModeAuto, floatPtr(0), 1},
+		{"auto skips sampled requests", CacheModeAuto, floatPtr(0.7), 2},
+		{"auto skips unset temperature", CacheModeAuto, nil, 2},
+		{"always caches sampled requests", CacheModeAlways, floatPtr(0.7), 1},
+		{"off never caches", CacheModeOff, floatPtr(0), 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubProvider{reply: "hi"}
+			c := NewCachingProvider(stub, CacheConfig{Mode: tt.mode}, ChatOptions{Model: "m", Temperature: tt.temperature})
+			for i := 0; i < 2; i++ {
+				got, err := c.Chat(context.Background(), userMessages("hello"))
+				if err != nil || got != "hi" {
+					t.Fatalf("Chat() = %q, %v", got, err)
+				}
+			}
+			if got := stub.callCount(); got != tt.wantCalls {
+				t.Errorf("provider called %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCachingProvider_PerRequestTemperature(t *testing.T) {
+	stub := &stubProvider{reply: "hi"}
+	c := NewCachingProvider(stub, CacheConfig{Mode: CacheModeAuto}, ChatOptions{Model: "m", Temperature: floatPtr(0.7)})
+
+	// 本次请求覆盖为 temperature=0 时按生效值判断，可以缓存
+	opts := ChatOptions{Temperature: floatPtr(0)}
+	c.ChatWithOptions(context.Background(), userMessages("hello"), opts)
+	c.ChatWithOptions(context.Background(), userMessages("hello"), opts)
+	if got := stub.callCount(); got != 1 {
+		t.Errorf("provider called %d times, want 1", got)
+	}
+}
+
+func TestCachingProvider_ErrorsNotCached(t *testing.T) {
+	stub := &stubProvider{err: errors.New("boom")}
+	c := NewCachingProvider(stub, CacheConfig{Mode: CacheModeAlways}, ChatOptions{})
+
+	if _, err := c.Chat(context.Background(), userMessages("hello")); err == nil {
+		t.Fatal("expected error")
+	}
+	stub.setErr(nil)
+	stub.reply = "ok"
+	got, err := c.Chat(context.Background(), userMessages("hello"))
+	if err != nil || got != "ok" {
+		t.Fatalf("Chat() = %q, %v, want fresh reply", got, err)
+	}
+	if got := stub.callCount(); got != 2 {
+		t.Errorf("provider called %d times, want 2", got)
+	}
+}
+
+func TestCachingProvider_TTL(t *testing.T) {
+	stub := &stubProvider{reply: "hi"}
+	c := NewCachingProvider(stub, CacheConfig{Mode: CacheModeAlways, TTL: 20 * time.Millisecond}, ChatOptions{})
+
+	c.Chat(context.Background(), userMessages("hello"))
+	c.Chat(context.Background(), userMessages("hello"))
+	if got := stub.callCount(); got != 1 {
+		t.Fatalf("before expiry: provider called %d times, want 1", got)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	c.Chat(context.Background(), userMessages("hello"))
+	if got := stub.callCount(); got != 2 {
+		t.Errorf("after expiry: provider called %d times, want 2", got)
+	}
+}
+
+func TestCachingProvider_LRU(t *testing.T) {
+	stub := &stubProvider{reply: "hi"}
+	c := NewCachingProvider(stub, CacheConfig{Mode: CacheModeAlways, MaxEntries: 2}, ChatOptions{})
+	ctx := context.Background()
+
+	c.Chat(ctx, userMessages("a"))
+	c.Chat(ctx, userMessages("b"))
+	c.Chat(ctx, userMessages("a")) // 命中，a 变为最近使用
+	c.Chat(ctx, userMessages("c")) // 淘汰最久未使用的 b
+	if got := stub.callCount(); got != 3 {
+		t.Fatalf("provider called %d times, want 3", got)
+	}
+
+	c.Chat(ctx, userMessages("a"))
+	if got := stub.callCount(); got != 3 {
+		t.Errorf("a was evicted: provider called %d times, want 3", got)
+	}
+	c.Chat(ctx, userMessages("b"))
+	if got := stub.callCount(); got != 4 {
+		t.Errorf("b was not evicted: provider called %d times, want 4", got)
+	}
+	if got := c.Stats().Entries; got != 2 {
+		t.Errorf("entries = %d, want 2", got)
+	}
+}
+
+func TestCachingProvider_Stats(t *testing.T) {
+	stub := &stubProvider{reply: "hi"}
+	c := NewCachingProvider(stub, CacheConfig{Mode: CacheModeAlways}, ChatOptions{})
+	if got := c.Stats(); got.HitRate != 0 {
+		t.Errorf("empty cache hit rate = %v, want 0", got.HitRate)
+	}
+
+	for i := 0; i < 4; i++ {
+		c.Chat(context.Background(), userMessages("hello"))
+	}
+	got := c.Stats()
+	if got.Hits != 3 || got.Misses != 1 || got.Entries != 1 {
+		t.Errorf("Stats() = %+v, want 3 hits, 1 miss, 1 entry", got)
+	}
+	if got.HitRate != 0.75 {
+		t.Errorf("hit rate = %v, want 0.75", got.HitRate)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	base := ChatOptions{Model: "m", Temperature: floatPtr(0)}
+	key := func(messages []Message, tools []ToolDefinition, opts ChatOptions) string {
+		t.Helper()
+		k, err := cacheKey(messages, tools, opts)
+		if err != nil {
+			t.Fatalf("cacheKey: %v", err)
+		}
+		return k
+	}
+	want := key(userMessages("hello"), nil, base)
+
+	// 相同请求的键稳定，与指针地址无关
+	if got := key(userMessages("hello"), nil, ChatOptions{Model: "m", Temperature: floatPtr(0)}); got != want {
+		t.Errorf("same request produced a different key")
+	}
+
+	different := map[string]string{
+		"message":         key(userMessages("hello!"), nil, base),
+		"role":            key([]Message{{Role: RoleSystem, Content: "hello"}}, nil, base),
+		"model":           key(userMessages("hello"), nil, ChatOptions{Model: "other", Temperature: floatPtr(0)}),
+		"temperature":     key(userMessages("hello"), nil, ChatOptions{Model: "m", Temperature: floatPtr(0.5)}),
+		"response format": key(userMessages("hello"), nil, ChatOptions{Model: "m", Temperature: floatPtr(0), ResponseFormat: ResponseFormatJSON}),
+		"tools":           key(userMessages("hello"), []ToolDefinition{{Name: "get_time"}}, base),
+	}
+	for name, got := range different {
+		if got == want {
+			t.Errorf("changing %s did not change the key", name)
+		}
+	}
+}
+
+func TestCachingProvider_DefaultsInKey(t *testing.T) {
+	stub := &stubProvider{reply: "hi"}
+	c := NewCachingProvider(stub, CacheConfig{Mode: CacheModeAlways}, ChatOptions{Model: "m"})
+
+	// 显式传入默认模型与不传等价，应命中同一条缓存
+	c.ChatWithOptions(context.Background(), userMessages("hello"), ChatOptions{})
+	c.ChatWithOptions(context.Background(), userMessages("hello"), ChatOptions{Model: "m"})
+	if got := stub.callCount(); got != 1 {
+		t.Errorf("provider called %d times, want 1", got)
+	}
+
+	c.ChatWithOptions(context.Background(), userMessages("hello"), ChatOptions{Model: "other"})
+	if got := stub.callCount(); got != 2 {
+		t.Errorf("model override shared the cache entry: provider called %d times, want 2", got)
+	}
+}
@@ -3,6 +3,7 @@ package llm
 
 import (
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -14,8 +15,8 @@ func LoadConfigFromEnv() Config {
 		BaseURL:     getEnv("AC_LLM_BASE_URL", "https://api.openai.com/v1"),
 		Model:       getEnv("AC_LLM_MODEL", "gpt-4"),
 		Timeout:     getEnvInt("AC_LLM_TIMEOUT", 60),
-		MaxTokens:   getEnvInt("AC_LLM_MAX_TOKENS", 4096),
-		Temperature: getEnvFloat("AC_LLM_TEMPERATURE", 0.7),
+		MaxTokens:   Int(getEnvInt("AC_LLM_MAX_TOKENS", 4096)),
+		Temperature: Float64(getEnvFloat("AC_LLM_TEMPERATURE", 0.7)),
 	}
 	return cfg
 }
@@ -71,26 +72,9 @@ func getEnvFloat(key string, defaultVal float64) float64 {
 	if val == "" {
 		return defaultVal
 	}
-	// 简单解析
-	var result float64
-	var decimal float64 = 0
-	var decimalPlace float64 = 1
-	inDecimal := false
-
-	for _, c := range val {
-		if c == '.' {
-			inDecimal = true
-		} else if c >= '0' && c <= '9' {
-			if inDecimal {
-				decimalPlace *= 10
-				decimal += float64(c-'0') / decimalPlace
-			} else {
-				result = result*10 + float64(c-'0')
-			}
-		}
-	}
-	result += decimal
-	if result == 0 {
+	// 0 是合法取值（如 Temperature=0 表示确定性输出），只有解析失败才使用默认值
+	result, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+	if err != nil {
 		return defaultVal
 	}
 	return result
@@ -104,7 +88,7 @@ func (c *Config) Validate() error {
 	if c.Model == "" {
 		return ErrMissingModel
 	}
-	return nil
+	return ValidateResponseFormatFor(c.ResponseFormat, c.CallMode)
 }
 
 // WithAPIKey 设置 API Key
@@ -0,0 +1,57 @@
+// Package llm 提供 LLM 适配层接口和实现
+package llm
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// APIError LLM 服务返回的非 200 错误
+// 调用方可用 errors.As 取出后按类型处理：鉴权失败提示更换 API Key，限流退避重试，参数错误修正请求
+type APIError struct {
+	StatusCode int    // HTTP 状态码
+	Type       string // 错误类型，如 invalid_request_error、rate_limit_error
+	Code       string // 错误码，如 invalid_api_key、rate_limit_exceeded
+	Message    string // 错误描述
+}
+
+// Error 实现 error 接口
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("API error (status %d, code %s): %s", e.StatusCode, e.Code, msg)
+	}
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, msg)
+}
+
+// IsAuth 是否为鉴权错误（API Key 无效或无权限），重试无意义
+func (e *APIError) IsAuth() bool {
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// IsRateLimited 是否被限流（或额度不足），应退避后重试
+func (e *APIError) IsRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsInvalidRequest 是否为请求参数错误（如模型不存在、上下文超长），需要修正请求而不是重试
+func (e *APIError) IsInvalidRequest() bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// IsServerError 是否为服务端错误
+func (e *APIError) IsServerError() bool {
+	return e.StatusCode >= http.StatusInternalServerError
+}
+
+// Retryable 是否值得重试：限流和服务端错误通常是暂时的
+func (e *APIError) Retryable() bool {
+	return e.IsRateLimited() || e.IsServerError()
+}
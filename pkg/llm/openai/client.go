@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -28,8 +29,14 @@ type Config struct {
 	BaseURL     string
 	Model       string
 	Timeout     time.Duration
-	MaxTokens   int
-	Temperature float64
+	MaxTokens   *int     // nil 时不发送 max_tokens
+	Temperature *float64 // nil 时不发送 temperature，0 会被显式发送
+
+	// ResponseFormat 默认输出格式，为 llm.ResponseFormatJSON 时要求模型输出 JSON 对象，为空时不发送
+	ResponseFormat string
+
+	// RoleConstraints 后端模型对消息角色顺序的要求，OpenAI 本身不要求，零值即可
+	RoleConstraints llm.RoleConstraints
 }
 
 // DefaultConfig 返回默认配置
@@ -38,8 +45,8 @@ func DefaultConfig() *Config {
 		BaseURL:     "https://api.openai.com/v1",
 		Model:       "gpt-4",
 		Timeout:     60 * time.Second,
-		MaxTokens:   4096,
-		Temperature: 0.7,
+		MaxTokens:   llm.Int(4096),
+		Temperature: llm.Float64(0.7),
 	}
 }
 
@@ -65,6 +72,7 @@ func NewProvider(cfg *Config) *Provider {
 
 // NewProviderFromLLMConfig 从通用 LLM 配置创建 Provider
 func NewProviderFromLLMConfig(cfg llm.Config) *Provider {
+	alternate := cfg.AlternateRoles != nil && *cfg.AlternateRoles
 	return NewProvider(&Config{
 		APIKey:      cfg.APIKey,
 		BaseURL:     cfg.BaseURL,
@@ -72,6 +80,10 @@ func NewProviderFromLLMConfig(cfg llm.Config) *Provider {
 		Timeout:     time.Duration(cfg.Timeout) * time.Second,
 		MaxTokens:   cfg.MaxTokens,
 		Temperature: cfg.Temperature,
+
+		ResponseFormat: cfg.ResponseFormat,
+
+		RoleConstraints: llm.RoleConstraints{Alternate: alternate, UserFirst: alternate},
 	})
 }
 
@@ -80,28 +92,49 @@ func (p *Provider) Name() string {
 	return "openai"
 }
 
+// RoleConstraints 返回配置的角色约束，实现 llm.RoleConstrainer
+func (p *Provider) RoleConstraints() llm.RoleConstraints {
+	return p.config.RoleConstraints
+}
+
 // Chat 发送对话请求
 func (p *Provider) Chat(ctx context.Context, messages []llm.Message) (string, error) {
+	return p.ChatWithOptions(ctx, messages, llm.ChatOptions{})
+}
+
+// ChatWithOptions 发送对话请求，opts 覆盖本次请求的模型参数
+func (p *Provider) ChatWithOptions(ctx context.Context, messages []llm.Message, opts llm.ChatOptions) (string, error) {
+	reply, err := p.ChatWithTools(ctx, messages, nil, opts)
+	if err != nil {
+		return "", err
+	}
+	return reply.Content, nil
+}
+
+// ChatWithTools 发送带工具定义的对话请求，返回包含 tool_calls 的完整回复
+func (p *Provider) ChatWithTools(ctx context.Context, messages []llm.Message, tools []llm.ToolDefinition, opts llm.ChatOptions) (llm.Message, error) {
 	start := time.Now()
-	observability.LLMRequestLog(ctx, p.Name(), p.config.Model, len(messages))
 
 	// 构建请求
-	reqBody := chatRequest{
-		Model:       p.config.Model,
-		Messages:    convertMessages(messages),
-		MaxTokens:   p.config.MaxTokens,
-		Temperature: p.config.Temperature,
-	}
+	reqBody := p.newChatRequest(messages, opts)
+	reqBody.Tools = convertTools(tools)
+
+	observability.LLMRequestLog(ctx, p.Name(), reqBody.Model, len(messages))
+	logRequestContent(ctx, p.Name(), messages)
 
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return llm.Message{}, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// 创建 HTTP 请求
-	req, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/chat/completions", bytes.NewReader(bodyBytes))
+	endpoint, err := p.endpoint("chat/completions")
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return llm.Message{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return llm.Message{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -110,34 +143,44 @@ func (p *Provider) Chat(ctx context.Context, messages []llm.Message) (string, er
 	// 发送请求
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return llm.Message{}, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return llm.Message{}, fmt.Errorf("failed to read response: %w", err)
 	}
+	observability.LLMResponseContentLog(ctx, p.Name(), string(respBody))
 
 	// 检查状态码
 	if resp.StatusCode != http.StatusOK {
-		var errResp errorResponse
-		json.Unmarshal(respBody, &errResp)
-		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, errResp.Error.Message)
+		return llm.Message{}, newAPIError(resp.StatusCode, respBody)
 	}
 
 	// 解析响应
 	var chatResp chatResponse
 	if err := json.Unmarshal(respBody, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return llm.Message{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+		return llm.Message{}, fmt.Errorf("no choices in response")
 	}
 
-	content := chatResp.Choices[0].Message.Content
+	choice := chatResp.Choices[0]
+	reply := llm.Message{
+		Role:    llm.RoleAssistant,
+		Content: choice.Message.Content,
+	}
+	for _, tc := range choice.Message.ToolCalls {
+		reply.ToolCalls = append(reply.ToolCalls, llm.ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		})
+	}
 	duration := time.Since(start)
 
 	// 记录响应日志
@@ -146,22 +189,65 @@ func (p *Provider) Chat(ctx context.Context, messages []llm.Message) (string, er
 		"completion": chatResp.Usage.CompletionTokens,
 		"total":      chatResp.Usage.TotalTokens,
 	})
+	llm.RecordUsage(ctx, llm.Usage{
+		PromptTokens:     chatResp.Usage.PromptTokens,
+		CompletionTokens: chatResp.Usage.CompletionTokens,
+		TotalTokens:      chatResp.Usage.TotalTokens,
+	})
+
+	return reply, nil
+}
+
+// endpoint 在 BaseURL 后拼接 API 路径
+// 兼容带尾斜杠或子路径前缀的网关地址（如 https://gw.company.com/openai/v1/），
+// BaseURL 中的查询参数（如 Azure 的 api-version）会保留
+func (p *Provider) endpoint(path string) (string, error) {
+	base, err := url.Parse(strings.TrimSpace(p.config.BaseURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid base_url %q: %w", p.config.BaseURL, err)
+	}
+	if base.Scheme == "" || base.Host == "" {
+		return "", fmt.Errorf("invalid base_url %q: scheme and host are required", p.config.BaseURL)
+	}
+	return base.JoinPath(path).String(), nil
+}
+
+// Ping 通过 GET /models 探测 API 是否可用及 API Key 是否有效
+// 该端点不消耗 Token，超时由 ctx 控制
+func (p *Provider) Ping(ctx context.Context) error {
+	endpoint, err := p.endpoint("models")
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
 
-	return content, nil
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return newAPIError(resp.StatusCode, respBody)
+	}
+	// 丢弃响应体以复用连接
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
 }
 
 // ChatStream 发送流式对话请求
 func (p *Provider) ChatStream(ctx context.Context, messages []llm.Message) (<-chan llm.StreamChunk, error) {
 	observability.LLMRequestLog(ctx, p.Name(), p.config.Model, len(messages))
+	logRequestContent(ctx, p.Name(), messages)
 
 	// 构建请求
-	reqBody := chatRequest{
-		Model:       p.config.Model,
-		Messages:    convertMessages(messages),
-		MaxTokens:   p.config.MaxTokens,
-		Temperature: p.config.Temperature,
-		Stream:      true,
-	}
+	reqBody := p.newChatRequest(messages, llm.ChatOptions{})
+	reqBody.Stream = true
 
 	bodyBytes, err := json.Marshal(reqBody)
 	if err != nil {
@@ -169,7 +255,11 @@ func (p *Provider) ChatStream(ctx context.Context, messages []llm.Message) (<-ch
 	}
 
 	// 创建 HTTP 请求（流式不设置超时，由 context 控制）
-	req, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/chat/completions", bytes.NewReader(bodyBytes))
+	endpoint, err := p.endpoint("chat/completions")
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -191,9 +281,7 @@ func (p *Provider) ChatStream(ctx context.Context, messages []llm.Message) (<-ch
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		var errResp errorResponse
-		json.Unmarshal(respBody, &errResp)
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, errResp.Error.Message)
+		return nil, newAPIError(resp.StatusCode, respBody)
 	}
 
 	// 创建输出 channel
@@ -204,6 +292,14 @@ func (p *Provider) ChatStream(ctx context.Context, messages []llm.Message) (<-ch
 		defer close(ch)
 		defer resp.Body.Close()
 
+		// verbose 模式下累积完整回复，结束时记录
+		var content strings.Builder
+		if observability.LLMVerbose(ctx) {
+			defer func() {
+				observability.LLMResponseContentLog(ctx, p.Name(), content.String())
+			}()
+		}
+
 		reader := bufio.NewReader(resp.Body)
 		for {
 			select {
@@ -244,26 +340,130 @@ func (p *Provider) ChatStream(ctx context.Context, messages []llm.Message) (<-ch
 				continue
 			}
 
-			if len(streamResp.Choices) > 0 && streamResp.Choices[0].Delta.Content != "" {
+			// 部分兼容服务会发送空 choices 的片段（如 usage 统计），直接跳过
+			if len(streamResp.Choices) == 0 {
+				continue
+			}
+
+			choice := streamResp.Choices[0]
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
 				ch <- llm.StreamChunk{
-					Content: streamResp.Choices[0].Delta.Content,
+					Content: choice.Delta.Content,
 					Done:    false,
 				}
 			}
+
+			// finish_reason 出现时 content 通常为空，单独发送结束片段
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				ch <- llm.StreamChunk{
+					Done:         true,
+					FinishReason: *choice.FinishReason,
+				}
+				return
+			}
 		}
 	}()
 
 	return ch, nil
 }
 
+// newChatRequest 构建请求体，opts 中设置的字段覆盖默认配置
+func (p *Provider) newChatRequest(messages []llm.Message, opts llm.ChatOptions) chatRequest {
+	req := chatRequest{
+		Model:       p.config.Model,
+		Messages:    convertMessages(messages),
+		MaxTokens:   p.config.MaxTokens,
+		Temperature: p.config.Temperature,
+	}
+	if opts.Model != "" {
+		req.Model = opts.Model
+	}
+	if opts.Temperature != nil {
+		req.Temperature = opts.Temperature
+	}
+	if opts.MaxTokens != nil {
+		req.MaxTokens = opts.MaxTokens
+	}
+
+	format := p.config.ResponseFormat
+	if opts.ResponseFormat != "" {
+		format = opts.ResponseFormat
+	}
+	if format != "" {
+		req.ResponseFormat = &responseFormat{Type: format}
+	}
+	if format == llm.ResponseFormatJSON {
+		req.Messages = ensureJSONInstruction(req.Messages)
+	}
+	return req
+}
+
+// jsonInstruction JSON 模式下补充的系统提示
+const jsonInstruction = "Respond only with a valid JSON object."
+
+// ensureJSONInstruction 确保 messages 中明确要求输出 JSON
+// OpenAI 在 json_object 模式下要求消息中出现 "JSON" 字样，否则直接返回 400
+func ensureJSONInstruction(messages []chatMessage) []chatMessage {
+	for _, m := range messages {
+		if strings.Contains(strings.ToLower(m.Content), "json") {
+			return messages
+		}
+	}
+
+	if len(messages) > 0 && messages[0].Role == string(llm.RoleSystem) {
+		patched := append([]chatMessage(nil), messages...)
+		patched[0].Content = strings.TrimRight(patched[0].Content, "\n") + "\n\n" + jsonInstruction
+		return patched
+	}
+	return append([]chatMessage{{Role: string(llm.RoleSystem), Content: jsonInstruction}}, messages...)
+}
+
+// logRequestContent 记录完整请求内容（仅 verbose 开启时构建日志数据）
+func logRequestContent(ctx context.Context, provider string, messages []llm.Message) {
+	if !observability.LLMVerbose(ctx) {
+		return
+	}
+	logged := make([]observability.LogMessage, len(messages))
+	for i, m := range messages {
+		logged[i] = observability.LogMessage{Role: string(m.Role), Content: m.Content}
+	}
+	observability.LLMRequestContentLog(ctx, provider, logged)
+}
+
 // convertMessages 转换消息格式
 func convertMessages(messages []llm.Message) []chatMessage {
 	result := make([]chatMessage, len(messages))
 	for i, m := range messages {
+		// XML 协议下的函数结果没有 tool_call_id，OpenAI 不接受，降级为带标记的 user 消息
+		m = llm.ToolResultAsUser(m)
 		result[i] = chatMessage{
-			Role:    string(m.Role),
-			Content: m.Content,
+			Role:       string(m.Role),
+			Content:    m.Content,
+			ToolCallID: m.ToolCallID,
+			Images:     m.Images,
 		}
+		for _, tc := range m.ToolCalls {
+			call := chatToolCall{ID: tc.ID, Type: "function"}
+			call.Function.Name = tc.Name
+			call.Function.Arguments = tc.Arguments
+			result[i].ToolCalls = append(result[i].ToolCalls, call)
+		}
+	}
+	return result
+}
+
+// convertTools 转换工具定义
+func convertTools(tools []llm.ToolDefinition) []chatTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	result := make([]chatTool, len(tools))
+	for i, t := range tools {
+		result[i] = chatTool{Type: "function"}
+		result[i].Function.Name = t.Name
+		result[i].Function.Description = t.Description
+		result[i].Function.Parameters = t.Parameters
 	}
 	return result
 }
@@ -273,14 +473,75 @@ func convertMessages(messages []llm.Message) []chatMessage {
 type chatRequest struct {
 	Model       string        `json:"model"`
 	Messages    []chatMessage `json:"messages"`
-	MaxTokens   int           `json:"max_tokens,omitempty"`
-	Temperature float64       `json:"temperature,omitempty"`
+	MaxTokens   *int          `json:"max_tokens,omitempty"`  // 指针：仅 nil 时省略
+	Temperature *float64      `json:"temperature,omitempty"` // 指针：显式的 0 也会发送
 	Stream      bool          `json:"stream,omitempty"`
+	Tools       []chatTool    `json:"tools,omitempty"`
+
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type string `json:"type"`
 }
 
 type chatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string         `json:"role"`
+	Content    string         `json:"content"`
+	ToolCalls  []chatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	Images     []llm.Image    `json:"-"` // 有图片时 content 编码为 text + image_url 片段数组
+}
+
+// contentPart 多模态消息中的一个内容片段
+type contentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
+// MarshalJSON 带图片的消息按多模态格式编码 content，其余消息保持字符串
+func (m chatMessage) MarshalJSON() ([]byte, error) {
+	type plain chatMessage
+	if len(m.Images) == 0 {
+		return json.Marshal(plain(m))
+	}
+
+	parts := make([]contentPart, 0, len(m.Images)+1)
+	if m.Content != "" {
+		parts = append(parts, contentPart{Type: "text", Text: m.Content})
+	}
+	for _, img := range m.Images {
+		part := contentPart{Type: "image_url"}
+		part.ImageURL = &struct {
+			URL string `json:"url"`
+		}{URL: img.DataURL()}
+		parts = append(parts, part)
+	}
+	return json.Marshal(struct {
+		plain
+		Content []contentPart `json:"content"`
+	}{plain(m), parts})
+}
+
+type chatTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+type chatToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
 }
 
 type chatResponse struct {
@@ -291,8 +552,9 @@ type chatResponse struct {
 	Choices []struct {
 		Index   int `json:"index"`
 		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string         `json:"role"`
+			Content   string         `json:"content"`
+			ToolCalls []chatToolCall `json:"tool_calls"`
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
@@ -322,6 +584,34 @@ type errorResponse struct {
 	Error struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
-		Code    string `json:"code"`
+		Code    any    `json:"code"` // 部分兼容服务返回数字错误码
 	} `json:"error"`
 }
+
+// newAPIError 根据非 200 响应构建 llm.APIError
+// 响应体不是 OpenAI 错误格式时（如网关返回的 HTML），用截断的原始响应作为错误描述
+func newAPIError(statusCode int, body []byte) *llm.APIError {
+	apiErr := &llm.APIError{StatusCode: statusCode}
+
+	var errResp errorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+		apiErr.Type = errResp.Error.Type
+		apiErr.Message = errResp.Error.Message
+		if errResp.Error.Code != nil {
+			apiErr.Code = fmt.Sprint(errResp.Error.Code)
+		}
+		return apiErr
+	}
+
+	apiErr.Message = truncate(strings.TrimSpace(string(body)), 200)
+	return apiErr
+}
+
+// truncate 按字符截断字符串，避免截断在多字节字符中间
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
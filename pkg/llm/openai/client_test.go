@@ -0,0 +1,345 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+func TestProvider_Endpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+		wantErr bool
+	}{
+		{"default", "https://api.openai.com/v1", "https://api.openai.com/v1/chat/completions", false},
+		{"trailing slash", "https://api.openai.com/v1/", "https://api.openai.com/v1/chat/completions", false},
+		{"subpath gateway", "https://gw.company.com/openai/v1/", "https://gw.company.com/openai/v1/chat/completions", false},
+		{"host only", "http://localhost:11434", "http://localhost:11434/chat/completions", false},
+		{"surrounding spaces", "  https://api.openai.com/v1  ", "https://api.openai.com/v1/chat/completions", false},
+		{"keeps query", "https://x.openai.azure.com/openai/deployments/gpt?api-version=2024-06-01",
+			"https://x.openai.azure.com/openai/deployments/gpt/chat/completions?api-version=2024-06-01", false},
+		{"missing scheme", "api.openai.com/v1", "", true},
+		{"unparsable", "http://[::1", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{config: &Config{BaseURL: tt.baseURL}}
+			got, err := p.endpoint("chat/completions")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("endpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("endpoint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvider_SubpathBaseURL(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	p := NewProvider(&Config{BaseURL: server.URL + "/openai/v1/", Model: "test"})
+	if err := p.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	reply, err := p.Chat(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "hello"}})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if reply != "hi" {
+		t.Errorf("reply = %q, want %q", reply, "hi")
+	}
+
+	want := "GET /openai/v1/models, POST /openai/v1/chat/completions"
+	if got := strings.Join(paths, ", "); got != want {
+		t.Errorf("requests = %q, want %q", got, want)
+	}
+}
+
+func TestChatMessage_MarshalJSON(t *testing.T) {
+	img := llm.Image{MimeType: "image/png", Data: []byte("png")}
+	dataURL := img.DataURL()
+
+	tests := []struct {
+		name string
+		msg  chatMessage
+		want string
+	}{
+		{
+			name: "text only",
+			msg:  chatMessage{Role: "user", Content: "hi"},
+			want: `{"role":"user","content":"hi"}`,
+		},
+		{
+			name: "empty content stays a string",
+			msg:  chatMessage{Role: "assistant", Content: "", ToolCalls: []chatToolCall{}},
+			want: `{"role":"assistant","content":""}`,
+		},
+		{
+			name: "text and image",
+			msg:  chatMessage{Role: "user", Content: "what is this?", Images: []llm.Image{img}},
+			want: `{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"` + dataURL + `"}}]}`,
+		},
+		{
+			name: "image only",
+			msg:  chatMessage{Role: "user", Images: []llm.Image{img, img}},
+			want: `{"role":"user","content":[{"type":"image_url","image_url":{"url":"` + dataURL + `"}},{"type":"image_url","image_url":{"url":"` + dataURL + `"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.msg)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvider_SamplingParams(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	messages := []llm.Message{{Role: llm.RoleUser, Content: "hello"}}
+	tests := []struct {
+		name            string
+		config          Config
+		opts            llm.ChatOptions
+		wantTemperature any // nil 表示不应发送
+		wantMaxTokens   any
+	}{
+		{
+			name:            "explicit zero temperature is sent",
+			config:          Config{Temperature: llm.Float64(0), MaxTokens: llm.Int(256)},
+			wantTemperature: 0.0,
+			wantMaxTokens:   256.0,
+		},
+		{
+			name:            "request options override config",
+			config:          Config{Temperature: llm.Float64(0.7), MaxTokens: llm.Int(4096)},
+			opts:            llm.ChatOptions{Temperature: llm.Float64(0), MaxTokens: llm.Int(32)},
+			wantTemperature: 0.0,
+			wantMaxTokens:   32.0,
+		},
+		{
+			name:   "unset params are omitted",
+			config: Config{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.config
+			cfg.BaseURL = server.URL
+			cfg.Model = "test"
+			if _, err := NewProvider(&cfg).ChatWithOptions(context.Background(), messages, tt.opts); err != nil {
+				t.Fatalf("ChatWithOptions: %v", err)
+			}
+
+			for key, want := range map[string]any{"temperature": tt.wantTemperature, "max_tokens": tt.wantMaxTokens} {
+				got, ok := body[key]
+				if want == nil {
+					if ok {
+						t.Errorf("%s = %v, want omitted", key, got)
+					}
+					continue
+				}
+				if !ok || got != want {
+					t.Errorf("%s = %v (present %v), want %v", key, got, ok, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProvider_ChatStreamFinishReason(t *testing.T) {
+	tests := []struct {
+		name       string
+		events     []string
+		wantReason string
+	}{
+		{
+			name: "finish reason with empty content",
+			events: []string{
+				`{"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}`,
+				`{"choices":[{"delta":{"content":"lo"}}]}`,
+				`{"choices":[]}`,
+				`{"choices":[{"delta":{},"finish_reason":"length"}]}`,
+				`[DONE]`,
+			},
+			wantReason: "length",
+		},
+		{
+			name: "finish reason with content",
+			events: []string{
+				`{"choices":[{"delta":{"content":"Hello"},"finish_reason":"stop"}]}`,
+				`[DONE]`,
+			},
+			wantReason: "stop",
+		},
+		{
+			name: "done without finish reason",
+			events: []string{
+				`{"choices":[{"delta":{"content":"Hello"}}]}`,
+				`[DONE]`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, e := range tt.events {
+					_, _ = w.Write([]byte("data: " + e + "\n\n"))
+				}
+			}))
+			defer server.Close()
+
+			ch, err := NewProvider(&Config{BaseURL: server.URL, Model: "test"}).ChatStream(context.Background(), []llm.Message{{Role: llm.RoleUser, Content: "hi"}})
+			if err != nil {
+				t.Fatalf("ChatStream: %v", err)
+			}
+
+			var content strings.Builder
+			var last llm.StreamChunk
+			for chunk := range ch {
+				if chunk.Error != nil {
+					t.Fatalf("chunk error: %v", chunk.Error)
+				}
+				content.WriteString(chunk.Content)
+				last = chunk
+			}
+			if content.String() != "Hello" {
+				t.Errorf("content = %q, want Hello", content.String())
+			}
+			// 最后一个片段是结束片段，带有 finish_reason
+			if !last.Done || last.FinishReason != tt.wantReason {
+				t.Errorf("last chunk = %+v, want done with finish reason %q", last, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestProvider_APIError(t *testing.T) {
+	longBody := strings.Repeat("错", 250)
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   llm.APIError
+	}{
+		{
+			name:   "string code",
+			status: http.StatusUnauthorized,
+			body:   `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`,
+			want:   llm.APIError{StatusCode: 401, Type: "invalid_request_error", Code: "invalid_api_key", Message: "Incorrect API key provided"},
+		},
+		{
+			name:   "numeric code",
+			status: http.StatusTooManyRequests,
+			body:   `{"error":{"message":"rate limited","type":"rate_limit_error","code":1302}}`,
+			want:   llm.APIError{StatusCode: 429, Type: "rate_limit_error", Code: "1302", Message: "rate limited"},
+		},
+		{
+			name:   "non-JSON body",
+			status: http.StatusBadGateway,
+			body:   "  <html>Bad Gateway</html>\n",
+			want:   llm.APIError{StatusCode: 502, Message: "<html>Bad Gateway</html>"},
+		},
+		{
+			// 按字符截断，不会切断多字节字符
+			name:   "long non-JSON body",
+			status: http.StatusInternalServerError,
+			body:   longBody,
+			want:   llm.APIError{StatusCode: 500, Message: strings.Repeat("错", 200) + "..."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			p := NewProvider(&Config{BaseURL: server.URL, Model: "test"})
+			messages := []llm.Message{{Role: llm.RoleUser, Content: "hi"}}
+
+			_, chatErr := p.Chat(context.Background(), messages)
+			_, streamErr := p.ChatStream(context.Background(), messages)
+			for name, err := range map[string]error{"Chat": chatErr, "ChatStream": streamErr} {
+				var apiErr *llm.APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("%s error = %v, want *llm.APIError", name, err)
+				}
+				if *apiErr != tt.want {
+					t.Errorf("%s error = %+v, want %+v", name, *apiErr, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestProvider_Ping(t *testing.T) {
+	var gotPath, gotAuth string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.Method+" "+r.URL.Path, r.Header.Get("Authorization")
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			_, _ = w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[]}`))
+	}))
+	defer server.Close()
+
+	p := NewProvider(&Config{BaseURL: server.URL + "/v1", APIKey: "sk-test", Model: "test"})
+	if err := p.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if gotPath != "GET /v1/models" || gotAuth != "Bearer sk-test" {
+		t.Errorf("request = %q with Authorization %q, want GET /v1/models with the bearer key", gotPath, gotAuth)
+	}
+
+	status = http.StatusUnauthorized
+	var apiErr *llm.APIError
+	if err := p.Ping(context.Background()); !errors.As(err, &apiErr) {
+		t.Fatalf("Ping error = %v, want *llm.APIError", err)
+	}
+	if !apiErr.IsAuth() || apiErr.Code != "invalid_api_key" {
+		t.Errorf("Ping error = %+v, want an auth error with code invalid_api_key", *apiErr)
+	}
+}
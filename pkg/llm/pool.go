@@ -0,0 +1,101 @@
+// Package llm 提供 LLM 适配层接口和实现
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ErrProviderNotFound Provider 池中没有指定名称的 Provider
+var ErrProviderNotFound = errors.New("llm provider not found in pool")
+
+// Pool 按名称管理多个 Provider，并按函数名路由
+// 典型用法：决定调用哪个函数的主对话使用强模型，函数内部的摘要、翻译等再调 LLM 时使用便宜的模型
+type Pool struct {
+	mu        sync.RWMutex
+	fallback  Provider
+	providers map[string]Provider
+	routes    map[string]string // 函数名 -> Provider 名称
+}
+
+// NewPool 创建 Provider 池，fallback 是未指定名称或没有路由时使用的默认 Provider
+func NewPool(fallback Provider) *Pool {
+	return &Pool{
+		fallback:  fallback,
+		providers: make(map[string]Provider),
+		routes:    make(map[string]string),
+	}
+}
+
+// Register 以名称注册 Provider，同名时覆盖
+func (p *Pool) Register(name string, provider Provider) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.providers[name] = provider
+}
+
+// Route 让函数 function 内部使用名为 name 的 Provider，name 必须已注册
+func (p *Pool) Route(function, name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.providers[name]; !ok {
+		return fmt.Errorf("%w: %s (routed from function %s)", ErrProviderNotFound, name, function)
+	}
+	p.routes[function] = name
+	return nil
+}
+
+// Get 按名称获取 Provider，名称为空时返回默认 Provider
+func (p *Pool) Get(name string) (Provider, bool) {
+	if name == "" {
+		return p.fallback, p.fallback != nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	provider, ok := p.providers[name]
+	return provider, ok
+}
+
+// Default 返回默认 Provider
+func (p *Pool) Default() Provider {
+	return p.fallback
+}
+
+// For 返回为函数路由的 Provider，没有配置路由时返回默认 Provider
+func (p *Pool) For(function string) Provider {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if name, ok := p.routes[function]; ok {
+		return p.providers[name]
+	}
+	return p.fallback
+}
+
+// Names 返回已注册的 Provider 名称（不含默认 Provider），按字母排序
+func (p *Pool) Names() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	names := make([]string, 0, len(p.providers))
+	for name := range p.providers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// poolKey context 中 Provider 池的 key
+type poolKey struct{}
+
+// WithPool 将 Provider 池写入 context
+func WithPool(ctx context.Context, pool *Pool) context.Context {
+	return context.WithValue(ctx, poolKey{}, pool)
+}
+
+// PoolFromContext 获取 context 中的 Provider 池，没有时返回 nil
+func PoolFromContext(ctx context.Context) *Pool {
+	pool, _ := ctx.Value(poolKey{}).(*Pool)
+	return pool
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChatOptions_ValidateFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     ChatOptions
+		callMode string
+		wantErr  string
+	}{
+		{name: "empty", opts: ChatOptions{}, callMode: "xml"},
+		{name: "text with xml", opts: ChatOptions{ResponseFormat: ResponseFormatText}, callMode: "xml"},
+		{name: "json with tools", opts: ChatOptions{ResponseFormat: ResponseFormatJSON}, callMode: "tools"},
+		{name: "json with xml", opts: ChatOptions{ResponseFormat: ResponseFormatJSON}, callMode: "xml", wantErr: "requires call_mode"},
+		{name: "json with default mode", opts: ChatOptions{ResponseFormat: ResponseFormatJSON}, callMode: "", wantErr: "requires call_mode"},
+		{name: "unknown format", opts: ChatOptions{ResponseFormat: "yaml"}, callMode: "tools", wantErr: "response_format must be"},
+		{name: "bad temperature", opts: ChatOptions{Temperature: Float64(3)}, callMode: "tools", wantErr: "temperature must be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.ValidateFor(tt.callMode)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateFor() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateFor() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_ValidateResponseFormat(t *testing.T) {
+	cfg := Config{APIKey: "sk-test", Model: "gpt-4o", ResponseFormat: ResponseFormatJSON}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() with json_object and xml call mode should fail")
+	}
+	cfg.CallMode = "tools"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with json_object and tools call mode error = %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Package llm 提供 LLM 适配层接口和实现
+package llm
+
+import "slices"
+
+// RolePlaceholder 需要插入占位消息时使用的内容
+const RolePlaceholder = "(continue)"
+
+// RoleConstraints 目标 API 对消息角色顺序的要求，零值表示不做任何处理
+type RoleConstraints struct {
+	// Alternate user/assistant 必须交替（如 Claude）：没有 tool_call_id 的函数结果转为 user 消息，
+	// 连续的同角色消息合并为一条
+	Alternate bool
+
+	// UserFirst system 之后的第一条消息必须是 user，否则在前面插入占位的 user 消息
+	UserFirst bool
+}
+
+// IsZero 是否没有任何约束
+func (c RoleConstraints) IsZero() bool {
+	return !c.Alternate && !c.UserFirst
+}
+
+// RoleConstrainer 可选接口：对消息角色顺序有要求的 Provider 实现后，
+// 发送前会按声明的约束规整消息
+type RoleConstrainer interface {
+	RoleConstraints() RoleConstraints
+}
+
+// ConstraintsOf 沿包装链查找 Provider 声明的角色约束，未声明时返回零值
+func ConstraintsOf(p Provider) RoleConstraints {
+	for p != nil {
+		if rc, ok := p.(RoleConstrainer); ok {
+			return rc.RoleConstraints()
+		}
+		u, ok := p.(Unwrapper)
+		if !ok {
+			break
+		}
+		p = u.Unwrap()
+	}
+	return RoleConstraints{}
+}
+
+// NormalizeRoles 按约束规整消息，返回新的切片，不修改传入的消息
+// 原生 tools 模式的 tool 消息（带 tool_call_id）与调用一一对应，不参与合并
+func NormalizeRoles(messages []Message, c RoleConstraints) []Message {
+	if c.IsZero() {
+		return messages
+	}
+
+	out := make([]Message, 0, len(messages)+1)
+	for _, m := range messages {
+		if c.Alternate {
+			m = ToolResultAsUser(m)
+			if n := len(out); n > 0 && mergeable(out[n-1], m) {
+				out[n-1] = mergeMessages(out[n-1], m)
+				continue
+			}
+		}
+		out = append(out, m)
+	}
+
+	if c.UserFirst {
+		i := 0
+		for i < len(out) && out[i].Role == RoleSystem {
+			i++
+		}
+		if i < len(out) && out[i].Role != RoleUser {
+			out = slices.Insert(out, i, Message{Role: RoleUser, Content: RolePlaceholder})
+		}
+	}
+	return out
+}
+
+// mergeable 两条相邻消息能否合并为一条
+func mergeable(prev, next Message) bool {
+	if prev.Role != next.Role || (prev.Role != RoleUser && prev.Role != RoleAssistant) {
+		return false
+	}
+	// 带 tool_calls 的 assistant 消息之后必须紧跟对应的工具结果
+	return prev.ToolCallID == "" && next.ToolCallID == "" && len(prev.ToolCalls) == 0
+}
+
+// mergeMessages 合并两条同角色消息，内容以空行分隔
+func mergeMessages(prev, next Message) Message {
+	merged := prev
+	switch {
+	case prev.Content == "":
+		merged.Content = next.Content
+	case next.Content != "":
+		merged.Content = prev.Content + "\n\n" + next.Content
+	}
+	if len(next.Images) > 0 {
+		merged.Images = append(slices.Clip(prev.Images), next.Images...)
+	}
+	merged.ToolCalls = next.ToolCalls
+	return merged
+}
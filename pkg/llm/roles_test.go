@@ -0,0 +1,169 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeRoles(t *testing.T) {
+	alternate := RoleConstraints{Alternate: true, UserFirst: true}
+	img := Image{MimeType: "image/png", Data: []byte{1}}
+
+	tests := []struct {
+		name     string
+		messages []Message
+		c        RoleConstraints
+		want     []Message
+	}{
+		{
+			name:     "no constraints",
+			messages: []Message{{Role: RoleAssistant, Content: "a"}, {Role: RoleAssistant, Content: "b"}},
+			c:        RoleConstraints{},
+			want:     []Message{{Role: RoleAssistant, Content: "a"}, {Role: RoleAssistant, Content: "b"}},
+		},
+		{
+			name: "merges consecutive user messages",
+			messages: []Message{
+				{Role: RoleSystem, Content: "sys"},
+				{Role: RoleUser, Content: "a"},
+				{Role: RoleUser, Content: "b"},
+				{Role: RoleAssistant, Content: "c"},
+			},
+			c: alternate,
+			want: []Message{
+				{Role: RoleSystem, Content: "sys"},
+				{Role: RoleUser, Content: "a\n\nb"},
+				{Role: RoleAssistant, Content: "c"},
+			},
+		},
+		{
+			name: "xml tool result becomes user and merges",
+			messages: []Message{
+				{Role: RoleUser, Content: "q"},
+				{Role: RoleAssistant, Content: "call"},
+				{Role: RoleTool, Content: "result"},
+				{Role: RoleUser, Content: "next"},
+			},
+			c: alternate,
+			want: []Message{
+				{Role: RoleUser, Content: "q"},
+				{Role: RoleAssistant, Content: "call"},
+				{Role: RoleUser, Content: ToolResultMarker + "\nresult\n\nnext"},
+			},
+		},
+		{
+			name: "native tool messages are kept",
+			messages: []Message{
+				{Role: RoleUser, Content: "q"},
+				{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "1", Name: "f"}}},
+				{Role: RoleTool, ToolCallID: "1", Content: "r1"},
+				{Role: RoleAssistant, Content: "done"},
+			},
+			c: alternate,
+			want: []Message{
+				{Role: RoleUser, Content: "q"},
+				{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "1", Name: "f"}}},
+				{Role: RoleTool, ToolCallID: "1", Content: "r1"},
+				{Role: RoleAssistant, Content: "done"},
+			},
+		},
+		{
+			name: "assistant with tool calls is not merged into",
+			messages: []Message{
+				{Role: RoleAssistant, Content: "thinking", ToolCalls: []ToolCall{{ID: "1", Name: "f"}}},
+				{Role: RoleAssistant, Content: "more"},
+			},
+			c: RoleConstraints{Alternate: true},
+			want: []Message{
+				{Role: RoleAssistant, Content: "thinking", ToolCalls: []ToolCall{{ID: "1", Name: "f"}}},
+				{Role: RoleAssistant, Content: "more"},
+			},
+		},
+		{
+			name: "merges images and skips empty content",
+			messages: []Message{
+				{Role: RoleUser, Content: "", Images: []Image{img}},
+				{Role: RoleUser, Content: "what is this", Images: []Image{img}},
+			},
+			c:    alternate,
+			want: []Message{{Role: RoleUser, Content: "what is this", Images: []Image{img, img}}},
+		},
+		{
+			name: "inserts placeholder before leading assistant",
+			messages: []Message{
+				{Role: RoleSystem, Content: "sys"},
+				{Role: RoleAssistant, Content: "summary"},
+				{Role: RoleUser, Content: "q"},
+			},
+			c: RoleConstraints{UserFirst: true},
+			want: []Message{
+				{Role: RoleSystem, Content: "sys"},
+				{Role: RoleUser, Content: RolePlaceholder},
+				{Role: RoleAssistant, Content: "summary"},
+				{Role: RoleUser, Content: "q"},
+			},
+		},
+		{
+			name:     "only system messages",
+			messages: []Message{{Role: RoleSystem, Content: "sys"}},
+			c:        alternate,
+			want:     []Message{{Role: RoleSystem, Content: "sys"}},
+		},
+		{
+			name:     "system messages are not merged",
+			messages: []Message{{Role: RoleSystem, Content: "a"}, {Role: RoleSystem, Content: "b"}},
+			c:        RoleConstraints{Alternate: true},
+			want:     []Message{{Role: RoleSystem, Content: "a"}, {Role: RoleSystem, Content: "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeRoles(tt.messages, tt.c)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeRoles() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRoles_DoesNotModifyInput(t *testing.T) {
+	img := Image{MimeType: "image/png", Data: []byte{1}}
+	first := make([]Image, 1, 4)
+	first[0] = img
+	messages := []Message{
+		{Role: RoleUser, Content: "a", Images: first},
+		{Role: RoleUser, Content: "b", Images: []Image{img}},
+	}
+
+	NormalizeRoles(messages, RoleConstraints{Alternate: true})
+	if messages[0].Content != "a" || len(messages[0].Images) != 1 {
+		t.Errorf("input message modified: %+v", messages[0])
+	}
+	if first[:2][1].MimeType != "" {
+		t.Errorf("merge wrote into the input's image backing array")
+	}
+}
+
+func TestConfig_InheritAlternateRoles(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry *bool
+		base  *bool
+		want  *bool
+	}{
+		{"unset inherits true", nil, Bool(true), Bool(true)},
+		{"unset inherits unset", nil, nil, nil},
+		{"explicit false overrides", Bool(false), Bool(true), Bool(false)},
+		{"explicit true kept", Bool(true), Bool(false), Bool(true)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Config{AlternateRoles: tt.entry}.Inherit(Config{AlternateRoles: tt.base}).AlternateRoles
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AlternateRoles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
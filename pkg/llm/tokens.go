@@ -0,0 +1,43 @@
+// Package llm 提供 LLM 适配层接口和实现
+package llm
+
+import "unicode/utf8"
+
+// messageTokenOverhead 每条消息的固定开销（角色、分隔符等），参考 OpenAI 的计费方式
+const messageTokenOverhead = 4
+
+// imageTokenEstimate 每张图片的估算 token 数，参考 OpenAI 高精度模式下 512px 图块的计费
+const imageTokenEstimate = 765
+
+// EstimateTokens 粗略估算文本的 token 数
+// 不依赖具体模型的分词器：ASCII 字符按 4 个 1 token 计，其余字符（如中文）按 1 个 1 token 计，
+// 对中英文混合的对话历史足够用于预算控制
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// EstimateMessageTokens 估算单条消息的 token 数，包括工具调用的名称和参数以及图片
+func EstimateMessageTokens(m Message) int {
+	tokens := messageTokenOverhead + EstimateTokens(m.Content) + len(m.Images)*imageTokenEstimate
+	for _, tc := range m.ToolCalls {
+		tokens += EstimateTokens(tc.Name) + EstimateTokens(tc.Arguments)
+	}
+	return tokens
+}
+
+// EstimateMessagesTokens 估算多条消息的 token 总数
+func EstimateMessagesTokens(messages []Message) int {
+	total := 0
+	for _, m := range messages {
+		total += EstimateMessageTokens(m)
+	}
+	return total
+}
@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"one ascii char", "a", 1},
+		{"four ascii chars", "abcd", 1},
+		{"five ascii chars", "abcde", 2},
+		{"english sentence", "Hello, world!", 4},
+		{"chinese", "你好世界", 4},
+		{"mixed", "hi 你好", 3},
+		{"emoji", "👍👍", 2},
+		{"long ascii", strings.Repeat("a", 400), 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.text); got != tt.want {
+				t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateMessageTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		want int
+	}{
+		{"empty message", Message{Role: RoleUser}, messageTokenOverhead},
+		{"text", Message{Role: RoleUser, Content: "abcdefgh"}, messageTokenOverhead + 2},
+		{"image", Message{Role: RoleUser, Images: []Image{{}, {}}}, messageTokenOverhead + 2*imageTokenEstimate},
+		{
+			"tool call",
+			Message{Role: RoleAssistant, ToolCalls: []ToolCall{{Name: "get_time", Arguments: `{"tz":"UTC"}`}}},
+			messageTokenOverhead + 2 + 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateMessageTokens(tt.msg); got != tt.want {
+				t.Errorf("EstimateMessageTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	messages := []Message{tests[0].msg, tests[1].msg}
+	if got, want := EstimateMessagesTokens(messages), tests[0].want+tests[1].want; got != want {
+		t.Errorf("EstimateMessagesTokens() = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,52 @@
+// Package llm 提供 LLM 适配层接口和实现
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// UsageRecorder 累计一次对话中所有 LLM 请求的 token 用量
+// 一次 Chat 可能包含多轮 LLM 调用（以及结果摘要等附带请求），并发安全
+type UsageRecorder struct {
+	mu    sync.Mutex
+	usage Usage
+	calls int
+}
+
+// usageRecorderKey context 中 UsageRecorder 的 key
+type usageRecorderKey struct{}
+
+// WithUsageRecorder 在 context 中挂载 UsageRecorder，Provider 会把每次请求的用量累加进去
+func WithUsageRecorder(ctx context.Context, recorder *UsageRecorder) context.Context {
+	return context.WithValue(ctx, usageRecorderKey{}, recorder)
+}
+
+// RecordUsage 将一次请求的用量累加到 context 中的 UsageRecorder，未挂载时忽略
+// Provider 实现在拿到服务端返回的 usage 后调用
+func RecordUsage(ctx context.Context, usage Usage) {
+	recorder, _ := ctx.Value(usageRecorderKey{}).(*UsageRecorder)
+	if recorder == nil {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.usage.PromptTokens += usage.PromptTokens
+	recorder.usage.CompletionTokens += usage.CompletionTokens
+	recorder.usage.TotalTokens += usage.TotalTokens
+	recorder.calls++
+}
+
+// Usage 返回累计的用量
+func (r *UsageRecorder) Usage() Usage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.usage
+}
+
+// Calls 返回已记录的 LLM 请求次数
+func (r *UsageRecorder) Calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
@@ -0,0 +1,131 @@
+// Package mcp 将 Function Registry 以 MCP (Model Context Protocol) server 的形式暴露
+// MCP 客户端（如 Claude Desktop）通过 tools/list 发现函数，通过 tools/call 调用函数
+package mcp
+
+import (
+	"encoding/json"
+)
+
+// jsonRPCVersion JSON-RPC 协议版本
+const jsonRPCVersion = "2.0"
+
+// LatestProtocolVersion 支持的最新 MCP 协议版本
+const LatestProtocolVersion = "2025-06-18"
+
+// supportedProtocolVersions 支持的 MCP 协议版本，客户端请求其中之一时原样返回，否则返回最新版本
+var supportedProtocolVersions = []string{LatestProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// JSON-RPC 错误码
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// MCP 方法名
+const (
+	methodInitialize  = "initialize"
+	methodInitialized = "notifications/initialized"
+	methodCancelled   = "notifications/cancelled"
+	methodPing        = "ping"
+	methodToolsList   = "tools/list"
+	methodToolsCall   = "tools/call"
+)
+
+// request JSON-RPC 请求或通知（ID 为空表示通知，不需要响应）
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// isNotification 是否为通知
+func (r *request) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+// response JSON-RPC 响应
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError JSON-RPC 错误
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// initializeParams initialize 请求参数
+type initializeParams struct {
+	ProtocolVersion string `json:"protocolVersion"`
+	ClientInfo      struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"clientInfo"`
+}
+
+// initializeResult initialize 响应
+type initializeResult struct {
+	ProtocolVersion string         `json:"protocolVersion"`
+	Capabilities    map[string]any `json:"capabilities"`
+	ServerInfo      serverInfo     `json:"serverInfo"`
+	Instructions    string         `json:"instructions,omitempty"`
+}
+
+// serverInfo 服务端信息
+type serverInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// tool tools/list 中的单个工具
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// listToolsResult tools/list 响应
+type listToolsResult struct {
+	Tools []tool `json:"tools"`
+}
+
+// callToolParams tools/call 请求参数
+type callToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// callToolResult tools/call 响应，函数执行失败时 IsError 为 true（不是 JSON-RPC 错误）
+type callToolResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// content 工具结果中的一段内容：text、image 或 resource
+type content struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	Data     string    `json:"data,omitempty"`     // image：base64 编码的图片
+	MimeType string    `json:"mimeType,omitempty"` // image
+	Resource *resource `json:"resource,omitempty"` // resource：内嵌的文件
+}
+
+// resource 内嵌的二进制资源
+type resource struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Blob     string `json:"blob"` // base64 编码
+}
+
+// cancelledParams notifications/cancelled 参数
+type cancelledParams struct {
+	RequestID json.RawMessage `json:"requestId"`
+	Reason    string          `json:"reason,omitempty"`
+}
@@ -0,0 +1,389 @@
+// Package mcp 将 Function Registry 以 MCP (Model Context Protocol) server 的形式暴露
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// DefaultServerName 默认的 MCP server 名称
+const DefaultServerName = "agentchassis"
+
+// maxMessageBytes 单条 JSON-RPC 消息的最大长度
+const maxMessageBytes = 10 << 20
+
+// Config MCP server 配置
+type Config struct {
+	// Name 在 initialize 中返回给客户端的 server 名称，默认 agentchassis
+	Name string
+
+	// Version 在 initialize 中返回给客户端的 server 版本
+	Version string
+
+	// Instructions 在 initialize 中返回给客户端的使用说明（可选）
+	Instructions string
+
+	// Scopes MCP 客户端拥有的作用域，为空时只能调用不需要作用域的函数，"*" 表示全部
+	Scopes []string
+
+	// Logger 连接和请求日志写入的 logger，为 nil 时使用全局 logger
+	Logger *slog.Logger
+}
+
+// Server MCP server，复用 Registry 和 Executor 把函数暴露为 MCP tools
+// 同一个 Server 可以服务多个连接，每个连接由 Serve 独立处理
+type Server struct {
+	registry *function.Registry
+	executor *function.Executor
+	config   Config
+}
+
+// NewServer 创建 MCP server
+func NewServer(registry *function.Registry, executor *function.Executor, config Config) *Server {
+	if config.Name == "" {
+		config.Name = DefaultServerName
+	}
+	return &Server{
+		registry: registry,
+		executor: executor,
+		config:   config,
+	}
+}
+
+// log 返回 Config.Logger，未设置时返回全局 logger
+func (s *Server) log() *slog.Logger {
+	if s.config.Logger != nil {
+		return s.config.Logger
+	}
+	return observability.DefaultLogger()
+}
+
+// ServeStdio 通过标准输入输出提供服务，直到 stdin 关闭或 ctx 取消
+// stdio 模式下标准输出只能写 JSON-RPC 消息，日志需输出到 stderr 或文件
+func (s *Server) ServeStdio(ctx context.Context) error {
+	return s.Serve(ctx, os.Stdin, os.Stdout)
+}
+
+// Serve 从 r 逐行读取 JSON-RPC 消息并把响应逐行写入 w（MCP stdio 传输格式）
+// 请求并发处理，一个耗时的 tools/call 不会阻塞 ping 等其他请求
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	conn := &connection{
+		server:   s,
+		w:        w,
+		inflight: make(map[string]context.CancelFunc),
+	}
+	defer conn.wg.Wait()
+
+	// 读取在独立 goroutine 中进行，ctx 取消时不必等待阻塞的 Read 返回
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxMessageBytes)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			select {
+			case lines <- bytes.Clone(line):
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case line := <-lines:
+			conn.dispatch(ctx, line)
+		}
+	}
+}
+
+// HandleMessage 处理单条 JSON-RPC 消息，返回需要写回的响应（通知返回 nil）
+// 适用于自行实现传输层（如 HTTP）的场景
+func (s *Server) HandleMessage(ctx context.Context, message []byte) []byte {
+	var req request
+	if err := json.Unmarshal(message, &req); err != nil {
+		return encodeResponse(errorResponse(nil, codeParseError, "parse error: "+err.Error()))
+	}
+	resp := s.handle(ctx, &req)
+	if resp == nil {
+		return nil
+	}
+	return encodeResponse(resp)
+}
+
+// handle 处理一个已解析的请求，通知返回 nil
+func (s *Server) handle(ctx context.Context, req *request) *response {
+	if req.JSONRPC != jsonRPCVersion || req.Method == "" {
+		if req.isNotification() {
+			return nil
+		}
+		return errorResponse(req.ID, codeInvalidRequest, "invalid request")
+	}
+
+	var (
+		result any
+		err    *rpcError
+	)
+	switch req.Method {
+	case methodInitialize:
+		result, err = s.initialize(req.Params)
+	case methodInitialized, methodCancelled:
+		// 取消由 connection 处理，这里无需响应
+		return nil
+	case methodPing:
+		result = struct{}{}
+	case methodToolsList:
+		result = s.listTools(ctx)
+	case methodToolsCall:
+		result, err = s.callTool(ctx, req.Params)
+	default:
+		if req.isNotification() {
+			// 未知通知直接忽略
+			return nil
+		}
+		err = &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+
+	if req.isNotification() {
+		return nil
+	}
+	if err != nil {
+		return &response{JSONRPC: jsonRPCVersion, ID: req.ID, Error: err}
+	}
+	return &response{JSONRPC: jsonRPCVersion, ID: req.ID, Result: result}
+}
+
+// initialize 协商协议版本并返回服务端能力
+func (s *Server) initialize(raw json.RawMessage) (any, *rpcError) {
+	var params initializeParams
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid initialize params: " + err.Error()}
+		}
+	}
+
+	version := LatestProtocolVersion
+	if slices.Contains(supportedProtocolVersions, params.ProtocolVersion) {
+		version = params.ProtocolVersion
+	}
+	s.log().Info("MCP client initialized",
+		"client", params.ClientInfo.Name,
+		"client_version", params.ClientInfo.Version,
+		"protocol_version", version,
+	)
+
+	return initializeResult{
+		ProtocolVersion: version,
+		Capabilities: map[string]any{
+			"tools": map[string]any{},
+		},
+		ServerInfo:   serverInfo{Name: s.config.Name, Version: s.config.Version},
+		Instructions: s.config.Instructions,
+	}, nil
+}
+
+// listTools 列出客户端有权调用的函数
+func (s *Server) listTools(ctx context.Context) listToolsResult {
+	schemas := s.registry.ExportJSONSchemaForContext(s.callerContext(ctx))
+	tools := make([]tool, 0, len(schemas))
+	for _, schema := range schemas {
+		tools = append(tools, tool{
+			Name:        schema.Name,
+			Description: schema.Description,
+			InputSchema: schema.Parameters,
+		})
+	}
+	return listToolsResult{Tools: tools}
+}
+
+// callTool 调用函数，函数本身的错误作为 isError 结果返回，便于模型看到并自我纠正
+func (s *Server) callTool(ctx context.Context, raw json.RawMessage) (any, *rpcError) {
+	var params callToolParams
+	if err := json.Unmarshal(raw, &params); err != nil || params.Name == "" {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid tools/call params: name is required"}
+	}
+
+	args, err := function.ParamsFromJSON(string(params.Arguments))
+	if err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+
+	resp := s.executor.Execute(s.callerContext(ctx), function.ExecuteRequest{
+		FunctionName: params.Name,
+		Params:       args,
+	})
+	if errors.Is(resp.Error, function.ErrFunctionNotFound) {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + params.Name}
+	}
+	return toolResult(resp), nil
+}
+
+// callerContext 写入 MCP 客户端的作用域
+// MCP 客户端总是作为外部调用方做作用域检查，没有配置作用域时也不会当作不受限的内部调用
+func (s *Server) callerContext(ctx context.Context) context.Context {
+	return function.WithCallerScopes(ctx, s.config.Scopes)
+}
+
+// toolResult 将函数执行结果转换为 MCP 工具结果
+func toolResult(resp function.ExecuteResponse) callToolResult {
+	if resp.Error != nil {
+		return callToolResult{
+			Content: []content{{Type: "text", Text: resp.Error.Error()}},
+			IsError: true,
+		}
+	}
+
+	result := resp.Result
+	var contents []content
+	if result.Message != "" {
+		contents = append(contents, content{Type: "text", Text: result.Message})
+	}
+	switch {
+	case result.Markdown != "":
+		contents = append(contents, content{Type: "text", Text: result.Markdown})
+	case result.Data != nil:
+		data, err := json.Marshal(result.Data)
+		if err != nil {
+			data = []byte(fmt.Sprint(result.Data))
+		}
+		contents = append(contents, content{Type: "text", Text: string(data)})
+	}
+	for _, att := range result.Attachments {
+		encoded := base64.StdEncoding.EncodeToString(att.Content)
+		if strings.HasPrefix(att.MimeType, "image/") {
+			contents = append(contents, content{Type: "image", Data: encoded, MimeType: att.MimeType})
+			continue
+		}
+		contents = append(contents, content{Type: "resource", Resource: &resource{
+			URI:      "attachment:///" + att.Filename,
+			MimeType: att.MimeType,
+			Blob:     encoded,
+		}})
+	}
+	if len(contents) == 0 {
+		contents = append(contents, content{Type: "text", Text: "success"})
+	}
+	return callToolResult{Content: contents}
+}
+
+// errorResponse 创建错误响应
+func errorResponse(id json.RawMessage, code int, message string) *response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: jsonRPCVersion, ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+// encodeResponse 编码响应，结果无法编码时返回内部错误
+func encodeResponse(resp *response) []byte {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		data, _ = json.Marshal(errorResponse(resp.ID, codeInternalError, "failed to encode response: "+err.Error()))
+	}
+	return data
+}
+
+// connection 一个 stdio 连接的状态：串行写出响应，记录进行中的请求以便取消
+type connection struct {
+	server *Server
+
+	writeMu sync.Mutex
+	w       io.Writer
+
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc // 请求 ID -> 取消函数
+
+	wg sync.WaitGroup
+}
+
+// dispatch 分发一条消息：通知同步处理，请求在独立 goroutine 中处理
+func (c *connection) dispatch(ctx context.Context, line []byte) {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		c.write(errorResponse(nil, codeParseError, "parse error: "+err.Error()))
+		return
+	}
+
+	if req.isNotification() {
+		if req.Method == methodCancelled {
+			c.cancel(req.Params)
+		}
+		c.server.handle(ctx, &req)
+		return
+	}
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	key := string(req.ID)
+	c.mu.Lock()
+	c.inflight[key] = cancel
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer func() {
+			c.mu.Lock()
+			delete(c.inflight, key)
+			c.mu.Unlock()
+			cancel()
+		}()
+
+		resp := c.server.handle(reqCtx, &req)
+		// 已被客户端取消的请求不再响应
+		if resp != nil && reqCtx.Err() == nil {
+			c.write(resp)
+		}
+	}()
+}
+
+// cancel 处理 notifications/cancelled，取消对应请求的 context
+func (c *connection) cancel(raw json.RawMessage) {
+	var params cancelledParams
+	if err := json.Unmarshal(raw, &params); err != nil || len(params.RequestID) == 0 {
+		return
+	}
+	c.mu.Lock()
+	cancel, ok := c.inflight[string(params.RequestID)]
+	c.mu.Unlock()
+	if ok {
+		c.server.log().Info("MCP request cancelled", "request_id", string(params.RequestID), "reason", params.Reason)
+		cancel()
+	}
+}
+
+// write 写出一条响应，每条消息占一行
+func (c *connection) write(resp *response) {
+	data := append(encodeResponse(resp), '\n')
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := c.w.Write(data); err != nil {
+		c.server.log().Error("Failed to write MCP response", "error", err)
+	}
+}
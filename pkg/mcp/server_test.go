@@ -0,0 +1,239 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+)
+
+// greetParams 测试函数参数
+type greetParams struct {
+	Name string `json:"name" desc:"名字" required:"true"`
+}
+
+// greetFunction 测试用函数
+type greetFunction struct{}
+
+func (f *greetFunction) Name() string             { return "greet" }
+func (f *greetFunction) Description() string      { return "打招呼" }
+func (f *greetFunction) ParamsType() reflect.Type { return reflect.TypeOf(greetParams{}) }
+func (f *greetFunction) Execute(ctx context.Context, params any) (function.Result, error) {
+	p := params.(greetParams)
+	if p.Name == "error" {
+		return function.Result{}, errors.New("greet failed")
+	}
+	return function.Result{Message: "hello " + p.Name, Data: map[string]string{"name": p.Name}}, nil
+}
+
+// adminFunction 需要 admin 作用域的测试函数
+type adminFunction struct{}
+
+func (f *adminFunction) Name() string             { return "admin_only" }
+func (f *adminFunction) Description() string      { return "仅管理员" }
+func (f *adminFunction) ParamsType() reflect.Type { return nil }
+func (f *adminFunction) Execute(ctx context.Context, params any) (function.Result, error) {
+	return function.Result{}, nil
+}
+
+func newTestServer(t *testing.T, config Config) *Server {
+	t.Helper()
+	registry := function.NewRegistry()
+	if err := registry.Register(&greetFunction{}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := registry.RegisterWithScopes(&adminFunction{}, "admin"); err != nil {
+		t.Fatalf("RegisterWithScopes() error = %v", err)
+	}
+	return NewServer(registry, function.NewExecutor(registry, 0), config)
+}
+
+// call 发送一条消息并解析响应
+func call(t *testing.T, s *Server, message string) map[string]any {
+	t.Helper()
+	out := s.HandleMessage(context.Background(), []byte(message))
+	if out == nil {
+		return nil
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("invalid response %s: %v", out, err)
+	}
+	return resp
+}
+
+func TestServer_Initialize(t *testing.T) {
+	var logs bytes.Buffer
+	s := newTestServer(t, Config{Version: "1.0.0", Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+
+	resp := call(t, s, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","clientInfo":{"name":"test"}}}`)
+	if !strings.Contains(logs.String(), "MCP client initialized") {
+		t.Errorf("initialize was not logged to the configured logger: %q", logs.String())
+	}
+	result := resp["result"].(map[string]any)
+	if result["protocolVersion"] != "2024-11-05" {
+		t.Errorf("protocolVersion = %v, want client's version", result["protocolVersion"])
+	}
+	if info := result["serverInfo"].(map[string]any); info["name"] != DefaultServerName || info["version"] != "1.0.0" {
+		t.Errorf("serverInfo = %v", info)
+	}
+
+	// 不支持的版本回退到最新版本
+	resp = call(t, s, `{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`)
+	if got := resp["result"].(map[string]any)["protocolVersion"]; got != LatestProtocolVersion {
+		t.Errorf("protocolVersion = %v, want %s", got, LatestProtocolVersion)
+	}
+
+	// 通知没有响应
+	if resp := call(t, s, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); resp != nil {
+		t.Errorf("notification response = %v, want nil", resp)
+	}
+}
+
+func TestServer_ToolsList(t *testing.T) {
+	resp := call(t, newTestServer(t, Config{Scopes: []string{"admin"}}), `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	tools := resp["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 2 {
+		t.Fatalf("tools = %v, want 2 tools", tools)
+	}
+	greet := tools[1].(map[string]any)
+	if greet["name"] != "greet" || greet["inputSchema"].(map[string]any)["type"] != "object" {
+		t.Errorf("greet tool = %v", greet)
+	}
+
+	// 只列出有权调用的函数，没有配置作用域时只有不需要作用域的函数
+	for _, scopes := range [][]string{nil, {"tasks"}} {
+		resp = call(t, newTestServer(t, Config{Scopes: scopes}), `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+		tools := resp["result"].(map[string]any)["tools"].([]any)
+		if len(tools) != 1 || tools[0].(map[string]any)["name"] != "greet" {
+			t.Errorf("scopes %v: tools = %v, want only greet", scopes, tools)
+		}
+	}
+	resp = call(t, newTestServer(t, Config{Scopes: []string{"*"}}), `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	if tools := resp["result"].(map[string]any)["tools"].([]any); len(tools) != 2 {
+		t.Errorf("scope * tools = %v, want 2 tools", tools)
+	}
+}
+
+func TestServer_ToolsCallScoped(t *testing.T) {
+	const req = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"admin_only"}}`
+
+	// 没有配置作用域时不能调用需要作用域的函数
+	resp := call(t, newTestServer(t, Config{}), req)
+	if result, ok := resp["result"].(map[string]any); !ok || result["isError"] != true {
+		t.Errorf("unscoped call = %v, want isError", resp)
+	}
+
+	resp = call(t, newTestServer(t, Config{Scopes: []string{"admin"}}), req)
+	if result, ok := resp["result"].(map[string]any); !ok || result["isError"] != nil {
+		t.Errorf("admin call = %v, want success", resp)
+	}
+}
+
+func TestServer_ToolsCall(t *testing.T) {
+	s := newTestServer(t, Config{})
+
+	resp := call(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"greet","arguments":{"name":"Tom"}}}`)
+	result := resp["result"].(map[string]any)
+	content := result["content"].([]any)
+	if result["isError"] != nil || len(content) != 2 {
+		t.Fatalf("result = %v", result)
+	}
+	if text := content[0].(map[string]any)["text"]; text != "hello Tom" {
+		t.Errorf("message = %v, want hello Tom", text)
+	}
+	if text := content[1].(map[string]any)["text"]; text != `{"name":"Tom"}` {
+		t.Errorf("data = %v", text)
+	}
+
+	// 函数错误作为 isError 结果返回
+	resp = call(t, s, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"greet","arguments":{"name":"error"}}}`)
+	if result := resp["result"].(map[string]any); result["isError"] != true {
+		t.Errorf("result = %v, want isError", result)
+	}
+
+	// 未知工具是协议错误
+	resp = call(t, s, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"missing"}}`)
+	if code := resp["error"].(map[string]any)["code"]; code != float64(codeInvalidParams) {
+		t.Errorf("error code = %v, want %d", code, codeInvalidParams)
+	}
+}
+
+func TestServer_ToolsCallRequiresApproval(t *testing.T) {
+	registry := function.NewRegistry()
+	if err := registry.Register(&greetFunction{}); err != nil {
+		t.Fatal(err)
+	}
+	executor := function.NewExecutor(registry, 0)
+	executor.SetApprovalFunctions([]string{"greet"})
+	s := NewServer(registry, executor, Config{})
+
+	// 需要审批的函数不能经由 MCP 绕过审批流程直接执行
+	resp := call(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"greet","arguments":{"name":"Tom"}}}`)
+	result := resp["result"].(map[string]any)
+	if result["isError"] != true {
+		t.Fatalf("result = %v, want isError", result)
+	}
+	if text := result["content"].([]any)[0].(map[string]any)["text"].(string); !strings.Contains(text, "approval") {
+		t.Errorf("error text = %q", text)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	s := newTestServer(t, Config{})
+
+	tests := []struct {
+		name     string
+		message  string
+		wantCode int
+	}{
+		{name: "parse error", message: `{not json`, wantCode: codeParseError},
+		{name: "invalid request", message: `{"id":1,"method":"ping"}`, wantCode: codeInvalidRequest},
+		{name: "method not found", message: `{"jsonrpc":"2.0","id":1,"method":"resources/list"}`, wantCode: codeMethodNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := call(t, s, tt.message)
+			if code := resp["error"].(map[string]any)["code"]; code != float64(tt.wantCode) {
+				t.Errorf("error code = %v, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestServer_Serve(t *testing.T) {
+	s := newTestServer(t, Config{})
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		``,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+	}, "\n")
+
+	var out strings.Builder
+	if err := s.Serve(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d responses, want 2: %q", len(lines), out.String())
+	}
+	ids := map[float64]bool{}
+	for _, line := range lines {
+		var resp map[string]any
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("invalid response line %q: %v", line, err)
+		}
+		ids[resp["id"].(float64)] = true
+	}
+	if !ids[1] || !ids[2] {
+		t.Errorf("response ids = %v, want 1 and 2", ids)
+	}
+}
@@ -0,0 +1,119 @@
+// Package memory 提供按用户持久化的长期记忆（key-value）
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/KodaTao/AgentChassis/pkg/storage"
+)
+
+// ErrMemoryNotFound 记忆不存在
+var ErrMemoryNotFound = errors.New("memory not found")
+
+// Memory 用户的一条记忆，如称呼、时区、常用地点
+type Memory struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    string    `gorm:"not null;uniqueIndex:idx_memory_user_key" json:"user_id"` // 用户标识，如 telegram:123456
+	Key       string    `gorm:"not null;uniqueIndex:idx_memory_user_key" json:"key"`     // 记忆键
+	Value     string    `gorm:"type:text" json:"value"`                                  // 记忆内容
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Memory) TableName() string {
+	return "user_memories"
+}
+
+// Repository 用户记忆数据访问层
+type Repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建 Repository
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// migrations 用户记忆表的迁移，schema 变化时在末尾追加新版本
+var migrations = []storage.Migration{
+	{
+		Version: 1,
+		Name:    "create user_memories",
+		Up:      storage.CreateTables(&Memory{}),
+		Down:    storage.DropTables(&Memory{}),
+	},
+}
+
+// Migrate 按版本执行未应用的迁移
+func (r *Repository) Migrate() error {
+	return storage.NewMigrator(r.db, "memory", migrations...).Migrate()
+}
+
+// Set 写入记忆，同一用户的相同 key 会覆盖旧值
+func (r *Repository) Set(userID, key, value string) error {
+	now := time.Now()
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]any{"value": value, "updated_at": now}),
+	}).Create(&Memory{
+		UserID:    userID,
+		Key:       key,
+		Value:     value,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}).Error
+}
+
+// Get 获取用户的一条记忆
+func (r *Repository) Get(userID, key string) (*Memory, error) {
+	var m Memory
+	err := r.db.Where("user_id = ? AND key = ?", userID, key).First(&m).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMemoryNotFound
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+// List 列出用户的全部记忆，按 key 排序
+func (r *Repository) List(userID string) ([]Memory, error) {
+	var memories []Memory
+	err := r.db.Where("user_id = ?", userID).Order("key").Find(&memories).Error
+	return memories, err
+}
+
+// Delete 删除用户的一条记忆
+func (r *Repository) Delete(userID, key string) error {
+	result := r.db.Where("user_id = ? AND key = ?", userID, key).Delete(&Memory{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMemoryNotFound
+	}
+	return nil
+}
+
+// userIDKey context 中用户标识的 key
+type userIDKey struct{}
+
+// WithUserID 将当前对话的用户标识写入 context，记忆函数据此读写对应用户的记忆
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFromContext 获取当前对话的用户标识，未设置时返回空字符串
+func UserIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(userIDKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/storage/storagetest"
+)
+
+func TestRepository_SetOverwrites(t *testing.T) {
+	repo := storagetest.NewRepository(t, NewRepository)
+	if err := repo.Set("telegram:1", "city", "Paris"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Set("telegram:1", "city", "Berlin"); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := repo.Get("telegram:1", "city")
+	if err != nil || m.Value != "Berlin" {
+		t.Errorf("Get = %+v, %v, want Berlin", m, err)
+	}
+	if memories, _ := repo.List("telegram:1"); len(memories) != 1 {
+		t.Errorf("List returned %d memories, want 1", len(memories))
+	}
+}
+
+func TestRepository_UserIsolation(t *testing.T) {
+	repo := storagetest.NewRepository(t, NewRepository)
+	for _, m := range []Memory{
+		{UserID: "telegram:1", Key: "name", Value: "Alice"},
+		{UserID: "telegram:1", Key: "city", Value: "Paris"},
+		{UserID: "api_key:web", Key: "name", Value: "Bob"},
+	} {
+		if err := repo.Set(m.UserID, m.Key, m.Value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 相同 key 按用户各自保存
+	if m, err := repo.Get("api_key:web", "name"); err != nil || m.Value != "Bob" {
+		t.Errorf("Get(api_key:web, name) = %+v, %v, want Bob", m, err)
+	}
+	if _, err := repo.Get("api_key:web", "city"); !errors.Is(err, ErrMemoryNotFound) {
+		t.Errorf("Get of another user's key error = %v, want ErrMemoryNotFound", err)
+	}
+
+	tests := []struct {
+		userID string
+		want   []string
+	}{
+		{"telegram:1", []string{"city", "name"}},
+		{"api_key:web", []string{"name"}},
+		{"telegram:2", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		memories, err := repo.List(tt.userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, m := range memories {
+			if m.UserID != tt.userID {
+				t.Errorf("List(%q) returned a memory of %q", tt.userID, m.UserID)
+			}
+			keys = append(keys, m.Key)
+		}
+		if len(keys) != len(tt.want) {
+			t.Errorf("List(%q) keys = %v, want %v", tt.userID, keys, tt.want)
+			continue
+		}
+		for i := range keys {
+			if keys[i] != tt.want[i] {
+				t.Errorf("List(%q) keys = %v, want %v", tt.userID, keys, tt.want)
+			}
+		}
+	}
+
+	// 删除只影响自己的记忆
+	if err := repo.Delete("api_key:web", "city"); !errors.Is(err, ErrMemoryNotFound) {
+		t.Errorf("Delete of another user's key error = %v, want ErrMemoryNotFound", err)
+	}
+	if err := repo.Delete("api_key:web", "name"); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := repo.Get("telegram:1", "name"); err != nil || m.Value != "Alice" {
+		t.Errorf("other user's memory after Delete = %+v, %v", m, err)
+	}
+}
+
+func TestUserIDContext(t *testing.T) {
+	if got := UserIDFromContext(context.Background()); got != "" {
+		t.Errorf("UserIDFromContext(empty) = %q", got)
+	}
+	if got := UserIDFromContext(WithUserID(context.Background(), "telegram:1")); got != "telegram:1" {
+		t.Errorf("UserIDFromContext = %q, want telegram:1", got)
+	}
+}
@@ -0,0 +1,199 @@
+// Package observability 提供可观测性功能：日志、指标、链路追踪
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 结构化事件类型
+const (
+	EventChat           = "chat"            // 一次完整的对话请求
+	EventFunctionCall   = "function_call"   // 一次函数调用
+	EventTaskExecution  = "task_execution"  // 一次延时/定时任务执行
+	EventCircuitBreaker = "circuit_breaker" // LLM 断路器状态变化，Status 为新状态
+	EventLLMCache       = "llm_cache"       // LLM 响应缓存命中
+	EventApproval       = "approval"        // 函数调用审批，Status 为 pending、approved 或 rejected
+)
+
+// Event 结构化事件，统一描述对话、函数调用和任务执行，便于导入外部分析系统
+type Event struct {
+	Kind       string         `json:"kind"`                 // 事件类型：chat, function_call, task_execution
+	Timestamp  time.Time      `json:"timestamp"`            // 事件结束时间
+	SessionID  string         `json:"session_id,omitempty"` // 所属会话
+	Name       string         `json:"name,omitempty"`       // 函数名或任务名
+	Status     string         `json:"status"`               // success, error, cancelled...
+	DurationMs int64          `json:"duration_ms"`          // 耗时（毫秒）
+	Error      string         `json:"error,omitempty"`      // 失败原因
+	Attributes map[string]any `json:"attributes,omitempty"` // 其他维度，如任务 ID、函数调用次数
+}
+
+// Sink 结构化事件的输出目标
+// 实现该接口即可把事件写入 ClickHouse、Kafka 等外部系统；Write 在事件发生的 goroutine 中同步调用，
+// 耗时的写入请自行缓冲或异步处理
+type Sink interface {
+	Write(event Event) error
+}
+
+// SinkFunc 函数形式的 Sink
+type SinkFunc func(event Event) error
+
+// Write 实现 Sink 接口
+func (f SinkFunc) Write(event Event) error {
+	return f(event)
+}
+
+// Emitter 一组事件输出目标
+// 每个 App 持有自己的 Emitter，同一进程中多个 App 的事件互不影响；nil Emitter 使用包级的输出目标
+type Emitter struct {
+	mu     sync.RWMutex
+	sinks  []Sink
+	logger *slog.Logger
+}
+
+// NewEmitter 创建 Emitter，Sink 写入失败时的警告写入 logger，为 nil 时使用全局 logger
+func NewEmitter(logger *slog.Logger) *Emitter {
+	return &Emitter{logger: logger}
+}
+
+// defaultEmitter 包级的事件输出目标，RegisterSink、EmitEvent 使用
+var defaultEmitter = NewEmitter(nil)
+
+// Register 注册事件输出目标，可注册多个，事件会依次写入每个 Sink
+func (e *Emitter) Register(sink Sink) {
+	if sink == nil {
+		return
+	}
+	if e == nil {
+		e = defaultEmitter
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sinks = append(e.sinks, sink)
+}
+
+// Reset 移除所有已注册的事件输出目标，之后的事件不再写入它们
+func (e *Emitter) Reset() {
+	if e == nil {
+		e = defaultEmitter
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sinks = nil
+}
+
+// Emit 把事件写入所有已注册的 Sink，单个 Sink 失败只记录警告
+func (e *Emitter) Emit(event Event) {
+	if e == nil {
+		e = defaultEmitter
+	}
+	e.mu.RLock()
+	list := e.sinks
+	e.mu.RUnlock()
+	if len(list) == 0 {
+		return
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	logger := e.logger
+	if logger == nil {
+		logger = DefaultLogger()
+	}
+	for _, sink := range list {
+		if err := sink.Write(event); err != nil {
+			logger.Warn("Failed to write event", "kind", event.Kind, "error", err)
+		}
+	}
+}
+
+// RegisterSink 注册包级的事件输出目标，未指定 Emitter 的事件都会写入
+func RegisterSink(sink Sink) {
+	defaultEmitter.Register(sink)
+}
+
+// ResetSinks 移除所有包级的事件输出目标
+func ResetSinks() {
+	defaultEmitter.Reset()
+}
+
+// EmitEvent 把事件写入所有包级的 Sink
+func EmitEvent(event Event) {
+	defaultEmitter.Emit(event)
+}
+
+// emitterKey context 中 Emitter 的 key
+type emitterKey struct{}
+
+// WithEmitter 在 context 中指定 Emitter，之后通过 EmitEventContext 输出的事件都写入该 Emitter
+func WithEmitter(ctx context.Context, e *Emitter) context.Context {
+	if e == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, emitterKey{}, e)
+}
+
+// EmitEventContext 把事件写入 context 中的 Emitter，没有指定时写入包级的 Sink
+func EmitEventContext(ctx context.Context, event Event) {
+	e, _ := ctx.Value(emitterKey{}).(*Emitter)
+	e.Emit(event)
+}
+
+// JSONSink 以 JSON Lines 格式（每行一个事件）写入 io.Writer
+type JSONSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewJSONSink 创建写入 w 的 JSONSink
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{writer: w}
+}
+
+// NewStdoutSink 创建写入标准输出的 JSONSink
+func NewStdoutSink() *JSONSink {
+	return NewJSONSink(os.Stdout)
+}
+
+// NewFileSink 创建追加写入文件的 JSONSink，目录不存在时自动创建
+func NewFileSink(path string) (*JSONSink, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONSink(f), nil
+}
+
+// Write 实现 Sink 接口
+func (s *JSONSink) Write(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.writer.Write(line)
+	return err
+}
+
+// Close 关闭底层 writer（如文件），writer 不可关闭时什么也不做
+func (s *JSONSink) Close() error {
+	if c, ok := s.writer.(io.Closer); ok && s.writer != os.Stdout {
+		return c.Close()
+	}
+	return nil
+}
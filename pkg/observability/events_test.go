@@ -0,0 +1,127 @@
+package observability
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSink 记录收到的事件，err 非 nil 时每次写入都返回该错误
+type fakeSink struct {
+	events []Event
+	err    error
+}
+
+func (s *fakeSink) Write(event Event) error {
+	s.events = append(s.events, event)
+	return s.err
+}
+
+func TestEmitEvent(t *testing.T) {
+	t.Cleanup(ResetSinks)
+
+	// 没有注册 Sink 时不做任何事
+	EmitEvent(Event{Kind: EventChat})
+
+	failing := &fakeSink{err: errors.New("disk full")}
+	ok := &fakeSink{}
+	RegisterSink(failing)
+	RegisterSink(nil) // 忽略
+	RegisterSink(ok)
+
+	EmitEvent(Event{Kind: EventFunctionCall, Name: "notify", Status: "success"})
+
+	// 单个 Sink 失败不影响其他 Sink
+	for name, sink := range map[string]*fakeSink{"failing": failing, "ok": ok} {
+		if len(sink.events) != 1 {
+			t.Fatalf("%s sink got %d events, want 1", name, len(sink.events))
+		}
+		e := sink.events[0]
+		if e.Kind != EventFunctionCall || e.Name != "notify" || e.Timestamp.IsZero() {
+			t.Errorf("%s sink got %+v, want function_call with a timestamp", name, e)
+		}
+	}
+
+	ResetSinks()
+	EmitEvent(Event{Kind: EventChat})
+	if len(ok.events) != 1 {
+		t.Errorf("sink got %d events after ResetSinks, want 1", len(ok.events))
+	}
+}
+
+func TestEmitter(t *testing.T) {
+	t.Cleanup(ResetSinks)
+	global := &fakeSink{}
+	RegisterSink(global)
+
+	a, b := NewEmitter(nil), NewEmitter(nil)
+	sinkA, sinkB := &fakeSink{}, &fakeSink{}
+	a.Register(sinkA)
+	b.Register(sinkB)
+
+	// 各 Emitter 只写入自己的 Sink；context 中没有 Emitter 时写入包级的 Sink
+	EmitEventContext(WithEmitter(context.Background(), a), Event{Kind: EventChat})
+	b.Emit(Event{Kind: EventFunctionCall})
+	EmitEventContext(context.Background(), Event{Kind: EventApproval})
+	for name, tt := range map[string]struct {
+		sink *fakeSink
+		want string
+	}{
+		"a":      {sinkA, EventChat},
+		"b":      {sinkB, EventFunctionCall},
+		"global": {global, EventApproval},
+	} {
+		if len(tt.sink.events) != 1 || tt.sink.events[0].Kind != tt.want {
+			t.Errorf("%s sink got %+v, want one %s event", name, tt.sink.events, tt.want)
+		}
+	}
+
+	// Reset 之后不再写入，关闭 Sink 前先 Reset 可避免写入已关闭的文件
+	a.Reset()
+	a.Emit(Event{Kind: EventChat})
+	if len(sinkA.events) != 1 {
+		t.Errorf("sink got %d events after Reset, want 1", len(sinkA.events))
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events", "events.jsonl")
+	for i := 0; i < 2; i++ {
+		sink, err := NewFileSink(path)
+		if err != nil {
+			t.Fatalf("NewFileSink: %v", err)
+		}
+		if err := sink.Write(Event{Kind: EventTaskExecution, Name: "report", Status: "success", Attributes: map[string]any{"task_id": i}}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	// 目录自动创建，重新打开时追加写入，每行一个 JSON 事件
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %d is not JSON: %v", lines+1, err)
+		}
+		if e.Kind != EventTaskExecution || e.Attributes["task_id"] != float64(lines) {
+			t.Errorf("line %d = %+v", lines+1, e)
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("got %d lines, want 2", lines)
+	}
+}
@@ -3,25 +3,67 @@ package observability
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"regexp"
 	"strings"
+
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// Logger 全局日志实例
+// Logger 全局日志实例，供包级的 Info、Warn 等函数使用
+// 需要在同一进程中运行多个配置不同的实例时，用 NewLogger 创建独立的 logger 并显式传递
 var Logger *slog.Logger
 
 // LogConfig 日志配置
 type LogConfig struct {
 	Level    string // debug, info, warn, error
 	Format   string // text, json
-	Output   string // stdout, file
+	Output   string // stdout, stderr, file
 	FilePath string // 日志文件路径
+
+	// 日志轮转配置（当 Output 为 file 时生效）
+	MaxSizeMB  int  // 单个日志文件最大大小（MB），默认 100
+	MaxBackups int  // 保留的旧日志文件数量，0 表示全部保留
+	MaxAgeDays int  // 旧日志文件保留天数，0 表示不按时间清理
+	Compress   bool // 是否 gzip 压缩旧日志文件
+
+	// LLM 完整内容日志（调试 prompt 用，生产环境建议关闭）
+	LLMVerbose         bool // 是否记录完整的 messages 和响应内容
+	LLMVerboseMaxChars int  // 单条内容最大记录字符数，默认 2000
+}
+
+// LLMVerboseConfig LLM 完整内容日志配置
+type LLMVerboseConfig struct {
+	Enabled  bool // 是否记录完整的 messages 和响应内容
+	MaxChars int  // 单条内容最大记录字符数，0 使用默认值
 }
 
-// InitLogger 初始化日志系统
+// llmVerbose 全局的 LLM 完整内容日志配置，由 InitLogger 设置，context 中没有配置时使用
+var llmVerbose LLMVerboseConfig
+
+// defaultLLMVerboseMaxChars 单条内容默认最大记录字符数
+const defaultLLMVerboseMaxChars = 2000
+
+// InitLogger 初始化日志系统：按配置创建 logger 并设置为全局默认实例
 func InitLogger(cfg LogConfig) error {
+	logger, err := NewLogger(cfg)
+	if err != nil {
+		return err
+	}
+
+	Logger = logger
+	slog.SetDefault(Logger)
+
+	llmVerbose = LLMVerboseConfig{Enabled: cfg.LLMVerbose, MaxChars: cfg.LLMVerboseMaxChars}
+
+	return nil
+}
+
+// NewLogger 按配置创建一个独立的 logger，不修改全局实例
+func NewLogger(cfg LogConfig) (*slog.Logger, error) {
 	var (
 		writer  io.Writer
 		handler slog.Handler
@@ -48,11 +90,21 @@ func InitLogger(cfg LogConfig) error {
 		if cfg.FilePath == "" {
 			cfg.FilePath = "agentchassis.log"
 		}
-		file, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			return err
+		maxSize := cfg.MaxSizeMB
+		if maxSize <= 0 {
+			maxSize = 100
 		}
-		writer = file
+		// 使用轮转 writer，避免日志文件无限增长
+		writer = &lumberjack.Logger{
+			Filename:   cfg.FilePath,
+			MaxSize:    maxSize,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+			Compress:   cfg.Compress,
+			LocalTime:  true,
+		}
+	case "stderr":
+		writer = os.Stderr
 	default:
 		writer = os.Stdout
 	}
@@ -70,10 +122,7 @@ func InitLogger(cfg LogConfig) error {
 		handler = slog.NewTextHandler(writer, opts)
 	}
 
-	Logger = slog.New(handler)
-	slog.SetDefault(Logger)
-
-	return nil
+	return slog.New(handler), nil
 }
 
 // DefaultLogger 返回默认日志实例
@@ -84,9 +133,61 @@ func DefaultLogger() *slog.Logger {
 	return Logger
 }
 
+// logFieldsKey context 中附加日志字段的 key
+type logFieldsKey struct{}
+
+// loggerKey context 中 logger 的 key
+type loggerKey struct{}
+
+// llmVerboseKey context 中 LLM 完整内容日志配置的 key
+type llmVerboseKey struct{}
+
+// WithLogger 在 context 中指定 logger，之后通过 WithContext 及 *Context 系列函数输出的日志都写入该 logger
+// 同一进程中运行多个 App 时，各自的请求日志不会写到全局实例或其他 App 的 logger 中
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	if logger == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// WithLLMVerbose 在 context 中指定 LLM 完整内容日志配置，覆盖 InitLogger 设置的全局配置
+func WithLLMVerbose(ctx context.Context, cfg LLMVerboseConfig) context.Context {
+	return context.WithValue(ctx, llmVerboseKey{}, cfg)
+}
+
+// llmVerboseFrom 返回 context 中的 LLM 完整内容日志配置，没有时使用全局配置
+func llmVerboseFrom(ctx context.Context) LLMVerboseConfig {
+	cfg, ok := ctx.Value(llmVerboseKey{}).(LLMVerboseConfig)
+	if !ok {
+		cfg = llmVerbose
+	}
+	if cfg.MaxChars <= 0 {
+		cfg.MaxChars = defaultLLMVerboseMaxChars
+	}
+	return cfg
+}
+
+// WithLogFields 在 context 中附加日志字段（key/value 交替），
+// 之后通过 WithContext 及 *Context 系列函数输出的日志都会带上这些字段
+func WithLogFields(ctx context.Context, args ...any) context.Context {
+	existing, _ := ctx.Value(logFieldsKey{}).([]any)
+	fields := make([]any, 0, len(existing)+len(args))
+	fields = append(fields, existing...)
+	fields = append(fields, args...)
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
 // WithContext 创建带有上下文信息的日志器
+// 使用 WithLogger 指定的 logger，没有指定时使用全局实例
 func WithContext(ctx context.Context) *slog.Logger {
-	logger := DefaultLogger()
+	logger, ok := ctx.Value(loggerKey{}).(*slog.Logger)
+	if !ok {
+		logger = DefaultLogger()
+	}
+	if fields, ok := ctx.Value(logFieldsKey{}).([]any); ok {
+		logger = logger.With(fields...)
+	}
 
 	// 从 context 中提取 trace_id 等信息
 	if traceID := ctx.Value("trace_id"); traceID != nil {
@@ -159,6 +260,64 @@ func LLMResponseLog(ctx context.Context, provider string, durationMs int64, toke
 	)
 }
 
+// LogMessage 用于完整内容日志的对话消息
+type LogMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// LLMVerbose 是否开启 LLM 完整内容日志
+// 调用方可据此跳过构建日志数据的开销
+func LLMVerbose(ctx context.Context) bool {
+	return llmVerboseFrom(ctx).Enabled
+}
+
+// LLMRequestContentLog 记录发送给 LLM 的完整 messages（仅 verbose 开启时）
+// 内容会脱敏并按 LLMVerboseMaxChars 截断
+func LLMRequestContentLog(ctx context.Context, provider string, messages []LogMessage) {
+	verbose := llmVerboseFrom(ctx)
+	if !verbose.Enabled {
+		return
+	}
+
+	logged := make([]LogMessage, len(messages))
+	for i, m := range messages {
+		logged[i] = LogMessage{Role: m.Role, Content: sanitizeContent(m.Content, verbose.MaxChars)}
+	}
+
+	WithContext(ctx).Info("LLM request content",
+		"provider", provider,
+		"messages", logged,
+	)
+}
+
+// LLMResponseContentLog 记录 LLM 的原始响应内容（仅 verbose 开启时）
+func LLMResponseContentLog(ctx context.Context, provider, content string) {
+	verbose := llmVerboseFrom(ctx)
+	if !verbose.Enabled {
+		return
+	}
+
+	WithContext(ctx).Info("LLM response content",
+		"provider", provider,
+		"content", sanitizeContent(content, verbose.MaxChars),
+	)
+}
+
+// secretPattern 匹配常见的密钥格式（OpenAI key、Bearer token、Telegram bot token）
+var secretPattern = regexp.MustCompile(`sk-[A-Za-z0-9_\-]{8,}|Bearer\s+[A-Za-z0-9._\-]{8,}|\d{6,}:[A-Za-z0-9_\-]{30,}`)
+
+// sanitizeContent 脱敏并截断日志内容，最多保留 maxChars 个字符
+func sanitizeContent(s string, maxChars int) string {
+	s = secretPattern.ReplaceAllString(s, "****")
+
+	runes := []rune(s)
+	if len(runes) > maxChars {
+		return fmt.Sprintf("%s...(truncated %d chars)", string(runes[:maxChars]), len(runes)-maxChars)
+	}
+	return s
+}
+
 // FunctionCallLog 记录 Function 调用日志
 func FunctionCallLog(ctx context.Context, funcName string, status string, durationMs int64) {
 	WithContext(ctx).Info("Function call",
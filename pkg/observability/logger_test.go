@@ -0,0 +1,68 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	ctx := WithLogFields(WithLogger(context.Background(), logger), "request_id", "r1")
+	InfoContext(ctx, "hello")
+	if out := buf.String(); !strings.Contains(out, "msg=hello") || !strings.Contains(out, "request_id=r1") {
+		t.Errorf("log output = %q", out)
+	}
+
+	if got := WithLogger(context.Background(), nil).Value(loggerKey{}); got != nil {
+		t.Errorf("nil logger should not be stored, got %v", got)
+	}
+}
+
+func TestLLMVerboseContext(t *testing.T) {
+	prev := llmVerbose
+	t.Cleanup(func() { llmVerbose = prev })
+	llmVerbose = LLMVerboseConfig{Enabled: true, MaxChars: 5}
+
+	if !LLMVerbose(context.Background()) {
+		t.Error("global config should apply without a context override")
+	}
+	ctx := WithLLMVerbose(context.Background(), LLMVerboseConfig{Enabled: false})
+	if LLMVerbose(ctx) {
+		t.Error("context config should override the global one")
+	}
+
+	var buf bytes.Buffer
+	ctx = WithLogger(context.Background(), slog.New(slog.NewTextHandler(&buf, nil)))
+	ctx = WithLLMVerbose(ctx, LLMVerboseConfig{Enabled: true, MaxChars: 4})
+	LLMResponseContentLog(ctx, "openai", "abcdefgh sk-secretsecret")
+	if out := buf.String(); !strings.Contains(out, "abcd...(truncated") {
+		t.Errorf("content should be truncated to 4 chars: %q", out)
+	}
+}
+
+func TestSanitizeContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		maxChars int
+		want     string
+	}{
+		{"plain", "hello", 10, "hello"},
+		{"openai key", "key sk-abcdefgh12345", 100, "key ****"},
+		{"bearer token", "Authorization: Bearer abcdefgh.ijk", 100, "Authorization: ****"},
+		{"telegram token", "123456789:" + strings.Repeat("A", 35), 100, "****"},
+		{"truncated", "héllo world", 5, "héllo...(truncated 6 chars)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeContent(tt.in, tt.maxChars); got != tt.want {
+				t.Errorf("sanitizeContent(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
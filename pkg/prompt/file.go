@@ -0,0 +1,131 @@
+// Package prompt 提供提示词生成和管理功能
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/prompt/templates"
+)
+
+// DefaultReloadInterval 热重载时检查模板文件变化的默认间隔
+const DefaultReloadInterval = 2 * time.Second
+
+// TemplateFiles 外置的系统提示词模板文件，路径为空或文件不存在时使用内置模板
+// 模板语法与内置模板相同，可以使用 TemplateData 的全部字段和 formatParams 函数
+type TemplateFiles struct {
+	System string // XML 调用模式的系统提示词模板
+	Tools  string // 原生 function calling 模式的系统提示词模板
+}
+
+// LoadTemplateFiles 从文件加载系统提示词模板，之后生成的提示词优先使用外部模板
+// 文件为空或解析失败时返回错误，并保留当前使用的模板
+func (g *Generator) LoadTemplateFiles(files TemplateFiles) error {
+	system, err := parseTemplateFile("system_file", files.System)
+	if err != nil {
+		return err
+	}
+	tools, err := parseTemplateFile("tools_file", files.Tools)
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.customSystem = system
+	g.customTools = tools
+	return nil
+}
+
+// WatchTemplateFiles 定期检查模板文件的修改时间，变化后重新加载，直到 stop 关闭
+// 新模板对之后生成的系统提示词生效；重新加载失败时记录错误并继续使用原来的模板
+func (g *Generator) WatchTemplateFiles(files TemplateFiles, interval time.Duration, logger *slog.Logger, stop <-chan struct{}) {
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	last := templateFilesVersion(files)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			current := templateFilesVersion(files)
+			if current == last {
+				continue
+			}
+			last = current
+			if err := g.LoadTemplateFiles(files); err != nil {
+				logger.Error("Failed to reload prompt templates, keeping the previous ones", "error", err)
+				continue
+			}
+			logger.Info("Prompt templates reloaded", "system", files.System, "tools", files.Tools)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// customTemplate 返回从文件加载的模板，tools 为 true 时返回原生 function calling 模式的模板
+func (g *Generator) customTemplate(tools bool) *template.Template {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if tools {
+		return g.customTools
+	}
+	return g.customSystem
+}
+
+// parseTemplateFile 读取并解析模板文件，路径为空或文件不存在时返回 nil（使用内置模板）
+func parseTemplateFile(name, path string) (*template.Template, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template %s: %w", path, err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return nil, fmt.Errorf("%w: %s", ErrEmptyTemplate, path)
+	}
+	tmpl, err := template.New(name).Funcs(templates.Funcs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template %s: %w", path, err)
+	}
+	// 用示例数据试渲染一次，引用了不存在的字段等错误在加载时就能发现，而不是在对话中失败
+	sample := TemplateData{
+		Functions:    []function.FunctionInfo{{Name: "example", Description: "example function"}},
+		HasFunctions: true,
+		Language:     templates.DefaultLanguage,
+	}
+	if err := tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, fmt.Errorf("failed to render prompt template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// templateFilesVersion 用各文件的修改时间和大小标识当前版本，文件不存在时对应部分为空
+func templateFilesVersion(files TemplateFiles) string {
+	version := func(path string) string {
+		if path == "" {
+			return ""
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+	}
+	return version(files.System) + "|" + version(files.Tools)
+}
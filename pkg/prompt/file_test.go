@@ -0,0 +1,153 @@
+package prompt
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+)
+
+// writeTemplate 写入模板文件，失败时终止测试
+func writeTemplate(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerator_LoadTemplateFiles(t *testing.T) {
+	dir := t.TempDir()
+	systemPath := filepath.Join(dir, "system.tmpl")
+	toolsPath := filepath.Join(dir, "tools.tmpl")
+	writeTemplate(t, systemPath, "custom system {{.Language}}{{range .Functions}} {{.Name}}{{end}}")
+	writeTemplate(t, toolsPath, "custom tools {{.Language}}")
+	functions := []function.FunctionInfo{{Name: "get_weather"}}
+
+	t.Run("uses file templates", func(t *testing.T) {
+		g := NewGenerator()
+		if err := g.LoadTemplateFiles(TemplateFiles{System: systemPath, Tools: toolsPath}); err != nil {
+			t.Fatalf("LoadTemplateFiles: %v", err)
+		}
+
+		system, err := g.GenerateSystemPromptForLanguage(functions, "", "zh")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if system != "custom system zh get_weather" {
+			t.Errorf("system prompt = %q", system)
+		}
+		tools, err := g.GenerateToolsPromptForLanguage(functions, "", "en")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tools != "custom tools en" {
+			t.Errorf("tools prompt = %q", tools)
+		}
+	})
+
+	t.Run("missing file falls back to built-in", func(t *testing.T) {
+		g := NewGenerator()
+		builtin, err := g.GenerateSystemPrompt(functions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.LoadTemplateFiles(TemplateFiles{System: filepath.Join(dir, "missing.tmpl")}); err != nil {
+			t.Fatalf("LoadTemplateFiles: %v", err)
+		}
+		got, err := g.GenerateSystemPrompt(functions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != builtin {
+			t.Error("missing template file should keep the built-in template")
+		}
+	})
+
+	t.Run("invalid file keeps current template", func(t *testing.T) {
+		emptyPath := filepath.Join(dir, "empty.tmpl")
+		badPath := filepath.Join(dir, "bad.tmpl")
+		writeTemplate(t, emptyPath, "  \n")
+		writeTemplate(t, badPath, "{{.NoSuchField}}")
+
+		g := NewGenerator()
+		if err := g.LoadTemplateFiles(TemplateFiles{System: systemPath}); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.LoadTemplateFiles(TemplateFiles{System: emptyPath}); !errors.Is(err, ErrEmptyTemplate) {
+			t.Errorf("empty template error = %v, want ErrEmptyTemplate", err)
+		}
+		if err := g.LoadTemplateFiles(TemplateFiles{System: badPath}); err == nil {
+			t.Error("template referencing an unknown field should fail to load")
+		}
+
+		got, err := g.GenerateSystemPromptForLanguage(functions, "", "en")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(got, "custom system") {
+			t.Errorf("failed load replaced the template: %q", got)
+		}
+	})
+}
+
+func TestGenerator_WatchTemplateFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "system.tmpl")
+	writeTemplate(t, path, "version one")
+	files := TemplateFiles{System: path}
+
+	g := NewGenerator()
+	if err := g.LoadTemplateFiles(files); err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	go func() {
+		g.WatchTemplateFiles(files, 10*time.Millisecond, logger, stop)
+		close(done)
+	}()
+
+	// waitFor 等待生成的系统提示词变为 want
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			got, err := g.GenerateSystemPrompt(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("system prompt = %q, want %q", got, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// 等监视协程记录下初始版本后再修改文件
+	time.Sleep(50 * time.Millisecond)
+
+	// 内容长度不同，即使文件系统的修改时间精度较粗也能检测到变化
+	writeTemplate(t, path, "version two, reloaded")
+	waitFor("version two, reloaded")
+
+	// 重新加载失败时继续使用上一个模板
+	writeTemplate(t, path, "{{.NoSuchField}} broken template")
+	time.Sleep(100 * time.Millisecond)
+	waitFor("version two, reloaded")
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("WatchTemplateFiles did not return after stop was closed")
+	}
+}
@@ -3,6 +3,12 @@ package prompt
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
 	"text/template"
 	"time"
 
@@ -10,18 +16,171 @@ import (
 	"github.com/KodaTao/AgentChassis/pkg/prompt/templates"
 )
 
+// 语言模板相关错误
+var (
+	ErrUnknownLanguage = errors.New("unknown prompt language")
+	ErrEmptyTemplate   = errors.New("system prompt template is empty")
+)
+
 // Generator 提示词生成器
 type Generator struct {
-	systemTemplate *template.Template
-	minimalTemplate *template.Template
+	mu             sync.RWMutex
+	languages      map[string]*templateSet // 语言代码 -> 解析后的模板
+	language       string                  // 未指定语言时使用的默认语言
+	extra          map[string]any          // 注入到模板的自定义变量
+	persona        string                  // 助手人设描述
+	channelPrompts map[string]string       // 渠道类型 -> 附加指令
+
+	// 从外部文件加载的模板，设置后所有语言都使用它，为 nil 时使用内置模板
+	customSystem *template.Template
+	customTools  *template.Template
 }
 
-// NewGenerator 创建提示词生成器
+// templateSet 一种语言解析后的系统提示词模板
+type templateSet struct {
+	system  *template.Template
+	minimal *template.Template
+	tools   *template.Template
+}
+
+// NewGenerator 创建提示词生成器，内置英文、中文、日文模板，默认使用英文
 func NewGenerator() *Generator {
-	return &Generator{
-		systemTemplate:  template.Must(template.New("system").Parse(templates.SystemPrompt)),
-		minimalTemplate: template.Must(template.New("minimal").Parse(templates.SystemPromptMinimal)),
+	g := &Generator{
+		languages: make(map[string]*templateSet),
+		language:  templates.DefaultLanguage,
+	}
+	for lang, set := range templates.Builtin() {
+		parsed, err := parseTemplateSet(lang, set)
+		if err != nil {
+			panic(err)
+		}
+		g.languages[lang] = parsed
 	}
+	return g
+}
+
+// parseTemplateSet 解析一种语言的模板，Tools 或 Minimal 为空时使用英文模板
+func parseTemplateSet(lang string, set templates.Set) (*templateSet, error) {
+	if strings.TrimSpace(set.System) == "" {
+		return nil, ErrEmptyTemplate
+	}
+	if set.Tools == "" {
+		set.Tools = templates.SystemPromptTools
+	}
+	if set.Minimal == "" {
+		set.Minimal = templates.SystemPromptMinimal
+	}
+
+	parse := func(name, text string) (*template.Template, error) {
+		return template.New(name + "_" + lang).Funcs(templates.Funcs).Parse(text)
+	}
+	system, err := parse("system", set.System)
+	if err != nil {
+		return nil, err
+	}
+	minimal, err := parse("minimal", set.Minimal)
+	if err != nil {
+		return nil, err
+	}
+	tools, err := parse("tools", set.Tools)
+	if err != nil {
+		return nil, err
+	}
+	return &templateSet{system: system, minimal: minimal, tools: tools}, nil
+}
+
+// normalizeLanguage 规范化语言代码：小写，下划线替换为连字符，如 zh_CN -> zh-cn
+func normalizeLanguage(lang string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(lang)), "_", "-")
+}
+
+// RegisterLanguage 注册（或覆盖）一种语言的系统提示词模板
+// 模板可以使用 TemplateData 的全部字段；Tools 或 Minimal 为空时使用英文模板
+func (g *Generator) RegisterLanguage(lang string, set templates.Set) error {
+	lang = normalizeLanguage(lang)
+	if lang == "" {
+		return ErrUnknownLanguage
+	}
+	parsed, err := parseTemplateSet(lang, set)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s templates: %w", lang, err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.languages[lang] = parsed
+	return nil
+}
+
+// SetDefaultLanguage 设置未指定语言（或指定的语言没有模板）时使用的语言
+func (g *Generator) SetDefaultLanguage(lang string) error {
+	lang = normalizeLanguage(lang)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.languages[lang]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownLanguage, lang)
+	}
+	g.language = lang
+	return nil
+}
+
+// DefaultLanguage 返回默认语言
+func (g *Generator) DefaultLanguage() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.language
+}
+
+// Languages 返回所有可用的语言代码，按字母排序
+func (g *Generator) Languages() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	langs := make([]string, 0, len(g.languages))
+	for lang := range g.languages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// ResolveLanguage 返回请求语言实际使用的模板语言
+// 依次尝试完整语言代码（zh-tw）、主语言（zh），都没有模板时使用默认语言
+func (g *Generator) ResolveLanguage(lang string) string {
+	lang, _ = g.templatesFor(lang)
+	return lang
+}
+
+// HasLanguage 是否有该语言（或其主语言）的模板，没有时 ResolveLanguage 会回退到默认语言
+func (g *Generator) HasLanguage(lang string) bool {
+	lang = normalizeLanguage(lang)
+	base, _, _ := strings.Cut(lang, "-")
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	_, ok := g.languages[lang]
+	if !ok {
+		_, ok = g.languages[base]
+	}
+	return ok
+}
+
+// templatesFor 查找请求语言对应的模板
+func (g *Generator) templatesFor(lang string) (string, *templateSet) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	lang = normalizeLanguage(lang)
+	if set, ok := g.languages[lang]; ok {
+		return lang, set
+	}
+	if base, _, found := strings.Cut(lang, "-"); found {
+		if set, ok := g.languages[base]; ok {
+			return base, set
+		}
+	}
+	return g.language, g.languages[g.language]
 }
 
 // TemplateData 模板数据
@@ -30,19 +189,129 @@ type TemplateData struct {
 	HasFunctions bool
 	CurrentTime  string // 当前时间 (ISO8601 格式)
 	Timezone     string // 时区
+
+	// Language 系统提示词的语言代码（实际使用的模板语言），如 en、zh、ja
+	Language string
+
+	// Extra 用户注入的自定义变量（如用户名、地点），模板中通过 {{.Extra.key}} 引用
+	Extra map[string]any
+
+	// Persona 助手的人设描述，为空时不输出
+	Persona string
+
+	// Channel 当前对话的渠道类型，ChannelPrompt 为该渠道的附加指令，为空时不输出
+	Channel       string
+	ChannelPrompt string
+}
+
+// SetExtra 设置注入到系统提示词的自定义变量，传 nil 清空
+// 只影响之后生成的提示词
+func (g *Generator) SetExtra(vars map[string]any) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.extra = maps.Clone(vars)
+}
+
+// Extra 返回当前注入的自定义变量副本
+func (g *Generator) Extra() map[string]any {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return maps.Clone(g.extra)
+}
+
+// SetPersona 设置助手的人设描述，传空字符串清空
+// 只影响之后生成的提示词
+func (g *Generator) SetPersona(persona string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.persona = persona
+}
+
+// Persona 返回当前的人设描述
+func (g *Generator) Persona() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.persona
+}
+
+// SetChannelPrompts 设置各渠道的附加指令（渠道类型 -> 指令），传 nil 清空
+// 只影响之后生成的提示词
+func (g *Generator) SetChannelPrompts(prompts map[string]string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.channelPrompts = maps.Clone(prompts)
+}
+
+// ChannelPrompt 返回指定渠道的附加指令，未配置时返回空字符串
+func (g *Generator) ChannelPrompt(channel string) string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.channelPrompts[channel]
+}
+
+// newTemplateData 构建模板数据，填充当前时间、时区、自定义变量和渠道指令
+func (g *Generator) newTemplateData(functions []function.FunctionInfo, channel, language string) TemplateData {
+	now := time.Now()
+	return TemplateData{
+		Functions:     functions,
+		HasFunctions:  len(functions) > 0,
+		CurrentTime:   now.Format(time.RFC3339),
+		Timezone:      now.Location().String(),
+		Language:      language,
+		Extra:         g.Extra(),
+		Persona:       g.Persona(),
+		Channel:       channel,
+		ChannelPrompt: g.ChannelPrompt(channel),
+	}
 }
 
 // GenerateSystemPrompt 生成完整的系统提示词
 func (g *Generator) GenerateSystemPrompt(functions []function.FunctionInfo) (string, error) {
+	return g.GenerateSystemPromptForChannel(functions, "")
+}
+
+// GenerateSystemPromptForChannel 生成完整的系统提示词，并附加指定渠道的指令
+func (g *Generator) GenerateSystemPromptForChannel(functions []function.FunctionInfo, channel string) (string, error) {
+	return g.GenerateSystemPromptForLanguage(functions, channel, "")
+}
+
+// GenerateSystemPromptForLanguage 使用指定语言的模板生成完整的系统提示词，language 为空时使用默认语言
+// 加载了外部模板文件时使用外部模板，language 仍会传入模板数据
+func (g *Generator) GenerateSystemPromptForLanguage(functions []function.FunctionInfo, channel, language string) (string, error) {
+	language, set := g.templatesFor(language)
+	tmpl := set.system
+	if custom := g.customTemplate(false); custom != nil {
+		tmpl = custom
+	}
 	var buf bytes.Buffer
-	now := time.Now()
-	data := TemplateData{
-		Functions:    functions,
-		HasFunctions: len(functions) > 0,
-		CurrentTime:  now.Format(time.RFC3339),
-		Timezone:     now.Location().String(),
+	data := g.newTemplateData(functions, channel, language)
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// GenerateToolsPrompt 生成原生 function calling 模式的系统提示词
+// 函数定义通过 tools 参数单独传给模型，这里只用于判断是否有可用函数
+func (g *Generator) GenerateToolsPrompt(functions []function.FunctionInfo) (string, error) {
+	return g.GenerateToolsPromptForChannel(functions, "")
+}
+
+// GenerateToolsPromptForChannel 生成原生 function calling 模式的系统提示词，并附加指定渠道的指令
+func (g *Generator) GenerateToolsPromptForChannel(functions []function.FunctionInfo, channel string) (string, error) {
+	return g.GenerateToolsPromptForLanguage(functions, channel, "")
+}
+
+// GenerateToolsPromptForLanguage 使用指定语言的模板生成原生 function calling 模式的系统提示词
+func (g *Generator) GenerateToolsPromptForLanguage(functions []function.FunctionInfo, channel, language string) (string, error) {
+	language, set := g.templatesFor(language)
+	tmpl := set.tools
+	if custom := g.customTemplate(true); custom != nil {
+		tmpl = custom
 	}
-	if err := g.systemTemplate.Execute(&buf, data); err != nil {
+	var buf bytes.Buffer
+	data := g.newTemplateData(functions, channel, language)
+	if err := tmpl.Execute(&buf, data); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
@@ -50,12 +319,15 @@ func (g *Generator) GenerateSystemPrompt(functions []function.FunctionInfo) (str
 
 // GenerateMinimalPrompt 生成精简版系统提示词
 func (g *Generator) GenerateMinimalPrompt(functions []function.FunctionInfo) (string, error) {
+	return g.GenerateMinimalPromptForLanguage(functions, "")
+}
+
+// GenerateMinimalPromptForLanguage 使用指定语言的模板生成精简版系统提示词
+func (g *Generator) GenerateMinimalPromptForLanguage(functions []function.FunctionInfo, language string) (string, error) {
+	language, set := g.templatesFor(language)
 	var buf bytes.Buffer
-	data := TemplateData{
-		Functions:    functions,
-		HasFunctions: len(functions) > 0,
-	}
-	if err := g.minimalTemplate.Execute(&buf, data); err != nil {
+	data := g.newTemplateData(functions, "", language)
+	if err := set.minimal.Execute(&buf, data); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
@@ -63,7 +335,7 @@ func (g *Generator) GenerateMinimalPrompt(functions []function.FunctionInfo) (st
 
 // GenerateWithCustomTemplate 使用自定义模板生成提示词
 func (g *Generator) GenerateWithCustomTemplate(tmplStr string, data any) (string, error) {
-	tmpl, err := template.New("custom").Parse(tmplStr)
+	tmpl, err := template.New("custom").Funcs(templates.Funcs).Parse(tmplStr)
 	if err != nil {
 		return "", err
 	}
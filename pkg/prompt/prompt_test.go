@@ -0,0 +1,135 @@
+package prompt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/prompt/templates"
+)
+
+func TestGenerator_ResolveLanguage(t *testing.T) {
+	g := NewGenerator()
+	if err := g.RegisterLanguage("zh_TW", templates.Set{System: "繁體 {{.Language}}"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"", templates.DefaultLanguage},
+		{"en", "en"},
+		{"ZH", "zh"},
+		{" ja ", "ja"},
+		{"zh-CN", "zh"},
+		{"zh_cn", "zh"},
+		{"zh-TW", "zh-tw"},
+		{"zh_tw", "zh-tw"},
+		{"ja-JP", "ja"},
+		{"fr", templates.DefaultLanguage},
+		{"fr-CA", templates.DefaultLanguage},
+	}
+
+	for _, tt := range tests {
+		if got := g.ResolveLanguage(tt.lang); got != tt.want {
+			t.Errorf("ResolveLanguage(%q) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestGenerator_TemplatesFor(t *testing.T) {
+	g := NewGenerator()
+	if err := g.SetDefaultLanguage("ja"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有模板的语言回退到默认语言，返回的模板与语言一致
+	lang, set := g.templatesFor("fr")
+	if lang != "ja" || set != g.languages["ja"] {
+		t.Errorf("templatesFor(fr) = %q, want the ja templates", lang)
+	}
+	lang, set = g.templatesFor("zh-Hans")
+	if lang != "zh" || set != g.languages["zh"] {
+		t.Errorf("templatesFor(zh-Hans) = %q, want the zh templates", lang)
+	}
+
+	prompt, err := g.GenerateSystemPromptForLanguage(nil, "", "zh-CN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if english, _ := g.GenerateSystemPromptForLanguage(nil, "", "en"); prompt == english {
+		t.Error("zh-CN prompt is the same as the English prompt")
+	}
+}
+
+func TestGenerator_HasLanguage(t *testing.T) {
+	g := NewGenerator()
+	tests := []struct {
+		lang string
+		want bool
+	}{
+		{"en", true},
+		{"zh-CN", true},
+		{"JA_jp", true},
+		{"fr", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := g.HasLanguage(tt.lang); got != tt.want {
+			t.Errorf("HasLanguage(%q) = %v, want %v", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestGenerator_SetDefaultLanguage(t *testing.T) {
+	g := NewGenerator()
+	if err := g.SetDefaultLanguage("fr"); !errors.Is(err, ErrUnknownLanguage) {
+		t.Errorf("SetDefaultLanguage(fr) error = %v, want ErrUnknownLanguage", err)
+	}
+	if got := g.DefaultLanguage(); got != templates.DefaultLanguage {
+		t.Errorf("DefaultLanguage() = %q after a rejected change", got)
+	}
+	if err := g.SetDefaultLanguage("ZH"); err != nil || g.DefaultLanguage() != "zh" {
+		t.Errorf("SetDefaultLanguage(ZH) = %v, default %q", err, g.DefaultLanguage())
+	}
+	if got := strings.Join(g.Languages(), ","); got != "en,ja,zh" {
+		t.Errorf("Languages() = %s", got)
+	}
+}
+
+func TestGenerator_ChannelPrompts(t *testing.T) {
+	g := NewGenerator()
+	g.SetChannelPrompts(map[string]string{"telegram": "Keep replies short and friendly."})
+
+	generators := map[string]func([]function.FunctionInfo, string) (string, error){
+		"xml":   g.GenerateSystemPromptForChannel,
+		"tools": g.GenerateToolsPromptForChannel,
+	}
+	for name, generate := range generators {
+		t.Run(name, func(t *testing.T) {
+			telegram, err := generate(nil, "telegram")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(telegram, "Keep replies short and friendly.") {
+				t.Errorf("telegram prompt does not contain the channel instruction:\n%s", telegram)
+			}
+
+			// 未配置指令的渠道不附加任何内容
+			api, err := generate(nil, "api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(api, "Keep replies short") {
+				t.Errorf("api prompt contains the telegram instruction:\n%s", api)
+			}
+		})
+	}
+
+	g.SetChannelPrompts(nil)
+	if got := g.ChannelPrompt("telegram"); got != "" {
+		t.Errorf("ChannelPrompt after clearing = %q", got)
+	}
+}
@@ -0,0 +1,48 @@
+// Package templates 提供所有提示词模板
+// 模板统一管理，方便其他模块引用和定制
+package templates
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+)
+
+// Funcs 模板可用的辅助函数，解析模板前需通过 template.Funcs 注册
+var Funcs = template.FuncMap{
+	"formatParams": FormatParams,
+}
+
+// FormatParams 将参数列表格式化为 Markdown 列表
+// 嵌套对象的子字段缩进展示，调用时使用 "parent.child" 作为参数名
+func FormatParams(params []function.ParamInfo) string {
+	var buf strings.Builder
+	writeParams(&buf, params, 0)
+	return buf.String()
+}
+
+// writeParams 按层级缩进写入参数
+func writeParams(buf *strings.Builder, params []function.ParamInfo, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, p := range params {
+		buf.WriteString(indent + "- " + p.Name + " (" + p.Type + ")")
+		if p.Required {
+			buf.WriteString(" *required*")
+		}
+		if p.Default != "" {
+			buf.WriteString(" (default: " + p.Default + ")")
+		}
+		if p.Validation != "" {
+			buf.WriteString(" [" + p.Validation + "]")
+		}
+		if p.Description != "" {
+			buf.WriteString(" - " + p.Description)
+		}
+		buf.WriteString("\n")
+
+		if len(p.Fields) > 0 {
+			writeParams(buf, p.Fields, depth+1)
+		}
+	}
+}
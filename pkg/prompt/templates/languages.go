@@ -0,0 +1,42 @@
+// Package templates 提供所有提示词模板
+// 模板统一管理，方便其他模块引用和定制
+package templates
+
+// 内置系统提示词的语言
+const (
+	LanguageEnglish  = "en"
+	LanguageChinese  = "zh"
+	LanguageJapanese = "ja"
+)
+
+// DefaultLanguage 未指定语言时使用的系统提示词语言
+const DefaultLanguage = LanguageEnglish
+
+// Set 一种语言的系统提示词模板
+// 各语言的模板使用相同的 TemplateData 字段，章节结构保持一致，只是说明文字不同
+type Set struct {
+	System  string // XML 调用模式的完整系统提示词
+	Tools   string // 原生 function calling 模式的系统提示词
+	Minimal string // 精简版系统提示词
+}
+
+// Builtin 返回内置的多语言模板（语言代码 -> 模板）
+func Builtin() map[string]Set {
+	return map[string]Set{
+		LanguageEnglish: {
+			System:  SystemPrompt,
+			Tools:   SystemPromptTools,
+			Minimal: SystemPromptMinimal,
+		},
+		LanguageChinese: {
+			System:  SystemPromptZh,
+			Tools:   SystemPromptToolsZh,
+			Minimal: SystemPromptMinimalZh,
+		},
+		LanguageJapanese: {
+			System:  SystemPromptJa,
+			Tools:   SystemPromptToolsJa,
+			Minimal: SystemPromptMinimalJa,
+		},
+	}
+}
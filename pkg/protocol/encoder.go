@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 )
 
 // ResultStatus 结果状态
@@ -15,6 +16,7 @@ type ResultStatus string
 const (
 	StatusSuccess ResultStatus = "success"
 	StatusError   ResultStatus = "error"
+	StatusPending ResultStatus = "pending" // 等待用户确认，尚未执行
 )
 
 // CallResult Function 执行结果
@@ -25,6 +27,15 @@ type CallResult struct {
 	Data     any          // 结构化数据（将编码为 TOON）
 	Markdown string       // Markdown 输出
 	Error    string       // 错误信息
+
+	Attachments []AttachmentInfo // 附件元信息（不含文件内容）
+}
+
+// AttachmentInfo 附件元信息，告诉 AI 生成了哪些文件
+type AttachmentInfo struct {
+	Filename string
+	MimeType string
+	Size     int
 }
 
 // Encoder 响应编码器
@@ -41,6 +52,7 @@ func NewEncoder() *Encoder {
 //   <message>操作完成</message>
 //   <data type="toon">TOON_CONTENT</data>
 //   <output type="markdown">MARKDOWN_CONTENT</output>
+//   <attachments><file name="report.pdf" type="application/pdf" size="1024"/></attachments>
 // </result>
 func (e *Encoder) EncodeResult(result *CallResult) (string, error) {
 	var buf bytes.Buffer
@@ -63,15 +75,15 @@ func (e *Encoder) EncodeResult(result *CallResult) (string, error) {
 
 	// 写入数据（TOON 格式）
 	if result.Data != nil {
-		toonContent, err := e.encodeToTOON(result.Data)
+		toonContent, err := EncodeTOON(result.Data)
 		if err != nil {
 			// 如果 TOON 编码失败，退回 JSON
 			jsonContent, _ := json.Marshal(result.Data)
-			buf.WriteString(fmt.Sprintf("  <data type=\"json\">%s</data>\n", string(jsonContent)))
+			buf.WriteString(fmt.Sprintf("  <data type=\"json\">%s</data>\n", escapeXMLText(string(jsonContent))))
 		} else if toonContent != "" {
 			buf.WriteString("  <data type=\"toon\">\n")
-			// 缩进 TOON 内容
-			for _, line := range strings.Split(toonContent, "\n") {
+			// 缩进 TOON 内容（转义后再写入，防止数据中的标签破坏协议结构）
+			for _, line := range strings.Split(escapeXMLText(toonContent), "\n") {
 				buf.WriteString("    " + line + "\n")
 			}
 			buf.WriteString("  </data>\n")
@@ -81,13 +93,23 @@ func (e *Encoder) EncodeResult(result *CallResult) (string, error) {
 	// 写入 Markdown 输出
 	if result.Markdown != "" {
 		buf.WriteString("  <output type=\"markdown\">\n")
-		buf.WriteString(result.Markdown)
+		buf.WriteString(escapeXMLText(result.Markdown))
 		if !strings.HasSuffix(result.Markdown, "\n") {
 			buf.WriteString("\n")
 		}
 		buf.WriteString("  </output>\n")
 	}
 
+	// 写入附件元信息（文件内容直接发给用户，不进入上下文）
+	if len(result.Attachments) > 0 {
+		buf.WriteString("  <attachments>\n")
+		for _, att := range result.Attachments {
+			buf.WriteString(fmt.Sprintf("    <file name=\"%s\" type=\"%s\" size=\"%d\"/>\n",
+				escapeXML(att.Filename), escapeXML(att.MimeType), att.Size))
+		}
+		buf.WriteString("  </attachments>\n")
+	}
+
 	buf.WriteString("</result>")
 	return buf.String(), nil
 }
@@ -99,10 +121,26 @@ func (e *Encoder) EncodeError(funcName string, errMsg string) string {
 </result>`, funcName, escapeXML(errMsg))
 }
 
-// encodeToTOON 将数据编码为 TOON 格式
+// EncodeClassifiedError 编码带错误类别和处理建议的错误响应，errType 或 hint 为空时省略对应部分
+func (e *Encoder) EncodeClassifiedError(funcName, errType, errMsg, hint string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`<result name="%s" status="error">`, funcName))
+	if errType != "" {
+		sb.WriteString(fmt.Sprintf("\n  <error type=\"%s\">%s</error>", escapeXML(errType), escapeXML(errMsg)))
+	} else {
+		sb.WriteString(fmt.Sprintf("\n  <error>%s</error>", escapeXML(errMsg)))
+	}
+	if hint != "" {
+		sb.WriteString(fmt.Sprintf("\n  <hint>%s</hint>", escapeXML(hint)))
+	}
+	sb.WriteString("\n</result>")
+	return sb.String()
+}
+
+// EncodeTOON 将数据编码为 TOON 格式
 // 简化实现：对于 slice of struct，生成表格格式
-// 对于单个 struct，生成 key: value 格式
-func (e *Encoder) encodeToTOON(data any) (string, error) {
+// 对于单个 struct，生成 key: value 格式；map 按 key 排序，值为 slice 时展开为带名称的表格
+func EncodeTOON(data any) (string, error) {
 	if data == nil {
 		return "", nil
 	}
@@ -119,20 +157,20 @@ func (e *Encoder) encodeToTOON(data any) (string, error) {
 
 	switch v.Kind() {
 	case reflect.Slice, reflect.Array:
-		return e.encodeSliceToTOON(v)
+		return encodeSliceToTOON("items", v)
 	case reflect.Struct:
-		return e.encodeStructToTOON(v)
+		return encodeStructToTOON(v)
 	case reflect.Map:
-		return e.encodeMapToTOON(v)
+		return encodeMapToTOON(v)
 	default:
 		// 简单类型直接返回字符串
 		return fmt.Sprintf("%v", v.Interface()), nil
 	}
 }
 
-// encodeSliceToTOON 将 slice 编码为 TOON 表格格式
-// 格式：items[N]{field1,field2}: row1 row2 ...
-func (e *Encoder) encodeSliceToTOON(v reflect.Value) (string, error) {
+// encodeSliceToTOON 将 slice 编码为 TOON 表格格式，name 为表格名称
+// 格式：name[N]{field1,field2}: row1 row2 ...
+func encodeSliceToTOON(name string, v reflect.Value) (string, error) {
 	if v.Len() == 0 {
 		return "", nil
 	}
@@ -147,7 +185,7 @@ func (e *Encoder) encodeSliceToTOON(v reflect.Value) (string, error) {
 
 	if elem.Kind() != reflect.Struct {
 		// 非结构体 slice，简单列出
-		buf.WriteString(fmt.Sprintf("items[%d]:", v.Len()))
+		buf.WriteString(fmt.Sprintf("%s[%d]:", name, v.Len()))
 		for i := 0; i < v.Len(); i++ {
 			if i > 0 {
 				buf.WriteString(",")
@@ -175,8 +213,8 @@ func (e *Encoder) encodeSliceToTOON(v reflect.Value) (string, error) {
 		fields = append(fields, name)
 	}
 
-	// 写入头部：items[N]{field1,field2}:
-	buf.WriteString(fmt.Sprintf("items[%d]{%s}:\n", v.Len(), strings.Join(fields, ",")))
+	// 写入头部：name[N]{field1,field2}:
+	buf.WriteString(fmt.Sprintf("%s[%d]{%s}:\n", name, v.Len(), strings.Join(fields, ",")))
 
 	// 写入每一行数据
 	for i := 0; i < v.Len(); i++ {
@@ -200,7 +238,7 @@ func (e *Encoder) encodeSliceToTOON(v reflect.Value) (string, error) {
 }
 
 // encodeStructToTOON 将单个 struct 编码为 key: value 格式
-func (e *Encoder) encodeStructToTOON(v reflect.Value) (string, error) {
+func encodeStructToTOON(v reflect.Value) (string, error) {
 	var buf bytes.Buffer
 	t := v.Type()
 
@@ -225,15 +263,28 @@ func (e *Encoder) encodeStructToTOON(v reflect.Value) (string, error) {
 	return strings.TrimSuffix(buf.String(), "\n"), nil
 }
 
-// encodeMapToTOON 将 map 编码为 key: value 格式
-func (e *Encoder) encodeMapToTOON(v reflect.Value) (string, error) {
+// encodeMapToTOON 将 map 编码为 key: value 格式，key 排序以保证输出稳定
+// 值为 slice 时编码为以 key 命名的表格，如 tasks[2]{id,name}:
+func encodeMapToTOON(v reflect.Value) (string, error) {
 	var buf bytes.Buffer
 
-	iter := v.MapRange()
-	for iter.Next() {
-		key := iter.Key()
-		val := iter.Value()
-		buf.WriteString(fmt.Sprintf("%v: %s\n", key.Interface(), formatValue(val)))
+	for _, key := range sortedMapKeys(v) {
+		name := fmt.Sprintf("%v", key.Interface())
+		val := indirectValue(v.MapIndex(key))
+
+		if val.IsValid() && (val.Kind() == reflect.Slice || val.Kind() == reflect.Array) {
+			if val.Len() == 0 {
+				buf.WriteString(fmt.Sprintf("%s[0]:\n", name))
+				continue
+			}
+			table, err := encodeSliceToTOON(name, val)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(table + "\n")
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("%s: %s\n", name, formatValue(val)))
 	}
 
 	return strings.TrimSuffix(buf.String(), "\n"), nil
@@ -245,6 +296,10 @@ func formatValue(v reflect.Value) string {
 		return ""
 	}
 
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+
 	switch v.Kind() {
 	case reflect.String:
 		s := v.String()
@@ -272,3 +327,23 @@ func escapeXML(s string) string {
 	s = strings.ReplaceAll(s, "'", "&apos;")
 	return s
 }
+
+// escapeXMLText 转义元素内容中的标签字符
+// 只处理 &、<、>，保留引号以免 data/markdown 内容过于冗长
+// 函数返回的内容中若含有 </result> 或 <call> 等标签，会被转义为普通文本
+func escapeXMLText(s string) string {
+	s = strings.ReplaceAll(s, "&", "&amp;")
+	s = strings.ReplaceAll(s, "<", "&lt;")
+	s = strings.ReplaceAll(s, ">", "&gt;")
+	return s
+}
+
+// unescapeXML 还原 escapeXML 转义的字符
+func unescapeXML(s string) string {
+	s = strings.ReplaceAll(s, "&lt;", "<")
+	s = strings.ReplaceAll(s, "&gt;", ">")
+	s = strings.ReplaceAll(s, "&quot;", "\"")
+	s = strings.ReplaceAll(s, "&apos;", "'")
+	s = strings.ReplaceAll(s, "&amp;", "&")
+	return s
+}
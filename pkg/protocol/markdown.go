@@ -0,0 +1,195 @@
+// Package protocol 提供 XML + TOON 协议解析和编码
+package protocol
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// EncodeMarkdownTable 将数据渲染为人类友好的 Markdown 表格，用于给终端用户展示
+// slice of struct / slice of map 渲染为多列表格，每个元素一行；
+// 单个 struct / map 渲染为 字段 | 值 两列表格；
+// 简单类型的 slice 渲染为单列表格。空数据返回空字符串
+func (e *Encoder) EncodeMarkdownTable(data any) string {
+	v := indirectValue(reflect.ValueOf(data))
+	if !v.IsValid() {
+		return ""
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return encodeSliceToMarkdown(v)
+	case reflect.Struct:
+		header, cells := structColumns(v.Type())
+		rows := make([][]string, len(header))
+		for i, name := range header {
+			rows[i] = []string{name, formatMarkdownCell(v.FieldByIndex(cells[i]))}
+		}
+		return renderMarkdownTable([]string{"field", "value"}, rows)
+	case reflect.Map:
+		keys := sortedMapKeys(v)
+		rows := make([][]string, len(keys))
+		for i, key := range keys {
+			rows[i] = []string{escapeMarkdownCell(fmt.Sprint(key.Interface())), formatMarkdownCell(v.MapIndex(key))}
+		}
+		return renderMarkdownTable([]string{"key", "value"}, rows)
+	default:
+		return ""
+	}
+}
+
+// encodeSliceToMarkdown 将 slice 渲染为表格，列由第一个元素决定（map 元素取所有 key 的并集）
+func encodeSliceToMarkdown(v reflect.Value) string {
+	if v.Len() == 0 {
+		return ""
+	}
+
+	first := indirectValue(v.Index(0))
+	switch first.Kind() {
+	case reflect.Struct:
+		header, indexes := structColumns(first.Type())
+		rows := make([][]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item := indirectValue(v.Index(i))
+			row := make([]string, len(indexes))
+			if item.IsValid() && item.Type() == first.Type() {
+				for j, index := range indexes {
+					row[j] = formatMarkdownCell(item.FieldByIndex(index))
+				}
+			}
+			rows = append(rows, row)
+		}
+		return renderMarkdownTable(header, rows)
+
+	case reflect.Map:
+		// 以字符串形式收集所有 key，保证不同元素的列对齐
+		seen := make(map[string]bool)
+		var header []string
+		for i := 0; i < v.Len(); i++ {
+			item := indirectValue(v.Index(i))
+			if item.Kind() != reflect.Map {
+				continue
+			}
+			for _, key := range item.MapKeys() {
+				name := fmt.Sprint(key.Interface())
+				if !seen[name] {
+					seen[name] = true
+					header = append(header, name)
+				}
+			}
+		}
+		sort.Strings(header)
+
+		rows := make([][]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item := indirectValue(v.Index(i))
+			values := make(map[string]string)
+			if item.Kind() == reflect.Map {
+				iter := item.MapRange()
+				for iter.Next() {
+					values[fmt.Sprint(iter.Key().Interface())] = formatMarkdownCell(iter.Value())
+				}
+			}
+			row := make([]string, len(header))
+			for j, name := range header {
+				row[j] = values[name]
+			}
+			rows = append(rows, row)
+		}
+		escaped := make([]string, len(header))
+		for i, name := range header {
+			escaped[i] = escapeMarkdownCell(name)
+		}
+		return renderMarkdownTable(escaped, rows)
+
+	default:
+		rows := make([][]string, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			rows[i] = []string{formatMarkdownCell(v.Index(i))}
+		}
+		return renderMarkdownTable([]string{"value"}, rows)
+	}
+}
+
+// structColumns 返回结构体导出字段的列名（优先使用 json tag）及字段索引
+func structColumns(t reflect.Type) ([]string, [][]int) {
+	var names []string
+	var indexes [][]int
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" { // 跳过非导出字段
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		names = append(names, escapeMarkdownCell(name))
+		indexes = append(indexes, field.Index)
+	}
+	return names, indexes
+}
+
+// renderMarkdownTable 按表头和行数据生成 | col | col | 形式的表格
+func renderMarkdownTable(header []string, rows [][]string) string {
+	if len(header) == 0 {
+		return ""
+	}
+
+	var buf strings.Builder
+	buf.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	buf.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+	for _, row := range rows {
+		buf.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// formatMarkdownCell 格式化单元格内容，嵌套的 slice / map / struct 以紧凑形式展示
+func formatMarkdownCell(v reflect.Value) string {
+	v = indirectValue(v)
+	if !v.IsValid() {
+		return ""
+	}
+	if v.Kind() == reflect.String {
+		return escapeMarkdownCell(v.String())
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return escapeMarkdownCell(s.String())
+	}
+	return escapeMarkdownCell(fmt.Sprintf("%v", v.Interface()))
+}
+
+// escapeMarkdownCell 转义会破坏表格结构的字符：竖线转义，换行替换为 <br>
+func escapeMarkdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	s = strings.ReplaceAll(s, "\n", "<br>")
+	return s
+}
+
+// indirectValue 解开指针和 interface，nil 时返回无效值
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// sortedMapKeys 返回按字符串形式排序的 map key，保证输出稳定
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
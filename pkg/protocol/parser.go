@@ -42,6 +42,7 @@ func NewParser() *Parser {
 // <call name="function_name">
 //   <p>key: value</p>
 //   <data type="toon">TOON_CONTENT</data>
+//   <data type="text" name="content">多行大文本</data>
 // </call>
 func (p *Parser) ParseCall(content string) (*CallRequest, error) {
 	// 提取 <call>...</call> 内容
@@ -50,6 +51,12 @@ func (p *Parser) ParseCall(content string) (*CallRequest, error) {
 		return nil, err
 	}
 
+	// 大文本参数按原文提取，不经过 XML 解析，内容中可以包含 < & 等字符
+	callContent, textParams, err := extractTextData(callContent)
+	if err != nil {
+		return nil, err
+	}
+
 	// 解析 XML
 	var rawCall RawCall
 	if err := xml.Unmarshal([]byte(callContent), &rawCall); err != nil {
@@ -69,8 +76,8 @@ func (p *Parser) ParseCall(content string) (*CallRequest, error) {
 	for _, item := range rawCall.Items {
 		switch item.XMLName.Local {
 		case "p":
-			// 解析 key: value 格式的参数
-			key, value := parseKeyValue(strings.TrimSpace(item.Content))
+			// 解析 key: value 格式的参数，值可以跨多行
+			key, value := parseParam(item.Content)
 			if key != "" {
 				req.Params[key] = value
 			}
@@ -81,6 +88,10 @@ func (p *Parser) ParseCall(content string) (*CallRequest, error) {
 		}
 	}
 
+	for key, value := range textParams {
+		req.Params[key] = value
+	}
+
 	if req.Name == "" {
 		return nil, &ParseError{Message: "call name is required"}
 	}
@@ -94,8 +105,7 @@ func (p *Parser) ParseCalls(content string) ([]*CallRequest, error) {
 	var calls []*CallRequest
 
 	// 使用正则找到所有 <call>...</call>
-	re := regexp.MustCompile(`(?s)<call[^>]*>.*?</call>`)
-	matches := re.FindAllString(content, -1)
+	matches := callBlockPattern.FindAllString(content, -1)
 
 	for _, match := range matches {
 		call, err := p.ParseCall(match)
@@ -133,6 +143,75 @@ func (p *Parser) ExtractTextAfterCall(content string) string {
 	return strings.TrimSpace(content[idx+7:])
 }
 
+// callBlockPattern 匹配完整的 <call>...</call> 块
+var callBlockPattern = regexp.MustCompile(`(?s)<call[^>]*>.*?</call>`)
+
+// blankLinesPattern 匹配连续的多个空行
+var blankLinesPattern = regexp.MustCompile(`\n[ \t]*\n(?:[ \t]*\n)+`)
+
+// StripCalls 移除所有函数调用块，保留其余全部文本
+// 多个调用之间的说明文字按原顺序以空行分隔，多余的空行会被压缩
+func (p *Parser) StripCalls(content string) string {
+	var kept []string
+	for _, part := range callBlockPattern.Split(content, -1) {
+		if text := strings.TrimSpace(part); text != "" {
+			kept = append(kept, text)
+		}
+	}
+	return blankLinesPattern.ReplaceAllString(strings.Join(kept, "\n\n"), "\n\n")
+}
+
+// ResultSummary 函数结果摘要（去掉 data/markdown 等冗长内容）
+type ResultSummary struct {
+	Name    string       `json:"name"`
+	Status  ResultStatus `json:"status"`
+	Message string       `json:"message,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+var (
+	resultBlockRe   = regexp.MustCompile(`(?s)<result name="([^"]*)" status="([^"]*)">(.*?)</result>`)
+	resultMessageRe = regexp.MustCompile(`(?s)<message>(.*?)</message>`)
+	resultErrorRe   = regexp.MustCompile(`(?s)<error[^>]*>(.*?)</error>`)
+)
+
+// HasResult 检查内容中是否包含函数执行结果
+func (p *Parser) HasResult(content string) bool {
+	return resultBlockRe.MatchString(content)
+}
+
+// ExtractResultSummaries 从函数结果内容中提取摘要
+// 只保留函数名、状态和 message/error，用于导出或精简存储
+func (p *Parser) ExtractResultSummaries(content string) []ResultSummary {
+	var summaries []ResultSummary
+	for _, m := range resultBlockRe.FindAllStringSubmatch(content, -1) {
+		summaries = append(summaries, resultSummary(m))
+	}
+	return summaries
+}
+
+// ReplaceResults 把内容中的每个函数结果块替换为 replace 对其摘要的返回值，结果块之外的文本原样保留
+func (p *Parser) ReplaceResults(content string, replace func(ResultSummary) string) string {
+	return resultBlockRe.ReplaceAllStringFunc(content, func(block string) string {
+		return replace(resultSummary(resultBlockRe.FindStringSubmatch(block)))
+	})
+}
+
+// resultSummary 由 resultBlockRe 的匹配结果构建摘要
+func resultSummary(m []string) ResultSummary {
+	summary := ResultSummary{
+		Name:   m[1],
+		Status: ResultStatus(m[2]),
+	}
+	if msg := resultMessageRe.FindStringSubmatch(m[3]); msg != nil {
+		summary.Message = unescapeXML(strings.TrimSpace(msg[1]))
+	}
+	if errMsg := resultErrorRe.FindStringSubmatch(m[3]); errMsg != nil {
+		summary.Error = unescapeXML(strings.TrimSpace(errMsg[1]))
+	}
+	return summary
+}
+
 // extractCallXML 从内容中提取 <call>...</call> XML
 func extractCallXML(content string) (string, error) {
 	// 查找 <call 开始位置
@@ -150,6 +229,88 @@ func extractCallXML(content string) (string, error) {
 	return content[startIdx : startIdx+endIdx+7], nil
 }
 
+var (
+	// textDataPattern 匹配 <data type="text" name="...">...</data> 大文本参数
+	textDataPattern = regexp.MustCompile(`(?s)<data\s+([^>]*\btype="text"[^>]*)>(.*?)</data>`)
+	nameAttrPattern = regexp.MustCompile(`\bname="([^"]*)"`)
+)
+
+// extractTextData 提取并移除调用中的大文本参数，返回剩余的调用 XML 和参数名到原文的映射
+// 原文只去掉首尾紧挨标签的换行和公共缩进，其余内容（包括换行）原样保留
+func extractTextData(callContent string) (string, map[string]string, error) {
+	matches := textDataPattern.FindAllStringSubmatch(callContent, -1)
+	if len(matches) == 0 {
+		return callContent, nil, nil
+	}
+
+	params := make(map[string]string, len(matches))
+	for _, m := range matches {
+		name := nameAttrPattern.FindStringSubmatch(m[1])
+		if name == nil || name[1] == "" {
+			return "", nil, &ParseError{Message: `<data type="text"> requires a name attribute`}
+		}
+		text := m[2]
+		if inner, ok := strings.CutPrefix(strings.TrimSpace(text), "<![CDATA["); ok {
+			text = strings.TrimSuffix(inner, "]]>")
+		}
+		params[name[1]] = dedent(trimBlankEdges(text))
+	}
+	return textDataPattern.ReplaceAllString(callContent, ""), params, nil
+}
+
+// parseParam 解析 <p> 中的参数，值可以跨多行
+// 值从下一行开始时（<p>key:\n  多行内容</p>），去掉各行的公共缩进；与 key 同行开始时原样保留换行和缩进
+func parseParam(content string) (string, string) {
+	key, value := parseKeyValue(strings.TrimSpace(content))
+	if key == "" || !strings.Contains(value, "\n") {
+		return key, value
+	}
+
+	_, rest, _ := strings.Cut(content, ":")
+	if first, _, _ := strings.Cut(rest, "\n"); strings.TrimSpace(first) == "" {
+		value = dedent(trimBlankEdges(rest))
+	}
+	return key, value
+}
+
+// trimBlankEdges 去掉开头和结尾的空白行，保留首行的缩进
+func trimBlankEdges(s string) string {
+	lines := strings.Split(s, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dedent 去掉所有非空行共同的前导空白（AI 常按 XML 层级缩进内容）
+func dedent(s string) string {
+	lines := strings.Split(s, "\n")
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == -1 || n < indent {
+			indent = n
+		}
+	}
+	if indent <= 0 {
+		return s
+	}
+	for i, line := range lines {
+		if len(line) >= indent {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 // parseKeyValue 解析 "key: value" 格式
 func parseKeyValue(s string) (string, string) {
 	idx := strings.Index(s, ":")
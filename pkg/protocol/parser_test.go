@@ -173,6 +173,121 @@ func TestParser_ExtractTextBeforeCall(t *testing.T) {
 	}
 }
 
+func TestParser_StripCalls(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name: "text between multiple calls",
+			input: `先做A
+<call name="a"><p>x: 1</p></call>
+然后做B<call name="b"></call>
+完成`,
+			want: "先做A\n\n然后做B\n\n完成",
+		},
+		{
+			name:  "no calls",
+			input: "  just text  ",
+			want:  "just text",
+		},
+		{
+			name:  "only calls",
+			input: `<call name="a"></call>  <call name="b"></call>`,
+			want:  "",
+		},
+		{
+			name:  "collapse blank lines",
+			input: "line1\n\n\n\nline2<call name=\"a\"></call>",
+			want:  "line1\n\nline2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parser.StripCalls(tt.input); got != tt.want {
+				t.Errorf("StripCalls() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParser_ParseCall_MultilineText(t *testing.T) {
+	parser := NewParser()
+
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]string
+	}{
+		{
+			name: "value continues on following lines",
+			input: `<call name="analyze">
+  <p>code: def f():
+    return 1</p>
+</call>`,
+			want: map[string]string{"code": "def f():\n    return 1"},
+		},
+		{
+			name: "value starts on next line",
+			input: `<call name="analyze">
+  <p>content:
+    first line
+      indented line
+
+    last line
+  </p>
+</call>`,
+			want: map[string]string{"content": "first line\n  indented line\n\nlast line"},
+		},
+		{
+			name: "text data block with special characters",
+			input: `<call name="analyze">
+  <p>mode: summary</p>
+  <data type="text" name="content">
+    if a < b && b > c {
+        return "<ok>"
+    }
+  </data>
+</call>`,
+			want: map[string]string{
+				"mode":    "summary",
+				"content": "if a < b && b > c {\n    return \"<ok>\"\n}",
+			},
+		},
+		{
+			name: "text data block with cdata",
+			input: `<call name="analyze">
+  <data name="content" type="text"><![CDATA[line 1
+line 2]]></data>
+</call>`,
+			want: map[string]string{"content": "line 1\nline 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.ParseCall(tt.input)
+			if err != nil {
+				t.Fatalf("ParseCall() error = %v", err)
+			}
+			for k, v := range tt.want {
+				if got.Params[k] != v {
+					t.Errorf("ParseCall() Params[%s] = %q, want %q", k, got.Params[k], v)
+				}
+			}
+		})
+	}
+
+	// 大文本参数必须指定参数名
+	if _, err := parser.ParseCall(`<call name="analyze"><data type="text">oops</data></call>`); err == nil {
+		t.Error("ParseCall() expected error for text data without name")
+	}
+}
+
 func TestParseKeyValue(t *testing.T) {
 	tests := []struct {
 		input     string
@@ -195,3 +310,55 @@ func TestParseKeyValue(t *testing.T) {
 		}
 	}
 }
+
+func TestParser_ExtractResultSummaries(t *testing.T) {
+	parser := NewParser()
+
+	input := `<result name="get_time" status="success">
+  <message>现在是 &lt;10:00&gt;</message>
+  <data type="toon">
+    time: 10:00
+  </data>
+</result>
+<result name="send_message" status="error">
+  <error>channel not configured</error>
+</result>
+<result name="fetch_url" status="error">
+  <error type="timeout">upstream timed out</error>
+  <hint>retry later</hint>
+</result>`
+
+	if !parser.HasResult(input) {
+		t.Fatal("HasResult() = false, want true")
+	}
+
+	summaries := parser.ExtractResultSummaries(input)
+	if len(summaries) != 3 {
+		t.Fatalf("ExtractResultSummaries() got %d results, want 3", len(summaries))
+	}
+
+	if summaries[0].Name != "get_time" || summaries[0].Status != StatusSuccess {
+		t.Errorf("summaries[0] = %+v", summaries[0])
+	}
+	if summaries[0].Message != "现在是 <10:00>" {
+		t.Errorf("summaries[0].Message = %q, want unescaped message", summaries[0].Message)
+	}
+	if summaries[1].Status != StatusError || summaries[1].Error != "channel not configured" {
+		t.Errorf("summaries[1] = %+v", summaries[1])
+	}
+	if summaries[2].Error != "upstream timed out" {
+		t.Errorf("summaries[2].Error = %q, want the classified error message", summaries[2].Error)
+	}
+}
+
+func TestParser_ReplaceResults(t *testing.T) {
+	parser := NewParser()
+	input := "note\n<result name=\"a\" status=\"success\">\n  <message>ok</message>\n  <data type=\"toon\">\n    x: 1\n  </data>\n</result>\ntail"
+
+	got := parser.ReplaceResults(input, func(s ResultSummary) string {
+		return "[" + s.Name + ":" + s.Message + "]"
+	})
+	if want := "note\n[a:ok]\ntail"; got != want {
+		t.Errorf("ReplaceResults() = %q, want %q", got, want)
+	}
+}
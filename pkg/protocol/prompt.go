@@ -20,7 +20,7 @@ type PromptGenerator struct {
 // NewPromptGenerator 创建 Prompt 生成器
 // Deprecated: 请使用 prompt.NewGenerator()
 func NewPromptGenerator() *PromptGenerator {
-	tmpl := template.Must(template.New("system_prompt").Parse(templates.SystemPrompt))
+	tmpl := template.Must(template.New("system_prompt").Funcs(templates.Funcs).Parse(templates.SystemPrompt))
 	return &PromptGenerator{
 		template: tmpl,
 	}
@@ -1,13 +1,79 @@
 // Package scheduler 提供定时任务调度功能
 package scheduler
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/types"
+)
 
 // AgentExecutor 定义 Agent 执行接口
 // 用于解耦 scheduler 和 chassis 包，避免循环依赖
 type AgentExecutor interface {
 	// Execute 执行一次对话，返回 LLM 的最终回复
 	// prompt: 发送给 LLM 的提示词
+	// channel: 创建任务时的渠道上下文，未记录渠道时为 nil
+	// scopes: 创建任务的调用者作用域，执行时只能调用这些作用域允许的函数；nil 表示内部创建的任务，不受限
 	// 返回: LLM 的最终回复文本
-	Execute(ctx context.Context, prompt string) (string, error)
+	Execute(ctx context.Context, prompt string, channel *types.ChannelContext, scopes []string) (string, error)
+}
+
+// EncodeCallerScopes 把创建者的作用域编码为任务中存储的 JSON
+// ok 为 false 表示未鉴权的内部调用，返回空字符串，执行时不受限
+func EncodeCallerScopes(scopes []string, ok bool) string {
+	if !ok {
+		return ""
+	}
+	if scopes == nil {
+		scopes = []string{}
+	}
+	data, _ := json.Marshal(scopes)
+	return string(data)
+}
+
+// ParseCallerScopes 解析 EncodeCallerScopes 存储的创建者作用域，为空时返回 nil（不受限）
+// 格式不合法时返回空列表，按无任何作用域执行，避免越权
+func ParseCallerScopes(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	scopes := []string{}
+	if err := json.Unmarshal([]byte(raw), &scopes); err != nil || scopes == nil {
+		return []string{}
+	}
+	return scopes
+}
+
+// parseChannel 解析任务中以 JSON 存储的渠道上下文，为空或格式不合法时返回 nil
+func parseChannel(raw string) *types.ChannelContext {
+	if raw == "" {
+		return nil
+	}
+	var channel types.ChannelContext
+	if err := json.Unmarshal([]byte(raw), &channel); err != nil || channel.Type == "" {
+		return nil
+	}
+	return &channel
+}
+
+// emitTaskEvent 输出一次任务执行的结构化事件，taskType 为 delay 或 cron；events 为 nil 时写入包级的 Sink
+func emitTaskEvent(events *observability.Emitter, taskType string, taskID uint, name string, start time.Time, err error) {
+	event := observability.Event{
+		Kind:       observability.EventTaskExecution,
+		Name:       name,
+		Status:     "success",
+		DurationMs: time.Since(start).Milliseconds(),
+		Attributes: map[string]any{
+			"task_type": taskType,
+			"task_id":   taskID,
+		},
+	}
+	if err != nil {
+		event.Status = "error"
+		event.Error = err.Error()
+	}
+	events.Emit(event)
 }
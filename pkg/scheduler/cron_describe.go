@@ -0,0 +1,279 @@
+// Package scheduler 提供定时任务调度功能
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cronMonthNames 月份字段支持的英文缩写
+var cronMonthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+// cronDowNames 星期字段支持的英文缩写
+var cronDowNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// weekdayNames 星期的中文名，下标为 cron 中的取值（0 为周日）
+var weekdayNames = []string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"}
+
+// cronField 解析后的单个 cron 字段
+type cronField struct {
+	any    bool  // * 或 ?，或取值覆盖了整个范围
+	step   int   // */n 形式的 n，其余为 0
+	values []int // 显式取值（已展开范围和步长），升序
+}
+
+// DescribeCron 将 6 字段（秒 分 时 日 月 周）cron 表达式转换为中文描述
+// 如 "0 30 9 * * *" → "每天 9:30"，"0 0 9 * * 1-5" → "每周一至周五 9:00"，"0 */5 * * * *" → "每 5 分钟"
+// 只覆盖常见写法，无法描述时返回空字符串
+func DescribeCron(expr string) string {
+	fields := strings.Fields(expr)
+	if len(fields) != 6 {
+		return ""
+	}
+
+	specs := []struct {
+		min, max int
+		names    map[string]int
+	}{
+		{0, 59, nil},
+		{0, 59, nil},
+		{0, 23, nil},
+		{1, 31, nil},
+		{1, 12, cronMonthNames},
+		{0, 6, cronDowNames},
+	}
+	parsed := make([]cronField, len(fields))
+	for i, f := range fields {
+		field, ok := parseCronField(f, specs[i].min, specs[i].max, specs[i].names)
+		if !ok {
+			return ""
+		}
+		parsed[i] = field
+	}
+
+	timePart, interval := describeCronTime(parsed[0], parsed[1], parsed[2])
+	datePart := describeCronDate(parsed[3], parsed[4], parsed[5])
+	if timePart == "" || datePart == "" {
+		return ""
+	}
+	if interval {
+		if datePart == "每天" {
+			return timePart
+		}
+		return datePart + "，" + timePart
+	}
+	return datePart + " " + timePart
+}
+
+// parseCronField 解析单个字段，支持 *、?、*/n、a、a-b、a-b/n、a/n 以及逗号分隔的组合
+func parseCronField(s string, min, max int, names map[string]int) (cronField, bool) {
+	if s == "*" || s == "?" {
+		return cronField{any: true}, true
+	}
+	if strings.HasPrefix(s, "*/") {
+		step, err := strconv.Atoi(s[2:])
+		if err != nil || step <= 0 {
+			return cronField{}, false
+		}
+		if step == 1 {
+			return cronField{any: true}, true
+		}
+		return cronField{step: step}, true
+	}
+
+	value := func(v string) (int, bool) {
+		if n, ok := names[strings.ToUpper(v)]; ok {
+			return n, true
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, false
+		}
+		// 星期字段中 7 也表示周日
+		if max == 6 && n == 7 {
+			n = 0
+		}
+		return n, n >= min && n <= max
+	}
+
+	set := make(map[int]bool)
+	for _, part := range strings.Split(s, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return cronField{}, false
+			}
+			step = n
+		}
+
+		lo, hi := 0, 0
+		if a, b, isRange := strings.Cut(rangePart, "-"); isRange {
+			var okA, okB bool
+			lo, okA = value(a)
+			hi, okB = value(b)
+			if !okA || !okB || lo > hi {
+				return cronField{}, false
+			}
+		} else {
+			var ok bool
+			lo, ok = value(rangePart)
+			if !ok {
+				return cronField{}, false
+			}
+			hi = lo
+			if hasStep {
+				hi = max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+
+	if len(set) == max-min+1 {
+		return cronField{any: true}, true
+	}
+	values := make([]int, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Ints(values)
+	return cronField{values: values}, true
+}
+
+// single 字段是否只有一个取值
+func (f cronField) single() bool {
+	return len(f.values) == 1
+}
+
+// contiguous 取值是否为连续的 3 个及以上的整数，适合描述为 "a至b"
+func contiguous(values []int) bool {
+	if len(values) < 3 {
+		return false
+	}
+	for i := 1; i < len(values); i++ {
+		if values[i] != values[i-1]+1 {
+			return false
+		}
+	}
+	return true
+}
+
+// joinValues 将取值描述为 "1、15" 或 "1至5"
+func joinValues(values []int, format func(int) string) string {
+	if contiguous(values) {
+		return format(values[0]) + "至" + format(values[len(values)-1])
+	}
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = format(v)
+	}
+	return strings.Join(parts, "、")
+}
+
+// clock 格式化时刻，秒为 0 时省略
+func clock(hour, minute, second int) string {
+	if second != 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hour, minute, second)
+	}
+	return fmt.Sprintf("%d:%02d", hour, minute)
+}
+
+// describeCronTime 描述秒、分、时三个字段，interval 表示结果是"每 N 分钟"这类间隔描述
+func describeCronTime(sec, min, hour cronField) (desc string, interval bool) {
+	// 秒级触发
+	if sec.any || sec.step > 0 {
+		if !min.any || !hour.any {
+			return "", false
+		}
+		if sec.any {
+			return "每秒", true
+		}
+		return fmt.Sprintf("每 %d 秒", sec.step), true
+	}
+	if !sec.single() {
+		return "", false
+	}
+	s := sec.values[0]
+
+	// 分钟级触发
+	if min.any || min.step > 0 {
+		if !hour.any {
+			return "", false
+		}
+		if min.step > 0 {
+			return fmt.Sprintf("每 %d 分钟", min.step), true
+		}
+		if s == 0 {
+			return "每分钟", true
+		}
+		return fmt.Sprintf("每分钟的第 %d 秒", s), true
+	}
+
+	minutes := joinValues(min.values, strconv.Itoa)
+	switch {
+	case hour.any:
+		return fmt.Sprintf("每小时的第 %s 分", minutes), true
+	case hour.step > 0:
+		return fmt.Sprintf("每 %d 小时的第 %s 分", hour.step, minutes), true
+	case len(hour.values)*len(min.values) <= 4:
+		times := make([]string, 0, len(hour.values)*len(min.values))
+		for _, h := range hour.values {
+			for _, m := range min.values {
+				times = append(times, clock(h, m, s))
+			}
+		}
+		return strings.Join(times, "、"), false
+	case contiguous(hour.values):
+		return fmt.Sprintf("%d 点至 %d 点每小时的第 %s 分", hour.values[0], hour.values[len(hour.values)-1], minutes), false
+	default:
+		return "", false
+	}
+}
+
+// describeCronDate 描述日、月、周三个字段
+func describeCronDate(dom, month, dow cronField) string {
+	if dom.step > 0 || month.step > 0 || dow.step > 0 {
+		return ""
+	}
+	// 日和周同时指定时 cron 按"或"匹配，描述容易误导，不处理
+	if !dom.any && !dow.any {
+		return ""
+	}
+
+	prefix := ""
+	if !month.any {
+		prefix = "每年 " + joinValues(month.values, strconv.Itoa) + " 月"
+	}
+
+	switch {
+	case !dom.any:
+		days := joinValues(dom.values, strconv.Itoa) + " 日"
+		if prefix == "" {
+			return "每月 " + days
+		}
+		return prefix + " " + days
+	case !dow.any:
+		// 按周一在前排序，周日排在最后
+		values := append([]int(nil), dow.values...)
+		sort.Slice(values, func(i, j int) bool { return (values[i]+6)%7 < (values[j]+6)%7 })
+		weekdays := "每" + joinValues(values, func(v int) string { return weekdayNames[v] })
+		if prefix == "" {
+			return weekdays
+		}
+		return prefix + "的" + weekdays
+	case prefix != "":
+		return prefix + "每天"
+	default:
+		return "每天"
+	}
+}
@@ -0,0 +1,41 @@
+// Package scheduler 提供定时任务调度功能
+package scheduler
+
+import "testing"
+
+func TestDescribeCron(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: "0 30 9 * * *", want: "每天 9:30"},
+		{expr: "0 0 9 * * 1-5", want: "每周一至周五 9:00"},
+		{expr: "0 0 9 * * MON-FRI", want: "每周一至周五 9:00"},
+		{expr: "0 0 10 * * 1,3,5", want: "每周一、周三、周五 10:00"},
+		{expr: "0 0 10 * * 6,0", want: "每周六、周日 10:00"},
+		{expr: "0 0 0 1 * *", want: "每月 1 日 0:00"},
+		{expr: "0 0 8 1,15 * *", want: "每月 1、15 日 8:00"},
+		{expr: "0 0 12 25 12 *", want: "每年 12 月 25 日 12:00"},
+		{expr: "0 30 9,18 * * *", want: "每天 9:30、18:30"},
+		{expr: "0 0 9-18 * * 1-5", want: "每周一至周五 9 点至 18 点每小时的第 0 分"},
+		{expr: "30 0 9 * * *", want: "每天 9:00:30"},
+		{expr: "*/10 * * * * *", want: "每 10 秒"},
+		{expr: "* * * * * *", want: "每秒"},
+		{expr: "0 */5 * * * *", want: "每 5 分钟"},
+		{expr: "0 * * * * *", want: "每分钟"},
+		{expr: "0 15 * * * *", want: "每小时的第 15 分"},
+		{expr: "0 0 */2 * * *", want: "每 2 小时的第 0 分"},
+		{expr: "0 */5 * * * 1-5", want: "每周一至周五，每 5 分钟"},
+		{expr: "0 0 9 1 * 1", want: ""},
+		{expr: "0 0 9 * *", want: ""},
+		{expr: "0 0 25 * * *", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := DescribeCron(tt.expr); got != tt.want {
+				t.Errorf("DescribeCron(%q) = %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
@@ -64,8 +64,13 @@ func (r *CronTaskRepository) DeleteByID(id uint) error {
 
 // List 列出任务
 func (r *CronTaskRepository) List(limit, offset int) ([]CronTask, error) {
+	return r.ListFiltered(CronTaskFilter{}, limit, offset)
+}
+
+// ListFiltered 列出符合条件的任务，新创建的在前
+func (r *CronTaskRepository) ListFiltered(filter CronTaskFilter, limit, offset int) ([]CronTask, error) {
 	var tasks []CronTask
-	query := r.db.Order("id DESC")
+	query := r.applyFilter(filter).Order("id DESC")
 	if limit > 0 {
 		query = query.Limit(limit)
 	}
@@ -80,13 +85,23 @@ func (r *CronTaskRepository) List(limit, offset int) ([]CronTask, error) {
 
 // Count 统计任务数量
 func (r *CronTaskRepository) Count() (int64, error) {
+	return r.CountFiltered(CronTaskFilter{})
+}
+
+// CountFiltered 统计符合条件的任务数量
+func (r *CronTaskRepository) CountFiltered(filter CronTaskFilter) (int64, error) {
 	var count int64
-	if err := r.db.Model(&CronTask{}).Count(&count).Error; err != nil {
+	if err := r.applyFilter(filter).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
+// applyFilter 按标签和分组过滤
+func (r *CronTaskRepository) applyFilter(filter CronTaskFilter) *gorm.DB {
+	return applyTagFilter(r.db.Model(&CronTask{}), CronTask{}.TableName(), filter.Tag, filter.Group)
+}
+
 // ListAll 列出所有任务（用于恢复调度）
 func (r *CronTaskRepository) ListAll() ([]CronTask, error) {
 	var tasks []CronTask
@@ -6,99 +6,187 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
+
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/storage"
+)
+
+// 预览次数限制
+const (
+	DefaultPreviewCount = 5
+	MaxPreviewCount     = 50
 )
 
+// cronParser 6 字段（秒级）cron 表达式解析器
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
 // CronScheduler Cron 定时任务调度器
 type CronScheduler struct {
 	db            *gorm.DB
 	taskRepo      *CronTaskRepository
 	execRepo      *CronExecutionRepository
 	agentExecutor AgentExecutor
+	notifier      ResultNotifier
 	logger        *slog.Logger
+	events        *observability.Emitter // 任务执行事件的输出，为 nil 时写入包级的 Sink
 
 	cron     *cron.Cron
 	mu       sync.RWMutex
 	entryMap map[uint]cron.EntryID // 任务ID -> cron EntryID
+	runs     map[uint]*taskRun     // 任务ID -> 运行状态（用于并发策略）
+	running  bool                  // 调度器是否在运行
+	inflight sync.WaitGroup        // 正在执行的任务
 
+	// 调度上下文，取消后不再接收新触发
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	// 执行上下文，供在途任务使用，drain 超时后才取消
+	execCtx    context.Context
+	execCancel context.CancelFunc
 }
 
 // NewCronScheduler 创建 Cron 调度器
 func NewCronScheduler(db *gorm.DB, logger *slog.Logger) *CronScheduler {
 	ctx, cancel := context.WithCancel(context.Background())
+	execCtx, execCancel := context.WithCancel(context.Background())
 
 	// 创建支持秒级的 cron 调度器（6 字段格式）
 	c := cron.New(cron.WithSeconds())
 
 	return &CronScheduler{
-		db:       db,
-		taskRepo: NewCronTaskRepository(db),
-		execRepo: NewCronExecutionRepository(db),
-		logger:   logger,
-		cron:     c,
-		entryMap: make(map[uint]cron.EntryID),
-		ctx:      ctx,
-		cancel:   cancel,
+		db:         db,
+		taskRepo:   NewCronTaskRepository(db),
+		execRepo:   NewCronExecutionRepository(db),
+		notifier:   newGuardedNotifier(),
+		logger:     logger,
+		cron:       c,
+		entryMap:   make(map[uint]cron.EntryID),
+		runs:       make(map[uint]*taskRun),
+		ctx:        ctx,
+		cancel:     cancel,
+		execCtx:    execCtx,
+		execCancel: execCancel,
 	}
 }
 
 // SetAgentExecutor 设置 Agent 执行器（用于依赖注入，避免循环依赖）
+// 应在 Start 之前设置，否则启动时恢复的到期任务会因为没有执行器而失败
 func (s *CronScheduler) SetAgentExecutor(executor AgentExecutor) {
+	s.mu.Lock()
 	s.agentExecutor = executor
+	s.mu.Unlock()
+}
+
+// executor 返回当前的 Agent 执行器，未设置时为 nil
+func (s *CronScheduler) executor() AgentExecutor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.agentExecutor
+}
+
+// SetEmitter 设置任务执行事件的输出，未设置时写入包级的 Sink；应在 Start 之前设置
+func (s *CronScheduler) SetEmitter(events *observability.Emitter) {
+	s.events = events
+}
+
+// SetResultNotifier 设置任务执行结果的通知器，默认拒绝推送到内网地址；应在 Start 之前设置
+func (s *CronScheduler) SetResultNotifier(notifier ResultNotifier) {
+	s.notifier = notifier
 }
 
 // Start 启动调度器
 func (s *CronScheduler) Start() error {
 	s.logger.Info("starting cron scheduler")
 
-	// 自动迁移表
-	if err := s.db.AutoMigrate(&CronTask{}, &CronExecution{}); err != nil {
+	// 按版本执行未应用的迁移
+	if err := storage.NewMigrator(s.db, cronMigrationScope, cronMigrations...).Migrate(); err != nil {
 		return fmt.Errorf("failed to migrate cron tables: %w", err)
 	}
 
 	// 恢复所有任务
-	if err := s.recoverTasks(); err != nil {
+	misfires, err := s.recoverTasks()
+	if err != nil {
 		return fmt.Errorf("failed to recover cron tasks: %w", err)
 	}
 
 	// 启动 cron 调度器
 	s.cron.Start()
 
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+
+	// 按补偿策略执行停机期间错过的触发
+	if len(misfires) > 0 {
+		go s.runMisfires(misfires)
+	}
+
 	s.logger.Info("cron scheduler started")
 	return nil
 }
 
 // Stop 停止调度器
-func (s *CronScheduler) Stop() {
-	s.logger.Info("stopping cron scheduler")
+// 立即停止接收新触发，并在 timeout 内等待在途任务完成，超时后强制取消
+// timeout <= 0 时直接取消在途任务；无论哪种情况都会等被取消的任务退出后才返回
+func (s *CronScheduler) Stop(timeout time.Duration) {
+	s.logger.Info("stopping cron scheduler", "drain_timeout", timeout)
+
+	s.mu.Lock()
 	s.cancel()
+	s.mu.Unlock()
 
-	// 停止 cron 调度器
-	ctx := s.cron.Stop()
-	<-ctx.Done()
+	// 停止 cron 调度器（不再触发新任务）
+	s.cron.Stop()
+
+	// 等待在途任务，超时后取消它们，并等待取消后的状态写入完成，避免在数据库关闭后写入
+	if !waitWithTimeout(&s.inflight, timeout) {
+		s.logger.Warn("drain timeout, cancelling in-flight tasks")
+	}
+	s.execCancel()
+	s.inflight.Wait()
 
 	s.mu.Lock()
 	s.entryMap = make(map[uint]cron.EntryID)
+	s.running = false
 	s.mu.Unlock()
 
 	s.logger.Info("cron scheduler stopped")
 }
 
-// recoverTasks 恢复所有任务
-func (s *CronScheduler) recoverTasks() error {
+// IsRunning 返回调度器是否在运行
+func (s *CronScheduler) IsRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.running
+}
+
+// misfire 一次需要补偿的错过执行
+type misfire struct {
+	taskID      uint
+	scheduledAt time.Time
+}
+
+// recoverTasks 恢复所有任务，返回需要按补偿策略补偿执行的错过触发
+func (s *CronScheduler) recoverTasks() ([]misfire, error) {
 	tasks, err := s.taskRepo.ListAll()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	s.logger.Info("recovering cron tasks", "count", len(tasks))
 
+	now := time.Now()
+	var misfires []misfire
 	for _, task := range tasks {
+		// 必须在 scheduleTask 更新 NextRunAt 之前计算错过的触发
+		misfires = append(misfires, s.checkMisfires(&task, now)...)
+
 		if err := s.scheduleTask(&task); err != nil {
 			s.logger.Error("failed to recover cron task", "task_id", task.ID, "name", task.Name, "error", err)
 		} else {
@@ -106,15 +194,124 @@ func (s *CronScheduler) recoverTasks() error {
 		}
 	}
 
-	return nil
+	return misfires, nil
 }
 
-// CreateTask 创建定时任务
+// missedRuns 计算任务从上次记录的下次执行时间到 now 之间错过的触发时间，最多 MaxMisfireRuns 个
+func missedRuns(task *CronTask, now time.Time) []time.Time {
+	if task.NextRunAt == nil || task.NextRunAt.After(now) {
+		return nil
+	}
+	schedule, err := cronParser.Parse(task.CronExpr)
+	if err != nil {
+		return nil
+	}
+
+	var runs []time.Time
+	for next := *task.NextRunAt; !next.IsZero() && !next.After(now) && len(runs) < MaxMisfireRuns; next = schedule.Next(next) {
+		runs = append(runs, next)
+	}
+	return runs
+}
+
+// checkMisfires 按任务的补偿策略决定错过的触发中哪些需要补偿执行
+func (s *CronScheduler) checkMisfires(task *CronTask, now time.Time) []misfire {
+	runs := missedRuns(task, now)
+	if len(runs) == 0 {
+		return nil
+	}
+
+	var selected []time.Time
+	switch task.MisfirePolicy {
+	case MisfireRunOnce:
+		// 补偿最近一次错过的触发
+		selected = runs[len(runs)-1:]
+	case MisfireRunAll:
+		selected = runs
+	}
+
+	s.logger.Warn("cron task missed runs while the scheduler was down",
+		"task_id", task.ID,
+		"name", task.Name,
+		"missed", len(runs),
+		"first_missed", runs[0],
+		"misfire_policy", task.MisfirePolicy,
+		"compensating", len(selected),
+	)
+
+	misfires := make([]misfire, 0, len(selected))
+	for _, at := range selected {
+		misfires = append(misfires, misfire{taskID: task.ID, scheduledAt: at})
+	}
+	return misfires
+}
+
+// runMisfires 依次补偿执行错过的触发，调度器停止后不再继续
+func (s *CronScheduler) runMisfires(misfires []misfire) {
+	for _, m := range misfires {
+		if s.ctx.Err() != nil {
+			return
+		}
+		s.logger.Info("running missed cron execution", "task_id", m.taskID, "scheduled_at", m.scheduledAt)
+		s.runTask(m.taskID, m.scheduledAt, true)
+	}
+}
+
+// CreateTask 创建定时任务（并发策略为 allow）
 // channel 参数为可选的渠道上下文 JSON 字符串
 func (s *CronScheduler) CreateTask(name, cronExpr, prompt, description string, channel ...string) (*CronTask, error) {
+	return s.CreateTaskWithPolicy(name, cronExpr, prompt, description, ConcurrencyAllow, channel...)
+}
+
+// CreateTaskWithPolicy 创建定时任务，并指定上次执行未完成时的并发策略（补偿策略为 ignore）
+func (s *CronScheduler) CreateTaskWithPolicy(name, cronExpr, prompt, description string, policy ConcurrencyPolicy, channel ...string) (*CronTask, error) {
+	return s.CreateTaskWithPolicies(name, cronExpr, prompt, description, policy, MisfireIgnore, channel...)
+}
+
+// CreateTaskWithPolicies 创建定时任务，并指定并发策略和错过执行时的补偿策略
+func (s *CronScheduler) CreateTaskWithPolicies(name, cronExpr, prompt, description string, policy ConcurrencyPolicy, misfirePolicy MisfirePolicy, channel ...string) (*CronTask, error) {
+	opts := CronTaskOptions{ConcurrencyPolicy: policy, MisfirePolicy: misfirePolicy}
+	if len(channel) > 0 {
+		opts.Channel = channel[0]
+	}
+	return s.CreateTaskWithOptions(name, cronExpr, prompt, description, opts)
+}
+
+// CronTaskOptions 创建定时任务的可选参数，零值字段使用默认值
+type CronTaskOptions struct {
+	ConcurrencyPolicy ConcurrencyPolicy // 并发策略，默认 allow
+	MisfirePolicy     MisfirePolicy     // 错过执行时的补偿策略，默认 ignore
+	Channel           string            // 渠道上下文 JSON 字符串
+	WebhookURL        string            // 每次执行结束后推送执行结果的地址
+	Tags              []string          // 标签，用于分类查看和批量管理
+	Group             string            // 分组，如项目名
+	CallerScopes      string            // 创建者的作用域，由 EncodeCallerScopes 生成
+}
+
+// CreateTaskWithOptions 按可选参数创建定时任务
+func (s *CronScheduler) CreateTaskWithOptions(name, cronExpr, prompt, description string, opts CronTaskOptions) (*CronTask, error) {
+	policy, err := ParseConcurrencyPolicy(string(opts.ConcurrencyPolicy))
+	if err != nil {
+		return nil, err
+	}
+	misfirePolicy, err := ParseMisfirePolicy(string(opts.MisfirePolicy))
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateWebhookURL(opts.WebhookURL); err != nil {
+		return nil, err
+	}
+	tags, err := NormalizeTags(opts.Tags)
+	if err != nil {
+		return nil, err
+	}
+	group, err := NormalizeGroup(opts.Group)
+	if err != nil {
+		return nil, err
+	}
+
 	// 验证 cron 表达式
-	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	schedule, err := parser.Parse(cronExpr)
+	schedule, err := cronParser.Parse(cronExpr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid cron expression: %w", err)
 	}
@@ -129,16 +326,19 @@ func (s *CronScheduler) CreateTask(name, cronExpr, prompt, description string, c
 
 	// 创建任务
 	task := &CronTask{
-		Name:        name,
-		CronExpr:    cronExpr,
-		Prompt:      prompt,
-		Description: description,
-		NextRunAt:   &nextRun,
-	}
-
-	// 设置渠道信息（如果提供）
-	if len(channel) > 0 && channel[0] != "" {
-		task.Channel = channel[0]
+		Name:         name,
+		CronExpr:     cronExpr,
+		Prompt:       prompt,
+		Description:  description,
+		Channel:      opts.Channel,
+		NextRunAt:    &nextRun,
+		WebhookURL:   opts.WebhookURL,
+		CallerScopes: opts.CallerScopes,
+		Tags:         tags,
+		Group:        group,
+
+		ConcurrencyPolicy: policy,
+		MisfirePolicy:     misfirePolicy,
 	}
 
 	if err := s.taskRepo.Create(task); err != nil {
@@ -157,11 +357,46 @@ func (s *CronScheduler) CreateTask(name, cronExpr, prompt, description string, c
 		"name", name,
 		"cron_expr", cronExpr,
 		"next_run", nextRun,
+		"concurrency_policy", policy,
+		"misfire_policy", misfirePolicy,
 	)
 
 	return task, nil
 }
 
+// PreviewNextRuns 预览 cron 表达式接下来 n 次的触发时间
+// n <= 0 时使用 DefaultPreviewCount，最多 MaxPreviewCount 次
+func (s *CronScheduler) PreviewNextRuns(cronExpr string, n int) ([]time.Time, error) {
+	return previewNextRuns(cronExpr, n, time.Now())
+}
+
+// previewNextRuns 从 from 开始计算接下来 n 次触发时间
+func previewNextRuns(cronExpr string, n int, from time.Time) ([]time.Time, error) {
+	schedule, err := cronParser.Parse(cronExpr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression: %w", err)
+	}
+
+	if n <= 0 {
+		n = DefaultPreviewCount
+	}
+	if n > MaxPreviewCount {
+		n = MaxPreviewCount
+	}
+
+	runs := make([]time.Time, 0, n)
+	next := from
+	for i := 0; i < n; i++ {
+		next = schedule.Next(next)
+		// 永远不会触发的表达式（如 2 月 30 日）返回零值
+		if next.IsZero() {
+			break
+		}
+		runs = append(runs, next)
+	}
+	return runs, nil
+}
+
 // scheduleTask 调度单个任务
 func (s *CronScheduler) scheduleTask(task *CronTask) error {
 	s.mu.Lock()
@@ -200,17 +435,97 @@ func (s *CronScheduler) scheduleTask(task *CronTask) error {
 	return nil
 }
 
-// executeTask 执行任务
+// beginTask 登记一个在途任务，调度器已停止时返回 false
+func (s *CronScheduler) beginTask() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return false
+	}
+	s.inflight.Add(1)
+	return true
+}
+
+// taskRun 单个任务的运行状态
+type taskRun struct {
+	mu      sync.Mutex  // 执行锁，持有期间表示任务正在运行
+	waiting atomic.Bool // queue 策略下是否已有排队等待的触发
+}
+
+// taskRunFor 获取任务的运行状态，不存在时创建
+func (s *CronScheduler) taskRunFor(taskID uint) *taskRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.runs[taskID]
+	if !ok {
+		run = &taskRun{}
+		s.runs[taskID] = run
+	}
+	return run
+}
+
+// acquireRun 按并发策略获取执行权，返回释放函数
+// ok 为 false 表示本次触发应跳过
+func (s *CronScheduler) acquireRun(task *CronTask) (release func(), ok bool) {
+	switch task.ConcurrencyPolicy {
+	case ConcurrencySkip:
+		run := s.taskRunFor(task.ID)
+		if !run.mu.TryLock() {
+			return nil, false
+		}
+		return run.mu.Unlock, true
+
+	case ConcurrencyQueue:
+		run := s.taskRunFor(task.ID)
+		if run.mu.TryLock() {
+			return run.mu.Unlock, true
+		}
+		// 已有排队的触发时不再继续排队，避免慢任务导致触发无限堆积
+		if !run.waiting.CompareAndSwap(false, true) {
+			return nil, false
+		}
+		run.mu.Lock()
+		run.waiting.Store(false)
+		return run.mu.Unlock, true
+
+	default:
+		return func() {}, true
+	}
+}
+
+// recordSkipped 记录一次因并发策略被跳过的执行
+func (s *CronScheduler) recordSkipped(task *CronTask, scheduledAt time.Time) {
+	now := time.Now()
+	exec := &CronExecution{
+		CronTaskID:  task.ID,
+		ScheduledAt: scheduledAt,
+		CronExpr:    task.CronExpr,
+		StartedAt:   now,
+		FinishedAt:  &now,
+		Status:      CronStatusSkipped,
+		Error:       "previous execution is still running",
+	}
+	if err := s.execRepo.Create(exec); err != nil {
+		s.logger.Error("failed to create skipped execution record", "task_id", task.ID, "error", err)
+	}
+}
+
+// executeTask 执行按计划触发的任务
 func (s *CronScheduler) executeTask(taskID uint) {
+	s.runTask(taskID, time.Now(), false)
+}
+
+// runTask 执行任务，misfire 为 true 表示补偿执行错过的触发
+func (s *CronScheduler) runTask(taskID uint, scheduledAt time.Time, misfire bool) {
 	// 检查调度器是否已停止
-	select {
-	case <-s.ctx.Done():
+	if !s.beginTask() {
 		return
-	default:
 	}
+	defer s.inflight.Done()
 
-	scheduledAt := time.Now()
-	s.logger.Info("executing cron task", "task_id", taskID, "scheduled_at", scheduledAt)
+	s.logger.Info("executing cron task", "task_id", taskID, "scheduled_at", scheduledAt, "misfire", misfire)
 
 	// 获取任务信息
 	task, err := s.taskRepo.GetByID(taskID)
@@ -219,13 +534,41 @@ func (s *CronScheduler) executeTask(taskID uint) {
 		return
 	}
 
+	// 按并发策略检查上次执行是否完成
+	release, ok := s.acquireRun(task)
+	if !ok {
+		s.logger.Warn("previous cron execution still running, skipping",
+			"task_id", taskID,
+			"concurrency_policy", task.ConcurrencyPolicy,
+		)
+		s.recordSkipped(task, scheduledAt)
+		return
+	}
+	defer release()
+
+	// 排队期间调度器可能已停止
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	// 执行前先更新下次执行时间，执行期间停机时本次触发不会被当作错过的执行
+	nextRunAt := s.nextRunAt(taskID)
+	if !nextRunAt.IsZero() {
+		_ = s.taskRepo.UpdateNextRunAt(taskID, nextRunAt)
+	}
+
 	// 创建执行记录
 	startedAt := time.Now()
 	exec := &CronExecution{
 		CronTaskID:  taskID,
 		ScheduledAt: scheduledAt,
+		CronExpr:    task.CronExpr,
 		StartedAt:   startedAt,
 		Status:      CronStatusRunning,
+		Misfire:     misfire,
+	}
+	if !nextRunAt.IsZero() {
+		exec.NextRunAt = &nextRunAt
 	}
 	if err := s.execRepo.Create(exec); err != nil {
 		s.logger.Error("failed to create execution record", "task_id", taskID, "error", err)
@@ -233,7 +576,8 @@ func (s *CronScheduler) executeTask(taskID uint) {
 	}
 
 	// 检查 AgentExecutor 是否已设置
-	if s.agentExecutor == nil {
+	executor := s.executor()
+	if executor == nil {
 		errMsg := "agent executor not set"
 		s.logger.Error(errMsg, "task_id", taskID)
 		s.finishExecution(exec, CronStatusFailed, "", errMsg)
@@ -241,10 +585,12 @@ func (s *CronScheduler) executeTask(taskID uint) {
 	}
 
 	// 执行：调用 Agent
-	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Minute)
+	ctx, cancel := context.WithTimeout(s.execCtx, 5*time.Minute)
 	defer cancel()
 
-	result, execErr := s.agentExecutor.Execute(ctx, task.Prompt)
+	start := time.Now()
+	result, execErr := executor.Execute(ctx, task.Prompt, parseChannel(task.Channel), ParseCallerScopes(task.CallerScopes))
+	emitTaskEvent(s.events, "cron", taskID, task.Name, start, execErr)
 
 	// 更新执行记录
 	if execErr != nil {
@@ -256,17 +602,34 @@ func (s *CronScheduler) executeTask(taskID uint) {
 		s.finishExecution(exec, CronStatusCompleted, result, "")
 	}
 
-	// 更新下次执行时间
+	notice := TaskResult{
+		TaskType:    "cron",
+		TaskID:      taskID,
+		TaskName:    task.Name,
+		ExecutionID: exec.ID,
+		Status:      string(CronStatusCompleted),
+		Result:      result,
+		ScheduledAt: scheduledAt,
+		StartedAt:   startedAt,
+		FinishedAt:  time.Now(),
+	}
+	if execErr != nil {
+		notice.Status, notice.Error = string(CronStatusFailed), execErr.Error()
+	}
+	notice.DurationMs = notice.FinishedAt.Sub(startedAt).Milliseconds()
+	dispatchNotify(s.execCtx, &s.inflight, s.notifier, s.logger, task.WebhookURL, notice)
+}
+
+// nextRunAt 获取任务在 cron 中的下一次计划时间，未调度时返回零值
+func (s *CronScheduler) nextRunAt(taskID uint) time.Time {
 	s.mu.RLock()
 	entryID, ok := s.entryMap[taskID]
 	s.mu.RUnlock()
 
-	if ok {
-		entry := s.cron.Entry(entryID)
-		if !entry.Next.IsZero() {
-			_ = s.taskRepo.UpdateNextRunAt(taskID, entry.Next)
-		}
+	if !ok {
+		return time.Time{}
 	}
+	return s.cron.Entry(entryID).Next
 }
 
 // finishExecution 完成执行记录
@@ -295,6 +658,7 @@ func (s *CronScheduler) DeleteTaskByID(id uint) error {
 		s.cron.Remove(entryID)
 		delete(s.entryMap, id)
 	}
+	delete(s.runs, id)
 	s.mu.Unlock()
 
 	// 删除执行历史
@@ -316,6 +680,27 @@ func (s *CronScheduler) GetTaskByID(id uint) (*CronTask, error) {
 	return s.taskRepo.GetByID(id)
 }
 
+// DeleteByFilter 批量删除符合标签和分组条件的任务（含执行历史），返回删除的数量
+// 标签和分组都为空时返回 ErrEmptyTaskFilter，避免误删全部任务
+func (s *CronScheduler) DeleteByFilter(filter CronTaskFilter) (int, error) {
+	if filter.IsEmpty() {
+		return 0, ErrEmptyTaskFilter
+	}
+	tasks, err := s.taskRepo.ListFiltered(filter, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, task := range tasks {
+		if err := s.DeleteTaskByID(task.ID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
 // ListTasks 列出任务
 func (s *CronScheduler) ListTasks(limit, offset int) ([]CronTask, error) {
 	return s.taskRepo.List(limit, offset)
@@ -326,6 +711,16 @@ func (s *CronScheduler) CountTasks() (int64, error) {
 	return s.taskRepo.Count()
 }
 
+// ListTasksFiltered 列出符合标签和分组条件的任务
+func (s *CronScheduler) ListTasksFiltered(filter CronTaskFilter, limit, offset int) ([]CronTask, error) {
+	return s.taskRepo.ListFiltered(filter, limit, offset)
+}
+
+// CountTasksFiltered 统计符合标签和分组条件的任务数量
+func (s *CronScheduler) CountTasksFiltered(filter CronTaskFilter) (int64, error) {
+	return s.taskRepo.CountFiltered(filter)
+}
+
 // GetExecutionHistory 获取任务的执行历史
 func (s *CronScheduler) GetExecutionHistory(taskID uint, limit, offset int) ([]CronExecution, error) {
 	return s.execRepo.ListByTaskID(taskID, limit, offset)
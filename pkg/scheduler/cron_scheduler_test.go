@@ -12,20 +12,26 @@ import (
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+
+	"github.com/KodaTao/AgentChassis/pkg/types"
 )
 
 // MockAgentExecutor 模拟 AgentExecutor
 type MockAgentExecutor struct {
 	mu         sync.Mutex
 	executions []string
+	channels   []*types.ChannelContext
+	scopes     [][]string
 	result     string
 	err        error
 }
 
-func (m *MockAgentExecutor) Execute(ctx context.Context, prompt string) (string, error) {
+func (m *MockAgentExecutor) Execute(ctx context.Context, prompt string, channel *types.ChannelContext, scopes []string) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.executions = append(m.executions, prompt)
+	m.channels = append(m.channels, channel)
+	m.scopes = append(m.scopes, scopes)
 	if m.err != nil {
 		return "", m.err
 	}
@@ -50,6 +56,24 @@ func (m *MockAgentExecutor) LastPrompt() string {
 	return m.executions[len(m.executions)-1]
 }
 
+func (m *MockAgentExecutor) LastChannel() *types.ChannelContext {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.channels) == 0 {
+		return nil
+	}
+	return m.channels[len(m.channels)-1]
+}
+
+func (m *MockAgentExecutor) LastScopes() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.scopes) == 0 {
+		return nil
+	}
+	return m.scopes[len(m.scopes)-1]
+}
+
 // setupCronTestDB 创建 Cron 测试数据库
 func setupCronTestDB(t *testing.T) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
@@ -80,7 +104,7 @@ func setupCronTestScheduler(t *testing.T) (*CronScheduler, *gorm.DB, *MockAgentE
 
 func TestCronScheduler_CreateTask(t *testing.T) {
 	scheduler, _, _ := setupCronTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	// 启动调度器
 	if err := scheduler.Start(); err != nil {
@@ -113,7 +137,7 @@ func TestCronScheduler_CreateTask(t *testing.T) {
 
 func TestCronScheduler_CreateTask_InvalidCronExpr(t *testing.T) {
 	scheduler, _, _ := setupCronTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -126,9 +150,51 @@ func TestCronScheduler_CreateTask_InvalidCronExpr(t *testing.T) {
 	}
 }
 
+func TestPreviewNextRuns(t *testing.T) {
+	from := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	runs, err := previewNextRuns("0 30 9 * * *", 3, from)
+	if err != nil {
+		t.Fatalf("previewNextRuns() error = %v", err)
+	}
+	want := []time.Time{
+		time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC),
+		time.Date(2024, 1, 3, 9, 30, 0, 0, time.UTC),
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("previewNextRuns() returned %d runs, want %d", len(runs), len(want))
+	}
+	for i := range want {
+		if !runs[i].Equal(want[i]) {
+			t.Errorf("runs[%d] = %v, want %v", i, runs[i], want[i])
+		}
+	}
+
+	// 默认次数与上限
+	runs, _ = previewNextRuns("*/10 * * * * *", 0, from)
+	if len(runs) != DefaultPreviewCount {
+		t.Errorf("Default count = %d, want %d", len(runs), DefaultPreviewCount)
+	}
+	runs, _ = previewNextRuns("*/10 * * * * *", 1000, from)
+	if len(runs) != MaxPreviewCount {
+		t.Errorf("Capped count = %d, want %d", len(runs), MaxPreviewCount)
+	}
+
+	// 永远不会触发的表达式
+	runs, err = previewNextRuns("0 0 0 30 2 *", 5, from)
+	if err != nil || len(runs) != 0 {
+		t.Errorf("Feb 30 preview = %v, %v, want empty", runs, err)
+	}
+
+	if _, err := previewNextRuns("invalid", 5, from); err == nil {
+		t.Error("Expected error for invalid cron expression")
+	}
+}
+
 func TestCronScheduler_CreateTask_EmptyPrompt(t *testing.T) {
 	scheduler, _, _ := setupCronTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -143,7 +209,7 @@ func TestCronScheduler_CreateTask_EmptyPrompt(t *testing.T) {
 
 func TestCronScheduler_CreateTask_DuplicateName(t *testing.T) {
 	scheduler, _, _ := setupCronTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -169,7 +235,7 @@ func TestCronScheduler_CreateTask_DuplicateName(t *testing.T) {
 
 func TestCronScheduler_DeleteTask(t *testing.T) {
 	scheduler, _, _ := setupCronTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -195,7 +261,7 @@ func TestCronScheduler_DeleteTask(t *testing.T) {
 
 func TestCronScheduler_ExecuteTask(t *testing.T) {
 	scheduler, _, mockExecutor := setupCronTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -231,11 +297,21 @@ func TestCronScheduler_ExecuteTask(t *testing.T) {
 	if len(executions) > 0 && executions[0].Status != CronStatusCompleted {
 		t.Errorf("Expected execution status 'completed', got '%s'", executions[0].Status)
 	}
+
+	// 验证执行记录保存了表达式快照和下一次计划时间
+	if len(executions) > 0 {
+		if executions[0].CronExpr != "* * * * * *" {
+			t.Errorf("Expected cron_expr snapshot '* * * * * *', got '%s'", executions[0].CronExpr)
+		}
+		if executions[0].NextRunAt == nil || !executions[0].NextRunAt.After(executions[0].ScheduledAt) {
+			t.Errorf("Expected next_run_at after scheduled_at, got %v", executions[0].NextRunAt)
+		}
+	}
 }
 
 func TestCronScheduler_ListTasks(t *testing.T) {
 	scheduler, _, _ := setupCronTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -262,7 +338,7 @@ func TestCronScheduler_ListTasks(t *testing.T) {
 
 func TestCronScheduler_ListTasks_Pagination(t *testing.T) {
 	scheduler, _, _ := setupCronTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -317,7 +393,7 @@ func TestCronScheduler_RecoverTasks(t *testing.T) {
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
 	}
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	// 验证任务被恢复
 	tasks, err := scheduler.ListTasks(20, 0)
@@ -450,7 +526,7 @@ func TestCronExecutionRepository_CRUD(t *testing.T) {
 
 func TestCronScheduler_SecondLevelPrecision(t *testing.T) {
 	scheduler, _, _ := setupCronTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -467,3 +543,179 @@ func TestCronScheduler_SecondLevelPrecision(t *testing.T) {
 		t.Errorf("Expected cron_expr '*/5 * * * * *', got '%s'", task.CronExpr)
 	}
 }
+
+func TestCronScheduler_ConcurrencyPolicy(t *testing.T) {
+	scheduler, _, _ := setupCronTestScheduler(t)
+
+	// 无效策略
+	if _, err := scheduler.CreateTaskWithPolicy("test", "0 * * * * *", "prompt", "", "parallel"); err != ErrInvalidConcurrencyPolicy {
+		t.Errorf("Expected ErrInvalidConcurrencyPolicy, got %v", err)
+	}
+
+	// allow：不做限制
+	allow := &CronTask{ConcurrencyPolicy: ConcurrencyAllow}
+	allow.ID = 1
+	release1, ok1 := scheduler.acquireRun(allow)
+	release2, ok2 := scheduler.acquireRun(allow)
+	if !ok1 || !ok2 {
+		t.Error("Expected allow policy to permit concurrent runs")
+	}
+	release1()
+	release2()
+
+	// skip：运行中时跳过
+	skip := &CronTask{ConcurrencyPolicy: ConcurrencySkip}
+	skip.ID = 2
+	release, ok := scheduler.acquireRun(skip)
+	if !ok {
+		t.Fatal("Expected first run to be acquired")
+	}
+	if _, ok := scheduler.acquireRun(skip); ok {
+		t.Error("Expected skip policy to reject overlapping run")
+	}
+	release()
+	release, ok = scheduler.acquireRun(skip)
+	if !ok {
+		t.Error("Expected run to be acquired after previous finished")
+	}
+	release()
+
+	// queue：排队一次，更多的触发跳过
+	queue := &CronTask{ConcurrencyPolicy: ConcurrencyQueue}
+	queue.ID = 3
+	release, ok = scheduler.acquireRun(queue)
+	if !ok {
+		t.Fatal("Expected first run to be acquired")
+	}
+
+	queued := make(chan func())
+	go func() {
+		r, ok := scheduler.acquireRun(queue)
+		if !ok {
+			close(queued)
+			return
+		}
+		queued <- r
+	}()
+
+	// 等待第二次触发进入排队状态
+	run := scheduler.taskRunFor(queue.ID)
+	deadline := time.Now().Add(time.Second)
+	for !run.waiting.Load() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, ok := scheduler.acquireRun(queue); ok {
+		t.Error("Expected queue policy to reject a second queued run")
+	}
+
+	release()
+	select {
+	case r, ok := <-queued:
+		if !ok {
+			t.Fatal("Expected queued run to be acquired")
+		}
+		r()
+	case <-time.After(time.Second):
+		t.Fatal("Queued run was not acquired after previous finished")
+	}
+}
+
+func TestCronScheduler_RecordSkipped(t *testing.T) {
+	scheduler, _, _ := setupCronTestScheduler(t)
+
+	task := &CronTask{CronExpr: "0 * * * * *"}
+	task.ID = 1
+	scheduler.recordSkipped(task, time.Now())
+
+	execs, err := scheduler.execRepo.ListByTaskIDWithStatus(task.ID, CronStatusSkipped, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to list executions: %v", err)
+	}
+	if len(execs) != 1 {
+		t.Fatalf("Expected 1 skipped execution, got %d", len(execs))
+	}
+	if execs[0].FinishedAt == nil {
+		t.Error("Expected skipped execution to have FinishedAt")
+	}
+}
+
+func TestMissedRuns(t *testing.T) {
+	now := time.Date(2024, 1, 15, 10, 0, 30, 0, time.UTC)
+	past := now.Add(-3 * time.Minute).Truncate(time.Minute) // 09:57:00
+
+	task := &CronTask{CronExpr: "0 * * * * *", NextRunAt: &past}
+	runs := missedRuns(task, now)
+	// 09:57、09:58、09:59、10:00
+	if len(runs) != 4 || !runs[0].Equal(past) || !runs[3].Equal(now.Truncate(time.Minute)) {
+		t.Errorf("missedRuns() = %v, want 4 runs from %v", runs, past)
+	}
+
+	future := now.Add(time.Minute)
+	if runs := missedRuns(&CronTask{CronExpr: "0 * * * * *", NextRunAt: &future}, now); len(runs) != 0 {
+		t.Errorf("missedRuns() with future NextRunAt = %v, want none", runs)
+	}
+
+	// 长时间停机后的秒级任务最多补偿 MaxMisfireRuns 次
+	longAgo := now.Add(-24 * time.Hour)
+	if runs := missedRuns(&CronTask{CronExpr: "* * * * * *", NextRunAt: &longAgo}, now); len(runs) != MaxMisfireRuns {
+		t.Errorf("missedRuns() = %d runs, want capped at %d", len(runs), MaxMisfireRuns)
+	}
+}
+
+func TestCronScheduler_MisfirePolicy(t *testing.T) {
+	if _, err := ParseMisfirePolicy("sometimes"); err != ErrInvalidMisfirePolicy {
+		t.Errorf("Expected ErrInvalidMisfirePolicy, got %v", err)
+	}
+
+	tests := []struct {
+		policy MisfirePolicy
+		want   int
+	}{
+		{MisfireIgnore, 0},
+		{MisfireRunOnce, 1},
+		{MisfireRunAll, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			scheduler, db, mockExecutor := setupCronTestScheduler(t)
+
+			// 停机前记录的下次执行时间在 3 小时前，每小时一次，期间错过 3 次
+			lastNext := time.Now().Add(-3 * time.Hour).Truncate(time.Hour).Add(time.Hour)
+			task := &CronTask{
+				Name:          "daily_report",
+				CronExpr:      "0 0 * * * *",
+				Prompt:        "生成报表",
+				NextRunAt:     &lastNext,
+				MisfirePolicy: tt.policy,
+			}
+			if err := db.Create(task).Error; err != nil {
+				t.Fatalf("Failed to create task: %v", err)
+			}
+
+			if err := scheduler.Start(); err != nil {
+				t.Fatalf("Failed to start scheduler: %v", err)
+			}
+			defer scheduler.Stop(0)
+
+			deadline := time.Now().Add(2 * time.Second)
+			for mockExecutor.ExecutionCount() < tt.want && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			time.Sleep(50 * time.Millisecond)
+			if got := mockExecutor.ExecutionCount(); got != tt.want {
+				t.Fatalf("Expected %d compensating executions, got %d", tt.want, got)
+			}
+
+			execs, err := scheduler.GetExecutionHistory(task.ID, 10, 0)
+			if err != nil {
+				t.Fatalf("Failed to get history: %v", err)
+			}
+			for _, exec := range execs {
+				if !exec.Misfire {
+					t.Errorf("Expected execution %d to be marked as misfire", exec.ID)
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package scheduler
 
 import (
+	"container/heap"
 	"context"
 	"fmt"
 	"log/slog"
@@ -9,6 +10,9 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/storage"
 )
 
 // DelayScheduler 延时任务调度器
@@ -16,40 +20,104 @@ type DelayScheduler struct {
 	db            *gorm.DB
 	repo          *DelayTaskRepository
 	agentExecutor AgentExecutor
+	notifier      ResultNotifier
 	logger        *slog.Logger
+	events        *observability.Emitter // 任务执行事件的输出，为 nil 时写入包级的 Sink
+
+	createMu sync.Mutex // 串行化带幂等键的创建，避免并发重复
+	mu       sync.RWMutex
+	timers   map[uint]*time.Timer // 任务ID -> 定时器
+	running  bool                 // 调度器是否在运行
+	inflight sync.WaitGroup       // 正在执行的任务
 
-	mu     sync.RWMutex
-	timers map[uint]*time.Timer // 任务ID -> 定时器
+	// 到期任务先进入优先级队列，由最多 maxConcurrent 个 worker 按优先级消费
+	queueMu       sync.Mutex
+	queue         taskQueue
+	workers       int // 当前运行中的 worker 数
+	maxConcurrent int
 
+	pastTolerance time.Duration // run_at 可以早于当前时间的范围，范围内立即执行
+
+	// 调度上下文，取消后不再接收新触发
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	// 执行上下文，供在途任务使用，drain 超时后才取消
+	execCtx    context.Context
+	execCancel context.CancelFunc
 }
 
 // NewDelayScheduler 创建延时任务调度器
 func NewDelayScheduler(db *gorm.DB, logger *slog.Logger) *DelayScheduler {
 	ctx, cancel := context.WithCancel(context.Background())
+	execCtx, execCancel := context.WithCancel(context.Background())
 
 	return &DelayScheduler{
-		db:     db,
-		repo:   NewDelayTaskRepository(db),
-		logger: logger,
-		timers: make(map[uint]*time.Timer),
-		ctx:    ctx,
-		cancel: cancel,
+		db:         db,
+		repo:       NewDelayTaskRepository(db),
+		notifier:   newGuardedNotifier(),
+		logger:     logger,
+		timers:     make(map[uint]*time.Timer),
+		ctx:        ctx,
+		cancel:     cancel,
+		execCtx:    execCtx,
+		execCancel: execCancel,
+
+		maxConcurrent: DefaultDelayMaxConcurrent,
+		pastTolerance: DefaultPastTolerance,
 	}
 }
 
 // SetAgentExecutor 设置 Agent 执行器（用于依赖注入，避免循环依赖）
+// 应在 Start 之前设置，否则启动时恢复的到期任务会因为没有执行器而失败
 func (s *DelayScheduler) SetAgentExecutor(executor AgentExecutor) {
+	s.mu.Lock()
 	s.agentExecutor = executor
+	s.mu.Unlock()
+}
+
+// executor 返回当前的 Agent 执行器，未设置时为 nil
+func (s *DelayScheduler) executor() AgentExecutor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.agentExecutor
+}
+
+// SetEmitter 设置任务执行事件的输出，未设置时写入包级的 Sink；应在 Start 之前设置
+func (s *DelayScheduler) SetEmitter(events *observability.Emitter) {
+	s.events = events
+}
+
+// SetResultNotifier 设置任务执行结果的通知器，默认拒绝推送到内网地址；应在 Start 之前设置
+func (s *DelayScheduler) SetResultNotifier(notifier ResultNotifier) {
+	s.notifier = notifier
+}
+
+// SetMaxConcurrent 设置同时执行的延时任务数上限，n <= 0 时使用 DefaultDelayMaxConcurrent
+// 达到上限后到期的任务在队列中按优先级等待
+func (s *DelayScheduler) SetMaxConcurrent(n int) {
+	if n <= 0 {
+		n = DefaultDelayMaxConcurrent
+	}
+	s.queueMu.Lock()
+	s.maxConcurrent = n
+	s.queueMu.Unlock()
+}
+
+// SetPastTolerance 设置 run_at 可以早于当前时间的范围，d 为 0 时使用 DefaultPastTolerance，负数表示不容忍
+func (s *DelayScheduler) SetPastTolerance(d time.Duration) {
+	if d == 0 {
+		d = DefaultPastTolerance
+	}
+	s.pastTolerance = max(d, 0)
 }
 
 // Start 启动调度器，恢复待执行的任务
 func (s *DelayScheduler) Start() error {
 	s.logger.Info("starting delay scheduler")
 
-	// 自动迁移表
-	if err := s.db.AutoMigrate(&DelayTask{}); err != nil {
+	// 按版本执行未应用的迁移
+	if err := storage.NewMigrator(s.db, delayMigrationScope, delayMigrations...).Migrate(); err != nil {
 		return fmt.Errorf("failed to migrate delay_tasks table: %w", err)
 	}
 
@@ -58,17 +126,22 @@ func (s *DelayScheduler) Start() error {
 		return fmt.Errorf("failed to recover tasks: %w", err)
 	}
 
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+
 	s.logger.Info("delay scheduler started")
 	return nil
 }
 
 // Stop 停止调度器
-func (s *DelayScheduler) Stop() {
-	s.logger.Info("stopping delay scheduler")
-	s.cancel()
+// 立即停止接收新触发，并在 timeout 内等待在途任务完成，超时后强制取消
+// timeout <= 0 时直接取消在途任务；无论哪种情况都会等被取消的任务退出后才返回
+func (s *DelayScheduler) Stop(timeout time.Duration) {
+	s.logger.Info("stopping delay scheduler", "drain_timeout", timeout)
 
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.cancel()
 
 	// 停止所有定时器
 	for id, timer := range s.timers {
@@ -76,10 +149,31 @@ func (s *DelayScheduler) Stop() {
 		s.logger.Debug("stopped timer", "task_id", id)
 	}
 	s.timers = make(map[uint]*time.Timer)
+	s.running = false
+	s.mu.Unlock()
+
+	// 丢弃尚未开始的排队任务，它们仍为 pending，重启后按 missed 处理
+	s.queueMu.Lock()
+	s.queue = nil
+	s.queueMu.Unlock()
+
+	// 等待在途任务，超时后取消它们，并等待取消后的状态写入完成，避免在数据库关闭后写入
+	if !waitWithTimeout(&s.inflight, timeout) {
+		s.logger.Warn("drain timeout, cancelling in-flight tasks")
+	}
+	s.execCancel()
+	s.inflight.Wait()
 
 	s.logger.Info("delay scheduler stopped")
 }
 
+// IsRunning 返回调度器是否在运行
+func (s *DelayScheduler) IsRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.running
+}
+
 // recoverTasks 恢复待执行的任务
 func (s *DelayScheduler) recoverTasks() error {
 	tasks, err := s.repo.ListPending()
@@ -113,9 +207,82 @@ func (s *DelayScheduler) recoverTasks() error {
 // CreateTask 创建并调度延时任务
 // channel 参数为可选的渠道上下文 JSON 字符串
 func (s *DelayScheduler) CreateTask(name string, runAt time.Time, prompt string, channel ...string) (*DelayTask, error) {
-	// 检查执行时间是否在未来
-	if runAt.Before(time.Now()) {
-		return nil, fmt.Errorf("run_at must be in the future")
+	task, _, err := s.CreateTaskWithKey("", name, runAt, prompt, channel...)
+	return task, err
+}
+
+// CreateTaskWithKey 创建延时任务，idempotencyKey 非空时保证幂等
+// 同一个 key 已有等待执行的任务时直接返回该任务（existed 为 true），不会重复创建；
+// 已执行、取消或删除的任务会释放 key，之后可以用同一个 key 创建新任务
+func (s *DelayScheduler) CreateTaskWithKey(idempotencyKey, name string, runAt time.Time, prompt string, channel ...string) (task *DelayTask, existed bool, err error) {
+	opts := DelayTaskOptions{IdempotencyKey: idempotencyKey}
+	if len(channel) > 0 {
+		opts.Channel = channel[0]
+	}
+	return s.CreateTaskWithOptions(name, runAt, prompt, opts)
+}
+
+// DelayTaskOptions 创建延时任务的可选参数
+type DelayTaskOptions struct {
+	IdempotencyKey string // 幂等键，语义见 CreateTaskWithKey
+	Channel        string // 渠道上下文 JSON 字符串
+	WebhookURL     string // 执行结束后推送执行结果的地址
+	Priority       int    // 优先级，越大越先执行，默认 0
+
+	Tags  []string // 标签，用于分类查看和批量管理
+	Group string   // 分组，如项目名
+
+	CallerScopes string // 创建者的作用域，由 EncodeCallerScopes 生成
+}
+
+// CreateTaskWithOptions 按可选参数创建延时任务，existed 的含义与 CreateTaskWithKey 相同
+func (s *DelayScheduler) CreateTaskWithOptions(name string, runAt time.Time, prompt string, opts DelayTaskOptions) (task *DelayTask, existed bool, err error) {
+	if err := ValidateWebhookURL(opts.WebhookURL); err != nil {
+		return nil, false, err
+	}
+	if opts.Tags, err = NormalizeTags(opts.Tags); err != nil {
+		return nil, false, err
+	}
+	if opts.Group, err = NormalizeGroup(opts.Group); err != nil {
+		return nil, false, err
+	}
+
+	idempotencyKey := opts.IdempotencyKey
+	if idempotencyKey != "" {
+		s.createMu.Lock()
+		defer s.createMu.Unlock()
+
+		existing, err := s.repo.GetByIdempotencyKey(idempotencyKey)
+		switch {
+		case err == nil && existing.IsPending() && !existing.DeletedAt.Valid:
+			s.logger.Info("delay task already exists for idempotency key",
+				"task_id", existing.ID,
+				"idempotency_key", idempotencyKey,
+			)
+			return existing, true, nil
+		case err == nil:
+			if err := s.repo.ReleaseIdempotencyKey(existing.ID); err != nil {
+				return nil, false, fmt.Errorf("failed to release idempotency key: %w", err)
+			}
+		case err != ErrTaskNotFound:
+			return nil, false, fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+	}
+
+	task, err = s.createTask(name, runAt, prompt, opts)
+	return task, false, err
+}
+
+// createTask 校验参数并创建、调度任务
+func (s *DelayScheduler) createTask(name string, runAt time.Time, prompt string, opts DelayTaskOptions) (*DelayTask, error) {
+	// 检查执行时间是否在未来，略早于当前时间（时钟漂移）时立即执行
+	resolved, err := resolveRunAt(runAt, time.Now(), s.pastTolerance)
+	if err != nil {
+		return nil, err
+	}
+	if !resolved.Equal(runAt) {
+		s.logger.Info("run_at is slightly in the past, running immediately", "name", name, "run_at", runAt)
+		runAt = resolved
 	}
 
 	// 检查 prompt 不能为空
@@ -125,15 +292,19 @@ func (s *DelayScheduler) CreateTask(name string, runAt time.Time, prompt string,
 
 	// 创建任务
 	task := &DelayTask{
-		Name:   name,
-		RunAt:  runAt,
-		Prompt: prompt,
-		Status: StatusPending,
+		Name:         name,
+		RunAt:        runAt,
+		Prompt:       prompt,
+		Channel:      opts.Channel,
+		Status:       StatusPending,
+		WebhookURL:   opts.WebhookURL,
+		CallerScopes: opts.CallerScopes,
+		Priority:     opts.Priority,
+		Tags:         opts.Tags,
+		Group:        opts.Group,
 	}
-
-	// 设置渠道信息（如果提供）
-	if len(channel) > 0 && channel[0] != "" {
-		task.Channel = channel[0]
+	if opts.IdempotencyKey != "" {
+		task.IdempotencyKey = &opts.IdempotencyKey
 	}
 
 	if err := s.repo.Create(task); err != nil {
@@ -151,6 +322,7 @@ func (s *DelayScheduler) CreateTask(name string, runAt time.Time, prompt string,
 		"task_id", task.ID,
 		"name", name,
 		"run_at", runAt,
+		"priority", opts.Priority,
 	)
 
 	return task, nil
@@ -171,10 +343,10 @@ func (s *DelayScheduler) scheduleTask(task *DelayTask) error {
 		existingTimer.Stop()
 	}
 
-	// 创建新定时器
-	taskID := task.ID
+	// 创建新定时器，到期后进入优先级队列
+	item := queuedTask{id: task.ID, priority: task.Priority, runAt: task.RunAt}
 	timer := time.AfterFunc(delay, func() {
-		s.executeTask(taskID)
+		s.enqueue(item)
 	})
 
 	s.timers[task.ID] = timer
@@ -184,19 +356,66 @@ func (s *DelayScheduler) scheduleTask(task *DelayTask) error {
 		"name", task.Name,
 		"delay", delay,
 		"run_at", task.RunAt,
+		"priority", task.Priority,
 	)
 
 	return nil
 }
 
+// enqueue 将到期任务放入优先级队列，worker 未满时启动一个新 worker
+func (s *DelayScheduler) enqueue(item queuedTask) {
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	s.queueMu.Lock()
+	heap.Push(&s.queue, item)
+	startWorker := s.workers < s.maxConcurrent
+	if startWorker {
+		s.workers++
+	}
+	s.queueMu.Unlock()
+
+	if startWorker {
+		go s.worker()
+	}
+}
+
+// worker 按优先级依次执行队列中的任务，队列为空时退出
+func (s *DelayScheduler) worker() {
+	for {
+		s.queueMu.Lock()
+		if s.queue.Len() == 0 {
+			s.workers--
+			s.queueMu.Unlock()
+			return
+		}
+		item := heap.Pop(&s.queue).(queuedTask)
+		s.queueMu.Unlock()
+
+		s.executeTask(item.id)
+	}
+}
+
+// beginTask 登记一个在途任务，调度器已停止时返回 false
+func (s *DelayScheduler) beginTask() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return false
+	}
+	s.inflight.Add(1)
+	return true
+}
+
 // executeTask 执行任务
 func (s *DelayScheduler) executeTask(taskID uint) {
 	// 检查调度器是否已停止
-	select {
-	case <-s.ctx.Done():
+	if !s.beginTask() {
 		return
-	default:
 	}
+	defer s.inflight.Done()
 
 	s.logger.Info("executing task", "task_id", taskID)
 
@@ -220,7 +439,8 @@ func (s *DelayScheduler) executeTask(taskID uint) {
 	}
 
 	// 检查 AgentExecutor 是否已设置
-	if s.agentExecutor == nil {
+	executor := s.executor()
+	if executor == nil {
 		errMsg := "agent executor not set"
 		s.logger.Error(errMsg, "task_id", taskID)
 		_ = s.repo.UpdateStatusByID(taskID, StatusFailed, "", errMsg)
@@ -228,20 +448,34 @@ func (s *DelayScheduler) executeTask(taskID uint) {
 	}
 
 	// 执行：调用 Agent
-	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Minute)
+	ctx, cancel := context.WithTimeout(s.execCtx, 5*time.Minute)
 	defer cancel()
 
-	result, err := s.agentExecutor.Execute(ctx, task.Prompt)
+	start := time.Now()
+	result, err := executor.Execute(ctx, task.Prompt, parseChannel(task.Channel), ParseCallerScopes(task.CallerScopes))
+	emitTaskEvent(s.events, "delay", taskID, task.Name, start, err)
 
 	// 更新任务状态
+	notice := TaskResult{
+		TaskType:    "delay",
+		TaskID:      taskID,
+		TaskName:    task.Name,
+		ScheduledAt: task.RunAt,
+		StartedAt:   start,
+		FinishedAt:  time.Now(),
+	}
+	notice.DurationMs = notice.FinishedAt.Sub(start).Milliseconds()
 	if err != nil {
 		errMsg := err.Error()
 		s.logger.Error("task execution failed", "task_id", taskID, "error", errMsg)
 		_ = s.repo.UpdateStatusByID(taskID, StatusFailed, "", errMsg)
+		notice.Status, notice.Error = string(StatusFailed), errMsg
 	} else {
 		s.logger.Info("task execution completed", "task_id", taskID, "result", result)
 		_ = s.repo.UpdateStatusByID(taskID, StatusCompleted, result, "")
+		notice.Status, notice.Result = string(StatusCompleted), result
 	}
+	dispatchNotify(s.execCtx, &s.inflight, s.notifier, s.logger, task.WebhookURL, notice)
 
 	// 从定时器映射中移除
 	s.mu.Lock()
@@ -268,6 +502,70 @@ func (s *DelayScheduler) CancelTaskByID(id uint) error {
 	return nil
 }
 
+// CancelAllPending 取消所有待执行的任务并停止对应的定时器，返回取消的数量
+func (s *DelayScheduler) CancelAllPending() (int, error) {
+	// 持有锁期间不会有新的定时器加入，避免刚调度的任务漏停
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := s.repo.CancelAllPending()
+	if err != nil {
+		return 0, err
+	}
+
+	// 定时器只对应待执行或正在执行的任务，正在执行的 Stop 不会产生影响
+	for id, timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+
+	s.logger.Info("all pending tasks cancelled", "count", n)
+	return n, nil
+}
+
+// CancelPending 取消符合标签和分组条件的待执行任务并停止对应的定时器，返回取消的数量
+func (s *DelayScheduler) CancelPending(filter DelayTaskFilter) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := StatusPending
+	filter.Status = &status
+	tasks, err := s.repo.ListFiltered(filter, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.repo.CancelPending(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, task := range tasks {
+		if timer, ok := s.timers[task.ID]; ok {
+			timer.Stop()
+			delete(s.timers, task.ID)
+		}
+	}
+
+	s.logger.Info("pending tasks cancelled", "tag", filter.Tag, "group", filter.Group, "count", n)
+	return n, nil
+}
+
+// DeleteByStatus 批量删除指定状态的任务，只允许终态（completed/failed/cancelled/missed）
+func (s *DelayScheduler) DeleteByStatus(status TaskStatus) (int, error) {
+	return s.DeleteByFilter(DelayTaskFilter{Status: &status})
+}
+
+// DeleteByFilter 批量删除符合条件的任务，filter.Status 必须是终态
+func (s *DelayScheduler) DeleteByFilter(filter DelayTaskFilter) (int, error) {
+	n, err := s.repo.DeleteByFilter(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Info("tasks deleted by filter", "status", *filter.Status, "tag", filter.Tag, "group", filter.Group, "count", n)
+	return n, nil
+}
+
 // GetTaskByID 根据 ID 获取任务信息
 func (s *DelayScheduler) GetTaskByID(id uint) (*DelayTask, error) {
 	return s.repo.GetByID(id)
@@ -283,6 +581,16 @@ func (s *DelayScheduler) CountTasks(status *TaskStatus) (int64, error) {
 	return s.repo.Count(status)
 }
 
+// ListTasksFiltered 列出符合状态、标签和分组条件的任务
+func (s *DelayScheduler) ListTasksFiltered(filter DelayTaskFilter, limit, offset int) ([]DelayTask, error) {
+	return s.repo.ListFiltered(filter, limit, offset)
+}
+
+// CountTasksFiltered 统计符合状态、标签和分组条件的任务数量
+func (s *DelayScheduler) CountTasksFiltered(filter DelayTaskFilter) (int64, error) {
+	return s.repo.CountFiltered(filter)
+}
+
 // ListPendingTasks 列出待执行的任务
 func (s *DelayScheduler) ListPendingTasks() ([]DelayTask, error) {
 	return s.repo.ListPending()
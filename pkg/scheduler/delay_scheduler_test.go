@@ -2,14 +2,19 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+
+	"github.com/KodaTao/AgentChassis/pkg/types"
 )
 
 // setupTestDB 创建测试数据库
@@ -42,7 +47,7 @@ func setupTestScheduler(t *testing.T) (*DelayScheduler, *gorm.DB, *MockAgentExec
 
 func TestDelayScheduler_CreateTask(t *testing.T) {
 	scheduler, _, _ := setupTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	// 启动调度器
 	if err := scheduler.Start(); err != nil {
@@ -73,7 +78,7 @@ func TestDelayScheduler_CreateTask(t *testing.T) {
 
 func TestDelayScheduler_CreateTask_EmptyPrompt(t *testing.T) {
 	scheduler, _, _ := setupTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -89,7 +94,7 @@ func TestDelayScheduler_CreateTask_EmptyPrompt(t *testing.T) {
 
 func TestDelayScheduler_CreateTask_PastTime(t *testing.T) {
 	scheduler, _, _ := setupTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -101,11 +106,43 @@ func TestDelayScheduler_CreateTask_PastTime(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error when run_at is in the past")
 	}
+	if !errors.Is(err, ErrRunAtInPast) {
+		t.Errorf("error = %v, want ErrRunAtInPast", err)
+	}
+	// 错误中附带服务器当前时间，并提示整数小时的时差可能是时区问题
+	if msg := err.Error(); !strings.Contains(msg, "current server time") || !strings.Contains(msg, "timezone offset") {
+		t.Errorf("error = %q, want current server time and timezone hint", msg)
+	}
+}
+
+func TestDelayScheduler_CreateTask_SlightlyPast(t *testing.T) {
+	scheduler, _, _ := setupTestScheduler(t)
+	defer scheduler.Stop(0)
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	// 容忍范围内的过去时间（时钟漂移）改为立即执行
+	runAt := time.Now().Add(-3 * time.Second)
+	task, err := scheduler.CreateTask("drift_task", runAt, "测试提示词")
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v, want slightly past run_at accepted", err)
+	}
+	if task.RunAt.Before(runAt.Add(3 * time.Second)) {
+		t.Errorf("RunAt = %v, want moved to now", task.RunAt)
+	}
+
+	// 关闭容忍后同样的时间被拒绝
+	scheduler.SetPastTolerance(-1)
+	if _, err := scheduler.CreateTask("drift_task_2", time.Now().Add(-3*time.Second), "测试提示词"); !errors.Is(err, ErrRunAtInPast) {
+		t.Errorf("CreateTask() without tolerance error = %v, want ErrRunAtInPast", err)
+	}
 }
 
 func TestDelayScheduler_CreateTask_DuplicateName(t *testing.T) {
 	scheduler, _, _ := setupTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -130,9 +167,64 @@ func TestDelayScheduler_CreateTask_DuplicateName(t *testing.T) {
 	}
 }
 
+func TestDelayScheduler_CreateTask_IdempotencyKey(t *testing.T) {
+	scheduler, _, _ := setupTestScheduler(t)
+	defer scheduler.Stop(0)
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	runAt := time.Now().Add(1 * time.Hour)
+	task1, existed, err := scheduler.CreateTaskWithKey("water-1030", "喝水提醒", runAt, "提醒用户喝水")
+	if err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	if existed {
+		t.Error("Expected first creation to create a new task")
+	}
+
+	// 相同 key 再次创建应返回已有任务
+	task2, existed, err := scheduler.CreateTaskWithKey("water-1030", "喝水提醒", runAt, "提醒用户喝水")
+	if err != nil {
+		t.Fatalf("Failed to create task with same key: %v", err)
+	}
+	if !existed {
+		t.Error("Expected existed to be true for duplicate key")
+	}
+	if task2.ID != task1.ID {
+		t.Errorf("Expected same task ID %d, got %d", task1.ID, task2.ID)
+	}
+
+	// 取消后 key 被释放，可以重新创建
+	if err := scheduler.CancelTaskByID(task1.ID); err != nil {
+		t.Fatalf("Failed to cancel task: %v", err)
+	}
+	task3, existed, err := scheduler.CreateTaskWithKey("water-1030", "喝水提醒", runAt, "提醒用户喝水")
+	if err != nil {
+		t.Fatalf("Failed to recreate task after cancel: %v", err)
+	}
+	if existed || task3.ID == task1.ID {
+		t.Errorf("Expected a new task after cancel, got ID %d (existed=%v)", task3.ID, existed)
+	}
+
+	// 空 key 不做幂等处理
+	task4, existed, err := scheduler.CreateTaskWithKey("", "喝水提醒", runAt, "提醒用户喝水")
+	if err != nil {
+		t.Fatalf("Failed to create task without key: %v", err)
+	}
+	task5, _, err := scheduler.CreateTaskWithKey("", "喝水提醒", runAt, "提醒用户喝水")
+	if err != nil {
+		t.Fatalf("Failed to create task without key: %v", err)
+	}
+	if existed || task4.ID == task5.ID {
+		t.Error("Expected empty key to always create new tasks")
+	}
+}
+
 func TestDelayScheduler_CancelTaskByID(t *testing.T) {
 	scheduler, _, _ := setupTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -162,7 +254,7 @@ func TestDelayScheduler_CancelTaskByID(t *testing.T) {
 
 func TestDelayScheduler_ExecuteTask(t *testing.T) {
 	scheduler, _, mockExecutor := setupTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -198,9 +290,121 @@ func TestDelayScheduler_ExecuteTask(t *testing.T) {
 	}
 }
 
+func TestDelayScheduler_ExecuteTaskWithChannel(t *testing.T) {
+	scheduler, _, mockExecutor := setupTestScheduler(t)
+	defer scheduler.Stop(0)
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	runAt := time.Now().Add(100 * time.Millisecond)
+	if _, err := scheduler.CreateTask("test_task", runAt, "提醒喝水", `{"type":"telegram","chat_id":"123"}`); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	// 验证执行时传递了结构化的渠道上下文
+	channel := mockExecutor.LastChannel()
+	if channel == nil {
+		t.Fatal("Expected channel to be passed to AgentExecutor")
+	}
+	if channel.Type != "telegram" || channel.ChatID != "123" {
+		t.Errorf("Expected telegram/123, got %s/%s", channel.Type, channel.ChatID)
+	}
+}
+
+func TestDelayScheduler_ExecuteTaskWithCallerScopes(t *testing.T) {
+	scheduler, _, mockExecutor := setupTestScheduler(t)
+	defer scheduler.Stop(0)
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	runAt := time.Now().Add(100 * time.Millisecond)
+	_, _, err := scheduler.CreateTaskWithOptions("scoped", runAt, "查询订单", DelayTaskOptions{
+		CallerScopes: EncodeCallerScopes([]string{"orders"}, true),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+
+	// 执行时应带上创建者的作用域
+	scopes := mockExecutor.LastScopes()
+	if len(scopes) != 1 || scopes[0] != "orders" {
+		t.Errorf("Expected scopes [orders], got %v", scopes)
+	}
+}
+
+func TestCallerScopes_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		ok     bool
+		raw    string
+		want   []string
+	}{
+		{name: "internal", scopes: nil, ok: false, raw: "", want: nil},
+		{name: "no scopes", scopes: nil, ok: true, raw: "[]", want: []string{}},
+		{name: "scoped", scopes: []string{"orders", "admin"}, ok: true, raw: `["orders","admin"]`, want: []string{"orders", "admin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := EncodeCallerScopes(tt.scopes, tt.ok)
+			if raw != tt.raw {
+				t.Fatalf("EncodeCallerScopes = %q, want %q", raw, tt.raw)
+			}
+			got := ParseCallerScopes(raw)
+			if (got == nil) != (tt.want == nil) || len(got) != len(tt.want) {
+				t.Fatalf("ParseCallerScopes(%q) = %#v, want %#v", raw, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ParseCallerScopes(%q) = %v, want %v", raw, got, tt.want)
+				}
+			}
+		})
+	}
+
+	// 格式不合法时按无作用域处理，不能退化为不受限
+	if got := ParseCallerScopes("admin"); got == nil || len(got) != 0 {
+		t.Errorf("ParseCallerScopes(invalid) = %#v, want empty non-nil", got)
+	}
+}
+
+func TestParseChannel(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want *types.ChannelContext
+	}{
+		{name: "empty", raw: "", want: nil},
+		{name: "invalid json", raw: "telegram", want: nil},
+		{name: "missing type", raw: `{"chat_id":"1"}`, want: nil},
+		{name: "telegram", raw: `{"type":"telegram","chat_id":"1"}`, want: &types.ChannelContext{Type: "telegram", ChatID: "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseChannel(tt.raw)
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("parseChannel(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+			if got != nil && (got.Type != tt.want.Type || got.ChatID != tt.want.ChatID) {
+				t.Errorf("parseChannel(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDelayScheduler_ListTasks(t *testing.T) {
 	scheduler, _, _ := setupTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -238,7 +442,7 @@ func TestDelayScheduler_ListTasks(t *testing.T) {
 
 func TestDelayScheduler_ListTasks_Pagination(t *testing.T) {
 	scheduler, _, _ := setupTestScheduler(t)
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
@@ -326,7 +530,7 @@ func TestDelayScheduler_RecoverTasks(t *testing.T) {
 	if err := scheduler.Start(); err != nil {
 		t.Fatalf("Failed to start scheduler: %v", err)
 	}
-	defer scheduler.Stop()
+	defer scheduler.Stop(0)
 
 	// 验证过期任务被标记为 missed
 	task1, err := scheduler.GetTaskByID(expiredID)
@@ -491,3 +695,148 @@ func TestRepository_List(t *testing.T) {
 		t.Errorf("Expected pending count 2, got %d", countPending)
 	}
 }
+
+func TestRepository_DeleteByStatus(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewDelayTaskRepository(db)
+
+	tasks := []DelayTask{
+		{Name: "task1", Prompt: "prompt1", RunAt: time.Now().Add(1 * time.Hour), Status: StatusPending},
+		{Name: "task2", Prompt: "prompt2", RunAt: time.Now().Add(2 * time.Hour), Status: StatusCompleted},
+		{Name: "task3", Prompt: "prompt3", RunAt: time.Now().Add(3 * time.Hour), Status: StatusCompleted},
+		{Name: "task4", Prompt: "prompt4", RunAt: time.Now().Add(4 * time.Hour), Status: StatusFailed},
+	}
+	for i := range tasks {
+		_ = repo.Create(&tasks[i])
+	}
+
+	n, err := repo.DeleteByStatus(StatusCompleted)
+	if err != nil {
+		t.Fatalf("Failed to delete by status: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 deleted tasks, got %d", n)
+	}
+
+	count, _ := repo.Count(nil)
+	if count != 2 {
+		t.Errorf("Expected 2 remaining tasks, got %d", count)
+	}
+
+	// 非终态不允许批量删除
+	if _, err := repo.DeleteByStatus(StatusPending); err != ErrStatusNotDeletable {
+		t.Errorf("Expected ErrStatusNotDeletable, got %v", err)
+	}
+}
+
+func TestDelayScheduler_CancelAllPending(t *testing.T) {
+	scheduler, _, mockExecutor := setupTestScheduler(t)
+	defer scheduler.Stop(0)
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := scheduler.CreateTask("test_task", time.Now().Add(100*time.Millisecond), "测试提示词"); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+	}
+
+	n, err := scheduler.CancelAllPending()
+	if err != nil {
+		t.Fatalf("Failed to cancel all pending: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 cancelled tasks, got %d", n)
+	}
+
+	// 定时器已停止，到点后不应再执行
+	time.Sleep(300 * time.Millisecond)
+	if mockExecutor.ExecutionCount() != 0 {
+		t.Errorf("Expected no executions after cancel, got %d", mockExecutor.ExecutionCount())
+	}
+
+	cancelled := StatusCancelled
+	count, _ := scheduler.CountTasks(&cancelled)
+	if count != 3 {
+		t.Errorf("Expected 3 cancelled tasks in database, got %d", count)
+	}
+}
+
+func TestDelayScheduler_IsRunning(t *testing.T) {
+	scheduler, _, _ := setupTestScheduler(t)
+
+	if scheduler.IsRunning() {
+		t.Error("Expected scheduler not running before Start")
+	}
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+	if !scheduler.IsRunning() {
+		t.Error("Expected scheduler running after Start")
+	}
+
+	scheduler.Stop(0)
+	if scheduler.IsRunning() {
+		t.Error("Expected scheduler not running after Stop")
+	}
+}
+
+// SlowAgentExecutor 模拟耗时执行，ctx 取消时提前返回
+type SlowAgentExecutor struct {
+	delay time.Duration
+}
+
+func (m *SlowAgentExecutor) Execute(ctx context.Context, prompt string, channel *types.ChannelContext, scopes []string) (string, error) {
+	select {
+	case <-time.After(m.delay):
+		return "执行完成: " + prompt, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func TestDelayScheduler_StopDrain(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		wantStatus TaskStatus
+	}{
+		{name: "drain completes in-flight task", timeout: 2 * time.Second, wantStatus: StatusCompleted},
+		{name: "drain timeout cancels task", timeout: 50 * time.Millisecond, wantStatus: StatusFailed},
+		{name: "zero timeout cancels task and waits for it", timeout: 0, wantStatus: StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler, _, _ := setupTestScheduler(t)
+			scheduler.SetAgentExecutor(&SlowAgentExecutor{delay: 500 * time.Millisecond})
+
+			if err := scheduler.Start(); err != nil {
+				t.Fatalf("Failed to start scheduler: %v", err)
+			}
+
+			task, err := scheduler.CreateTask("slow_task", time.Now().Add(10*time.Millisecond), "慢任务")
+			if err != nil {
+				t.Fatalf("Failed to create task: %v", err)
+			}
+
+			// 等待任务开始执行
+			time.Sleep(100 * time.Millisecond)
+			scheduler.Stop(tt.timeout)
+
+			// 超时取消后任务状态异步写入，稍等片刻
+			time.Sleep(50 * time.Millisecond)
+
+			retrieved, err := scheduler.GetTaskByID(task.ID)
+			if err != nil {
+				t.Fatalf("Failed to get task: %v", err)
+			}
+			if retrieved.Status != tt.wantStatus {
+				t.Errorf("Expected status '%s', got '%s'", tt.wantStatus, retrieved.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+// Package scheduler 提供定时任务调度功能
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// waitWithTimeout 等待 WaitGroup 完成，超时返回 false
+// timeout <= 0 时不等待
+func waitWithTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return false
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
@@ -0,0 +1,84 @@
+// Package scheduler 提供定时任务调度功能
+package scheduler
+
+import "github.com/KodaTao/AgentChassis/pkg/storage"
+
+// 迁移版本序列的 scope，延时和定时调度器可能共用同一个数据库
+const (
+	delayMigrationScope = "delay_scheduler"
+	cronMigrationScope  = "cron_scheduler"
+)
+
+// delayMigrations 延时任务表的迁移，schema 变化时在末尾追加新版本
+var delayMigrations = []storage.Migration{
+	{
+		Version: 1,
+		Name:    "create delay_tasks",
+		Up:      storage.CreateTables(&DelayTask{}),
+		Down:    storage.DropTables(&DelayTask{}),
+	},
+	{
+		Version: 2,
+		Name:    "add webhook_url to delay_tasks",
+		Up:      storage.AddColumns(&DelayTask{}, "WebhookURL"),
+		Down:    storage.DropColumns(&DelayTask{}, "WebhookURL"),
+	},
+	{
+		Version: 3,
+		Name:    "add priority to delay_tasks",
+		Up:      storage.AddColumns(&DelayTask{}, "Priority"),
+		Down:    storage.DropColumns(&DelayTask{}, "Priority"),
+	},
+	{
+		Version: 4,
+		Name:    "add tags and task_group to delay_tasks",
+		Up:      storage.AddColumns(&DelayTask{}, "Tags", "Group"),
+		Down:    storage.DropColumns(&DelayTask{}, "Tags", "Group"),
+	},
+	{
+		Version: 5,
+		Name:    "add caller_scopes to delay_tasks",
+		Up:      storage.AddColumns(&DelayTask{}, "CallerScopes"),
+		Down:    storage.DropColumns(&DelayTask{}, "CallerScopes"),
+	},
+}
+
+// cronMigrations 定时任务及执行历史表的迁移，schema 变化时在末尾追加新版本
+var cronMigrations = []storage.Migration{
+	{
+		Version: 1,
+		Name:    "create cron_tasks and cron_executions",
+		Up:      storage.CreateTables(&CronTask{}, &CronExecution{}),
+		Down:    storage.DropTables(&CronExecution{}, &CronTask{}),
+	},
+	{
+		Version: 2,
+		Name:    "add misfire_policy to cron_tasks",
+		Up:      storage.AddColumns(&CronTask{}, "MisfirePolicy"),
+		Down:    storage.DropColumns(&CronTask{}, "MisfirePolicy"),
+	},
+	{
+		Version: 3,
+		Name:    "add misfire to cron_executions",
+		Up:      storage.AddColumns(&CronExecution{}, "Misfire"),
+		Down:    storage.DropColumns(&CronExecution{}, "Misfire"),
+	},
+	{
+		Version: 4,
+		Name:    "add webhook_url to cron_tasks",
+		Up:      storage.AddColumns(&CronTask{}, "WebhookURL"),
+		Down:    storage.DropColumns(&CronTask{}, "WebhookURL"),
+	},
+	{
+		Version: 5,
+		Name:    "add tags and task_group to cron_tasks",
+		Up:      storage.AddColumns(&CronTask{}, "Tags", "Group"),
+		Down:    storage.DropColumns(&CronTask{}, "Tags", "Group"),
+	},
+	{
+		Version: 6,
+		Name:    "add caller_scopes to cron_tasks",
+		Up:      storage.AddColumns(&CronTask{}, "CallerScopes"),
+		Down:    storage.DropColumns(&CronTask{}, "CallerScopes"),
+	},
+}
@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/KodaTao/AgentChassis/pkg/storage"
+)
+
+func TestDelayScheduler_StartRecordsMigrations(t *testing.T) {
+	scheduler, db, _ := setupTestScheduler(t)
+	defer scheduler.Stop(0)
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	applied, err := storage.NewMigrator(db, delayMigrationScope, delayMigrations...).Applied()
+	if err != nil {
+		t.Fatalf("Applied() error = %v", err)
+	}
+	if len(applied) != len(delayMigrations) || applied[0].Version != 1 {
+		t.Errorf("applied migrations = %+v, want version 1 recorded", applied)
+	}
+}
+
+func TestMigrator_AppliesPendingInOrder(t *testing.T) {
+	db := setupTestDB(t)
+
+	var order []int
+	step := func(v int) func(*gorm.DB) error {
+		return func(*gorm.DB) error {
+			order = append(order, v)
+			return nil
+		}
+	}
+	migrations := []storage.Migration{
+		{Version: 2, Name: "second", Up: step(2), Down: step(-2)},
+		{Version: 1, Name: "first", Up: step(1)},
+	}
+
+	m := storage.NewMigrator(db, "test", migrations...)
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	// 已应用的版本不会重复执行
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("second Migrate() error = %v", err)
+	}
+	if len(order) != 2 || order[0] != 1 || order[1] != 2 {
+		t.Fatalf("execution order = %v, want [1 2]", order)
+	}
+
+	// 回滚最近的版本，再次 Migrate 会重新应用
+	if err := m.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if err := m.Migrate(); err != nil {
+		t.Fatalf("Migrate() after rollback error = %v", err)
+	}
+	if len(order) != 4 || order[2] != -2 || order[3] != 2 {
+		t.Errorf("execution order = %v, want [1 2 -2 2]", order)
+	}
+
+	// 版本 1 没有 Down，不能回滚
+	if err := m.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if err := m.Rollback(); !errors.Is(err, storage.ErrIrreversible) {
+		t.Errorf("Rollback() without down step should return ErrIrreversible, got %v", err)
+	}
+
+	// 重复的版本号
+	dup := storage.NewMigrator(db, "dup", storage.Migration{Version: 1, Up: step(1)}, storage.Migration{Version: 1, Up: step(1)})
+	if err := dup.Migrate(); !errors.Is(err, storage.ErrDuplicateMigration) {
+		t.Errorf("Migrate() with duplicate versions should return ErrDuplicateMigration, got %v", err)
+	}
+}
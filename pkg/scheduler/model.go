@@ -19,6 +19,15 @@ const (
 	StatusMissed    TaskStatus = "missed"    // 错过执行（重启时已过期）
 )
 
+// IsFinal 检查状态是否为终态（不会再被调度执行）
+func (s TaskStatus) IsFinal() bool {
+	switch s {
+	case StatusCompleted, StatusFailed, StatusCancelled, StatusMissed:
+		return true
+	}
+	return false
+}
+
 // DelayTask 一次性延时任务
 type DelayTask struct {
 	gorm.Model
@@ -30,6 +39,25 @@ type DelayTask struct {
 	Result     string     `gorm:"type:text" json:"result,omitempty"`   // LLM 最终回复
 	Error      string     `gorm:"type:text" json:"error,omitempty"`    // 错误信息
 	ExecutedAt *time.Time `json:"executed_at,omitempty"`               // 实际执行时间
+
+	// IdempotencyKey 幂等键，同一个 key 同时只会有一个等待执行的任务
+	// 使用指针使未设置的任务存为 NULL，不受唯一索引限制
+	IdempotencyKey *string `gorm:"uniqueIndex" json:"idempotency_key,omitempty"`
+
+	// WebhookURL 执行结束后以 POST 推送执行结果的地址，为空时不推送
+	WebhookURL string `gorm:"size:2048" json:"webhook_url,omitempty"`
+
+	// Priority 优先级，越大越先执行；同时到期的任务超出并发上限时按优先级排队，默认 0
+	Priority int `gorm:"default:0" json:"priority"`
+
+	// Tags 标签（小写），用于分类查看和批量管理，以 JSON 数组存储
+	Tags []string `gorm:"serializer:json;type:text" json:"tags,omitempty"`
+
+	// Group 分组，如项目名；列名避开 SQL 关键字 group
+	Group string `gorm:"column:task_group;size:64;index" json:"group,omitempty"`
+
+	// CallerScopes 创建者的作用域（JSON 数组），执行时按此限制可调用的函数；为空表示内部创建，不受限
+	CallerScopes string `gorm:"type:text" json:"-"`
 }
 
 // TableName 指定表名
@@ -66,6 +94,69 @@ type CronTask struct {
 	Channel     string     `gorm:"type:text" json:"channel,omitempty"` // 渠道上下文（JSON 格式存储）
 	Description string     `gorm:"type:text" json:"description"`      // 任务描述
 	NextRunAt   *time.Time `json:"next_run_at,omitempty"`             // 下次执行时间
+
+	// ConcurrencyPolicy 上次执行未完成时的处理策略，默认 allow
+	ConcurrencyPolicy ConcurrencyPolicy `gorm:"default:allow" json:"concurrency_policy"`
+
+	// MisfirePolicy 服务停机期间错过的执行如何补偿，默认 ignore
+	MisfirePolicy MisfirePolicy `gorm:"default:ignore" json:"misfire_policy"`
+
+	// WebhookURL 每次执行结束后以 POST 推送执行结果的地址，为空时不推送
+	WebhookURL string `gorm:"size:2048" json:"webhook_url,omitempty"`
+
+	// Tags 标签（小写），用于分类查看和批量管理，以 JSON 数组存储
+	Tags []string `gorm:"serializer:json;type:text" json:"tags,omitempty"`
+
+	// Group 分组，如项目名；列名避开 SQL 关键字 group
+	Group string `gorm:"column:task_group;size:64;index" json:"group,omitempty"`
+
+	// CallerScopes 创建者的作用域（JSON 数组），执行时按此限制可调用的函数；为空表示内部创建，不受限
+	CallerScopes string `gorm:"type:text" json:"-"`
+}
+
+// ConcurrencyPolicy 定时任务的并发执行策略
+type ConcurrencyPolicy string
+
+const (
+	ConcurrencyAllow ConcurrencyPolicy = "allow" // 允许多次执行并发运行（默认）
+	ConcurrencySkip  ConcurrencyPolicy = "skip"  // 上次执行未完成时跳过本次，记录为 skipped
+	ConcurrencyQueue ConcurrencyPolicy = "queue" // 上次执行未完成时排队等待，最多排队一次，更多的触发记录为 skipped
+)
+
+// ParseConcurrencyPolicy 解析并发策略，空字符串视为 allow
+func ParseConcurrencyPolicy(s string) (ConcurrencyPolicy, error) {
+	switch p := ConcurrencyPolicy(s); p {
+	case "":
+		return ConcurrencyAllow, nil
+	case ConcurrencyAllow, ConcurrencySkip, ConcurrencyQueue:
+		return p, nil
+	default:
+		return "", ErrInvalidConcurrencyPolicy
+	}
+}
+
+// MisfirePolicy 定时任务错过执行时的补偿策略
+type MisfirePolicy string
+
+const (
+	MisfireIgnore  MisfirePolicy = "ignore"   // 忽略错过的执行，等待下一次触发（默认）
+	MisfireRunOnce MisfirePolicy = "run_once" // 启动后立即补偿执行一次
+	MisfireRunAll  MisfirePolicy = "run_all"  // 启动后依次补偿执行每一次错过的触发，最多 MaxMisfireRuns 次
+)
+
+// MaxMisfireRuns 单个任务最多补偿执行的次数，避免长时间停机后的秒级任务引发补偿风暴
+const MaxMisfireRuns = 100
+
+// ParseMisfirePolicy 解析补偿策略，空字符串视为 ignore
+func ParseMisfirePolicy(s string) (MisfirePolicy, error) {
+	switch p := MisfirePolicy(s); p {
+	case "":
+		return MisfireIgnore, nil
+	case MisfireIgnore, MisfireRunOnce, MisfireRunAll:
+		return p, nil
+	default:
+		return "", ErrInvalidMisfirePolicy
+	}
 }
 
 // TableName 指定表名
@@ -80,6 +171,7 @@ const (
 	CronStatusRunning   CronExecutionStatus = "running"   // 正在执行
 	CronStatusCompleted CronExecutionStatus = "completed" // 执行完成
 	CronStatusFailed    CronExecutionStatus = "failed"    // 执行失败
+	CronStatusSkipped   CronExecutionStatus = "skipped"   // 上次执行未完成，按并发策略跳过
 )
 
 // CronExecution 定时任务执行历史记录
@@ -87,12 +179,15 @@ type CronExecution struct {
 	gorm.Model
 	CronTaskID  uint                `gorm:"not null;index" json:"cron_task_id"` // 关联的 CronTask ID
 	ScheduledAt time.Time           `gorm:"not null;index" json:"scheduled_at"` // 计划执行时间
+	CronExpr    string              `json:"cron_expr"`                          // 执行时使用的 Cron 表达式快照
+	NextRunAt   *time.Time          `json:"next_run_at,omitempty"`              // 本次执行后的下一次计划时间
 	StartedAt   time.Time           `gorm:"not null" json:"started_at"`         // 开始执行时间
 	FinishedAt  *time.Time          `json:"finished_at,omitempty"`              // 结束时间
 	Status      CronExecutionStatus `gorm:"not null;index" json:"status"`       // 执行状态
 	Result      string              `gorm:"type:text" json:"result,omitempty"`  // LLM 最终回复
 	Error       string              `gorm:"type:text" json:"error,omitempty"`   // 错误信息
 	Duration    int64               `json:"duration_ms,omitempty"`              // 执行耗时（毫秒）
+	Misfire     bool                `json:"misfire,omitempty"`                  // 是否为错过执行的补偿执行
 }
 
 // TableName 指定表名
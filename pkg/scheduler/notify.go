@@ -0,0 +1,170 @@
+// Package scheduler 提供定时任务调度功能
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/function/webhook"
+)
+
+// 结果通知的默认参数
+const (
+	DefaultNotifyAttempts = 3                // 最多推送次数（含首次）
+	DefaultNotifyBackoff  = time.Second      // 首次重试前的等待时间，之后每次翻倍
+	DefaultNotifyTimeout  = 10 * time.Second // 单次推送的超时
+)
+
+// ErrInvalidWebhookURL webhook_url 不是合法的 http/https 地址
+var ErrInvalidWebhookURL = errors.New("invalid webhook_url")
+
+// TaskResult 任务执行结果，执行结束后推送到任务的 webhook_url
+type TaskResult struct {
+	TaskType    string    `json:"task_type"`              // delay 或 cron
+	TaskID      uint      `json:"task_id"`                // 任务ID
+	TaskName    string    `json:"task_name"`              // 任务名称
+	ExecutionID uint      `json:"execution_id,omitempty"` // 执行记录ID（仅 cron）
+	Status      string    `json:"status"`                 // completed 或 failed
+	Result      string    `json:"result,omitempty"`       // LLM 最终回复
+	Error       string    `json:"error,omitempty"`        // 错误信息
+	ScheduledAt time.Time `json:"scheduled_at"`           // 计划执行时间
+	StartedAt   time.Time `json:"started_at"`             // 实际开始时间
+	FinishedAt  time.Time `json:"finished_at"`            // 结束时间
+	DurationMs  int64     `json:"duration_ms"`            // 执行耗时（毫秒）
+}
+
+// ResultNotifier 任务执行结果通知接口
+// 调度器在任务执行结束后异步调用，返回的错误只记录日志，不影响任务状态
+type ResultNotifier interface {
+	Notify(ctx context.Context, webhookURL string, result TaskResult) error
+}
+
+// WebhookNotifier 以 JSON POST 推送执行结果，网络错误、5xx、408 和 429 按指数退避重试
+type WebhookNotifier struct {
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+// 确保 WebhookNotifier 实现了 ResultNotifier 接口
+var _ ResultNotifier = (*WebhookNotifier)(nil)
+
+// NewWebhookNotifier 创建 webhook 结果通知器，client 为 nil 时使用 http.DefaultClient
+func NewWebhookNotifier(client *http.Client) *WebhookNotifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookNotifier{
+		client:   client,
+		attempts: DefaultNotifyAttempts,
+		backoff:  DefaultNotifyBackoff,
+	}
+}
+
+// newGuardedNotifier 调度器默认的结果通知器，与 webhook 函数一样拒绝推送到内网地址
+// 需要访问内网时由调用方通过 SetResultNotifier 显式替换
+func newGuardedNotifier() *WebhookNotifier {
+	return NewWebhookNotifier(webhook.Guard{}.HTTPClient())
+}
+
+// Notify 推送执行结果，全部重试失败后返回最后一次的错误
+func (n *WebhookNotifier) Notify(ctx context.Context, webhookURL string, result TaskResult) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode task result: %w", err)
+	}
+
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, webhookURL, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= n.attempts {
+			return fmt.Errorf("webhook notification failed after %d attempt(s): %w", attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook notification aborted: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post 发送一次请求，返回失败时是否值得重试
+func (n *WebhookNotifier) post(ctx context.Context, webhookURL string, body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultNotifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AgentChassis-Scheduler")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode >= 500 ||
+		resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+}
+
+// ValidateWebhookURL 校验 webhook_url 是否为合法的 http/https 地址，空字符串表示不通知
+// 目标地址是否允许访问（内网限制等）由注入的 HTTP 客户端在连接时校验
+func ValidateWebhookURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhookURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme must be http or https", ErrInvalidWebhookURL)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%w: missing host", ErrInvalidWebhookURL)
+	}
+	return nil
+}
+
+// dispatchNotify 异步推送执行结果，推送计入 inflight，调度器停止时会等待推送完成
+func dispatchNotify(ctx context.Context, inflight *sync.WaitGroup, notifier ResultNotifier, logger *slog.Logger, webhookURL string, result TaskResult) {
+	if webhookURL == "" || notifier == nil {
+		return
+	}
+	inflight.Add(1)
+	go func() {
+		defer inflight.Done()
+		if err := notifier.Notify(ctx, webhookURL, result); err != nil {
+			logger.Warn("failed to notify task result",
+				"task_type", result.TaskType,
+				"task_id", result.TaskID,
+				"error", err,
+			)
+			return
+		}
+		logger.Info("task result notified", "task_type", result.TaskType, "task_id", result.TaskID)
+	}()
+}
@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestNotifier 创建重试间隔很短的通知器
+func newTestNotifier() *WebhookNotifier {
+	n := NewWebhookNotifier(nil)
+	n.backoff = 10 * time.Millisecond
+	return n
+}
+
+func TestWebhookNotifier_RetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	var got TaskResult
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	err := newTestNotifier().Notify(context.Background(), srv.URL, TaskResult{TaskType: "delay", TaskID: 7, Status: "completed"})
+	if err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", calls.Load())
+	}
+	if got.TaskID != 7 || got.Status != "completed" {
+		t.Errorf("payload = %+v", got)
+	}
+}
+
+func TestWebhookNotifier_NoRetryOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	if err := newTestNotifier().Notify(context.Background(), srv.URL, TaskResult{}); err == nil {
+		t.Fatal("expected error for 400 response")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	for _, raw := range []string{"", "https://example.com/hook", "http://localhost:8080/cb"} {
+		if err := ValidateWebhookURL(raw); err != nil {
+			t.Errorf("ValidateWebhookURL(%q) error = %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"ftp://example.com", "example.com/hook", "https://"} {
+		if err := ValidateWebhookURL(raw); !errors.Is(err, ErrInvalidWebhookURL) {
+			t.Errorf("ValidateWebhookURL(%q) error = %v, want ErrInvalidWebhookURL", raw, err)
+		}
+	}
+}
+
+func TestDelayScheduler_NotifiesWebhook(t *testing.T) {
+	scheduler, _, _ := setupTestScheduler(t)
+	defer scheduler.Stop(0)
+	// 测试服务监听在回环地址上，默认的通知器会拒绝推送
+	scheduler.SetResultNotifier(NewWebhookNotifier(nil))
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	received := make(chan TaskResult, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var result TaskResult
+		_ = json.NewDecoder(r.Body).Decode(&result)
+		received <- result
+	}))
+	defer srv.Close()
+
+	if _, _, err := scheduler.CreateTaskWithOptions("notify_task", time.Now().Add(-time.Second), "请问候用户", DelayTaskOptions{
+		WebhookURL: "not a url",
+	}); !errors.Is(err, ErrInvalidWebhookURL) {
+		t.Fatalf("expected ErrInvalidWebhookURL, got %v", err)
+	}
+
+	task, _, err := scheduler.CreateTaskWithOptions("notify_task", time.Now().Add(100*time.Millisecond), "请问候用户", DelayTaskOptions{
+		WebhookURL: srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+
+	select {
+	case result := <-received:
+		if result.TaskType != "delay" || result.TaskID != task.ID || result.Status != string(StatusCompleted) {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if result.Result != "执行完成: 请问候用户" {
+			t.Errorf("result = %q", result.Result)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("webhook was not notified")
+	}
+}
+
+func TestDefaultNotifierRejectsPrivateNetworks(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	// 两个调度器未设置通知器时都不能推送到内网地址
+	delay, _, _ := setupTestScheduler(t)
+	for name, notifier := range map[string]ResultNotifier{
+		"delay": delay.notifier,
+		"cron":  NewCronScheduler(delay.db, delay.logger).notifier,
+	} {
+		n := notifier.(*WebhookNotifier)
+		n.attempts = 1
+		if err := n.Notify(context.Background(), srv.URL, TaskResult{TaskType: name}); err == nil {
+			t.Errorf("%s: default notifier posted to %s", name, srv.URL)
+		}
+	}
+	if hits.Load() != 0 {
+		t.Errorf("loopback server received %d requests", hits.Load())
+	}
+}
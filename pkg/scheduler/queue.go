@@ -0,0 +1,48 @@
+// Package scheduler 提供定时任务调度功能
+package scheduler
+
+import (
+	"container/heap"
+	"time"
+)
+
+// DefaultDelayMaxConcurrent 延时任务默认的最大并发执行数
+const DefaultDelayMaxConcurrent = 4
+
+// queuedTask 已到执行时间、等待执行的延时任务
+type queuedTask struct {
+	id       uint
+	priority int
+	runAt    time.Time
+}
+
+// taskQueue 到期延时任务的优先级队列（最大堆）
+// 优先级高的先出队；优先级相同时 run_at 早的先出队，再按任务ID（即创建顺序）
+type taskQueue []queuedTask
+
+var _ heap.Interface = (*taskQueue)(nil)
+
+func (q taskQueue) Len() int { return len(q) }
+
+func (q taskQueue) Less(i, j int) bool {
+	a, b := q[i], q[j]
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if !a.runAt.Equal(b.runAt) {
+		return a.runAt.Before(b.runAt)
+	}
+	return a.id < b.id
+}
+
+func (q taskQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *taskQueue) Push(x any) { *q = append(*q, x.(queuedTask)) }
+
+func (q *taskQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KodaTao/AgentChassis/pkg/types"
+)
+
+func TestTaskQueue_Order(t *testing.T) {
+	now := time.Now()
+	var q taskQueue
+	heap.Push(&q, queuedTask{id: 1, priority: 0, runAt: now})
+	heap.Push(&q, queuedTask{id: 2, priority: 5, runAt: now.Add(time.Second)})
+	heap.Push(&q, queuedTask{id: 3, priority: 5, runAt: now})
+	heap.Push(&q, queuedTask{id: 4, priority: 0, runAt: now})
+	heap.Push(&q, queuedTask{id: 5, priority: -1, runAt: now.Add(-time.Minute)})
+
+	want := []uint{3, 2, 1, 4, 5}
+	for i, id := range want {
+		got := heap.Pop(&q).(queuedTask)
+		if got.id != id {
+			t.Fatalf("pop %d: got task %d, want %d", i, got.id, id)
+		}
+	}
+}
+
+// gatedExecutor 第一次执行时阻塞直到 release 关闭，用于制造排队
+type gatedExecutor struct {
+	mu      sync.Mutex
+	prompts []string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (e *gatedExecutor) Execute(ctx context.Context, prompt string, channel *types.ChannelContext, scopes []string) (string, error) {
+	e.mu.Lock()
+	e.prompts = append(e.prompts, prompt)
+	first := len(e.prompts) == 1
+	e.mu.Unlock()
+	if first {
+		close(e.started)
+		<-e.release
+	}
+	return "ok", nil
+}
+
+func TestDelayScheduler_PriorityQueue(t *testing.T) {
+	scheduler, _, _ := setupTestScheduler(t)
+	defer scheduler.Stop(time.Second)
+
+	executor := &gatedExecutor{started: make(chan struct{}), release: make(chan struct{})}
+	scheduler.SetAgentExecutor(executor)
+	scheduler.SetMaxConcurrent(1)
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	// 先占住唯一的 worker
+	if _, _, err := scheduler.CreateTaskWithOptions("blocker", time.Now().Add(50*time.Millisecond), "blocker", DelayTaskOptions{}); err != nil {
+		t.Fatalf("Failed to create task: %v", err)
+	}
+	select {
+	case <-executor.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("blocker task did not start")
+	}
+
+	// 同一时刻到期的三个任务在队列中等待
+	runAt := time.Now().Add(50 * time.Millisecond)
+	for _, c := range []struct {
+		prompt   string
+		priority int
+	}{{"low", 0}, {"high", 10}, {"mid", 5}} {
+		if _, _, err := scheduler.CreateTaskWithOptions(c.prompt, runAt, c.prompt, DelayTaskOptions{Priority: c.priority}); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+	close(executor.release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		executor.mu.Lock()
+		n := len(executor.prompts)
+		executor.mu.Unlock()
+		if n == 4 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	executor.mu.Lock()
+	defer executor.mu.Unlock()
+	want := []string{"blocker", "high", "mid", "low"}
+	if len(executor.prompts) != len(want) {
+		t.Fatalf("executed %v, want %v", executor.prompts, want)
+	}
+	for i := range want {
+		if executor.prompts[i] != want[i] {
+			t.Fatalf("executed %v, want %v", executor.prompts, want)
+		}
+	}
+}
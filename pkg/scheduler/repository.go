@@ -36,6 +36,24 @@ func (r *DelayTaskRepository) GetByID(id uint) (*DelayTask, error) {
 	return &task, nil
 }
 
+// GetByIdempotencyKey 根据幂等键获取任务（包含已删除的任务，因为唯一索引同样约束它们）
+func (r *DelayTaskRepository) GetByIdempotencyKey(key string) (*DelayTask, error) {
+	var task DelayTask
+	err := r.db.Unscoped().Where("idempotency_key = ?", key).First(&task).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ReleaseIdempotencyKey 清除任务的幂等键，使该 key 可以被新任务使用
+func (r *DelayTaskRepository) ReleaseIdempotencyKey(id uint) error {
+	return r.db.Unscoped().Model(&DelayTask{}).Where("id = ?", id).Update("idempotency_key", nil).Error
+}
+
 // Update 更新任务
 func (r *DelayTaskRepository) Update(task *DelayTask) error {
 	return r.db.Save(task).Error
@@ -50,14 +68,24 @@ func (r *DelayTaskRepository) DeleteByID(id uint) error {
 	return result.Error
 }
 
-// List 列出任务
-func (r *DelayTaskRepository) List(status *TaskStatus, limit, offset int) ([]DelayTask, error) {
-	var tasks []DelayTask
+// applyFilter 按状态、标签和分组过滤
+func (r *DelayTaskRepository) applyFilter(filter DelayTaskFilter) *gorm.DB {
 	query := r.db.Model(&DelayTask{})
-
-	if status != nil {
-		query = query.Where("status = ?", *status)
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
 	}
+	return applyTagFilter(query, DelayTask{}.TableName(), filter.Tag, filter.Group)
+}
+
+// List 列出任务，status 为 nil 时不按状态过滤
+func (r *DelayTaskRepository) List(status *TaskStatus, limit, offset int) ([]DelayTask, error) {
+	return r.ListFiltered(DelayTaskFilter{Status: status}, limit, offset)
+}
+
+// ListFiltered 列出符合条件的任务，按执行时间排序
+func (r *DelayTaskRepository) ListFiltered(filter DelayTaskFilter, limit, offset int) ([]DelayTask, error) {
+	var tasks []DelayTask
+	query := r.applyFilter(filter)
 
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -82,14 +110,15 @@ func (r *DelayTaskRepository) ListByStatus(status TaskStatus) ([]DelayTask, erro
 	return r.List(&status, 0, 0)
 }
 
-// Count 统计任务数量
+// Count 统计任务数量，status 为 nil 时不按状态过滤
 func (r *DelayTaskRepository) Count(status *TaskStatus) (int64, error) {
+	return r.CountFiltered(DelayTaskFilter{Status: status})
+}
+
+// CountFiltered 统计符合条件的任务数量
+func (r *DelayTaskRepository) CountFiltered(filter DelayTaskFilter) (int64, error) {
 	var count int64
-	query := r.db.Model(&DelayTask{})
-	if status != nil {
-		query = query.Where("status = ?", *status)
-	}
-	err := query.Count(&count).Error
+	err := r.applyFilter(filter).Count(&count).Error
 	return count, err
 }
 
@@ -142,8 +171,39 @@ func (r *DelayTaskRepository) CancelByID(id uint) error {
 	return res.Error
 }
 
+// CancelAllPending 批量取消所有待执行的任务，返回取消的数量
+func (r *DelayTaskRepository) CancelAllPending() (int, error) {
+	return r.CancelPending(DelayTaskFilter{})
+}
+
+// CancelPending 批量取消符合标签和分组条件的待执行任务（忽略 filter.Status），返回取消的数量
+func (r *DelayTaskRepository) CancelPending(filter DelayTaskFilter) (int, error) {
+	status := StatusPending
+	filter.Status = &status
+	res := r.applyFilter(filter).Update("status", StatusCancelled)
+	return int(res.RowsAffected), res.Error
+}
+
+// DeleteByStatus 批量删除指定状态的任务，只允许删除终态任务，返回删除的数量
+func (r *DelayTaskRepository) DeleteByStatus(status TaskStatus) (int, error) {
+	return r.DeleteByFilter(DelayTaskFilter{Status: &status})
+}
+
+// DeleteByFilter 批量删除符合条件的任务，filter.Status 必须是终态，返回删除的数量
+func (r *DelayTaskRepository) DeleteByFilter(filter DelayTaskFilter) (int, error) {
+	if filter.Status == nil || !filter.Status.IsFinal() {
+		return 0, ErrStatusNotDeletable
+	}
+	res := r.applyFilter(filter).Delete(&DelayTask{})
+	return int(res.RowsAffected), res.Error
+}
+
 // 错误定义
 var (
-	ErrTaskNotFound   = errors.New("task not found")
-	ErrTaskNotPending = errors.New("task is not in pending status")
+	ErrTaskNotFound       = errors.New("task not found")
+	ErrTaskNotPending     = errors.New("task is not in pending status")
+	ErrStatusNotDeletable = errors.New("only tasks in a final status (completed/failed/cancelled/missed) can be deleted in bulk")
+
+	ErrInvalidConcurrencyPolicy = errors.New("invalid concurrency policy, expected allow/skip/queue")
+	ErrInvalidMisfirePolicy     = errors.New("invalid misfire policy, expected ignore/run_once/run_all")
 )
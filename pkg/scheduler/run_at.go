@@ -0,0 +1,63 @@
+// Package scheduler 提供定时任务调度功能
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DefaultPastTolerance 延时任务 run_at 默认可以早于当前时间的范围，范围内视为立即执行
+// 用于吸收时钟漂移和"1 秒后"这类计算与创建之间的耗时
+const DefaultPastTolerance = 10 * time.Second
+
+// ErrRunAtInPast 延时任务的执行时间明显早于当前时间
+var ErrRunAtInPast = errors.New("run_at must be in the future")
+
+// resolveRunAt 校验执行时间：在容忍范围内的过去时间改为立即执行，更早的返回 ErrRunAtInPast
+// 错误信息附带服务器当前时间和时区，AI 可据此发现时差并重新计算
+func resolveRunAt(runAt, now time.Time, tolerance time.Duration) (time.Time, error) {
+	if !runAt.Before(now) {
+		return runAt, nil
+	}
+	behind := now.Sub(runAt)
+	if behind <= tolerance {
+		return now, nil
+	}
+
+	var hint string
+	if hours, ok := wholeHours(behind); ok {
+		hint = fmt.Sprintf("; the difference is about %d hour(s), which usually means the timezone offset in run_at is wrong", hours)
+	}
+	name, offset := now.Zone()
+	return time.Time{}, fmt.Errorf("%w: run_at %s is %s in the past; the current server time is %s (timezone %s, UTC%s)%s; recalculate run_at from the current server time",
+		ErrRunAtInPast,
+		runAt.Format(time.RFC3339),
+		behind.Round(time.Second),
+		now.Format(time.RFC3339),
+		name,
+		formatUTCOffset(offset),
+		hint,
+	)
+}
+
+// wholeHours 时差是否接近整数小时（误差 2 分钟内），通常是时区偏移写错导致的
+func wholeHours(d time.Duration) (int, bool) {
+	hours := math.Round(d.Hours())
+	if hours < 1 || hours > 26 {
+		return 0, false
+	}
+	diff := d - time.Duration(hours)*time.Hour
+	return int(hours), diff.Abs() <= 2*time.Minute
+}
+
+// formatUTCOffset 将秒数偏移格式化为 +08:00 形式
+func formatUTCOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, offset/3600, offset%3600/60)
+}
@@ -0,0 +1,99 @@
+// Package scheduler 提供定时任务调度功能
+package scheduler
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// 标签和分组的限制
+const (
+	MaxTaskTags    = 10 // 单个任务最多的标签数
+	MaxTagLength   = 32 // 单个标签的最大字符数
+	MaxGroupLength = 64 // 分组名的最大字符数
+)
+
+// 标签和分组错误
+var (
+	ErrInvalidTag   = errors.New("invalid tag: tags must be at most 32 characters and must not contain commas")
+	ErrTooManyTags  = errors.New("too many tags: at most 10 tags are allowed per task")
+	ErrInvalidGroup = errors.New("invalid group: group must be at most 64 characters")
+
+	ErrEmptyTaskFilter = errors.New("tag or group is required for bulk operations")
+)
+
+// DelayTaskFilter 延时任务的查询条件，零值表示不过滤
+type DelayTaskFilter struct {
+	Status *TaskStatus
+	Tag    string // 包含该标签的任务
+	Group  string // 属于该分组的任务
+}
+
+// CronTaskFilter 定时任务的查询条件，零值表示不过滤
+type CronTaskFilter struct {
+	Tag   string // 包含该标签的任务
+	Group string // 属于该分组的任务
+}
+
+// IsEmpty 是否没有任何过滤条件
+func (f CronTaskFilter) IsEmpty() bool {
+	return normalizeTag(f.Tag) == "" && strings.TrimSpace(f.Group) == ""
+}
+
+// NormalizeTags 规范化标签：去掉首尾空白、转为小写、去重，忽略空标签
+// 标签超长、包含逗号或数量超过上限时返回错误
+func NormalizeTags(tags []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLength || strings.Contains(tag, ",") {
+			return nil, ErrInvalidTag
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxTaskTags {
+		return nil, ErrTooManyTags
+	}
+	return normalized, nil
+}
+
+// ParseTags 解析逗号分隔的标签，如 "reminder, work"
+func ParseTags(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	return NormalizeTags(strings.Split(s, ","))
+}
+
+// NormalizeGroup 规范化分组名：去掉首尾空白，超长时返回错误
+func NormalizeGroup(group string) (string, error) {
+	group = strings.TrimSpace(group)
+	if utf8.RuneCountInString(group) > MaxGroupLength {
+		return "", ErrInvalidGroup
+	}
+	return group, nil
+}
+
+// normalizeTag 规范化单个标签，过滤条件与存储使用同样的规则
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// applyTagFilter 为查询加上标签和分组条件，标签以 JSON 数组存储，用 json_each 精确匹配
+func applyTagFilter(query *gorm.DB, table, tag, group string) *gorm.DB {
+	if tag = normalizeTag(tag); tag != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM json_each("+table+".tags) WHERE json_each.value = ?)", tag)
+	}
+	if group = strings.TrimSpace(group); group != "" {
+		query = query.Where(table+".task_group = ?", group)
+	}
+	return query
+}
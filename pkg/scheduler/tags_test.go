@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := NormalizeTags([]string{" Reminder", "work", "", "reminder"})
+	if err != nil {
+		t.Fatalf("NormalizeTags() error = %v", err)
+	}
+	if want := []string{"reminder", "work"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeTags() = %v, want %v", got, want)
+	}
+
+	if _, err := NormalizeTags([]string{strings.Repeat("a", MaxTagLength+1)}); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("too long tag: err = %v, want ErrInvalidTag", err)
+	}
+	many := make([]string, MaxTaskTags+1)
+	for i := range many {
+		many[i] = strings.Repeat("t", i+1)
+	}
+	if _, err := NormalizeTags(many); !errors.Is(err, ErrTooManyTags) {
+		t.Errorf("too many tags: err = %v, want ErrTooManyTags", err)
+	}
+
+	if got, _ := ParseTags("report, Project-X"); !reflect.DeepEqual(got, []string{"report", "project-x"}) {
+		t.Errorf("ParseTags() = %v", got)
+	}
+}
+
+func TestDelayScheduler_TagFilter(t *testing.T) {
+	scheduler, _, mockExecutor := setupTestScheduler(t)
+	defer scheduler.Stop(0)
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	runAt := time.Now().Add(200 * time.Millisecond)
+	create := func(name string, tags []string, group string) {
+		t.Helper()
+		if _, _, err := scheduler.CreateTaskWithOptions(name, runAt, name, DelayTaskOptions{Tags: tags, Group: group}); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+	}
+	create("water", []string{"Reminder", "health"}, "")
+	create("meeting", []string{"reminder"}, "project-x")
+	create("deploy", nil, "project-x")
+
+	count := func(filter DelayTaskFilter) int64 {
+		t.Helper()
+		n, err := scheduler.CountTasksFiltered(filter)
+		if err != nil {
+			t.Fatalf("CountTasksFiltered() error = %v", err)
+		}
+		return n
+	}
+	if n := count(DelayTaskFilter{Tag: "reminder"}); n != 2 {
+		t.Errorf("tag=reminder: got %d tasks, want 2", n)
+	}
+	if n := count(DelayTaskFilter{Tag: "remind"}); n != 0 {
+		t.Errorf("tag=remind should not match partially, got %d", n)
+	}
+	if n := count(DelayTaskFilter{Group: "project-x"}); n != 2 {
+		t.Errorf("group=project-x: got %d tasks, want 2", n)
+	}
+	if n := count(DelayTaskFilter{Tag: "REMINDER", Group: "project-x"}); n != 1 {
+		t.Errorf("tag+group: got %d tasks, want 1", n)
+	}
+
+	tasks, err := scheduler.ListTasksFiltered(DelayTaskFilter{Tag: "health"}, 20, 0)
+	if err != nil || len(tasks) != 1 || !reflect.DeepEqual(tasks[0].Tags, []string{"reminder", "health"}) {
+		t.Fatalf("ListTasksFiltered(tag=health) = %+v, %v", tasks, err)
+	}
+
+	// 只取消 reminder 标签的任务，其余任务照常执行
+	n, err := scheduler.CancelPending(DelayTaskFilter{Tag: "reminder"})
+	if err != nil || n != 2 {
+		t.Fatalf("CancelPending() = %d, %v; want 2", n, err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if got := mockExecutor.ExecutionCount(); got != 1 {
+		t.Errorf("Expected 1 execution after cancelling tagged tasks, got %d", got)
+	}
+}
+
+func TestCronScheduler_TagFilter(t *testing.T) {
+	scheduler, _, _ := setupCronTestScheduler(t)
+	defer scheduler.Stop(0)
+
+	for _, c := range []struct {
+		name  string
+		tags  []string
+		group string
+	}{
+		{"daily report", []string{"report"}, "project-x"},
+		{"weekly report", []string{"report"}, "project-y"},
+		{"backup", nil, "project-x"},
+	} {
+		if _, err := scheduler.CreateTaskWithOptions(c.name, "0 0 9 * * *", c.name, "", CronTaskOptions{Tags: c.tags, Group: c.group}); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+	}
+
+	tasks, err := scheduler.ListTasksFiltered(CronTaskFilter{Tag: "report"}, 20, 0)
+	if err != nil || len(tasks) != 2 {
+		t.Fatalf("ListTasksFiltered(tag=report) = %d tasks, %v; want 2", len(tasks), err)
+	}
+
+	if _, err := scheduler.DeleteByFilter(CronTaskFilter{}); !errors.Is(err, ErrEmptyTaskFilter) {
+		t.Errorf("DeleteByFilter() with empty filter: err = %v, want ErrEmptyTaskFilter", err)
+	}
+	n, err := scheduler.DeleteByFilter(CronTaskFilter{Group: "project-x"})
+	if err != nil || n != 2 {
+		t.Fatalf("DeleteByFilter(group=project-x) = %d, %v; want 2", n, err)
+	}
+	if total, _ := scheduler.CountTasks(); total != 1 {
+		t.Errorf("Expected 1 remaining task, got %d", total)
+	}
+}
@@ -0,0 +1,141 @@
+// Package server 提供 HTTP Server 功能
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/KodaTao/AgentChassis/pkg/approval"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// DecideApprovalRequest 审批请求体，可省略
+// 审批人总是记录为调用方的 API Key 名称，不能由请求体指定
+type DecideApprovalRequest struct {
+	Reason string `json:"reason"` // 审批意见，拒绝时会告知 AI 和用户
+}
+
+// 查询审批请求
+// 支持 status（pending、approved、rejected）、session_id、function 过滤和分页
+func (s *Server) listApprovals(c *gin.Context) {
+	repo := s.app.GetApprovalRepository()
+	if repo == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Approval workflow not enabled",
+		})
+		return
+	}
+
+	filter := approval.Filter{
+		Status:       c.Query("status"),
+		SessionID:    c.Query("session_id"),
+		FunctionName: c.Query("function"),
+	}
+
+	// 分页参数
+	limit := 20
+	offset := 0
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	requests, err := repo.List(filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list approval requests: " + err.Error(),
+		})
+		return
+	}
+
+	total, err := repo.Count(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to count approval requests: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"approvals": requests,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// 获取审批请求详情
+func (s *Server) getApproval(c *gin.Context) {
+	repo := s.app.GetApprovalRepository()
+	if repo == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Approval workflow not enabled",
+		})
+		return
+	}
+
+	req, err := repo.Get(c.Param("id"))
+	if respondApprovalError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// 批准函数调用：执行该调用并恢复对话，返回 AI 继续处理后的回复
+func (s *Server) approveCall(c *gin.Context) {
+	s.decideApproval(c, true)
+}
+
+// 拒绝函数调用：不执行，把拒绝原因告知 AI 和用户
+func (s *Server) rejectCall(c *gin.Context) {
+	s.decideApproval(c, false)
+}
+
+// decideApproval 处理批准/拒绝请求
+func (s *Server) decideApproval(c *gin.Context, approved bool) {
+	var req DecideApprovalRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindError(c, err)
+			return
+		}
+	}
+	decidedBy := c.GetString("api_key_name")
+
+	resp, err := s.app.DecideApproval(c.Request.Context(), c.Param("id"), approved, decidedBy, req.Reason)
+	if respondApprovalError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// respondApprovalError 把审批相关错误映射为 HTTP 响应，返回是否已响应
+func respondApprovalError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, approval.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Approval request not found: " + c.Param("id"),
+		})
+	case errors.Is(err, approval.ErrAlreadyDecided):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		observability.ErrorContext(c.Request.Context(), "Approval failed", "id", c.Param("id"), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Approval failed: " + err.Error(),
+		})
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/approval"
+	"github.com/KodaTao/AgentChassis/pkg/chassis"
+	"github.com/KodaTao/AgentChassis/pkg/function"
+)
+
+// wireFunction 需要管理员审批的测试函数
+type wireFunction struct{}
+
+func (f *wireFunction) Name() string             { return "wire" }
+func (f *wireFunction) Description() string      { return "wire money" }
+func (f *wireFunction) ParamsType() reflect.Type { return nil }
+func (f *wireFunction) RequiresApproval() bool   { return true }
+func (f *wireFunction) Execute(ctx context.Context, params any) (function.Result, error) {
+	return function.Result{Message: "wired"}, nil
+}
+
+// newApprovalServer 创建开启鉴权的 Server：ops 拥有 admin 作用域，web 没有作用域；AI 总是调用 wire
+func newApprovalServer(t *testing.T) *Server {
+	t.Helper()
+	llmURL, _ := replyingLLM(t, `<call name="wire"></call>`)
+	app := chassis.New(testAppOptions(t, llmURL, chassis.WithAuth(chassis.AuthConfig{
+		APIKeys: []chassis.APIKeyConfig{
+			{Name: "ops", Key: "ops-key", Scopes: []string{AdminScope}},
+			{Name: "web", Key: "web-key"},
+		},
+	}))...)
+	if err := app.Register(&wireFunction{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { app.Shutdown() })
+	return NewServer(app, &ServerConfig{Mode: "test"})
+}
+
+// doWithKey 携带 API Key 发送 JSON 请求
+func (s *Server) doWithKey(t *testing.T, key, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", key)
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestServer_ApprovalWorkflow(t *testing.T) {
+	s := newApprovalServer(t)
+
+	w := s.doWithKey(t, "web-key", http.MethodPost, "/api/v1/chat", map[string]any{"session_id": "s1", "message": "wire 10 to bob"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("chat status = %d, body = %s", w.Code, w.Body)
+	}
+	var resp chassis.ChatResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Approval == nil {
+		t.Fatalf("expected an approval request, got %s", w.Body)
+	}
+	id := resp.Approval.ID
+
+	// 审批完成前会话暂停
+	w = s.doWithKey(t, "web-key", http.MethodPost, "/api/v1/chat", map[string]any{"session_id": "s1", "message": "hurry"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("chat on paused session status = %d, want 409, body = %s", w.Code, w.Body)
+	}
+
+	// 没有 admin 作用域的 Key 不能审批
+	for _, action := range []string{"approve", "reject"} {
+		w = s.doWithKey(t, "web-key", http.MethodPost, "/api/v1/approvals/"+id+"/"+action, map[string]any{})
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s without admin scope status = %d, want 403", action, w.Code)
+		}
+	}
+
+	// 也不能查看审批记录
+	for _, path := range []string{"/api/v1/approvals", "/api/v1/approvals/" + id} {
+		if w = s.doWithKey(t, "web-key", http.MethodGet, path, nil); w.Code != http.StatusForbidden {
+			t.Errorf("GET %s without admin scope status = %d, want 403", path, w.Code)
+		}
+		if w = s.doWithKey(t, "ops-key", http.MethodGet, path, nil); w.Code != http.StatusOK {
+			t.Errorf("GET %s with admin scope status = %d, body = %s", path, w.Code, w.Body)
+		}
+	}
+
+	// 审批人总是记录为 API Key 名称，请求体中的 decided_by 被忽略
+	w = s.doWithKey(t, "ops-key", http.MethodPost, "/api/v1/approvals/"+id+"/reject", map[string]any{"reason": "no", "decided_by": "mallory"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("reject status = %d, body = %s", w.Code, w.Body)
+	}
+	req, err := s.app.GetApprovalRepository().Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Status != approval.StatusRejected || req.DecidedBy != "ops" {
+		t.Errorf("approval request = %+v", req)
+	}
+
+	// 已决定的请求再次审批返回 409
+	w = s.doWithKey(t, "ops-key", http.MethodPost, "/api/v1/approvals/"+id+"/approve", map[string]any{})
+	if w.Code != http.StatusConflict {
+		t.Errorf("second decision status = %d, want 409", w.Code)
+	}
+}
@@ -0,0 +1,192 @@
+// Package server 提供 HTTP Server 功能
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/KodaTao/AgentChassis/pkg/audit"
+)
+
+// 审计记录导出格式
+const (
+	AuditExportJSONL = "jsonl"
+	AuditExportCSV   = "csv"
+)
+
+// auditCSVHeader CSV 导出的表头，与 auditCSVRow 的列一一对应
+var auditCSVHeader = []string{
+	"id", "created_at", "action", "request_id", "session_id", "caller", "user_id", "channel", "status",
+	"input", "output", "function_calls", "prompt_tokens", "completion_tokens", "total_tokens",
+	"duration_ms", "error", "masked",
+}
+
+// 查询审计记录
+// 支持 action、caller、user_id、session_id、request_id、status、since、until（RFC3339）过滤和分页
+func (s *Server) listAuditRecords(c *gin.Context) {
+	repo := s.app.GetAuditRepository()
+	if repo == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Audit log not enabled",
+		})
+		return
+	}
+
+	filter, ok := auditFilter(c)
+	if !ok {
+		return
+	}
+
+	// 分页参数
+	limit := 20
+	offset := 0
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	records, err := repo.List(filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list audit records: " + err.Error(),
+		})
+		return
+	}
+
+	total, err := repo.Count(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to count audit records: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"records": records,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// 导出审计记录
+// 过滤条件同查询接口，format 为 jsonl（默认）或 csv，按时间正序流式输出全部符合条件的记录
+func (s *Server) exportAuditRecords(c *gin.Context) {
+	repo := s.app.GetAuditRepository()
+	if repo == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Audit log not enabled",
+		})
+		return
+	}
+
+	filter, ok := auditFilter(c)
+	if !ok {
+		return
+	}
+
+	filename := "audit-" + time.Now().Format("20060102-150405")
+	var err error
+	switch c.DefaultQuery("format", AuditExportJSONL) {
+	case AuditExportJSONL:
+		c.Header("Content-Disposition", "attachment; filename=\""+filename+".jsonl\"")
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		enc := json.NewEncoder(c.Writer)
+		err = repo.Each(filter, 0, func(record audit.Record) error {
+			return enc.Encode(record)
+		})
+	case AuditExportCSV:
+		c.Header("Content-Disposition", "attachment; filename=\""+filename+".csv\"")
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		w := csv.NewWriter(c.Writer)
+		if err = w.Write(auditCSVHeader); err == nil {
+			err = repo.Each(filter, 0, func(record audit.Record) error {
+				return w.Write(auditCSVRow(record))
+			})
+		}
+		w.Flush()
+		if err == nil {
+			err = w.Error()
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid format, expected jsonl or csv",
+		})
+		return
+	}
+
+	// 响应头已发出，只能中断输出并记录错误
+	if err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// auditFilter 解析审计记录的过滤参数，参数无效时写入 400 响应并返回 false
+func auditFilter(c *gin.Context) (audit.Filter, bool) {
+	filter := audit.Filter{
+		Action:    c.Query("action"),
+		Caller:    c.Query("caller"),
+		UserID:    c.Query("user_id"),
+		SessionID: c.Query("session_id"),
+		RequestID: c.Query("request_id"),
+		Status:    c.Query("status"),
+	}
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid since format, expected RFC3339",
+			})
+			return filter, false
+		}
+		filter.Since = t
+	}
+	if until := c.Query("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid until format, expected RFC3339",
+			})
+			return filter, false
+		}
+		filter.Until = t
+	}
+	return filter, true
+}
+
+// auditCSVRow 将审计记录转换为 CSV 行
+func auditCSVRow(r audit.Record) []string {
+	return []string{
+		strconv.FormatUint(uint64(r.ID), 10),
+		r.CreatedAt.Format(time.RFC3339),
+		r.Action,
+		r.RequestID,
+		r.SessionID,
+		r.Caller,
+		r.UserID,
+		r.Channel,
+		r.Status,
+		r.Input,
+		r.Output,
+		r.FunctionCalls,
+		strconv.Itoa(r.PromptTokens),
+		strconv.Itoa(r.CompletionTokens),
+		strconv.Itoa(r.TotalTokens),
+		strconv.FormatInt(r.DurationMs, 10),
+		r.Error,
+		strconv.FormatBool(r.Masked),
+	}
+}
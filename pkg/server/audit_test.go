@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/chassis"
+)
+
+func TestServer_AuditRequiresAdmin(t *testing.T) {
+	llmURL, _ := replyingLLM(t, "ok")
+	app := chassis.New(testAppOptions(t, llmURL,
+		chassis.WithAudit(chassis.AuditConfig{Enabled: true}),
+		chassis.WithAuth(chassis.AuthConfig{
+			APIKeys: []chassis.APIKeyConfig{
+				{Name: "ops", Key: "ops-key", Scopes: []string{AdminScope}},
+				{Name: "web", Key: "web-key"},
+			},
+		}))...)
+	if err := app.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { app.Shutdown() })
+	s := NewServer(app, &ServerConfig{Mode: "test"})
+
+	for _, path := range []string{"/api/v1/audit", "/api/v1/audit/export"} {
+		if w := s.doWithKey(t, "web-key", http.MethodGet, path, nil); w.Code != http.StatusForbidden {
+			t.Errorf("GET %s without admin scope status = %d, want 403", path, w.Code)
+		}
+		if w := s.doWithKey(t, "ops-key", http.MethodGet, path, nil); w.Code != http.StatusOK {
+			t.Errorf("GET %s with admin scope status = %d, want 200", path, w.Code)
+		}
+	}
+}
+
+func TestServer_FunctionCallsRequireAdmin(t *testing.T) {
+	s := newApprovalServer(t)
+
+	if w := s.doWithKey(t, "web-key", http.MethodGet, "/api/v1/function-calls", nil); w.Code != http.StatusForbidden {
+		t.Errorf("GET /function-calls without admin scope status = %d, want 403", w.Code)
+	}
+	if w := s.doWithKey(t, "ops-key", http.MethodGet, "/api/v1/function-calls", nil); w.Code != http.StatusOK {
+		t.Errorf("GET /function-calls with admin scope status = %d, want 200: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,46 @@
+// Package server 提供 HTTP Server 功能
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 获取每个函数的资源画像（goroutine 残留、堆分配量、异常次数、是否不健康）
+func (s *Server) listFunctionResources(c *gin.Context) {
+	monitor := s.app.GetResourceMonitor()
+	if monitor == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "resource monitoring is disabled"})
+		return
+	}
+
+	profiles := monitor.Profiles()
+	var unhealthy []string
+	for _, profile := range profiles {
+		if profile.Unhealthy {
+			unhealthy = append(unhealthy, profile.Name)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"functions": profiles,
+		"unhealthy": unhealthy,
+	})
+}
+
+// 清除函数的不健康标记（修复或替换函数实现之后）
+func (s *Server) resetFunctionResources(c *gin.Context) {
+	monitor := s.app.GetResourceMonitor()
+	if monitor == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "resource monitoring is disabled"})
+		return
+	}
+
+	name := c.Param("name")
+	if !monitor.Reset(name) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no resource profile for function: " + name})
+		return
+	}
+	profile, _ := monitor.Profile(name)
+	c.JSON(http.StatusOK, profile)
+}
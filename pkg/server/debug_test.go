@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/chassis"
+)
+
+func TestServer_FunctionResourcesOptIn(t *testing.T) {
+	llmURL, _ := replyingLLM(t, "ok")
+
+	t.Run("disabled by default", func(t *testing.T) {
+		s := newTestServer(t, llmURL)
+		if s.app.GetResourceMonitor() != nil {
+			t.Fatal("resource monitor should be off unless enabled")
+		}
+		if w := s.do(t, http.MethodGet, "/api/v1/debug/functions", nil); w.Code != http.StatusNotFound {
+			t.Errorf("GET /debug/functions status = %d, want 404", w.Code)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		s := newTestServer(t, llmURL, chassis.WithResources(chassis.ResourceConfig{Enabled: true}))
+		if s.app.GetResourceMonitor() == nil {
+			t.Fatal("resource monitor should be on when enabled")
+		}
+		if w := s.do(t, http.MethodGet, "/api/v1/debug/functions", nil); w.Code != http.StatusOK {
+			t.Errorf("GET /debug/functions status = %d, want 200", w.Code)
+		}
+	})
+}
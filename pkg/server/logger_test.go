@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/chassis"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// syncBuffer 可并发写入的 bytes.Buffer
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestServer_SeparateLoggers(t *testing.T) {
+	// 全局 logger 换成缓冲区，确认两个 App 的日志都不会写到全局实例
+	var global syncBuffer
+	prev := observability.Logger
+	observability.Logger = slog.New(slog.NewTextHandler(&global, nil))
+	t.Cleanup(func() { observability.Logger = prev })
+
+	llmURL, _ := replyingLLM(t, "hello")
+	var bufA, bufB syncBuffer
+	serverA := newTestServer(t, llmURL, chassis.WithLogger(slog.New(slog.NewTextHandler(&bufA, nil))))
+	serverB := newTestServer(t, llmURL, chassis.WithLogger(slog.New(slog.NewTextHandler(&bufB, nil))))
+
+	chat := func(s *Server, requestID string) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", strings.NewReader(`{"session_id":"`+requestID+`","message":"hi"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-ID", requestID)
+		w := httptest.NewRecorder()
+		s.engine.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("chat status = %d, body = %s", w.Code, w.Body)
+		}
+	}
+	chat(serverA, "req-a")
+	chat(serverB, "req-b")
+
+	logA, logB := bufA.String(), bufB.String()
+	for _, want := range []string{"Database initialized", "Migration applied", "Function registered", "LLM request", "HTTP request"} {
+		if !strings.Contains(logA, want) || !strings.Contains(logB, want) {
+			t.Errorf("both loggers should contain %q", want)
+		}
+	}
+	if !strings.Contains(logA, "req-a") || strings.Contains(logA, "req-b") {
+		t.Errorf("logger A should only see its own request:\n%s", logA)
+	}
+	if !strings.Contains(logB, "req-b") || strings.Contains(logB, "req-a") {
+		t.Errorf("logger B should only see its own request:\n%s", logB)
+	}
+	if g := global.String(); strings.Contains(g, "req-a") || strings.Contains(g, "req-b") || strings.Contains(g, "LLM request") ||
+		strings.Contains(g, "Database initialized") || strings.Contains(g, "Migration applied") {
+		t.Errorf("App logs leaked to the global logger:\n%s", g)
+	}
+}
+
+// eventRecorder 记录收到的结构化事件，可并发写入
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []observability.Event
+}
+
+func (r *eventRecorder) Write(event observability.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+// sessions 返回收到的对话事件所属的会话
+func (r *eventRecorder) sessions() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []string
+	for _, e := range r.events {
+		if e.Kind == observability.EventChat {
+			ids = append(ids, e.SessionID)
+		}
+	}
+	return ids
+}
+
+func TestServer_SeparateEventSinks(t *testing.T) {
+	var global eventRecorder
+	observability.RegisterSink(&global)
+	t.Cleanup(observability.ResetSinks)
+
+	llmURL, _ := replyingLLM(t, "hello")
+	serverA := newTestServer(t, llmURL)
+	serverB := newTestServer(t, llmURL)
+	var sinkA, sinkB eventRecorder
+	serverA.app.RegisterSink(&sinkA)
+	serverB.app.RegisterSink(&sinkB)
+
+	for _, c := range []struct {
+		s       *Server
+		session string
+	}{{serverA, "a"}, {serverB, "b"}} {
+		if w := c.s.do(t, http.MethodPost, "/api/v1/chat", map[string]any{"session_id": c.session, "message": "hi"}); w.Code != http.StatusOK {
+			t.Fatalf("chat status = %d, body = %s", w.Code, w.Body)
+		}
+	}
+
+	if got := sinkA.sessions(); len(got) != 1 || got[0] != "a" {
+		t.Errorf("sink A chat events = %v, want [a]", got)
+	}
+	if got := sinkB.sessions(); len(got) != 1 || got[0] != "b" {
+		t.Errorf("sink B chat events = %v, want [b]", got)
+	}
+	if got := global.sessions(); len(got) != 0 {
+		t.Errorf("App events leaked to the package-level sinks: %v", got)
+	}
+}
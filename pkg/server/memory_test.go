@@ -0,0 +1,105 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/chassis"
+)
+
+// systemPrompt 返回 LLM 收到的最后一次请求的系统提示
+func systemPrompt(t *testing.T, requests func() [][]map[string]any) string {
+	t.Helper()
+	received := requests()
+	if len(received) == 0 {
+		t.Fatal("LLM received no request")
+	}
+	messages := received[len(received)-1]
+	if len(messages) == 0 || messages[0]["role"] != "system" {
+		t.Fatalf("first message is not a system prompt: %v", messages)
+	}
+	content, _ := messages[0]["content"].(string)
+	return content
+}
+
+func TestServer_MemoryUserFromCaller(t *testing.T) {
+	llmURL, requests := replyingLLM(t, "ok")
+	app := chassis.New(testAppOptions(t, llmURL, chassis.WithAuth(chassis.AuthConfig{
+		APIKeys: []chassis.APIKeyConfig{{Name: "web", Key: "web-key"}},
+	}))...)
+	if err := app.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { app.Shutdown() })
+	s := NewServer(app, &ServerConfig{Mode: "test"})
+
+	repo := app.GetMemoryRepository()
+	if err := repo.Set("api_key:web", "name", "Web User"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Set("telegram:42", "secret", "telegram-only"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		body map[string]any
+	}{
+		{
+			name: "chat with spoofed user_id and channel",
+			path: "/api/v1/chat",
+			body: map[string]any{
+				"message": "hi",
+				"user_id": "telegram:42",
+				"channel": map[string]any{"type": "telegram", "chat_id": "42"},
+			},
+		},
+		{
+			name: "openai with spoofed user",
+			path: "/v1/chat/completions",
+			body: map[string]any{
+				"messages": []map[string]any{{"role": "user", "content": "hi"}},
+				"user":     "telegram:42",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.doWithKey(t, "web-key", http.MethodPost, tt.path, tt.body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+			}
+			prompt := systemPrompt(t, requests)
+			if !strings.Contains(prompt, "name: Web User") {
+				t.Errorf("system prompt misses the caller's memory:\n%s", prompt)
+			}
+			if strings.Contains(prompt, "telegram-only") {
+				t.Errorf("system prompt leaks another user's memory:\n%s", prompt)
+			}
+		})
+	}
+}
+
+func TestServer_MemoryAnonymousWithoutAuth(t *testing.T) {
+	llmURL, requests := replyingLLM(t, "ok")
+	s := newTestServer(t, llmURL)
+
+	repo := s.app.GetMemoryRepository()
+	if err := repo.Set(anonymousUserID, "lang", "French"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Set("telegram:42", "secret", "telegram-only"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := s.do(t, http.MethodPost, "/api/v1/chat", map[string]any{"message": "hi", "user_id": "telegram:42"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+	prompt := systemPrompt(t, requests)
+	if !strings.Contains(prompt, "lang: French") || strings.Contains(prompt, "telegram-only") {
+		t.Errorf("unexpected memories in system prompt:\n%s", prompt)
+	}
+}
@@ -0,0 +1,336 @@
+// Package server 提供 HTTP Server 功能
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/KodaTao/AgentChassis/pkg/chassis"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// openAISessionHeader 指定服务端会话的请求头，会话已存在时只取最后一条用户消息，历史由服务端会话维护
+const openAISessionHeader = "X-Session-ID"
+
+// clientInstructionsPrefix 客户端 system 消息转为用户消息时的开头
+// 客户端的系统提示不能以 system 角色写入会话，否则会与框架的系统提示（函数调用协议、安全规则）并列甚至覆盖它
+const clientInstructionsPrefix = "[Instructions from the client application]\n"
+
+// openAIChatRequest OpenAI chat completions 请求中本框架使用的字段
+type openAIChatRequest struct {
+	Model               string          `json:"model"`
+	Messages            []openAIMessage `json:"messages"`
+	Stream              bool            `json:"stream"`
+	Temperature         *float64        `json:"temperature,omitempty"`
+	MaxTokens           *int            `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int            `json:"max_completion_tokens,omitempty"`
+	ResponseFormat      *struct {
+		Type string `json:"type"`
+	} `json:"response_format,omitempty"`
+}
+
+// openAIMessage OpenAI 消息，content 可以是字符串或 [{type: text, text: ...}] 数组
+type openAIMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// text 取出消息的文本内容，数组形式时拼接所有 text 片段，忽略图片等其他类型
+func (m openAIMessage) text() (string, error) {
+	if len(m.Content) == 0 || string(m.Content) == "null" {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return s, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return "", fmt.Errorf("invalid content of %s message: must be a string or an array of content parts", m.Role)
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// openAIChoice 非流式响应中的一个候选
+type openAIChoice struct {
+	Index        int                   `json:"index"`
+	Message      openAIResponseMessage `json:"message"`
+	FinishReason string                `json:"finish_reason"`
+}
+
+// openAIResponseMessage 响应中的助手消息
+type openAIResponseMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// openAICompletion chat.completion 响应
+type openAICompletion struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []openAIChoice `json:"choices"`
+}
+
+// openAIChunkChoice 流式响应中的一个候选
+type openAIChunkChoice struct {
+	Index        int               `json:"index"`
+	Delta        map[string]string `json:"delta"`
+	FinishReason *string           `json:"finish_reason"`
+}
+
+// openAIChunk chat.completion.chunk 流式响应
+type openAIChunk struct {
+	ID      string              `json:"id"`
+	Object  string              `json:"object"`
+	Created int64               `json:"created"`
+	Model   string              `json:"model"`
+	Choices []openAIChunkChoice `json:"choices"`
+}
+
+// openAIChatErrorStatus 对话失败时的 HTTP 状态码，与 /chat 接口一致
+func openAIChatErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, llm.ErrCircuitOpen):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, chassis.ErrSessionAwaitingApproval), errors.Is(err, chassis.ErrSessionBusy):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondOpenAIError 以 OpenAI 的错误格式响应
+func respondOpenAIError(c *gin.Context, status int, errType, message string) {
+	c.AbortWithStatusJSON(status, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    errType,
+			"code":    nil,
+		},
+	})
+}
+
+// toChatRequest 把 OpenAI 请求转换为 Agent 对话请求
+// 最后一条消息必须是用户消息；之前的 user、assistant 消息作为历史写入新会话，tool 消息忽略，
+// system 消息转为带 clientInstructionsPrefix 的用户消息
+func toChatRequest(req openAIChatRequest) (chassis.ChatRequest, error) {
+	if len(req.Messages) == 0 {
+		return chassis.ChatRequest{}, errors.New("messages is required")
+	}
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role != string(llm.RoleUser) {
+		return chassis.ChatRequest{}, errors.New("the last message must be a user message")
+	}
+	message, err := last.text()
+	if err != nil {
+		return chassis.ChatRequest{}, err
+	}
+	if strings.TrimSpace(message) == "" {
+		return chassis.ChatRequest{}, errors.New("the last user message is empty")
+	}
+
+	var history []chassis.HistoryMessage
+	for _, msg := range req.Messages[:len(req.Messages)-1] {
+		switch llm.Role(msg.Role) {
+		case llm.RoleSystem, llm.RoleUser, llm.RoleAssistant:
+		default:
+			continue
+		}
+		content, err := msg.text()
+		if err != nil {
+			return chassis.ChatRequest{}, err
+		}
+		if content == "" {
+			continue
+		}
+		role := msg.Role
+		if llm.Role(role) == llm.RoleSystem {
+			role, content = string(llm.RoleUser), clientInstructionsPrefix+content
+		}
+		history = append(history, chassis.HistoryMessage{Role: role, Content: content})
+	}
+
+	chatReq := chassis.ChatRequest{
+		Message:     message,
+		History:     history,
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+	if chatReq.MaxTokens == nil {
+		chatReq.MaxTokens = req.MaxCompletionTokens
+	}
+	if req.ResponseFormat != nil {
+		chatReq.ResponseFormat = req.ResponseFormat.Type
+	}
+	return chatReq, nil
+}
+
+// OpenAI 兼容的对话接口，请求和响应（含 stream 的 SSE）与 /v1/chat/completions 一致
+// 默认每次请求使用临时会话，历史消息取自请求中的 messages；带 X-Session-ID 请求头时使用服务端会话
+func (s *Server) openAIChatCompletions(c *gin.Context) {
+	var req openAIChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respondOpenAIError(c, http.StatusRequestEntityTooLarge, "invalid_request_error",
+				fmt.Sprintf("request body too large: limit is %d bytes", maxErr.Limit))
+			return
+		}
+		respondOpenAIError(c, http.StatusBadRequest, "invalid_request_error", "Invalid request: "+err.Error())
+		return
+	}
+
+	chatReq, err := toChatRequest(req)
+	if err != nil {
+		respondOpenAIError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	// user 字段由客户端任意填写，记忆归属取自鉴权得到的调用方
+	chatReq.UserID = callerUserID(c.Request.Context())
+	if limit := s.config.MaxMessageChars; limit > 0 && utf8.RuneCountInString(chatReq.Message) > limit {
+		respondOpenAIError(c, http.StatusBadRequest, "invalid_request_error",
+			fmt.Sprintf("message is too long: at most %d characters are allowed", limit))
+		return
+	}
+	opts := llm.ChatOptions{Model: chatReq.Model, Temperature: chatReq.Temperature, MaxTokens: chatReq.MaxTokens, ResponseFormat: chatReq.ResponseFormat}
+	if err := opts.ValidateFor(s.app.GetConfig().LLM.CallMode); err != nil {
+		respondOpenAIError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	chatReq.Language = acceptLanguage(c.GetHeader("Accept-Language"))
+
+	// 无状态调用方每次都带完整历史，使用临时会话并在结束后删除
+	// 指定了服务端会话时，历史消息只在会话首次创建时写入
+	agent := s.app.GetAgent()
+	if sessionID := c.GetHeader(openAISessionHeader); sessionID != "" {
+		if msg := s.sessionAccessError(c.Request.Context(), sessionID); msg != "" {
+			respondOpenAIError(c, http.StatusForbidden, "permission_error", msg)
+			return
+		}
+		chatReq.SessionID = sessionID
+	} else {
+		chatReq.SessionID = fmt.Sprintf("openai_%d", time.Now().UnixNano())
+		defer agent.DeleteSession(chatReq.SessionID)
+	}
+
+	requestID := GetTraceID(c)
+	ctx := chassis.WithRequestID(c.Request.Context(), requestID)
+	completionID := "chatcmpl-" + requestID
+	model := req.Model
+	if model == "" {
+		model = s.app.GetConfig().LLM.Model
+	}
+
+	if !req.Stream {
+		resp, err := agent.Chat(ctx, chatReq)
+		if err != nil {
+			observability.ErrorContext(ctx, "OpenAI-compatible chat failed", "error", err)
+			respondOpenAIError(c, openAIChatErrorStatus(err), "server_error", "Chat failed: "+err.Error())
+			return
+		}
+		c.JSON(http.StatusOK, openAICompletion{
+			ID:      completionID,
+			Object:  "chat.completion",
+			Created: time.Now().Unix(),
+			Model:   model,
+			Choices: []openAIChoice{{
+				Message:      openAIResponseMessage{Role: string(llm.RoleAssistant), Content: resp.Reply},
+				FinishReason: "stop",
+			}},
+		})
+		return
+	}
+
+	stream, err := agent.ChatStream(ctx, chatReq)
+	if err != nil {
+		respondOpenAIError(c, openAIChatErrorStatus(err), "server_error", "Chat failed: "+err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	created := time.Now().Unix()
+	chunk := func(delta map[string]string, finish *string) openAIChunk {
+		return openAIChunk{
+			ID:      completionID,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []openAIChunkChoice{{Delta: delta, FinishReason: finish}},
+		}
+	}
+
+	writeSSE(c.Writer, chunk(map[string]string{"role": string(llm.RoleAssistant)}, nil))
+	c.Stream(func(w io.Writer) bool {
+		resp, ok := <-stream
+		if !ok {
+			return false
+		}
+		switch {
+		case resp.Error != nil:
+			observability.ErrorContext(ctx, "OpenAI-compatible chat stream failed", "error", resp.Error)
+			writeSSE(w, gin.H{"error": gin.H{"message": "Chat failed: " + resp.Error.Error(), "type": "server_error"}})
+		case resp.Done:
+			stop := "stop"
+			writeSSE(w, chunk(map[string]string{}, &stop))
+		case resp.Content != "":
+			writeSSE(w, chunk(map[string]string{"content": resp.Content}, nil))
+		}
+		return !resp.Done
+	})
+	// 客户端提前断开时排空剩余内容，避免 ChatStream 的发送方阻塞
+	go func() {
+		for range stream {
+		}
+	}()
+	fmt.Fprint(c.Writer, "data: [DONE]\n\n")
+	c.Writer.Flush()
+}
+
+// writeSSE 写出一条 SSE data 事件
+func writeSSE(w io.Writer, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// OpenAI 兼容的模型列表，返回当前配置的模型，供只认 OpenAI 接口的客户端选择
+func (s *Server) openAIListModels(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"object": "list",
+		"data": []gin.H{{
+			"id":       s.app.GetConfig().LLM.Model,
+			"object":   "model",
+			"created":  0,
+			"owned_by": "agentchassis",
+		}},
+	})
+}
@@ -0,0 +1,116 @@
+// Package server 提供 HTTP Server 功能
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/KodaTao/AgentChassis/pkg/chassis"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// traceIDKey gin 上下文中保存 trace_id 的键
+const traceIDKey = "trace_id"
+
+// APIError 统一的错误响应结构
+// error 字段与其他接口的 {"error": ...} 保持兼容，trace_id 用于报障时在日志中定位
+type APIError struct {
+	Error   string `json:"error"`
+	Code    string `json:"code,omitempty"`
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// RecoveryMiddleware 捕获 handler 的 panic，替代 gin.Recovery()
+// panic 和堆栈以 trace_id 关联记录到日志，客户端收到带 trace_id 的 APIError 500 响应。
+// trace_id 沿用请求头 X-Request-ID，没有或不满足 chassis.ValidRequestID 时自动生成，并写入响应头 X-Request-ID
+func RecoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traceID := c.GetHeader("X-Request-ID")
+		if !chassis.ValidRequestID(traceID) {
+			traceID = newTraceID()
+		}
+		c.Set(traceIDKey, traceID)
+		c.Request = c.Request.WithContext(observability.WithLogFields(c.Request.Context(), traceIDKey, traceID))
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			// 客户端已断开时无法再写响应，只记录日志
+			if isBrokenPipe(recovered) {
+				observability.WarnContext(c.Request.Context(), "HTTP client connection broken",
+					"method", c.Request.Method,
+					"path", c.Request.URL.Path,
+					"error", recovered,
+				)
+				c.Abort()
+				return
+			}
+
+			observability.ErrorContext(c.Request.Context(), "HTTP handler panic",
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"panic", fmt.Sprint(recovered),
+				"stack", string(debug.Stack()),
+			)
+
+			// handler 已经写出响应头时无法再改状态码
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.Header("X-Request-ID", traceID)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, APIError{
+				Error:   "Internal server error",
+				Code:    "internal_error",
+				TraceID: traceID,
+			})
+		}()
+
+		c.Next()
+	}
+}
+
+// GetTraceID 获取当前请求的 trace_id，未经过 RecoveryMiddleware 时返回空字符串
+func GetTraceID(c *gin.Context) string {
+	return c.GetString(traceIDKey)
+}
+
+// newTraceID 生成随机 trace_id
+func newTraceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// isBrokenPipe panic 是否由客户端断开连接（broken pipe / connection reset）引起
+func isBrokenPipe(recovered any) bool {
+	err, ok := recovered.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+	msg := strings.ToLower(syscallErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
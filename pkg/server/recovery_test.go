@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRecoveryEngine 返回只挂载 RecoveryMiddleware 的 engine，handler 处理 GET /
+func newRecoveryEngine(handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(RecoveryMiddleware())
+	engine.GET("/", handler)
+	return engine
+}
+
+func TestRecoveryMiddleware_Panic(t *testing.T) {
+	engine := newRecoveryEngine(func(c *gin.Context) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "upstream-1")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+	var body APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not an APIError: %v: %s", err, w.Body)
+	}
+	want := APIError{Error: "Internal server error", Code: "internal_error", TraceID: "upstream-1"}
+	if body != want {
+		t.Errorf("body = %+v, want %+v", body, want)
+	}
+	if got := w.Header().Get("X-Request-ID"); got != "upstream-1" {
+		t.Errorf("X-Request-ID = %q, want upstream-1", got)
+	}
+}
+
+func TestRecoveryMiddleware_PanicAfterWrite(t *testing.T) {
+	engine := newRecoveryEngine(func(c *gin.Context) {
+		c.String(http.StatusAccepted, "partial")
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	// 响应头已写出，状态码和内容保持 handler 写出的样子
+	if w.Code != http.StatusAccepted || w.Body.String() != "partial" {
+		t.Errorf("response = %d %q, want 202 partial", w.Code, w.Body)
+	}
+}
+
+func TestRecoveryMiddleware_BrokenPipe(t *testing.T) {
+	engine := newRecoveryEngine(func(c *gin.Context) {
+		panic(&net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)})
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	// 客户端已断开，不写 500 响应体
+	if w.Body.Len() != 0 {
+		t.Errorf("body = %q, want empty", w.Body)
+	}
+}
+
+func TestRecoveryMiddleware_TraceID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"valid id is kept", "abc-123.x_y", true},
+		{"missing id is generated", "", false},
+		{"too long id is replaced", strings.Repeat("a", 65), false},
+		{"unsafe characters are replaced", "id with spaces", false},
+		{"log injection is replaced", "id\" level=ERROR", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var traceID string
+			engine := newRecoveryEngine(func(c *gin.Context) {
+				traceID = GetTraceID(c)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Request-ID", tt.header)
+			}
+			engine.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got := traceID == tt.header; got != tt.keep {
+				t.Errorf("trace_id = %q, kept = %v, want %v", traceID, got, tt.keep)
+			}
+			if traceID == "" || len(traceID) > 64 {
+				t.Errorf("trace_id = %q, want a generated id", traceID)
+			}
+		})
+	}
+}
+
+func TestIsBrokenPipe(t *testing.T) {
+	opErr := func(errno syscall.Errno) error {
+		return &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", errno)}
+	}
+
+	tests := []struct {
+		name      string
+		recovered any
+		want      bool
+	}{
+		{"broken pipe", opErr(syscall.EPIPE), true},
+		{"connection reset", opErr(syscall.ECONNRESET), true},
+		{"wrapped broken pipe", fmt.Errorf("write response: %w", opErr(syscall.EPIPE)), true},
+		{"other syscall error", opErr(syscall.EACCES), false},
+		{"plain error", errors.New("broken pipe"), false},
+		{"string panic", "broken pipe", false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBrokenPipe(tt.recovered); got != tt.want {
+				t.Errorf("isBrokenPipe() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
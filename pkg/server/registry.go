@@ -0,0 +1,37 @@
+// Package server 提供 HTTP Server 功能
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/KodaTao/AgentChassis/pkg/function"
+)
+
+// 获取当前注册表的能力快照
+// 返回结果可以保存下来，之后作为基线 POST 到 /registry/diff 进行比较
+func (s *Server) registrySnapshot(c *gin.Context) {
+	c.JSON(http.StatusOK, s.app.GetRegistry().Snapshot())
+}
+
+// 比较当前注册表与基线快照的差异
+// GET 以服务初始化完成时的快照为基线；POST 以请求体中的快照为基线
+func (s *Server) registryDiff(c *gin.Context) {
+	base := s.app.GetBaselineSnapshot()
+	if c.Request.Method == http.MethodPost {
+		var posted function.Snapshot
+		if err := c.ShouldBindJSON(&posted); err != nil {
+			respondBindError(c, err)
+			return
+		}
+		base = posted
+	}
+
+	current := s.app.GetRegistry().Snapshot()
+	c.JSON(http.StatusOK, gin.H{
+		"baseline_taken_at": base.TakenAt,
+		"current_version":   current.Version,
+		"diff":              function.DiffSnapshots(base, current),
+	})
+}
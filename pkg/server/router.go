@@ -2,22 +2,43 @@
 package server
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/KodaTao/AgentChassis/pkg/chassis"
+	"github.com/KodaTao/AgentChassis/pkg/function"
+	"github.com/KodaTao/AgentChassis/pkg/function/webhook"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
 	"github.com/KodaTao/AgentChassis/pkg/observability"
+	"github.com/KodaTao/AgentChassis/pkg/protocol"
 	scheduler_pkg "github.com/KodaTao/AgentChassis/pkg/scheduler"
+	"github.com/KodaTao/AgentChassis/pkg/types"
 )
 
+// MIMETOON TOON 格式的响应类型，请求头 Accept: application/toon 时列表接口以 TOON 返回
+const MIMETOON = "application/toon"
+
+// ErrInvalidPort 监听端口不在 1-65535 范围内
+var ErrInvalidPort = errors.New("invalid server port")
+
 // Server HTTP 服务器
 type Server struct {
 	app    *chassis.App
 	engine *gin.Engine
+	api    *gin.RouterGroup // /api/v1 分组，自定义路由挂在这里以沿用鉴权
 	config *ServerConfig
+	logger *slog.Logger // 使用 App 的 logger
 }
 
 // ServerConfig 服务器配置
@@ -25,8 +46,17 @@ type ServerConfig struct {
 	Host string
 	Port int
 	Mode string // debug, release, test
+
+	MaxBodyBytes    int64 // 请求体大小上限（字节），0 使用默认值，负数不限制
+	MaxMessageChars int   // 对话消息的字符数上限，0 使用默认值，负数不限制
 }
 
+// 默认的请求大小限制
+const (
+	DefaultMaxBodyBytes    int64 = 1 << 20 // 1MB
+	DefaultMaxMessageChars       = 16000
+)
+
 // NewServer 创建 HTTP 服务器
 func NewServer(app *chassis.App, config *ServerConfig) *Server {
 	// 设置 Gin 模式
@@ -39,17 +69,27 @@ func NewServer(app *chassis.App, config *ServerConfig) *Server {
 		gin.SetMode(gin.DebugMode)
 	}
 
+	if config.MaxBodyBytes == 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if config.MaxMessageChars == 0 {
+		config.MaxMessageChars = DefaultMaxMessageChars
+	}
+
 	engine := gin.New()
 
-	// 添加中间件
-	engine.Use(gin.Recovery())
+	// 添加中间件，请求日志和 handler 中的日志写入 App 的 logger
+	engine.Use(LogContextMiddleware(app.GetLogger()))
+	engine.Use(RecoveryMiddleware())
 	engine.Use(LoggerMiddleware())
 	engine.Use(CORSMiddleware())
+	engine.Use(MaxBodySizeMiddleware(config.MaxBodyBytes))
 
 	server := &Server{
 		app:    app,
 		engine: engine,
 		config: config,
+		logger: app.GetLogger(),
 	}
 
 	// 注册路由
@@ -62,92 +102,269 @@ func NewServer(app *chassis.App, config *ServerConfig) *Server {
 func (s *Server) setupRoutes() {
 	// 健康检查
 	s.engine.GET("/health", s.healthCheck)
+	s.engine.GET("/health/live", s.livenessCheck)
+	s.engine.GET("/health/ready", s.readinessCheck)
+
+	// OpenAI 兼容接口，现有 OpenAI 客户端把 base_url 指向本服务即可接入
+	openai := s.engine.Group("/v1", AuthMiddleware(s.app.GetConfig().Auth))
+	openai.POST("/chat/completions", s.openAIChatCompletions)
+	openai.GET("/models", s.openAIListModels)
 
 	// API v1
-	v1 := s.engine.Group("/api/v1")
+	v1 := s.engine.Group("/api/v1", AuthMiddleware(s.app.GetConfig().Auth))
+	s.api = v1
 	{
 		// 对话接口
 		v1.POST("/chat", s.chat)
+		v1.POST("/chat/stream", s.chatStream)
 
 		// Function 管理
 		v1.GET("/functions", s.listFunctions)
+		v1.GET("/functions/schema", s.exportFunctionSchemas)
 		v1.GET("/functions/:name", s.getFunction)
+		v1.POST("/functions", RequireScopeMiddleware(AdminScope), s.registerWebhookFunction)
+		v1.DELETE("/functions/:name", RequireScopeMiddleware(AdminScope), s.unregisterWebhookFunction)
+
+		// 能力快照与差异
+		v1.GET("/registry/snapshot", s.registrySnapshot)
+		v1.GET("/registry/diff", s.registryDiff)
+		v1.POST("/registry/diff", s.registryDiff)
 
 		// Session 管理
 		v1.GET("/sessions", s.listSessions)
 		v1.DELETE("/sessions/:id", s.deleteSession)
+		v1.GET("/sessions/:id/export", s.exportSession)
+		v1.POST("/sessions/:id/cancel", s.cancelSession)
+		v1.POST("/sessions/:id/fork", s.forkSession)
+		v1.PATCH("/sessions/:id/messages/:index", s.editSessionMessage)
+		v1.DELETE("/sessions/:id/messages/:index", s.deleteSessionMessage)
 
 		// 延时任务管理
 		v1.GET("/delay-tasks", s.listDelayTasks)
 		v1.POST("/delay-tasks", s.createDelayTask)
 		v1.GET("/delay-tasks/:id", s.getDelayTask)
+		v1.DELETE("/delay-tasks", RequireScopeMiddleware(AdminScope), s.bulkDeleteDelayTasks)
 		v1.DELETE("/delay-tasks/:id", s.cancelDelayTask)
 
 		// 定时任务管理
 		v1.GET("/crons", s.listCronTasks)
 		v1.POST("/crons", s.createCronTask)
+		v1.GET("/crons/preview", s.previewCronExpr)
 		v1.GET("/crons/:id", s.getCronTask)
+		v1.DELETE("/crons", RequireScopeMiddleware(AdminScope), s.bulkDeleteCronTasks)
 		v1.DELETE("/crons/:id", s.deleteCronTask)
 		v1.GET("/crons/:id/history", s.getCronTaskHistory)
+
+		// 函数调用记录，包含全部调用方的函数参数，仅限管理员
+		v1.GET("/function-calls", RequireScopeMiddleware(AdminScope), s.listFunctionCalls)
+
+		// 函数资源画像
+		v1.GET("/debug/functions", s.listFunctionResources)
+		v1.POST("/debug/functions/:name/reset", RequireScopeMiddleware(AdminScope), s.resetFunctionResources)
+
+		// 对话审计记录（需开启 audit.enabled），包含全部调用方的对话内容，仅限管理员
+		v1.GET("/audit", RequireScopeMiddleware(AdminScope), s.listAuditRecords)
+		v1.GET("/audit/export", RequireScopeMiddleware(AdminScope), s.exportAuditRecords)
+
+		// 函数调用审批，审批记录包含函数参数，查看和决定都仅限管理员
+		v1.GET("/approvals", RequireScopeMiddleware(AdminScope), s.listApprovals)
+		v1.GET("/approvals/:id", RequireScopeMiddleware(AdminScope), s.getApproval)
+		v1.POST("/approvals/:id/approve", RequireScopeMiddleware(AdminScope), s.approveCall)
+		v1.POST("/approvals/:id/reject", RequireScopeMiddleware(AdminScope), s.rejectCall)
 	}
 }
 
 // Run 启动服务器
 func (s *Server) Run() error {
-	addr := s.config.Host + ":" + itoa(s.config.Port)
-	observability.Info("Starting HTTP server", "address", addr)
+	// 端口为 0 时 net.Listen 会随机选择端口，通常是漏配了 port，直接报错
+	if s.config.Port <= 0 || s.config.Port > 65535 {
+		return fmt.Errorf("%w: %d (must be between 1 and 65535)", ErrInvalidPort, s.config.Port)
+	}
+
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	s.logger.Info("Starting HTTP server", "address", addr)
 	return s.engine.Run(addr)
 }
 
+// RegisterRoutes 在 /api/v1 分组下注册自定义路由，沿用 API 的鉴权等中间件
+// 需要在 NewServer 之后、Run 之前调用；fn 拿到的是独立的子分组，对它调用 Use 只影响本次注册的路由
+func (s *Server) RegisterRoutes(fn func(*gin.RouterGroup)) {
+	fn(s.api.Group(""))
+}
+
+// RegisterPublicRoutes 在根路径下注册不需要鉴权的路由，如静态文件、第三方回调
+// 调用时机和中间件作用范围与 RegisterRoutes 相同
+func (s *Server) RegisterPublicRoutes(fn func(*gin.RouterGroup)) {
+	fn(s.engine.Group(""))
+}
+
 // GetEngine 获取 Gin 引擎（用于测试）
 func (s *Server) GetEngine() *gin.Engine {
 	return s.engine
 }
 
-// 健康检查
+// 健康检查（返回各依赖的详细状态，始终返回 200，是否就绪看 status 字段或 /health/ready）
 func (s *Server) healthCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, s.app.CheckHealth(c.Request.Context()))
+}
+
+// 存活检查（进程能响应即视为存活）
+func (s *Server) livenessCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"status":    "healthy",
+		"status":    chassis.HealthStatusHealthy,
 		"timestamp": time.Now().Unix(),
 	})
 }
 
+// 就绪检查（依赖不可用时返回 503）
+func (s *Server) readinessCheck(c *gin.Context) {
+	report := s.app.CheckHealth(c.Request.Context())
+	if !report.IsHealthy() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
 // 对话接口
 func (s *Server) chat(c *gin.Context) {
-	var req chassis.ChatRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request: " + err.Error(),
-		})
+	req, ctx, ok := s.bindChatRequest(c)
+	if !ok {
 		return
 	}
 
-	if req.Message == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "message is required",
+	// 执行对话
+	resp, err := s.app.GetAgent().Chat(ctx, req)
+	if errors.Is(err, llm.ErrCircuitOpen) {
+		// 上游 LLM 不可用，断路器快速失败，提示客户端稍后重试
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Chat failed: " + err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, chassis.ErrSessionAwaitingApproval) {
+		// 会话中有函数调用等待审批，审批完成后才能继续
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
 		})
 		return
 	}
-
-	// 执行对话
-	resp, err := s.app.GetAgent().Chat(c.Request.Context(), req)
 	if err != nil {
-		observability.Error("Chat failed", "error", err)
+		observability.ErrorContext(c.Request.Context(), "Chat failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Chat failed: " + err.Error(),
 		})
 		return
 	}
 
+	c.Header("X-Request-ID", resp.RequestID)
 	c.JSON(http.StatusOK, resp)
 }
 
+// bindChatRequest 解析并校验对话请求，返回带请求 ID 的 context，校验失败时已写入 400（无权使用会话时为 403）响应
+func (s *Server) bindChatRequest(c *gin.Context) (chassis.ChatRequest, context.Context, bool) {
+	var req chassis.ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return req, nil, false
+	}
+
+	if req.Message == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "message is required",
+		})
+		return req, nil, false
+	}
+	if limit := s.config.MaxMessageChars; limit > 0 && utf8.RuneCountInString(req.Message) > limit {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("message is too long: at most %d characters are allowed, please shorten it or split it into several messages", limit),
+		})
+		return req, nil, false
+	}
+
+	// 继续已有会话时只有会话的创建者或管理员可以使用
+	if !s.requireSessionAccess(c, req.SessionID) {
+		return req, nil, false
+	}
+
+	// 未指定语言时按 Accept-Language 请求头选择系统提示语言
+	if req.Language == "" {
+		req.Language = acceptLanguage(c.GetHeader("Accept-Language"))
+	}
+
+	opts := llm.ChatOptions{Model: req.Model, Temperature: req.Temperature, MaxTokens: req.MaxTokens, ResponseFormat: req.ResponseFormat}
+	if err := opts.ValidateFor(s.app.GetConfig().LLM.CallMode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return req, nil, false
+	}
+
+	// 请求 ID 与 trace_id 一致（调用方传入 X-Request-ID 时沿用），便于和上游链路及 panic 日志串联
+	ctx := c.Request.Context()
+	if requestID := GetTraceID(c); requestID != "" {
+		ctx = chassis.WithRequestID(ctx, requestID)
+	}
+	req.UserID = callerUserID(ctx)
+	return req, ctx, true
+}
+
+// anonymousUserID 未开启鉴权时所有 HTTP 调用方共用的用户标识
+const anonymousUserID = "api:anonymous"
+
+// callerUserID 返回 HTTP 调用方的用户标识，长期记忆按它隔离：开启鉴权时为 api_key:<名称>，否则为 anonymousUserID
+// 不取自请求体，也不回退到请求体中的渠道，避免冒用其他 API Key 或 Telegram 用户的记忆
+func callerUserID(ctx context.Context) string {
+	if caller := types.CallerFromContext(ctx); caller != "" {
+		return caller
+	}
+	return anonymousUserID
+}
+
 // 列出所有 Function
+// 支持 q（匹配名称、别名、描述）、category、tag 过滤，以及 limit/offset 分页（不传 limit 时返回全部）
 func (s *Server) listFunctions(c *gin.Context) {
-	functions := s.app.GetRegistry().ListInfo()
-	c.JSON(http.StatusOK, gin.H{
+	filter := function.FunctionFilter{
+		Query:    c.Query("q"),
+		Category: c.Query("category"),
+		Tag:      c.Query("tag"),
+	}
+	functions := s.app.GetRegistry().ListInfoFiltered(c.Request.Context(), filter)
+	total := len(functions)
+
+	// 分页参数
+	limit := 0
+	offset := 0
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+	functions = functions[min(offset, total):]
+	if limit > 0 && limit < len(functions) {
+		functions = functions[:limit]
+	}
+
+	respondNegotiated(c, http.StatusOK, gin.H{
 		"functions": functions,
 		"count":     len(functions),
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// 导出 Function 的 JSON Schema，供 OpenAI function calling、MCP 等外部系统使用
+func (s *Server) exportFunctionSchemas(c *gin.Context) {
+	schemas := s.app.GetRegistry().ExportJSONSchemaForContext(c.Request.Context())
+	c.JSON(http.StatusOK, gin.H{
+		"functions": schemas,
+		"count":     len(schemas),
 	})
 }
 
@@ -155,7 +372,11 @@ func (s *Server) listFunctions(c *gin.Context) {
 func (s *Server) getFunction(c *gin.Context) {
 	name := c.Param("name")
 
+	// 无权调用的函数对调用者不可见
 	fn, ok := s.app.GetRegistry().Get(name)
+	if ok && s.app.GetRegistry().Authorize(c.Request.Context(), name) != nil {
+		ok = false
+	}
 	if !ok {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Function not found: " + name,
@@ -174,18 +395,104 @@ func (s *Server) getFunction(c *gin.Context) {
 	c.JSON(http.StatusOK, info)
 }
 
-// 列出所有 Session
+// RegisterWebhookFunctionRequest 注册 webhook 函数请求
+type RegisterWebhookFunctionRequest struct {
+	Name        string              `json:"name" binding:"required"`
+	Description string              `json:"description" binding:"required"`
+	URL         string              `json:"url" binding:"required"`
+	Params      []webhook.ParamSpec `json:"params"`
+	TimeoutSec  int                 `json:"timeout_sec"`
+}
+
+// 注册 webhook 函数
+func (s *Server) registerWebhookFunction(c *gin.Context) {
+	manager := s.app.GetWebhookManager()
+	if manager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Webhook manager not initialized",
+		})
+		return
+	}
+
+	var req RegisterWebhookFunctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	def, err := manager.Register(c.Request.Context(), webhook.Definition{
+		Name:        req.Name,
+		Description: req.Description,
+		URL:         req.URL,
+		Params:      req.Params,
+		TimeoutSec:  req.TimeoutSec,
+	})
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, webhook.ErrNameConflict) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, def)
+}
+
+// 注销 webhook 函数
+func (s *Server) unregisterWebhookFunction(c *gin.Context) {
+	manager := s.app.GetWebhookManager()
+	if manager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Webhook manager not initialized",
+		})
+		return
+	}
+
+	name := c.Param("name")
+	if err := manager.Unregister(name); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, webhook.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, webhook.ErrNotWebhook):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Function unregistered",
+		"name":    name,
+	})
+}
+
+// 列出 Session，管理员可以看到所有会话，其他调用方只能看到自己创建的会话
 func (s *Server) listSessions(c *gin.Context) {
-	sessions := s.app.GetAgent().ListSessions()
+	ctx := c.Request.Context()
+	var sessions []string
+	if function.HasScope(ctx, AdminScope) {
+		sessions = s.app.GetAgent().ListSessions()
+	} else {
+		sessions = s.app.GetAgent().ListSessionsOwnedBy(types.CallerFromContext(ctx))
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"sessions": sessions,
 		"count":    len(sessions),
 	})
 }
 
-// 删除 Session
+// 删除 Session，只有会话的创建者或管理员可以删除
 func (s *Server) deleteSession(c *gin.Context) {
 	id := c.Param("id")
+	if !s.requireSessionAccess(c, id) {
+		return
+	}
 
 	if s.app.GetAgent().DeleteSession(id) {
 		c.JSON(http.StatusOK, gin.H{
@@ -198,11 +505,210 @@ func (s *Server) deleteSession(c *gin.Context) {
 	}
 }
 
+// 取消 Session 上正在进行的对话，只有会话的创建者或管理员可以取消
+func (s *Server) cancelSession(c *gin.Context) {
+	id := c.Param("id")
+	if !s.requireSessionAccess(c, id) {
+		return
+	}
+
+	if s.app.GetAgent().CancelSession(id) {
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Chat cancelled",
+			"session_id": id,
+		})
+	} else {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No chat in progress for session: " + id,
+		})
+	}
+}
+
+// ForkSessionRequest 分叉会话请求
+type ForkSessionRequest struct {
+	// UpToMessageIndex 复制到第几条消息（含，下标从 0 开始，有系统提示时 0 为系统提示），不传时复制全部
+	UpToMessageIndex *int `json:"up_to_message_index"`
+}
+
+// 从 Session 的某条消息处分叉出新 Session，只有会话的创建者或管理员可以分叉，新会话归属于源会话的创建者
+func (s *Server) forkSession(c *gin.Context) {
+	id := c.Param("id")
+	if !s.requireSessionAccess(c, id) {
+		return
+	}
+
+	var req ForkSessionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBindError(c, err)
+			return
+		}
+	}
+	upTo := -1
+	if req.UpToMessageIndex != nil {
+		upTo = *req.UpToMessageIndex
+	}
+
+	newID, err := s.app.GetAgent().ForkSession(id, upTo)
+	switch {
+	case errors.Is(err, chassis.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found: " + id,
+		})
+	case errors.Is(err, chassis.ErrSessionBusy):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusCreated, gin.H{
+			"message":           "Session forked",
+			"session_id":        newID,
+			"source_session_id": id,
+		})
+	}
+}
+
+// EditMessageRequest 修正消息请求
+type EditMessageRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// 修正 Session 中的某条用户消息，其后的 AI 回复和函数结果会被删除，只有会话的创建者或管理员可以修改
+func (s *Server) editSessionMessage(c *gin.Context) {
+	id := c.Param("id")
+	if !s.requireSessionAccess(c, id) {
+		return
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid message index: " + c.Param("index"),
+		})
+		return
+	}
+
+	var req EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	err = s.app.GetAgent().EditMessage(id, index, req.Content)
+	if respondMessageEditError(c, id, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Message updated",
+		"session_id":    id,
+		"message_count": index + 1,
+	})
+}
+
+// 删除 Session 中的某条用户消息及其后的所有消息，只有会话的创建者或管理员可以删除
+func (s *Server) deleteSessionMessage(c *gin.Context) {
+	id := c.Param("id")
+	if !s.requireSessionAccess(c, id) {
+		return
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid message index: " + c.Param("index"),
+		})
+		return
+	}
+
+	err = s.app.GetAgent().DeleteMessage(id, index)
+	if respondMessageEditError(c, id, err) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Message deleted",
+		"session_id":    id,
+		"message_count": index,
+	})
+}
+
+// respondMessageEditError 把修正/删除消息的错误映射为 HTTP 响应，返回是否已响应
+func respondMessageEditError(c *gin.Context, id string, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, chassis.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found: " + id,
+		})
+	case errors.Is(err, chassis.ErrSessionBusy):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	}
+	return true
+}
+
+// 导出 Session 对话，只有会话的创建者或管理员可以导出
+// 支持 format=markdown|json，include_system=true 时包含系统提示
+func (s *Server) exportSession(c *gin.Context) {
+	id := c.Param("id")
+	if !s.requireSessionAccess(c, id) {
+		return
+	}
+
+	session, err := s.app.GetAgent().ExportSession(id)
+	switch {
+	case errors.Is(err, chassis.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found: " + id,
+		})
+		return
+	case errors.Is(err, chassis.ErrSessionBusy):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	includeSystem := c.Query("include_system") == "true"
+
+	switch c.DefaultQuery("format", chassis.ExportFormatMarkdown) {
+	case chassis.ExportFormatMarkdown:
+		c.Header("Content-Disposition", "attachment; filename=\""+id+".md\"")
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(session.ExportMarkdown(includeSystem)))
+	case chassis.ExportFormatJSON:
+		c.Header("Content-Disposition", "attachment; filename=\""+id+".json\"")
+		c.JSON(http.StatusOK, session.Export(includeSystem))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid format, expected markdown or json",
+		})
+	}
+}
+
 // CreateDelayTaskRequest 创建延时任务请求
 type CreateDelayTaskRequest struct {
 	Name   string `json:"name" binding:"required"`
 	RunAt  string `json:"run_at" binding:"required"` // ISO8601 格式
 	Prompt string `json:"prompt" binding:"required"` // 触发时发给AI的提示词
+
+	IdempotencyKey string `json:"idempotency_key"` // 可选，相同键的待执行任务已存在时直接返回
+	WebhookURL     string `json:"webhook_url"`     // 可选，执行结束后 POST 推送执行结果
+	Priority       int    `json:"priority"`        // 可选，越大越先执行，默认 0
+
+	Tags  []string `json:"tags"`  // 可选，标签
+	Group string   `json:"group"` // 可选，分组
 }
 
 // 列出延时任务
@@ -237,7 +743,8 @@ func (s *Server) listDelayTasks(c *gin.Context) {
 		}
 	}
 
-	tasks, err := scheduler.ListTasks(status, limit, offset)
+	filter := scheduler_pkg.DelayTaskFilter{Status: status, Tag: c.Query("tag"), Group: c.Query("group")}
+	tasks, err := scheduler.ListTasksFiltered(filter, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to list tasks: " + err.Error(),
@@ -245,7 +752,7 @@ func (s *Server) listDelayTasks(c *gin.Context) {
 		return
 	}
 
-	total, err := scheduler.CountTasks(status)
+	total, err := scheduler.CountTasksFiltered(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to count tasks: " + err.Error(),
@@ -253,7 +760,7 @@ func (s *Server) listDelayTasks(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	respondNegotiated(c, http.StatusOK, gin.H{
 		"tasks":  tasks,
 		"total":  total,
 		"limit":  limit,
@@ -273,9 +780,7 @@ func (s *Server) createDelayTask(c *gin.Context) {
 
 	var req CreateDelayTaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request: " + err.Error(),
-		})
+		respondBindError(c, err)
 		return
 	}
 
@@ -288,8 +793,21 @@ func (s *Server) createDelayTask(c *gin.Context) {
 		return
 	}
 
-	// 创建任务
-	task, err := scheduler.CreateTask(req.Name, runAt, req.Prompt)
+	// 创建任务，记录创建者的作用域，执行时不能超出
+	task, existed, err := scheduler.CreateTaskWithOptions(req.Name, runAt, req.Prompt, scheduler_pkg.DelayTaskOptions{
+		IdempotencyKey: req.IdempotencyKey,
+		WebhookURL:     req.WebhookURL,
+		Priority:       req.Priority,
+		Tags:           req.Tags,
+		Group:          req.Group,
+		CallerScopes:   scheduler_pkg.EncodeCallerScopes(function.CallerScopesFromContext(c.Request.Context())),
+	})
+	if errors.Is(err, scheduler_pkg.ErrInvalidWebhookURL) || isInvalidLabel(err) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -297,6 +815,10 @@ func (s *Server) createDelayTask(c *gin.Context) {
 		return
 	}
 
+	if existed {
+		c.JSON(http.StatusOK, task)
+		return
+	}
 	c.JSON(http.StatusCreated, task)
 }
 
@@ -373,6 +895,76 @@ func (s *Server) cancelDelayTask(c *gin.Context) {
 	})
 }
 
+// 按状态批量处理延时任务，可用 tag、group 缩小范围
+// status=pending 取消待执行任务，终态（completed/failed/cancelled/missed）则删除对应任务
+func (s *Server) bulkDeleteDelayTasks(c *gin.Context) {
+	scheduler := s.app.GetDelayScheduler()
+	if scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "DelayScheduler not initialized",
+		})
+		return
+	}
+
+	statusStr := c.Query("status")
+	if statusStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "status query parameter is required",
+		})
+		return
+	}
+	status := scheduler_pkg.TaskStatus(statusStr)
+	filter := scheduler_pkg.DelayTaskFilter{Status: &status, Tag: c.Query("tag"), Group: c.Query("group")}
+
+	if status == scheduler_pkg.StatusPending {
+		count, err := scheduler.CancelPending(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Pending tasks cancelled successfully",
+			"count":   count,
+		})
+		return
+	}
+
+	count, err := scheduler.DeleteByFilter(filter)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, scheduler_pkg.ErrStatusNotDeletable) {
+			code = http.StatusBadRequest
+		}
+		c.JSON(code, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tasks deleted successfully",
+		"status":  status,
+		"count":   count,
+	})
+}
+
+// isInvalidLabel 是否为标签或分组不合法的错误
+func isInvalidLabel(err error) bool {
+	return errors.Is(err, scheduler_pkg.ErrInvalidTag) || errors.Is(err, scheduler_pkg.ErrTooManyTags) ||
+		errors.Is(err, scheduler_pkg.ErrInvalidGroup)
+}
+
+// LogContextMiddleware 把 logger 写入请求 context，之后的中间件和 handler 通过 *Context 日志函数写入该 logger
+// 同一进程中运行多个 Server 时，各自的日志互不混杂
+func LogContextMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(observability.WithLogger(c.Request.Context(), logger))
+		c.Next()
+	}
+}
+
 // LoggerMiddleware 日志中间件
 func LoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -384,7 +976,7 @@ func LoggerMiddleware() gin.HandlerFunc {
 		latency := time.Since(start)
 		status := c.Writer.Status()
 
-		observability.Info("HTTP request",
+		observability.InfoContext(c.Request.Context(), "HTTP request",
 			"method", c.Request.Method,
 			"path", path,
 			"status", status,
@@ -399,7 +991,7 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-API-Key")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -410,18 +1002,164 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
-// itoa 简单的整数转字符串
-func itoa(n int) string {
-	if n == 0 {
-		return "0"
+// MaxBodySizeMiddleware 请求体大小限制中间件
+// Content-Length 已超限时直接返回 413；否则用 http.MaxBytesReader 包装请求体，
+// 读取超限时由 respondBindError 返回 413。limit <= 0 时不限制
+func MaxBodySizeMiddleware(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("request body too large: limit is %d bytes", limit),
+			})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// respondNegotiated 按 Accept 头协商响应格式
+// 客户端接受 application/toon 时以 TOON 编码返回（节省 token，便于直接喂给 LLM），否则返回 JSON
+func respondNegotiated(c *gin.Context, code int, data any) {
+	if c.NegotiateFormat(gin.MIMEJSON, MIMETOON) != MIMETOON {
+		c.JSON(code, data)
+		return
 	}
 
-	var digits []byte
-	for n > 0 {
-		digits = append([]byte{byte('0' + n%10)}, digits...)
-		n /= 10
+	body, err := protocol.EncodeTOON(data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to encode TOON: " + err.Error(),
+		})
+		return
 	}
-	return string(digits)
+	c.Data(code, MIMETOON+"; charset=utf-8", []byte(body))
+}
+
+// respondBindError 返回请求体解析错误，请求体超限时返回 413，其余返回 400
+func respondBindError(c *gin.Context, err error) {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("request body too large: limit is %d bytes", maxErr.Limit),
+		})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": "Invalid request: " + err.Error(),
+	})
+}
+
+// acceptLanguage 返回 Accept-Language 请求头中的首选语言，如 "ja,en;q=0.8" -> ja
+// 客户端通常按偏好顺序列出语言，这里不再按 q 值排序
+func acceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		if tag = strings.TrimSpace(tag); tag != "" && tag != "*" {
+			return tag
+		}
+	}
+	return ""
+}
+
+// AuthMiddleware API Key 鉴权中间件
+// 未配置 API Key 时不做校验；校验通过后将 Key 的作用域写入请求 context，
+// Agent 和 Executor 据此过滤系统提示中的函数并拦截越权调用
+func AuthMiddleware(cfg chassis.AuthConfig) gin.HandlerFunc {
+	if !cfg.Enabled() {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	// 预先解析环境变量引用
+	keys := make([]chassis.APIKeyConfig, 0, len(cfg.APIKeys))
+	for _, k := range cfg.APIKeys {
+		k.Key = llm.ResolveAPIKey(k.Key)
+		if k.Key == "" {
+			observability.Warn("Ignoring empty API key", "name", k.Name)
+			continue
+		}
+		keys = append(keys, k)
+	}
+
+	return func(c *gin.Context) {
+		token := c.GetHeader("X-API-Key")
+		if token == "" {
+			token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+
+		for _, k := range keys {
+			if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(k.Key)) == 1 {
+				ctx := function.WithCallerScopes(c.Request.Context(), k.Scopes)
+				ctx = types.WithCaller(ctx, "api_key:"+k.Name)
+				c.Request = c.Request.WithContext(ctx)
+				c.Set("api_key_name", k.Name)
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or missing API key",
+		})
+	}
+}
+
+// AdminScope 审批等管理操作需要的作用域，拥有 "*" 的 API Key 同样可以执行
+const AdminScope = "admin"
+
+// RequireScopeMiddleware 要求 API Key 拥有指定作用域（或 "*"），否则返回 403；未开启鉴权时不限制
+func RequireScopeMiddleware(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !function.HasScope(c.Request.Context(), scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "API key lacks the required scope: " + scope,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// canAccessSession 调用方能否访问该会话：拥有 AdminScope（未开启鉴权时视为拥有）或是会话的创建者
+// 会话不存在时返回 true，由处理函数按各自的方式处理（返回 404 或新建会话）
+func (s *Server) canAccessSession(ctx context.Context, id string) bool {
+	if function.HasScope(ctx, AdminScope) {
+		return true
+	}
+	owner, err := s.app.GetAgent().SessionOwner(id)
+	if errors.Is(err, chassis.ErrSessionNotFound) {
+		return true
+	}
+	return owner != "" && owner == types.CallerFromContext(ctx)
+}
+
+// sessionAccessError 会话访问检查，所有按会话 ID 操作的接口（包括对话和 OpenAI 兼容接口）共用
+// 调用方无权访问会话时返回 403 响应的错误描述，可以访问时返回空字符串；id 为空（由服务端新建会话）时总是可以访问
+func (s *Server) sessionAccessError(ctx context.Context, id string) string {
+	if id == "" || s.canAccessSession(ctx, id) {
+		return ""
+	}
+	return "Session belongs to another caller: " + id
+}
+
+// requireSessionAccess 调用方无权访问会话时返回 403，返回是否可以继续处理
+func (s *Server) requireSessionAccess(c *gin.Context, id string) bool {
+	msg := s.sessionAccessError(c.Request.Context(), id)
+	if msg == "" {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error": msg,
+	})
+	return false
 }
 
 // CreateCronTaskRequest 创建定时任务请求
@@ -430,6 +1168,13 @@ type CreateCronTaskRequest struct {
 	CronExpr    string `json:"cron_expr" binding:"required"`
 	Prompt      string `json:"prompt" binding:"required"` // 触发时发给AI的提示词
 	Description string `json:"description"`
+
+	ConcurrencyPolicy string `json:"concurrency_policy"` // allow（默认）/skip/queue
+	MisfirePolicy     string `json:"misfire_policy"`     // ignore（默认）/run_once/run_all
+	WebhookURL        string `json:"webhook_url"`        // 可选，每次执行结束后 POST 推送执行结果
+
+	Tags  []string `json:"tags"`  // 可选，标签
+	Group string   `json:"group"` // 可选，分组
 }
 
 // 列出定时任务
@@ -456,7 +1201,8 @@ func (s *Server) listCronTasks(c *gin.Context) {
 		}
 	}
 
-	tasks, err := scheduler.ListTasks(limit, offset)
+	filter := scheduler_pkg.CronTaskFilter{Tag: c.Query("tag"), Group: c.Query("group")}
+	tasks, err := scheduler.ListTasksFiltered(filter, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to list cron tasks: " + err.Error(),
@@ -464,7 +1210,7 @@ func (s *Server) listCronTasks(c *gin.Context) {
 		return
 	}
 
-	total, err := scheduler.CountTasks()
+	total, err := scheduler.CountTasksFiltered(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to count cron tasks: " + err.Error(),
@@ -472,7 +1218,7 @@ func (s *Server) listCronTasks(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	respondNegotiated(c, http.StatusOK, gin.H{
 		"tasks":  tasks,
 		"total":  total,
 		"limit":  limit,
@@ -492,14 +1238,26 @@ func (s *Server) createCronTask(c *gin.Context) {
 
 	var req CreateCronTaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	// 创建任务，记录创建者的作用域，执行时不能超出
+	task, err := scheduler.CreateTaskWithOptions(req.Name, req.CronExpr, req.Prompt, req.Description, scheduler_pkg.CronTaskOptions{
+		ConcurrencyPolicy: scheduler_pkg.ConcurrencyPolicy(req.ConcurrencyPolicy),
+		MisfirePolicy:     scheduler_pkg.MisfirePolicy(req.MisfirePolicy),
+		WebhookURL:        req.WebhookURL,
+		Tags:              req.Tags,
+		Group:             req.Group,
+		CallerScopes:      scheduler_pkg.EncodeCallerScopes(function.CallerScopesFromContext(c.Request.Context())),
+	})
+	if errors.Is(err, scheduler_pkg.ErrInvalidConcurrencyPolicy) || errors.Is(err, scheduler_pkg.ErrInvalidMisfirePolicy) ||
+		errors.Is(err, scheduler_pkg.ErrInvalidWebhookURL) || isInvalidLabel(err) {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request: " + err.Error(),
+			"error": err.Error(),
 		})
 		return
 	}
-
-	// 创建任务
-	task, err := scheduler.CreateTask(req.Name, req.CronExpr, req.Prompt, req.Description)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -510,6 +1268,45 @@ func (s *Server) createCronTask(c *gin.Context) {
 	c.JSON(http.StatusCreated, task)
 }
 
+// 预览 cron 表达式接下来的触发时间
+func (s *Server) previewCronExpr(c *gin.Context) {
+	scheduler := s.app.GetCronScheduler()
+	if scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "CronScheduler not initialized",
+		})
+		return
+	}
+
+	expr := c.Query("expr")
+	if expr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "expr is required",
+		})
+		return
+	}
+
+	count := scheduler_pkg.DefaultPreviewCount
+	if n := c.Query("count"); n != "" {
+		if parsed, err := strconv.Atoi(n); err == nil && parsed > 0 {
+			count = parsed
+		}
+	}
+
+	runs, err := scheduler.PreviewNextRuns(expr, count)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"expr":      expr,
+		"next_runs": runs,
+	})
+}
+
 // 获取定时任务详情
 func (s *Server) getCronTask(c *gin.Context) {
 	scheduler := s.app.GetCronScheduler()
@@ -581,6 +1378,38 @@ func (s *Server) deleteCronTask(c *gin.Context) {
 	})
 }
 
+// 按标签或分组批量删除定时任务（tag、group 至少指定一个）
+func (s *Server) bulkDeleteCronTasks(c *gin.Context) {
+	scheduler := s.app.GetCronScheduler()
+	if scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "CronScheduler not initialized",
+		})
+		return
+	}
+
+	filter := scheduler_pkg.CronTaskFilter{Tag: c.Query("tag"), Group: c.Query("group")}
+	count, err := scheduler.DeleteByFilter(filter)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, scheduler_pkg.ErrEmptyTaskFilter) {
+			code = http.StatusBadRequest
+		}
+		c.JSON(code, gin.H{
+			"error": err.Error(),
+			"count": count,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cron tasks deleted successfully",
+		"tag":     filter.Tag,
+		"group":   filter.Group,
+		"count":   count,
+	})
+}
+
 // 获取定时任务执行历史
 func (s *Server) getCronTaskHistory(c *gin.Context) {
 	scheduler := s.app.GetCronScheduler()
@@ -652,3 +1481,78 @@ func (s *Server) getCronTaskHistory(c *gin.Context) {
 		"offset":     offset,
 	})
 }
+
+// 查询函数调用记录
+// 支持 request_id、function_name、status、since、until（RFC3339）过滤和分页
+func (s *Server) listFunctionCalls(c *gin.Context) {
+	repo := s.app.GetCallLogRepository()
+	if repo == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Function call log not initialized",
+		})
+		return
+	}
+
+	filter := function.CallLogFilter{
+		RequestID:    c.Query("request_id"),
+		FunctionName: c.Query("function_name"),
+		Status:       c.Query("status"),
+	}
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid since format, expected RFC3339",
+			})
+			return
+		}
+		filter.Since = t
+	}
+	if until := c.Query("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid until format, expected RFC3339",
+			})
+			return
+		}
+		filter.Until = t
+	}
+
+	// 分页参数
+	limit := 20
+	offset := 0
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	calls, err := repo.List(filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list function calls: " + err.Error(),
+		})
+		return
+	}
+
+	total, err := repo.Count(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to count function calls: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"calls":  calls,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
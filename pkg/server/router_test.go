@@ -0,0 +1,462 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/KodaTao/AgentChassis/pkg/chassis"
+	"github.com/KodaTao/AgentChassis/pkg/prompt"
+)
+
+// startBusyChat 在后台发起一次卡在 LLM 请求上的对话，返回对话结束时关闭的 channel
+func startBusyChat(t *testing.T, s *Server, started <-chan struct{}, sessionID string) <-chan struct{} {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.do(t, http.MethodPost, "/api/v1/chat", map[string]any{"session_id": sessionID, "message": "hello"})
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("chat did not reach the LLM")
+	}
+	return done
+}
+
+// finishChat 取消进行中的对话并等待请求返回
+func finishChat(t *testing.T, s *Server, sessionID string, done <-chan struct{}) {
+	t.Helper()
+	s.do(t, http.MethodPost, "/api/v1/sessions/"+sessionID+"/cancel", nil)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("chat did not finish after cancel")
+	}
+}
+
+func TestServer_EditMessageWhileBusy(t *testing.T) {
+	url, started := blockingLLM(t)
+	s := newTestServer(t, url)
+	done := startBusyChat(t, s, started, "busy")
+
+	if w := s.do(t, http.MethodPatch, "/api/v1/sessions/busy/messages/1", map[string]string{"content": "edited"}); w.Code != http.StatusConflict {
+		t.Errorf("PATCH during chat = %d, want 409: %s", w.Code, w.Body)
+	}
+	if w := s.do(t, http.MethodDelete, "/api/v1/sessions/busy/messages/1", nil); w.Code != http.StatusConflict {
+		t.Errorf("DELETE during chat = %d, want 409: %s", w.Code, w.Body)
+	}
+	if w := s.do(t, http.MethodPost, "/api/v1/sessions/busy/fork", nil); w.Code != http.StatusConflict {
+		t.Errorf("fork during chat = %d, want 409: %s", w.Code, w.Body)
+	}
+	if w := s.do(t, http.MethodGet, "/api/v1/sessions/busy/export", nil); w.Code != http.StatusConflict {
+		t.Errorf("export during chat = %d, want 409: %s", w.Code, w.Body)
+	}
+
+	finishChat(t, s, "busy", done)
+	if w := s.do(t, http.MethodPatch, "/api/v1/sessions/busy/messages/1", map[string]string{"content": "edited"}); w.Code != http.StatusOK {
+		t.Errorf("PATCH after chat = %d, want 200: %s", w.Code, w.Body)
+	}
+}
+
+func TestServer_EditMessageInvalidIndex(t *testing.T) {
+	url, _ := blockingLLM(t)
+	s := newTestServer(t, url)
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"unknown session", "/api/v1/sessions/missing/messages/1", http.StatusNotFound},
+		{"non-numeric index", "/api/v1/sessions/missing/messages/abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := s.do(t, http.MethodPatch, tt.path, map[string]string{"content": "x"}); w.Code != tt.want {
+				t.Errorf("PATCH %s = %d, want %d", tt.path, w.Code, tt.want)
+			}
+		})
+	}
+}
+
+// newSessionOwnerServer 创建开启鉴权的 Server：ops 拥有 admin 作用域，web 和 other 没有作用域；web 已创建会话 s1
+func newSessionOwnerServer(t *testing.T) *Server {
+	t.Helper()
+	llmURL, _ := replyingLLM(t, "ok")
+	app := chassis.New(testAppOptions(t, llmURL, chassis.WithAuth(chassis.AuthConfig{
+		APIKeys: []chassis.APIKeyConfig{
+			{Name: "ops", Key: "ops-key", Scopes: []string{AdminScope}},
+			{Name: "web", Key: "web-key"},
+			{Name: "other", Key: "other-key"},
+		},
+	}))...)
+	if err := app.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { app.Shutdown() })
+	s := NewServer(app, &ServerConfig{Mode: "test"})
+
+	if w := s.doWithKey(t, "web-key", http.MethodPost, "/api/v1/chat", map[string]any{"session_id": "s1", "message": "hi"}); w.Code != http.StatusOK {
+		t.Fatalf("chat status = %d, body = %s", w.Code, w.Body)
+	}
+	return s
+}
+
+func TestServer_ExportSessionOwner(t *testing.T) {
+	s := newSessionOwnerServer(t)
+
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{"other-key", http.StatusForbidden},
+		{"web-key", http.StatusOK},
+		{"ops-key", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := s.doWithKey(t, tt.key, http.MethodGet, "/api/v1/sessions/s1/export?include_system=true", nil); w.Code != tt.want {
+			t.Errorf("export with %s status = %d, want %d", tt.key, w.Code, tt.want)
+		}
+	}
+}
+
+func TestServer_ChatSessionOwner(t *testing.T) {
+	s := newSessionOwnerServer(t)
+
+	// 其他调用方不能继续别人的会话，无论是普通对话还是流式对话
+	for _, path := range []string{"/api/v1/chat", "/api/v1/chat/stream"} {
+		if w := s.doWithKey(t, "other-key", http.MethodPost, path, map[string]any{"session_id": "s1", "message": "what did I say?"}); w.Code != http.StatusForbidden {
+			t.Errorf("%s by another caller = %d, want 403", path, w.Code)
+		}
+	}
+	if got := len(s.app.GetAgent().GetSession("s1").Messages); got != 3 {
+		t.Errorf("session s1 has %d messages after rejected chats, want 3", got)
+	}
+
+	// 新的会话 ID 归属于发起对话的调用方
+	if w := s.doWithKey(t, "other-key", http.MethodPost, "/api/v1/chat", map[string]any{"session_id": "s2", "message": "hi"}); w.Code != http.StatusOK {
+		t.Fatalf("chat on a new session = %d: %s", w.Code, w.Body)
+	}
+	for _, key := range []string{"web-key", "ops-key"} {
+		if w := s.doWithKey(t, key, http.MethodPost, "/api/v1/chat", map[string]any{"session_id": "s1", "message": "again"}); w.Code != http.StatusOK {
+			t.Errorf("chat with %s = %d, want 200: %s", key, w.Code, w.Body)
+		}
+	}
+}
+
+func TestServer_ListSessionsOwner(t *testing.T) {
+	s := newSessionOwnerServer(t)
+	if w := s.doWithKey(t, "other-key", http.MethodPost, "/api/v1/chat", map[string]any{"session_id": "s2", "message": "hi"}); w.Code != http.StatusOK {
+		t.Fatalf("chat status = %d, body = %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"web-key", []string{"s1"}},
+		{"other-key", []string{"s2"}},
+		{"ops-key", []string{"s1", "s2"}},
+	}
+	for _, tt := range tests {
+		w := s.doWithKey(t, tt.key, http.MethodGet, "/api/v1/sessions", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list with %s = %d", tt.key, w.Code)
+		}
+		var resp struct {
+			Sessions []string `json:"sessions"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(resp.Sessions)
+		if !slices.Equal(resp.Sessions, tt.want) {
+			t.Errorf("list with %s = %v, want %v", tt.key, resp.Sessions, tt.want)
+		}
+	}
+}
+
+func TestServer_CancelDeleteSessionOwner(t *testing.T) {
+	s := newSessionOwnerServer(t)
+
+	// 其他调用方不能取消或删除别人的会话
+	if w := s.doWithKey(t, "other-key", http.MethodPost, "/api/v1/sessions/s1/cancel", nil); w.Code != http.StatusForbidden {
+		t.Errorf("cancel by another caller = %d, want 403", w.Code)
+	}
+	if w := s.doWithKey(t, "other-key", http.MethodDelete, "/api/v1/sessions/s1", nil); w.Code != http.StatusForbidden {
+		t.Errorf("delete by another caller = %d, want 403", w.Code)
+	}
+	if s.app.GetAgent().GetSession("s1") == nil {
+		t.Fatal("session deleted by another caller")
+	}
+
+	// 创建者可以取消（没有进行中的对话时为 404）和删除
+	if w := s.doWithKey(t, "web-key", http.MethodPost, "/api/v1/sessions/s1/cancel", nil); w.Code != http.StatusNotFound {
+		t.Errorf("cancel by owner = %d, want 404: %s", w.Code, w.Body)
+	}
+	if w := s.doWithKey(t, "web-key", http.MethodDelete, "/api/v1/sessions/s1", nil); w.Code != http.StatusOK {
+		t.Errorf("delete by owner = %d, want 200: %s", w.Code, w.Body)
+	}
+}
+
+func TestServer_EditMessageOwner(t *testing.T) {
+	s := newSessionOwnerServer(t)
+
+	// 其他调用方不能修改或删除会话中的消息
+	if w := s.doWithKey(t, "other-key", http.MethodPatch, "/api/v1/sessions/s1/messages/1", map[string]string{"content": "edited"}); w.Code != http.StatusForbidden {
+		t.Errorf("PATCH by another caller = %d, want 403", w.Code)
+	}
+	if w := s.doWithKey(t, "other-key", http.MethodDelete, "/api/v1/sessions/s1/messages/1", nil); w.Code != http.StatusForbidden {
+		t.Errorf("DELETE by another caller = %d, want 403", w.Code)
+	}
+	if got := s.app.GetAgent().GetSession("s1").Messages[1].Content; got != "hi" {
+		t.Errorf("message changed by another caller: %q", got)
+	}
+
+	if w := s.doWithKey(t, "web-key", http.MethodPatch, "/api/v1/sessions/s1/messages/1", map[string]string{"content": "edited"}); w.Code != http.StatusOK {
+		t.Errorf("PATCH by owner = %d, want 200: %s", w.Code, w.Body)
+	}
+	if w := s.doWithKey(t, "ops-key", http.MethodDelete, "/api/v1/sessions/s1/messages/1", nil); w.Code != http.StatusOK {
+		t.Errorf("DELETE by admin = %d, want 200: %s", w.Code, w.Body)
+	}
+}
+
+func TestServer_ForkSessionOwner(t *testing.T) {
+	s := newSessionOwnerServer(t)
+
+	if w := s.doWithKey(t, "other-key", http.MethodPost, "/api/v1/sessions/s1/fork", nil); w.Code != http.StatusForbidden {
+		t.Errorf("fork by another caller = %d, want 403", w.Code)
+	}
+
+	w := s.doWithKey(t, "web-key", http.MethodPost, "/api/v1/sessions/s1/fork", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("fork by owner = %d, want 201: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		SessionID string `json:"session_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	// 分叉出的会话仍归属于源会话的创建者
+	if w := s.doWithKey(t, "other-key", http.MethodGet, "/api/v1/sessions/"+resp.SessionID+"/export", nil); w.Code != http.StatusForbidden {
+		t.Errorf("export of the fork by another caller = %d, want 403", w.Code)
+	}
+	if w := s.doWithKey(t, "web-key", http.MethodGet, "/api/v1/sessions/"+resp.SessionID+"/export", nil); w.Code != http.StatusOK {
+		t.Errorf("export of the fork by owner = %d, want 200", w.Code)
+	}
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"ja", "ja"},
+		{"ja,en;q=0.8", "ja"},
+		{"zh-CN;q=0.9, en;q=0.8", "zh-CN"},
+		{" en-US , fr", "en-US"},
+		{"*", ""},
+		{"*, de", "de"},
+		{";q=0.5, ja", "ja"},
+	}
+
+	for _, tt := range tests {
+		if got := acceptLanguage(tt.header); got != tt.want {
+			t.Errorf("acceptLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestServer_InvalidDefaultLanguage(t *testing.T) {
+	url, _ := blockingLLM(t)
+	agent := chassis.DefaultConfig().Agent
+	agent.Language = "klingon"
+
+	app := chassis.New(testAppOptions(t, url, chassis.WithAgentConfig(agent))...)
+	defer app.Shutdown()
+	err := app.Initialize()
+	if !errors.Is(err, prompt.ErrUnknownLanguage) {
+		t.Fatalf("Initialize() error = %v, want ErrUnknownLanguage", err)
+	}
+}
+
+func TestServer_FunctionRegistrationRequiresAdmin(t *testing.T) {
+	s := newApprovalServer(t)
+
+	// 没有 admin 作用域的 key 不能注册或注销函数
+	for _, r := range []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/functions"},
+		{http.MethodDelete, "/api/v1/functions/wire"},
+	} {
+		if w := s.doWithKey(t, "web-key", r.method, r.path, map[string]any{}); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s without admin scope status = %d, want 403", r.method, r.path, w.Code)
+		}
+	}
+
+	// admin key 通过作用域检查，交由处理函数校验
+	if w := s.doWithKey(t, "ops-key", http.MethodDelete, "/api/v1/functions/missing", nil); w.Code != http.StatusNotFound {
+		t.Errorf("DELETE with admin scope status = %d, want 404", w.Code)
+	}
+}
+
+func TestServer_AdminOnlyRoutes(t *testing.T) {
+	s := newApprovalServer(t)
+
+	routes := []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/debug/functions/wire/reset"},
+		{http.MethodDelete, "/api/v1/delay-tasks?status=pending"},
+		{http.MethodDelete, "/api/v1/crons?group=project-x"},
+	}
+	for _, r := range routes {
+		if w := s.doWithKey(t, "web-key", r.method, r.path, nil); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s without admin scope status = %d, want 403", r.method, r.path, w.Code)
+		}
+		if w := s.doWithKey(t, "ops-key", r.method, r.path, nil); w.Code == http.StatusForbidden {
+			t.Errorf("%s %s with admin scope status = 403", r.method, r.path)
+		}
+	}
+}
+
+func TestServer_RequestLimits(t *testing.T) {
+	llmURL, _ := replyingLLM(t, "ok")
+	app := chassis.New(testAppOptions(t, llmURL)...)
+	if err := app.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { app.Shutdown() })
+	s := NewServer(app, &ServerConfig{Mode: "test", MaxBodyBytes: 1024, MaxMessageChars: 10})
+
+	post := func(body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/chat", body)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		s.engine.ServeHTTP(w, req)
+		return w
+	}
+	oversized := `{"message":"` + strings.Repeat("a", 2048) + `"}`
+
+	tests := []struct {
+		name string
+		body io.Reader
+		want int
+	}{
+		// Content-Length 已超限，中间件直接拒绝
+		{"content length over limit", strings.NewReader(oversized), http.StatusRequestEntityTooLarge},
+		// 未知长度的请求体在读取超限时拒绝
+		{"streamed body over limit", struct{ io.Reader }{strings.NewReader(oversized)}, http.StatusRequestEntityTooLarge},
+		{"message too long", strings.NewReader(`{"message":"这条消息超过了十个字符的限制"}`), http.StatusBadRequest},
+		{"within limits", strings.NewReader(`{"message":"你好"}`), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := post(tt.body); w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestServer_RegisterRoutes(t *testing.T) {
+	s := newApprovalServer(t)
+
+	s.RegisterRoutes(func(g *gin.RouterGroup) {
+		g.Use(func(c *gin.Context) {
+			c.Header("X-Custom", "1")
+			c.Next()
+		})
+		g.GET("/hello", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"caller": c.GetString("api_key_name")})
+		})
+	})
+	s.RegisterPublicRoutes(func(g *gin.RouterGroup) {
+		g.GET("/public/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	})
+
+	// 自定义 API 路由沿用鉴权
+	if w := s.doWithKey(t, "", http.MethodGet, "/api/v1/hello", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/hello without key = %d, want 401", w.Code)
+	}
+	w := s.doWithKey(t, "web-key", http.MethodGet, "/api/v1/hello", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"caller":"web"`) {
+		t.Errorf("GET /api/v1/hello = %d %s", w.Code, w.Body)
+	}
+	if w.Header().Get("X-Custom") != "1" {
+		t.Error("group middleware did not run for the custom route")
+	}
+
+	// 分组中间件不影响已有路由
+	if w := s.doWithKey(t, "ops-key", http.MethodGet, "/api/v1/approvals", nil); w.Header().Get("X-Custom") != "" {
+		t.Error("custom middleware leaked into built-in routes")
+	}
+
+	// 公开路由不需要鉴权
+	if w := s.doWithKey(t, "", http.MethodGet, "/public/ping", nil); w.Code != http.StatusOK || w.Body.String() != "pong" {
+		t.Errorf("GET /public/ping = %d %s", w.Code, w.Body)
+	}
+}
+
+func TestServer_RunInvalidPort(t *testing.T) {
+	s := newTestServer(t, "http://127.0.0.1:1")
+
+	for _, port := range []int{0, -1, 65536} {
+		s.config.Port = port
+		if err := s.Run(); !errors.Is(err, ErrInvalidPort) {
+			t.Errorf("Run with port %d error = %v, want ErrInvalidPort", port, err)
+		}
+	}
+
+	// 合法端口会真正监听：端口已被占用时返回监听错误，说明地址按 host:port 拼接
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	s.config.Host = "127.0.0.1"
+	s.config.Port = ln.Addr().(*net.TCPAddr).Port
+	err = s.Run()
+	if err == nil || errors.Is(err, ErrInvalidPort) || !strings.Contains(err.Error(), ln.Addr().String()) {
+		t.Errorf("Run on a busy port error = %v, want a listen error for %s", err, ln.Addr())
+	}
+}
+
+func TestServer_HealthStatusCodes(t *testing.T) {
+	// /models 返回 401，LLM 探测失败，整体不就绪
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"invalid api key"}}`, http.StatusUnauthorized)
+	}))
+	t.Cleanup(srv.Close)
+	s := newTestServer(t, srv.URL)
+
+	w := s.do(t, http.MethodGet, "/health", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("/health status = %d, want 200 even when unhealthy", w.Code)
+	}
+	var report chassis.HealthReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != chassis.HealthStatusUnhealthy || report.Checks["llm"].Status != chassis.HealthStatusUnhealthy {
+		t.Errorf("/health report = %+v, want unhealthy llm check", report)
+	}
+
+	if w := s.do(t, http.MethodGet, "/health/ready", nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("/health/ready status = %d, want 503", w.Code)
+	}
+	if w := s.do(t, http.MethodGet, "/health/live", nil); w.Code != http.StatusOK {
+		t.Errorf("/health/live status = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/KodaTao/AgentChassis/pkg/chassis"
+	"github.com/KodaTao/AgentChassis/pkg/llm"
+)
+
+// newTestServer 创建使用临时数据库的 Server，LLM 请求发往 llmURL
+func newTestServer(t *testing.T, llmURL string, opts ...chassis.Option) *Server {
+	t.Helper()
+	app := chassis.New(testAppOptions(t, llmURL, opts...)...)
+	if err := app.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { app.Shutdown() })
+	return NewServer(app, &ServerConfig{Mode: "test"})
+}
+
+// testAppOptions 返回测试 App 的选项：临时数据库、丢弃日志、LLM 请求发往 llmURL，opts 追加在后面可以覆盖
+func testAppOptions(t *testing.T, llmURL string, opts ...chassis.Option) []chassis.Option {
+	t.Helper()
+	dir := t.TempDir()
+	return append([]chassis.Option{
+		chassis.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		chassis.WithDatabasePath(filepath.Join(dir, "data.db")),
+		chassis.WithLLMConfig(llm.Config{
+			Provider: "openai",
+			APIKey:   "test",
+			BaseURL:  llmURL,
+			Model:    "test-model",
+			Timeout:  5,
+		}),
+	}, opts...)
+}
+
+// do 发送请求并返回响应，body 不为 nil 时编码为 JSON
+func (s *Server) do(t *testing.T, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+	return w
+}
+
+// blockingLLM 对话请求阻塞到测试结束的 LLM 服务，用于构造正在对话中的会话
+// 每收到一个对话请求向 started 发送一次；健康探测（GET /models）直接返回成功
+func blockingLLM(t *testing.T) (url string, started <-chan struct{}) {
+	t.Helper()
+	ch := make(chan struct{}, 8)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		ch <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(func() {
+		close(release)
+		srv.Close()
+	})
+	return srv.URL, ch
+}
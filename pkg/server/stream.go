@@ -0,0 +1,54 @@
+// Package server 提供 HTTP Server 功能
+package server
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/KodaTao/AgentChassis/pkg/chassis"
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// 流式对话接口（SSE）
+// 请求与 /chat 相同；每个 data 事件是一个 JSON 对象，type 区分 content、thought、
+// function_call_start、function_call_end、done、error，函数调用事件夹在回复内容之前
+func (s *Server) chatStream(c *gin.Context) {
+	req, ctx, ok := s.bindChatRequest(c)
+	if !ok {
+		return
+	}
+
+	stream, err := s.app.GetAgent().ChatStream(ctx, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Chat failed: " + err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Request-ID", chassis.GetRequestID(ctx))
+
+	c.Stream(func(w io.Writer) bool {
+		resp, ok := <-stream
+		if !ok {
+			return false
+		}
+		if resp.Error != nil {
+			observability.ErrorContext(c.Request.Context(), "Chat stream failed", "error", resp.Error)
+			writeSSE(w, gin.H{"type": chassis.StreamError, "error": "Chat failed: " + resp.Error.Error(), "done": true})
+			return false
+		}
+		writeSSE(w, resp)
+		return !resp.Done
+	})
+	// 客户端提前断开时排空剩余事件，避免 ChatStream 的发送方阻塞
+	go func() {
+		for range stream {
+		}
+	}()
+}
@@ -0,0 +1,106 @@
+// Package storage 提供数据存储功能
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// 内置的命名连接
+const (
+	DefaultDBName   = "default"   // 默认库：webhook 函数等
+	SchedulerDBName = "scheduler" // 调度库：延时任务、定时任务及执行历史
+	SessionDBName   = "sessions"  // 会话库：会话相关数据（函数调用记录）
+)
+
+// Databases 管理多个命名的数据库连接
+// 高写入的数据分开存放到不同 SQLite 文件，可避免互相争抢写锁；
+// 路径相同的连接会复用同一个实例，避免同一文件被打开多次
+type Databases struct {
+	mu     sync.RWMutex
+	byName map[string]*gorm.DB
+	byPath map[string]*gorm.DB
+}
+
+// NewDatabases 创建连接管理器
+func NewDatabases() *Databases {
+	return &Databases{
+		byName: make(map[string]*gorm.DB),
+		byPath: make(map[string]*gorm.DB),
+	}
+}
+
+// Open 以指定名称打开数据库连接
+// 名称已存在时返回错误；路径与已打开的连接相同时直接复用该实例
+func (d *Databases) Open(name string, cfg Config) (*gorm.DB, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.byName[name]; ok {
+		return nil, fmt.Errorf("database %q already opened", name)
+	}
+
+	path := expandPath(cfg.Path)
+	if db, ok := d.byPath[path]; ok {
+		d.byName[name] = db
+		return db, nil
+	}
+
+	db, err := Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %q: %w", name, err)
+	}
+	d.byName[name] = db
+	d.byPath[path] = db
+	return db, nil
+}
+
+// Get 获取指定名称的连接，不存在时返回 nil
+func (d *Databases) Get(name string) *gorm.DB {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.byName[name]
+}
+
+// Names 返回所有已打开的连接名称（已排序）
+func (d *Databases) Names() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	names := make([]string, 0, len(d.byName))
+	for name := range d.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Ping 检查所有连接是否可用，返回第一个失败的连接错误
+func (d *Databases) Ping() error {
+	for _, name := range d.Names() {
+		if err := PingDB(d.Get(name)); err != nil {
+			return fmt.Errorf("database %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Close 关闭所有连接，复用的实例只关闭一次
+func (d *Databases) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var errs []error
+	for path, db := range d.byPath {
+		if err := CloseDB(db); err != nil {
+			errs = append(errs, fmt.Errorf("database %s: %w", path, err))
+		}
+	}
+	d.byName = make(map[string]*gorm.DB)
+	d.byPath = make(map[string]*gorm.DB)
+	return errors.Join(errs...)
+}
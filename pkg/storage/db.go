@@ -2,9 +2,14 @@
 package storage
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -13,48 +18,133 @@ import (
 	"github.com/KodaTao/AgentChassis/pkg/observability"
 )
 
-// DB 全局数据库实例
+// DB 全局数据库实例，仅由 InitDB 设置
+// App 通过自己的 Databases 管理连接，不使用也不修改该实例
 var DB *gorm.DB
 
 // Config 数据库配置
+// 连接参数为零值时使用适合单机写入的默认值
 type Config struct {
-	Path string // 数据库文件路径
+	Path         string        // 数据库文件路径
+	JournalMode  string        // 日志模式，默认 WAL，读写互不阻塞
+	BusyTimeout  time.Duration // 遇到锁时的等待时间，默认 5s，避免立即返回 "database is locked"
+	Synchronous  string        // 同步级别，默认 NORMAL（WAL 模式下安全且更快）
+	MaxOpenConns int           // 最大连接数，默认 1，SQLite 同一时刻只允许一个写入者
+	Logger       *slog.Logger  // 连接和迁移日志写入的 logger，为 nil 时使用全局 logger
 }
 
-// InitDB 初始化数据库连接
-func InitDB(cfg Config) error {
+// 默认连接参数
+const (
+	DefaultJournalMode  = "WAL"
+	DefaultBusyTimeout  = 5 * time.Second
+	DefaultSynchronous  = "NORMAL"
+	DefaultMaxOpenConns = 1
+)
+
+// withDefaults 填充未设置的连接参数
+func (c Config) withDefaults() Config {
+	if c.JournalMode == "" {
+		c.JournalMode = DefaultJournalMode
+	}
+	if c.BusyTimeout <= 0 {
+		c.BusyTimeout = DefaultBusyTimeout
+	}
+	if c.Synchronous == "" {
+		c.Synchronous = DefaultSynchronous
+	}
+	if c.MaxOpenConns <= 0 {
+		c.MaxOpenConns = DefaultMaxOpenConns
+	}
+	return c
+}
+
+// dsn 构建带 PRAGMA 参数的连接串
+// 通过 DSN 设置可保证连接池中每个新连接都应用相同的参数
+func (c Config) dsn(path string) string {
+	params := url.Values{}
+	params.Set("_journal_mode", c.JournalMode)
+	params.Set("_busy_timeout", fmt.Sprintf("%d", c.BusyTimeout.Milliseconds()))
+	params.Set("_synchronous", c.Synchronous)
+	return path + "?" + params.Encode()
+}
+
+// InitDB 初始化数据库连接，返回实例并设置为全局默认实例
+//
+// Deprecated: 全局实例无法支持同一进程中的多个 App，请使用 Open 或 Databases 并显式传递连接
+func InitDB(cfg Config) (*gorm.DB, error) {
+	db, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	DB = db
+	return db, nil
+}
+
+// Open 打开一个独立的数据库连接，不影响全局实例
+func Open(cfg Config) (*gorm.DB, error) {
+	cfg = cfg.withDefaults()
+
 	// 处理路径中的 ~
 	dbPath := expandPath(cfg.Path)
 
 	// 确保目录存在
 	dir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
+		return nil, err
 	}
 
 	// 配置 GORM 日志
 	gormLogger := logger.Default.LogMode(logger.Silent)
 
 	// 打开数据库连接
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	db, err := gorm.Open(sqlite.Open(cfg.dsn(dbPath)), &gorm.Config{
 		Logger: gormLogger,
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	DB = db
-	observability.Info("Database initialized", "path", dbPath)
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
 
-	return nil
+	// 读取实际生效的日志模式（内存数据库等场景可能不支持 WAL）
+	var journalMode string
+	if err := db.Raw("PRAGMA journal_mode").Scan(&journalMode).Error; err != nil {
+		return nil, fmt.Errorf("failed to query journal mode: %w", err)
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = observability.DefaultLogger()
+	}
+	logger.Info("Database initialized",
+		"path", dbPath,
+		"journal_mode", journalMode,
+		"busy_timeout", cfg.BusyTimeout,
+		"synchronous", cfg.Synchronous,
+		"max_open_conns", cfg.MaxOpenConns,
+	)
+
+	// 指定了 logger 时放入连接的默认 context，迁移等基于该连接的日志都写入它
+	if cfg.Logger != nil {
+		db = db.WithContext(observability.WithLogger(context.Background(), cfg.Logger))
+	}
+	return db, nil
 }
 
 // GetDB 获取数据库实例
+//
+// Deprecated: 请显式传递 Open 或 Databases 返回的连接
 func GetDB() *gorm.DB {
 	return DB
 }
 
-// AutoMigrate 自动迁移数据库表
+// AutoMigrate 自动迁移全局数据库实例中的表
+//
+// Deprecated: 请对具体连接使用 NewMigrator
 func AutoMigrate(models ...any) error {
 	if DB == nil {
 		return ErrDBNotInitialized
@@ -62,12 +152,38 @@ func AutoMigrate(models ...any) error {
 	return DB.AutoMigrate(models...)
 }
 
-// Close 关闭数据库连接
+// Ping 检查全局数据库连接是否可用
+//
+// Deprecated: 请使用 PingDB
+func Ping() error {
+	return PingDB(DB)
+}
+
+// PingDB 检查指定数据库连接是否可用
+func PingDB(db *gorm.DB) error {
+	if db == nil {
+		return ErrDBNotInitialized
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Ping()
+}
+
+// Close 关闭全局数据库连接
+//
+// Deprecated: 请使用 CloseDB 或 Databases.Close
 func Close() error {
-	if DB == nil {
+	return CloseDB(DB)
+}
+
+// CloseDB 关闭指定数据库连接
+func CloseDB(db *gorm.DB) error {
+	if db == nil {
 		return nil
 	}
-	sqlDB, err := DB.DB()
+	sqlDB, err := db.DB()
 	if err != nil {
 		return err
 	}
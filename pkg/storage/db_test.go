@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpen_Pragmas(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		journalMode string
+		busyTimeout int
+		synchronous int // 0 OFF, 1 NORMAL, 2 FULL
+		maxOpen     int
+	}{
+		{name: "defaults", cfg: Config{}, journalMode: "wal", busyTimeout: 5000, synchronous: 1, maxOpen: 1},
+		{
+			name:        "custom",
+			cfg:         Config{JournalMode: "DELETE", BusyTimeout: 2 * time.Second, Synchronous: "FULL", MaxOpenConns: 4},
+			journalMode: "delete",
+			busyTimeout: 2000,
+			synchronous: 2,
+			maxOpen:     4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.Path = filepath.Join(t.TempDir(), "nested", "data.db")
+			db, err := Open(cfg)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			sqlDB, err := db.DB()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sqlDB.Close()
+
+			var journalMode string
+			var busyTimeout, synchronous int
+			db.Raw("PRAGMA journal_mode").Scan(&journalMode)
+			db.Raw("PRAGMA busy_timeout").Scan(&busyTimeout)
+			db.Raw("PRAGMA synchronous").Scan(&synchronous)
+
+			if strings.ToLower(journalMode) != tt.journalMode {
+				t.Errorf("journal_mode = %s, want %s", journalMode, tt.journalMode)
+			}
+			if busyTimeout != tt.busyTimeout {
+				t.Errorf("busy_timeout = %d, want %d", busyTimeout, tt.busyTimeout)
+			}
+			if synchronous != tt.synchronous {
+				t.Errorf("synchronous = %d, want %d", synchronous, tt.synchronous)
+			}
+			if got := sqlDB.Stats().MaxOpenConnections; got != tt.maxOpen {
+				t.Errorf("max open conns = %d, want %d", got, tt.maxOpen)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+// Package storage 提供数据存储功能
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/KodaTao/AgentChassis/pkg/observability"
+)
+
+// Migration 一个有序的 schema 迁移
+// Version 在同一 scope 内唯一且只增不改；已发布的迁移不要修改，schema 变化一律追加新版本
+type Migration struct {
+	Version int                  // 版本号，按从小到大的顺序执行
+	Name    string               // 简短描述，如 "add priority to delay_tasks"
+	Up      func(*gorm.DB) error // 执行迁移
+	Down    func(*gorm.DB) error // 回滚迁移，可选
+}
+
+// SchemaMigration 已应用的迁移记录
+// 多个组件可能共用同一个数据库文件，用 Scope 区分各自的版本序列
+type SchemaMigration struct {
+	Scope     string    `gorm:"primaryKey;size:64" json:"scope"`
+	Version   int       `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"size:255" json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// TableName 指定表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// 迁移相关错误
+var (
+	ErrDuplicateMigration = errors.New("duplicate migration version")
+	ErrIrreversible       = errors.New("migration has no down step")
+	ErrNoMigration        = errors.New("no applied migration to roll back")
+)
+
+// Migrator 按版本顺序执行某个 scope 下未应用的迁移，并在 schema_migrations 表中记录
+type Migrator struct {
+	db         *gorm.DB
+	scope      string
+	migrations []Migration
+}
+
+// NewMigrator 创建迁移器，migrations 无需预先排序
+func NewMigrator(db *gorm.DB, scope string, migrations ...Migration) *Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, scope: scope, migrations: sorted}
+}
+
+// context 返回连接的默认 context，Open 指定了 logger 时迁移日志写入该 logger
+func (m *Migrator) context() context.Context {
+	if ctx := m.db.Statement.Context; ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// Migrate 执行所有未应用的迁移
+// 每个迁移与其版本记录在同一事务中提交，失败时停在该版本，已成功的迁移保留
+func (m *Migrator) Migrate() error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	if err := m.db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return fmt.Errorf("failed to migrate schema_migrations table: %w", err)
+	}
+
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return err
+	}
+
+	for _, mig := range m.migrations {
+		if applied[mig.Version] {
+			continue
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := mig.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Scope:     m.scope,
+				Version:   mig.Version,
+				Name:      mig.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s/%d (%s) failed: %w", m.scope, mig.Version, mig.Name, err)
+		}
+		observability.InfoContext(m.context(), "Migration applied", "scope", m.scope, "version", mig.Version, "name", mig.Name)
+	}
+	return nil
+}
+
+// Rollback 回滚最近一次应用的迁移
+func (m *Migrator) Rollback() error {
+	var last SchemaMigration
+	err := m.db.Where("scope = ?", m.scope).Order("version DESC").First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNoMigration
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+
+	var mig *Migration
+	for i := range m.migrations {
+		if m.migrations[i].Version == last.Version {
+			mig = &m.migrations[i]
+			break
+		}
+	}
+	if mig == nil || mig.Down == nil {
+		return fmt.Errorf("%w: %s/%d", ErrIrreversible, m.scope, last.Version)
+	}
+
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		if err := mig.Down(tx); err != nil {
+			return err
+		}
+		return tx.Where("scope = ? AND version = ?", m.scope, last.Version).Delete(&SchemaMigration{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("rollback of %s/%d (%s) failed: %w", m.scope, mig.Version, mig.Name, err)
+	}
+	observability.InfoContext(m.context(), "Migration rolled back", "scope", m.scope, "version", mig.Version, "name", mig.Name)
+	return nil
+}
+
+// Applied 返回该 scope 下已应用的迁移记录（按版本升序）
+func (m *Migrator) Applied() ([]SchemaMigration, error) {
+	var records []SchemaMigration
+	err := m.db.Where("scope = ?", m.scope).Order("version ASC").Find(&records).Error
+	return records, err
+}
+
+// appliedVersions 返回已应用的版本集合
+func (m *Migrator) appliedVersions() (map[int]bool, error) {
+	records, err := m.Applied()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+	applied := make(map[int]bool, len(records))
+	for _, r := range records {
+		applied[r.Version] = true
+	}
+	return applied, nil
+}
+
+// validate 检查版本号是否重复
+func (m *Migrator) validate() error {
+	for i := 1; i < len(m.migrations); i++ {
+		if m.migrations[i].Version == m.migrations[i-1].Version {
+			return fmt.Errorf("%w: %s/%d", ErrDuplicateMigration, m.scope, m.migrations[i].Version)
+		}
+	}
+	return nil
+}
+
+// CreateTables 返回以当前模型建表的迁移步骤，适合作为各组件的初始版本
+// 对已有的库（此前由 AutoMigrate 建表）同样安全，只会补齐缺失的表和列
+func CreateTables(models ...any) func(*gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.AutoMigrate(models...)
+	}
+}
+
+// DropTables 返回删除指定模型对应表的回滚步骤
+func DropTables(models ...any) func(*gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(models...)
+	}
+}
+
+// AddColumns 返回为模型添加字段对应列的迁移步骤，已存在的列会跳过，
+// 字段上声明的索引一并创建
+func AddColumns(model any, fields ...string) func(*gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		m := tx.Migrator()
+		for _, field := range fields {
+			if !m.HasColumn(model, field) {
+				if err := m.AddColumn(model, field); err != nil {
+					return err
+				}
+			}
+			if stmt.Schema.LookIndex(field) != nil && !m.HasIndex(model, field) {
+				if err := m.CreateIndex(model, field); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// DropColumns 返回删除字段对应列的回滚步骤
+func DropColumns(model any, fields ...string) func(*gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, field := range fields {
+			if err := tx.Migrator().DropColumn(model, field); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,42 @@
+// Package storagetest 提供测试用的内存数据库和仓库辅助函数
+package storagetest
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Migrator 可以执行迁移的仓库
+type Migrator interface {
+	Migrate() error
+}
+
+// NewDB 创建内存 SQLite 数据库，不输出 SQL 日志，测试结束时关闭连接
+func NewDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// NewRepository 在内存数据库上用 newRepo 创建仓库并执行迁移
+func NewRepository[R Migrator](t testing.TB, newRepo func(*gorm.DB) R) R {
+	t.Helper()
+	repo := newRepo(NewDB(t))
+	if err := repo.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return repo
+}
@@ -3,13 +3,16 @@ package telegram
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strconv"
 	"strings"
+	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
+	"github.com/KodaTao/AgentChassis/pkg/llm"
 	"github.com/KodaTao/AgentChassis/pkg/types"
 )
 
@@ -19,9 +22,13 @@ type Bot struct {
 	config       Config
 	sessionStore *SessionStore
 	sender       *Sender
+	queues       *chatQueues
 	agent        types.Agent
 	logger       *slog.Logger
 
+	activeMu sync.Mutex
+	active   map[int64]string // chat ID -> 正在处理的 session ID，用于 /cancel
+
 	ctx    context.Context
 	cancel context.CancelFunc
 }
@@ -53,10 +60,12 @@ func NewBot(config Config, agent types.Agent, logger *slog.Logger) (*Bot, error)
 		sessionStore: NewSessionStore(config.SessionTTL),
 		agent:        agent,
 		logger:       logger,
+		active:       make(map[int64]string),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
 	bot.sender = NewSender(api, logger)
+	bot.queues = newChatQueues(config.MaxQueuedMessages, bot.handleUpdate)
 
 	logger.Info("telegram bot created",
 		"username", api.Self.UserName,
@@ -81,14 +90,24 @@ func (b *Bot) Start() {
 				b.logger.Info("telegram bot stopped")
 				return
 			case update := <-updates:
+				// 内联按钮点击与消息进入同一 chat 的串行队列，确认执行不会与该 chat 的对话并发
+				if update.CallbackQuery != nil {
+					b.enqueueUpdate(update)
+					continue
+				}
 				if update.Message != nil {
+					// /cancel 不进入串行队列，否则要等当前消息处理完才会执行
+					if b.isCancelCommand(update.Message) {
+						go b.handleCancel(update.Message)
+						continue
+					}
 					if update.Message.Chat.IsGroup() || update.Message.Chat.IsChannel() {
 						// 群聊必须@才生效
-						if !strings.Contains(update.Message.Text, "@"+b.api.Self.UserName+" ") {
+						if !strings.Contains(messageText(update.Message), "@"+b.api.Self.UserName+" ") {
 							continue
 						}
 					}
-					go b.handleMessage(update.Message)
+					b.enqueueUpdate(update)
 				}
 			}
 		}
@@ -104,23 +123,108 @@ func (b *Bot) Stop() {
 	b.api.StopReceivingUpdates()
 }
 
+// enqueueUpdate 将消息或按钮点击放入所属 chat 的串行队列
+// 同一 chat 排队过多时直接提示用户，避免刷屏占用内存
+func (b *Bot) enqueueUpdate(update tgbotapi.Update) {
+	if cq := update.CallbackQuery; cq != nil {
+		// 内联模式消息上的按钮没有所属 chat，不会是本 Bot 发出的确认按钮
+		if cq.Message == nil {
+			b.answerCallback(cq.ID, "")
+			return
+		}
+		if b.queues.Enqueue(cq.Message.Chat.ID, update) {
+			return
+		}
+		b.logger.Warn("chat queue full, callback dropped",
+			"chat_id", cq.Message.Chat.ID,
+			"message_id", cq.Message.MessageID,
+		)
+		b.answerCallback(cq.ID, "消息太多啦，请等前面的消息处理完再点击。")
+		return
+	}
+
+	msg := update.Message
+	if b.queues.Enqueue(msg.Chat.ID, update) {
+		return
+	}
+
+	b.logger.Warn("chat queue full, message dropped",
+		"chat_id", msg.Chat.ID,
+		"message_id", msg.MessageID,
+	)
+	_, _ = b.sender.SendReply(msg.Chat.ID, msg.MessageID, "消息太多啦，请等前面的消息处理完再发送。")
+}
+
+// handleUpdate 处理串行队列中的一条更新
+func (b *Bot) handleUpdate(update tgbotapi.Update) {
+	if update.CallbackQuery != nil {
+		b.handleCallback(update.CallbackQuery)
+		return
+	}
+	b.handleMessage(update.Message)
+}
+
 // handleMessage 处理收到的消息
 func (b *Bot) handleMessage(msg *tgbotapi.Message) {
-	// 忽略非文本消息
-	if msg.Text == "" {
+	// 只处理文本和图片消息，贴纸、语音等直接忽略
+	text := messageText(msg)
+	image, hasImage := messageImage(msg, b.config.maxImageBytes())
+	if text == "" && !hasImage {
 		return
 	}
 
 	chatID := msg.Chat.ID
 	userMsgID := msg.MessageID
 
+	var username string
+	if msg.From != nil {
+		username = msg.From.UserName
+	}
+
+	// 白名单校验，不在白名单内的来源不调用 Agent
+	if !b.config.IsAllowed(chatID, username) {
+		b.logger.Warn("message rejected by whitelist",
+			"chat_id", chatID,
+			"from", username,
+		)
+		_, _ = b.sender.SendReply(chatID, userMsgID, "抱歉，你没有使用此 Bot 的权限。")
+		return
+	}
+
 	b.logger.Info("received message",
 		"chat_id", chatID,
 		"message_id", userMsgID,
-		"from", msg.From.UserName,
-		"text", truncateText(msg.Text, 50),
+		"from", username,
+		"text", truncateText(text, 50),
+		"image", hasImage,
 	)
 
+	var images []types.Attachment
+	if hasImage {
+		if !b.config.AcceptImages {
+			_, _ = b.sender.SendReply(chatID, userMsgID, "抱歉，暂不支持图片消息，请用文字描述。")
+			return
+		}
+		att, err := b.downloadImage(b.ctx, image, b.config.maxImageBytes())
+		if err != nil {
+			b.logger.Error("failed to download image",
+				"chat_id", chatID,
+				"message_id", userMsgID,
+				"error", err,
+			)
+			reply := "抱歉，图片下载失败，请稍后重试。"
+			if errors.Is(err, ErrImageTooLarge) {
+				reply = "抱歉，图片太大了，请压缩后再发送。"
+			}
+			_, _ = b.sender.SendReply(chatID, userMsgID, reply)
+			return
+		}
+		images = append(images, att)
+		if text == "" {
+			text = defaultImagePrompt
+		}
+	}
+
 	// 确定 session ID
 	var sessionID string
 	if msg.ReplyToMessage != nil && msg.ReplyToMessage.From.ID == b.api.Self.ID {
@@ -162,11 +266,26 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 	// 这样 AI 在创建任务时会把渠道信息包含在 channel 参数中
 	req := types.ChatRequest{
 		SessionID: sessionID,
-		Message:   fmt.Sprintf("【当前渠道：%s】\n%s", string(channelJSON), msg.Text),
+		Message:   fmt.Sprintf("【当前渠道：%s】\n%s", string(channelJSON), text),
 		Channel:   channel,
+		Images:    images,
+	}
+	// 按发送者记忆偏好，群聊中每个成员各自独立；发送者由 Telegram 验证，作为审计的调用方身份
+	ctx := b.ctx
+	if msg.From != nil {
+		req.UserID = telegramUserID(msg.From.ID)
+		req.Language = msg.From.LanguageCode
+		ctx = types.WithCaller(ctx, req.UserID)
+	}
+
+	// 支持按钮确认时，创建任务等操作由按钮确认，Agent 会在系统提示中告诉 AI 不必先用文字询问
+	if _, ok := b.agent.(types.CallConfirmer); ok {
+		req.ConfirmCalls = true
 	}
 
-	resp, err := b.agent.Chat(b.ctx, req)
+	b.setActive(chatID, sessionID)
+	resp, err := b.agent.Chat(ctx, req)
+	b.clearActive(chatID, sessionID)
 	if err != nil {
 		b.logger.Error("agent chat failed",
 			"chat_id", chatID,
@@ -174,12 +293,30 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 			"error", err,
 		)
 		// 发送错误提示给用户
-		_, _ = b.sender.SendReply(chatID, userMsgID, "抱歉，处理消息时出现了错误，请稍后重试。")
+		_, _ = b.sender.SendReply(chatID, userMsgID, chatErrorReply(err))
 		return
 	}
 
+	reply := resp.Reply
+	if resp.Cancelled {
+		reply = "已取消。"
+	}
+
+	if resp.Approval != nil {
+		reply = fmt.Sprintf("%s\n\n⏳ 该操作需要管理员审批，审批后会通知你结果。", reply)
+	}
+
+	// 有待确认的调用时附带 确认/取消 按钮
+	var keyboard *tgbotapi.InlineKeyboardMarkup
+	if resp.Pending != nil {
+		keyboard = confirmKeyboard(resp.Pending.ID)
+		if resp.Pending.HumanReadable != "" {
+			reply = fmt.Sprintf("%s\n\n⏰ %s", reply, resp.Pending.HumanReadable)
+		}
+	}
+
 	// 发送回复（reply 用户的消息）
-	botMsgID, err := b.sender.SendReply(chatID, userMsgID, resp.Reply)
+	botMsgID, err := b.sender.SendReplyWithKeyboard(chatID, userMsgID, reply, keyboard)
 	if err != nil {
 		b.logger.Error("failed to send reply",
 			"chat_id", chatID,
@@ -188,8 +325,14 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 		return
 	}
 
-	// 记录映射：bot 消息 ID -> session ID
-	b.sessionStore.Set(chatID, botMsgID, resp.SessionID)
+	// 记录映射：bot 消息 ID -> session ID；带按钮时同时记录发送者，只有他能确认或取消
+	if resp.Pending != nil && msg.From != nil {
+		b.sessionStore.SetWithOwner(chatID, botMsgID, resp.SessionID, msg.From.ID)
+	} else {
+		b.sessionStore.Set(chatID, botMsgID, resp.SessionID)
+	}
+
+	b.sendAttachments(chatID, botMsgID, resp.FunctionCalls)
 
 	b.logger.Info("message handled",
 		"chat_id", chatID,
@@ -203,11 +346,110 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 	b.sessionStore.Set(chatID, userMsgID, resp.SessionID)
 }
 
+// sendAttachments 将函数返回的附件逐个发送给用户，reply 到机器人的回复消息
+func (b *Bot) sendAttachments(chatID int64, replyToMsgID int, calls []types.FunctionCall) {
+	for _, fc := range calls {
+		for _, att := range fc.Attachments {
+			_, _ = b.sender.SendAttachment(chatID, replyToMsgID, att)
+		}
+	}
+}
+
+// chatErrorReply 根据对话失败的原因生成给用户的提示
+func chatErrorReply(err error) string {
+	if errors.Is(err, types.ErrSessionAwaitingApproval) {
+		return "该对话中有操作正在等待管理员审批，审批完成后才能继续。"
+	}
+	var apiErr *llm.APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.IsAuth():
+			return "抱歉，AI 服务鉴权失败，请联系管理员检查 API Key。"
+		case apiErr.IsRateLimited():
+			return "抱歉，AI 服务当前繁忙，请稍后重试。"
+		}
+	}
+	return "抱歉，处理消息时出现了错误，请稍后重试。"
+}
+
+// isCancelCommand 判断是否为 /cancel 命令，群聊中必须是 /cancel@bot 形式
+func (b *Bot) isCancelCommand(msg *tgbotapi.Message) bool {
+	if !msg.IsCommand() || msg.Command() != "cancel" {
+		return false
+	}
+	if msg.Chat.IsGroup() || msg.Chat.IsChannel() {
+		return msg.CommandWithAt() == "cancel@"+b.api.Self.UserName
+	}
+	return true
+}
+
+// handleCancel 处理 /cancel 命令，取消当前 chat 正在处理的消息
+func (b *Bot) handleCancel(msg *tgbotapi.Message) {
+	chatID := msg.Chat.ID
+
+	var username string
+	if msg.From != nil {
+		username = msg.From.UserName
+	}
+	if !b.config.IsAllowed(chatID, username) {
+		_, _ = b.sender.SendReply(chatID, msg.MessageID, "抱歉，你没有使用此 Bot 的权限。")
+		return
+	}
+
+	canceller, ok := b.agent.(types.SessionCanceller)
+	if !ok {
+		_, _ = b.sender.SendReply(chatID, msg.MessageID, "当前 Agent 不支持取消。")
+		return
+	}
+
+	b.activeMu.Lock()
+	sessionID, running := b.active[chatID]
+	b.activeMu.Unlock()
+
+	if !running || !canceller.CancelSession(sessionID) {
+		_, _ = b.sender.SendReply(chatID, msg.MessageID, "当前没有正在处理的消息。")
+		return
+	}
+
+	b.logger.Info("chat cancelled by user",
+		"chat_id", chatID,
+		"session_id", sessionID,
+		"from", username,
+	)
+	_, _ = b.sender.SendReply(chatID, msg.MessageID, "已取消当前任务。")
+}
+
+// setActive 记录 chat 正在处理的 session
+func (b *Bot) setActive(chatID int64, sessionID string) {
+	b.activeMu.Lock()
+	b.active[chatID] = sessionID
+	b.activeMu.Unlock()
+}
+
+// clearActive 清除 chat 正在处理的 session
+func (b *Bot) clearActive(chatID int64, sessionID string) {
+	b.activeMu.Lock()
+	if b.active[chatID] == sessionID {
+		delete(b.active, chatID)
+	}
+	b.activeMu.Unlock()
+}
+
 // GetSender 获取消息发送器（供外部使用，如任务触发通知）
 func (b *Bot) GetSender() *Sender {
 	return b.sender
 }
 
+// SendSessionMessage 发送属于某个会话的消息（如审批结果），用户 Reply 该消息即可继续这个会话
+func (b *Bot) SendSessionMessage(chatID int64, sessionID, text string) error {
+	msgID, err := b.sender.SendMessage(chatID, text)
+	if err != nil {
+		return err
+	}
+	b.sessionStore.Set(chatID, msgID, sessionID)
+	return nil
+}
+
 // SendNotification 发送通知消息到指定 chat
 // 用于任务触发时的通知
 func (b *Bot) SendNotification(chatID int64, text string) error {
@@ -223,3 +465,8 @@ func truncateText(text string, maxLen int) string {
 	}
 	return string(runes[:maxLen]) + "..."
 }
+
+// telegramUserID Telegram 用户的标识，用于长期记忆和审计的调用方身份
+func telegramUserID(id int64) string {
+	return "telegram:" + strconv.FormatInt(id, 10)
+}
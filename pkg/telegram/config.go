@@ -1,13 +1,26 @@
 // Package telegram 提供 Telegram Bot 集成功能
 package telegram
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Config Telegram Bot 配置
 type Config struct {
 	Enabled    bool          `mapstructure:"enabled"`     // 是否启用 Telegram Bot
 	Token      string        `mapstructure:"token"`       // Bot Token
 	SessionTTL time.Duration `mapstructure:"session_ttl"` // Session 映射保留时间
+
+	MaxQueuedMessages int `mapstructure:"max_queued_messages"` // 每个 chat 最多排队的消息数，超出的消息被丢弃
+
+	// 图片消息：开启后下载用户发送的图片，作为多模态输入传给模型（需要模型支持，如 gpt-4o）
+	AcceptImages  bool `mapstructure:"accept_images"`   // 是否接收图片
+	MaxImageBytes int  `mapstructure:"max_image_bytes"` // 单张图片最大字节数，默认 DefaultMaxImageBytes
+
+	// 白名单：均为空时不限制；任一匹配即允许
+	AllowedChatIDs   []int64  `mapstructure:"allowed_chat_ids"`  // 允许的会话 ID（私聊为用户 ID，群聊为负数群 ID）
+	AllowedUsernames []string `mapstructure:"allowed_usernames"` // 允许的用户名（不含 @，不区分大小写）
 }
 
 // DefaultConfig 返回默认配置
@@ -16,6 +29,8 @@ func DefaultConfig() *Config {
 		Enabled:    false,
 		Token:      "",
 		SessionTTL: 24 * time.Hour,
+
+		MaxQueuedMessages: DefaultMaxQueuedMessages,
 	}
 }
 
@@ -26,3 +41,34 @@ func (c Config) Validate() error {
 	}
 	return nil
 }
+
+// maxImageBytes 返回单张图片的大小上限
+func (c Config) maxImageBytes() int {
+	if c.MaxImageBytes > 0 {
+		return c.MaxImageBytes
+	}
+	return DefaultMaxImageBytes
+}
+
+// IsAllowed 判断消息来源是否在白名单内
+func (c Config) IsAllowed(chatID int64, username string) bool {
+	if len(c.AllowedChatIDs) == 0 && len(c.AllowedUsernames) == 0 {
+		return true
+	}
+
+	for _, id := range c.AllowedChatIDs {
+		if id == chatID {
+			return true
+		}
+	}
+
+	if username != "" {
+		for _, name := range c.AllowedUsernames {
+			if strings.EqualFold(strings.TrimPrefix(name, "@"), username) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,36 @@
+package telegram
+
+import "testing"
+
+func TestConfig_IsAllowed(t *testing.T) {
+	restricted := Config{
+		AllowedChatIDs:   []int64{1001, -100200},
+		AllowedUsernames: []string{"@Alice", "bob"},
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		chatID   int64
+		username string
+		want     bool
+	}{
+		{name: "empty lists allow everyone", config: Config{}, chatID: 42, username: "mallory", want: true},
+		{name: "listed chat ID", config: restricted, chatID: 1001, want: true},
+		{name: "unlisted chat ID", config: restricted, chatID: 1002, username: "mallory", want: false},
+		{name: "listed group chat", config: restricted, chatID: -100200, username: "mallory", want: true},
+		{name: "unlisted group chat", config: restricted, chatID: -100300, want: false},
+		{name: "listed username in an unlisted group", config: restricted, chatID: -100300, username: "bob", want: true},
+		{name: "username ignores case and @", config: restricted, chatID: 1002, username: "alice", want: true},
+		{name: "empty username does not match", config: Config{AllowedUsernames: []string{""}}, chatID: 1002, want: false},
+		{name: "chat IDs only", config: Config{AllowedChatIDs: []int64{1001}}, chatID: 1002, username: "bob", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.IsAllowed(tt.chatID, tt.username); got != tt.want {
+				t.Errorf("IsAllowed(%d, %q) = %v, want %v", tt.chatID, tt.username, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package telegram
+
+import (
+	"errors"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/KodaTao/AgentChassis/pkg/types"
+)
+
+// callback data 前缀，格式为 "<action>:<pending call id>"
+const (
+	callbackConfirm = "confirm"
+	callbackCancel  = "cancel"
+)
+
+// confirmKeyboard 生成待确认调用的 确认/取消 按钮
+func confirmKeyboard(callID string) *tgbotapi.InlineKeyboardMarkup {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ 确认", callbackConfirm+":"+callID),
+			tgbotapi.NewInlineKeyboardButtonData("❌ 取消", callbackCancel+":"+callID),
+		),
+	)
+	return &keyboard
+}
+
+// parseCallbackData 解析 callback data，返回是否确认和待确认调用 ID
+func parseCallbackData(data string) (approved bool, callID string, ok bool) {
+	action, callID, found := strings.Cut(data, ":")
+	if !found || callID == "" {
+		return false, "", false
+	}
+	switch action {
+	case callbackConfirm:
+		return true, callID, true
+	case callbackCancel:
+		return false, callID, true
+	default:
+		return false, "", false
+	}
+}
+
+// handleCallback 处理内联按钮点击，确认或取消挂起的函数调用
+// 由 chat 的串行队列调用，cq.Message 不为 nil
+func (b *Bot) handleCallback(cq *tgbotapi.CallbackQuery) {
+	chatID := cq.Message.Chat.ID
+	botMsgID := cq.Message.MessageID
+
+	var username string
+	if cq.From != nil {
+		username = cq.From.UserName
+	}
+	if !b.config.IsAllowed(chatID, username) {
+		b.answerCallback(cq.ID, "抱歉，你没有使用此 Bot 的权限。")
+		return
+	}
+
+	approved, callID, ok := parseCallbackData(cq.Data)
+	confirmer, supported := b.agent.(types.CallConfirmer)
+	if !ok || !supported {
+		b.answerCallback(cq.ID, "")
+		return
+	}
+
+	sessionID := b.sessionStore.Get(chatID, botMsgID)
+	if sessionID == "" {
+		b.answerCallback(cq.ID, "操作已过期。")
+		b.removeKeyboard(chatID, botMsgID)
+		return
+	}
+
+	// 群聊中其他成员也能看到按钮，只有发起该操作的用户可以确认或取消
+	if owner := b.sessionStore.Owner(chatID, botMsgID); owner != 0 && (cq.From == nil || cq.From.ID != owner) {
+		b.answerCallback(cq.ID, "只有发起该操作的用户可以确认或取消。")
+		return
+	}
+
+	// 先应答并移除按钮，避免重复点击
+	if approved {
+		b.answerCallback(cq.ID, "正在执行…")
+	} else {
+		b.answerCallback(cq.ID, "已取消")
+	}
+	b.removeKeyboard(chatID, botMsgID)
+
+	ctx := b.ctx
+	if cq.From != nil {
+		ctx = types.WithCaller(ctx, telegramUserID(cq.From.ID))
+	}
+	resp, err := confirmer.ConfirmCall(ctx, sessionID, callID, approved)
+	if err != nil {
+		if errors.Is(err, types.ErrPendingCallNotFound) {
+			_, _ = b.sender.SendReply(chatID, botMsgID, "该操作已处理或已过期。")
+			return
+		}
+		b.logger.Error("confirm call failed",
+			"chat_id", chatID,
+			"session_id", sessionID,
+			"error", err,
+		)
+		_, _ = b.sender.SendReply(chatID, botMsgID, "抱歉，处理操作时出现了错误，请稍后重试。")
+		return
+	}
+
+	reply := resp.Reply
+	if resp.Cancelled {
+		reply = "已取消。"
+	}
+	replyMsgID, err := b.sender.SendReply(chatID, botMsgID, reply)
+	if err != nil {
+		return
+	}
+	// 用户 reply 这条结果时继续同一个会话
+	b.sessionStore.Set(chatID, replyMsgID, sessionID)
+
+	b.sendAttachments(chatID, replyMsgID, resp.FunctionCalls)
+
+	b.logger.Info("pending call handled",
+		"chat_id", chatID,
+		"session_id", sessionID,
+		"approved", approved,
+		"from", username,
+	)
+}
+
+// answerCallback 应答 callback query，text 非空时在客户端弹出提示
+func (b *Bot) answerCallback(id, text string) {
+	if _, err := b.api.Request(tgbotapi.NewCallback(id, text)); err != nil {
+		b.logger.Warn("failed to answer callback query", "error", err)
+	}
+}
+
+// removeKeyboard 移除消息上的内联按钮
+func (b *Bot) removeKeyboard(chatID int64, msgID int) {
+	edit := tgbotapi.NewEditMessageReplyMarkup(chatID, msgID, tgbotapi.InlineKeyboardMarkup{
+		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{},
+	})
+	if _, err := b.api.Request(edit); err != nil {
+		b.logger.Warn("failed to remove inline keyboard", "chat_id", chatID, "error", err)
+	}
+}
@@ -0,0 +1,54 @@
+package telegram
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCallbackData(t *testing.T) {
+	tests := []struct {
+		data         string
+		wantApproved bool
+		wantID       string
+		wantOK       bool
+	}{
+		{"confirm:abc123", true, "abc123", true},
+		{"cancel:abc123", false, "abc123", true},
+		{"confirm:", false, "", false},
+		{"confirm", false, "", false},
+		{"delete:abc123", false, "", false},
+		{"", false, "", false},
+	}
+	for _, tt := range tests {
+		approved, id, ok := parseCallbackData(tt.data)
+		if approved != tt.wantApproved || id != tt.wantID || ok != tt.wantOK {
+			t.Errorf("parseCallbackData(%q) = %v, %q, %v, want %v, %q, %v",
+				tt.data, approved, id, ok, tt.wantApproved, tt.wantID, tt.wantOK)
+		}
+	}
+}
+
+func TestSessionStore_Owner(t *testing.T) {
+	store := NewSessionStore(time.Hour)
+	store.SetWithOwner(1, 10, "s1", 42)
+	store.Set(1, 11, "s1")
+
+	if got := store.Get(1, 10); got != "s1" {
+		t.Errorf("Get = %q, want s1", got)
+	}
+	if got := store.Owner(1, 10); got != 42 {
+		t.Errorf("Owner = %d, want 42", got)
+	}
+	if got := store.Owner(1, 11); got != 0 {
+		t.Errorf("Owner without owner = %d, want 0", got)
+	}
+	if got := store.Owner(2, 10); got != 0 {
+		t.Errorf("Owner of another chat = %d, want 0", got)
+	}
+
+	expired := NewSessionStore(-time.Second)
+	expired.SetWithOwner(1, 10, "s1", 42)
+	if got := expired.Owner(1, 10); got != 0 {
+		t.Errorf("Owner of expired entry = %d, want 0", got)
+	}
+}
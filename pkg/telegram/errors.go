@@ -11,4 +11,7 @@ var (
 
 	// ErrSessionNotFound Session 未找到
 	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrImageTooLarge 图片超过下载大小上限
+	ErrImageTooLarge = errors.New("image is too large")
 )
@@ -0,0 +1,159 @@
+package telegram
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// markdownV2Special MarkdownV2 中所有需要转义的字符
+const markdownV2Special = "\\_*[]()~`>#+-=|{}.!"
+
+// headingPattern 匹配 Markdown 标题行，MarkdownV2 没有标题语法，转为粗体
+var headingPattern = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.+?)\s*#*\s*$`)
+
+// inlinePattern 匹配 AI 常用的行内格式标记：
+// `code`、**bold**、__bold__、~~strike~~、[text](url)、*italic*、_italic_
+var inlinePattern = regexp.MustCompile("`[^`\n]+`" +
+	`|\*\*[^*\n]+?\*\*` +
+	`|__[^_\n]+?__` +
+	`|~~[^~\n]+?~~` +
+	`|\[[^\]\n]+\]\([^)\s]+\)` +
+	`|\*[^*\s](?:[^*\n]*[^*\s])?\*` +
+	`|_[^_\s](?:[^_\n]*[^_\s])?_`)
+
+// ToMarkdownV2 将 AI 生成的常规 Markdown 文本转换为 Telegram MarkdownV2
+// 保留代码块、行内代码、粗体、斜体、删除线和链接等格式标记，其余特殊字符（. ! - ( ) 等）全部转义，
+// 避免因为一个句号导致整条消息被 Telegram 拒绝
+func ToMarkdownV2(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	inFence := false
+
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			// 代码块的开始/结束标记原样保留（可带语言标识）
+			inFence = !inFence
+			out = append(out, strings.TrimSpace(line))
+			continue
+		}
+		if inFence {
+			out = append(out, escapeMarkdownV2Code(line))
+			continue
+		}
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			out = append(out, "*"+EscapeMarkdownV2(stripEmphasis(m[1]))+"*")
+			continue
+		}
+		out = append(out, convertInline(line))
+	}
+
+	// AI 输出被截断时代码块可能没有闭合
+	if inFence {
+		out = append(out, "```")
+	}
+	return strings.Join(out, "\n")
+}
+
+// EscapeMarkdownV2 转义文本中所有 MarkdownV2 特殊字符，结果按纯文本展示
+func EscapeMarkdownV2(text string) string {
+	var buf strings.Builder
+	buf.Grow(len(text))
+	for _, r := range text {
+		if strings.ContainsRune(markdownV2Special, r) {
+			buf.WriteByte('\\')
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// escapeMarkdownV2Code 转义代码内容，代码中只有 ` 和 \ 需要转义
+func escapeMarkdownV2Code(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(text)
+}
+
+// convertInline 转换一行中的行内格式标记，标记之外的文本全部转义
+func convertInline(line string) string {
+	var buf strings.Builder
+	last := 0
+	for _, loc := range inlinePattern.FindAllStringIndex(line, -1) {
+		start, end := loc[0], loc[1]
+		token := line[start:end]
+		// 单字符的 * _ 只在词边界处视为格式标记，避免误伤 snake_case 和 2*3*4
+		if isSingleDelimited(token) && !atWordBoundary(line, start, end) {
+			continue
+		}
+		// __x__ 同样要求词边界，且内容是单个标识符时视为 Python 的 __init__ 这类名字，按原文展示
+		if strings.HasPrefix(token, "__") && (!atWordBoundary(line, start, end) || isIdentifier(token[2:len(token)-2])) {
+			continue
+		}
+		buf.WriteString(EscapeMarkdownV2(line[last:start]))
+		buf.WriteString(convertToken(token))
+		last = end
+	}
+	buf.WriteString(EscapeMarkdownV2(line[last:]))
+	return buf.String()
+}
+
+// convertToken 将单个格式标记转换为 MarkdownV2 写法
+func convertToken(token string) string {
+	switch {
+	case strings.HasPrefix(token, "`"):
+		return "`" + escapeMarkdownV2Code(token[1:len(token)-1]) + "`"
+	case strings.HasPrefix(token, "**"), strings.HasPrefix(token, "__"):
+		// 常规 Markdown 中两者都是粗体；MarkdownV2 中 __ 是下划线，统一转成 *
+		return "*" + EscapeMarkdownV2(token[2:len(token)-2]) + "*"
+	case strings.HasPrefix(token, "~~"):
+		return "~" + EscapeMarkdownV2(token[2:len(token)-2]) + "~"
+	case strings.HasPrefix(token, "["):
+		sep := strings.Index(token, "](")
+		label, url := token[1:sep], token[sep+2:len(token)-1]
+		return "[" + EscapeMarkdownV2(label) + "](" + strings.NewReplacer("\\", "\\\\", ")", "\\)").Replace(url) + ")"
+	default:
+		// *italic* 或 _italic_
+		return "_" + EscapeMarkdownV2(token[1:len(token)-1]) + "_"
+	}
+}
+
+// isSingleDelimited 是否为 *x* 或 _x_ 形式的标记
+func isSingleDelimited(token string) bool {
+	return (token[0] == '*' || token[0] == '_') && token[1] != token[0]
+}
+
+// atWordBoundary 标记前后是否都不是字母或数字
+func atWordBoundary(line string, start, end int) bool {
+	if start > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(line[:start]); isWordRune(r) {
+			return false
+		}
+	}
+	if end < len(line) {
+		if r, _ := utf8.DecodeRuneInString(line[end:]); isWordRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isWordRune 是否为组成标识符或数字的 ASCII 字符
+// 中文等非 ASCII 字符紧挨着格式标记很常见（如 这是*重点*内容），不视为单词的一部分
+func isWordRune(r rune) bool {
+	return r < utf8.RuneSelf && (r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// isIdentifier 是否只由 ASCII 字母、数字和下划线组成
+func isIdentifier(text string) bool {
+	for _, r := range text {
+		if !isWordRune(r) {
+			return false
+		}
+	}
+	return text != ""
+}
+
+// stripEmphasis 去掉标题文本中的粗体标记，标题整体已经加粗
+func stripEmphasis(text string) string {
+	return strings.NewReplacer("**", "", "__", "").Replace(text)
+}
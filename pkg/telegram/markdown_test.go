@@ -0,0 +1,50 @@
+package telegram
+
+import "testing"
+
+func TestToMarkdownV2(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain text escapes punctuation", "Done. Cost: $1.50 (approx)!", `Done\. Cost: $1\.50 \(approx\)\!`},
+		{"bold stars", "this is **important**", `this is *important*`},
+		{"bold underscores", "this is __very important__ now", `this is *very important* now`},
+		{"italic star", "an *emphasis* here", `an _emphasis_ here`},
+		{"italic underscore", "an _emphasis_ here", `an _emphasis_ here`},
+		{"strike", "~~old~~ new", `~old~ new`},
+		{"inline code", "run `a_b*c` now", "run `a_b*c` now"},
+		{"link", "see [the docs](https://example.com/a_b)", `see [the docs](https://example.com/a_b)`},
+		{"heading", "## Step 1. **Setup**", `*Step 1\. Setup*`},
+		{"snake_case", "set max_retry_count to 3", `set max\_retry\_count to 3`},
+		{"multiplication", "2*3*4 = 24", `2\*3\*4 \= 24`},
+		{"dunder method", "override __init__ first", `override \_\_init\_\_ first`},
+		{"dunder call", "call self.__init__() again", `call self\.\_\_init\_\_\(\) again`},
+		{"dunder main", `if __name__ == "__main__":`, `if \_\_name\_\_ \=\= "\_\_main\_\_":`},
+		{"underscores inside a word", "foo__bar baz__qux", `foo\_\_bar baz\_\_qux`},
+		{"cjk next to emphasis", "这是*重点*内容", `这是_重点_内容`},
+		{
+			"code block",
+			"```go\nfmt.Println(\"a_b\")\n```",
+			"```go\nfmt.Println(\"a_b\")\n```",
+		},
+		{"unclosed code block", "```\nx := `y`", "```\nx := \\`y\\`\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToMarkdownV2(tt.in); got != tt.want {
+				t.Errorf("ToMarkdownV2(%q)\n got  %s\n want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	in := `_*[]()~` + "`" + `>#+-=|{}.!\`
+	want := `\_\*\[\]\(\)\~\` + "`" + `\>\#\+\-\=\|\{\}\.\!\\`
+	if got := EscapeMarkdownV2(in); got != want {
+		t.Errorf("EscapeMarkdownV2() = %s, want %s", got, want)
+	}
+}
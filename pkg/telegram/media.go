@@ -0,0 +1,123 @@
+// Package telegram 提供 Telegram Bot 集成功能
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/KodaTao/AgentChassis/pkg/types"
+)
+
+// DefaultMaxImageBytes 单张图片默认的最大字节数
+// OpenAI 限制单张图片 20MB，这里取更保守的值以控制内存和请求大小
+const DefaultMaxImageBytes = 10 << 20
+
+// imageDownloadTimeout 下载单张图片的超时时间
+const imageDownloadTimeout = 30 * time.Second
+
+// defaultImagePrompt 用户只发图片、没有附带文字时使用的提示
+const defaultImagePrompt = "请描述这张图片。"
+
+// imageFile 消息中的图片文件
+type imageFile struct {
+	fileID   string
+	filename string
+	mimeType string
+	size     int
+}
+
+// messageText 返回消息的文字内容：文本消息取 Text，图片、文件消息取说明文字（Caption）
+func messageText(msg *tgbotapi.Message) string {
+	if msg.Text != "" {
+		return msg.Text
+	}
+	return msg.Caption
+}
+
+// messageImage 返回消息中的图片：照片取不超过 maxBytes 的最大尺寸，文件仅接受 image/* 类型
+func messageImage(msg *tgbotapi.Message, maxBytes int) (imageFile, bool) {
+	if len(msg.Photo) > 0 {
+		// Photo 按尺寸从小到大排列，选择大小未超限的最大一张；都超限时取最小的一张，由下载时报错
+		best := msg.Photo[0]
+		for _, p := range msg.Photo[1:] {
+			if p.FileSize == 0 || p.FileSize <= maxBytes {
+				best = p
+			}
+		}
+		return imageFile{
+			fileID:   best.FileID,
+			filename: best.FileUniqueID + ".jpg",
+			mimeType: "image/jpeg", // Telegram 会把照片统一压缩为 JPEG
+			size:     best.FileSize,
+		}, true
+	}
+	if doc := msg.Document; doc != nil && strings.HasPrefix(doc.MimeType, "image/") {
+		return imageFile{
+			fileID:   doc.FileID,
+			filename: doc.FileName,
+			mimeType: doc.MimeType,
+			size:     doc.FileSize,
+		}, true
+	}
+	return imageFile{}, false
+}
+
+// downloadImage 通过 Bot API 下载图片，超过 maxBytes 时返回 ErrImageTooLarge
+func (b *Bot) downloadImage(ctx context.Context, img imageFile, maxBytes int) (types.Attachment, error) {
+	if img.size > maxBytes {
+		return types.Attachment{}, fmt.Errorf("%w: %d bytes (limit %d)", ErrImageTooLarge, img.size, maxBytes)
+	}
+
+	fileURL, err := b.api.GetFileDirectURL(img.fileID)
+	if err != nil {
+		return types.Attachment{}, fmt.Errorf("failed to get file url: %w", err)
+	}
+	return fetchImage(ctx, fileURL, img, maxBytes)
+}
+
+// fetchImage 从文件地址下载图片，最多读取 maxBytes 字节，超过时返回 ErrImageTooLarge
+// 未知 MIME 类型时按内容识别
+func fetchImage(ctx context.Context, fileURL string, img imageFile, maxBytes int) (types.Attachment, error) {
+	ctx, cancel := context.WithTimeout(ctx, imageDownloadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return types.Attachment{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// url.Error 的信息中包含带 Bot Token 的下载地址，只保留内层错误
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return types.Attachment{}, fmt.Errorf("failed to download image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return types.Attachment{}, fmt.Errorf("failed to download image: status %d", resp.StatusCode)
+	}
+
+	// 多读 1 字节用于判断是否超限
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+	if err != nil {
+		return types.Attachment{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > maxBytes {
+		return types.Attachment{}, fmt.Errorf("%w: more than %d bytes", ErrImageTooLarge, maxBytes)
+	}
+
+	mimeType := img.mimeType
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return types.Attachment{Filename: img.filename, MimeType: mimeType, Content: data}, nil
+}
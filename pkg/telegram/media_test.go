@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestMessageImage(t *testing.T) {
+	photos := []tgbotapi.PhotoSize{
+		{FileID: "small", FileUniqueID: "s", FileSize: 100},
+		{FileID: "medium", FileUniqueID: "m", FileSize: 1000},
+		{FileID: "large", FileUniqueID: "l", FileSize: 10000},
+	}
+
+	tests := []struct {
+		name     string
+		msg      *tgbotapi.Message
+		maxBytes int
+		wantOK   bool
+		wantID   string
+		wantMime string
+	}{
+		{"text only", &tgbotapi.Message{Text: "hi"}, 5000, false, "", ""},
+		{"largest photo within limit", &tgbotapi.Message{Photo: photos}, 5000, true, "medium", "image/jpeg"},
+		{"all photos within limit", &tgbotapi.Message{Photo: photos}, 20000, true, "large", "image/jpeg"},
+		{"all photos too large", &tgbotapi.Message{Photo: photos}, 50, true, "small", "image/jpeg"},
+		{"unknown photo size", &tgbotapi.Message{Photo: []tgbotapi.PhotoSize{photos[0], {FileID: "unknown"}}}, 50, true, "unknown", "image/jpeg"},
+		{"image document", &tgbotapi.Message{Document: &tgbotapi.Document{FileID: "doc", MimeType: "image/png"}}, 5000, true, "doc", "image/png"},
+		{"non-image document", &tgbotapi.Message{Document: &tgbotapi.Document{FileID: "doc", MimeType: "application/pdf"}}, 5000, false, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, ok := messageImage(tt.msg, tt.maxBytes)
+			if ok != tt.wantOK {
+				t.Fatalf("messageImage() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if img.fileID != tt.wantID || img.mimeType != tt.wantMime {
+				t.Errorf("messageImage() = %+v, want file %q (%s)", img, tt.wantID, tt.wantMime)
+			}
+		})
+	}
+}
+
+func TestDownloadImage_KnownSizeTooLarge(t *testing.T) {
+	// 已知大小超限时不请求 Bot API
+	b := &Bot{}
+	_, err := b.downloadImage(context.Background(), imageFile{fileID: "x", size: 2048}, 1024)
+	if !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("downloadImage() error = %v, want ErrImageTooLarge", err)
+	}
+}
+
+func TestFetchImage(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 56)...) // 64 字节
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(png)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		img      imageFile
+		maxBytes int
+		wantErr  error
+		wantMime string
+	}{
+		{"within limit", "/photo", imageFile{filename: "a.jpg", mimeType: "image/jpeg"}, 64, nil, "image/jpeg"},
+		{"detects unknown mime type", "/photo", imageFile{filename: "a"}, 1024, nil, "image/png"},
+		{"body over limit", "/photo", imageFile{mimeType: "image/png"}, 63, ErrImageTooLarge, ""},
+		{"download failed", "/missing", imageFile{mimeType: "image/png"}, 1024, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			att, err := fetchImage(context.Background(), srv.URL+tt.path, tt.img, tt.maxBytes)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("fetchImage() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantMime == "" {
+				if err == nil {
+					t.Fatal("fetchImage() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetchImage() error = %v", err)
+			}
+			if att.MimeType != tt.wantMime || att.Filename != tt.img.filename || !bytes.Equal(att.Content, png) {
+				t.Errorf("fetchImage() = %s %q (%d bytes)", att.MimeType, att.Filename, len(att.Content))
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Package telegram 提供 Telegram Bot 集成功能
+package telegram
+
+import (
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// DefaultMaxQueuedMessages 每个 chat 默认最多排队的消息数
+const DefaultMaxQueuedMessages = 10
+
+// chatQueues 按 chat 串行处理消息和按钮点击
+// 同一 chat 的更新按到达顺序依次处理，不同 chat 之间并发
+type chatQueues struct {
+	mu         sync.Mutex
+	queues     map[int64]chan tgbotapi.Update
+	maxPending int
+	handle     func(tgbotapi.Update)
+}
+
+// newChatQueues 创建 chat 消息队列，maxPending 为每个 chat 的排队上限
+func newChatQueues(maxPending int, handle func(tgbotapi.Update)) *chatQueues {
+	if maxPending <= 0 {
+		maxPending = DefaultMaxQueuedMessages
+	}
+	return &chatQueues{
+		queues:     make(map[int64]chan tgbotapi.Update),
+		maxPending: maxPending,
+		handle:     handle,
+	}
+}
+
+// Enqueue 将更新加入 chatID 的队列，队列已满时返回 false
+func (q *chatQueues) Enqueue(chatID int64, update tgbotapi.Update) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ch, ok := q.queues[chatID]
+	if !ok {
+		ch = make(chan tgbotapi.Update, q.maxPending)
+		q.queues[chatID] = ch
+		go q.worker(chatID, ch)
+	}
+
+	select {
+	case ch <- update:
+		return true
+	default:
+		return false
+	}
+}
+
+// worker 依次处理单个 chat 的更新，队列清空后退出，避免空闲 chat 常驻 goroutine
+func (q *chatQueues) worker(chatID int64, ch chan tgbotapi.Update) {
+	for {
+		select {
+		case update := <-ch:
+			q.handle(update)
+		default:
+			// 持锁确认队列为空后再移除，Enqueue 同样持锁写入，不会丢消息
+			q.mu.Lock()
+			if len(ch) == 0 {
+				delete(q.queues, chatID)
+				q.mu.Unlock()
+				return
+			}
+			q.mu.Unlock()
+		}
+	}
+}
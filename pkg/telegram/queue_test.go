@@ -0,0 +1,91 @@
+package telegram
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestChatQueues_CallbacksSerializedWithMessages(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	running := 0
+	release := make(chan struct{})
+	done := make(chan struct{}, 3)
+
+	q := newChatQueues(5, func(update tgbotapi.Update) {
+		mu.Lock()
+		running++
+		if running > 1 {
+			t.Errorf("updates of the same chat handled concurrently")
+		}
+		if update.CallbackQuery != nil {
+			order = append(order, "callback:"+update.CallbackQuery.Data)
+		} else {
+			order = append(order, "message:"+update.Message.Text)
+		}
+		mu.Unlock()
+
+		if update.Message != nil && update.Message.Text == "first" {
+			<-release
+		}
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		done <- struct{}{}
+	})
+
+	chat := &tgbotapi.Chat{ID: 1}
+	q.Enqueue(1, tgbotapi.Update{Message: &tgbotapi.Message{Chat: chat, Text: "first"}})
+	q.Enqueue(1, tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{Data: "confirm:abc", Message: &tgbotapi.Message{Chat: chat}}})
+	q.Enqueue(1, tgbotapi.Update{Message: &tgbotapi.Message{Chat: chat, Text: "second"}})
+	close(release)
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for queued updates")
+		}
+	}
+
+	want := []string{"message:first", "callback:confirm:abc", "message:second"}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestChatQueues_Full(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	q := newChatQueues(1, func(tgbotapi.Update) { <-release })
+
+	update := tgbotapi.Update{Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 1}}}
+	if !q.Enqueue(1, update) {
+		t.Fatal("first update rejected")
+	}
+	// 等 worker 取走第一条，队列中再放一条即满
+	deadline := time.Now().Add(2 * time.Second)
+	for !q.Enqueue(1, update) {
+		if time.Now().After(deadline) {
+			t.Fatal("queue never accepted a second update")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if q.Enqueue(1, update) {
+		t.Error("update accepted beyond maxPending")
+	}
+	if !q.Enqueue(2, update) {
+		t.Error("other chats should have their own queue")
+	}
+}
@@ -3,8 +3,11 @@ package telegram
 import (
 	"fmt"
 	"log/slog"
+	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/KodaTao/AgentChassis/pkg/types"
 )
 
 // Sender 消息发送器
@@ -25,17 +28,27 @@ func NewSender(bot *tgbotapi.BotAPI, logger *slog.Logger) *Sender {
 // SendReply 发送回复消息（reply 指定的消息）
 // 返回发送的消息 ID
 func (s *Sender) SendReply(chatID int64, replyToMsgID int, text string) (int, error) {
-	msg := tgbotapi.NewMessage(chatID, text)
+	return s.SendReplyWithKeyboard(chatID, replyToMsgID, text, nil)
+}
+
+// SendReplyWithKeyboard 发送带内联按钮的回复消息，keyboard 为 nil 时等同于 SendReply
+// 返回发送的消息 ID
+func (s *Sender) SendReplyWithKeyboard(chatID int64, replyToMsgID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) (int, error) {
+	msg := tgbotapi.NewMessage(chatID, ToMarkdownV2(text))
 	msg.ReplyToMessageID = replyToMsgID
 	msg.ParseMode = tgbotapi.ModeMarkdownV2
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
 
 	sent, err := s.bot.Send(msg)
 	if err != nil {
-		// 如果 Markdown 解析失败，尝试以纯文本发送
+		// 转换后仍解析失败（如格式标记嵌套不合法），以原始文本按纯文本发送
 		s.logger.Warn("failed to send markdown message, retrying as plain text",
 			"chat_id", chatID,
 			"error", err,
 		)
+		msg.Text = text
 		msg.ParseMode = ""
 		sent, err = s.bot.Send(msg)
 		if err != nil {
@@ -59,16 +72,17 @@ func (s *Sender) SendReply(chatID int64, replyToMsgID int, text string) (int, er
 // SendMessage 发送消息（不 reply）
 // 用于任务触发时的通知
 func (s *Sender) SendMessage(chatID int64, text string) (int, error) {
-	msg := tgbotapi.NewMessage(chatID, text)
+	msg := tgbotapi.NewMessage(chatID, ToMarkdownV2(text))
 	msg.ParseMode = tgbotapi.ModeMarkdownV2
 
 	sent, err := s.bot.Send(msg)
 	if err != nil {
-		// 如果 Markdown 解析失败，尝试以纯文本发送
+		// 转换后仍解析失败（如格式标记嵌套不合法），以原始文本按纯文本发送
 		s.logger.Warn("failed to send markdown message, retrying as plain text",
 			"chat_id", chatID,
 			"error", err,
 		)
+		msg.Text = text
 		msg.ParseMode = ""
 		sent, err = s.bot.Send(msg)
 		if err != nil {
@@ -87,3 +101,39 @@ func (s *Sender) SendMessage(chatID int64, text string) (int, error) {
 
 	return sent.MessageID, nil
 }
+
+// SendAttachment 发送函数返回的附件（reply 指定的消息）
+// 图片类型以照片发送，其余以文件发送
+func (s *Sender) SendAttachment(chatID int64, replyToMsgID int, att types.Attachment) (int, error) {
+	file := tgbotapi.FileBytes{Name: att.Filename, Bytes: att.Content}
+
+	var msg tgbotapi.Chattable
+	if strings.HasPrefix(att.MimeType, "image/") {
+		photo := tgbotapi.NewPhoto(chatID, file)
+		photo.ReplyToMessageID = replyToMsgID
+		msg = photo
+	} else {
+		doc := tgbotapi.NewDocument(chatID, file)
+		doc.ReplyToMessageID = replyToMsgID
+		msg = doc
+	}
+
+	sent, err := s.bot.Send(msg)
+	if err != nil {
+		s.logger.Error("failed to send attachment",
+			"chat_id", chatID,
+			"filename", att.Filename,
+			"error", err,
+		)
+		return 0, fmt.Errorf("failed to send attachment: %w", err)
+	}
+
+	s.logger.Debug("attachment sent",
+		"chat_id", chatID,
+		"message_id", sent.MessageID,
+		"filename", att.Filename,
+		"size", len(att.Content),
+	)
+
+	return sent.MessageID, nil
+}
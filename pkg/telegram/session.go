@@ -9,6 +9,7 @@ import (
 // SessionEntry 会话条目
 type SessionEntry struct {
 	SessionID string    // Agent session ID
+	OwnerID   int64     // 有权点击该消息按钮的用户 ID，0 表示不限
 	CreatedAt time.Time // 创建时间
 }
 
@@ -34,6 +35,11 @@ func NewSessionStore(ttl time.Duration) *SessionStore {
 
 // Set 记录映射关系
 func (s *SessionStore) Set(chatID int64, msgID int, sessionID string) {
+	s.SetWithOwner(chatID, msgID, sessionID, 0)
+}
+
+// SetWithOwner 记录映射关系，并记录有权点击该消息按钮的用户
+func (s *SessionStore) SetWithOwner(chatID int64, msgID int, sessionID string, ownerID int64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -43,6 +49,7 @@ func (s *SessionStore) Set(chatID int64, msgID int, sessionID string) {
 
 	s.sessions[chatID][msgID] = &SessionEntry{
 		SessionID: sessionID,
+		OwnerID:   ownerID,
 		CreatedAt: time.Now(),
 	}
 }
@@ -64,6 +71,17 @@ func (s *SessionStore) Get(chatID int64, msgID int) string {
 	return ""
 }
 
+// Owner 返回有权点击该消息按钮的用户 ID，未记录或已过期时返回 0
+func (s *SessionStore) Owner(chatID int64, msgID int) int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if entry, ok := s.sessions[chatID][msgID]; ok && time.Since(entry.CreatedAt) <= s.ttl {
+		return entry.OwnerID
+	}
+	return 0
+}
+
 // GenerateSessionID 生成新的 session ID
 func GenerateSessionID(chatID int64) string {
 	return fmt.Sprintf("tg_%d_%d", chatID, time.Now().UnixNano())
@@ -1,7 +1,16 @@
 // Package types 提供跨包共享的类型定义
 package types
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrPendingCallNotFound 待确认的调用不存在、已处理或已过期
+var ErrPendingCallNotFound = errors.New("pending call not found or expired")
+
+// ErrSessionAwaitingApproval 会话中有函数调用等待管理员审批，审批完成前不能继续对话
+var ErrSessionAwaitingApproval = errors.New("session is awaiting approval")
 
 // ChannelContext 渠道上下文
 // 用于标识消息来源渠道，任务执行时也会使用此信息进行通知
@@ -11,25 +20,117 @@ type ChannelContext struct {
 	Extra  map[string]string `json:"extra,omitempty"`   // 其他扩展参数
 }
 
+// channelKey context 中渠道上下文的 key
+type channelKey struct{}
+
+// WithChannel 将当前对话的渠道上下文写入 context
+// 函数（如 send_message）可据此获取默认的通知渠道，无需 AI 手动传入 chat_id
+func WithChannel(ctx context.Context, channel *ChannelContext) context.Context {
+	return context.WithValue(ctx, channelKey{}, channel)
+}
+
+// ChannelFromContext 获取当前对话的渠道上下文，未设置时返回 nil
+func ChannelFromContext(ctx context.Context) *ChannelContext {
+	channel, _ := ctx.Value(channelKey{}).(*ChannelContext)
+	return channel
+}
+
+// callerKey context 中调用方身份的 key
+type callerKey struct{}
+
+// WithCaller 将经过鉴权的调用方身份（如 api_key:admin、telegram:123456）写入 context
+// 只应由鉴权中间件、渠道接入等可信代码设置，不能取自请求内容，审计记录据此记录是谁发起的操作
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext 获取经过鉴权的调用方身份，未设置时返回空字符串
+func CallerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
 // ChatRequest 对话请求
 type ChatRequest struct {
 	SessionID string          `json:"session_id"`
 	Message   string          `json:"message"`
 	Channel   *ChannelContext `json:"channel,omitempty"` // 渠道上下文
+
+	// UserID 用户标识，用于读写该用户的长期记忆；为空时由渠道类型和聊天 ID 组成
+	// 由接入方根据已验证的身份设置（HTTP 为 API Key 名称，Telegram 为消息发送者），不从请求体解析，
+	// 否则调用方可以冒用其他用户的记忆
+	UserID string `json:"-"`
+
+	// Language 系统提示词的语言（如 en、zh、ja、zh-CN），未指定时使用配置的默认语言
+	Language string `json:"language,omitempty"`
+
+	// 可选的单次请求模型参数覆盖，未指定时使用全局默认配置
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+
+	// ResponseFormat 输出格式覆盖：text 或 json_object（要求模型直接输出 JSON 对象）
+	ResponseFormat string `json:"response_format,omitempty"`
+
+	// History 调用方自带的历史消息，只在新会话中写入（位于系统提示之后、本次用户消息之前）
+	// 用于每次请求都携带完整对话的无状态调用方，如 OpenAI 兼容接口
+	History []HistoryMessage `json:"-"`
+
+	// Images 随本次消息发送的图片（如 Telegram 用户发来的照片），作为多模态输入传给模型
+	Images []Attachment `json:"-"`
+
+	// ConfirmCalls 渠道支持交互式确认（如 Telegram 按钮）时设置，
+	// 需要确认的函数调用会先挂起，通过 CallConfirmer.ConfirmCall 确认后才执行
+	ConfirmCalls bool `json:"-"`
+}
+
+// HistoryMessage 调用方提供的一条历史消息
+type HistoryMessage struct {
+	Role    string `json:"role"` // system、user、assistant
+	Content string `json:"content"`
 }
 
 // ChatResponse 对话响应
 type ChatResponse struct {
 	SessionID     string         `json:"session_id"`
+	RequestID     string         `json:"request_id,omitempty"` // 本次请求的 ID，可用于查询该请求触发的函数调用记录
 	Reply         string         `json:"reply"`
 	FunctionCalls []FunctionCall `json:"function_calls,omitempty"`
+	Thoughts      []string       `json:"thoughts,omitempty"`  // 各轮函数调用前的说明文字（需开启 ShowThoughts）
+	Cancelled     bool           `json:"cancelled,omitempty"` // 对话被中途取消
+	Pending       *PendingCall   `json:"pending,omitempty"`   // 等待用户确认的函数调用
+	Approval      *PendingCall   `json:"approval,omitempty"`  // 等待管理员审批的函数调用，ID 为审批请求 ID
+}
+
+// PendingCall 挂起等待用户确认的函数调用
+type PendingCall struct {
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params,omitempty"`
+
+	// HumanReadable 调用的可读摘要（如 cron 表达式的中文描述），渠道可展示给用户辅助确认
+	HumanReadable string `json:"human_readable,omitempty"`
 }
 
 // FunctionCall 函数调用记录
 type FunctionCall struct {
 	Name   string `json:"name"`
-	Status string `json:"status"` // success, error
+	CallID string `json:"call_id,omitempty"` // <request_id>.<第几轮>.<第几个>
+	Status string `json:"status"`            // success, error, pending
 	Result string `json:"result"`
+
+	// Markdown 供终端用户展示的 Markdown 输出（如结果表格），展示时优先于 TOON 使用
+	Markdown string `json:"markdown,omitempty"`
+
+	// Attachments 函数生成的文件，JSON 中 content 为 base64 编码
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment 函数返回给用户的文件
+type Attachment struct {
+	Filename string `json:"filename"`
+	MimeType string `json:"mime_type"`
+	Content  []byte `json:"content"`
 }
 
 // Agent 接口定义
@@ -37,3 +138,15 @@ type FunctionCall struct {
 type Agent interface {
 	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
 }
+
+// CallConfirmer 可选接口：支持确认或拒绝挂起的函数调用
+type CallConfirmer interface {
+	// ConfirmCall approved 为 true 时执行挂起的调用，否则丢弃
+	ConfirmCall(ctx context.Context, sessionID, callID string, approved bool) (*ChatResponse, error)
+}
+
+// SessionCanceller 可选接口：支持取消会话上正在进行的对话
+type SessionCanceller interface {
+	// CancelSession 取消会话上正在进行的对话，没有进行中的对话时返回 false
+	CancelSession(sessionID string) bool
+}